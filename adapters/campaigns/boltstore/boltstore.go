@@ -0,0 +1,263 @@
+// Package boltstore provides an ICampaignRepository backed by an embedded
+// bbolt key-value database, so campaigns resume after a restart.
+package boltstore
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	dto_campaign "github.com/irissonnlima/chatgraph-go/adapters/dto/campaign"
+	d_campaign "github.com/irissonnlima/chatgraph-go/core/domain/campaign"
+	adapter_output "github.com/irissonnlima/chatgraph-go/core/ports/adapters/output"
+	bolt "go.etcd.io/bbolt"
+)
+
+var _ adapter_output.ICampaignRepository = (*BoltStore)(nil)
+
+const (
+	// DEFAULT_BUCKET is the bucket holding the campaigns and their recipients.
+	DEFAULT_BUCKET = "campaigns"
+	// DEFAULT_TIMEOUT is how long to wait for the database file lock.
+	DEFAULT_TIMEOUT = 5 * time.Second
+	// DEFAULT_FILE_MODE is the permission of the database file.
+	DEFAULT_FILE_MODE os.FileMode = 0o600
+)
+
+// Nested buckets of the root bucket.
+var (
+	campaignsBucket  = []byte("campaigns")
+	recipientsBucket = []byte("recipients")
+)
+
+// ErrMissingPath is returned when no database path is given.
+var ErrMissingPath = errors.New("boltstore: path is required")
+
+// BoltStoreOptions configures the bbolt campaign repository.
+type BoltStoreOptions struct {
+	// Bucket is the bucket holding the campaigns. Defaults to DEFAULT_BUCKET.
+	Bucket string
+	// Timeout is how long to wait for the file lock held by another process.
+	// Defaults to DEFAULT_TIMEOUT.
+	Timeout time.Duration
+}
+
+// BoltStore stores campaigns as JSON values keyed by their ID, and the
+// recipients of each campaign in a bucket of its own, keyed by their Index,
+// so updating a recipient does not rewrite the whole list.
+type BoltStore struct {
+	db     *bolt.DB
+	bucket []byte
+}
+
+// NewBoltStore opens (or creates) the database at path.
+// Only one process can open the database at a time.
+func NewBoltStore(path string, options ...BoltStoreOptions) (*BoltStore, error) {
+	if path == "" {
+		return nil, ErrMissingPath
+	}
+
+	opts := BoltStoreOptions{}
+	if len(options) > 0 {
+		opts = options[0]
+	}
+	if opts.Bucket == "" {
+		opts.Bucket = DEFAULT_BUCKET
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = DEFAULT_TIMEOUT
+	}
+
+	db, err := bolt.Open(path, DEFAULT_FILE_MODE, &bolt.Options{Timeout: opts.Timeout})
+	if err != nil {
+		return nil, fmt.Errorf("boltstore: %w", err)
+	}
+
+	store := &BoltStore{db: db, bucket: []byte(opts.Bucket)}
+	err = db.Update(func(tx *bolt.Tx) error {
+		root, err := tx.CreateBucketIfNotExists(store.bucket)
+		if err != nil {
+			return err
+		}
+		if _, err := root.CreateBucketIfNotExists(campaignsBucket); err != nil {
+			return err
+		}
+		_, err = root.CreateBucketIfNotExists(recipientsBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("boltstore: %w", err)
+	}
+
+	return store, nil
+}
+
+// SaveCampaign stores a campaign, replacing the stored campaign with the same ID.
+func (s *BoltStore) SaveCampaign(campaign d_campaign.Campaign) error {
+	data, err := json.Marshal(dto_campaign.FromDomain(campaign))
+	if err != nil {
+		return fmt.Errorf("boltstore: %w", err)
+	}
+
+	err = s.db.Update(func(tx *bolt.Tx) error {
+		return s.campaigns(tx).Put([]byte(campaign.ID), data)
+	})
+	if err != nil {
+		return fmt.Errorf("boltstore: %w", err)
+	}
+	return nil
+}
+
+// Campaign returns a stored campaign.
+func (s *BoltStore) Campaign(id string) (d_campaign.Campaign, bool, error) {
+	var (
+		campaign d_campaign.Campaign
+		ok       bool
+	)
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		data := s.campaigns(tx).Get([]byte(id))
+		if data == nil {
+			return nil
+		}
+
+		var err error
+		campaign, err = decodeCampaign(data)
+		ok = err == nil
+		return err
+	})
+	if err != nil {
+		return d_campaign.Campaign{}, false, fmt.Errorf("boltstore: %w", err)
+	}
+	return campaign, ok, nil
+}
+
+// Campaigns returns every stored campaign, oldest first.
+func (s *BoltStore) Campaigns() ([]d_campaign.Campaign, error) {
+	var campaigns []d_campaign.Campaign
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return s.campaigns(tx).ForEach(func(_, data []byte) error {
+			campaign, err := decodeCampaign(data)
+			if err != nil {
+				return err
+			}
+			campaigns = append(campaigns, campaign)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("boltstore: %w", err)
+	}
+
+	sort.Slice(campaigns, func(i, j int) bool {
+		return campaigns[i].CreatedAt.Before(campaigns[j].CreatedAt)
+	})
+	return campaigns, nil
+}
+
+// SaveRecipients stores recipients of a campaign, replacing the stored
+// recipients with the same Index, in a single transaction.
+func (s *BoltStore) SaveRecipients(campaignID string, recipients []d_campaign.Recipient) error {
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		bucket, err := s.recipients(tx).CreateBucketIfNotExists([]byte(campaignID))
+		if err != nil {
+			return err
+		}
+
+		for _, recipient := range recipients {
+			data, err := json.Marshal(dto_campaign.RecipientFromDomain(recipient))
+			if err != nil {
+				return err
+			}
+			if err := bucket.Put(indexKey(recipient.Index), data); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("boltstore: %w", err)
+	}
+	return nil
+}
+
+// Recipients returns the stored recipients of a campaign, by Index.
+func (s *BoltStore) Recipients(campaignID string) ([]d_campaign.Recipient, error) {
+	var recipients []d_campaign.Recipient
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		bucket := s.recipients(tx).Bucket([]byte(campaignID))
+		if bucket == nil {
+			return nil
+		}
+
+		// Keys are big-endian indexes, so they are iterated in order.
+		return bucket.ForEach(func(_, data []byte) error {
+			var dto dto_campaign.Recipient
+			if err := json.Unmarshal(data, &dto); err != nil {
+				return fmt.Errorf("invalid recipient: %w", err)
+			}
+			recipients = append(recipients, dto.ToDomain())
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("boltstore: %w", err)
+	}
+	return recipients, nil
+}
+
+// DeleteCampaign removes a campaign and its recipients.
+func (s *BoltStore) DeleteCampaign(id string) error {
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		if err := s.campaigns(tx).Delete([]byte(id)); err != nil {
+			return err
+		}
+		err := s.recipients(tx).DeleteBucket([]byte(id))
+		if errors.Is(err, bolt.ErrBucketNotFound) {
+			return nil
+		}
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("boltstore: %w", err)
+	}
+	return nil
+}
+
+// Close closes the database.
+func (s *BoltStore) Close() error {
+	return s.db.Close()
+}
+
+// campaigns returns the bucket holding the campaigns.
+func (s *BoltStore) campaigns(tx *bolt.Tx) *bolt.Bucket {
+	return tx.Bucket(s.bucket).Bucket(campaignsBucket)
+}
+
+// recipients returns the bucket holding a bucket of recipients per campaign.
+func (s *BoltStore) recipients(tx *bolt.Tx) *bolt.Bucket {
+	return tx.Bucket(s.bucket).Bucket(recipientsBucket)
+}
+
+// indexKey returns the key of the recipient at index.
+func indexKey(index int) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, uint64(index))
+	return key
+}
+
+// decodeCampaign reads a stored campaign.
+func decodeCampaign(data []byte) (d_campaign.Campaign, error) {
+	var dto dto_campaign.Campaign
+	if err := json.Unmarshal(data, &dto); err != nil {
+		return d_campaign.Campaign{}, fmt.Errorf("invalid campaign: %w", err)
+	}
+	return dto.ToDomain(), nil
+}
@@ -0,0 +1,113 @@
+package boltstore
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	d_campaign "github.com/irissonnlima/chatgraph-go/core/domain/campaign"
+	d_message "github.com/irissonnlima/chatgraph-go/core/domain/message"
+	d_user "github.com/irissonnlima/chatgraph-go/core/domain/user"
+)
+
+func TestNewBoltStore_MissingPath(t *testing.T) {
+	if _, err := NewBoltStore(""); !errors.Is(err, ErrMissingPath) {
+		t.Errorf("expected ErrMissingPath, got %v", err)
+	}
+}
+
+func TestBoltStore_RoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "campaigns.db")
+
+	store, err := NewBoltStore(path)
+	if err != nil {
+		t.Fatalf("NewBoltStore() error = %v", err)
+	}
+
+	created := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	first := d_campaign.Campaign{
+		ID: "c1", Name: "Black Friday", ReplyRoute: "promo",
+		Message:     d_message.Message{TextMessage: d_message.TextMessage{Detail: "Hi {{name}}"}},
+		MaxAttempts: 3, Status: d_campaign.RUNNING, CreatedAt: created,
+	}
+	second := d_campaign.Campaign{ID: "c2", EntryRoute: "survey", Status: d_campaign.FINISHED, CreatedAt: created.Add(time.Hour)}
+	for _, campaign := range []d_campaign.Campaign{second, first} {
+		if err := store.SaveCampaign(campaign); err != nil {
+			t.Fatalf("SaveCampaign() error = %v", err)
+		}
+	}
+
+	// 300 recipients check that indexes are ordered numerically.
+	recipients := make([]d_campaign.Recipient, 300)
+	for i := range recipients {
+		recipients[i] = d_campaign.Recipient{
+			Index:  i,
+			ChatID: d_user.ChatID{UserID: "u", CompanyID: "c"},
+			Status: d_campaign.PENDING,
+		}
+	}
+	recipients[0].Variables = map[string]string{"name": "Ana"}
+	if err := store.SaveRecipients("c1", recipients); err != nil {
+		t.Fatalf("SaveRecipients() error = %v", err)
+	}
+	sent := d_campaign.Recipient{Index: 256, ChatID: recipients[256].ChatID, Status: d_campaign.SENT, Attempts: 1}
+	if err := store.SaveRecipients("c1", []d_campaign.Recipient{sent}); err != nil {
+		t.Fatalf("SaveRecipients() error = %v", err)
+	}
+	if err := store.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	// Reopening the database keeps the campaigns.
+	store, err = NewBoltStore(path)
+	if err != nil {
+		t.Fatalf("NewBoltStore() error = %v", err)
+	}
+	defer store.Close()
+
+	campaigns, err := store.Campaigns()
+	if err != nil {
+		t.Fatalf("Campaigns() error = %v", err)
+	}
+	if len(campaigns) != 2 || campaigns[0].ID != "c1" || campaigns[1].ID != "c2" {
+		t.Fatalf("expected c1 then c2, got %+v", campaigns)
+	}
+	got, ok, err := store.Campaign("c1")
+	if err != nil || !ok {
+		t.Fatalf("Campaign() = %v, %v", ok, err)
+	}
+	if got.Name != "Black Friday" || got.ReplyRoute != "promo" || got.Message.TextMessage.Detail != "Hi {{name}}" ||
+		got.Status != d_campaign.RUNNING || got.MaxAttempts != 3 {
+		t.Errorf("unexpected campaign %+v", got)
+	}
+
+	stored, err := store.Recipients("c1")
+	if err != nil {
+		t.Fatalf("Recipients() error = %v", err)
+	}
+	if len(stored) != 300 {
+		t.Fatalf("expected 300 recipients, got %d", len(stored))
+	}
+	for i, recipient := range stored {
+		if recipient.Index != i {
+			t.Fatalf("expected recipient %d at position %d", recipient.Index, i)
+		}
+	}
+	if stored[0].Variables["name"] != "Ana" || stored[256].Status != d_campaign.SENT || stored[256].Attempts != 1 {
+		t.Errorf("unexpected recipients %+v, %+v", stored[0], stored[256])
+	}
+
+	if err := store.DeleteCampaign("c1"); err != nil {
+		t.Fatalf("DeleteCampaign() error = %v", err)
+	}
+	if _, ok, _ := store.Campaign("c1"); ok {
+		t.Error("expected c1 to be deleted")
+	}
+	if stored, _ := store.Recipients("c1"); len(stored) != 0 {
+		t.Errorf("expected the recipients to be deleted, got %d", len(stored))
+	}
+	if err := store.DeleteCampaign("missing"); err != nil {
+		t.Errorf("deleting a missing campaign should not fail, got %v", err)
+	}
+}
@@ -0,0 +1,150 @@
+// Package campaigns provides the repositories of broadcast campaigns (see
+// d_campaign) and an opt-out list. MemoryRepository keeps campaigns in
+// memory; boltstore keeps them in an embedded database, so sending resumes
+// after a restart.
+package campaigns
+
+import (
+	"sort"
+	"sync"
+
+	d_campaign "github.com/irissonnlima/chatgraph-go/core/domain/campaign"
+	d_user "github.com/irissonnlima/chatgraph-go/core/domain/user"
+	adapter_output "github.com/irissonnlima/chatgraph-go/core/ports/adapters/output"
+)
+
+var (
+	_ adapter_output.ICampaignRepository = (*MemoryRepository)(nil)
+	_ adapter_output.IOptOutList         = (*MemoryOptOutList)(nil)
+)
+
+// MemoryRepository is an ICampaignRepository kept in memory. Campaigns are
+// lost when the process stops; use boltstore for durable campaigns.
+type MemoryRepository struct {
+	mu         sync.Mutex
+	campaigns  map[string]d_campaign.Campaign
+	recipients map[string]map[int]d_campaign.Recipient
+}
+
+// NewMemoryRepository creates an empty in-memory campaign repository.
+func NewMemoryRepository() *MemoryRepository {
+	return &MemoryRepository{
+		campaigns:  make(map[string]d_campaign.Campaign),
+		recipients: make(map[string]map[int]d_campaign.Recipient),
+	}
+}
+
+// SaveCampaign stores a campaign, replacing the stored campaign with the same ID.
+func (r *MemoryRepository) SaveCampaign(campaign d_campaign.Campaign) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.campaigns[campaign.ID] = campaign
+	return nil
+}
+
+// Campaign returns a stored campaign.
+func (r *MemoryRepository) Campaign(id string) (d_campaign.Campaign, bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	campaign, ok := r.campaigns[id]
+	return campaign, ok, nil
+}
+
+// Campaigns returns every stored campaign, oldest first.
+func (r *MemoryRepository) Campaigns() ([]d_campaign.Campaign, error) {
+	r.mu.Lock()
+	campaigns := make([]d_campaign.Campaign, 0, len(r.campaigns))
+	for _, campaign := range r.campaigns {
+		campaigns = append(campaigns, campaign)
+	}
+	r.mu.Unlock()
+
+	sort.Slice(campaigns, func(i, j int) bool {
+		return campaigns[i].CreatedAt.Before(campaigns[j].CreatedAt)
+	})
+	return campaigns, nil
+}
+
+// SaveRecipients stores recipients of a campaign, replacing the stored
+// recipients with the same Index.
+func (r *MemoryRepository) SaveRecipients(campaignID string, recipients []d_campaign.Recipient) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.recipients[campaignID]
+	if !ok {
+		stored = make(map[int]d_campaign.Recipient, len(recipients))
+		r.recipients[campaignID] = stored
+	}
+	for _, recipient := range recipients {
+		stored[recipient.Index] = recipient
+	}
+	return nil
+}
+
+// Recipients returns the stored recipients of a campaign, by Index.
+func (r *MemoryRepository) Recipients(campaignID string) ([]d_campaign.Recipient, error) {
+	r.mu.Lock()
+	stored := r.recipients[campaignID]
+	recipients := make([]d_campaign.Recipient, 0, len(stored))
+	for _, recipient := range stored {
+		recipients = append(recipients, recipient)
+	}
+	r.mu.Unlock()
+
+	sort.Slice(recipients, func(i, j int) bool {
+		return recipients[i].Index < recipients[j].Index
+	})
+	return recipients, nil
+}
+
+// DeleteCampaign removes a campaign and its recipients.
+func (r *MemoryRepository) DeleteCampaign(id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.campaigns, id)
+	delete(r.recipients, id)
+	return nil
+}
+
+// Close does nothing.
+func (r *MemoryRepository) Close() error {
+	return nil
+}
+
+// MemoryOptOutList is an IOptOutList kept in memory, e.g. loaded from a
+// file at startup or updated by a route where users opt out.
+type MemoryOptOutList struct {
+	mu    sync.RWMutex
+	chats map[d_user.ChatID]bool
+}
+
+// NewMemoryOptOutList creates an opt-out list holding chatIDs.
+func NewMemoryOptOutList(chatIDs ...d_user.ChatID) *MemoryOptOutList {
+	list := &MemoryOptOutList{chats: make(map[d_user.ChatID]bool, len(chatIDs))}
+	for _, chatID := range chatIDs {
+		list.chats[chatID] = true
+	}
+	return list
+}
+
+// OptOut adds a chat to the list.
+func (l *MemoryOptOutList) OptOut(chatID d_user.ChatID) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.chats[chatID] = true
+}
+
+// OptIn removes a chat from the list.
+func (l *MemoryOptOutList) OptIn(chatID d_user.ChatID) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.chats, chatID)
+}
+
+// OptedOut reports whether the chat is in the list.
+func (l *MemoryOptOutList) OptedOut(chatID d_user.ChatID) (bool, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.chats[chatID], nil
+}
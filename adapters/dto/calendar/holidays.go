@@ -0,0 +1,108 @@
+// Package dto_calendar provides the file formats of holiday lists, so the
+// holidays of a calendar, e.g. municipal ones, can be kept out of the code.
+package dto_calendar
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	d_calendar "github.com/irissonnlima/chatgraph-go/core/domain/calendar"
+)
+
+// Columns of the CSV holiday lists.
+const (
+	// COLUMN_DATE holds the date of a holiday, as YYYY-MM-DD, or MM-DD when
+	// it recurs every year.
+	COLUMN_DATE = "date"
+	// COLUMN_NAME holds the name of a holiday.
+	COLUMN_NAME = "name"
+)
+
+// ErrMissingDateColumn is returned for CSV holiday lists without a date column.
+var ErrMissingDateColumn = errors.New("holiday list must have a date column")
+
+// Holiday is the JSON representation of a holiday.
+type Holiday struct {
+	Date string `json:"date"`
+	Name string `json:"name,omitempty"`
+}
+
+// ToDomain converts the DTO into a domain holiday.
+func (h Holiday) ToDomain() (d_calendar.Holiday, error) {
+	date, err := d_calendar.ParseDate(h.Date)
+	if err != nil {
+		return d_calendar.Holiday{}, err
+	}
+	return d_calendar.Holiday{Date: date, Name: h.Name}, nil
+}
+
+// ParseHolidaysCSV reads a CSV holiday list. The header must have a date
+// column and may have a name column; other columns are ignored.
+//
+//	date,name
+//	01-25,Aniversário de São Paulo
+//	2025-12-31,Véspera de Ano Novo
+func ParseHolidaysCSV(r io.Reader) ([]d_calendar.Holiday, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("reading holiday list header: %w", err)
+	}
+	dateColumn, nameColumn := -1, -1
+	for i, name := range header {
+		switch name {
+		case COLUMN_DATE:
+			dateColumn = i
+		case COLUMN_NAME:
+			nameColumn = i
+		}
+	}
+	if dateColumn < 0 {
+		return nil, ErrMissingDateColumn
+	}
+
+	var holidays []d_calendar.Holiday
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return holidays, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("reading holiday list: %w", err)
+		}
+
+		dto := Holiday{Date: record[dateColumn]}
+		if nameColumn >= 0 {
+			dto.Name = record[nameColumn]
+		}
+		holiday, err := dto.ToDomain()
+		if err != nil {
+			return nil, fmt.Errorf("holiday list line %d: %w", line, err)
+		}
+		holidays = append(holidays, holiday)
+	}
+}
+
+// ParseHolidaysJSON reads a JSON holiday list: an array of objects with a
+// date and an optional name.
+func ParseHolidaysJSON(r io.Reader) ([]d_calendar.Holiday, error) {
+	var dtos []Holiday
+	if err := json.NewDecoder(r).Decode(&dtos); err != nil {
+		return nil, fmt.Errorf("decoding holiday list: %w", err)
+	}
+
+	holidays := make([]d_calendar.Holiday, 0, len(dtos))
+	for i, dto := range dtos {
+		holiday, err := dto.ToDomain()
+		if err != nil {
+			return nil, fmt.Errorf("holiday %d: %w", i, err)
+		}
+		holidays = append(holidays, holiday)
+	}
+	return holidays, nil
+}
@@ -0,0 +1,50 @@
+package dto_calendar
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	d_calendar "github.com/irissonnlima/chatgraph-go/core/domain/calendar"
+)
+
+func TestParseHolidaysCSV(t *testing.T) {
+	holidays, err := ParseHolidaysCSV(strings.NewReader(
+		"name,date\nAniversário de São Paulo,01-25\nVéspera de Ano Novo,2025-12-31\n"))
+	if err != nil {
+		t.Fatalf("ParseHolidaysCSV() error = %v", err)
+	}
+
+	want := []d_calendar.Holiday{
+		{Date: d_calendar.Date{Month: time.January, Day: 25}, Name: "Aniversário de São Paulo"},
+		{Date: d_calendar.Date{Year: 2025, Month: time.December, Day: 31}, Name: "Véspera de Ano Novo"},
+	}
+	if len(holidays) != len(want) || holidays[0] != want[0] || holidays[1] != want[1] {
+		t.Errorf("ParseHolidaysCSV() = %+v, want %+v", holidays, want)
+	}
+}
+
+func TestParseHolidaysCSV_Errors(t *testing.T) {
+	if _, err := ParseHolidaysCSV(strings.NewReader("name\nNatal\n")); !errors.Is(err, ErrMissingDateColumn) {
+		t.Errorf("expected ErrMissingDateColumn, got %v", err)
+	}
+	if _, err := ParseHolidaysCSV(strings.NewReader("date\n25/12\n")); !errors.Is(err, d_calendar.ErrInvalidDate) {
+		t.Errorf("expected ErrInvalidDate, got %v", err)
+	}
+}
+
+func TestParseHolidaysJSON(t *testing.T) {
+	holidays, err := ParseHolidaysJSON(strings.NewReader(`[{"date": "11-20", "name": "Consciência Negra"}, {"date": "2025-03-05"}]`))
+	if err != nil {
+		t.Fatalf("ParseHolidaysJSON() error = %v", err)
+	}
+	if len(holidays) != 2 || holidays[0].Date != (d_calendar.Date{Month: time.November, Day: 20}) ||
+		holidays[1].Date != (d_calendar.Date{Year: 2025, Month: time.March, Day: 5}) {
+		t.Errorf("unexpected holidays %+v", holidays)
+	}
+
+	if _, err := ParseHolidaysJSON(strings.NewReader(`[{"date": "tomorrow"}]`)); !errors.Is(err, d_calendar.ErrInvalidDate) {
+		t.Errorf("expected ErrInvalidDate, got %v", err)
+	}
+}
@@ -0,0 +1,95 @@
+// Package dto_campaign provides the storage format of broadcast campaigns
+// shared by the campaign repositories, and the CSV and JSON formats of their
+// recipient lists and delivery reports.
+package dto_campaign
+
+import (
+	"time"
+
+	dto_message "github.com/irissonnlima/chatgraph-go/adapters/dto/message"
+	dto_user "github.com/irissonnlima/chatgraph-go/adapters/dto/user"
+	d_campaign "github.com/irissonnlima/chatgraph-go/core/domain/campaign"
+)
+
+// Campaign is the JSON representation of a campaign.
+type Campaign struct {
+	ID          string              `json:"id"`
+	Name        string              `json:"name,omitempty"`
+	Message     dto_message.Message `json:"message"`
+	EntryRoute  string              `json:"entry_route,omitempty"`
+	ReplyRoute  string              `json:"reply_route,omitempty"`
+	Platform    string              `json:"platform,omitempty"`
+	MaxAttempts int                 `json:"max_attempts"`
+	Status      string              `json:"status"`
+	CreatedAt   time.Time           `json:"created_at"`
+	UpdatedAt   time.Time           `json:"updated_at"`
+}
+
+// FromDomain converts a domain campaign into its DTO.
+func FromDomain(c d_campaign.Campaign) Campaign {
+	return Campaign{
+		ID:          c.ID,
+		Name:        c.Name,
+		Message:     dto_message.MessageFromDomain(c.Message),
+		EntryRoute:  c.EntryRoute,
+		ReplyRoute:  c.ReplyRoute,
+		Platform:    c.Platform,
+		MaxAttempts: c.MaxAttempts,
+		Status:      string(c.Status),
+		CreatedAt:   c.CreatedAt,
+		UpdatedAt:   c.UpdatedAt,
+	}
+}
+
+// ToDomain converts the DTO into a domain campaign.
+func (c Campaign) ToDomain() d_campaign.Campaign {
+	return d_campaign.Campaign{
+		ID:          c.ID,
+		Name:        c.Name,
+		Message:     c.Message.ToDomain(),
+		EntryRoute:  c.EntryRoute,
+		ReplyRoute:  c.ReplyRoute,
+		Platform:    c.Platform,
+		MaxAttempts: c.MaxAttempts,
+		Status:      d_campaign.Status(c.Status),
+		CreatedAt:   c.CreatedAt,
+		UpdatedAt:   c.UpdatedAt,
+	}
+}
+
+// Recipient is the JSON representation of a campaign recipient.
+type Recipient struct {
+	Index     int               `json:"index"`
+	ChatID    dto_user.ChatID   `json:"chat_id"`
+	Variables map[string]string `json:"variables,omitempty"`
+	Status    string            `json:"status,omitempty"`
+	Attempts  int               `json:"attempts,omitempty"`
+	Error     string            `json:"error,omitempty"`
+	UpdatedAt time.Time         `json:"updated_at,omitempty"`
+}
+
+// RecipientFromDomain converts a domain recipient into its DTO.
+func RecipientFromDomain(r d_campaign.Recipient) Recipient {
+	return Recipient{
+		Index:     r.Index,
+		ChatID:    dto_user.ChatIDFromDomain(r.ChatID),
+		Variables: r.Variables,
+		Status:    string(r.Status),
+		Attempts:  r.Attempts,
+		Error:     r.Error,
+		UpdatedAt: r.UpdatedAt,
+	}
+}
+
+// ToDomain converts the DTO into a domain recipient.
+func (r Recipient) ToDomain() d_campaign.Recipient {
+	return d_campaign.Recipient{
+		Index:     r.Index,
+		ChatID:    r.ChatID.ToDomain(),
+		Variables: r.Variables,
+		Status:    d_campaign.RecipientStatus(r.Status),
+		Attempts:  r.Attempts,
+		Error:     r.Error,
+		UpdatedAt: r.UpdatedAt,
+	}
+}
@@ -0,0 +1,125 @@
+package dto_campaign
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	d_campaign "github.com/irissonnlima/chatgraph-go/core/domain/campaign"
+	d_user "github.com/irissonnlima/chatgraph-go/core/domain/user"
+)
+
+// Columns of the recipient lists and delivery reports.
+const (
+	// COLUMN_USER_ID holds the user ID of a recipient's chat.
+	COLUMN_USER_ID = "user_id"
+	// COLUMN_COMPANY_ID holds the company ID of a recipient's chat.
+	COLUMN_COMPANY_ID = "company_id"
+)
+
+// ErrMissingColumns is returned for CSV recipient lists without the user_id
+// and company_id columns.
+var ErrMissingColumns = errors.New("recipient list must have user_id and company_id columns")
+
+// ParseRecipientsCSV reads a CSV recipient list. The header must have the
+// user_id and company_id columns; every other column becomes a variable of
+// the recipients, filling in the {{column}} placeholders of the message.
+func ParseRecipientsCSV(r io.Reader) ([]d_campaign.Recipient, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("reading recipient list header: %w", err)
+	}
+	userColumn, companyColumn := -1, -1
+	for i, name := range header {
+		switch name {
+		case COLUMN_USER_ID:
+			userColumn = i
+		case COLUMN_COMPANY_ID:
+			companyColumn = i
+		}
+	}
+	if userColumn < 0 || companyColumn < 0 {
+		return nil, ErrMissingColumns
+	}
+
+	var recipients []d_campaign.Recipient
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return recipients, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("reading recipient list: %w", err)
+		}
+
+		recipient := d_campaign.Recipient{ChatID: d_user.ChatID{
+			UserID:    record[userColumn],
+			CompanyID: record[companyColumn],
+		}}
+		for i, value := range record {
+			if i == userColumn || i == companyColumn {
+				continue
+			}
+			if recipient.Variables == nil {
+				recipient.Variables = make(map[string]string)
+			}
+			recipient.Variables[header[i]] = value
+		}
+		recipients = append(recipients, recipient)
+	}
+}
+
+// ParseRecipientsJSON reads a JSON recipient list: an array of objects with
+// a chat_id and optional variables.
+func ParseRecipientsJSON(r io.Reader) ([]d_campaign.Recipient, error) {
+	var list []Recipient
+	if err := json.NewDecoder(r).Decode(&list); err != nil {
+		return nil, fmt.Errorf("invalid recipient list: %w", err)
+	}
+
+	recipients := make([]d_campaign.Recipient, len(list))
+	for i, recipient := range list {
+		recipients[i] = d_campaign.Recipient{
+			ChatID:    recipient.ChatID.ToDomain(),
+			Variables: recipient.Variables,
+		}
+	}
+	return recipients, nil
+}
+
+// WriteReportCSV writes the delivery report of a campaign as CSV, one row
+// per recipient, in list order.
+func WriteReportCSV(w io.Writer, report d_campaign.Report) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write([]string{COLUMN_USER_ID, COLUMN_COMPANY_ID, "status", "attempts", "error", "updated_at"}); err != nil {
+		return err
+	}
+
+	for _, recipient := range report.Recipients {
+		updatedAt := ""
+		if !recipient.UpdatedAt.IsZero() {
+			updatedAt = recipient.UpdatedAt.Format(time.RFC3339)
+		}
+		err := writer.Write([]string{
+			recipient.ChatID.UserID,
+			recipient.ChatID.CompanyID,
+			string(recipient.Status),
+			strconv.Itoa(recipient.Attempts),
+			recipient.Error,
+			updatedAt,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
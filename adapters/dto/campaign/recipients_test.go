@@ -0,0 +1,71 @@
+package dto_campaign
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	d_campaign "github.com/irissonnlima/chatgraph-go/core/domain/campaign"
+	d_user "github.com/irissonnlima/chatgraph-go/core/domain/user"
+)
+
+func TestParseRecipientsCSV(t *testing.T) {
+	list := "name,user_id,company_id\nAna,u1,c1\nBia,u2,c1\n"
+
+	recipients, err := ParseRecipientsCSV(strings.NewReader(list))
+	if err != nil {
+		t.Fatalf("ParseRecipientsCSV() error = %v", err)
+	}
+	if len(recipients) != 2 {
+		t.Fatalf("expected 2 recipients, got %+v", recipients)
+	}
+	if recipients[1].ChatID != (d_user.ChatID{UserID: "u2", CompanyID: "c1"}) || recipients[1].Variables["name"] != "Bia" {
+		t.Errorf("unexpected recipient %+v", recipients[1])
+	}
+	if len(recipients[0].Variables) != 1 {
+		t.Errorf("expected only the name variable, got %v", recipients[0].Variables)
+	}
+}
+
+func TestParseRecipientsCSV_MissingColumns(t *testing.T) {
+	if _, err := ParseRecipientsCSV(strings.NewReader("user_id,name\nu1,Ana\n")); !errors.Is(err, ErrMissingColumns) {
+		t.Errorf("expected ErrMissingColumns, got %v", err)
+	}
+}
+
+func TestParseRecipientsJSON(t *testing.T) {
+	list := `[{"chat_id": {"user_id": "u1", "company_id": "c1"}, "variables": {"name": "Ana"}}, {"chat_id": {"user_id": "u2", "company_id": "c1"}}]`
+
+	recipients, err := ParseRecipientsJSON(strings.NewReader(list))
+	if err != nil {
+		t.Fatalf("ParseRecipientsJSON() error = %v", err)
+	}
+	if len(recipients) != 2 || recipients[0].ChatID.UserID != "u1" || recipients[0].Variables["name"] != "Ana" {
+		t.Errorf("unexpected recipients %+v", recipients)
+	}
+
+	if _, err := ParseRecipientsJSON(strings.NewReader(`{"chat_id": 1}`)); err == nil {
+		t.Error("expected an error for an invalid list")
+	}
+}
+
+func TestWriteReportCSV(t *testing.T) {
+	updated := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	report := d_campaign.NewReport(d_campaign.Campaign{ID: "c"}, []d_campaign.Recipient{
+		{ChatID: d_user.ChatID{UserID: "u1", CompanyID: "c1"}, Status: d_campaign.SENT, Attempts: 1, UpdatedAt: updated},
+		{ChatID: d_user.ChatID{UserID: "u2", CompanyID: "c1"}, Status: d_campaign.SKIPPED, Error: d_campaign.REASON_OPTED_OUT},
+	})
+
+	var out strings.Builder
+	if err := WriteReportCSV(&out, report); err != nil {
+		t.Fatalf("WriteReportCSV() error = %v", err)
+	}
+
+	want := "user_id,company_id,status,attempts,error,updated_at\n" +
+		"u1,c1,sent,1,,2024-01-01T12:00:00Z\n" +
+		"u2,c1,skipped,0,opted out,\n"
+	if out.String() != want {
+		t.Errorf("unexpected report:\n%s", out.String())
+	}
+}
@@ -0,0 +1,43 @@
+// Package dto_event provides the JSON format of the backend events received
+// by the HTTP event endpoint.
+package dto_event
+
+import (
+	"encoding/json"
+	"fmt"
+
+	dto_user "github.com/irissonnlima/chatgraph-go/adapters/dto/user"
+	d_event "github.com/irissonnlima/chatgraph-go/core/domain/event"
+)
+
+// Event is the JSON representation of a backend event. Its payload is any
+// JSON value.
+type Event struct {
+	ID       string          `json:"id,omitempty"`
+	Name     string          `json:"name,omitempty"`
+	ChatID   dto_user.ChatID `json:"chat_id"`
+	Route    string          `json:"route"`
+	Platform string          `json:"platform,omitempty"`
+	Payload  json.RawMessage `json:"payload,omitempty"`
+}
+
+// ToDomain converts the DTO into a domain event.
+func (e Event) ToDomain() d_event.Event {
+	return d_event.Event{
+		ID:       e.ID,
+		Name:     e.Name,
+		ChatID:   e.ChatID.ToDomain(),
+		Route:    e.Route,
+		Platform: e.Platform,
+		Payload:  string(e.Payload),
+	}
+}
+
+// ParseEvent decodes a raw Event payload into a domain event.
+func ParseEvent(body []byte) (d_event.Event, error) {
+	var event Event
+	if err := json.Unmarshal(body, &event); err != nil {
+		return d_event.Event{}, fmt.Errorf("invalid event payload: %w", err)
+	}
+	return event.ToDomain(), nil
+}
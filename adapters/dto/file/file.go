@@ -29,3 +29,13 @@ func (f File) ToDomain() d_file.File {
 		Name: f.Name,
 	}
 }
+
+// FileFromDomain converts a domain File into its DTO.
+func FileFromDomain(f d_file.File) File {
+	return File{
+		ID:   f.ID,
+		Type: f.Type.String(),
+		URL:  f.URL,
+		Name: f.Name,
+	}
+}
@@ -0,0 +1,78 @@
+// Package dto_handoff provides the storage format of handoff tickets shared
+// by the handoff repositories.
+package dto_handoff
+
+import (
+	"time"
+
+	dto_session "github.com/irissonnlima/chatgraph-go/adapters/dto/session"
+	d_department "github.com/irissonnlima/chatgraph-go/core/domain/department"
+	d_handoff "github.com/irissonnlima/chatgraph-go/core/domain/handoff"
+)
+
+// Department is the JSON representation of a department.
+type Department struct {
+	ID          string `json:"id"`
+	ParentID    string `json:"parent_id,omitempty"`
+	Name        string `json:"name,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// Ticket is the JSON representation of a handoff ticket.
+type Ticket struct {
+	ID          string              `json:"id"`
+	Session     dto_session.Session `json:"session"`
+	Department  Department          `json:"department"`
+	Agent       string              `json:"agent,omitempty"`
+	Status      string              `json:"status"`
+	Reason      string              `json:"reason,omitempty"`
+	ResumeRoute string              `json:"resume_route"`
+	LastMessage string              `json:"last_message,omitempty"`
+	CreatedAt   time.Time           `json:"created_at"`
+	AssignedAt  time.Time           `json:"assigned_at"`
+	ClosedAt    time.Time           `json:"closed_at"`
+}
+
+// FromDomain converts a domain ticket into its DTO.
+func FromDomain(t d_handoff.Ticket) Ticket {
+	return Ticket{
+		ID:      t.ID,
+		Session: dto_session.FromDomain(t.Session),
+		Department: Department{
+			ID:          t.Department.ID,
+			ParentID:    t.Department.ParentID,
+			Name:        t.Department.Name,
+			Description: t.Department.Description,
+		},
+		Agent:       t.Agent,
+		Status:      string(t.Status),
+		Reason:      t.Reason,
+		ResumeRoute: t.ResumeRoute,
+		LastMessage: t.LastMessage,
+		CreatedAt:   t.CreatedAt,
+		AssignedAt:  t.AssignedAt,
+		ClosedAt:    t.ClosedAt,
+	}
+}
+
+// ToDomain converts the DTO into a domain ticket.
+func (t Ticket) ToDomain() d_handoff.Ticket {
+	return d_handoff.Ticket{
+		ID:      t.ID,
+		Session: t.Session.ToDomain(),
+		Department: d_department.Department{
+			ID:          t.Department.ID,
+			ParentID:    t.Department.ParentID,
+			Name:        t.Department.Name,
+			Description: t.Department.Description,
+		},
+		Agent:       t.Agent,
+		Status:      d_handoff.Status(t.Status),
+		Reason:      t.Reason,
+		ResumeRoute: t.ResumeRoute,
+		LastMessage: t.LastMessage,
+		CreatedAt:   t.CreatedAt,
+		AssignedAt:  t.AssignedAt,
+		ClosedAt:    t.ClosedAt,
+	}
+}
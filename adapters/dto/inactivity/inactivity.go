@@ -0,0 +1,47 @@
+// Package dto_inactivity provides the storage format of inactivity timers
+// shared by the inactivity repositories.
+package dto_inactivity
+
+import (
+	"time"
+
+	dto_session "github.com/irissonnlima/chatgraph-go/adapters/dto/session"
+	d_inactivity "github.com/irissonnlima/chatgraph-go/core/domain/inactivity"
+)
+
+// Timer is the JSON representation of an inactivity timer.
+type Timer struct {
+	ID        string              `json:"id"`
+	Session   dto_session.Session `json:"session"`
+	Route     string              `json:"route"`
+	RemindAt  time.Time           `json:"remind_at"`
+	Reminded  bool                `json:"reminded,omitempty"`
+	Deadline  time.Time           `json:"deadline"`
+	CreatedAt time.Time           `json:"created_at"`
+}
+
+// FromDomain converts a domain timer into its DTO.
+func FromDomain(t d_inactivity.Timer) Timer {
+	return Timer{
+		ID:        t.ID,
+		Session:   dto_session.FromDomain(t.Session),
+		Route:     t.Route,
+		RemindAt:  t.RemindAt,
+		Reminded:  t.Reminded,
+		Deadline:  t.Deadline,
+		CreatedAt: t.CreatedAt,
+	}
+}
+
+// ToDomain converts the DTO into a domain timer.
+func (t Timer) ToDomain() d_inactivity.Timer {
+	return d_inactivity.Timer{
+		ID:        t.ID,
+		Session:   t.Session.ToDomain(),
+		Route:     t.Route,
+		RemindAt:  t.RemindAt,
+		Reminded:  t.Reminded,
+		Deadline:  t.Deadline,
+		CreatedAt: t.CreatedAt,
+	}
+}
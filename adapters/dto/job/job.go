@@ -0,0 +1,77 @@
+// Package dto_job provides the storage format of background jobs shared by
+// the job repositories.
+package dto_job
+
+import (
+	"time"
+
+	dto_session "github.com/irissonnlima/chatgraph-go/adapters/dto/session"
+	d_job "github.com/irissonnlima/chatgraph-go/core/domain/job"
+)
+
+// Job is the JSON representation of a background job.
+type Job struct {
+	ID              string              `json:"id"`
+	Task            string              `json:"task"`
+	Payload         string              `json:"payload,omitempty"`
+	Session         dto_session.Session `json:"session"`
+	CompletionRoute string              `json:"completion_route"`
+	FailureRoute    string              `json:"failure_route,omitempty"`
+	WaitingRoute    string              `json:"waiting_route,omitempty"`
+	Status          string              `json:"status"`
+	Result          string              `json:"result,omitempty"`
+	Error           string              `json:"error,omitempty"`
+	Attempts        int                 `json:"attempts"`
+	MaxAttempts     int                 `json:"max_attempts"`
+	Resumed         bool                `json:"resumed,omitempty"`
+	Stale           bool                `json:"stale,omitempty"`
+	CreatedAt       time.Time           `json:"created_at"`
+	UpdatedAt       time.Time           `json:"updated_at"`
+	NextAttempt     time.Time           `json:"next_attempt"`
+}
+
+// FromDomain converts a domain job into its DTO.
+func FromDomain(j d_job.Job) Job {
+	return Job{
+		ID:              j.ID,
+		Task:            j.Task,
+		Payload:         j.Payload,
+		Session:         dto_session.FromDomain(j.Session),
+		CompletionRoute: j.CompletionRoute,
+		FailureRoute:    j.FailureRoute,
+		WaitingRoute:    j.WaitingRoute,
+		Status:          string(j.Status),
+		Result:          j.Result,
+		Error:           j.Error,
+		Attempts:        j.Attempts,
+		MaxAttempts:     j.MaxAttempts,
+		Resumed:         j.Resumed,
+		Stale:           j.Stale,
+		CreatedAt:       j.CreatedAt,
+		UpdatedAt:       j.UpdatedAt,
+		NextAttempt:     j.NextAttempt,
+	}
+}
+
+// ToDomain converts the DTO into a domain job.
+func (j Job) ToDomain() d_job.Job {
+	return d_job.Job{
+		ID:              j.ID,
+		Task:            j.Task,
+		Payload:         j.Payload,
+		Session:         j.Session.ToDomain(),
+		CompletionRoute: j.CompletionRoute,
+		FailureRoute:    j.FailureRoute,
+		WaitingRoute:    j.WaitingRoute,
+		Status:          d_job.Status(j.Status),
+		Result:          j.Result,
+		Error:           j.Error,
+		Attempts:        j.Attempts,
+		MaxAttempts:     j.MaxAttempts,
+		Resumed:         j.Resumed,
+		Stale:           j.Stale,
+		CreatedAt:       j.CreatedAt,
+		UpdatedAt:       j.UpdatedAt,
+		NextAttempt:     j.NextAttempt,
+	}
+}
@@ -31,6 +31,17 @@ func (tm TextMessage) ToDomain() d_message.TextMessage {
 	}
 }
 
+// TextMessageFromDomain converts a domain TextMessage into its DTO.
+func TextMessageFromDomain(tm d_message.TextMessage) TextMessage {
+	return TextMessage{
+		ID:           tm.ID,
+		Title:        tm.Title,
+		Detail:       tm.Detail,
+		Caption:      tm.Caption,
+		MentionedIds: tm.MentionedIds,
+	}
+}
+
 // Button represents an interactive button in a message.
 type Button struct {
 	// Type indicates whether this is a POSTBACK or URL button.
@@ -54,6 +65,15 @@ func (b Button) ToDomain() d_message.Button {
 	}
 }
 
+// ButtonFromDomain converts a domain Button into its DTO.
+func ButtonFromDomain(b d_message.Button) Button {
+	return Button{
+		Type:   b.Type.String(),
+		Title:  b.Title,
+		Detail: b.Detail,
+	}
+}
+
 // Message represents a complete chat message with optional buttons and file attachments.
 type Message struct {
 	// TextMessage contains the text content of the message.
@@ -74,11 +94,48 @@ func (m Message) ToDomain() d_message.Message {
 		buttons[i] = btn.ToDomain()
 	}
 
-	return d_message.Message{
-		TextMessage:   m.TextMessage.ToDomain(),
-		Buttons:       buttons,
-		DisplayButton: m.DisplayButton.ToDomain(),
-		DateTime:      m.DateTime,
-		File:          m.File.ToDomain(),
+	message := d_message.Message{
+		Buttons:  buttons,
+		DateTime: m.DateTime,
+	}
+
+	// Optional fields may be omitted from the payload.
+	if m.TextMessage != nil {
+		message.TextMessage = m.TextMessage.ToDomain()
+	}
+	if m.DisplayButton != nil {
+		message.DisplayButton = m.DisplayButton.ToDomain()
+	}
+	if m.File != nil {
+		message.File = m.File.ToDomain()
+	}
+
+	return message
+}
+
+// MessageFromDomain converts a domain Message into its DTO.
+// An empty display button and a missing file are left out.
+func MessageFromDomain(m d_message.Message) Message {
+	buttons := make([]Button, len(m.Buttons))
+	for i, btn := range m.Buttons {
+		buttons[i] = ButtonFromDomain(btn)
+	}
+
+	textMessage := TextMessageFromDomain(m.TextMessage)
+	message := Message{
+		TextMessage: &textMessage,
+		Buttons:     buttons,
+		DateTime:    m.DateTime,
 	}
+
+	if !m.DisplayButton.IsEmpty() {
+		displayButton := ButtonFromDomain(m.DisplayButton)
+		message.DisplayButton = &displayButton
+	}
+	if m.HasFile() {
+		file := dto_file.FileFromDomain(m.File)
+		message.File = &file
+	}
+
+	return message
 }
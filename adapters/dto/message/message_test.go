@@ -140,6 +140,10 @@ func TestMessage_ToDomain(t *testing.T) {
 				File:          &dto_file.File{},
 			},
 		},
+		{
+			name:    "message with omitted optional fields",
+			message: Message{DateTime: "2024-01-01T00:00:00Z"},
+		},
 	}
 
 	for _, tt := range tests {
@@ -158,3 +162,39 @@ func TestMessage_ToDomain(t *testing.T) {
 		})
 	}
 }
+
+func TestMessageFromDomain_RoundTrip(t *testing.T) {
+	message := Message{
+		TextMessage: &TextMessage{ID: "1", Title: "Title", Detail: "Detail", MentionedIds: []string{"u1"}},
+		Buttons: []Button{
+			{Type: "postback", Title: "Btn1", Detail: "btn1"},
+			{Type: "url", Title: "Btn2", Detail: "https://x.com"},
+		},
+		DisplayButton: &Button{Type: "postback", Title: "Main", Detail: "main"},
+		DateTime:      "2024-01-01T00:00:00Z",
+		File:          &dto_file.File{ID: "f1", Type: "IMAGE", URL: "https://x.com/img.png", Name: "img.png"},
+	}
+
+	got := MessageFromDomain(message.ToDomain())
+
+	if got.TextMessage.ID != "1" || got.TextMessage.Title != "Title" || len(got.TextMessage.MentionedIds) != 1 {
+		t.Errorf("unexpected text message %+v", got.TextMessage)
+	}
+	if len(got.Buttons) != 2 || got.Buttons[0] != message.Buttons[0] || got.Buttons[1] != message.Buttons[1] {
+		t.Errorf("unexpected buttons %+v", got.Buttons)
+	}
+	if got.DisplayButton == nil || *got.DisplayButton != *message.DisplayButton {
+		t.Errorf("unexpected display button %+v", got.DisplayButton)
+	}
+	if got.File == nil || *got.File != *message.File {
+		t.Errorf("unexpected file %+v", got.File)
+	}
+	if got.DateTime != message.DateTime {
+		t.Errorf("unexpected date time %q", got.DateTime)
+	}
+
+	empty := MessageFromDomain(d_message.Message{})
+	if empty.DisplayButton != nil || empty.File != nil {
+		t.Errorf("expected empty optional fields to be left out, got %+v", empty)
+	}
+}
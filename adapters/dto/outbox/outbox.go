@@ -0,0 +1,85 @@
+// Package dto_outbox provides the storage format of outbox actions shared by
+// the outbox repositories.
+package dto_outbox
+
+import (
+	"time"
+
+	dto_action "github.com/irissonnlima/chatgraph-go/adapters/dto/action"
+	dto_message "github.com/irissonnlima/chatgraph-go/adapters/dto/message"
+	dto_user "github.com/irissonnlima/chatgraph-go/adapters/dto/user"
+	d_action "github.com/irissonnlima/chatgraph-go/core/domain/action"
+	d_outbox "github.com/irissonnlima/chatgraph-go/core/domain/outbox"
+)
+
+// Action is the JSON representation of an outbox action.
+type Action struct {
+	ID             uint64                     `json:"id"`
+	Type           string                     `json:"type"`
+	ChatID         dto_user.ChatID            `json:"chat_id"`
+	Platform       string                     `json:"platform,omitempty"`
+	Message        *dto_message.Message       `json:"message,omitempty"`
+	Route          string                     `json:"route,omitempty"`
+	Observation    string                     `json:"observation,omitempty"`
+	EndActionID    string                     `json:"end_action_id,omitempty"`
+	Transfer       *dto_action.TransferToMenu `json:"transfer,omitempty"`
+	IdempotencyKey string                     `json:"idempotency_key,omitempty"`
+	CreatedAt      time.Time                  `json:"created_at"`
+	Attempts       int                        `json:"attempts"`
+	LastError      string                     `json:"last_error,omitempty"`
+	NextAttempt    time.Time                  `json:"next_attempt"`
+}
+
+// FromDomain converts a domain action into its DTO.
+func FromDomain(a d_outbox.Action) Action {
+	dto := Action{
+		ID:             a.ID,
+		Type:           string(a.Type),
+		ChatID:         dto_user.ChatIDFromDomain(a.ChatID),
+		Platform:       a.Platform,
+		Route:          a.Route,
+		Observation:    a.Observation,
+		EndActionID:    a.EndActionID,
+		IdempotencyKey: a.IdempotencyKey,
+		CreatedAt:      a.CreatedAt,
+		Attempts:       a.Attempts,
+		LastError:      a.LastError,
+		NextAttempt:    a.NextAttempt,
+	}
+
+	switch a.Type {
+	case d_outbox.SEND_MESSAGE:
+		message := dto_message.MessageFromDomain(a.Message)
+		dto.Message = &message
+	case d_outbox.TRANSFER_TO_MENU:
+		message := dto_message.MessageFromDomain(a.Message)
+		dto.Message = &message
+		dto.Transfer = &dto_action.TransferToMenu{MenuID: a.Transfer.MenuID, Route: a.Transfer.Route}
+	}
+	return dto
+}
+
+// ToDomain converts the DTO into a domain action.
+func (a Action) ToDomain() d_outbox.Action {
+	action := d_outbox.Action{
+		ID:             a.ID,
+		Type:           d_outbox.ActionType(a.Type),
+		ChatID:         a.ChatID.ToDomain(),
+		Platform:       a.Platform,
+		Route:          a.Route,
+		Observation:    a.Observation,
+		EndActionID:    a.EndActionID,
+		IdempotencyKey: a.IdempotencyKey,
+		CreatedAt:      a.CreatedAt,
+		Attempts:       a.Attempts,
+		LastError:      a.LastError,
+		NextAttempt:    a.NextAttempt,
+	}
+	if a.Message != nil {
+		action.Message = a.Message.ToDomain()
+	}
+	if a.Transfer != nil {
+		action.Transfer = d_action.TransferToMenu{MenuID: a.Transfer.MenuID, Route: a.Transfer.Route}
+	}
+	return action
+}
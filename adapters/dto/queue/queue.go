@@ -0,0 +1,38 @@
+// Package dto_queue provides the wire format of incoming chat messages shared by
+// the input adapters (RabbitMQ, HTTP webhook).
+package dto_queue
+
+import (
+	"encoding/json"
+	"fmt"
+
+	dto_message "github.com/irissonnlima/chatgraph-go/adapters/dto/message"
+	dto_user "github.com/irissonnlima/chatgraph-go/adapters/dto/user"
+	adapter_input "github.com/irissonnlima/chatgraph-go/core/ports/adapters/input"
+)
+
+// QueueMessage represents the structure of an incoming message payload.
+type QueueMessage struct {
+	UserState dto_user.UserState  `json:"user_state"`
+	Message   dto_message.Message `json:"message"`
+}
+
+// ParseDelivery decodes a raw QueueMessage payload into a domain delivery.
+// Returns an error if the payload is not valid JSON or if the observation
+// cannot be unmarshaled into Obs. The returned delivery has no Acknowledger.
+func ParseDelivery[Obs any](body []byte) (adapter_input.Delivery[Obs], error) {
+	var queueMsg QueueMessage
+	if err := json.Unmarshal(body, &queueMsg); err != nil {
+		return adapter_input.Delivery[Obs]{}, fmt.Errorf("invalid message payload: %w", err)
+	}
+
+	userState, err := dto_user.ParseUserState[Obs](queueMsg.UserState)
+	if err != nil {
+		return adapter_input.Delivery[Obs]{}, err
+	}
+
+	return adapter_input.Delivery[Obs]{
+		UserState: userState,
+		Message:   queueMsg.Message.ToDomain(),
+	}, nil
+}
@@ -0,0 +1,45 @@
+package dto_queue
+
+import (
+	"testing"
+)
+
+type testObs struct {
+	Value string `json:"value"`
+}
+
+func TestParseDelivery(t *testing.T) {
+	t.Run("valid payload", func(t *testing.T) {
+		body := []byte(`{
+			"user_state": {"chat_id": {"user_id": "u1", "company_id": "c1"}, "route": "start", "observation": "{\"value\":\"x\"}"},
+			"message": {"text_message": {"detail": "hello"}}
+		}`)
+
+		delivery, err := ParseDelivery[testObs](body)
+		if err != nil {
+			t.Fatalf("ParseDelivery() error = %v", err)
+		}
+		if delivery.UserState.ChatID.UserID != "u1" {
+			t.Errorf("ParseDelivery().UserState.ChatID.UserID = %v, want u1", delivery.UserState.ChatID.UserID)
+		}
+		if delivery.UserState.Observation.Value != "x" {
+			t.Errorf("ParseDelivery().UserState.Observation.Value = %v, want x", delivery.UserState.Observation.Value)
+		}
+		if delivery.Message.TextMessage.Detail != "hello" {
+			t.Errorf("ParseDelivery().Message.TextMessage.Detail = %v, want hello", delivery.Message.TextMessage.Detail)
+		}
+	})
+
+	t.Run("malformed json", func(t *testing.T) {
+		if _, err := ParseDelivery[testObs]([]byte("{not json")); err == nil {
+			t.Error("ParseDelivery() should return error for malformed JSON")
+		}
+	})
+
+	t.Run("invalid observation", func(t *testing.T) {
+		body := []byte(`{"user_state": {"observation": "not json"}, "message": {}}`)
+		if _, err := ParseDelivery[testObs](body); err == nil {
+			t.Error("ParseDelivery() should return error for invalid observation")
+		}
+	})
+}
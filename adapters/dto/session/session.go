@@ -0,0 +1,53 @@
+// Package dto_session provides the storage format of chat sessions shared by
+// the session store adapters.
+package dto_session
+
+import (
+	"time"
+
+	dto_user "github.com/irissonnlima/chatgraph-go/adapters/dto/user"
+	d_session "github.com/irissonnlima/chatgraph-go/core/domain/session"
+)
+
+// Session is the JSON representation of a stored chat session.
+type Session struct {
+	ID          int64           `json:"id"`
+	ChatID      dto_user.ChatID `json:"chat_id"`
+	User        dto_user.User   `json:"user"`
+	Menu        dto_user.Menu   `json:"menu"`
+	Route       string          `json:"route"`
+	Observation string          `json:"observation,omitempty"`
+	Platform    string          `json:"platform,omitempty"`
+	CreatedAt   time.Time       `json:"created_at"`
+	UpdatedAt   time.Time       `json:"updated_at"`
+}
+
+// FromDomain converts a domain session into its DTO.
+func FromDomain(s d_session.Session) Session {
+	return Session{
+		ID:          s.ID,
+		ChatID:      dto_user.ChatIDFromDomain(s.ChatID),
+		User:        dto_user.UserFromDomain(s.User),
+		Menu:        dto_user.MenuFromDomain(s.Menu),
+		Route:       s.Route,
+		Observation: s.Observation,
+		Platform:    s.Platform,
+		CreatedAt:   s.CreatedAt,
+		UpdatedAt:   s.UpdatedAt,
+	}
+}
+
+// ToDomain converts the DTO into a domain session.
+func (s Session) ToDomain() d_session.Session {
+	return d_session.Session{
+		ID:          s.ID,
+		ChatID:      s.ChatID.ToDomain(),
+		User:        s.User.ToDomain(),
+		Menu:        s.Menu.ToDomain(),
+		Route:       s.Route,
+		Observation: s.Observation,
+		Platform:    s.Platform,
+		CreatedAt:   s.CreatedAt,
+		UpdatedAt:   s.UpdatedAt,
+	}
+}
@@ -17,3 +17,11 @@ func (c ChatID) ToDomain() d_user.ChatID {
 		CompanyID: c.CompanyID,
 	}
 }
+
+// ChatIDFromDomain converts a domain ChatID into its DTO.
+func ChatIDFromDomain(c d_user.ChatID) ChatID {
+	return ChatID{
+		UserID:    c.UserID,
+		CompanyID: c.CompanyID,
+	}
+}
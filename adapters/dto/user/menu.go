@@ -19,3 +19,11 @@ func (m Menu) ToDomain() d_user.Menu {
 		Name: m.Name,
 	}
 }
+
+// MenuFromDomain converts a domain Menu into its DTO.
+func MenuFromDomain(m d_user.Menu) Menu {
+	return Menu{
+		ID:   m.ID,
+		Name: m.Name,
+	}
+}
@@ -6,6 +6,7 @@ import (
 	d_route "github.com/irissonnlima/chatgraph-go/core/domain/route"
 	d_user "github.com/irissonnlima/chatgraph-go/core/domain/user"
 	"encoding/json"
+	"fmt"
 	"log"
 )
 
@@ -34,6 +35,17 @@ func (u User) ToDomain() d_user.User {
 	}
 }
 
+// UserFromDomain converts a domain User into its DTO.
+func UserFromDomain(u d_user.User) User {
+	return User{
+		CPF:               u.CPF,
+		AuthorizationCode: u.AuthorizationCode,
+		Name:              u.Name,
+		Phone:             u.Phone,
+		Email:             u.Email,
+	}
+}
+
 // UserState represents the complete state of a user's chat session.
 // It is generic over Obs, which allows custom observation data to be
 // associated with the session.
@@ -60,13 +72,26 @@ type UserState struct {
 	DtCreated string `json:"dt_created,omitempty"`
 }
 
+// UserStateToDomain converts the DTO into a domain UserState.
+// It panics if the observation cannot be unmarshaled into Obs; use
+// ParseUserState when the input is untrusted.
 func UserStateToDomain[Obs any](u UserState) d_user.UserState[Obs] {
+	state, err := ParseUserState[Obs](u)
+	if err != nil {
+		log.Println(err)
+		panic("failed to unmarshal observation")
+	}
+	return state
+}
+
+// ParseUserState converts the DTO into a domain UserState.
+// Returns an error if the observation cannot be unmarshaled into Obs.
+func ParseUserState[Obs any](u UserState) (d_user.UserState[Obs], error) {
 	var obs Obs
 	if u.Observation != "" {
 		err := json.Unmarshal([]byte(u.Observation), &obs)
 		if err != nil {
-			log.Println(err)
-			panic("failed to unmarshal observation")
+			return d_user.UserState[Obs]{}, fmt.Errorf("failed to unmarshal observation: %w", err)
 		}
 	}
 
@@ -89,5 +114,5 @@ func UserStateToDomain[Obs any](u UserState) d_user.UserState[Obs] {
 		state.Menu = u.Menu.ToDomain()
 	}
 
-	return state
+	return state, nil
 }
@@ -201,3 +201,33 @@ func TestUserStateToDomain_InvalidObservation(t *testing.T) {
 
 	UserStateToDomain[TestObs](userState)
 }
+
+func TestParseUserState(t *testing.T) {
+	type TestObs struct {
+		Value string `json:"value"`
+	}
+
+	t.Run("valid observation", func(t *testing.T) {
+		got, err := ParseUserState[TestObs](UserState{
+			ChatID:      &ChatID{UserID: "user1", CompanyID: "comp1"},
+			Route:       "start.menu",
+			Observation: `{"value":"test"}`,
+		})
+		if err != nil {
+			t.Fatalf("ParseUserState() error = %v", err)
+		}
+		if got.Observation.Value != "test" {
+			t.Errorf("ParseUserState().Observation.Value = %v, want %v", got.Observation.Value, "test")
+		}
+		if got.Route.Current() != "menu" {
+			t.Errorf("ParseUserState().Route.Current() = %v, want %v", got.Route.Current(), "menu")
+		}
+	})
+
+	t.Run("invalid observation", func(t *testing.T) {
+		_, err := ParseUserState[TestObs](UserState{Observation: "invalid json"})
+		if err == nil {
+			t.Error("ParseUserState() should return error with invalid observation JSON")
+		}
+	})
+}
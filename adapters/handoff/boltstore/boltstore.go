@@ -0,0 +1,181 @@
+// Package boltstore provides an IHandoffRepository backed by an embedded
+// bbolt key-value database, so handed-off chats stay paused across restarts.
+package boltstore
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	dto_handoff "github.com/irissonnlima/chatgraph-go/adapters/dto/handoff"
+	d_handoff "github.com/irissonnlima/chatgraph-go/core/domain/handoff"
+	d_user "github.com/irissonnlima/chatgraph-go/core/domain/user"
+	adapter_output "github.com/irissonnlima/chatgraph-go/core/ports/adapters/output"
+	bolt "go.etcd.io/bbolt"
+)
+
+var _ adapter_output.IHandoffRepository = (*BoltStore)(nil)
+
+const (
+	// DEFAULT_BUCKET is the bucket holding the tickets.
+	DEFAULT_BUCKET = "handoff"
+	// DEFAULT_TIMEOUT is how long to wait for the database file lock.
+	DEFAULT_TIMEOUT = 5 * time.Second
+	// DEFAULT_FILE_MODE is the permission of the database file.
+	DEFAULT_FILE_MODE os.FileMode = 0o600
+)
+
+// ErrMissingPath is returned when no database path is given.
+var ErrMissingPath = errors.New("boltstore: path is required")
+
+// BoltStoreOptions configures the bbolt handoff repository.
+type BoltStoreOptions struct {
+	// Bucket is the bucket holding the tickets. Defaults to DEFAULT_BUCKET.
+	Bucket string
+	// Timeout is how long to wait for the file lock held by another process.
+	// Defaults to DEFAULT_TIMEOUT.
+	Timeout time.Duration
+}
+
+// BoltStore stores tickets as JSON values keyed by their chat.
+type BoltStore struct {
+	db     *bolt.DB
+	bucket []byte
+}
+
+// NewBoltStore opens (or creates) the database at path.
+// Only one process can open the database at a time.
+func NewBoltStore(path string, options ...BoltStoreOptions) (*BoltStore, error) {
+	if path == "" {
+		return nil, ErrMissingPath
+	}
+
+	opts := BoltStoreOptions{}
+	if len(options) > 0 {
+		opts = options[0]
+	}
+	if opts.Bucket == "" {
+		opts.Bucket = DEFAULT_BUCKET
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = DEFAULT_TIMEOUT
+	}
+
+	db, err := bolt.Open(path, DEFAULT_FILE_MODE, &bolt.Options{Timeout: opts.Timeout})
+	if err != nil {
+		return nil, fmt.Errorf("boltstore: %w", err)
+	}
+
+	store := &BoltStore{db: db, bucket: []byte(opts.Bucket)}
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(store.bucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("boltstore: %w", err)
+	}
+
+	return store, nil
+}
+
+// Save stores a ticket, replacing the ticket of its chat.
+func (s *BoltStore) Save(ticket d_handoff.Ticket) error {
+	data, err := json.Marshal(dto_handoff.FromDomain(ticket))
+	if err != nil {
+		return fmt.Errorf("boltstore: %w", err)
+	}
+
+	err = s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(s.bucket).Put(key(ticket.ChatID()), data)
+	})
+	if err != nil {
+		return fmt.Errorf("boltstore: %w", err)
+	}
+	return nil
+}
+
+// Get returns the ticket of a chat.
+func (s *BoltStore) Get(chatID d_user.ChatID) (d_handoff.Ticket, bool, error) {
+	var (
+		ticket d_handoff.Ticket
+		ok     bool
+	)
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(s.bucket).Get(key(chatID))
+		if data == nil {
+			return nil
+		}
+
+		var err error
+		ticket, err = decode(data)
+		ok = err == nil
+		return err
+	})
+	if err != nil {
+		return d_handoff.Ticket{}, false, fmt.Errorf("boltstore: %w", err)
+	}
+	return ticket, ok, nil
+}
+
+// List returns every open ticket, oldest first.
+func (s *BoltStore) List() ([]d_handoff.Ticket, error) {
+	var tickets []d_handoff.Ticket
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(s.bucket).ForEach(func(_, data []byte) error {
+			ticket, err := decode(data)
+			if err != nil {
+				return err
+			}
+			tickets = append(tickets, ticket)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("boltstore: %w", err)
+	}
+
+	sort.Slice(tickets, func(i, j int) bool {
+		if !tickets[i].CreatedAt.Equal(tickets[j].CreatedAt) {
+			return tickets[i].CreatedAt.Before(tickets[j].CreatedAt)
+		}
+		return tickets[i].ID < tickets[j].ID
+	})
+	return tickets, nil
+}
+
+// Delete removes the ticket of a chat.
+func (s *BoltStore) Delete(chatID d_user.ChatID) error {
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(s.bucket).Delete(key(chatID))
+	})
+	if err != nil {
+		return fmt.Errorf("boltstore: %w", err)
+	}
+	return nil
+}
+
+// Close closes the database.
+func (s *BoltStore) Close() error {
+	return s.db.Close()
+}
+
+// key returns the database key of a chat, separating the IDs with a NUL byte,
+// which chat IDs do not contain.
+func key(chatID d_user.ChatID) []byte {
+	return []byte(chatID.CompanyID + "\x00" + chatID.UserID)
+}
+
+// decode reads a stored ticket.
+func decode(data []byte) (d_handoff.Ticket, error) {
+	var dto dto_handoff.Ticket
+	if err := json.Unmarshal(data, &dto); err != nil {
+		return d_handoff.Ticket{}, fmt.Errorf("invalid ticket: %w", err)
+	}
+	return dto.ToDomain(), nil
+}
@@ -0,0 +1,93 @@
+package boltstore
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	d_department "github.com/irissonnlima/chatgraph-go/core/domain/department"
+	d_handoff "github.com/irissonnlima/chatgraph-go/core/domain/handoff"
+	d_session "github.com/irissonnlima/chatgraph-go/core/domain/session"
+	d_user "github.com/irissonnlima/chatgraph-go/core/domain/user"
+)
+
+var (
+	chatA = d_user.ChatID{UserID: "a", CompanyID: "c1"}
+	chatB = d_user.ChatID{UserID: "b", CompanyID: "c1"}
+)
+
+func TestNewBoltStore_MissingPath(t *testing.T) {
+	if _, err := NewBoltStore(""); !errors.Is(err, ErrMissingPath) {
+		t.Errorf("expected ErrMissingPath, got %v", err)
+	}
+}
+
+func TestBoltStore_RoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "handoff.db")
+
+	store, err := NewBoltStore(path)
+	if err != nil {
+		t.Fatalf("NewBoltStore() error = %v", err)
+	}
+
+	created := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	session := d_session.New(chatA, 7, "start.support", created)
+	session.Platform = "whatsapp"
+	billing := d_department.Department{ID: "billing", Name: "Billing"}
+
+	first := d_handoff.Ticket{
+		ID: "t1", Session: session, Department: billing, Agent: "maria",
+		Status: d_handoff.ASSIGNED, Reason: "invoice", ResumeRoute: "start",
+		LastMessage: "help", CreatedAt: created, AssignedAt: created.Add(time.Minute),
+	}
+	second := d_handoff.Ticket{
+		ID: "t2", Session: d_session.New(chatB, 1, "start", created), Department: billing,
+		Status: d_handoff.QUEUED, ResumeRoute: "start", CreatedAt: created.Add(time.Second),
+	}
+	for _, ticket := range []d_handoff.Ticket{second, first} {
+		if err := store.Save(ticket); err != nil {
+			t.Fatalf("Save() error = %v", err)
+		}
+	}
+	if err := store.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	// Reopening the database keeps the tickets.
+	store, err = NewBoltStore(path)
+	if err != nil {
+		t.Fatalf("NewBoltStore() error = %v", err)
+	}
+	defer store.Close()
+
+	tickets, err := store.List()
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(tickets) != 2 || tickets[0].ID != "t1" || tickets[1].ID != "t2" {
+		t.Fatalf("expected t1 then t2, got %+v", tickets)
+	}
+
+	got, ok, err := store.Get(chatA)
+	if err != nil || !ok {
+		t.Fatalf("Get() = %v, %v", ok, err)
+	}
+	if got.Status != d_handoff.ASSIGNED || got.Agent != "maria" || got.Department != billing ||
+		got.Reason != "invoice" || !got.AssignedAt.Equal(first.AssignedAt) {
+		t.Errorf("unexpected ticket %+v", got)
+	}
+	if got.ChatID() != chatA || got.Session.Route != "start.support" || got.Session.Platform != "whatsapp" {
+		t.Errorf("unexpected session %+v", got.Session)
+	}
+
+	if err := store.Delete(chatA); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, ok, _ := store.Get(chatA); ok {
+		t.Error("expected the ticket to be deleted")
+	}
+	if err := store.Delete(chatA); err != nil {
+		t.Errorf("Delete() of a missing ticket error = %v", err)
+	}
+}
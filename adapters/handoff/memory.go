@@ -0,0 +1,74 @@
+// Package handoff provides the repositories of handoff tickets (see
+// d_handoff). MemoryRepository keeps them in memory; boltstore keeps them
+// in an embedded database, so handed-off chats stay paused across restarts.
+package handoff
+
+import (
+	"sort"
+	"sync"
+
+	d_handoff "github.com/irissonnlima/chatgraph-go/core/domain/handoff"
+	d_user "github.com/irissonnlima/chatgraph-go/core/domain/user"
+	adapter_output "github.com/irissonnlima/chatgraph-go/core/ports/adapters/output"
+)
+
+var _ adapter_output.IHandoffRepository = (*MemoryRepository)(nil)
+
+// MemoryRepository is an IHandoffRepository kept in memory. Tickets are
+// lost when the process stops; use boltstore for durable tickets.
+type MemoryRepository struct {
+	mu      sync.Mutex
+	tickets map[d_user.ChatID]d_handoff.Ticket
+}
+
+// NewMemoryRepository creates an empty in-memory handoff repository.
+func NewMemoryRepository() *MemoryRepository {
+	return &MemoryRepository{tickets: make(map[d_user.ChatID]d_handoff.Ticket)}
+}
+
+// Save stores a ticket, replacing the ticket of its chat.
+func (r *MemoryRepository) Save(ticket d_handoff.Ticket) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.tickets[ticket.ChatID()] = ticket
+	return nil
+}
+
+// Get returns the ticket of a chat.
+func (r *MemoryRepository) Get(chatID d_user.ChatID) (d_handoff.Ticket, bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	ticket, ok := r.tickets[chatID]
+	return ticket, ok, nil
+}
+
+// List returns every open ticket, oldest first.
+func (r *MemoryRepository) List() ([]d_handoff.Ticket, error) {
+	r.mu.Lock()
+	tickets := make([]d_handoff.Ticket, 0, len(r.tickets))
+	for _, ticket := range r.tickets {
+		tickets = append(tickets, ticket)
+	}
+	r.mu.Unlock()
+
+	sort.Slice(tickets, func(i, j int) bool {
+		if !tickets[i].CreatedAt.Equal(tickets[j].CreatedAt) {
+			return tickets[i].CreatedAt.Before(tickets[j].CreatedAt)
+		}
+		return tickets[i].ID < tickets[j].ID
+	})
+	return tickets, nil
+}
+
+// Delete removes the ticket of a chat.
+func (r *MemoryRepository) Delete(chatID d_user.ChatID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.tickets, chatID)
+	return nil
+}
+
+// Close does nothing.
+func (r *MemoryRepository) Close() error {
+	return nil
+}
@@ -0,0 +1,178 @@
+// Package boltstore provides an IInactivityRepository backed by an embedded
+// bbolt key-value database, so inactivity timers survive restarts.
+package boltstore
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	dto_inactivity "github.com/irissonnlima/chatgraph-go/adapters/dto/inactivity"
+	d_inactivity "github.com/irissonnlima/chatgraph-go/core/domain/inactivity"
+	d_user "github.com/irissonnlima/chatgraph-go/core/domain/user"
+	adapter_output "github.com/irissonnlima/chatgraph-go/core/ports/adapters/output"
+	bolt "go.etcd.io/bbolt"
+)
+
+var _ adapter_output.IInactivityRepository = (*BoltStore)(nil)
+
+const (
+	// DEFAULT_BUCKET is the bucket holding the timers.
+	DEFAULT_BUCKET = "inactivity"
+	// DEFAULT_TIMEOUT is how long to wait for the database file lock.
+	DEFAULT_TIMEOUT = 5 * time.Second
+	// DEFAULT_FILE_MODE is the permission of the database file.
+	DEFAULT_FILE_MODE os.FileMode = 0o600
+)
+
+// ErrMissingPath is returned when no database path is given.
+var ErrMissingPath = errors.New("boltstore: path is required")
+
+// BoltStoreOptions configures the bbolt inactivity repository.
+type BoltStoreOptions struct {
+	// Bucket is the bucket holding the timers. Defaults to DEFAULT_BUCKET.
+	Bucket string
+	// Timeout is how long to wait for the file lock held by another process.
+	// Defaults to DEFAULT_TIMEOUT.
+	Timeout time.Duration
+}
+
+// BoltStore stores timers as JSON values keyed by their chat.
+type BoltStore struct {
+	db     *bolt.DB
+	bucket []byte
+}
+
+// NewBoltStore opens (or creates) the database at path.
+// Only one process can open the database at a time.
+func NewBoltStore(path string, options ...BoltStoreOptions) (*BoltStore, error) {
+	if path == "" {
+		return nil, ErrMissingPath
+	}
+
+	opts := BoltStoreOptions{}
+	if len(options) > 0 {
+		opts = options[0]
+	}
+	if opts.Bucket == "" {
+		opts.Bucket = DEFAULT_BUCKET
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = DEFAULT_TIMEOUT
+	}
+
+	db, err := bolt.Open(path, DEFAULT_FILE_MODE, &bolt.Options{Timeout: opts.Timeout})
+	if err != nil {
+		return nil, fmt.Errorf("boltstore: %w", err)
+	}
+
+	store := &BoltStore{db: db, bucket: []byte(opts.Bucket)}
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(store.bucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("boltstore: %w", err)
+	}
+
+	return store, nil
+}
+
+// Save stores a timer, replacing the timer of its chat.
+func (s *BoltStore) Save(timer d_inactivity.Timer) error {
+	data, err := json.Marshal(dto_inactivity.FromDomain(timer))
+	if err != nil {
+		return fmt.Errorf("boltstore: %w", err)
+	}
+
+	err = s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(s.bucket).Put(key(timer.ChatID()), data)
+	})
+	if err != nil {
+		return fmt.Errorf("boltstore: %w", err)
+	}
+	return nil
+}
+
+// Get returns the timer of a chat.
+func (s *BoltStore) Get(chatID d_user.ChatID) (d_inactivity.Timer, bool, error) {
+	var (
+		timer d_inactivity.Timer
+		ok    bool
+	)
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(s.bucket).Get(key(chatID))
+		if data == nil {
+			return nil
+		}
+
+		var err error
+		timer, err = decode(data)
+		ok = err == nil
+		return err
+	})
+	if err != nil {
+		return d_inactivity.Timer{}, false, fmt.Errorf("boltstore: %w", err)
+	}
+	return timer, ok, nil
+}
+
+// List returns every stored timer, earliest deadline first.
+func (s *BoltStore) List() ([]d_inactivity.Timer, error) {
+	var timers []d_inactivity.Timer
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(s.bucket).ForEach(func(_, data []byte) error {
+			timer, err := decode(data)
+			if err != nil {
+				return err
+			}
+			timers = append(timers, timer)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("boltstore: %w", err)
+	}
+
+	sort.Slice(timers, func(i, j int) bool {
+		return timers[i].Deadline.Before(timers[j].Deadline)
+	})
+	return timers, nil
+}
+
+// Delete removes the timer of a chat.
+func (s *BoltStore) Delete(chatID d_user.ChatID) error {
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(s.bucket).Delete(key(chatID))
+	})
+	if err != nil {
+		return fmt.Errorf("boltstore: %w", err)
+	}
+	return nil
+}
+
+// Close closes the database.
+func (s *BoltStore) Close() error {
+	return s.db.Close()
+}
+
+// key returns the database key of a chat, separating the IDs with a NUL byte,
+// which chat IDs do not contain.
+func key(chatID d_user.ChatID) []byte {
+	return []byte(chatID.CompanyID + "\x00" + chatID.UserID)
+}
+
+// decode reads a stored timer.
+func decode(data []byte) (d_inactivity.Timer, error) {
+	var dto dto_inactivity.Timer
+	if err := json.Unmarshal(data, &dto); err != nil {
+		return d_inactivity.Timer{}, fmt.Errorf("invalid timer: %w", err)
+	}
+	return dto.ToDomain(), nil
+}
@@ -0,0 +1,98 @@
+package boltstore
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	d_inactivity "github.com/irissonnlima/chatgraph-go/core/domain/inactivity"
+	d_session "github.com/irissonnlima/chatgraph-go/core/domain/session"
+	d_user "github.com/irissonnlima/chatgraph-go/core/domain/user"
+)
+
+var (
+	chatA = d_user.ChatID{UserID: "a", CompanyID: "c1"}
+	chatB = d_user.ChatID{UserID: "b", CompanyID: "c1"}
+)
+
+func TestNewBoltStore_MissingPath(t *testing.T) {
+	if _, err := NewBoltStore(""); !errors.Is(err, ErrMissingPath) {
+		t.Errorf("expected ErrMissingPath, got %v", err)
+	}
+}
+
+func TestBoltStore_RoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "inactivity.db")
+
+	store, err := NewBoltStore(path)
+	if err != nil {
+		t.Fatalf("NewBoltStore() error = %v", err)
+	}
+
+	created := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	session := d_session.New(chatA, 7, "start.ask", created)
+	session.Platform = "whatsapp"
+
+	first := d_inactivity.Timer{
+		ID: "t1", Session: session, Route: "ask",
+		RemindAt: created.Add(5 * time.Minute), Reminded: true,
+		Deadline: created.Add(30 * time.Minute), CreatedAt: created,
+	}
+	second := d_inactivity.Timer{
+		ID: "t2", Session: d_session.New(chatB, 1, "survey", created), Route: "survey",
+		Deadline: created.Add(time.Hour), CreatedAt: created,
+	}
+	for _, timer := range []d_inactivity.Timer{second, first} {
+		if err := store.Save(timer); err != nil {
+			t.Fatalf("Save() error = %v", err)
+		}
+	}
+	if err := store.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	// Reopening the database keeps the timers.
+	store, err = NewBoltStore(path)
+	if err != nil {
+		t.Fatalf("NewBoltStore() error = %v", err)
+	}
+	defer store.Close()
+
+	timers, err := store.List()
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(timers) != 2 || timers[0].ID != "t1" || timers[1].ID != "t2" {
+		t.Fatalf("expected t1 then t2, got %+v", timers)
+	}
+
+	got, ok, err := store.Get(chatA)
+	if err != nil || !ok {
+		t.Fatalf("Get() = %v, %v", ok, err)
+	}
+	if got.Route != "ask" || !got.Reminded || !got.RemindAt.Equal(first.RemindAt) || !got.Deadline.Equal(first.Deadline) {
+		t.Errorf("unexpected timer %+v", got)
+	}
+	if got.ChatID() != chatA || got.Session.Route != "start.ask" || got.Session.Platform != "whatsapp" {
+		t.Errorf("unexpected session %+v", got.Session)
+	}
+
+	// Saving replaces the timer of the chat.
+	if err := store.Save(d_inactivity.Timer{ID: "t3", Session: session, Deadline: created}); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	if got, _, _ := store.Get(chatA); got.ID != "t3" {
+		t.Errorf("expected t3, got %+v", got)
+	}
+
+	if err := store.Delete(chatA); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, ok, _ := store.Get(chatA); ok {
+		t.Error("expected the timer to be deleted")
+	}
+	if err := store.Delete(chatA); err != nil {
+		t.Errorf("Delete() of a missing timer error = %v", err)
+	}
+}
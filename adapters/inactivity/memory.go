@@ -0,0 +1,71 @@
+// Package inactivity provides the repositories of inactivity timers (see
+// d_inactivity). MemoryRepository keeps them in memory; boltstore keeps them
+// in an embedded database, so timers survive restarts.
+package inactivity
+
+import (
+	"sort"
+	"sync"
+
+	d_inactivity "github.com/irissonnlima/chatgraph-go/core/domain/inactivity"
+	d_user "github.com/irissonnlima/chatgraph-go/core/domain/user"
+	adapter_output "github.com/irissonnlima/chatgraph-go/core/ports/adapters/output"
+)
+
+var _ adapter_output.IInactivityRepository = (*MemoryRepository)(nil)
+
+// MemoryRepository is an IInactivityRepository kept in memory. Timers are
+// lost when the process stops; use boltstore for durable timers.
+type MemoryRepository struct {
+	mu     sync.Mutex
+	timers map[d_user.ChatID]d_inactivity.Timer
+}
+
+// NewMemoryRepository creates an empty in-memory inactivity repository.
+func NewMemoryRepository() *MemoryRepository {
+	return &MemoryRepository{timers: make(map[d_user.ChatID]d_inactivity.Timer)}
+}
+
+// Save stores a timer, replacing the timer of its chat.
+func (r *MemoryRepository) Save(timer d_inactivity.Timer) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.timers[timer.ChatID()] = timer
+	return nil
+}
+
+// Get returns the timer of a chat.
+func (r *MemoryRepository) Get(chatID d_user.ChatID) (d_inactivity.Timer, bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	timer, ok := r.timers[chatID]
+	return timer, ok, nil
+}
+
+// List returns every stored timer, earliest deadline first.
+func (r *MemoryRepository) List() ([]d_inactivity.Timer, error) {
+	r.mu.Lock()
+	timers := make([]d_inactivity.Timer, 0, len(r.timers))
+	for _, timer := range r.timers {
+		timers = append(timers, timer)
+	}
+	r.mu.Unlock()
+
+	sort.Slice(timers, func(i, j int) bool {
+		return timers[i].Deadline.Before(timers[j].Deadline)
+	})
+	return timers, nil
+}
+
+// Delete removes the timer of a chat.
+func (r *MemoryRepository) Delete(chatID d_user.ChatID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.timers, chatID)
+	return nil
+}
+
+// Close does nothing.
+func (r *MemoryRepository) Close() error {
+	return nil
+}
@@ -0,0 +1,89 @@
+// Package fanin provides an input adapter that merges several message receivers
+// into one, so an application can consume from RabbitMQ and an HTTP webhook at once.
+package fanin
+
+import (
+	"errors"
+	"sync"
+
+	adapter_input "github.com/irissonnlima/chatgraph-go/core/ports/adapters/input"
+)
+
+// FanIn is an IMessageReceiver that yields the deliveries of all its receivers.
+// Each delivery keeps the Acknowledger of the receiver it came from.
+type FanIn[Obs any] struct {
+	receivers []adapter_input.IMessageReceiver[Obs]
+
+	once      sync.Once
+	out       chan adapter_input.Delivery[Obs]
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// ErrClosed is the reason used to nack deliveries that arrive after Close.
+var ErrClosed = errors.New("fanin: receiver is closed")
+
+// NewFanIn creates a receiver that merges the deliveries of the given receivers.
+func NewFanIn[Obs any](receivers ...adapter_input.IMessageReceiver[Obs]) *FanIn[Obs] {
+	return &FanIn[Obs]{
+		receivers: receivers,
+		out:       make(chan adapter_input.Delivery[Obs]),
+		done:      make(chan struct{}),
+	}
+}
+
+// ConsumeMessage starts consuming from every receiver and returns the merged channel.
+// The channel is closed once all receivers have closed their channels.
+func (f *FanIn[Obs]) ConsumeMessage() <-chan adapter_input.Delivery[Obs] {
+	f.once.Do(func() {
+		var wg sync.WaitGroup
+
+		for _, receiver := range f.receivers {
+			wg.Add(1)
+			go func(in <-chan adapter_input.Delivery[Obs]) {
+				defer wg.Done()
+				for delivery := range in {
+					select {
+					case f.out <- delivery:
+					case <-f.done:
+						// Nobody consumes after Close: hand the delivery back to its source.
+						_ = delivery.Nack(ErrClosed)
+					}
+				}
+			}(receiver.ConsumeMessage())
+		}
+
+		go func() {
+			wg.Wait()
+			close(f.out)
+		}()
+	})
+
+	return f.out
+}
+
+// SetPrefetch forwards the prefetch limit to every receiver that supports it.
+// Each receiver gets the full count, so up to count deliveries may be pending
+// per receiver.
+func (f *FanIn[Obs]) SetPrefetch(count int) error {
+	var errs []error
+	for _, receiver := range f.receivers {
+		if setter, ok := receiver.(adapter_input.IPrefetchSetter); ok {
+			errs = append(errs, setter.SetPrefetch(count))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Close closes every receiver. Deliveries still being forwarded are nacked
+// with ErrClosed. The merged channel is closed once all receivers have
+// closed their channels.
+func (f *FanIn[Obs]) Close() error {
+	f.closeOnce.Do(func() { close(f.done) })
+
+	var errs []error
+	for _, receiver := range f.receivers {
+		errs = append(errs, receiver.Close())
+	}
+	return errors.Join(errs...)
+}
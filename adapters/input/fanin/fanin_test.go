@@ -0,0 +1,142 @@
+package fanin
+
+import (
+	"errors"
+	"sort"
+	"testing"
+
+	d_user "github.com/irissonnlima/chatgraph-go/core/domain/user"
+	adapter_input "github.com/irissonnlima/chatgraph-go/core/ports/adapters/input"
+)
+
+type testObs struct{}
+
+// fakeAcknowledger reports nacks on a channel.
+type fakeAcknowledger struct {
+	nacked chan error
+}
+
+func (a *fakeAcknowledger) Ack() error { return nil }
+
+func (a *fakeAcknowledger) Nack(reason error) error {
+	a.nacked <- reason
+	return nil
+}
+
+// fakeReceiver yields its deliveries and closes its channel on Close.
+type fakeReceiver struct {
+	deliveries []adapter_input.Delivery[testObs]
+	out        chan adapter_input.Delivery[testObs]
+	prefetch   int
+	closed     int
+}
+
+func newFakeReceiver(userIDs ...string) *fakeReceiver {
+	r := &fakeReceiver{out: make(chan adapter_input.Delivery[testObs], len(userIDs))}
+	for _, id := range userIDs {
+		r.deliveries = append(r.deliveries, adapter_input.Delivery[testObs]{
+			UserState: d_user.UserState[testObs]{ChatID: d_user.ChatID{UserID: id}},
+		})
+	}
+	return r
+}
+
+func (r *fakeReceiver) ConsumeMessage() <-chan adapter_input.Delivery[testObs] {
+	for _, d := range r.deliveries {
+		r.out <- d
+	}
+	return r.out
+}
+
+func (r *fakeReceiver) Close() error {
+	r.closed++
+	if r.closed == 1 {
+		close(r.out)
+	}
+	return nil
+}
+
+func (r *fakeReceiver) SetPrefetch(count int) error {
+	r.prefetch = count
+	return nil
+}
+
+// plainReceiver does not support prefetch.
+type plainReceiver struct {
+	inner *fakeReceiver
+}
+
+func (r plainReceiver) ConsumeMessage() <-chan adapter_input.Delivery[testObs] {
+	return r.inner.ConsumeMessage()
+}
+
+func (r plainReceiver) Close() error {
+	return r.inner.Close()
+}
+
+func TestFanIn_MergesDeliveries(t *testing.T) {
+	a := newFakeReceiver("a1", "a2")
+	b := newFakeReceiver("b1")
+	f := NewFanIn[testObs](a, b)
+
+	deliveries := f.ConsumeMessage()
+
+	var got []string
+	for i := 0; i < 3; i++ {
+		got = append(got, (<-deliveries).UserState.ChatID.UserID)
+	}
+	sort.Strings(got)
+
+	want := []string{"a1", "a2", "b1"}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("got %v, want %v", got, want)
+		}
+	}
+
+	if err := f.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if _, ok := <-deliveries; ok {
+		t.Error("expected merged channel to be closed after all receivers closed")
+	}
+	if a.closed != 1 || b.closed != 1 {
+		t.Errorf("expected each receiver to be closed once, got %d and %d", a.closed, b.closed)
+	}
+}
+
+func TestFanIn_SetPrefetch(t *testing.T) {
+	a := newFakeReceiver()
+	b := newFakeReceiver()
+	f := NewFanIn[testObs](a, plainReceiver{b})
+
+	if err := f.SetPrefetch(7); err != nil {
+		t.Fatalf("SetPrefetch() error = %v", err)
+	}
+	if a.prefetch != 7 {
+		t.Errorf("expected prefetch 7, got %d", a.prefetch)
+	}
+	if b.prefetch != 0 {
+		t.Errorf("expected receiver without IPrefetchSetter to be skipped, got %d", b.prefetch)
+	}
+}
+
+func TestFanIn_NacksAfterClose(t *testing.T) {
+	ack := &fakeAcknowledger{nacked: make(chan error, 1)}
+	a := newFakeReceiver("a1")
+	a.deliveries[0].Acknowledger = ack
+	f := NewFanIn[testObs](a)
+
+	deliveries := f.ConsumeMessage()
+	if err := f.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	// Nobody reads the pending delivery: it must be handed back to its source.
+	if reason := <-ack.nacked; !errors.Is(reason, ErrClosed) {
+		t.Errorf("expected delivery to be nacked with ErrClosed, got %v", reason)
+	}
+	if _, ok := <-deliveries; ok {
+		t.Error("expected merged channel to be closed")
+	}
+}
@@ -0,0 +1,186 @@
+package rabbitmq
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+const (
+	// RetryCountHeader carries how many times a message has been redelivered after a processing failure.
+	RetryCountHeader = "x-retry-count"
+	// DeathReasonHeader carries the reason a message was dead-lettered.
+	DeathReasonHeader = "x-death-reason"
+)
+
+// rabbitAcknowledger settles a single RabbitMQ delivery.
+type rabbitAcknowledger[Obs any] struct {
+	rabbit   *RabbitMQ[Obs]
+	delivery amqp.Delivery
+}
+
+// Ack confirms the delivery with the broker.
+func (a *rabbitAcknowledger[Obs]) Ack() error {
+	return a.delivery.Ack(false)
+}
+
+// Nack redelivers the message with an incremented retry count, or dead-letters it
+// once MaxRetries is exhausted.
+func (a *rabbitAcknowledger[Obs]) Nack(reason error) error {
+	retries := retryCount(a.delivery.Headers)
+	if retries >= a.rabbit.options.MaxRetries {
+		log.Printf("[RABBITMQ - Nack] Message exhausted %d retries: %v", retries, reason)
+		return a.rabbit.deadLetter(a.delivery, reason)
+	}
+
+	return a.rabbit.retry(a.delivery, retries+1)
+}
+
+// retry publishes the delivery to the retry queue with the given retry count
+// and acknowledges the original once the broker confirms the copy. The message expires from the retry queue
+// after the RetryBackoff delay and is dead-lettered back to the consumed
+// queue. If publishing fails or is not confirmed, the original is requeued by
+// the broker instead.
+func (r *RabbitMQ[Obs]) retry(delivery amqp.Delivery, retries int) error {
+	headers := copyHeaders(delivery.Headers)
+	headers[RetryCountHeader] = int32(retries)
+
+	delay := r.options.RetryBackoff.Delay(retries - 1)
+	err := r.publish("", r.options.RetryQueue, delivery, headers, expiration(delay))
+	if err != nil {
+		log.Printf("[RABBITMQ - retry] Error republishing message: %v. Requeueing...", err)
+		return delivery.Nack(false, true)
+	}
+
+	log.Printf("[RABBITMQ - retry] Message redelivered in %s (%d/%d)", delay, retries, r.options.MaxRetries)
+	return delivery.Ack(false)
+}
+
+// expiration returns the per-message TTL of a delay, in milliseconds.
+func expiration(delay time.Duration) string {
+	return strconv.FormatInt(delay.Milliseconds(), 10)
+}
+
+// deadLetter routes a poison message to the configured dead-letter exchange and
+// acknowledges the original once the broker confirms the copy. Without a
+// dead-letter exchange, or if publishing fails or is not confirmed, the message
+// is rejected without requeue.
+func (r *RabbitMQ[Obs]) deadLetter(delivery amqp.Delivery, reason error) error {
+	if r.options.DeadLetterExchange == "" {
+		return delivery.Nack(false, false)
+	}
+
+	headers := copyHeaders(delivery.Headers)
+	headers[RetryCountHeader] = int32(retryCount(delivery.Headers))
+	if reason != nil {
+		headers[DeathReasonHeader] = reason.Error()
+	}
+
+	err := r.publish(r.options.DeadLetterExchange, r.options.DeadLetterRoutingKey, delivery, headers, "")
+	if err != nil {
+		log.Printf("[RABBITMQ - deadLetter] Error publishing to dead-letter exchange: %v", err)
+		return delivery.Nack(false, false)
+	}
+
+	log.Printf("[RABBITMQ - deadLetter] Message sent to dead-letter exchange %s: %v", r.options.DeadLetterExchange, reason)
+	return delivery.Ack(false)
+}
+
+// publish sends a copy of the delivery body with the given headers and
+// expiration (per-message TTL, empty for none), and waits for the broker to
+// confirm it.
+func (r *RabbitMQ[Obs]) publish(exchange, key string, delivery amqp.Delivery, headers amqp.Table, expiration string) error {
+	channel := r.currentChannel()
+	if channel == nil {
+		return errNoChannel
+	}
+
+	confirmation, err := channel.PublishWithDeferredConfirmWithContext(
+		context.Background(),
+		exchange,
+		key,
+		false, // mandatory
+		false, // immediate
+		amqp.Publishing{
+			Headers:         headers,
+			ContentType:     delivery.ContentType,
+			ContentEncoding: delivery.ContentEncoding,
+			DeliveryMode:    delivery.DeliveryMode,
+			Priority:        delivery.Priority,
+			CorrelationId:   delivery.CorrelationId,
+			MessageId:       delivery.MessageId,
+			Timestamp:       delivery.Timestamp,
+			Expiration:      expiration,
+			Type:            delivery.Type,
+			AppId:           delivery.AppId,
+			Body:            delivery.Body,
+		},
+	)
+	if err != nil {
+		return err
+	}
+	if confirmation == nil {
+		// Without confirm mode the copy cannot be confirmed: keep the original.
+		return errNotConfirmed
+	}
+	return awaitConfirm(confirmation, r.options.ConfirmTimeout)
+}
+
+// confirmation is the pending broker confirm of a published message.
+type confirmation interface {
+	WaitContext(ctx context.Context) (bool, error)
+}
+
+// awaitConfirm waits up to timeout for the broker to confirm a published
+// message. Returns errNotConfirmed when the broker rejects it, and the
+// context's error when the confirm does not arrive in time.
+func awaitConfirm(confirmation confirmation, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	acked, err := confirmation.WaitContext(ctx)
+	if err != nil {
+		return fmt.Errorf("waiting for publisher confirm: %w", err)
+	}
+	if !acked {
+		return errNotConfirmed
+	}
+	return nil
+}
+
+// retryCount reads the retry count header, returning 0 when absent or invalid.
+func retryCount(headers amqp.Table) int {
+	switch v := headers[RetryCountHeader].(type) {
+	case int:
+		return v
+	case int8:
+		return int(v)
+	case int16:
+		return int(v)
+	case int32:
+		return int(v)
+	case int64:
+		return int(v)
+	case uint8:
+		return int(v)
+	case uint16:
+		return int(v)
+	case uint32:
+		return int(v)
+	default:
+		return 0
+	}
+}
+
+// copyHeaders returns a shallow copy of the headers table, never nil.
+func copyHeaders(headers amqp.Table) amqp.Table {
+	out := make(amqp.Table, len(headers)+2)
+	for k, v := range headers {
+		out[k] = v
+	}
+	return out
+}
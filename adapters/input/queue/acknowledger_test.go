@@ -0,0 +1,79 @@
+package rabbitmq
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+func TestRetryCount(t *testing.T) {
+	tests := []struct {
+		name     string
+		headers  amqp.Table
+		expected int
+	}{
+		{name: "nil headers", headers: nil, expected: 0},
+		{name: "missing header", headers: amqp.Table{"other": "x"}, expected: 0},
+		{name: "int32 header", headers: amqp.Table{RetryCountHeader: int32(2)}, expected: 2},
+		{name: "int64 header", headers: amqp.Table{RetryCountHeader: int64(4)}, expected: 4},
+		{name: "invalid header", headers: amqp.Table{RetryCountHeader: "3"}, expected: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := retryCount(tt.headers); got != tt.expected {
+				t.Errorf("retryCount() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestCopyHeaders(t *testing.T) {
+	original := amqp.Table{"a": "1"}
+	copied := copyHeaders(original)
+	copied["b"] = "2"
+
+	if _, exists := original["b"]; exists {
+		t.Error("copyHeaders() should not mutate the original table")
+	}
+	if copyHeaders(nil) == nil {
+		t.Error("copyHeaders(nil) should return an empty table")
+	}
+}
+
+func TestExpiration(t *testing.T) {
+	if got := expiration(2500 * time.Millisecond); got != "2500" {
+		t.Errorf("expiration() = %q, want %q", got, "2500")
+	}
+}
+
+// fakeConfirmation is a confirm that arrives after delay, acked or not.
+type fakeConfirmation struct {
+	acked bool
+	delay time.Duration
+}
+
+func (c fakeConfirmation) WaitContext(ctx context.Context) (bool, error) {
+	select {
+	case <-time.After(c.delay):
+		return c.acked, nil
+	case <-ctx.Done():
+		return false, ctx.Err()
+	}
+}
+
+func TestAwaitConfirm(t *testing.T) {
+	if err := awaitConfirm(fakeConfirmation{acked: true}, time.Second); err != nil {
+		t.Errorf("expected a confirmed publish, got %v", err)
+	}
+	if err := awaitConfirm(fakeConfirmation{acked: false}, time.Second); !errors.Is(err, errNotConfirmed) {
+		t.Errorf("expected errNotConfirmed for a rejected publish, got %v", err)
+	}
+	err := awaitConfirm(fakeConfirmation{acked: true, delay: time.Second}, 10*time.Millisecond)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected the wait to time out, got %v", err)
+	}
+}
@@ -1,13 +1,12 @@
 package rabbitmq
 
 import (
-	"encoding/json"
+	"errors"
 	"log"
 
-	dto_message "github.com/irissonnlima/chatgraph-go/adapters/dto/message"
-	dto_user "github.com/irissonnlima/chatgraph-go/adapters/dto/user"
-	d_message "github.com/irissonnlima/chatgraph-go/core/domain/message"
-	d_user "github.com/irissonnlima/chatgraph-go/core/domain/user"
+	dto_queue "github.com/irissonnlima/chatgraph-go/adapters/dto/queue"
+	adapter_input "github.com/irissonnlima/chatgraph-go/core/ports/adapters/input"
+	amqp "github.com/rabbitmq/amqp091-go"
 )
 
 // MessageType represents the type of message received from the queue.
@@ -22,69 +21,96 @@ const (
 	MessageTypeEvent MessageType = "event"
 )
 
+var (
+	// errNoChannel is returned when the consumer has no open channel to work with.
+	errNoChannel = errors.New("rabbitmq channel is not open")
+	// errNotConfirmed is returned when the broker rejects a published message.
+	errNotConfirmed = errors.New("rabbitmq rejected the published message")
+	// errClosed is returned when the adapter has been closed.
+	errClosed = errors.New("rabbitmq adapter is closed")
+	// errChannelClosed is reported when the delivery channel is closed by the broker.
+	errChannelClosed = errors.New("rabbitmq delivery channel closed")
+)
+
 // QueueMessage represents the structure of a message received from RabbitMQ.
-type QueueMessage struct {
-	UserState dto_user.UserState  `json:"user_state"`
-	Message   dto_message.Message `json:"message"`
-}
+type QueueMessage = dto_queue.QueueMessage
 
 // ConsumeMessage starts consuming messages from the RabbitMQ queue.
-// It returns a channel that yields UserState and Message pairs for each
-// message of type "message" received from the queue.
-// The consumer runs in an infinite loop and automatically reconnects if the connection drops.
-func (r *RabbitMQ[Obs]) ConsumeMessage() <-chan struct {
-	UserState d_user.UserState[Obs]
-	Message   d_message.Message
-} {
-	out := make(chan struct {
-		UserState d_user.UserState[Obs]
-		Message   d_message.Message
-	})
+// It returns a channel that yields a Delivery for each message received from the queue.
+// Deliveries are consumed with manual acknowledgement: the caller must Ack or Nack each one.
+// Messages that cannot be decoded are dead-lettered without reaching the caller.
+// The consumer automatically reconnects if the connection drops, and stops when Close is called.
+func (r *RabbitMQ[Obs]) ConsumeMessage() <-chan adapter_input.Delivery[Obs] {
+	out := make(chan adapter_input.Delivery[Obs])
 
 	go func() {
+		defer close(out)
+
 		for {
-			msgs, err := r.channel.Consume(
-				r.queue, // queue name
-				"",      // consumer tag
-				true,    // auto-ack
-				false,   // exclusive
-				false,   // no-local
-				false,   // no-wait
-				nil,     // args
-			)
+			if r.isClosed() {
+				return
+			}
+
+			msgs, err := r.consume()
 			if err != nil {
+				if r.isClosed() {
+					return
+				}
 				log.Printf("[RABBITMQ - ConsumeMessage] Error consuming queue: %v. Reconnecting...", err)
-				r.reconnect(5)
+				r.notifyState(StateDisconnected, err)
+				r.reconnect()
 				continue
 			}
 
 			log.Printf("[RABBITMQ - ConsumeMessage] Listening to queue: %s", r.queue)
 
 			for msg := range msgs {
-				var queueMsg QueueMessage
-
-				if err := json.Unmarshal(msg.Body, &queueMsg); err != nil {
-					log.Printf("[RABBITMQ - ConsumeMessage] Error unmarshalling message: %v", err)
+				delivery, err := dto_queue.ParseDelivery[Obs](msg.Body)
+				if err != nil {
+					log.Printf("[RABBITMQ - ConsumeMessage] Error decoding message: %v", err)
+					r.deadLetter(msg, err)
 					continue
 				}
 
-				userState := dto_user.UserStateToDomain[Obs](queueMsg.UserState)
-				message := queueMsg.Message.ToDomain()
+				delivery.Acknowledger = &rabbitAcknowledger[Obs]{rabbit: r, delivery: msg}
 
-				out <- struct {
-					UserState d_user.UserState[Obs]
-					Message   d_message.Message
-				}{
-					UserState: userState,
-					Message:   message,
+				select {
+				case out <- delivery:
+				case <-r.done:
+					// The broker requeues the unacknowledged delivery when the channel closes.
+					return
 				}
 			}
 
+			if r.isClosed() {
+				log.Printf("[RABBITMQ - ConsumeMessage] Consumer stopped.")
+				return
+			}
+
 			// If we get here, the channel was closed (connection lost)
 			log.Printf("[RABBITMQ - ConsumeMessage] Channel closed. Reconnecting...")
-			r.reconnect(5)
+			r.notifyState(StateDisconnected, errChannelClosed)
+			r.reconnect()
 		}
 	}()
 
 	return out
 }
+
+// consume registers a consumer with manual acknowledgement on the current channel.
+func (r *RabbitMQ[Obs]) consume() (<-chan amqp.Delivery, error) {
+	channel := r.currentChannel()
+	if channel == nil {
+		return nil, errNoChannel
+	}
+
+	return channel.Consume(
+		r.queue,       // queue name
+		r.consumerTag, // consumer tag
+		false,         // auto-ack
+		false,         // exclusive
+		false,         // no-local
+		false,         // no-wait
+		nil,           // args
+	)
+}
@@ -2,95 +2,327 @@ package rabbitmq
 
 import (
 	adapter_input "github.com/irissonnlima/chatgraph-go/core/ports/adapters/input"
+	"errors"
 	"fmt"
 	"log"
+	"os"
+	"sync"
 	"time"
 
 	amqp "github.com/rabbitmq/amqp091-go"
 )
 
 type RabbitMQ[Obs any] struct {
-	user     string
-	password string
-	host     string
-	vhost    string
-	queue    string
-
-	connection *amqp.Connection
-	channel    *amqp.Channel
+	queue   string
+	options RabbitMQOptions
+
+	mu          sync.RWMutex
+	prefetch    int
+	consumerTag string
+	connection  *amqp.Connection
+	channel     *amqp.Channel
+
+	// done is closed by Close to stop the consume and reconnect loops.
+	done      chan struct{}
+	closeOnce sync.Once
 }
 
+// NewRabbitMQ creates a RabbitMQ message receiver and connects to the broker.
+// The queue must already exist. Optional options configure redelivery and
+// dead-lettering of failed messages, QoS, topology and reconnection; their URL
+// and Queue fields are overridden by the arguments. Invalid options are logged
+// and nil is returned; use NewRabbitMQWithOptions to get the error instead.
 func NewRabbitMQ[Obs any](
 	user string,
 	password string,
 	host string,
 	vhost string,
 	queue string,
+	options ...RabbitMQOptions,
 ) adapter_input.IMessageReceiver[Obs] {
-	rabbit := RabbitMQ[Obs]{
-		user:     user,
-		password: password,
-		host:     host,
-		vhost:    vhost,
-		queue:    queue,
+	opts := RabbitMQOptions{}
+	if len(options) > 0 {
+		opts = options[0]
+	}
+	opts.URL = buildURL(user, password, host, vhost)
+	opts.Queue = queue
+
+	rabbit, err := newRabbitMQ[Obs](opts)
+	if err != nil {
+		log.Printf("[RABBITMQ - NewRabbitMQ] - Invalid options: %v", err)
+		return nil
+	}
+
+	return rabbit
+}
+
+// NewRabbitMQWithOptions creates a RabbitMQ message receiver from the given options
+// and connects to the broker. The queue, exchange and bindings described in the
+// options are declared on every (re)connection.
+//
+// Returns an error only if the options are invalid. Connection failures are
+// retried in the background with exponential backoff and reported through
+// OnStateChange.
+func NewRabbitMQWithOptions[Obs any](options RabbitMQOptions) (adapter_input.IMessageReceiver[Obs], error) {
+	rabbit, err := newRabbitMQ[Obs](options)
+	if err != nil {
+		return nil, err
+	}
+	return rabbit, nil
+}
+
+// newRabbitMQ validates the options, builds the receiver and performs the first connection attempt.
+func newRabbitMQ[Obs any](options RabbitMQOptions) (*RabbitMQ[Obs], error) {
+	opts, err := options.withDefaults()
+	if err != nil {
+		return nil, err
 	}
 
-	rabbit.connect()
+	consumerTag := opts.ConsumerTag
+	if consumerTag == "" {
+		consumerTag = fmt.Sprintf("chatgraph-%d-%d", os.Getpid(), time.Now().UnixNano())
+	}
+
+	rabbit := &RabbitMQ[Obs]{
+		queue:       opts.Queue,
+		options:     opts,
+		consumerTag: consumerTag,
+		done:        make(chan struct{}),
+	}
 
-	return &rabbit
+	if err := rabbit.connect(); err != nil {
+		log.Printf("[RABBITMQ - connect] - Initial connection failed, retrying in background: %v", err)
+	}
+
+	return rabbit, nil
 }
 
 func (r *RabbitMQ[Obs]) connect() error {
 	log.Println("[RABBITMQ - connect] - Attempting to connect to RabbitMQ...")
+	r.notifyState(StateConnecting, nil)
+
+	err := r.dial()
+	if err != nil {
+		r.notifyState(StateDisconnected, err)
+		return err
+	}
+
+	log.Println("[RABBITMQ - connect] - Connection established successfully to RabbitMQ.")
+	r.notifyState(StateConnected, nil)
+	return nil
+}
+
+// dial replaces the current connection and channel with fresh ones,
+// declares the topology and applies QoS.
+func (r *RabbitMQ[Obs]) dial() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.isClosed() {
+		return errClosed
+	}
 
 	if r.channel != nil {
 		log.Println("[RABBITMQ - connect] - Closing existing channel before reconnecting...")
 		_ = r.channel.Close()
+		r.channel = nil
 	}
 
 	if r.connection != nil {
 		log.Println("[RABBITMQ - connect] - Closing existing connection before reconnecting...")
 		_ = r.connection.Close()
+		r.connection = nil
 	}
 
-	var err error
-	connStr := "amqp://" + r.user + ":" + r.password + "@" + r.host + "/" + r.vhost
-	log.Println(fmt.Sprintf("[RABBITMQ - connect] - Connection string: %s", connStr))
+	redacted := redactURL(r.options.URL)
+	log.Printf("[RABBITMQ - connect] - Connection string: %s", redacted)
+
+	properties := amqp.NewConnectionProperties()
+	properties.SetClientConnectionName(r.consumerTag)
 
-	// Configure connection with heartbeat
-	r.connection, err = amqp.DialConfig(connStr, amqp.Config{
-		Heartbeat: 10 * time.Second, // heartbeat configuration
+	connection, err := amqp.DialConfig(r.options.URL, amqp.Config{
+		Heartbeat:       r.options.Heartbeat,
+		TLSClientConfig: r.options.TLSConfig,
+		Properties:      properties,
 	})
 	if err != nil {
-		log.Println(fmt.Sprintf("[RABBITMQ - connect] - Error connecting to RabbitMQ: %s", connStr), err)
-		return err
+		return fmt.Errorf("connecting to %s: %w", redacted, err)
 	}
 
-	r.channel, err = r.connection.Channel()
+	channel, err := connection.Channel()
 	if err != nil {
-		log.Println("[RABBITMQ - connect] - Error creating channel", err)
+		_ = connection.Close()
+		return fmt.Errorf("creating channel: %w", err)
+	}
+
+	if err := r.declareTopology(channel); err != nil {
+		_ = connection.Close()
 		return err
 	}
 
-	log.Println("[RABBITMQ - connect] - Connection established successfully to RabbitMQ.")
+	if prefetch := r.effectivePrefetch(); prefetch > 0 {
+		if err := channel.Qos(prefetch, 0, false); err != nil {
+			_ = connection.Close()
+			return fmt.Errorf("setting QoS: %w", err)
+		}
+	}
+
+	// Retried and dead-lettered copies are confirmed before their original is acked.
+	if err := channel.Confirm(false); err != nil {
+		_ = connection.Close()
+		return fmt.Errorf("enabling publisher confirms: %w", err)
+	}
+
+	r.connection = connection
+	r.channel = channel
 	return nil
 }
 
-func (r *RabbitMQ[Obs]) reconnect(timeTry uint) {
-	log.Println("[RABBITMQ - reconnect] - Attempting to reconnect to RabbitMQ...")
-	if timeTry == 0 {
-		timeTry = 10
+// declareTopology declares the configured exchange, queue and bindings.
+func (r *RabbitMQ[Obs]) declareTopology(channel *amqp.Channel) error {
+	if ex := r.options.Exchange; ex != nil {
+		err := channel.ExchangeDeclare(ex.Name, ex.Kind, ex.Durable, ex.AutoDelete, ex.Internal, false, ex.Args)
+		if err != nil {
+			return fmt.Errorf("declaring exchange %s: %w", ex.Name, err)
+		}
+	}
+
+	if q := r.options.QueueDeclare; q != nil {
+		_, err := channel.QueueDeclare(r.queue, q.Durable, q.AutoDelete, q.Exclusive, false, q.Args)
+		if err != nil {
+			return fmt.Errorf("declaring queue %s: %w", r.queue, err)
+		}
+	}
+
+	// Expired retries are dead-lettered back to the consumed queue.
+	_, err := channel.QueueDeclare(r.options.RetryQueue, true, false, false, false, amqp.Table{
+		"x-dead-letter-exchange":    "",
+		"x-dead-letter-routing-key": r.queue,
+	})
+	if err != nil {
+		return fmt.Errorf("declaring retry queue %s: %w", r.options.RetryQueue, err)
+	}
+
+	for _, binding := range r.options.Bindings {
+		exchange := binding.Exchange
+		if exchange == "" && r.options.Exchange != nil {
+			exchange = r.options.Exchange.Name
+		}
+
+		err := channel.QueueBind(r.queue, binding.RoutingKey, exchange, false, binding.Args)
+		if err != nil {
+			return fmt.Errorf("binding queue %s to %s (%s): %w", r.queue, exchange, binding.RoutingKey, err)
+		}
+	}
+
+	return nil
+}
+
+// effectivePrefetch returns the configured prefetch, falling back to the one
+// requested through SetPrefetch. Must be called with r.mu held.
+func (r *RabbitMQ[Obs]) effectivePrefetch() int {
+	if r.options.Prefetch > 0 {
+		return r.options.Prefetch
+	}
+	return r.prefetch
+}
+
+// SetPrefetch limits the number of unacknowledged deliveries on the channel.
+// The limit is kept and re-applied whenever the adapter reconnects.
+// It has no effect when RabbitMQOptions.Prefetch is set.
+func (r *RabbitMQ[Obs]) SetPrefetch(count int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.prefetch = count
+	if r.channel == nil || r.options.Prefetch > 0 {
+		return nil
+	}
+	return r.channel.Qos(count, 0, false)
+}
+
+// currentChannel returns the channel of the active connection.
+func (r *RabbitMQ[Obs]) currentChannel() *amqp.Channel {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.channel
+}
+
+// notifyState reports a connection state change to the configured callback.
+func (r *RabbitMQ[Obs]) notifyState(state ConnectionState, err error) {
+	if r.options.OnStateChange != nil {
+		r.options.OnStateChange(state, err)
 	}
+}
 
-	for i := range 5 {
-		log.Println(fmt.Sprintf("[RABBITMQ - reconnect] %d/5: Attempting to reconnect to RabbitMQ...", i+1))
+// reconnect retries the connection with exponential backoff and jitter until
+// it succeeds or the receiver is closed.
+func (r *RabbitMQ[Obs]) reconnect() {
+	log.Println("[RABBITMQ - reconnect] - Attempting to reconnect to RabbitMQ...")
+
+	for attempt := 0; ; attempt++ {
+		if r.isClosed() {
+			return
+		}
+
+		log.Printf("[RABBITMQ - reconnect] Attempt %d: Attempting to reconnect to RabbitMQ...", attempt+1)
 		err := r.connect()
 		if err == nil {
 			log.Println("[RABBITMQ - reconnect] Successfully reconnected to RabbitMQ!")
-			break
+			return
+		}
+
+		delay := r.options.Backoff.Delay(attempt)
+		log.Printf("[RABBITMQ - reconnect] Error attempting to reconnect: %v. Trying again in %s...", err, delay)
+		select {
+		case <-time.After(delay):
+		case <-r.done:
+			return
 		}
+	}
+}
 
-		log.Println(fmt.Sprintf("[RABBITMQ - reconnect] Error attempting to reconnect: %v. Trying again in %d seconds...", err, timeTry))
-		time.Sleep(time.Duration(timeTry) * time.Second)
+// isClosed reports whether Close has been called.
+func (r *RabbitMQ[Obs]) isClosed() bool {
+	select {
+	case <-r.done:
+		return true
+	default:
+		return false
 	}
 }
+
+// Close cancels the consumer and closes the channel and connection.
+// Deliveries that were not acknowledged are requeued by the broker.
+// The delivery channel returned by ConsumeMessage is closed once the
+// consume loop observes the shutdown. Close is idempotent.
+func (r *RabbitMQ[Obs]) Close() error {
+	var errs []error
+
+	r.closeOnce.Do(func() {
+		close(r.done)
+
+		r.mu.Lock()
+		defer r.mu.Unlock()
+
+		if r.channel != nil {
+			if err := r.channel.Cancel(r.consumerTag, false); err != nil && !errors.Is(err, amqp.ErrClosed) {
+				errs = append(errs, fmt.Errorf("cancel consumer: %w", err))
+			}
+			if err := r.channel.Close(); err != nil && !errors.Is(err, amqp.ErrClosed) {
+				errs = append(errs, fmt.Errorf("close channel: %w", err))
+			}
+		}
+
+		if r.connection != nil {
+			if err := r.connection.Close(); err != nil && !errors.Is(err, amqp.ErrClosed) {
+				errs = append(errs, fmt.Errorf("close connection: %w", err))
+			}
+		}
+
+		log.Println("[RABBITMQ - Close] - Connection closed.")
+		r.notifyState(StateClosed, nil)
+	})
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,120 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	dto_event "github.com/irissonnlima/chatgraph-go/adapters/dto/event"
+	adapter_input "github.com/irissonnlima/chatgraph-go/core/ports/adapters/input"
+)
+
+// EventHandler is an http.Handler that runs backend events, sent as signed
+// POST requests carrying an Event JSON body, through an IEventHandler such as
+// the ChatbotApp. It is authenticated like the Webhook, with the same
+// headers and secret format (see Sign).
+//
+// Requests are handled synchronously. Responses:
+//   - 200: the event's route ran and its result was handled.
+//   - 400: the body is not a valid event, or its route is not registered.
+//   - 401: the signature is missing, invalid or outside the allowed clock skew.
+//   - 405: the method is not POST.
+//   - 413: the body exceeds MaxBodyBytes.
+//   - 500: handling the event failed.
+//   - 503: the event cannot be handled now; retry after the Retry-After delay.
+type EventHandler struct {
+	secret  string
+	handler adapter_input.IEventHandler
+	options WebhookOptions
+	now     func() time.Time
+}
+
+// NewEventHandler creates an HTTP endpoint for backend events that
+// authenticates requests with the given shared secret. Of the optional
+// options, only the signature, clock skew, body size and Retry-After settings
+// apply: mount the handler on your own server. Handlers implementing
+// IEventChecker are checked first, so one unable to handle any event (e.g. an
+// app whose executor keeps no chat state) is rejected here.
+func NewEventHandler(secret string, handler adapter_input.IEventHandler, options ...WebhookOptions) (*EventHandler, error) {
+	if secret == "" {
+		return nil, ErrMissingSecret
+	}
+	if checker, ok := handler.(adapter_input.IEventChecker); ok {
+		if err := checker.CheckEvents(); err != nil {
+			return nil, err
+		}
+	}
+
+	opts := WebhookOptions{}
+	if len(options) > 0 {
+		opts = options[0]
+	}
+
+	return &EventHandler{
+		secret:  secret,
+		handler: handler,
+		options: opts.withDefaults(),
+		now:     time.Now,
+	}, nil
+}
+
+// ServeHTTP authenticates, decodes and handles an event request.
+func (h *EventHandler) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		rw.Header().Set("Allow", http.MethodPost)
+		http.Error(rw, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(rw, req.Body, h.options.MaxBodyBytes))
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			http.Error(rw, "request body too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(rw, "failed to read request body", http.StatusBadRequest)
+		return
+	}
+
+	err = verify(
+		h.secret,
+		req.Header.Get(h.options.TimestampHeader),
+		req.Header.Get(h.options.SignatureHeader),
+		body,
+		h.now(),
+		h.options.MaxClockSkew,
+	)
+	if err != nil {
+		log.Printf("[WEBHOOK - EventHandler] Rejected request from %s: %v", req.RemoteAddr, err)
+		http.Error(rw, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	event, err := dto_event.ParseEvent(body)
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// The route keeps running if the sender disconnects, so its result is
+	// still handled.
+	err = h.handler.HandleEvent(context.WithoutCancel(req.Context()), event)
+	switch {
+	case err == nil:
+		rw.WriteHeader(http.StatusOK)
+	case errors.Is(err, adapter_input.ErrInvalidEvent):
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, adapter_input.ErrUnavailable):
+		seconds := int((h.options.RetryAfter + time.Second - 1) / time.Second)
+		rw.Header().Set("Retry-After", strconv.Itoa(seconds))
+		http.Error(rw, err.Error(), http.StatusServiceUnavailable)
+	default:
+		log.Printf("[WEBHOOK - EventHandler] Failed to handle event %s for chat %v: %v", event.ID, event.ChatID, err)
+		http.Error(rw, "processing failed", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,117 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	d_event "github.com/irissonnlima/chatgraph-go/core/domain/event"
+	adapter_input "github.com/irissonnlima/chatgraph-go/core/ports/adapters/input"
+)
+
+const testEventBody = `{"id": "e1", "name": "order_shipped", "chat_id": {"user_id": "u1", "company_id": "c1"}, "route": "shipped", "payload": {"order": 42}}`
+
+type eventHandlerFunc func(ctx context.Context, event d_event.Event) error
+
+func (f eventHandlerFunc) HandleEvent(ctx context.Context, event d_event.Event) error {
+	return f(ctx, event)
+}
+
+func TestNewEventHandler_MissingSecret(t *testing.T) {
+	if _, err := NewEventHandler("", nil); !errors.Is(err, ErrMissingSecret) {
+		t.Errorf("expected ErrMissingSecret, got %v", err)
+	}
+}
+
+// checkedEventHandler is an event handler that reports err upfront.
+type checkedEventHandler struct {
+	eventHandlerFunc
+	err error
+}
+
+func (h checkedEventHandler) CheckEvents() error {
+	return h.err
+}
+
+func TestNewEventHandler_CheckEvents(t *testing.T) {
+	errNoState := errors.New("no chat state")
+	handler := checkedEventHandler{err: errNoState}
+	if _, err := NewEventHandler(testSecret, handler); !errors.Is(err, errNoState) {
+		t.Errorf("expected the handler's error, got %v", err)
+	}
+
+	handler.err = nil
+	if _, err := NewEventHandler(testSecret, handler); err != nil {
+		t.Errorf("NewEventHandler() error = %v", err)
+	}
+}
+
+func TestEventHandler_HandlesEvent(t *testing.T) {
+	var got d_event.Event
+	h, err := NewEventHandler(testSecret, eventHandlerFunc(func(ctx context.Context, event d_event.Event) error {
+		got = event
+		return nil
+	}))
+	if err != nil {
+		t.Fatalf("NewEventHandler() error = %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, newSignedRequest(t, testSecret, testEventBody, time.Now()))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if got.ID != "e1" || got.Name != "order_shipped" || got.Route != "shipped" || got.ChatID.UserID != "u1" {
+		t.Errorf("unexpected event %+v", got)
+	}
+	var payload struct{ Order int }
+	if err := got.DecodePayload(&payload); err != nil || payload.Order != 42 {
+		t.Errorf("DecodePayload() = %+v, %v", payload, err)
+	}
+}
+
+func TestEventHandler_Responses(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		secret   string
+		err      error
+		expected int
+	}{
+		{name: "wrong secret", body: testEventBody, secret: "other", expected: http.StatusUnauthorized},
+		{name: "malformed payload", body: `{"route": 1}`, expected: http.StatusBadRequest},
+		{name: "invalid event", body: testEventBody, err: fmt.Errorf("%w: route not found", adapter_input.ErrInvalidEvent), expected: http.StatusBadRequest},
+		{name: "unavailable", body: testEventBody, err: fmt.Errorf("%w: executor unavailable", adapter_input.ErrUnavailable), expected: http.StatusServiceUnavailable},
+		{name: "failure", body: testEventBody, err: errors.New("boom"), expected: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, err := NewEventHandler(testSecret, eventHandlerFunc(func(ctx context.Context, event d_event.Event) error {
+				return tt.err
+			}), WebhookOptions{RetryAfter: 3 * time.Second})
+			if err != nil {
+				t.Fatalf("NewEventHandler() error = %v", err)
+			}
+
+			secret := tt.secret
+			if secret == "" {
+				secret = testSecret
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, newSignedRequest(t, secret, tt.body, time.Now()))
+
+			if rec.Code != tt.expected {
+				t.Errorf("expected %d, got %d: %s", tt.expected, rec.Code, rec.Body.String())
+			}
+			if tt.expected == http.StatusServiceUnavailable && rec.Header().Get("Retry-After") != "3" {
+				t.Errorf("expected Retry-After 3, got %q", rec.Header().Get("Retry-After"))
+			}
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package webhook
+
+import (
+	"errors"
+	"time"
+)
+
+// Default values for webhook options.
+const (
+	// DEFAULT_PATH is the default path served when the webhook runs its own HTTP server.
+	DEFAULT_PATH = "/webhook"
+	// DEFAULT_SIGNATURE_HEADER carries the HMAC signature of the request.
+	DEFAULT_SIGNATURE_HEADER = "X-Chatgraph-Signature"
+	// DEFAULT_TIMESTAMP_HEADER carries the Unix timestamp (in seconds) covered by the signature.
+	DEFAULT_TIMESTAMP_HEADER = "X-Chatgraph-Timestamp"
+	// DEFAULT_MAX_CLOCK_SKEW is the default tolerance between the request timestamp and the server clock.
+	DEFAULT_MAX_CLOCK_SKEW = 5 * time.Minute
+	// DEFAULT_MAX_BODY_BYTES is the default maximum request body size.
+	DEFAULT_MAX_BODY_BYTES = 1 << 20
+	// DEFAULT_BUFFER_SIZE is the default number of deliveries buffered before requests are rejected.
+	DEFAULT_BUFFER_SIZE = 64
+	// DEFAULT_RETRY_AFTER is the default Retry-After hint sent with 429 and 503 responses.
+	DEFAULT_RETRY_AFTER = 1 * time.Second
+	// DEFAULT_ACK_TIMEOUT is the default time a request waits for its delivery to be settled.
+	DEFAULT_ACK_TIMEOUT = 30 * time.Second
+	// DEFAULT_SHUTDOWN_TIMEOUT bounds how long Close waits for the HTTP server to stop.
+	DEFAULT_SHUTDOWN_TIMEOUT = 10 * time.Second
+)
+
+var (
+	// ErrMissingSecret is returned when the webhook is created without a signing secret.
+	ErrMissingSecret = errors.New("webhook: secret is required")
+	// ErrClosed is the reason used to nack deliveries left in the buffer when the webhook is closed.
+	ErrClosed = errors.New("webhook: receiver is closed")
+)
+
+// WebhookOptions configures the HTTP webhook receiver.
+type WebhookOptions struct {
+	// Addr makes the webhook run its own HTTP server on this address (e.g. ":8080"),
+	// started by ConsumeMessage and stopped by Close. When empty, mount the webhook
+	// as an http.Handler on your own server.
+	Addr string
+	// Path is the path served when Addr is set. Defaults to DEFAULT_PATH.
+	Path string
+
+	// SignatureHeader is the header carrying the request signature. Defaults to DEFAULT_SIGNATURE_HEADER.
+	SignatureHeader string
+	// TimestampHeader is the header carrying the signed timestamp. Defaults to DEFAULT_TIMESTAMP_HEADER.
+	TimestampHeader string
+	// MaxClockSkew rejects requests whose timestamp differs from the server clock
+	// by more than this duration, protecting against replays. Defaults to DEFAULT_MAX_CLOCK_SKEW.
+	MaxClockSkew time.Duration
+	// MaxBodyBytes limits the request body size. Defaults to DEFAULT_MAX_BODY_BYTES.
+	MaxBodyBytes int64
+
+	// BufferSize is the number of deliveries buffered for the application. Defaults to DEFAULT_BUFFER_SIZE.
+	BufferSize int
+	// EnqueueTimeout is how long a request waits for room in the buffer before
+	// being rejected with 429. Zero rejects immediately.
+	EnqueueTimeout time.Duration
+	// RetryAfter is the Retry-After hint sent with 429 and 503 responses. Defaults to DEFAULT_RETRY_AFTER.
+	RetryAfter time.Duration
+
+	// WaitForAck makes each request wait until its delivery is settled: the response
+	// is 200 on Ack and 500 on Nack, so the sender can retry failed messages.
+	// When false, requests are answered with 202 as soon as they are buffered.
+	WaitForAck bool
+	// AckTimeout bounds how long a request waits for its delivery to be settled
+	// when WaitForAck is set. On timeout the response is 202 and processing
+	// continues in the background. Defaults to DEFAULT_ACK_TIMEOUT.
+	AckTimeout time.Duration
+}
+
+// withDefaults fills in default values.
+func (o WebhookOptions) withDefaults() WebhookOptions {
+	if o.Path == "" {
+		o.Path = DEFAULT_PATH
+	}
+	if o.SignatureHeader == "" {
+		o.SignatureHeader = DEFAULT_SIGNATURE_HEADER
+	}
+	if o.TimestampHeader == "" {
+		o.TimestampHeader = DEFAULT_TIMESTAMP_HEADER
+	}
+	if o.MaxClockSkew <= 0 {
+		o.MaxClockSkew = DEFAULT_MAX_CLOCK_SKEW
+	}
+	if o.MaxBodyBytes <= 0 {
+		o.MaxBodyBytes = DEFAULT_MAX_BODY_BYTES
+	}
+	if o.BufferSize <= 0 {
+		o.BufferSize = DEFAULT_BUFFER_SIZE
+	}
+	if o.RetryAfter <= 0 {
+		o.RetryAfter = DEFAULT_RETRY_AFTER
+	}
+	if o.AckTimeout <= 0 {
+		o.AckTimeout = DEFAULT_ACK_TIMEOUT
+	}
+	return o
+}
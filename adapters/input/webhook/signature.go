@@ -0,0 +1,62 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// signaturePrefix identifies the signing algorithm in the signature header.
+const signaturePrefix = "sha256="
+
+// Errors returned when verifying a request signature.
+var (
+	// ErrMissingSignature is returned when the signature or timestamp header is absent.
+	ErrMissingSignature = errors.New("webhook: missing signature")
+	// ErrInvalidSignature is returned when the signature does not match the body.
+	ErrInvalidSignature = errors.New("webhook: invalid signature")
+	// ErrStaleTimestamp is returned when the signed timestamp is outside the allowed clock skew.
+	ErrStaleTimestamp = errors.New("webhook: timestamp outside allowed clock skew")
+)
+
+// Sign computes the signature header value for a request body.
+// The signature is "sha256=" followed by the hex-encoded HMAC-SHA256 of
+// "<timestamp>.<body>" keyed with the shared secret, where timestamp is the
+// value sent in the timestamp header (Unix seconds).
+func Sign(secret string, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return signaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// verify checks the signature and timestamp of a request body.
+func verify(secret, timestamp, signature string, body []byte, now time.Time, maxSkew time.Duration) error {
+	if timestamp == "" || signature == "" {
+		return ErrMissingSignature
+	}
+
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return ErrStaleTimestamp
+	}
+	skew := now.Sub(time.Unix(seconds, 0))
+	if skew > maxSkew || skew < -maxSkew {
+		return ErrStaleTimestamp
+	}
+
+	if !strings.HasPrefix(signature, signaturePrefix) {
+		return ErrInvalidSignature
+	}
+	expected := Sign(secret, timestamp, body)
+	if !hmac.Equal([]byte(signature), []byte(expected)) {
+		return ErrInvalidSignature
+	}
+
+	return nil
+}
@@ -0,0 +1,334 @@
+// Package webhook provides an HTTP input adapter that receives chat messages
+// pushed by HTTP callbacks instead of a message broker.
+package webhook
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	dto_queue "github.com/irissonnlima/chatgraph-go/adapters/dto/queue"
+	adapter_input "github.com/irissonnlima/chatgraph-go/core/ports/adapters/input"
+)
+
+// Webhook is an IMessageReceiver fed by HTTP POST requests carrying a
+// QueueMessage JSON body signed with a shared secret.
+//
+// Responses:
+//   - 200: the delivery was processed and acked (WaitForAck only).
+//   - 202: the delivery was accepted for processing.
+//   - 400: the body is not a valid QueueMessage.
+//   - 401: the signature is missing, invalid or outside the allowed clock skew.
+//   - 405: the method is not POST.
+//   - 413: the body exceeds MaxBodyBytes.
+//   - 429: too many deliveries are pending; retry after the Retry-After delay.
+//   - 500: processing failed and the delivery was nacked (WaitForAck only).
+//   - 503: the receiver is closed; retry after the Retry-After delay.
+type Webhook[Obs any] struct {
+	secret  string
+	options WebhookOptions
+	now     func() time.Time
+
+	// mu guards out and server: handlers send under the read lock, Close closes out under the write lock.
+	mu     sync.RWMutex
+	out    chan adapter_input.Delivery[Obs]
+	closed bool
+
+	// inflight counts deliveries handed to the application and not yet settled.
+	inflightMu sync.Mutex
+	inflight   int
+	prefetch   int
+
+	server     *http.Server
+	serverOnce sync.Once
+	done       chan struct{}
+	closeOnce  sync.Once
+}
+
+// NewWebhook creates an HTTP webhook receiver that authenticates requests with
+// the given shared secret.
+// Optional options configure the server, signature headers, buffering and
+// acknowledgement behaviour.
+func NewWebhook[Obs any](secret string, options ...WebhookOptions) (*Webhook[Obs], error) {
+	if secret == "" {
+		return nil, ErrMissingSecret
+	}
+
+	opts := WebhookOptions{}
+	if len(options) > 0 {
+		opts = options[0]
+	}
+	opts = opts.withDefaults()
+
+	return &Webhook[Obs]{
+		secret:  secret,
+		options: opts,
+		now:     time.Now,
+		out:     make(chan adapter_input.Delivery[Obs], opts.BufferSize),
+		done:    make(chan struct{}),
+	}, nil
+}
+
+// ConsumeMessage returns the channel that yields a Delivery for each accepted request.
+// When WebhookOptions.Addr is set, the first call starts the HTTP server.
+func (w *Webhook[Obs]) ConsumeMessage() <-chan adapter_input.Delivery[Obs] {
+	if w.options.Addr != "" {
+		w.serverOnce.Do(w.listen)
+	}
+	return w.out
+}
+
+// listen starts the HTTP server serving the webhook on Addr and Path.
+func (w *Webhook[Obs]) listen() {
+	mux := http.NewServeMux()
+	mux.Handle(w.options.Path, w)
+
+	server := &http.Server{
+		Addr:              w.options.Addr,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	w.mu.Lock()
+	w.server = server
+	w.mu.Unlock()
+
+	go func() {
+		log.Printf("[WEBHOOK - listen] Listening on %s%s", w.options.Addr, w.options.Path)
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Printf("[WEBHOOK - listen] Server stopped: %v", err)
+		}
+	}()
+}
+
+// SetPrefetch limits the number of unsettled deliveries. Requests beyond the
+// limit are rejected with 429. Zero removes the limit.
+func (w *Webhook[Obs]) SetPrefetch(count int) error {
+	w.inflightMu.Lock()
+	defer w.inflightMu.Unlock()
+	w.prefetch = count
+	return nil
+}
+
+// ServeHTTP authenticates, decodes and enqueues a webhook request.
+func (w *Webhook[Obs]) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		rw.Header().Set("Allow", http.MethodPost)
+		http.Error(rw, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if w.isClosed() {
+		w.reject(rw, http.StatusServiceUnavailable, "receiver is closed")
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(rw, req.Body, w.options.MaxBodyBytes))
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			http.Error(rw, "request body too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(rw, "failed to read request body", http.StatusBadRequest)
+		return
+	}
+
+	err = verify(
+		w.secret,
+		req.Header.Get(w.options.TimestampHeader),
+		req.Header.Get(w.options.SignatureHeader),
+		body,
+		w.now(),
+		w.options.MaxClockSkew,
+	)
+	if err != nil {
+		log.Printf("[WEBHOOK - ServeHTTP] Rejected request from %s: %v", req.RemoteAddr, err)
+		http.Error(rw, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	delivery, err := dto_queue.ParseDelivery[Obs](body)
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if !w.acquire() {
+		w.reject(rw, http.StatusTooManyRequests, "too many pending messages")
+		return
+	}
+
+	ack := &webhookAcknowledger{release: w.release, result: make(chan error, 1)}
+	delivery.Acknowledger = ack
+
+	if status := w.enqueue(req.Context(), delivery); status != 0 {
+		w.release()
+		w.reject(rw, status, http.StatusText(status))
+		return
+	}
+
+	if !w.options.WaitForAck {
+		rw.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	timer := time.NewTimer(w.options.AckTimeout)
+	defer timer.Stop()
+
+	select {
+	case reason := <-ack.result:
+		if errors.Is(reason, ErrClosed) {
+			w.reject(rw, http.StatusServiceUnavailable, "receiver is closed")
+			return
+		}
+		if reason != nil {
+			http.Error(rw, "processing failed", http.StatusInternalServerError)
+			return
+		}
+		rw.WriteHeader(http.StatusOK)
+	case <-timer.C:
+		rw.WriteHeader(http.StatusAccepted)
+	case <-req.Context().Done():
+	}
+}
+
+// enqueue hands the delivery to the application, waiting up to EnqueueTimeout
+// for room in the buffer. Returns 0 on success or the HTTP status to reject with.
+func (w *Webhook[Obs]) enqueue(ctx context.Context, delivery adapter_input.Delivery[Obs]) int {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	if w.closed {
+		return http.StatusServiceUnavailable
+	}
+
+	select {
+	case w.out <- delivery:
+		return 0
+	default:
+	}
+
+	if w.options.EnqueueTimeout <= 0 {
+		return http.StatusTooManyRequests
+	}
+
+	timer := time.NewTimer(w.options.EnqueueTimeout)
+	defer timer.Stop()
+
+	select {
+	case w.out <- delivery:
+		return 0
+	case <-timer.C:
+		return http.StatusTooManyRequests
+	case <-w.done:
+		return http.StatusServiceUnavailable
+	case <-ctx.Done():
+		return http.StatusServiceUnavailable
+	}
+}
+
+// reject answers with the given status and a Retry-After hint.
+func (w *Webhook[Obs]) reject(rw http.ResponseWriter, status int, message string) {
+	seconds := int((w.options.RetryAfter + time.Second - 1) / time.Second)
+	rw.Header().Set("Retry-After", strconv.Itoa(seconds))
+	http.Error(rw, message, status)
+}
+
+// acquire reserves an in-flight slot, returning false when the prefetch limit is reached.
+func (w *Webhook[Obs]) acquire() bool {
+	w.inflightMu.Lock()
+	defer w.inflightMu.Unlock()
+
+	if w.prefetch > 0 && w.inflight >= w.prefetch {
+		return false
+	}
+	w.inflight++
+	return true
+}
+
+// release frees an in-flight slot.
+func (w *Webhook[Obs]) release() {
+	w.inflightMu.Lock()
+	defer w.inflightMu.Unlock()
+	w.inflight--
+}
+
+// isClosed reports whether Close has been called.
+func (w *Webhook[Obs]) isClosed() bool {
+	select {
+	case <-w.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// Close stops accepting requests (answering 503), shuts down the HTTP server
+// when Addr is set and closes the delivery channel. Deliveries still buffered
+// are nacked with ErrClosed so waiting senders are answered with 503.
+// Close is idempotent.
+func (w *Webhook[Obs]) Close() error {
+	var err error
+
+	w.closeOnce.Do(func() {
+		close(w.done)
+
+		w.mu.Lock()
+		w.closed = true
+		close(w.out)
+		server := w.server
+		w.mu.Unlock()
+
+		// Deliveries still buffered were never processed: tell their senders to retry.
+		for delivery := range w.out {
+			_ = delivery.Nack(ErrClosed)
+		}
+
+		if server != nil {
+			ctx, cancel := context.WithTimeout(context.Background(), DEFAULT_SHUTDOWN_TIMEOUT)
+			defer cancel()
+			err = server.Shutdown(ctx)
+		}
+
+		log.Println("[WEBHOOK - Close] - Webhook closed.")
+	})
+
+	return err
+}
+
+// webhookAcknowledger frees the in-flight slot of a delivery and reports
+// its result to the waiting request.
+type webhookAcknowledger struct {
+	once    sync.Once
+	release func()
+	result  chan error
+}
+
+// Ack reports that the delivery was processed successfully.
+func (a *webhookAcknowledger) Ack() error {
+	a.settle(nil)
+	return nil
+}
+
+// Nack reports that processing the delivery failed.
+func (a *webhookAcknowledger) Nack(reason error) error {
+	if reason == nil {
+		reason = errors.New("delivery nacked")
+	}
+	a.settle(reason)
+	return nil
+}
+
+// settle records the result once.
+func (a *webhookAcknowledger) settle(reason error) {
+	a.once.Do(func() {
+		a.release()
+		a.result <- reason
+	})
+}
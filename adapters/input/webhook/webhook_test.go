@@ -0,0 +1,263 @@
+package webhook
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	adapter_input "github.com/irissonnlima/chatgraph-go/core/ports/adapters/input"
+)
+
+type testObs struct {
+	Value string `json:"value"`
+}
+
+const (
+	testSecret = "secret"
+	testBody   = `{"user_state": {"chat_id": {"user_id": "u1", "company_id": "c1"}, "route": "start"}, "message": {"text_message": {"detail": "hello"}}}`
+)
+
+func newSignedRequest(t *testing.T, secret string, body string, at time.Time) *http.Request {
+	t.Helper()
+
+	timestamp := strconv.FormatInt(at.Unix(), 10)
+	req := httptest.NewRequest(http.MethodPost, "/webhook", strings.NewReader(body))
+	req.Header.Set(DEFAULT_TIMESTAMP_HEADER, timestamp)
+	req.Header.Set(DEFAULT_SIGNATURE_HEADER, Sign(secret, timestamp, []byte(body)))
+	return req
+}
+
+func newTestWebhook(t *testing.T, options ...WebhookOptions) *Webhook[testObs] {
+	t.Helper()
+
+	w, err := NewWebhook[testObs](testSecret, options...)
+	if err != nil {
+		t.Fatalf("NewWebhook() error = %v", err)
+	}
+	return w
+}
+
+func TestNewWebhook_MissingSecret(t *testing.T) {
+	if _, err := NewWebhook[testObs](""); !errors.Is(err, ErrMissingSecret) {
+		t.Errorf("expected ErrMissingSecret, got %v", err)
+	}
+}
+
+func TestServeHTTP_Accepted(t *testing.T) {
+	w := newTestWebhook(t)
+	deliveries := w.ConsumeMessage()
+
+	rec := httptest.NewRecorder()
+	w.ServeHTTP(rec, newSignedRequest(t, testSecret, testBody, time.Now()))
+
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	delivery := <-deliveries
+	if delivery.UserState.ChatID.UserID != "u1" {
+		t.Errorf("expected user u1, got %q", delivery.UserState.ChatID.UserID)
+	}
+	if delivery.Message.TextMessage.Detail != "hello" {
+		t.Errorf("expected message hello, got %q", delivery.Message.TextMessage.Detail)
+	}
+	if delivery.Acknowledger == nil {
+		t.Error("expected delivery to have an acknowledger")
+	}
+}
+
+func TestServeHTTP_Rejections(t *testing.T) {
+	now := time.Now()
+
+	tests := []struct {
+		name     string
+		request  func() *http.Request
+		expected int
+	}{
+		{
+			name: "wrong method",
+			request: func() *http.Request {
+				return httptest.NewRequest(http.MethodGet, "/webhook", nil)
+			},
+			expected: http.StatusMethodNotAllowed,
+		},
+		{
+			name: "missing signature",
+			request: func() *http.Request {
+				return httptest.NewRequest(http.MethodPost, "/webhook", strings.NewReader(testBody))
+			},
+			expected: http.StatusUnauthorized,
+		},
+		{
+			name:     "wrong secret",
+			request:  func() *http.Request { return newSignedRequest(t, "other", testBody, now) },
+			expected: http.StatusUnauthorized,
+		},
+		{
+			name:     "stale timestamp",
+			request:  func() *http.Request { return newSignedRequest(t, testSecret, testBody, now.Add(-time.Hour)) },
+			expected: http.StatusUnauthorized,
+		},
+		{
+			name:     "malformed payload",
+			request:  func() *http.Request { return newSignedRequest(t, testSecret, `{"message": 1}`, now) },
+			expected: http.StatusBadRequest,
+		},
+		{
+			name:     "body too large",
+			request:  func() *http.Request { return newSignedRequest(t, testSecret, testBody+" ", now) },
+			expected: http.StatusRequestEntityTooLarge,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := newTestWebhook(t, WebhookOptions{MaxBodyBytes: int64(len(testBody))})
+
+			rec := httptest.NewRecorder()
+			w.ServeHTTP(rec, tt.request())
+
+			if rec.Code != tt.expected {
+				t.Errorf("expected %d, got %d: %s", tt.expected, rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestServeHTTP_BufferFull(t *testing.T) {
+	w := newTestWebhook(t, WebhookOptions{BufferSize: 1, RetryAfter: 3 * time.Second})
+
+	first := httptest.NewRecorder()
+	w.ServeHTTP(first, newSignedRequest(t, testSecret, testBody, time.Now()))
+	if first.Code != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d", first.Code)
+	}
+
+	second := httptest.NewRecorder()
+	w.ServeHTTP(second, newSignedRequest(t, testSecret, testBody, time.Now()))
+	if second.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected 429, got %d", second.Code)
+	}
+	if second.Header().Get("Retry-After") != "3" {
+		t.Errorf("expected Retry-After 3, got %q", second.Header().Get("Retry-After"))
+	}
+}
+
+func TestServeHTTP_PrefetchLimit(t *testing.T) {
+	w := newTestWebhook(t)
+	deliveries := w.ConsumeMessage()
+	if err := w.SetPrefetch(1); err != nil {
+		t.Fatalf("SetPrefetch() error = %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	w.ServeHTTP(rec, newSignedRequest(t, testSecret, testBody, time.Now()))
+	delivery := <-deliveries
+
+	rec = httptest.NewRecorder()
+	w.ServeHTTP(rec, newSignedRequest(t, testSecret, testBody, time.Now()))
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected 429 while a delivery is unsettled, got %d", rec.Code)
+	}
+
+	_ = delivery.Ack()
+
+	rec = httptest.NewRecorder()
+	w.ServeHTTP(rec, newSignedRequest(t, testSecret, testBody, time.Now()))
+	if rec.Code != http.StatusAccepted {
+		t.Errorf("expected 202 after the delivery was settled, got %d", rec.Code)
+	}
+}
+
+func TestServeHTTP_WaitForAck(t *testing.T) {
+	tests := []struct {
+		name     string
+		settle   func(d adapter_input.Delivery[testObs])
+		expected int
+	}{
+		{name: "ack", settle: func(d adapter_input.Delivery[testObs]) { _ = d.Ack() }, expected: http.StatusOK},
+		{name: "nack", settle: func(d adapter_input.Delivery[testObs]) { _ = d.Nack(errors.New("failed")) }, expected: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := newTestWebhook(t, WebhookOptions{WaitForAck: true})
+			deliveries := w.ConsumeMessage()
+
+			go func() {
+				d := <-deliveries
+				tt.settle(d)
+			}()
+
+			rec := httptest.NewRecorder()
+			w.ServeHTTP(rec, newSignedRequest(t, testSecret, testBody, time.Now()))
+			if rec.Code != tt.expected {
+				t.Errorf("expected %d, got %d", tt.expected, rec.Code)
+			}
+		})
+	}
+}
+
+func TestClose(t *testing.T) {
+	w := newTestWebhook(t, WebhookOptions{WaitForAck: true})
+	deliveries := w.ConsumeMessage()
+
+	result := make(chan int, 1)
+	go func() {
+		rec := httptest.NewRecorder()
+		w.ServeHTTP(rec, newSignedRequest(t, testSecret, testBody, time.Now()))
+		result <- rec.Code
+	}()
+
+	// Wait for the request to be buffered, then close without consuming it.
+	for len(deliveries) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	if code := <-result; code != http.StatusServiceUnavailable {
+		t.Errorf("expected buffered request to get 503, got %d", code)
+	}
+	if _, ok := <-deliveries; ok {
+		t.Error("expected delivery channel to be closed")
+	}
+
+	rec := httptest.NewRecorder()
+	w.ServeHTTP(rec, newSignedRequest(t, testSecret, testBody, time.Now()))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected 503 after Close, got %d", rec.Code)
+	}
+	if err := w.Close(); err != nil {
+		t.Errorf("expected Close to be idempotent, got %v", err)
+	}
+}
+
+func TestServer(t *testing.T) {
+	w := newTestWebhook(t)
+	server := httptest.NewServer(w)
+	defer server.Close()
+	deliveries := w.ConsumeMessage()
+
+	req := newSignedRequest(t, testSecret, testBody, time.Now())
+	httpReq, _ := http.NewRequest(http.MethodPost, server.URL, strings.NewReader(testBody))
+	httpReq.Header = req.Header
+
+	resp, err := http.DefaultClient.Do(httpReq)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d", resp.StatusCode)
+	}
+	if d := <-deliveries; d.UserState.ChatID.UserID != "u1" {
+		t.Errorf("expected user u1, got %q", d.UserState.ChatID.UserID)
+	}
+}
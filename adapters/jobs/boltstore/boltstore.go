@@ -0,0 +1,171 @@
+// Package boltstore provides an IJobRepository backed by an embedded bbolt
+// key-value database, so background jobs survive restarts.
+package boltstore
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	dto_job "github.com/irissonnlima/chatgraph-go/adapters/dto/job"
+	d_job "github.com/irissonnlima/chatgraph-go/core/domain/job"
+	adapter_output "github.com/irissonnlima/chatgraph-go/core/ports/adapters/output"
+	bolt "go.etcd.io/bbolt"
+)
+
+var _ adapter_output.IJobRepository = (*BoltStore)(nil)
+
+const (
+	// DEFAULT_BUCKET is the bucket holding the jobs.
+	DEFAULT_BUCKET = "jobs"
+	// DEFAULT_TIMEOUT is how long to wait for the database file lock.
+	DEFAULT_TIMEOUT = 5 * time.Second
+	// DEFAULT_FILE_MODE is the permission of the database file.
+	DEFAULT_FILE_MODE os.FileMode = 0o600
+)
+
+// ErrMissingPath is returned when no database path is given.
+var ErrMissingPath = errors.New("boltstore: path is required")
+
+// BoltStoreOptions configures the bbolt job repository.
+type BoltStoreOptions struct {
+	// Bucket is the bucket holding the jobs. Defaults to DEFAULT_BUCKET.
+	Bucket string
+	// Timeout is how long to wait for the file lock held by another process.
+	// Defaults to DEFAULT_TIMEOUT.
+	Timeout time.Duration
+}
+
+// BoltStore stores jobs as JSON values keyed by their ID.
+type BoltStore struct {
+	db     *bolt.DB
+	bucket []byte
+}
+
+// NewBoltStore opens (or creates) the database at path.
+// Only one process can open the database at a time.
+func NewBoltStore(path string, options ...BoltStoreOptions) (*BoltStore, error) {
+	if path == "" {
+		return nil, ErrMissingPath
+	}
+
+	opts := BoltStoreOptions{}
+	if len(options) > 0 {
+		opts = options[0]
+	}
+	if opts.Bucket == "" {
+		opts.Bucket = DEFAULT_BUCKET
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = DEFAULT_TIMEOUT
+	}
+
+	db, err := bolt.Open(path, DEFAULT_FILE_MODE, &bolt.Options{Timeout: opts.Timeout})
+	if err != nil {
+		return nil, fmt.Errorf("boltstore: %w", err)
+	}
+
+	store := &BoltStore{db: db, bucket: []byte(opts.Bucket)}
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(store.bucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("boltstore: %w", err)
+	}
+
+	return store, nil
+}
+
+// Save stores a job, replacing the stored job with the same ID.
+func (s *BoltStore) Save(job d_job.Job) error {
+	data, err := json.Marshal(dto_job.FromDomain(job))
+	if err != nil {
+		return fmt.Errorf("boltstore: %w", err)
+	}
+
+	err = s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(s.bucket).Put([]byte(job.ID), data)
+	})
+	if err != nil {
+		return fmt.Errorf("boltstore: %w", err)
+	}
+	return nil
+}
+
+// Get returns a stored job.
+func (s *BoltStore) Get(id string) (d_job.Job, bool, error) {
+	var (
+		job d_job.Job
+		ok  bool
+	)
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(s.bucket).Get([]byte(id))
+		if data == nil {
+			return nil
+		}
+
+		var err error
+		job, err = decode(data)
+		ok = err == nil
+		return err
+	})
+	if err != nil {
+		return d_job.Job{}, false, fmt.Errorf("boltstore: %w", err)
+	}
+	return job, ok, nil
+}
+
+// List returns every stored job, oldest first.
+func (s *BoltStore) List() ([]d_job.Job, error) {
+	var jobs []d_job.Job
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(s.bucket).ForEach(func(_, data []byte) error {
+			job, err := decode(data)
+			if err != nil {
+				return err
+			}
+			jobs = append(jobs, job)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("boltstore: %w", err)
+	}
+
+	sort.Slice(jobs, func(i, j int) bool {
+		return jobs[i].CreatedAt.Before(jobs[j].CreatedAt)
+	})
+	return jobs, nil
+}
+
+// Delete removes a job.
+func (s *BoltStore) Delete(id string) error {
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(s.bucket).Delete([]byte(id))
+	})
+	if err != nil {
+		return fmt.Errorf("boltstore: %w", err)
+	}
+	return nil
+}
+
+// Close closes the database.
+func (s *BoltStore) Close() error {
+	return s.db.Close()
+}
+
+// decode reads a stored job.
+func decode(data []byte) (d_job.Job, error) {
+	var dto dto_job.Job
+	if err := json.Unmarshal(data, &dto); err != nil {
+		return d_job.Job{}, fmt.Errorf("invalid job: %w", err)
+	}
+	return dto.ToDomain(), nil
+}
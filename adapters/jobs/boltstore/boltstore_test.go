@@ -0,0 +1,86 @@
+package boltstore
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	d_job "github.com/irissonnlima/chatgraph-go/core/domain/job"
+	d_session "github.com/irissonnlima/chatgraph-go/core/domain/session"
+	d_user "github.com/irissonnlima/chatgraph-go/core/domain/user"
+)
+
+var chat = d_user.ChatID{UserID: "a", CompanyID: "c1"}
+
+func TestNewBoltStore_MissingPath(t *testing.T) {
+	if _, err := NewBoltStore(""); !errors.Is(err, ErrMissingPath) {
+		t.Errorf("expected ErrMissingPath, got %v", err)
+	}
+}
+
+func TestBoltStore_RoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jobs.db")
+
+	store, err := NewBoltStore(path)
+	if err != nil {
+		t.Fatalf("NewBoltStore() error = %v", err)
+	}
+
+	created := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	session := d_session.New(chat, 7, "start.invoice", created)
+	session.Observation = `{"name":"Ana"}`
+
+	second := d_job.Job{ID: "j2", Task: "invoice", Session: session, Status: d_job.PENDING, CreatedAt: created.Add(time.Minute)}
+	first := d_job.Job{
+		ID: "j1", Task: "invoice", Payload: `{"order":42}`, Session: session,
+		CompletionRoute: "invoice_ready", FailureRoute: "invoice_failed",
+		Status: d_job.SUCCEEDED, Result: `{"url":"u"}`, Attempts: 2, MaxAttempts: 3,
+		CreatedAt: created,
+	}
+	for _, job := range []d_job.Job{second, first} {
+		if err := store.Save(job); err != nil {
+			t.Fatalf("Save() error = %v", err)
+		}
+	}
+	if err := store.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	// Reopening the database keeps the jobs.
+	store, err = NewBoltStore(path)
+	if err != nil {
+		t.Fatalf("NewBoltStore() error = %v", err)
+	}
+	defer store.Close()
+
+	jobs, err := store.List()
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(jobs) != 2 || jobs[0].ID != "j1" || jobs[1].ID != "j2" {
+		t.Fatalf("expected j1 then j2, got %+v", jobs)
+	}
+
+	got, ok, err := store.Get("j1")
+	if err != nil || !ok {
+		t.Fatalf("Get() = %v, %v", ok, err)
+	}
+	if got.Payload != first.Payload || got.Result != first.Result || got.Status != d_job.SUCCEEDED ||
+		got.Attempts != 2 || got.CompletionRoute != "invoice_ready" || got.FailureRoute != "invoice_failed" {
+		t.Errorf("unexpected job %+v", got)
+	}
+	if got.Session.ChatID != chat || got.Session.Route != "start.invoice" || got.Session.Observation != session.Observation {
+		t.Errorf("unexpected session %+v", got.Session)
+	}
+
+	if err := store.Delete("j1"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, ok, _ := store.Get("j1"); ok {
+		t.Error("expected j1 to be deleted")
+	}
+	if err := store.Delete("missing"); err != nil {
+		t.Errorf("deleting a missing job should not fail, got %v", err)
+	}
+}
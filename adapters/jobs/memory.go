@@ -0,0 +1,70 @@
+// Package jobs provides the repositories of background jobs (see
+// d_job). MemoryRepository keeps them in memory; boltstore keeps them in an
+// embedded database, so jobs survive restarts.
+package jobs
+
+import (
+	"sort"
+	"sync"
+
+	d_job "github.com/irissonnlima/chatgraph-go/core/domain/job"
+	adapter_output "github.com/irissonnlima/chatgraph-go/core/ports/adapters/output"
+)
+
+var _ adapter_output.IJobRepository = (*MemoryRepository)(nil)
+
+// MemoryRepository is an IJobRepository kept in memory. Jobs are lost when
+// the process stops; use boltstore for durable jobs.
+type MemoryRepository struct {
+	mu   sync.Mutex
+	jobs map[string]d_job.Job
+}
+
+// NewMemoryRepository creates an empty in-memory job repository.
+func NewMemoryRepository() *MemoryRepository {
+	return &MemoryRepository{jobs: make(map[string]d_job.Job)}
+}
+
+// Save stores a job, replacing the stored job with the same ID.
+func (r *MemoryRepository) Save(job d_job.Job) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.jobs[job.ID] = job
+	return nil
+}
+
+// Get returns a stored job.
+func (r *MemoryRepository) Get(id string) (d_job.Job, bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	job, ok := r.jobs[id]
+	return job, ok, nil
+}
+
+// List returns every stored job, oldest first.
+func (r *MemoryRepository) List() ([]d_job.Job, error) {
+	r.mu.Lock()
+	jobs := make([]d_job.Job, 0, len(r.jobs))
+	for _, job := range r.jobs {
+		jobs = append(jobs, job)
+	}
+	r.mu.Unlock()
+
+	sort.Slice(jobs, func(i, j int) bool {
+		return jobs[i].CreatedAt.Before(jobs[j].CreatedAt)
+	})
+	return jobs, nil
+}
+
+// Delete removes a job.
+func (r *MemoryRepository) Delete(id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.jobs, id)
+	return nil
+}
+
+// Close does nothing.
+func (r *MemoryRepository) Close() error {
+	return nil
+}
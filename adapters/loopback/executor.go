@@ -0,0 +1,186 @@
+package loopback
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	d_action "github.com/irissonnlima/chatgraph-go/core/domain/action"
+	d_file "github.com/irissonnlima/chatgraph-go/core/domain/file"
+	d_message "github.com/irissonnlima/chatgraph-go/core/domain/message"
+	d_session "github.com/irissonnlima/chatgraph-go/core/domain/session"
+	d_user "github.com/irissonnlima/chatgraph-go/core/domain/user"
+)
+
+// ErrFileNotFound is returned by GetFile for unknown file IDs.
+var ErrFileNotFound = errors.New("loopback: file not found")
+
+// storedFile is an uploaded file kept in memory.
+type storedFile struct {
+	name string
+	data []byte
+}
+
+// Executor is an IBotExecutor that persists session changes in a Store and
+// records the bot's messages as replies. Uploaded files are kept in memory
+// and served over a local HTTP server so handlers can download them with File.Bytes.
+type Executor struct {
+	store *Store
+
+	mu       sync.Mutex
+	files    map[string]storedFile
+	fileSeq  int
+	listener net.Listener
+}
+
+// NewExecutor creates an executor backed by the given store.
+func NewExecutor(store *Store) *Executor {
+	return &Executor{
+		store: store,
+		files: make(map[string]storedFile),
+	}
+}
+
+// Store returns the session store of the executor.
+func (e *Executor) Store() *Store {
+	return e.store
+}
+
+// SendMessage records the message as a reply to the chat.
+func (e *Executor) SendMessage(to d_user.ChatID, message d_message.Message, platform string) error {
+	e.store.addReply(to, message)
+	return nil
+}
+
+// LoadState returns the session of a chat, starting one if needed.
+func (e *Executor) LoadState(chatID d_user.ChatID) (d_session.Session, bool, error) {
+	session := e.store.Session(chatID)
+	return d_session.Session{
+		ID:          session.SessionID,
+		ChatID:      session.ChatID,
+		User:        session.User,
+		Menu:        session.Menu,
+		Route:       session.Route,
+		Observation: session.Observation,
+		Platform:    session.Platform,
+	}, true, nil
+}
+
+// SetObservation stores the JSON observation of the chat.
+func (e *Executor) SetObservation(chatID d_user.ChatID, observation string) error {
+	e.store.setObservation(chatID, observation)
+	return nil
+}
+
+// SetRoute appends the route to the chat's route history.
+func (e *Executor) SetRoute(chatID d_user.ChatID, route string) error {
+	e.store.setRoute(chatID, route)
+	return nil
+}
+
+// EndSession ends the chat's session; the next message starts a new one.
+func (e *Executor) EndSession(chatID d_user.ChatID, actionId string) error {
+	e.store.endSession(chatID)
+	return nil
+}
+
+// TransferToMenu moves the chat to the target menu and route, recording the
+// transfer message as a reply when it has content.
+func (e *Executor) TransferToMenu(chatID d_user.ChatID, transfer d_action.TransferToMenu, message d_message.Message) error {
+	e.store.transfer(chatID, transfer)
+	if message.EntireText() != "" || message.HasFile() {
+		e.store.addReply(chatID, message)
+	}
+	return nil
+}
+
+// UploadFile reads a local file into memory and returns it with a local URL.
+func (e *Executor) UploadFile(path string) (*d_file.File, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if err := e.serveFiles(); err != nil {
+		return nil, err
+	}
+
+	e.fileSeq++
+	id := fmt.Sprintf("file-%d", e.fileSeq)
+	e.files[id] = storedFile{name: filepath.Base(path), data: data}
+
+	return e.file(id), nil
+}
+
+// GetFile returns a previously uploaded file.
+func (e *Executor) GetFile(fileID string) (*d_file.File, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if _, ok := e.files[fileID]; !ok {
+		return nil, ErrFileNotFound
+	}
+	return e.file(fileID), nil
+}
+
+// file builds the domain file for an uploaded file. Must be called with e.mu held.
+func (e *Executor) file(id string) *d_file.File {
+	stored := e.files[id]
+	return &d_file.File{
+		ID:   id,
+		Type: d_file.TypeFromName(stored.name),
+		URL:  fmt.Sprintf("http://%s/files/%s/%s", e.listener.Addr(), id, stored.name),
+		Name: stored.name,
+	}
+}
+
+// serveFiles starts the local file server on first use. Must be called with e.mu held.
+func (e *Executor) serveFiles() error {
+	if e.listener != nil {
+		return nil
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return fmt.Errorf("starting file server: %w", err)
+	}
+	e.listener = listener
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/files/{id}/{name}", func(w http.ResponseWriter, r *http.Request) {
+		e.mu.Lock()
+		stored, ok := e.files[r.PathValue("id")]
+		e.mu.Unlock()
+
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		http.ServeContent(w, r, stored.name, time.Time{}, bytes.NewReader(stored.data))
+	})
+
+	go http.Serve(listener, mux)
+	return nil
+}
+
+// Close stops the local file server.
+func (e *Executor) Close() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.listener == nil {
+		return nil
+	}
+	err := e.listener.Close()
+	e.listener = nil
+	return err
+}
@@ -0,0 +1,324 @@
+package loopback
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	route_return "github.com/irissonnlima/chatgraph-go/core/domain"
+	d_action "github.com/irissonnlima/chatgraph-go/core/domain/action"
+	d_context "github.com/irissonnlima/chatgraph-go/core/domain/context"
+	d_event "github.com/irissonnlima/chatgraph-go/core/domain/event"
+	d_message "github.com/irissonnlima/chatgraph-go/core/domain/message"
+	d_user "github.com/irissonnlima/chatgraph-go/core/domain/user"
+	"github.com/irissonnlima/chatgraph-go/core/service"
+)
+
+type testObs struct {
+	Name string `json:"name,omitempty"`
+}
+
+var testChat = d_user.ChatID{UserID: "u1", CompanyID: "c1"}
+
+func text(detail string) d_message.Message {
+	return d_message.Message{TextMessage: d_message.TextMessage{Detail: detail}}
+}
+
+func newTestEngine() *service.Engine[testObs] {
+	engine := service.NewEngine[testObs]()
+	engine.RegisterRoute("timeout_route", func(ctx *d_context.ChatContext[testObs]) route_return.RouteReturn {
+		return nil
+	})
+	engine.RegisterRoute("loop_route", func(ctx *d_context.ChatContext[testObs]) route_return.RouteReturn {
+		return nil
+	})
+	engine.RegisterRoute("start", func(ctx *d_context.ChatContext[testObs]) route_return.RouteReturn {
+		ctx.SendTextMessage("What is your name?")
+		return ctx.NextRoute("name")
+	})
+	engine.RegisterRoute("name", func(ctx *d_context.ChatContext[testObs]) route_return.RouteReturn {
+		if ctx.Message.EntireText() == "bye" {
+			return &d_action.EndAction{ID: "bye"}
+		}
+		ctx.SetObservation(testObs{Name: ctx.Message.EntireText()})
+		ctx.SendTextMessage("Hello " + ctx.Message.EntireText())
+		return nil
+	})
+	return engine
+}
+
+// startApp runs a ChatbotApp over a loopback receiver and executor.
+func startApp(t *testing.T) (*Store, *Receiver[testObs]) {
+	t.Helper()
+
+	store := NewStore()
+	receiver := NewReceiver[testObs](store)
+	executor := NewExecutor(store)
+	app := service.NewChatbotApp(newTestEngine(), receiver, executor)
+
+	done := make(chan error, 1)
+	go func() { done <- app.Start(context.Background()) }()
+
+	t.Cleanup(func() {
+		_ = app.Shutdown(context.Background())
+		<-done
+		_ = executor.Close()
+	})
+	return store, receiver
+}
+
+func TestStore_Routes(t *testing.T) {
+	store := NewStore()
+
+	if got := store.Session(testChat).Route; got != DEFAULT_START_ROUTE {
+		t.Fatalf("expected new session at %q, got %q", DEFAULT_START_ROUTE, got)
+	}
+
+	store.setRoute(testChat, "menu")
+	store.setRoute(testChat, "menu")
+	if got := store.Session(testChat).Route; got != "start.menu.menu" {
+		t.Errorf("expected every route to be appended, got %q", got)
+	}
+
+	state, err := UserState[testObs](store.Session(testChat))
+	if err != nil {
+		t.Fatalf("UserState() error = %v", err)
+	}
+	if state.Route.Current() != "menu" || state.Route.Previous().Current() != "start" {
+		t.Errorf("unexpected route %+v", state.Route)
+	}
+}
+
+func TestStore_EndSession(t *testing.T) {
+	store := NewStore(StoreOptions{StartRoute: "begin"})
+	store.SetSession(testChat, Session{
+		Route:       "begin.menu",
+		Observation: `{"name":"Ana"}`,
+		User:        d_user.User{Name: "Ana"},
+	})
+
+	store.endSession(testChat)
+
+	session := store.Session(testChat)
+	if session.Route != "begin" || session.Observation != "" {
+		t.Errorf("expected a fresh session, got %+v", session)
+	}
+	if session.SessionID != 1 {
+		t.Errorf("expected session id 1, got %d", session.SessionID)
+	}
+	if session.User.Name != "Ana" {
+		t.Errorf("expected user to be kept, got %+v", session.User)
+	}
+}
+
+func TestStore_Transfer(t *testing.T) {
+	store := NewStore()
+	store.transfer(testChat, d_action.TransferToMenu{MenuID: 3, Route: "support"})
+
+	session := store.Session(testChat)
+	if session.Menu.ID != 3 || session.Route != "support" {
+		t.Errorf("unexpected session after transfer: %+v", session)
+	}
+}
+
+func TestUserState_InvalidObservation(t *testing.T) {
+	if _, err := UserState[testObs](Session{Route: "start", Observation: "{"}); err == nil {
+		t.Error("expected an error for an invalid observation")
+	}
+}
+
+func TestStore_AwaitReplies(t *testing.T) {
+	store := NewStore()
+
+	go func() {
+		store.addReply(testChat, text("one"))
+		store.addReply(testChat, text("two"))
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	replies, err := store.AwaitReplies(ctx, testChat, 2)
+	if err != nil {
+		t.Fatalf("AwaitReplies() error = %v", err)
+	}
+	if len(replies) != 2 {
+		t.Fatalf("expected 2 replies, got %d", len(replies))
+	}
+	if len(store.Replies(testChat)) != 0 {
+		t.Error("expected replies to be consumed")
+	}
+
+	store.addReply(testChat, text("three"))
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	replies, err = store.AwaitReplies(ctx, testChat, 2)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected DeadlineExceeded, got %v", err)
+	}
+	if len(replies) != 1 {
+		t.Errorf("expected the pending reply, got %d", len(replies))
+	}
+	if len(store.Replies(testChat)) != 1 {
+		t.Error("expected pending replies to be left unread on timeout")
+	}
+}
+
+func TestApp_Conversation(t *testing.T) {
+	store, receiver := startApp(t)
+	ctx := context.Background()
+
+	if err := receiver.SendAndWait(ctx, testChat, text("hi")); err != nil {
+		t.Fatalf("SendAndWait() error = %v", err)
+	}
+	if err := receiver.SendAndWait(ctx, testChat, text("Ana")); err != nil {
+		t.Fatalf("SendAndWait() error = %v", err)
+	}
+
+	replies := store.Replies(testChat)
+	if len(replies) != 2 || replies[1].EntireText() != "Hello Ana" {
+		t.Fatalf("unexpected replies %+v", replies)
+	}
+
+	state, err := UserState[testObs](store.Session(testChat))
+	if err != nil {
+		t.Fatalf("UserState() error = %v", err)
+	}
+	if state.Observation.Name != "Ana" {
+		t.Errorf("expected observation name Ana, got %q", state.Observation.Name)
+	}
+	if state.Route.Current() != "name" {
+		t.Errorf("expected route name, got %q", state.Route.Current())
+	}
+
+	if err := receiver.SendAndWait(ctx, testChat, text("bye")); err != nil {
+		t.Fatalf("SendAndWait() error = %v", err)
+	}
+	if session := store.Session(testChat); session.Route != DEFAULT_START_ROUTE || session.SessionID != 2 {
+		t.Errorf("expected a new session after EndAction, got %+v", session)
+	}
+}
+
+func TestApp_Send(t *testing.T) {
+	store, receiver := startApp(t)
+
+	if err := receiver.Send(context.Background(), testChat, text("hi")); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	replies, err := store.AwaitReplies(ctx, testChat, 1)
+	if err != nil {
+		t.Fatalf("AwaitReplies() error = %v", err)
+	}
+	if replies[0].EntireText() != "What is your name?" {
+		t.Errorf("unexpected reply %q", replies[0].EntireText())
+	}
+}
+
+// TestApp_QueuedMessages tests that a message queued behind another of the
+// same chat runs on the state the earlier one left.
+func TestApp_QueuedMessages(t *testing.T) {
+	store, receiver := startApp(t)
+	ctx := context.Background()
+
+	if err := receiver.Send(ctx, testChat, text("hi")); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if err := receiver.Send(ctx, testChat, text("Ana")); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+	replies, err := store.AwaitReplies(ctx, testChat, 2)
+	if err != nil {
+		t.Fatalf("AwaitReplies() error = %v", err)
+	}
+	if replies[1].EntireText() != "Hello Ana" {
+		t.Errorf("expected the second message to run on the name route, got %q", replies[1].EntireText())
+	}
+}
+
+func TestReceiver_Closed(t *testing.T) {
+	receiver := NewReceiver[testObs](NewStore())
+	if err := receiver.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if err := receiver.Close(); err != nil {
+		t.Errorf("expected Close to be idempotent, got %v", err)
+	}
+
+	if err := receiver.Send(context.Background(), testChat, text("hi")); !errors.Is(err, ErrClosed) {
+		t.Errorf("expected ErrClosed, got %v", err)
+	}
+	if _, ok := <-receiver.ConsumeMessage(); ok {
+		t.Error("expected delivery channel to be closed")
+	}
+}
+
+func TestExecutor_Files(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "note.txt")
+	if err := os.WriteFile(path, []byte("content"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	executor := NewExecutor(NewStore())
+	defer executor.Close()
+
+	file, err := executor.UploadFile(path)
+	if err != nil {
+		t.Fatalf("UploadFile() error = %v", err)
+	}
+	if file.Name != "note.txt" {
+		t.Errorf("expected name note.txt, got %q", file.Name)
+	}
+
+	got, err := executor.GetFile(file.ID)
+	if err != nil {
+		t.Fatalf("GetFile() error = %v", err)
+	}
+
+	resp, err := http.Get(got.URL)
+	if err != nil {
+		t.Fatalf("download failed: %v", err)
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(resp.Body)
+	if string(data) != "content" {
+		t.Errorf("expected content, got %q", data)
+	}
+
+	if _, err := executor.GetFile("missing"); !errors.Is(err, ErrFileNotFound) {
+		t.Errorf("expected ErrFileNotFound, got %v", err)
+	}
+}
+
+func TestExecutor_HandleEvent(t *testing.T) {
+	store := NewStore()
+	store.SetSession(testChat, Session{Route: "start.name", Observation: `{"name":"Ana"}`})
+	engine := newTestEngine()
+	engine.RegisterRoute("reminder", func(ctx *d_context.ChatContext[testObs]) route_return.RouteReturn {
+		ctx.SendTextMessage("See you tomorrow " + ctx.UserState.Observation.Name)
+		return nil
+	})
+	app := service.NewChatbotApp(engine, NewReceiver[testObs](store), NewExecutor(store))
+
+	err := app.HandleEvent(context.Background(), d_event.Event{ID: "e1", ChatID: testChat, Route: "reminder"})
+	if err != nil {
+		t.Fatalf("HandleEvent() error = %v", err)
+	}
+
+	replies := store.Replies(testChat)
+	if len(replies) != 1 || replies[0].TextMessage.Detail != "See you tomorrow Ana" {
+		t.Errorf("unexpected replies %+v", replies)
+	}
+	if got := store.Session(testChat).Route; got != "start.name.reminder" {
+		t.Errorf("expected the event route to be stored, got %q", got)
+	}
+}
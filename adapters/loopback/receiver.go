@@ -0,0 +1,141 @@
+// Package loopback provides in-memory input and output adapters for running the
+// full ChatbotApp pipeline in-process: integration tests, demos, or embedding a
+// bot in another Go service.
+//
+// A Receiver and an Executor share a Store. Messages sent through the Receiver
+// get the stored user state injected, the way the Router API does; the Executor
+// persists route and observation changes and records the bot's replies:
+//
+//	store := loopback.NewStore()
+//	receiver := loopback.NewReceiver[Obs](store)
+//	app := service.NewChatbotApp(engine, receiver, loopback.NewExecutor(store))
+//	go app.Start(ctx)
+//
+//	receiver.SendAndWait(ctx, chatID, message)
+//	replies := store.Replies(chatID)
+package loopback
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	d_message "github.com/irissonnlima/chatgraph-go/core/domain/message"
+	d_user "github.com/irissonnlima/chatgraph-go/core/domain/user"
+	adapter_input "github.com/irissonnlima/chatgraph-go/core/ports/adapters/input"
+)
+
+// ErrClosed is returned when sending to a closed receiver.
+var ErrClosed = errors.New("loopback: receiver is closed")
+
+// Receiver is a channel-backed IMessageReceiver fed by Send and SendAndWait.
+type Receiver[Obs any] struct {
+	store *Store
+
+	// mu guards out: senders hold the read lock, Close closes out under the write lock.
+	mu        sync.RWMutex
+	out       chan adapter_input.Delivery[Obs]
+	closed    bool
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// NewReceiver creates a receiver that injects the user state stored in store.
+func NewReceiver[Obs any](store *Store) *Receiver[Obs] {
+	return &Receiver[Obs]{
+		store: store,
+		out:   make(chan adapter_input.Delivery[Obs]),
+		done:  make(chan struct{}),
+	}
+}
+
+// ConsumeMessage returns the channel of sent messages.
+func (r *Receiver[Obs]) ConsumeMessage() <-chan adapter_input.Delivery[Obs] {
+	return r.out
+}
+
+// Send delivers a message from the chat with its stored user state, returning
+// once the application has taken it. It does not wait for the bot to handle it.
+// The application loads the state again from the Executor right before the
+// handler runs, so messages sent in a row see each other's changes.
+func (r *Receiver[Obs]) Send(ctx context.Context, chatID d_user.ChatID, message d_message.Message) error {
+	_, err := r.send(ctx, chatID, message)
+	return err
+}
+
+// SendAndWait delivers a message like Send and waits until the bot finishes
+// handling it. Returns the reason the delivery was nacked, if any.
+func (r *Receiver[Obs]) SendAndWait(ctx context.Context, chatID d_user.ChatID, message d_message.Message) error {
+	ack, err := r.send(ctx, chatID, message)
+	if err != nil {
+		return err
+	}
+
+	select {
+	case reason := <-ack.done:
+		return reason
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// send builds the delivery from the stored session and hands it to the application.
+func (r *Receiver[Obs]) send(ctx context.Context, chatID d_user.ChatID, message d_message.Message) (*acknowledger, error) {
+	state, err := UserState[Obs](r.store.Session(chatID))
+	if err != nil {
+		return nil, err
+	}
+
+	ack := &acknowledger{done: make(chan error, 1)}
+	delivery := adapter_input.Delivery[Obs]{
+		UserState:    state,
+		Message:      message,
+		Acknowledger: ack,
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if r.closed {
+		return nil, ErrClosed
+	}
+
+	select {
+	case r.out <- delivery:
+		return ack, nil
+	case <-r.done:
+		return nil, ErrClosed
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Close closes the delivery channel. Pending sends fail with ErrClosed.
+func (r *Receiver[Obs]) Close() error {
+	r.closeOnce.Do(func() {
+		close(r.done)
+
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.closed = true
+		close(r.out)
+	})
+	return nil
+}
+
+// acknowledger reports the result of a delivery to SendAndWait.
+type acknowledger struct {
+	done chan error
+}
+
+// Ack reports that the delivery was handled successfully.
+func (a *acknowledger) Ack() error {
+	a.done <- nil
+	return nil
+}
+
+// Nack reports that handling the delivery failed.
+func (a *acknowledger) Nack(reason error) error {
+	a.done <- reason
+	return nil
+}
@@ -0,0 +1,255 @@
+package loopback
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	d_action "github.com/irissonnlima/chatgraph-go/core/domain/action"
+	d_message "github.com/irissonnlima/chatgraph-go/core/domain/message"
+	d_route "github.com/irissonnlima/chatgraph-go/core/domain/route"
+	d_user "github.com/irissonnlima/chatgraph-go/core/domain/user"
+)
+
+// Default values for store options.
+const (
+	// DEFAULT_START_ROUTE is the route of a new session.
+	DEFAULT_START_ROUTE = "start"
+	// DEFAULT_PLATFORM is the platform reported in the injected user state.
+	DEFAULT_PLATFORM = "loopback"
+	// ROUTE_SEPARATOR separates routes in the session history, as in the Router API.
+	ROUTE_SEPARATOR = '.'
+)
+
+// StoreOptions configures the in-memory session store.
+type StoreOptions struct {
+	// StartRoute is the route of a new session. Defaults to DEFAULT_START_ROUTE.
+	StartRoute string
+	// Platform is reported in the injected user state. Defaults to DEFAULT_PLATFORM.
+	Platform string
+}
+
+// Session is the stored state of a chat, in the same format the Router API
+// sends it: the route is the full history path and the observation is JSON.
+type Session struct {
+	// SessionID increases every time the session ends.
+	SessionID int64
+	// ChatID identifies the chat.
+	ChatID d_user.ChatID
+	// User holds the user's personal information.
+	User d_user.User
+	// Menu is the menu the chat was last transferred to.
+	Menu d_user.Menu
+	// Route is the route history joined by ROUTE_SEPARATOR.
+	Route string
+	// Observation is the JSON observation, empty for a new session.
+	Observation string
+	// Platform identifies the messaging platform.
+	Platform string
+}
+
+// chat holds the session and the unread bot replies of a chat.
+type chat struct {
+	session Session
+	replies []d_message.Message
+}
+
+// Store is an in-memory session store shared by a loopback Receiver and Executor.
+// It persists route and observation changes and collects the bot's replies.
+type Store struct {
+	options StoreOptions
+
+	mu    sync.Mutex
+	chats map[d_user.ChatID]*chat
+	// changed is closed and replaced whenever a reply is added, waking AwaitReplies.
+	changed chan struct{}
+}
+
+// NewStore creates an empty in-memory session store.
+// Optional options configure the start route and platform of new sessions.
+func NewStore(options ...StoreOptions) *Store {
+	opts := StoreOptions{}
+	if len(options) > 0 {
+		opts = options[0]
+	}
+	if opts.StartRoute == "" {
+		opts.StartRoute = DEFAULT_START_ROUTE
+	}
+	if opts.Platform == "" {
+		opts.Platform = DEFAULT_PLATFORM
+	}
+
+	return &Store{
+		options: opts,
+		chats:   make(map[d_user.ChatID]*chat),
+		changed: make(chan struct{}),
+	}
+}
+
+// chat returns the chat for chatID, creating a new session if needed.
+// Must be called with s.mu held.
+func (s *Store) chat(chatID d_user.ChatID) *chat {
+	c, ok := s.chats[chatID]
+	if !ok {
+		c = &chat{session: s.newSession(chatID, 1)}
+		s.chats[chatID] = c
+	}
+	return c
+}
+
+// newSession returns a fresh session at the start route.
+func (s *Store) newSession(chatID d_user.ChatID, sessionID int64) Session {
+	return Session{
+		SessionID: sessionID,
+		ChatID:    chatID,
+		Route:     s.options.StartRoute,
+		Platform:  s.options.Platform,
+	}
+}
+
+// Session returns the current session of a chat, starting one if needed.
+func (s *Store) Session(chatID d_user.ChatID) Session {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.chat(chatID).session
+}
+
+// SetSession replaces the stored session of a chat, e.g. to start a test
+// from a given route and observation. The ChatID of the session is ignored.
+func (s *Store) SetSession(chatID d_user.ChatID, session Session) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session.ChatID = chatID
+	if session.Route == "" {
+		session.Route = s.options.StartRoute
+	}
+	if session.Platform == "" {
+		session.Platform = s.options.Platform
+	}
+	s.chat(chatID).session = session
+}
+
+// Reset ends the session of a chat and discards its unread replies.
+func (s *Store) Reset(chatID d_user.ChatID) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c := s.chat(chatID)
+	c.session = s.newSession(chatID, c.session.SessionID+1)
+	c.replies = nil
+}
+
+// setRoute appends a route to the session history.
+func (s *Store) setRoute(chatID d_user.ChatID, route string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c := s.chat(chatID)
+	if c.session.Route == "" {
+		c.session.Route = route
+		return
+	}
+	c.session.Route += string(ROUTE_SEPARATOR) + route
+}
+
+// setObservation stores the JSON observation of a session.
+func (s *Store) setObservation(chatID d_user.ChatID, observation string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.chat(chatID).session.Observation = observation
+}
+
+// endSession starts a new session for the chat, keeping the user information.
+func (s *Store) endSession(chatID d_user.ChatID) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c := s.chat(chatID)
+	user := c.session.User
+	c.session = s.newSession(chatID, c.session.SessionID+1)
+	c.session.User = user
+}
+
+// transfer moves the session to another menu and route.
+func (s *Store) transfer(chatID d_user.ChatID, transfer d_action.TransferToMenu) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	route := transfer.Route
+	if route == "" {
+		route = s.options.StartRoute
+	}
+	c := s.chat(chatID)
+	c.session.Menu = d_user.Menu{ID: transfer.MenuID}
+	c.session.Route = route
+}
+
+// addReply records a bot reply and wakes up waiters.
+func (s *Store) addReply(chatID d_user.ChatID, message d_message.Message) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c := s.chat(chatID)
+	c.replies = append(c.replies, message)
+
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
+
+// Replies returns and removes the unread bot replies of a chat.
+func (s *Store) Replies(chatID d_user.ChatID) []d_message.Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c := s.chat(chatID)
+	replies := c.replies
+	c.replies = nil
+	return replies
+}
+
+// AwaitReplies waits until the chat has at least n unread replies, then
+// returns and removes all of them. Returns ctx.Err() if ctx is done first,
+// together with the replies received so far, which are left unread.
+func (s *Store) AwaitReplies(ctx context.Context, chatID d_user.ChatID, n int) ([]d_message.Message, error) {
+	for {
+		s.mu.Lock()
+		c := s.chat(chatID)
+		if len(c.replies) >= n {
+			replies := c.replies
+			c.replies = nil
+			s.mu.Unlock()
+			return replies, nil
+		}
+		pending := append([]d_message.Message(nil), c.replies...)
+		changed := s.changed
+		s.mu.Unlock()
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return pending, ctx.Err()
+		}
+	}
+}
+
+// UserState converts a stored session into the user state injected into
+// incoming deliveries, decoding the observation into Obs.
+func UserState[Obs any](session Session) (d_user.UserState[Obs], error) {
+	state := d_user.UserState[Obs]{
+		SessionID:   session.SessionID,
+		ChatID:      session.ChatID,
+		User:        session.User,
+		Menu:        session.Menu,
+		Route:       d_route.NewRoute(session.Route, ROUTE_SEPARATOR),
+		DirectionIn: true,
+		Platform:    session.Platform,
+	}
+
+	if strings.TrimSpace(session.Observation) != "" {
+		if err := state.LoadObservation(session.Observation); err != nil {
+			return state, err
+		}
+	}
+	return state, nil
+}
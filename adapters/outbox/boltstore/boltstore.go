@@ -0,0 +1,277 @@
+// Package boltstore provides an IOutboxRepository backed by an embedded bbolt
+// key-value database, so queued actions survive restarts.
+package boltstore
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	dto_outbox "github.com/irissonnlima/chatgraph-go/adapters/dto/outbox"
+	d_outbox "github.com/irissonnlima/chatgraph-go/core/domain/outbox"
+	d_user "github.com/irissonnlima/chatgraph-go/core/domain/user"
+	adapter_output "github.com/irissonnlima/chatgraph-go/core/ports/adapters/output"
+	bolt "go.etcd.io/bbolt"
+)
+
+var _ adapter_output.IOutboxRepository = (*BoltStore)(nil)
+
+const (
+	// DEFAULT_BUCKET is the bucket holding the actions.
+	DEFAULT_BUCKET = "outbox"
+	// DEFAULT_TIMEOUT is how long to wait for the database file lock.
+	DEFAULT_TIMEOUT = 5 * time.Second
+	// DEFAULT_FILE_MODE is the permission of the database file.
+	DEFAULT_FILE_MODE os.FileMode = 0o600
+)
+
+// ErrMissingPath is returned when no database path is given.
+var ErrMissingPath = errors.New("boltstore: path is required")
+
+// BoltStoreOptions configures the bbolt outbox.
+type BoltStoreOptions struct {
+	// Bucket is the bucket holding the actions. The number of actions of each
+	// chat is kept in the bucket Bucket + ".chats", and actions that cannot
+	// be decoded are moved to the bucket Bucket + ".quarantine". Defaults to
+	// DEFAULT_BUCKET.
+	Bucket string
+	// Timeout is how long to wait for the file lock held by another process.
+	// Defaults to DEFAULT_TIMEOUT.
+	Timeout time.Duration
+}
+
+// BoltStore stores actions as JSON values keyed by their big-endian ID, so
+// iterating the bucket returns them in order.
+type BoltStore struct {
+	db         *bolt.DB
+	bucket     []byte
+	chats      []byte
+	quarantine []byte
+}
+
+// NewBoltStore opens (or creates) the database at path.
+// Only one process can open the database at a time.
+func NewBoltStore(path string, options ...BoltStoreOptions) (*BoltStore, error) {
+	if path == "" {
+		return nil, ErrMissingPath
+	}
+
+	opts := BoltStoreOptions{}
+	if len(options) > 0 {
+		opts = options[0]
+	}
+	if opts.Bucket == "" {
+		opts.Bucket = DEFAULT_BUCKET
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = DEFAULT_TIMEOUT
+	}
+
+	db, err := bolt.Open(path, DEFAULT_FILE_MODE, &bolt.Options{Timeout: opts.Timeout})
+	if err != nil {
+		return nil, fmt.Errorf("boltstore: %w", err)
+	}
+
+	store := &BoltStore{
+		db:         db,
+		bucket:     []byte(opts.Bucket),
+		chats:      []byte(opts.Bucket + ".chats"),
+		quarantine: []byte(opts.Bucket + ".quarantine"),
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, bucket := range [][]byte{store.bucket, store.chats, store.quarantine} {
+			if _, err := tx.CreateBucketIfNotExists(bucket); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("boltstore: %w", err)
+	}
+
+	return store, nil
+}
+
+// Append stores an action with the next ID.
+func (s *BoltStore) Append(action d_outbox.Action) (d_outbox.Action, error) {
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(s.bucket)
+		id, err := bucket.NextSequence()
+		if err != nil {
+			return err
+		}
+		action.ID = id
+
+		if err := s.put(tx, action); err != nil {
+			return err
+		}
+		return s.count(tx, action.ChatID, 1)
+	})
+	if err != nil {
+		return d_outbox.Action{}, fmt.Errorf("boltstore: %w", err)
+	}
+	return action, nil
+}
+
+// Pending returns every stored action, ordered by ID. Actions that cannot
+// be decoded are logged and moved to the quarantine bucket, so they do not
+// block the others. Their chat cannot be told, so its count is left as is:
+// the chat keeps going through the outbox, which still delivers in order.
+func (s *BoltStore) Pending() ([]d_outbox.Action, error) {
+	var actions []d_outbox.Action
+	var corrupt [][]byte
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(s.bucket).ForEach(func(key, data []byte) error {
+			action, err := decode(data)
+			if err != nil {
+				log.Printf("[ERROR] boltstore: quarantining outbox action %d: %v", binary.BigEndian.Uint64(key), err)
+				corrupt = append(corrupt, append([]byte(nil), key...))
+				return nil
+			}
+			actions = append(actions, action)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("boltstore: %w", err)
+	}
+
+	if len(corrupt) > 0 {
+		if err := s.db.Update(func(tx *bolt.Tx) error {
+			return s.quarantineKeys(tx, corrupt)
+		}); err != nil {
+			log.Printf("[ERROR] boltstore: failed to quarantine outbox actions: %v", err)
+		}
+	}
+	return actions, nil
+}
+
+// quarantineKeys moves the actions stored at keys to the quarantine bucket
+// within tx.
+func (s *BoltStore) quarantineKeys(tx *bolt.Tx, keys [][]byte) error {
+	bucket := tx.Bucket(s.bucket)
+	quarantine := tx.Bucket(s.quarantine)
+	for _, key := range keys {
+		data := bucket.Get(key)
+		if data == nil {
+			continue
+		}
+		if err := quarantine.Put(key, append([]byte(nil), data...)); err != nil {
+			return err
+		}
+		if err := bucket.Delete(key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// HasPending reports whether a chat has stored actions.
+func (s *BoltStore) HasPending(chatID d_user.ChatID) (bool, error) {
+	var pending bool
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		pending = tx.Bucket(s.chats).Get(chatKey(chatID)) != nil
+		return nil
+	})
+	if err != nil {
+		return false, fmt.Errorf("boltstore: %w", err)
+	}
+	return pending, nil
+}
+
+// Update replaces a stored action. Missing actions are not recreated.
+func (s *BoltStore) Update(action d_outbox.Action) error {
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		if tx.Bucket(s.bucket).Get(idKey(action.ID)) == nil {
+			return nil
+		}
+		return s.put(tx, action)
+	})
+	if err != nil {
+		return fmt.Errorf("boltstore: %w", err)
+	}
+	return nil
+}
+
+// Delete removes an action.
+func (s *BoltStore) Delete(id uint64) error {
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(s.bucket)
+		data := bucket.Get(idKey(id))
+		if data == nil {
+			return nil
+		}
+
+		action, err := decode(data)
+		if err != nil {
+			return err
+		}
+		if err := bucket.Delete(idKey(id)); err != nil {
+			return err
+		}
+		return s.count(tx, action.ChatID, -1)
+	})
+	if err != nil {
+		return fmt.Errorf("boltstore: %w", err)
+	}
+	return nil
+}
+
+// Close closes the database.
+func (s *BoltStore) Close() error {
+	return s.db.Close()
+}
+
+// put writes an action within tx.
+func (s *BoltStore) put(tx *bolt.Tx, action d_outbox.Action) error {
+	data, err := json.Marshal(dto_outbox.FromDomain(action))
+	if err != nil {
+		return err
+	}
+	return tx.Bucket(s.bucket).Put(idKey(action.ID), data)
+}
+
+// count adds delta to the number of actions of a chat within tx, removing
+// the chat when it reaches zero.
+func (s *BoltStore) count(tx *bolt.Tx, chatID d_user.ChatID, delta int64) error {
+	bucket := tx.Bucket(s.chats)
+	key := chatKey(chatID)
+
+	var count int64
+	if data := bucket.Get(key); len(data) == 8 {
+		count = int64(binary.BigEndian.Uint64(data))
+	}
+	count += delta
+	if count <= 0 {
+		return bucket.Delete(key)
+	}
+	return bucket.Put(key, binary.BigEndian.AppendUint64(nil, uint64(count)))
+}
+
+// decode reads a stored action.
+func decode(data []byte) (d_outbox.Action, error) {
+	var dto dto_outbox.Action
+	if err := json.Unmarshal(data, &dto); err != nil {
+		return d_outbox.Action{}, fmt.Errorf("invalid action: %w", err)
+	}
+	return dto.ToDomain(), nil
+}
+
+// idKey returns the database key of an action.
+func idKey(id uint64) []byte {
+	return binary.BigEndian.AppendUint64(nil, id)
+}
+
+// chatKey returns the database key of a chat, separating the IDs with a NUL
+// byte, which chat IDs do not contain.
+func chatKey(chatID d_user.ChatID) []byte {
+	return []byte(chatID.CompanyID + "\x00" + chatID.UserID)
+}
@@ -0,0 +1,150 @@
+package boltstore
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	d_action "github.com/irissonnlima/chatgraph-go/core/domain/action"
+	d_message "github.com/irissonnlima/chatgraph-go/core/domain/message"
+	d_outbox "github.com/irissonnlima/chatgraph-go/core/domain/outbox"
+	d_user "github.com/irissonnlima/chatgraph-go/core/domain/user"
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	chatA = d_user.ChatID{UserID: "a", CompanyID: "c1"}
+	chatB = d_user.ChatID{UserID: "b", CompanyID: "c1"}
+)
+
+func TestNewBoltStore_MissingPath(t *testing.T) {
+	if _, err := NewBoltStore(""); !errors.Is(err, ErrMissingPath) {
+		t.Errorf("expected ErrMissingPath, got %v", err)
+	}
+}
+
+func TestBoltStore_RoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "outbox.db")
+
+	store, err := NewBoltStore(path)
+	if err != nil {
+		t.Fatalf("NewBoltStore() error = %v", err)
+	}
+
+	created := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	message := d_message.Message{TextMessage: d_message.TextMessage{Detail: "hi"}}
+
+	first, err := store.Append(d_outbox.Action{
+		Type: d_outbox.SEND_MESSAGE, ChatID: chatA, Message: message, Platform: "whatsapp",
+		IdempotencyKey: "k1", CreatedAt: created,
+	})
+	if err != nil {
+		t.Fatalf("Append() error = %v", err)
+	}
+	second, _ := store.Append(d_outbox.Action{
+		Type: d_outbox.TRANSFER_TO_MENU, ChatID: chatA, Message: message,
+		Transfer: d_action.TransferToMenu{MenuID: 3, Route: "start"}, CreatedAt: created,
+	})
+	if second.ID <= first.ID {
+		t.Errorf("IDs should increase, got %d then %d", first.ID, second.ID)
+	}
+
+	first.Attempts = 2
+	first.LastError = "connection refused"
+	if err := store.Update(first); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if err := store.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	// Reopening the database keeps the actions.
+	store, err = NewBoltStore(path)
+	if err != nil {
+		t.Fatalf("NewBoltStore() error = %v", err)
+	}
+	defer store.Close()
+
+	actions, err := store.Pending()
+	if err != nil {
+		t.Fatalf("Pending() error = %v", err)
+	}
+	if len(actions) != 2 {
+		t.Fatalf("expected 2 actions, got %d", len(actions))
+	}
+	got := actions[0]
+	if got.ID != first.ID || got.Attempts != 2 || got.LastError != "connection refused" ||
+		got.IdempotencyKey != "k1" || got.Platform != "whatsapp" ||
+		got.Message.TextMessage.Detail != "hi" || !got.CreatedAt.Equal(created) {
+		t.Errorf("unexpected first action %+v", got)
+	}
+	if actions[1].Transfer.MenuID != 3 || actions[1].Transfer.Route != "start" {
+		t.Errorf("unexpected transfer %+v", actions[1].Transfer)
+	}
+}
+
+func TestBoltStore_HasPending(t *testing.T) {
+	store, err := NewBoltStore(filepath.Join(t.TempDir(), "outbox.db"))
+	if err != nil {
+		t.Fatalf("NewBoltStore() error = %v", err)
+	}
+	defer store.Close()
+
+	first, _ := store.Append(d_outbox.Action{Type: d_outbox.SET_ROUTE, ChatID: chatA, Route: "a"})
+	second, _ := store.Append(d_outbox.Action{Type: d_outbox.SET_ROUTE, ChatID: chatA, Route: "b"})
+
+	if pending, _ := store.HasPending(chatB); pending {
+		t.Error("chat b has no actions")
+	}
+
+	store.Delete(first.ID)
+	if pending, _ := store.HasPending(chatA); !pending {
+		t.Error("chat a still has an action")
+	}
+
+	store.Delete(second.ID)
+	if pending, _ := store.HasPending(chatA); pending {
+		t.Error("chat a has no actions left")
+	}
+
+	// Deleting twice does not corrupt the count.
+	if err := store.Delete(second.ID); err != nil {
+		t.Errorf("Delete() error = %v", err)
+	}
+	store.Append(d_outbox.Action{Type: d_outbox.SET_ROUTE, ChatID: chatA, Route: "c"})
+	if pending, _ := store.HasPending(chatA); !pending {
+		t.Error("chat a has a new action")
+	}
+}
+
+func TestBoltStore_QuarantinesCorruptActions(t *testing.T) {
+	store, err := NewBoltStore(filepath.Join(t.TempDir(), "outbox.db"))
+	if err != nil {
+		t.Fatalf("NewBoltStore() error = %v", err)
+	}
+	defer store.Close()
+
+	corrupt, _ := store.Append(d_outbox.Action{Type: d_outbox.SET_ROUTE, ChatID: chatA, Route: "a"})
+	valid, _ := store.Append(d_outbox.Action{Type: d_outbox.SET_ROUTE, ChatID: chatB, Route: "b"})
+	store.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(store.bucket).Put(idKey(corrupt.ID), []byte("{not json"))
+	})
+
+	for i := 0; i < 2; i++ {
+		actions, err := store.Pending()
+		if err != nil {
+			t.Fatalf("Pending() error = %v", err)
+		}
+		if len(actions) != 1 || actions[0].ID != valid.ID {
+			t.Fatalf("expected only the valid action, got %+v", actions)
+		}
+	}
+
+	store.db.View(func(tx *bolt.Tx) error {
+		if tx.Bucket(store.quarantine).Get(idKey(corrupt.ID)) == nil {
+			t.Error("expected the corrupt action to be quarantined")
+		}
+		return nil
+	})
+}
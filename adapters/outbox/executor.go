@@ -0,0 +1,440 @@
+// Package outbox keeps the outbound actions that fail because the executor's
+// backend is down, and performs them later, so the conversation state does
+// not diverge from what the user saw.
+//
+// The Executor wraps another executor. When a message, route change,
+// observation, session end or transfer fails with a retryable error, it is
+// stored in an IOutboxRepository and the handler goes on. While a chat has
+// actions in the outbox, its new actions are queued behind them, so a chat's
+// actions are always performed in order. Run dispatches the outbox in the
+// background, retrying each chat's oldest action with backoff until it
+// succeeds or expires:
+//
+//	repository, _ := boltstore.NewBoltStore("outbox.db")
+//	executor := outbox.NewExecutor(routerApi, repository)
+//	go executor.Run(ctx)
+//	app := service.NewChatbotApp(engine, rabbit, executor)
+package outbox
+
+import (
+	"context"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	d_action "github.com/irissonnlima/chatgraph-go/core/domain/action"
+	d_file "github.com/irissonnlima/chatgraph-go/core/domain/file"
+	d_idempotency "github.com/irissonnlima/chatgraph-go/core/domain/idempotency"
+	d_message "github.com/irissonnlima/chatgraph-go/core/domain/message"
+	d_outbox "github.com/irissonnlima/chatgraph-go/core/domain/outbox"
+	d_session "github.com/irissonnlima/chatgraph-go/core/domain/session"
+	d_user "github.com/irissonnlima/chatgraph-go/core/domain/user"
+	adapter_output "github.com/irissonnlima/chatgraph-go/core/ports/adapters/output"
+)
+
+var (
+	_ adapter_output.IBotExecutor          = (*Executor)(nil)
+	_ adapter_output.IContextBinder        = (*Executor)(nil)
+	_ adapter_output.IAvailabilityReporter = (*Executor)(nil)
+)
+
+// Metrics is a snapshot of the outbox.
+type Metrics struct {
+	// Pending is the number of actions in the outbox.
+	Pending int
+	// Chats is the number of chats with actions in the outbox.
+	Chats int
+	// OldestAge is the age of the oldest action, zero when the outbox is empty.
+	OldestAge time.Duration
+	// Queued is the number of actions added to the outbox since the executor was created.
+	Queued uint64
+	// Delivered is the number of actions performed by the dispatcher.
+	Delivered uint64
+	// Expired is the number of actions dropped after the TTL.
+	Expired uint64
+	// Dropped is the number of actions dropped after a non-retryable error.
+	Dropped uint64
+}
+
+// outbox is the state shared by an executor and the copies bound by WithContext.
+type outbox struct {
+	repository adapter_output.IOutboxRepository
+	options    OutboxOptions
+	// wake signals the dispatcher that actions were queued.
+	wake chan struct{}
+	// dispatching serializes dispatches.
+	dispatching sync.Mutex
+
+	queued, delivered, expired, dropped atomic.Uint64
+}
+
+// Executor is an IBotExecutor that queues failed actions in an outbox.
+type Executor struct {
+	executor adapter_output.IBotExecutor
+	outbox   *outbox
+	ctx      context.Context
+}
+
+// NewExecutor wraps executor, keeping its failed actions in repository.
+func NewExecutor(
+	executor adapter_output.IBotExecutor,
+	repository adapter_output.IOutboxRepository,
+	options ...OutboxOptions,
+) *Executor {
+	opts := OutboxOptions{}
+	if len(options) > 0 {
+		opts = options[0]
+	}
+
+	return &Executor{
+		executor: executor,
+		outbox: &outbox{
+			repository: repository,
+			options:    opts.withDefaults(),
+			wake:       make(chan struct{}, 1),
+		},
+	}
+}
+
+// WithContext returns an executor whose calls are bound to ctx. Queued
+// actions are dispatched later, independently of ctx.
+func (e *Executor) WithContext(ctx context.Context) adapter_output.IBotExecutor {
+	bound := *e
+	bound.ctx = ctx
+	return &bound
+}
+
+// context returns the context the executor is bound to.
+func (e *Executor) context() context.Context {
+	if e.ctx == nil {
+		return context.Background()
+	}
+	return e.ctx
+}
+
+// bind returns the wrapped executor bound to ctx, when it supports it.
+func (e *Executor) bind(ctx context.Context) adapter_output.IBotExecutor {
+	if binder, ok := e.executor.(adapter_output.IContextBinder); ok {
+		return binder.WithContext(ctx)
+	}
+	return e.executor
+}
+
+// bindKey binds the wrapped executor to ctx with the idempotency key of action.
+func (e *Executor) bindKey(ctx context.Context, action d_outbox.Action) adapter_output.IBotExecutor {
+	if action.IdempotencyKey != "" {
+		ctx = d_idempotency.WithKey(ctx, action.IdempotencyKey)
+	}
+	return e.bind(ctx)
+}
+
+// perform performs an action, or queues it behind the chat's pending actions
+// or after a retryable failure.
+func (e *Executor) perform(action d_outbox.Action) error {
+	ctx := e.context()
+	action.IdempotencyKey = d_idempotency.ActionKey(ctx)
+
+	pending, err := e.outbox.repository.HasPending(action.ChatID)
+	if err != nil {
+		log.Printf("[ERROR] Failed to read the outbox for chat %v: %v", action.ChatID, err)
+	}
+	if pending {
+		return e.enqueue(action, nil)
+	}
+
+	err = apply(e.bindKey(ctx, action), action)
+	if err == nil || !e.outbox.options.Retryable(err) {
+		return err
+	}
+	return e.enqueue(action, err)
+}
+
+// enqueue adds an action to the outbox. cause is the error of the failed
+// attempt, nil when the action is queued behind others. Returns cause when
+// the action cannot be stored.
+func (e *Executor) enqueue(action d_outbox.Action, cause error) error {
+	now := e.outbox.options.Now()
+	action.CreatedAt = now
+	if cause != nil {
+		action.Attempts = 1
+		action.LastError = cause.Error()
+		action.NextAttempt = now.Add(e.outbox.options.retryDelay(1))
+	}
+
+	if _, err := e.outbox.repository.Append(action); err != nil {
+		log.Printf("[ERROR] Failed to queue %s for chat %v in the outbox: %v", action.Type, action.ChatID, err)
+		if cause != nil {
+			return cause
+		}
+		return err
+	}
+	e.outbox.queued.Add(1)
+
+	if cause != nil {
+		log.Printf("[WARN] Queued %s for chat %v in the outbox: %v", action.Type, action.ChatID, cause)
+	} else {
+		log.Printf("[INFO] Queued %s for chat %v behind its pending actions", action.Type, action.ChatID)
+	}
+
+	select {
+	case e.outbox.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// apply performs an action with executor.
+func apply(executor adapter_output.IBotExecutor, action d_outbox.Action) error {
+	switch action.Type {
+	case d_outbox.SEND_MESSAGE:
+		return executor.SendMessage(action.ChatID, action.Message, action.Platform)
+	case d_outbox.SET_ROUTE:
+		return executor.SetRoute(action.ChatID, action.Route)
+	case d_outbox.SET_OBSERVATION:
+		return executor.SetObservation(action.ChatID, action.Observation)
+	case d_outbox.END_SESSION:
+		return executor.EndSession(action.ChatID, action.EndActionID)
+	case d_outbox.TRANSFER_TO_MENU:
+		return executor.TransferToMenu(action.ChatID, action.Transfer, action.Message)
+	}
+	return adapter_output.ErrUnsupported
+}
+
+// Run dispatches the outbox every Interval, and whenever actions are queued,
+// until ctx is cancelled.
+func (e *Executor) Run(ctx context.Context) {
+	ticker := time.NewTicker(e.outbox.options.Interval)
+	defer ticker.Stop()
+
+	for {
+		e.Dispatch(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-e.outbox.wake:
+		}
+	}
+}
+
+// Dispatch performs the due actions of the outbox once and returns how many
+// were delivered. Each chat's actions are performed in order: after a failure,
+// the chat's later actions wait for the next dispatch. Expired actions and
+// actions failing with a non-retryable error are dropped.
+func (e *Executor) Dispatch(ctx context.Context) int {
+	e.outbox.dispatching.Lock()
+	defer e.outbox.dispatching.Unlock()
+
+	actions, err := e.outbox.repository.Pending()
+	if err != nil {
+		log.Printf("[ERROR] Failed to read the outbox: %v", err)
+		return 0
+	}
+
+	options := e.outbox.options
+	blocked := make(map[d_user.ChatID]bool)
+	delivered := 0
+
+	for _, action := range actions {
+		if ctx.Err() != nil {
+			break
+		}
+		if blocked[action.ChatID] {
+			continue
+		}
+
+		now := options.Now()
+		if action.Expired(now, options.TTL) {
+			log.Printf("[ERROR] Dropping expired %s for chat %v after %d attempts: %s",
+				action.Type, action.ChatID, action.Attempts, action.LastError)
+			e.remove(action)
+			e.outbox.expired.Add(1)
+			continue
+		}
+		if !action.Due(now) {
+			blocked[action.ChatID] = true
+			continue
+		}
+
+		err := apply(e.bindKey(ctx, action), action)
+		switch {
+		case err == nil:
+			e.remove(action)
+			e.outbox.delivered.Add(1)
+			delivered++
+
+		case !options.Retryable(err):
+			log.Printf("[ERROR] Dropping %s for chat %v: %v", action.Type, action.ChatID, err)
+			e.remove(action)
+			e.outbox.dropped.Add(1)
+
+		default:
+			action.Attempts++
+			action.LastError = err.Error()
+			action.NextAttempt = now.Add(options.retryDelay(action.Attempts))
+			if err := e.outbox.repository.Update(action); err != nil {
+				log.Printf("[ERROR] Failed to update the outbox for chat %v: %v", action.ChatID, err)
+			}
+			blocked[action.ChatID] = true
+		}
+	}
+	return delivered
+}
+
+// remove deletes an action from the outbox.
+func (e *Executor) remove(action d_outbox.Action) {
+	if err := e.outbox.repository.Delete(action.ID); err != nil {
+		log.Printf("[ERROR] Failed to delete %s for chat %v from the outbox: %v", action.Type, action.ChatID, err)
+	}
+}
+
+// Metrics returns a snapshot of the outbox backlog and counters.
+func (e *Executor) Metrics() Metrics {
+	metrics := Metrics{
+		Queued:    e.outbox.queued.Load(),
+		Delivered: e.outbox.delivered.Load(),
+		Expired:   e.outbox.expired.Load(),
+		Dropped:   e.outbox.dropped.Load(),
+	}
+
+	actions, err := e.outbox.repository.Pending()
+	if err != nil {
+		log.Printf("[ERROR] Failed to read the outbox: %v", err)
+		return metrics
+	}
+
+	chats := make(map[d_user.ChatID]bool)
+	for _, action := range actions {
+		chats[action.ChatID] = true
+	}
+	metrics.Pending = len(actions)
+	metrics.Chats = len(chats)
+	if len(actions) > 0 {
+		metrics.OldestAge = e.outbox.options.Now().Sub(actions[0].CreatedAt)
+	}
+	return metrics
+}
+
+// SendMessage sends the message, or queues it.
+func (e *Executor) SendMessage(to d_user.ChatID, message d_message.Message, platform string) error {
+	return e.perform(d_outbox.Action{Type: d_outbox.SEND_MESSAGE, ChatID: to, Message: message, Platform: platform})
+}
+
+// SetObservation stores the observation, or queues it.
+func (e *Executor) SetObservation(chatID d_user.ChatID, observation string) error {
+	return e.perform(d_outbox.Action{Type: d_outbox.SET_OBSERVATION, ChatID: chatID, Observation: observation})
+}
+
+// SetRoute stores the route, or queues it.
+func (e *Executor) SetRoute(chatID d_user.ChatID, route string) error {
+	return e.perform(d_outbox.Action{Type: d_outbox.SET_ROUTE, ChatID: chatID, Route: route})
+}
+
+// EndSession ends the session, or queues it.
+func (e *Executor) EndSession(chatID d_user.ChatID, actionId string) error {
+	return e.perform(d_outbox.Action{Type: d_outbox.END_SESSION, ChatID: chatID, EndActionID: actionId})
+}
+
+// TransferToMenu transfers the chat, or queues it.
+func (e *Executor) TransferToMenu(chatID d_user.ChatID, transfer d_action.TransferToMenu, message d_message.Message) error {
+	return e.perform(d_outbox.Action{Type: d_outbox.TRANSFER_TO_MENU, ChatID: chatID, Transfer: transfer, Message: message})
+}
+
+// UploadFile uploads the file. File operations are not queued.
+func (e *Executor) UploadFile(filepath string) (*d_file.File, error) {
+	return e.bind(e.context()).UploadFile(filepath)
+}
+
+// GetFile retrieves the file.
+func (e *Executor) GetFile(fileID string) (*d_file.File, error) {
+	return e.bind(e.context()).GetFile(fileID)
+}
+
+// SendTyping shows a typing indicator if the wrapped executor supports it.
+func (e *Executor) SendTyping(chatID d_user.ChatID, platform string) error {
+	notifier, ok := e.bind(e.context()).(adapter_output.ITypingNotifier)
+	if !ok {
+		return adapter_output.ErrUnsupported
+	}
+	return notifier.SendTyping(chatID, platform)
+}
+
+// EditMessage edits a sent message if the wrapped executor supports it.
+// Edits are not queued.
+func (e *Executor) EditMessage(chatID d_user.ChatID, messageID string, message d_message.Message, platform string) error {
+	editor, ok := e.bind(e.context()).(adapter_output.IMessageEditor)
+	if !ok {
+		return adapter_output.ErrUnsupported
+	}
+	return editor.EditMessage(chatID, messageID, message, platform)
+}
+
+// React reacts to a message if the wrapped executor supports it.
+// Reactions are not queued.
+func (e *Executor) React(chatID d_user.ChatID, messageID string, reaction string, platform string) error {
+	reactor, ok := e.bind(e.context()).(adapter_output.IReactor)
+	if !ok {
+		return adapter_output.ErrUnsupported
+	}
+	return reactor.React(chatID, messageID, reaction, platform)
+}
+
+// LoadState returns the stored state of a chat if the wrapped executor keeps
+// it. The chat's actions still in the outbox are applied on top of it, so it
+// is not older than what the chat's handlers already did: route changes are
+// appended to the route history, observations replace the stored one,
+// transfers set the menu and route, and a session end starts a new session
+// whose route is left empty, since the store's start route is not known.
+func (e *Executor) LoadState(chatID d_user.ChatID) (d_session.Session, bool, error) {
+	loader, ok := e.executor.(adapter_output.IStateLoader)
+	if !ok {
+		return d_session.Session{}, false, adapter_output.ErrUnsupported
+	}
+	session, found, err := loader.LoadState(chatID)
+	if err != nil {
+		return session, found, err
+	}
+
+	pending, err := e.outbox.repository.HasPending(chatID)
+	if err != nil || !pending {
+		return session, found, err
+	}
+	actions, err := e.outbox.repository.Pending()
+	if err != nil {
+		return session, found, err
+	}
+	for _, action := range actions {
+		if action.ChatID != chatID || action.Type == d_outbox.SEND_MESSAGE {
+			continue
+		}
+		if !found {
+			session = d_session.Session{ChatID: chatID}
+			found = true
+		}
+		applyQueued(&session, action)
+	}
+	return session, found, nil
+}
+
+// applyQueued applies a queued action to a stored session.
+func applyQueued(session *d_session.Session, action d_outbox.Action) {
+	switch action.Type {
+	case d_outbox.SET_ROUTE:
+		session.AppendRoute(action.Route)
+	case d_outbox.SET_OBSERVATION:
+		session.Observation = action.Observation
+	case d_outbox.TRANSFER_TO_MENU:
+		session.Menu = d_user.Menu{ID: action.Transfer.MenuID}
+		session.Route = action.Transfer.Route
+	case d_outbox.END_SESSION:
+		*session = session.Next("", action.CreatedAt)
+	}
+}
+
+// Available reports whether the wrapped executor is available.
+func (e *Executor) Available() bool {
+	reporter, ok := e.executor.(adapter_output.IAvailabilityReporter)
+	return !ok || reporter.Available()
+}
@@ -0,0 +1,360 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/irissonnlima/chatgraph-go/core/clocktest"
+	d_action "github.com/irissonnlima/chatgraph-go/core/domain/action"
+	d_file "github.com/irissonnlima/chatgraph-go/core/domain/file"
+	d_idempotency "github.com/irissonnlima/chatgraph-go/core/domain/idempotency"
+	d_message "github.com/irissonnlima/chatgraph-go/core/domain/message"
+	d_session "github.com/irissonnlima/chatgraph-go/core/domain/session"
+	d_user "github.com/irissonnlima/chatgraph-go/core/domain/user"
+	adapter_output "github.com/irissonnlima/chatgraph-go/core/ports/adapters/output"
+)
+
+var (
+	chatA = d_user.ChatID{UserID: "a", CompanyID: "c1"}
+	chatB = d_user.ChatID{UserID: "b", CompanyID: "c1"}
+
+	errDown = errors.New("connection refused")
+)
+
+// permanentError is an error that must not be retried.
+type permanentError struct{}
+
+func (permanentError) Error() string   { return "invalid request" }
+func (permanentError) Retryable() bool { return false }
+
+// backend records the actions it performs and fails while err is set.
+type backend struct {
+	mu    *sync.Mutex
+	ctx   context.Context
+	calls *[]string
+	keys  *[]string
+	err   *error
+}
+
+func newBackend() *backend {
+	var err error
+	return &backend{mu: &sync.Mutex{}, ctx: context.Background(), calls: &[]string{}, keys: &[]string{}, err: &err}
+}
+
+func (b *backend) WithContext(ctx context.Context) adapter_output.IBotExecutor {
+	bound := *b
+	bound.ctx = ctx
+	return &bound
+}
+
+func (b *backend) fail(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	*b.err = err
+}
+
+func (b *backend) performed() ([]string, []string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]string(nil), *b.calls...), append([]string(nil), *b.keys...)
+}
+
+func (b *backend) record(call string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if *b.err != nil {
+		return *b.err
+	}
+	*b.calls = append(*b.calls, call)
+	*b.keys = append(*b.keys, d_idempotency.ActionKey(b.ctx))
+	return nil
+}
+
+func (b *backend) SendMessage(to d_user.ChatID, message d_message.Message, _ string) error {
+	return b.record(fmt.Sprintf("%s:send:%s", to.UserID, message.TextMessage.Detail))
+}
+func (b *backend) SetObservation(chatID d_user.ChatID, observation string) error {
+	return b.record(fmt.Sprintf("%s:observation:%s", chatID.UserID, observation))
+}
+func (b *backend) SetRoute(chatID d_user.ChatID, route string) error {
+	return b.record(fmt.Sprintf("%s:route:%s", chatID.UserID, route))
+}
+func (b *backend) EndSession(chatID d_user.ChatID, actionID string) error {
+	return b.record(fmt.Sprintf("%s:end:%s", chatID.UserID, actionID))
+}
+func (b *backend) TransferToMenu(chatID d_user.ChatID, transfer d_action.TransferToMenu, _ d_message.Message) error {
+	return b.record(fmt.Sprintf("%s:transfer:%d", chatID.UserID, transfer.MenuID))
+}
+func (b *backend) UploadFile(string) (*d_file.File, error) { return &d_file.File{}, nil }
+func (b *backend) GetFile(string) (*d_file.File, error)    { return &d_file.File{}, nil }
+
+func text(detail string) d_message.Message {
+	return d_message.Message{TextMessage: d_message.TextMessage{Detail: detail}}
+}
+
+func newExecutor(inner adapter_output.IBotExecutor) (*Executor, *clocktest.FakeClock) {
+	clock := clocktest.NewFakeClock(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+	return NewExecutor(inner, NewMemoryRepository(), OutboxOptions{Now: clock.Now}), clock
+}
+
+func equal(a, b []string) bool {
+	return fmt.Sprint(a) == fmt.Sprint(b)
+}
+
+func TestExecutor_PassesThroughWhenAvailable(t *testing.T) {
+	inner := newBackend()
+	executor, _ := newExecutor(inner)
+
+	if err := executor.SendMessage(chatA, text("hi"), ""); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if err := executor.SetRoute(chatA, "start.menu"); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	calls, _ := inner.performed()
+	if !equal(calls, []string{"a:send:hi", "a:route:start.menu"}) {
+		t.Errorf("calls = %v", calls)
+	}
+	if metrics := executor.Metrics(); metrics.Pending != 0 || metrics.Queued != 0 {
+		t.Errorf("expected an empty outbox, got %+v", metrics)
+	}
+}
+
+func TestExecutor_QueuesAndDeliversInOrderPerChat(t *testing.T) {
+	inner := newBackend()
+	executor, clock := newExecutor(inner)
+
+	inner.fail(errDown)
+	if err := executor.SendMessage(chatA, text("1"), ""); err != nil {
+		t.Fatalf("retryable failures should be queued, got %v", err)
+	}
+	inner.fail(nil)
+
+	// chat a is behind its queued message, chat b is not.
+	executor.SetObservation(chatA, "obs")
+	executor.SetRoute(chatA, "next")
+	executor.SendMessage(chatB, text("b"), "")
+
+	calls, _ := inner.performed()
+	if !equal(calls, []string{"b:send:b"}) {
+		t.Fatalf("expected only chat b to go through, got %v", calls)
+	}
+	if metrics := executor.Metrics(); metrics.Pending != 3 || metrics.Chats != 1 || metrics.Queued != 3 {
+		t.Errorf("metrics = %+v", metrics)
+	}
+
+	// The failed message waits for its retry delay, keeping the chat blocked.
+	if delivered := executor.Dispatch(context.Background()); delivered != 0 {
+		t.Errorf("expected nothing to be due, delivered %d", delivered)
+	}
+
+	clock.Advance(DEFAULT_RETRY_BASE_DELAY)
+	if delivered := executor.Dispatch(context.Background()); delivered != 3 {
+		t.Errorf("delivered = %d, want 3", delivered)
+	}
+
+	calls, _ = inner.performed()
+	want := []string{"b:send:b", "a:send:1", "a:observation:obs", "a:route:next"}
+	if !equal(calls, want) {
+		t.Errorf("calls = %v, want %v", calls, want)
+	}
+	if metrics := executor.Metrics(); metrics.Pending != 0 || metrics.Delivered != 3 {
+		t.Errorf("metrics = %+v", metrics)
+	}
+}
+
+func TestExecutor_ReturnsPermanentErrors(t *testing.T) {
+	inner := newBackend()
+	executor, _ := newExecutor(inner)
+
+	inner.fail(permanentError{})
+	if err := executor.SendMessage(chatA, text("1"), ""); !errors.As(err, &permanentError{}) {
+		t.Fatalf("expected the permanent error, got %v", err)
+	}
+	if metrics := executor.Metrics(); metrics.Pending != 0 {
+		t.Errorf("permanent failures should not be queued, got %+v", metrics)
+	}
+}
+
+func TestExecutor_BacksOffAndKeepsOrder(t *testing.T) {
+	inner := newBackend()
+	executor, clock := newExecutor(inner)
+
+	inner.fail(errDown)
+	executor.SendMessage(chatA, text("1"), "")
+	executor.SendMessage(chatA, text("2"), "")
+
+	clock.Advance(DEFAULT_RETRY_BASE_DELAY)
+	executor.Dispatch(context.Background())
+
+	actions, _ := executor.outbox.repository.Pending()
+	if actions[0].Attempts != 2 || actions[0].LastError != errDown.Error() {
+		t.Errorf("first action = %+v", actions[0])
+	}
+	if actions[1].Attempts != 0 {
+		t.Errorf("later actions should not be attempted before the first, got %+v", actions[1])
+	}
+	if got, want := actions[0].NextAttempt, clock.Now().Add(2*DEFAULT_RETRY_BASE_DELAY); !got.Equal(want) {
+		t.Errorf("NextAttempt = %v, want %v", got, want)
+	}
+
+	inner.fail(nil)
+	clock.Advance(2 * DEFAULT_RETRY_BASE_DELAY)
+	executor.Dispatch(context.Background())
+
+	if calls, _ := inner.performed(); !equal(calls, []string{"a:send:1", "a:send:2"}) {
+		t.Errorf("calls = %v", calls)
+	}
+}
+
+func TestExecutor_DropsExpiredAndPermanentlyFailingActions(t *testing.T) {
+	inner := newBackend()
+	executor, clock := newExecutor(inner)
+
+	inner.fail(errDown)
+	executor.SendMessage(chatA, text("old"), "")
+	clock.Advance(DEFAULT_TTL / 2)
+	executor.SendMessage(chatA, text("invalid"), "")
+	clock.Advance(DEFAULT_TTL / 2)
+
+	if age := executor.Metrics().OldestAge; age != DEFAULT_TTL {
+		t.Errorf("OldestAge = %v, want %v", age, DEFAULT_TTL)
+	}
+
+	// The expired message is dropped, the invalid one fails permanently.
+	inner.fail(permanentError{})
+	executor.Dispatch(context.Background())
+
+	// The chat is no longer blocked.
+	inner.fail(nil)
+	if err := executor.SendMessage(chatA, text("new"), ""); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if calls, _ := inner.performed(); !equal(calls, []string{"a:send:new"}) {
+		t.Errorf("calls = %v", calls)
+	}
+	metrics := executor.Metrics()
+	if metrics.Expired != 1 || metrics.Dropped != 1 || metrics.Pending != 0 {
+		t.Errorf("metrics = %+v", metrics)
+	}
+}
+
+func TestExecutor_ReusesIdempotencyKeys(t *testing.T) {
+	inner := newBackend()
+	executor, clock := newExecutor(inner)
+
+	ctx := d_idempotency.WithScope(context.Background(), d_idempotency.NewScope(chatA, "m1", "start"))
+	bound := executor.WithContext(ctx)
+
+	inner.fail(errDown)
+	bound.SendMessage(chatA, text("1"), "")
+	inner.fail(nil)
+	bound.SetRoute(chatA, "next")
+
+	clock.Advance(DEFAULT_RETRY_BASE_DELAY)
+	executor.Dispatch(context.Background())
+
+	_, keys := inner.performed()
+	want := []string{
+		d_idempotency.Key(chatA, "m1", "start", 1),
+		d_idempotency.Key(chatA, "m1", "start", 2),
+	}
+	if !equal(keys, want) {
+		t.Errorf("keys = %v, want %v", keys, want)
+	}
+}
+
+func TestExecutor_RunDeliversQueuedActions(t *testing.T) {
+	inner := newBackend()
+	executor := NewExecutor(inner, NewMemoryRepository(), OutboxOptions{
+		Interval:       10 * time.Millisecond,
+		RetryBaseDelay: time.Millisecond,
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		executor.Run(ctx)
+		close(done)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	inner.fail(errDown)
+	executor.SendMessage(chatA, text("1"), "")
+	inner.fail(nil)
+
+	deadline := time.Now().Add(2 * time.Second)
+	for executor.Metrics().Pending > 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("the dispatcher did not deliver the queued action")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if calls, _ := inner.performed(); !equal(calls, []string{"a:send:1"}) {
+		t.Errorf("calls = %v", calls)
+	}
+}
+
+func TestRetryable(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{errDown, true},
+		{fmt.Errorf("wrapped: %w", permanentError{}), false},
+		{adapter_output.ErrUnsupported, false},
+	}
+	for _, tt := range tests {
+		if got := Retryable(tt.err); got != tt.want {
+			t.Errorf("Retryable(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
+
+// stateBackend is a backend that keeps a session for chat a.
+type stateBackend struct {
+	*backend
+	session d_session.Session
+}
+
+func (b *stateBackend) LoadState(chatID d_user.ChatID) (d_session.Session, bool, error) {
+	return b.session, chatID == b.session.ChatID, nil
+}
+
+func TestExecutor_LoadStateAppliesQueuedActions(t *testing.T) {
+	inner := &stateBackend{backend: newBackend(), session: d_session.Session{
+		ID: 1, ChatID: chatA, Route: "start", Observation: `{"step":1}`,
+	}}
+	executor, _ := newExecutor(inner)
+
+	inner.fail(errDown)
+	executor.SetRoute(chatA, "menu")
+	executor.SetObservation(chatA, `{"step":2}`)
+	executor.SendMessage(chatA, text("1"), "")
+
+	session, found, err := executor.LoadState(chatA)
+	if err != nil || !found {
+		t.Fatalf("LoadState() = %v, %v", found, err)
+	}
+	if session.Route != "start.menu" || session.Observation != `{"step":2}` {
+		t.Errorf("expected the queued route and observation, got %+v", session)
+	}
+
+	executor.EndSession(chatA, "done")
+	executor.SetRoute(chatA, "start")
+	if session, _, _ := executor.LoadState(chatA); session.ID != 2 || session.Route != "start" || session.Observation != "" {
+		t.Errorf("expected a new session after the queued end, got %+v", session)
+	}
+
+	if session, found, _ := executor.LoadState(chatB); found {
+		t.Errorf("expected no state for chat b, got %+v", session)
+	}
+}
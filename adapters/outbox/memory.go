@@ -0,0 +1,88 @@
+package outbox
+
+import (
+	"sync"
+
+	d_outbox "github.com/irissonnlima/chatgraph-go/core/domain/outbox"
+	d_user "github.com/irissonnlima/chatgraph-go/core/domain/user"
+	adapter_output "github.com/irissonnlima/chatgraph-go/core/ports/adapters/output"
+)
+
+var _ adapter_output.IOutboxRepository = (*MemoryRepository)(nil)
+
+// MemoryRepository is an IOutboxRepository kept in memory. Actions are lost
+// when the process stops; use boltstore for a durable outbox.
+type MemoryRepository struct {
+	mu      sync.Mutex
+	nextID  uint64
+	actions []d_outbox.Action
+}
+
+// NewMemoryRepository creates an empty in-memory outbox.
+func NewMemoryRepository() *MemoryRepository {
+	return &MemoryRepository{}
+}
+
+// Append stores an action with the next ID.
+func (r *MemoryRepository) Append(action d_outbox.Action) (d_outbox.Action, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.nextID++
+	action.ID = r.nextID
+	r.actions = append(r.actions, action)
+	return action, nil
+}
+
+// Pending returns every stored action, ordered by ID.
+func (r *MemoryRepository) Pending() ([]d_outbox.Action, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]d_outbox.Action(nil), r.actions...), nil
+}
+
+// HasPending reports whether a chat has stored actions.
+func (r *MemoryRepository) HasPending(chatID d_user.ChatID) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, action := range r.actions {
+		if action.ChatID == chatID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// Update replaces a stored action.
+func (r *MemoryRepository) Update(action d_outbox.Action) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i := range r.actions {
+		if r.actions[i].ID == action.ID {
+			r.actions[i] = action
+			return nil
+		}
+	}
+	return nil
+}
+
+// Delete removes an action.
+func (r *MemoryRepository) Delete(id uint64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i := range r.actions {
+		if r.actions[i].ID == id {
+			r.actions = append(r.actions[:i], r.actions[i+1:]...)
+			return nil
+		}
+	}
+	return nil
+}
+
+// Close does nothing.
+func (r *MemoryRepository) Close() error {
+	return nil
+}
@@ -0,0 +1,88 @@
+package outbox
+
+import (
+	"errors"
+	"time"
+
+	adapter_output "github.com/irissonnlima/chatgraph-go/core/ports/adapters/output"
+)
+
+// Default values of OutboxOptions.
+const (
+	// DEFAULT_INTERVAL is how often the dispatcher looks for due actions.
+	DEFAULT_INTERVAL = 5 * time.Second
+	// DEFAULT_RETRY_BASE_DELAY is the delay after the first failed attempt.
+	DEFAULT_RETRY_BASE_DELAY = time.Second
+	// DEFAULT_RETRY_MAX_DELAY caps the delay between attempts.
+	DEFAULT_RETRY_MAX_DELAY = 5 * time.Minute
+	// DEFAULT_TTL is how long an action is retried before it expires.
+	DEFAULT_TTL = 24 * time.Hour
+)
+
+// OutboxOptions configures the outbox executor and its dispatcher.
+type OutboxOptions struct {
+	// Interval is how often the dispatcher looks for due actions. New actions
+	// wake it up earlier. Defaults to DEFAULT_INTERVAL.
+	Interval time.Duration
+	// RetryBaseDelay is the delay after the first failed attempt, doubled
+	// after each one. Defaults to DEFAULT_RETRY_BASE_DELAY.
+	RetryBaseDelay time.Duration
+	// RetryMaxDelay caps the delay between attempts. Defaults to DEFAULT_RETRY_MAX_DELAY.
+	RetryMaxDelay time.Duration
+	// TTL is how long an action is retried. Expired actions are dropped, so
+	// the chat's later actions can go through. Defaults to DEFAULT_TTL.
+	TTL time.Duration
+	// Retryable tells whether a failed action should be kept in the outbox.
+	// Defaults to Retryable.
+	Retryable func(err error) bool
+	// Now returns the current time. Defaults to time.Now.
+	Now func() time.Time
+}
+
+// withDefaults returns a copy of the options with defaults applied.
+func (o OutboxOptions) withDefaults() OutboxOptions {
+	if o.Interval <= 0 {
+		o.Interval = DEFAULT_INTERVAL
+	}
+	if o.RetryBaseDelay <= 0 {
+		o.RetryBaseDelay = DEFAULT_RETRY_BASE_DELAY
+	}
+	if o.RetryMaxDelay <= 0 {
+		o.RetryMaxDelay = DEFAULT_RETRY_MAX_DELAY
+	}
+	if o.TTL <= 0 {
+		o.TTL = DEFAULT_TTL
+	}
+	if o.Retryable == nil {
+		o.Retryable = Retryable
+	}
+	if o.Now == nil {
+		o.Now = time.Now
+	}
+	return o
+}
+
+// retryDelay returns the delay before the next attempt of an action that
+// failed attempts times.
+func (o OutboxOptions) retryDelay(attempts int) time.Duration {
+	delay := o.RetryBaseDelay
+	for i := 1; i < attempts && delay < o.RetryMaxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, o.RetryMaxDelay)
+}
+
+// Retryable is the default classification of failed actions. Errors
+// implementing adapter_output.IRetryableError tell for themselves, e.g. a
+// Router API validation error is not retried; unsupported operations are not
+// retried; any other error, such as a network failure, is.
+func Retryable(err error) bool {
+	if errors.Is(err, adapter_output.ErrUnsupported) {
+		return false
+	}
+	var retryable adapter_output.IRetryableError
+	if errors.As(err, &retryable) {
+		return retryable.Retryable()
+	}
+	return true
+}
@@ -0,0 +1,185 @@
+// Package output_compose builds an IBotExecutor out of focused ports, so each
+// concern (messages, sessions, menu transfers, files) can come from a
+// different adapter.
+package output_compose
+
+import (
+	"context"
+
+	d_action "github.com/irissonnlima/chatgraph-go/core/domain/action"
+	d_file "github.com/irissonnlima/chatgraph-go/core/domain/file"
+	d_message "github.com/irissonnlima/chatgraph-go/core/domain/message"
+	d_session "github.com/irissonnlima/chatgraph-go/core/domain/session"
+	d_user "github.com/irissonnlima/chatgraph-go/core/domain/user"
+	adapter_output "github.com/irissonnlima/chatgraph-go/core/ports/adapters/output"
+)
+
+// Parts holds the ports an Executor delegates to.
+// Operations of a nil port return adapter_output.ErrUnsupported.
+type Parts struct {
+	// Messenger sends messages. Its optional capabilities (typing, edits,
+	// reactions) are exposed by the Executor.
+	Messenger adapter_output.IMessenger
+	// Sessions keeps routes, observations and session ends.
+	Sessions adapter_output.ISessionStore
+	// Transferer transfers chats to other menus.
+	Transferer adapter_output.ITransferer
+	// Files uploads and retrieves files.
+	Files adapter_output.IFileStore
+}
+
+// Executor is an IBotExecutor that delegates each operation to one of its parts.
+type Executor struct {
+	parts Parts
+}
+
+// NewExecutor creates an executor from its parts.
+func NewExecutor(parts Parts) *Executor {
+	return &Executor{parts: parts}
+}
+
+// NewExecutorFrom creates an executor whose parts all come from executor,
+// replacing the ones set in overrides. It adapts an existing IBotExecutor,
+// such as the Router API, to a mix of adapters.
+func NewExecutorFrom(executor adapter_output.IBotExecutor, overrides Parts) *Executor {
+	parts := Parts{
+		Messenger:  executor,
+		Sessions:   executor,
+		Transferer: executor,
+		Files:      executor,
+	}
+	if overrides.Messenger != nil {
+		parts.Messenger = overrides.Messenger
+	}
+	if overrides.Sessions != nil {
+		parts.Sessions = overrides.Sessions
+	}
+	if overrides.Transferer != nil {
+		parts.Transferer = overrides.Transferer
+	}
+	if overrides.Files != nil {
+		parts.Files = overrides.Files
+	}
+	return NewExecutor(parts)
+}
+
+// WithContext returns an executor whose parts are bound to ctx, for the parts
+// that support it.
+func (e *Executor) WithContext(ctx context.Context) adapter_output.IBotExecutor {
+	parts := e.parts
+	if binder, ok := parts.Messenger.(adapter_output.IContextBinder); ok {
+		parts.Messenger = binder.WithContext(ctx)
+	}
+	if binder, ok := parts.Sessions.(adapter_output.IContextBinder); ok {
+		parts.Sessions = binder.WithContext(ctx)
+	}
+	if binder, ok := parts.Transferer.(adapter_output.IContextBinder); ok {
+		parts.Transferer = binder.WithContext(ctx)
+	}
+	if binder, ok := parts.Files.(adapter_output.IContextBinder); ok {
+		parts.Files = binder.WithContext(ctx)
+	}
+	return NewExecutor(parts)
+}
+
+// SendMessage sends the message through the messenger.
+func (e *Executor) SendMessage(to d_user.ChatID, message d_message.Message, platform string) error {
+	if e.parts.Messenger == nil {
+		return adapter_output.ErrUnsupported
+	}
+	return e.parts.Messenger.SendMessage(to, message, platform)
+}
+
+// SetObservation stores the observation through the session store.
+func (e *Executor) SetObservation(chatID d_user.ChatID, observation string) error {
+	if e.parts.Sessions == nil {
+		return adapter_output.ErrUnsupported
+	}
+	return e.parts.Sessions.SetObservation(chatID, observation)
+}
+
+// SetRoute stores the route through the session store.
+func (e *Executor) SetRoute(chatID d_user.ChatID, route string) error {
+	if e.parts.Sessions == nil {
+		return adapter_output.ErrUnsupported
+	}
+	return e.parts.Sessions.SetRoute(chatID, route)
+}
+
+// EndSession ends the session through the session store.
+func (e *Executor) EndSession(chatID d_user.ChatID, actionId string) error {
+	if e.parts.Sessions == nil {
+		return adapter_output.ErrUnsupported
+	}
+	return e.parts.Sessions.EndSession(chatID, actionId)
+}
+
+// TransferToMenu transfers the chat through the transferer.
+func (e *Executor) TransferToMenu(chatID d_user.ChatID, transfer d_action.TransferToMenu, message d_message.Message) error {
+	if e.parts.Transferer == nil {
+		return adapter_output.ErrUnsupported
+	}
+	return e.parts.Transferer.TransferToMenu(chatID, transfer, message)
+}
+
+// UploadFile uploads the file through the file store.
+func (e *Executor) UploadFile(filepath string) (*d_file.File, error) {
+	if e.parts.Files == nil {
+		return nil, adapter_output.ErrUnsupported
+	}
+	return e.parts.Files.UploadFile(filepath)
+}
+
+// GetFile retrieves the file through the file store.
+func (e *Executor) GetFile(fileID string) (*d_file.File, error) {
+	if e.parts.Files == nil {
+		return nil, adapter_output.ErrUnsupported
+	}
+	return e.parts.Files.GetFile(fileID)
+}
+
+// SendTyping shows a typing indicator if the messenger supports it.
+func (e *Executor) SendTyping(chatID d_user.ChatID, platform string) error {
+	notifier, ok := e.parts.Messenger.(adapter_output.ITypingNotifier)
+	if !ok {
+		return adapter_output.ErrUnsupported
+	}
+	return notifier.SendTyping(chatID, platform)
+}
+
+// EditMessage edits a sent message if the messenger supports it.
+func (e *Executor) EditMessage(chatID d_user.ChatID, messageID string, message d_message.Message, platform string) error {
+	editor, ok := e.parts.Messenger.(adapter_output.IMessageEditor)
+	if !ok {
+		return adapter_output.ErrUnsupported
+	}
+	return editor.EditMessage(chatID, messageID, message, platform)
+}
+
+// React reacts to a message if the messenger supports it.
+func (e *Executor) React(chatID d_user.ChatID, messageID string, reaction string, platform string) error {
+	reactor, ok := e.parts.Messenger.(adapter_output.IReactor)
+	if !ok {
+		return adapter_output.ErrUnsupported
+	}
+	return reactor.React(chatID, messageID, reaction, platform)
+}
+
+// LoadState returns the stored state of a chat if the session store keeps it.
+func (e *Executor) LoadState(chatID d_user.ChatID) (d_session.Session, bool, error) {
+	loader, ok := e.parts.Sessions.(adapter_output.IStateLoader)
+	if !ok {
+		return d_session.Session{}, false, adapter_output.ErrUnsupported
+	}
+	return loader.LoadState(chatID)
+}
+
+// Available reports whether every part that can tell is available.
+func (e *Executor) Available() bool {
+	for _, part := range []any{e.parts.Messenger, e.parts.Sessions, e.parts.Transferer, e.parts.Files} {
+		if reporter, ok := part.(adapter_output.IAvailabilityReporter); ok && !reporter.Available() {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,123 @@
+package output_compose
+
+import (
+	"errors"
+	"testing"
+
+	d_action "github.com/irissonnlima/chatgraph-go/core/domain/action"
+	d_file "github.com/irissonnlima/chatgraph-go/core/domain/file"
+	d_message "github.com/irissonnlima/chatgraph-go/core/domain/message"
+	d_user "github.com/irissonnlima/chatgraph-go/core/domain/user"
+	adapter_output "github.com/irissonnlima/chatgraph-go/core/ports/adapters/output"
+)
+
+// recorder implements every port and records the calls it receives.
+type recorder struct {
+	calls []string
+}
+
+func (r *recorder) record(call string) error {
+	r.calls = append(r.calls, call)
+	return nil
+}
+
+func (r *recorder) SendMessage(d_user.ChatID, d_message.Message, string) error {
+	return r.record("SendMessage")
+}
+func (r *recorder) SetObservation(d_user.ChatID, string) error { return r.record("SetObservation") }
+func (r *recorder) SetRoute(d_user.ChatID, string) error       { return r.record("SetRoute") }
+func (r *recorder) EndSession(d_user.ChatID, string) error     { return r.record("EndSession") }
+func (r *recorder) TransferToMenu(d_user.ChatID, d_action.TransferToMenu, d_message.Message) error {
+	return r.record("TransferToMenu")
+}
+func (r *recorder) UploadFile(string) (*d_file.File, error) {
+	return &d_file.File{}, r.record("UploadFile")
+}
+func (r *recorder) GetFile(string) (*d_file.File, error) {
+	return &d_file.File{}, r.record("GetFile")
+}
+
+// typingRecorder also supports typing indicators.
+type typingRecorder struct {
+	recorder
+}
+
+func (r *typingRecorder) SendTyping(d_user.ChatID, string) error { return r.record("SendTyping") }
+
+// callAll calls every executor operation.
+func callAll(e *Executor) []error {
+	chatID := d_user.ChatID{UserID: "u1", CompanyID: "c1"}
+	_, uploadErr := e.UploadFile("f")
+	_, getErr := e.GetFile("f")
+	return []error{
+		e.SendMessage(chatID, d_message.Message{}, ""),
+		e.SetObservation(chatID, "{}"),
+		e.SetRoute(chatID, "start"),
+		e.EndSession(chatID, "end"),
+		e.TransferToMenu(chatID, d_action.TransferToMenu{}, d_message.Message{}),
+		uploadErr,
+		getErr,
+	}
+}
+
+func TestExecutor_DelegatesToParts(t *testing.T) {
+	messenger, sessions, transferer, files := &recorder{}, &recorder{}, &recorder{}, &recorder{}
+	e := NewExecutor(Parts{Messenger: messenger, Sessions: sessions, Transferer: transferer, Files: files})
+
+	for _, err := range callAll(e) {
+		if err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+	}
+
+	expected := map[*recorder]int{messenger: 1, sessions: 3, transferer: 1, files: 2}
+	for part, n := range expected {
+		if len(part.calls) != n {
+			t.Errorf("expected %d calls, got %v", n, part.calls)
+		}
+	}
+}
+
+func TestExecutor_MissingParts(t *testing.T) {
+	e := NewExecutor(Parts{})
+
+	for i, err := range callAll(e) {
+		if !errors.Is(err, adapter_output.ErrUnsupported) {
+			t.Errorf("operation %d: expected ErrUnsupported, got %v", i, err)
+		}
+	}
+}
+
+func TestNewExecutorFrom(t *testing.T) {
+	base, sessions := &recorder{}, &recorder{}
+	e := NewExecutorFrom(base, Parts{Sessions: sessions})
+
+	callAll(e)
+
+	if len(base.calls) != 4 {
+		t.Errorf("expected the base executor to serve 4 calls, got %v", base.calls)
+	}
+	if len(sessions.calls) != 3 {
+		t.Errorf("expected the override to serve 3 calls, got %v", sessions.calls)
+	}
+}
+
+func TestExecutor_Capabilities(t *testing.T) {
+	chatID := d_user.ChatID{UserID: "u1", CompanyID: "c1"}
+
+	messenger := &typingRecorder{}
+	e := NewExecutor(Parts{Messenger: messenger})
+
+	if err := e.SendTyping(chatID, ""); err != nil {
+		t.Errorf("SendTyping() error = %v", err)
+	}
+	if len(messenger.calls) != 1 {
+		t.Errorf("expected typing to reach the messenger, got %v", messenger.calls)
+	}
+	if err := e.EditMessage(chatID, "m1", d_message.Message{}, ""); !errors.Is(err, adapter_output.ErrUnsupported) {
+		t.Errorf("expected ErrUnsupported for edits, got %v", err)
+	}
+	if err := e.React(chatID, "m1", "👍", ""); !errors.Is(err, adapter_output.ErrUnsupported) {
+		t.Errorf("expected ErrUnsupported for reactions, got %v", err)
+	}
+}
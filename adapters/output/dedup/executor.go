@@ -0,0 +1,200 @@
+// Package output_dedup suppresses outbound actions that were already
+// performed, so a message processed twice (after a redelivery, or a retry
+// whose first response was lost) does not message the user twice.
+//
+// Actions are recognized by their idempotency key (see d_idempotency): the
+// Executor skips an action whose key is in the store, and records the key
+// once the action succeeds. Actions without a key, such as the ones made for
+// messages without an ID, are always performed.
+//
+//	executor := output_dedup.NewExecutor(routerApi, output_dedup.NewMemoryStore())
+//	app := service.NewChatbotApp(engine, rabbit, executor)
+package output_dedup
+
+import (
+	"context"
+	"log"
+
+	d_action "github.com/irissonnlima/chatgraph-go/core/domain/action"
+	d_file "github.com/irissonnlima/chatgraph-go/core/domain/file"
+	d_idempotency "github.com/irissonnlima/chatgraph-go/core/domain/idempotency"
+	d_message "github.com/irissonnlima/chatgraph-go/core/domain/message"
+	d_session "github.com/irissonnlima/chatgraph-go/core/domain/session"
+	d_user "github.com/irissonnlima/chatgraph-go/core/domain/user"
+	adapter_output "github.com/irissonnlima/chatgraph-go/core/ports/adapters/output"
+)
+
+var (
+	_ adapter_output.IBotExecutor          = (*Executor)(nil)
+	_ adapter_output.IContextBinder        = (*Executor)(nil)
+	_ adapter_output.IAvailabilityReporter = (*Executor)(nil)
+)
+
+// Executor is an IBotExecutor that performs each keyed action of another
+// executor at most once. Messages, edits, reactions, session changes and
+// transfers are deduplicated; typing indicators and file operations, which
+// are harmless to repeat, are passed through.
+//
+// The key is passed on to the wrapped executor, e.g. so the Router API sends
+// the same Idempotency-Key header.
+type Executor struct {
+	executor adapter_output.IBotExecutor
+	store    adapter_output.IDedupStore
+	ctx      context.Context
+}
+
+// NewExecutor wraps executor, recording performed actions in store.
+func NewExecutor(executor adapter_output.IBotExecutor, store adapter_output.IDedupStore) *Executor {
+	return &Executor{executor: executor, store: store}
+}
+
+// WithContext returns an executor bound to ctx, whose idempotency scope
+// numbers the actions.
+func (e *Executor) WithContext(ctx context.Context) adapter_output.IBotExecutor {
+	bound := *e
+	bound.ctx = ctx
+	return &bound
+}
+
+// context returns the context the executor is bound to.
+func (e *Executor) context() context.Context {
+	if e.ctx == nil {
+		return context.Background()
+	}
+	return e.ctx
+}
+
+// bind returns the wrapped executor bound to ctx, when it supports it.
+func (e *Executor) bind(ctx context.Context) adapter_output.IBotExecutor {
+	if binder, ok := e.executor.(adapter_output.IContextBinder); ok {
+		return binder.WithContext(ctx)
+	}
+	return e.executor
+}
+
+// perform runs action once per idempotency key. A failing store does not
+// block the action: it is performed and may be repeated.
+func (e *Executor) perform(name string, chatID d_user.ChatID, action func(executor adapter_output.IBotExecutor) error) error {
+	ctx := e.context()
+	key := d_idempotency.ActionKey(ctx)
+	if key == "" {
+		return action(e.bind(ctx))
+	}
+
+	seen, err := e.store.Seen(key)
+	if err != nil {
+		log.Printf("[WARN] Dedup store lookup failed for chat %v: %v", chatID, err)
+	}
+	if seen {
+		log.Printf("[INFO] Skipping %s for chat %v: already performed (key %s)", name, chatID, key)
+		return nil
+	}
+
+	if err := action(e.bind(d_idempotency.WithKey(ctx, key))); err != nil {
+		return err
+	}
+
+	if err := e.store.Record(key); err != nil {
+		log.Printf("[WARN] Dedup store record failed for chat %v: %v", chatID, err)
+	}
+	return nil
+}
+
+// SendMessage sends the message unless it was already sent.
+func (e *Executor) SendMessage(to d_user.ChatID, message d_message.Message, platform string) error {
+	return e.perform("SendMessage", to, func(executor adapter_output.IBotExecutor) error {
+		return executor.SendMessage(to, message, platform)
+	})
+}
+
+// SetObservation stores the observation unless it was already stored.
+func (e *Executor) SetObservation(chatID d_user.ChatID, observation string) error {
+	return e.perform("SetObservation", chatID, func(executor adapter_output.IBotExecutor) error {
+		return executor.SetObservation(chatID, observation)
+	})
+}
+
+// SetRoute stores the route unless it was already stored.
+func (e *Executor) SetRoute(chatID d_user.ChatID, route string) error {
+	return e.perform("SetRoute", chatID, func(executor adapter_output.IBotExecutor) error {
+		return executor.SetRoute(chatID, route)
+	})
+}
+
+// EndSession ends the session unless it was already ended.
+func (e *Executor) EndSession(chatID d_user.ChatID, actionId string) error {
+	return e.perform("EndSession", chatID, func(executor adapter_output.IBotExecutor) error {
+		return executor.EndSession(chatID, actionId)
+	})
+}
+
+// TransferToMenu transfers the chat unless it was already transferred.
+func (e *Executor) TransferToMenu(chatID d_user.ChatID, transfer d_action.TransferToMenu, message d_message.Message) error {
+	return e.perform("TransferToMenu", chatID, func(executor adapter_output.IBotExecutor) error {
+		return executor.TransferToMenu(chatID, transfer, message)
+	})
+}
+
+// UploadFile uploads the file. Uploads are not deduplicated.
+func (e *Executor) UploadFile(filepath string) (*d_file.File, error) {
+	return e.bind(e.context()).UploadFile(filepath)
+}
+
+// GetFile retrieves the file.
+func (e *Executor) GetFile(fileID string) (*d_file.File, error) {
+	return e.bind(e.context()).GetFile(fileID)
+}
+
+// SendTyping shows a typing indicator if the wrapped executor supports it.
+func (e *Executor) SendTyping(chatID d_user.ChatID, platform string) error {
+	notifier, ok := e.bind(e.context()).(adapter_output.ITypingNotifier)
+	if !ok {
+		return adapter_output.ErrUnsupported
+	}
+	return notifier.SendTyping(chatID, platform)
+}
+
+// EditMessage edits a sent message, if the wrapped executor supports it,
+// unless it was already edited.
+func (e *Executor) EditMessage(chatID d_user.ChatID, messageID string, message d_message.Message, platform string) error {
+	if _, ok := e.executor.(adapter_output.IMessageEditor); !ok {
+		return adapter_output.ErrUnsupported
+	}
+	return e.perform("EditMessage", chatID, func(executor adapter_output.IBotExecutor) error {
+		editor, ok := executor.(adapter_output.IMessageEditor)
+		if !ok {
+			return adapter_output.ErrUnsupported
+		}
+		return editor.EditMessage(chatID, messageID, message, platform)
+	})
+}
+
+// React reacts to a message, if the wrapped executor supports it, unless it
+// already did.
+func (e *Executor) React(chatID d_user.ChatID, messageID string, reaction string, platform string) error {
+	if _, ok := e.executor.(adapter_output.IReactor); !ok {
+		return adapter_output.ErrUnsupported
+	}
+	return e.perform("React", chatID, func(executor adapter_output.IBotExecutor) error {
+		reactor, ok := executor.(adapter_output.IReactor)
+		if !ok {
+			return adapter_output.ErrUnsupported
+		}
+		return reactor.React(chatID, messageID, reaction, platform)
+	})
+}
+
+// LoadState returns the stored state of a chat if the wrapped executor keeps it.
+func (e *Executor) LoadState(chatID d_user.ChatID) (d_session.Session, bool, error) {
+	loader, ok := e.executor.(adapter_output.IStateLoader)
+	if !ok {
+		return d_session.Session{}, false, adapter_output.ErrUnsupported
+	}
+	return loader.LoadState(chatID)
+}
+
+// Available reports whether the wrapped executor is available.
+func (e *Executor) Available() bool {
+	reporter, ok := e.executor.(adapter_output.IAvailabilityReporter)
+	return !ok || reporter.Available()
+}
@@ -0,0 +1,170 @@
+package output_dedup
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	d_action "github.com/irissonnlima/chatgraph-go/core/domain/action"
+	d_file "github.com/irissonnlima/chatgraph-go/core/domain/file"
+	d_idempotency "github.com/irissonnlima/chatgraph-go/core/domain/idempotency"
+	d_message "github.com/irissonnlima/chatgraph-go/core/domain/message"
+	d_user "github.com/irissonnlima/chatgraph-go/core/domain/user"
+	adapter_output "github.com/irissonnlima/chatgraph-go/core/ports/adapters/output"
+)
+
+var chatID = d_user.ChatID{UserID: "u1", CompanyID: "c1"}
+
+// recorder implements every port and records the calls it receives, with the
+// idempotency key of each.
+type recorder struct {
+	ctx   context.Context
+	calls *[]string
+	keys  *[]string
+	err   error
+}
+
+func newRecorder() *recorder {
+	return &recorder{ctx: context.Background(), calls: &[]string{}, keys: &[]string{}}
+}
+
+func (r *recorder) WithContext(ctx context.Context) adapter_output.IBotExecutor {
+	bound := *r
+	bound.ctx = ctx
+	return &bound
+}
+
+func (r *recorder) record(call string) error {
+	*r.calls = append(*r.calls, call)
+	*r.keys = append(*r.keys, d_idempotency.ActionKey(r.ctx))
+	return r.err
+}
+
+func (r *recorder) SendMessage(d_user.ChatID, d_message.Message, string) error {
+	return r.record("SendMessage")
+}
+func (r *recorder) SetObservation(d_user.ChatID, string) error { return r.record("SetObservation") }
+func (r *recorder) SetRoute(d_user.ChatID, string) error       { return r.record("SetRoute") }
+func (r *recorder) EndSession(d_user.ChatID, string) error     { return r.record("EndSession") }
+func (r *recorder) TransferToMenu(d_user.ChatID, d_action.TransferToMenu, d_message.Message) error {
+	return r.record("TransferToMenu")
+}
+func (r *recorder) UploadFile(string) (*d_file.File, error) {
+	return &d_file.File{}, r.record("UploadFile")
+}
+func (r *recorder) GetFile(string) (*d_file.File, error) {
+	return &d_file.File{}, r.record("GetFile")
+}
+
+// process runs the actions of a handler for message m1 on the start route.
+func process(executor *Executor) []error {
+	ctx := d_idempotency.WithScope(context.Background(), d_idempotency.NewScope(chatID, "m1", "start"))
+	bound := executor.WithContext(ctx)
+
+	return []error{
+		bound.SendMessage(chatID, d_message.Message{}, ""),
+		bound.SetObservation(chatID, "{}"),
+		bound.SetRoute(chatID, "next"),
+	}
+}
+
+func TestExecutor_SuppressesRepeatedActions(t *testing.T) {
+	inner := newRecorder()
+	executor := NewExecutor(inner, NewMemoryStore())
+
+	for run := 0; run < 2; run++ {
+		for _, err := range process(executor) {
+			if err != nil {
+				t.Fatalf("run %d: unexpected error %v", run+1, err)
+			}
+		}
+	}
+
+	if len(*inner.calls) != 3 {
+		t.Fatalf("expected the actions to be performed once, got %v", *inner.calls)
+	}
+	for i, key := range *inner.keys {
+		if want := d_idempotency.Key(chatID, "m1", "start", int64(i+1)); key != want {
+			t.Errorf("%s: expected the key to be passed on, got %q", (*inner.calls)[i], key)
+		}
+	}
+}
+
+func TestExecutor_RetriesFailedActions(t *testing.T) {
+	inner := newRecorder()
+	inner.err = errors.New("api down")
+	executor := NewExecutor(inner, NewMemoryStore())
+
+	process(executor)
+	inner.err = nil
+	process(executor)
+
+	if len(*inner.calls) != 6 {
+		t.Errorf("expected failed actions to be performed again, got %v", *inner.calls)
+	}
+}
+
+func TestExecutor_WithoutKey(t *testing.T) {
+	inner := newRecorder()
+	executor := NewExecutor(inner, NewMemoryStore())
+
+	for i := 0; i < 2; i++ {
+		executor.SendMessage(chatID, d_message.Message{}, "")
+		executor.WithContext(context.Background()).SendMessage(chatID, d_message.Message{}, "")
+	}
+
+	if len(*inner.calls) != 4 {
+		t.Errorf("expected actions without a key to always be performed, got %v", *inner.calls)
+	}
+}
+
+func TestExecutor_PassesThroughFiles(t *testing.T) {
+	inner := newRecorder()
+	executor := NewExecutor(inner, NewMemoryStore())
+	ctx := d_idempotency.WithScope(context.Background(), d_idempotency.NewScope(chatID, "m1", "start"))
+
+	for i := 0; i < 2; i++ {
+		bound := executor.WithContext(ctx)
+		bound.UploadFile("f")
+		bound.GetFile("f")
+	}
+
+	if len(*inner.calls) != 4 {
+		t.Errorf("expected file operations to be passed through, got %v", *inner.calls)
+	}
+}
+
+func TestExecutor_UnsupportedCapabilities(t *testing.T) {
+	executor := NewExecutor(newRecorder(), NewMemoryStore())
+
+	if err := executor.SendTyping(chatID, ""); !errors.Is(err, adapter_output.ErrUnsupported) {
+		t.Errorf("SendTyping: expected ErrUnsupported, got %v", err)
+	}
+	if err := executor.EditMessage(chatID, "m", d_message.Message{}, ""); !errors.Is(err, adapter_output.ErrUnsupported) {
+		t.Errorf("EditMessage: expected ErrUnsupported, got %v", err)
+	}
+	if err := executor.React(chatID, "m", "👍", ""); !errors.Is(err, adapter_output.ErrUnsupported) {
+		t.Errorf("React: expected ErrUnsupported, got %v", err)
+	}
+}
+
+func TestMemoryStore_TTL(t *testing.T) {
+	now := time.Unix(0, 0)
+	store := NewMemoryStore(MemoryStoreOptions{TTL: time.Minute, Now: func() time.Time { return now }})
+
+	store.Record("a")
+	if seen, _ := store.Seen("a"); !seen {
+		t.Error("expected the key to be seen")
+	}
+
+	now = now.Add(time.Minute)
+	if seen, _ := store.Seen("a"); seen {
+		t.Error("expected the key to expire")
+	}
+
+	store.Record("b")
+	if store.Len() != 1 {
+		t.Errorf("expected expired keys to be swept, got %d keys", store.Len())
+	}
+}
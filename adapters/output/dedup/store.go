@@ -0,0 +1,88 @@
+package output_dedup
+
+import (
+	"sync"
+	"time"
+
+	adapter_output "github.com/irissonnlima/chatgraph-go/core/ports/adapters/output"
+)
+
+// DEFAULT_DEDUP_TTL is how long a MemoryStore remembers an action by default.
+// It should exceed the time the broker takes to redeliver a message.
+const DEFAULT_DEDUP_TTL = 24 * time.Hour
+
+var _ adapter_output.IDedupStore = (*MemoryStore)(nil)
+
+// MemoryStoreOptions configures a MemoryStore.
+type MemoryStoreOptions struct {
+	// TTL is how long a key is remembered. Defaults to DEFAULT_DEDUP_TTL.
+	TTL time.Duration
+	// Now returns the current time. Defaults to time.Now.
+	Now func() time.Time
+}
+
+// MemoryStore is an IDedupStore kept in memory. Keys expire after the TTL, so
+// it only suppresses repeats within one process; use a shared store when
+// several instances consume the same queue.
+type MemoryStore struct {
+	options MemoryStoreOptions
+
+	mu        sync.Mutex
+	keys      map[string]time.Time
+	lastSweep time.Time
+}
+
+// NewMemoryStore creates an in-memory dedup store.
+func NewMemoryStore(options ...MemoryStoreOptions) *MemoryStore {
+	opts := MemoryStoreOptions{}
+	if len(options) > 0 {
+		opts = options[0]
+	}
+	if opts.TTL <= 0 {
+		opts.TTL = DEFAULT_DEDUP_TTL
+	}
+	if opts.Now == nil {
+		opts.Now = time.Now
+	}
+
+	return &MemoryStore{
+		options:   opts,
+		keys:      make(map[string]time.Time),
+		lastSweep: opts.Now(),
+	}
+}
+
+// Seen reports whether key was recorded within the TTL.
+func (s *MemoryStore) Seen(key string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	expires, ok := s.keys[key]
+	return ok && s.options.Now().Before(expires), nil
+}
+
+// Record stores key for the TTL. Expired keys are swept once per TTL.
+func (s *MemoryStore) Record(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.options.Now()
+	if now.Sub(s.lastSweep) >= s.options.TTL {
+		for k, expires := range s.keys {
+			if !now.Before(expires) {
+				delete(s.keys, k)
+			}
+		}
+		s.lastSweep = now
+	}
+
+	s.keys[key] = now.Add(s.options.TTL)
+	return nil
+}
+
+// Len returns the number of keys held, expired ones not yet swept included.
+func (s *MemoryStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.keys)
+}
@@ -1,11 +1,76 @@
 package output_router_api
 
 import (
+	"encoding/json"
+	"fmt"
+
+	dto_action "github.com/irissonnlima/chatgraph-go/adapters/dto/action"
+	dto_message "github.com/irissonnlima/chatgraph-go/adapters/dto/message"
+	dto_user "github.com/irissonnlima/chatgraph-go/adapters/dto/user"
 	d_action "github.com/irissonnlima/chatgraph-go/core/domain/action"
 	d_message "github.com/irissonnlima/chatgraph-go/core/domain/message"
 	d_user "github.com/irissonnlima/chatgraph-go/core/domain/user"
 )
 
+// TRANSFER_ENDPOINT transfers a session to another menu.
+const TRANSFER_ENDPOINT = "/v1/actions/session/transfer"
+
+// TransferToMenuPayload is the body of a menu transfer.
+// Message is the message that triggered the transfer, so the new menu can process it.
+type TransferToMenuPayload struct {
+	ChatID   dto_user.ChatID           `json:"chat_id"`
+	Transfer dto_action.TransferToMenu `json:"transfer"`
+	Message  dto_message.Message       `json:"message"`
+}
+
+// transferReturn is the data of a menu transfer response: the session state
+// after the transfer.
+type transferReturn struct {
+	Data *dto_user.UserState `json:"data"`
+}
+
+// TransferToMenu moves the session to another menu, forwarding the triggering
+// message. Incoming messages of the chat then carry the new menu in UserState.Menu.
+// The response is validated: a refused transfer, an answer without the session
+// state, or one whose UserState.Menu is not the new menu returns an error.
 func (r *RouterApi) TransferToMenu(chatID d_user.ChatID, transfer d_action.TransferToMenu, message d_message.Message) error {
+	if transfer.MenuID < 1 {
+		return &APIError{
+			Kind:     ErrValidation,
+			Endpoint: TRANSFER_ENDPOINT,
+			Message:  fmt.Sprintf("invalid menu id %d for transfer", transfer.MenuID),
+		}
+	}
+
+	payload := TransferToMenuPayload{
+		ChatID: dto_user.ChatIDFromDomain(chatID),
+		Transfer: dto_action.TransferToMenu{
+			MenuID: transfer.MenuID,
+			Route:  transfer.Route,
+		},
+		Message: dto_message.MessageFromDomain(message),
+	}
+
+	jsonPayload, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	body, err := r.postEnvelope(TRANSFER_ENDPOINT, chatID, jsonPayload)
+	if err != nil {
+		return err
+	}
+
+	var result transferReturn
+	if err := json.Unmarshal(body, &result); err != nil {
+		return fmt.Errorf("router api: invalid response from %s: %w", TRANSFER_ENDPOINT, err)
+	}
+	if result.Data == nil || result.Data.Menu == nil {
+		return fmt.Errorf("router api: transfer to menu %d answered without the session menu", transfer.MenuID)
+	}
+	if result.Data.Menu.ID != transfer.MenuID {
+		return fmt.Errorf("router api: transfer to menu %d answered with menu %d", transfer.MenuID, result.Data.Menu.ID)
+	}
+
 	return nil
 }
@@ -0,0 +1,128 @@
+package output_router_api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	d_action "github.com/irissonnlima/chatgraph-go/core/domain/action"
+	d_message "github.com/irissonnlima/chatgraph-go/core/domain/message"
+	adapter_output "github.com/irissonnlima/chatgraph-go/core/ports/adapters/output"
+)
+
+func TestTransferToMenu_Payload(t *testing.T) {
+	var got TransferToMenuPayload
+	api, calls := newTestApi(t, func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodPost || req.URL.Path != TRANSFER_ENDPOINT {
+			t.Errorf("unexpected request %s %s", req.Method, req.URL.Path)
+		}
+		if err := json.NewDecoder(req.Body).Decode(&got); err != nil {
+			t.Errorf("invalid payload: %v", err)
+		}
+		fmt.Fprint(w, `{"status": true, "message": "transferred", "data": {"menu": {"id": 7, "name": "Billing"}, "route": "start"}}`)
+	})
+
+	message := d_message.Message{TextMessage: d_message.TextMessage{ID: "m1", Detail: "second copy"}}
+	transfer := d_action.TransferToMenu{MenuID: 7, Route: "invoices"}
+	if err := api.TransferToMenu(testChat, transfer, message); err != nil {
+		t.Fatalf("TransferToMenu() error = %v", err)
+	}
+
+	if calls.Load() != 1 {
+		t.Fatalf("expected 1 call, got %d", calls.Load())
+	}
+	if got.ChatID.UserID != testChat.UserID || got.ChatID.CompanyID != testChat.CompanyID {
+		t.Errorf("unexpected chat id %+v", got.ChatID)
+	}
+	if got.Transfer.MenuID != 7 || got.Transfer.Route != "invoices" {
+		t.Errorf("unexpected transfer %+v", got.Transfer)
+	}
+	if got.Message.TextMessage == nil || got.Message.TextMessage.ID != "m1" || got.Message.TextMessage.Detail != "second copy" {
+		t.Errorf("expected the triggering message to be forwarded, got %+v", got.Message.TextMessage)
+	}
+}
+
+func TestTransferToMenu_Errors(t *testing.T) {
+	tests := []struct {
+		name     string
+		transfer d_action.TransferToMenu
+		status   int
+		body     string
+		calls    int32
+		kind     error
+		// retryable is whether the error may succeed if the action is repeated.
+		retryable bool
+	}{
+		{
+			name:     "invalid menu",
+			transfer: d_action.TransferToMenu{MenuID: 0},
+			kind:     ErrValidation,
+		},
+		{
+			name:     "rejected",
+			transfer: d_action.TransferToMenu{MenuID: 7},
+			status:   http.StatusOK,
+			body:     `{"status": false, "message": "menu is inactive"}`,
+			calls:    1,
+			kind:     ErrRejected,
+		},
+		{
+			name:     "menu not found",
+			transfer: d_action.TransferToMenu{MenuID: 7},
+			status:   http.StatusNotFound,
+			body:     `{"status": false, "message": "menu not found"}`,
+			calls:    1,
+			kind:     ErrNotFound,
+		},
+		{
+			name:     "different menu",
+			transfer: d_action.TransferToMenu{MenuID: 7},
+			status:   http.StatusOK,
+			body:     `{"status": true, "message": "ok", "data": {"menu": {"id": 8}}}`,
+			calls:    1,
+		},
+		{
+			name:     "missing state",
+			transfer: d_action.TransferToMenu{MenuID: 7},
+			status:   http.StatusOK,
+			body:     `{"status": true, "message": "ok"}`,
+			calls:    1,
+		},
+		{
+			name:     "invalid response",
+			transfer: d_action.TransferToMenu{MenuID: 7},
+			status:   http.StatusOK,
+			body:     `<html>`,
+			calls:    1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api, calls := newTestApi(t, func(w http.ResponseWriter, req *http.Request) {
+				w.WriteHeader(tt.status)
+				fmt.Fprint(w, tt.body)
+			})
+
+			err := api.TransferToMenu(testChat, tt.transfer, d_message.Message{})
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			if tt.kind != nil && !errors.Is(err, tt.kind) {
+				t.Errorf("expected %v, got %v", tt.kind, err)
+			}
+			var retryable adapter_output.IRetryableError
+			if errors.As(err, &retryable) && retryable.Retryable() != tt.retryable {
+				t.Errorf("expected retryable %v, got %v", tt.retryable, retryable.Retryable())
+			}
+			if tt.kind == ErrValidation && !errors.As(err, &retryable) {
+				t.Errorf("expected a validation error to tell it is not retryable, got %T", err)
+			}
+			if calls.Load() != tt.calls {
+				t.Errorf("expected %d calls, got %d", tt.calls, calls.Load())
+			}
+		})
+	}
+}
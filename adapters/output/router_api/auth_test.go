@@ -0,0 +1,316 @@
+package output_router_api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/irissonnlima/chatgraph-go/core/clocktest"
+)
+
+// exerciseAllCalls makes a JSON action, a file lookup and a multipart upload.
+func exerciseAllCalls(t *testing.T, api *RouterApi) {
+	t.Helper()
+
+	if err := api.SetRoute(testChat, "menu"); err != nil {
+		t.Fatalf("SetRoute() error = %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "doc.txt")
+	if err := os.WriteFile(path, []byte("content"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := api.UploadFile(path); err != nil {
+		t.Fatalf("UploadFile() error = %v", err)
+	}
+}
+
+// fileServer answers every call: file lookups with 404, uploads and actions with success.
+func fileServer(check func(req *http.Request, body []byte)) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+		check(req, body)
+
+		switch {
+		case req.Method == http.MethodGet:
+			w.WriteHeader(http.StatusNotFound)
+		case strings.HasSuffix(req.URL.Path, "/upload"):
+			fmt.Fprint(w, `{"status": true, "message": "ok", "data": {"id": "f1", "type": "FILE", "name": "doc.txt"}}`)
+		default:
+			ok(w, req)
+		}
+	}
+}
+
+func TestAuth_AppliedToEveryCall(t *testing.T) {
+	secret := []byte("shared")
+	now := time.Unix(1700000000, 0)
+
+	tests := []struct {
+		name  string
+		auth  IAuthenticator
+		check func(t *testing.T, req *http.Request, body []byte)
+	}{
+		{
+			name: "basic",
+			auth: BasicAuth{Username: "u", Password: "p"},
+			check: func(t *testing.T, req *http.Request, _ []byte) {
+				if user, pass, ok := req.BasicAuth(); !ok || user != "u" || pass != "p" {
+					t.Errorf("%s %s: expected basic auth, got %q", req.Method, req.URL.Path, req.Header.Get("Authorization"))
+				}
+			},
+		},
+		{
+			name: "bearer",
+			auth: BearerToken{Token: "static"},
+			check: func(t *testing.T, req *http.Request, _ []byte) {
+				if got := req.Header.Get("Authorization"); got != "Bearer static" {
+					t.Errorf("%s %s: expected bearer token, got %q", req.Method, req.URL.Path, got)
+				}
+			},
+		},
+		{
+			name: "hmac",
+			auth: HMACSigner{KeyID: "partner", Secret: secret, Now: func() time.Time { return now }},
+			check: func(t *testing.T, req *http.Request, body []byte) {
+				timestamp := req.Header.Get(DEFAULT_HMAC_TIMESTAMP_HEADER)
+				expected := SignRequest(secret, timestamp, req.Method, req.URL.Path, body)
+				if timestamp != "1700000000" || req.Header.Get(DEFAULT_HMAC_SIGNATURE_HEADER) != expected {
+					t.Errorf("%s %s: invalid signature", req.Method, req.URL.Path)
+				}
+				if req.Header.Get(DEFAULT_HMAC_KEY_ID_HEADER) != "partner" {
+					t.Errorf("%s %s: expected the key id", req.Method, req.URL.Path)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := fastOptions
+			opts.Auth = tt.auth
+			api, calls := newTestApi(t, fileServer(func(req *http.Request, body []byte) {
+				tt.check(t, req, body)
+			}), opts)
+
+			exerciseAllCalls(t, api)
+			if calls.Load() != 3 {
+				t.Errorf("expected 3 calls, got %d", calls.Load())
+			}
+		})
+	}
+}
+
+func TestSignRequest(t *testing.T) {
+	signature := SignRequest([]byte("secret"), "1", http.MethodPost, "/v1/actions/session/route", []byte("{}"))
+
+	if !strings.HasPrefix(signature, "sha256=") {
+		t.Errorf("expected a sha256 signature, got %q", signature)
+	}
+	if signature == SignRequest([]byte("secret"), "1", http.MethodPost, "/v1/actions/session/end", []byte("{}")) {
+		t.Error("expected the path to be signed")
+	}
+	if signature == SignRequest([]byte("secret"), "2", http.MethodPost, "/v1/actions/session/route", []byte("{}")) {
+		t.Error("expected the timestamp to be signed")
+	}
+}
+
+// tokenServer is an OAuth2 token endpoint issuing numbered tokens.
+type tokenServer struct {
+	issued    atomic.Int32
+	expiresIn int
+	fail      atomic.Bool
+	// gate, when set, holds every token request until it is closed.
+	gate chan struct{}
+}
+
+func (s *tokenServer) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if s.gate != nil {
+		<-s.gate
+	}
+	if s.fail.Load() {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, `{"error": "invalid_client", "error_description": "unknown client"}`)
+		return
+	}
+	if id, secret, _ := req.BasicAuth(); id != "client" || secret != "secret" {
+		w.WriteHeader(http.StatusUnauthorized)
+		fmt.Fprint(w, `{"error": "invalid_client"}`)
+		return
+	}
+	if req.FormValue("grant_type") != "client_credentials" || req.FormValue("scope") != "actions files" {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, `{"error": "invalid_request"}`)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]any{
+		"access_token": fmt.Sprintf("token-%d", s.issued.Add(1)),
+		"token_type":   "Bearer",
+		"expires_in":   s.expiresIn,
+	})
+}
+
+func newTestOAuth2(t *testing.T, tokens *tokenServer, now func() time.Time) *OAuth2ClientCredentials {
+	t.Helper()
+
+	server := httptest.NewServer(tokens)
+	t.Cleanup(server.Close)
+
+	return NewOAuth2ClientCredentials(OAuth2Options{
+		TokenURL:     server.URL,
+		ClientID:     "client",
+		ClientSecret: "secret",
+		Scopes:       []string{"actions", "files"},
+		Now:          now,
+	})
+}
+
+func TestOAuth2_CachesAndRefreshes(t *testing.T) {
+	clock := clocktest.NewFakeClock(time.Unix(0, 0))
+	tokens := &tokenServer{expiresIn: 3600}
+	auth := newTestOAuth2(t, tokens, clock.Now)
+
+	opts := fastOptions
+	opts.Auth = auth
+	var seen []string
+	var mu sync.Mutex
+	api, _ := newTestApi(t, fileServer(func(req *http.Request, _ []byte) {
+		mu.Lock()
+		defer mu.Unlock()
+		seen = append(seen, req.Header.Get("Authorization"))
+	}), opts)
+
+	exerciseAllCalls(t, api)
+	for _, header := range seen {
+		if header != "Bearer token-1" {
+			t.Errorf("expected every call to reuse the cached token, got %q", header)
+		}
+	}
+
+	// Within RefreshBefore of the expiry, a new token is requested.
+	clock.Advance(time.Hour - DEFAULT_OAUTH2_REFRESH_BEFORE)
+	if err := api.SetRoute(testChat, "menu"); err != nil {
+		t.Fatalf("SetRoute() error = %v", err)
+	}
+	if last := seen[len(seen)-1]; last != "Bearer token-2" {
+		t.Errorf("expected a refreshed token, got %q", last)
+	}
+}
+
+func TestOAuth2_ReusesShortLivedToken(t *testing.T) {
+	clock := clocktest.NewFakeClock(time.Unix(0, 0))
+	tokens := &tokenServer{expiresIn: 10}
+	auth := newTestOAuth2(t, tokens, clock.Now)
+
+	// RefreshBefore (30s) exceeds the lifetime, so it is capped at half of it.
+	for _, advance := range []time.Duration{0, 4 * time.Second} {
+		clock.Advance(advance)
+		if token, err := auth.Token(context.Background()); err != nil || token != "token-1" {
+			t.Fatalf("expected the cached token, got %q, %v", token, err)
+		}
+	}
+
+	clock.Advance(time.Second)
+	if token, _ := auth.Token(context.Background()); token != "token-2" {
+		t.Errorf("expected a refreshed token, got %q", token)
+	}
+}
+
+func TestOAuth2_SharesTokenRequest(t *testing.T) {
+	tokens := &tokenServer{expiresIn: 3600, gate: make(chan struct{})}
+	auth := newTestOAuth2(t, tokens, nil)
+
+	results := make(chan string, 5)
+	for range cap(results) {
+		go func() {
+			token, _ := auth.Token(context.Background())
+			results <- token
+		}()
+	}
+
+	for deadline := time.Now().Add(time.Second); ; time.Sleep(time.Millisecond) {
+		auth.mu.Lock()
+		pending := auth.pending != nil
+		auth.mu.Unlock()
+		if pending {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("expected a token request in flight")
+		}
+	}
+	// The lock is not held during the request.
+	auth.Invalidate()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := auth.Token(ctx); !errors.Is(err, ErrTokenRequest) {
+		t.Errorf("expected a waiting caller to give up with its context, got %v", err)
+	}
+
+	close(tokens.gate)
+	for range cap(results) {
+		if token := <-results; token != "token-1" {
+			t.Errorf("expected every caller to get the shared token, got %q", token)
+		}
+	}
+	if tokens.issued.Load() != 1 {
+		t.Errorf("expected a single token request, got %d", tokens.issued.Load())
+	}
+}
+
+func TestOAuth2_RenewsRefusedToken(t *testing.T) {
+	tokens := &tokenServer{expiresIn: 3600}
+	auth := newTestOAuth2(t, tokens, nil)
+
+	opts := fastOptions
+	opts.Auth = auth
+	api, calls := newTestApi(t, func(w http.ResponseWriter, req *http.Request) {
+		// The first token was revoked on the server.
+		if req.Header.Get("Authorization") == "Bearer token-1" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		ok(w, req)
+	}, opts)
+
+	if err := api.SetRoute(testChat, "menu"); err != nil {
+		t.Fatalf("expected the request to succeed with a new token, got %v", err)
+	}
+	if calls.Load() != 2 || tokens.issued.Load() != 2 {
+		t.Errorf("expected 2 calls and 2 tokens, got %d and %d", calls.Load(), tokens.issued.Load())
+	}
+}
+
+func TestOAuth2_TokenFailure(t *testing.T) {
+	tokens := &tokenServer{}
+	tokens.fail.Store(true)
+	auth := newTestOAuth2(t, tokens, nil)
+
+	opts := fastOptions
+	opts.Auth = auth
+	api, calls := newTestApi(t, ok, opts)
+
+	err := api.SetRoute(testChat, "menu")
+	if !errors.Is(err, ErrAuthentication) || !errors.Is(err, ErrTokenRequest) {
+		t.Fatalf("expected a token request error, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "unknown client") {
+		t.Errorf("expected the error description, got %v", err)
+	}
+	if calls.Load() != 0 {
+		t.Errorf("expected the request not to be sent, got %d calls", calls.Load())
+	}
+}
@@ -0,0 +1,194 @@
+package output_router_api
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+const (
+	// DEFAULT_BREAKER_FAILURE_THRESHOLD is the number of consecutive failures that opens the breaker.
+	DEFAULT_BREAKER_FAILURE_THRESHOLD = 5
+	// DEFAULT_BREAKER_OPEN_TIMEOUT is how long the breaker stays open before letting probes through.
+	DEFAULT_BREAKER_OPEN_TIMEOUT = 30 * time.Second
+	// DEFAULT_BREAKER_HALF_OPEN_PROBES is the number of concurrent probe calls while half-open.
+	DEFAULT_BREAKER_HALF_OPEN_PROBES = 1
+)
+
+// ErrCircuitOpen is returned without calling the Router API while the circuit breaker is open.
+var ErrCircuitOpen = errors.New("router api: circuit breaker is open")
+
+// BreakerState is the state of the circuit breaker.
+type BreakerState int
+
+const (
+	// BreakerClosed lets every call through.
+	BreakerClosed BreakerState = iota
+	// BreakerOpen fails every call fast with ErrCircuitOpen.
+	BreakerOpen
+	// BreakerHalfOpen lets a few probe calls through to test whether the API recovered.
+	BreakerHalfOpen
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerClosed:
+		return "closed"
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	}
+	return "unknown"
+}
+
+// BreakerOptions configures the circuit breaker around Router API calls.
+//
+// Network failures, timeouts, 5xx and 429 responses count as failures; other
+// responses show the API is up and count as successes. After FailureThreshold
+// consecutive failures the breaker opens and calls fail fast. Once OpenTimeout
+// elapses it becomes half-open: up to HalfOpenProbes calls go through, and the
+// first result closes the breaker again or reopens it.
+type BreakerOptions struct {
+	// FailureThreshold defaults to DEFAULT_BREAKER_FAILURE_THRESHOLD.
+	FailureThreshold int
+	// OpenTimeout defaults to DEFAULT_BREAKER_OPEN_TIMEOUT.
+	OpenTimeout time.Duration
+	// HalfOpenProbes defaults to DEFAULT_BREAKER_HALF_OPEN_PROBES.
+	HalfOpenProbes int
+	// OnStateChange, if set, is called after every state change. It must not block.
+	OnStateChange func(from, to BreakerState)
+}
+
+// outcome is the result of a call as seen by the breaker.
+type outcome int
+
+const (
+	outcomeSuccess outcome = iota
+	outcomeFailure
+	// outcomeIgnored releases a probe without judging the API, e.g. when the
+	// caller gave up before the request was answered.
+	outcomeIgnored
+)
+
+// circuitBreaker tracks consecutive failures of the Router API.
+type circuitBreaker struct {
+	options BreakerOptions
+	now     func() time.Time
+
+	mu       sync.Mutex
+	state    BreakerState
+	failures int
+	openedAt time.Time
+	probes   int
+}
+
+// newCircuitBreaker creates a closed breaker, applying option defaults.
+func newCircuitBreaker(options BreakerOptions) *circuitBreaker {
+	if options.FailureThreshold <= 0 {
+		options.FailureThreshold = DEFAULT_BREAKER_FAILURE_THRESHOLD
+	}
+	if options.OpenTimeout <= 0 {
+		options.OpenTimeout = DEFAULT_BREAKER_OPEN_TIMEOUT
+	}
+	if options.HalfOpenProbes <= 0 {
+		options.HalfOpenProbes = DEFAULT_BREAKER_HALF_OPEN_PROBES
+	}
+	return &circuitBreaker{options: options, now: time.Now}
+}
+
+// current returns the current state of the breaker.
+func (b *circuitBreaker) current() BreakerState {
+	b.mu.Lock()
+	state, notify := b.refresh()
+	b.mu.Unlock()
+
+	notify()
+	return state
+}
+
+// allow reserves a call. Every allowed call must be followed by done.
+func (b *circuitBreaker) allow() error {
+	b.mu.Lock()
+	state, notify := b.refresh()
+
+	var err error
+	switch state {
+	case BreakerOpen:
+		err = ErrCircuitOpen
+	case BreakerHalfOpen:
+		if b.probes >= b.options.HalfOpenProbes {
+			err = ErrCircuitOpen
+		} else {
+			b.probes++
+		}
+	}
+	b.mu.Unlock()
+
+	notify()
+	return err
+}
+
+// done records the outcome of a call reserved with allow.
+func (b *circuitBreaker) done(result outcome) {
+	b.mu.Lock()
+	var notify func()
+
+	switch b.state {
+	case BreakerClosed:
+		switch result {
+		case outcomeSuccess:
+			b.failures = 0
+		case outcomeFailure:
+			b.failures++
+			if b.failures >= b.options.FailureThreshold {
+				notify = b.setState(BreakerOpen)
+			}
+		}
+
+	case BreakerHalfOpen:
+		b.probes--
+		switch result {
+		case outcomeSuccess:
+			notify = b.setState(BreakerClosed)
+		case outcomeFailure:
+			notify = b.setState(BreakerOpen)
+		}
+
+	case BreakerOpen:
+		// A call allowed before the breaker opened; its result is stale.
+	}
+	b.mu.Unlock()
+
+	if notify != nil {
+		notify()
+	}
+}
+
+// refresh moves an open breaker to half-open once OpenTimeout has elapsed.
+// Must be called with b.mu held; the returned notify must be called without it.
+func (b *circuitBreaker) refresh() (BreakerState, func()) {
+	if b.state == BreakerOpen && b.now().Sub(b.openedAt) >= b.options.OpenTimeout {
+		return BreakerHalfOpen, b.setState(BreakerHalfOpen)
+	}
+	return b.state, func() {}
+}
+
+// setState changes the state and returns a function that reports the change.
+// Must be called with b.mu held.
+func (b *circuitBreaker) setState(state BreakerState) func() {
+	from := b.state
+	b.state = state
+	b.failures = 0
+	b.probes = 0
+	if state == BreakerOpen {
+		b.openedAt = b.now()
+	}
+
+	callback := b.options.OnStateChange
+	return func() {
+		if callback != nil && from != state {
+			callback(from, state)
+		}
+	}
+}
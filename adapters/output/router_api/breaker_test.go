@@ -0,0 +1,138 @@
+package output_router_api
+
+import (
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/irissonnlima/chatgraph-go/core/clocktest"
+)
+
+func newTestBreaker(options BreakerOptions) (*circuitBreaker, *clocktest.FakeClock) {
+	clock := clocktest.NewFakeClock(time.Unix(0, 0))
+	breaker := newCircuitBreaker(options)
+	breaker.now = clock.Now
+	return breaker, clock
+}
+
+// call runs one call through the breaker with the given outcome.
+func (b *circuitBreaker) call(result outcome) error {
+	if err := b.allow(); err != nil {
+		return err
+	}
+	b.done(result)
+	return nil
+}
+
+func TestBreaker_OpensAfterConsecutiveFailures(t *testing.T) {
+	var changes []string
+	breaker, _ := newTestBreaker(BreakerOptions{
+		FailureThreshold: 3,
+		OnStateChange: func(from, to BreakerState) {
+			changes = append(changes, from.String()+"->"+to.String())
+		},
+	})
+
+	breaker.call(outcomeFailure)
+	breaker.call(outcomeFailure)
+	breaker.call(outcomeSuccess)
+	breaker.call(outcomeFailure)
+	breaker.call(outcomeFailure)
+	if breaker.current() != BreakerClosed {
+		t.Fatalf("expected a success to reset the failure count, got %s", breaker.current())
+	}
+
+	breaker.call(outcomeFailure)
+	if breaker.current() != BreakerOpen {
+		t.Fatalf("expected the breaker to open, got %s", breaker.current())
+	}
+	if err := breaker.call(outcomeSuccess); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("expected ErrCircuitOpen, got %v", err)
+	}
+	if len(changes) != 1 || changes[0] != "closed->open" {
+		t.Errorf("unexpected state changes %v", changes)
+	}
+}
+
+func TestBreaker_HalfOpen(t *testing.T) {
+	breaker, clock := newTestBreaker(BreakerOptions{FailureThreshold: 1, OpenTimeout: time.Minute})
+
+	breaker.call(outcomeFailure)
+	clock.Advance(59 * time.Second)
+	if breaker.current() != BreakerOpen {
+		t.Fatalf("expected the breaker to stay open, got %s", breaker.current())
+	}
+
+	clock.Advance(time.Second)
+	if breaker.current() != BreakerHalfOpen {
+		t.Fatalf("expected the breaker to be half-open, got %s", breaker.current())
+	}
+
+	// Only one probe at a time.
+	if err := breaker.allow(); err != nil {
+		t.Fatalf("expected a probe to be allowed, got %v", err)
+	}
+	if err := breaker.allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("expected a second probe to be refused, got %v", err)
+	}
+
+	// A failed probe reopens the breaker.
+	breaker.done(outcomeFailure)
+	if breaker.current() != BreakerOpen {
+		t.Fatalf("expected a failed probe to reopen the breaker, got %s", breaker.current())
+	}
+
+	// An ignored probe leaves it half-open, a successful one closes it.
+	clock.Advance(time.Minute)
+	breaker.call(outcomeIgnored)
+	if breaker.current() != BreakerHalfOpen {
+		t.Fatalf("expected an ignored probe to keep the breaker half-open, got %s", breaker.current())
+	}
+	breaker.call(outcomeSuccess)
+	if breaker.current() != BreakerClosed {
+		t.Errorf("expected a successful probe to close the breaker, got %s", breaker.current())
+	}
+}
+
+func TestRouterApi_BreakerFailsFast(t *testing.T) {
+	var down atomic.Bool
+	down.Store(true)
+
+	opts := fastOptions
+	opts.MaxRetries = 2
+	opts.Breaker = &BreakerOptions{FailureThreshold: 2, OpenTimeout: time.Hour}
+	api, calls := newTestApi(t, func(w http.ResponseWriter, req *http.Request) {
+		if down.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		ok(w, req)
+	}, opts)
+
+	if err := api.SetRoute(testChat, "menu"); !errors.Is(err, ErrServer) {
+		t.Fatalf("expected ErrServer, got %v", err)
+	}
+	if api.Available() {
+		t.Fatal("expected the API to be unavailable once the breaker opens")
+	}
+
+	err := api.SetRoute(testChat, "menu")
+	if !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("expected ErrCircuitOpen, got %v", err)
+	}
+	if calls.Load() != 2 {
+		t.Errorf("expected the open breaker to skip the request, got %d calls", calls.Load())
+	}
+
+	// Once the open timeout elapses, a successful probe closes the breaker.
+	down.Store(false)
+	api.breaker.now = func() time.Time { return time.Now().Add(time.Hour) }
+	if err := api.SetRoute(testChat, "menu"); err != nil {
+		t.Fatalf("expected the probe to succeed, got %v", err)
+	}
+	if api.BreakerState() != BreakerClosed {
+		t.Errorf("expected the breaker to close, got %s", api.BreakerState())
+	}
+}
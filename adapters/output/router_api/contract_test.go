@@ -0,0 +1,269 @@
+package output_router_api_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	output_router_api "github.com/irissonnlima/chatgraph-go/adapters/output/router_api"
+	"github.com/irissonnlima/chatgraph-go/adapters/output/router_api/fakeserver"
+	d_action "github.com/irissonnlima/chatgraph-go/core/domain/action"
+	d_file "github.com/irissonnlima/chatgraph-go/core/domain/file"
+	d_idempotency "github.com/irissonnlima/chatgraph-go/core/domain/idempotency"
+	d_message "github.com/irissonnlima/chatgraph-go/core/domain/message"
+	d_user "github.com/irissonnlima/chatgraph-go/core/domain/user"
+	adapter_output "github.com/irissonnlima/chatgraph-go/core/ports/adapters/output"
+)
+
+var chatID = d_user.ChatID{UserID: "5511999999999", CompanyID: "acme"}
+
+// newContract starts a fake server and returns it with a client pointed at it.
+func newContract(t *testing.T, options ...output_router_api.RouterApiOptions) (*fakeserver.Server, adapter_output.IBotExecutor) {
+	t.Helper()
+
+	server := fakeserver.New(fakeserver.Options{Username: "user", Password: "pass"})
+	url := server.Start()
+	t.Cleanup(server.Close)
+
+	opts := output_router_api.RouterApiOptions{
+		Timeout:        time.Second,
+		MaxRetries:     3,
+		RetryBaseDelay: time.Millisecond,
+		RetryMaxDelay:  5 * time.Millisecond,
+	}
+	if len(options) > 0 {
+		opts = options[0]
+	}
+	return server, output_router_api.NewRouterApi(url, "user", "pass", opts)
+}
+
+func TestContract_Session(t *testing.T) {
+	server, api := newContract(t)
+
+	if err := api.SetRoute(chatID, "start"); err != nil {
+		t.Fatalf("SetRoute() error = %v", err)
+	}
+	if err := api.SetRoute(chatID, "menu"); err != nil {
+		t.Fatalf("SetRoute() error = %v", err)
+	}
+	if err := api.SetObservation(chatID, `{"name":"Ana"}`); err != nil {
+		t.Fatalf("SetObservation() error = %v", err)
+	}
+
+	session, ok := server.Session(chatID)
+	if !ok {
+		t.Fatal("expected the server to know the chat")
+	}
+	if session.Route != "start.menu" || session.Observation != `{"name":"Ana"}` {
+		t.Errorf("unexpected session %+v", session)
+	}
+
+	if err := api.EndSession(chatID, "resolved"); err != nil {
+		t.Fatalf("EndSession() error = %v", err)
+	}
+	session, _ = server.Session(chatID)
+	if session.SessionID != 2 || session.Route != "" || len(session.EndActions) != 1 || session.EndActions[0] != "resolved" {
+		t.Errorf("unexpected session after end %+v", session)
+	}
+
+	// Ending a closed session is refused with status:false.
+	if err := api.EndSession(chatID, "resolved"); !errors.Is(err, output_router_api.ErrRejected) {
+		t.Errorf("expected ErrRejected, got %v", err)
+	}
+}
+
+func TestContract_SendMessage(t *testing.T) {
+	server, api := newContract(t)
+
+	message := d_message.Message{
+		TextMessage: d_message.TextMessage{Title: "Menu", Detail: "Choose an option"},
+		Buttons: []d_message.Button{
+			{Type: d_message.POSTBACK, Title: "Billing", Detail: "billing"},
+			{Type: d_message.URL, Title: "Site", Detail: "https://example.com"},
+		},
+	}
+	if err := api.SendMessage(chatID, message, "whatsapp"); err != nil {
+		t.Fatalf("SendMessage() error = %v", err)
+	}
+
+	sent := server.Messages(chatID)
+	if len(sent) != 1 {
+		t.Fatalf("expected 1 message, got %d", len(sent))
+	}
+	got := sent[0]
+	if got.TextMessage.Detail != "Choose an option" || len(got.Buttons) != 2 || got.Buttons[1].Type != d_message.URL {
+		t.Errorf("unexpected message %+v", got)
+	}
+	if got.DisplayButton.IsEmpty() {
+		t.Error("expected the client to add a display button for the buttons")
+	}
+}
+
+func TestContract_TransferToMenu(t *testing.T) {
+	server, api := newContract(t)
+
+	message := d_message.Message{TextMessage: d_message.TextMessage{ID: "m1", Detail: "my invoice"}}
+	if err := api.TransferToMenu(chatID, d_action.TransferToMenu{MenuID: 3, Route: "invoices"}, message); err != nil {
+		t.Fatalf("TransferToMenu() error = %v", err)
+	}
+
+	session, _ := server.Session(chatID)
+	if session.Menu.ID != 3 || session.Route != "invoices" {
+		t.Errorf("unexpected session after transfer %+v", session)
+	}
+	if len(session.Transfers) != 1 || session.Transfers[0].Message.TextMessage.Detail != "my invoice" {
+		t.Errorf("expected the triggering message to be forwarded, got %+v", session.Transfers)
+	}
+}
+
+func TestContract_Files(t *testing.T) {
+	server, api := newContract(t)
+
+	path := filepath.Join(t.TempDir(), "photo.png")
+	if err := os.WriteFile(path, []byte("png bytes"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	uploaded, err := api.UploadFile(path)
+	if err != nil {
+		t.Fatalf("UploadFile() error = %v", err)
+	}
+	if uploaded.Name != "photo.png" || uploaded.Type != d_file.IMAGE_SEND_TYPE {
+		t.Errorf("unexpected file %+v", uploaded)
+	}
+
+	data, err := uploaded.Bytes()
+	if err != nil || string(data) != "png bytes" {
+		t.Errorf("expected to download the content, got %q, %v", data, err)
+	}
+
+	// Uploading the same content again finds the stored file.
+	again, err := api.UploadFile(path)
+	if err != nil || again.ID != uploaded.ID {
+		t.Fatalf("expected the stored file, got %+v, %v", again, err)
+	}
+	if count := server.RequestCount(fakeserver.UPLOAD_FILE); count != 1 {
+		t.Errorf("expected 1 upload, got %d", count)
+	}
+
+	got, err := api.GetFile(uploaded.ID)
+	if err != nil || got.URL != uploaded.URL {
+		t.Errorf("GetFile() = %+v, %v", got, err)
+	}
+	if _, err := api.GetFile("missing"); !errors.Is(err, output_router_api.ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestContract_Failures(t *testing.T) {
+	t.Run("retries server errors", func(t *testing.T) {
+		server, api := newContract(t)
+		server.Fail(fakeserver.Failure{Endpoint: fakeserver.SET_ROUTE, StatusCode: 500, Times: 2})
+
+		if err := api.SetRoute(chatID, "menu"); err != nil {
+			t.Fatalf("expected the third attempt to succeed, got %v", err)
+		}
+		if count := server.RequestCount(fakeserver.SET_ROUTE); count != 3 {
+			t.Errorf("expected 3 attempts, got %d", count)
+		}
+	})
+
+	t.Run("retries timeouts", func(t *testing.T) {
+		server, api := newContract(t, output_router_api.RouterApiOptions{
+			Timeout:        50 * time.Millisecond,
+			MaxRetries:     2,
+			RetryBaseDelay: time.Millisecond,
+		})
+		server.Fail(fakeserver.Failure{Endpoint: fakeserver.SEND_MESSAGE, Delay: time.Second})
+
+		message := d_message.Message{TextMessage: d_message.TextMessage{Detail: "hi"}}
+		if err := api.SendMessage(chatID, message, "whatsapp"); err != nil {
+			t.Fatalf("expected the retry to succeed, got %v", err)
+		}
+		if sent := server.Messages(chatID); len(sent) != 1 {
+			t.Errorf("expected the timed out attempt not to be applied, got %d messages", len(sent))
+		}
+	})
+
+	t.Run("lost responses are not applied twice", func(t *testing.T) {
+		server, api := newContract(t)
+		server.Fail(fakeserver.Failure{Endpoint: fakeserver.SEND_MESSAGE, StatusCode: 502, AfterApply: true})
+
+		ctx := d_idempotency.WithScope(context.Background(), d_idempotency.NewScope(chatID, "m1", "start"))
+		bound := api.(adapter_output.IContextBinder).WithContext(ctx)
+		message := d_message.Message{TextMessage: d_message.TextMessage{Detail: "hi"}}
+		if err := bound.SendMessage(chatID, message, "whatsapp"); err != nil {
+			t.Fatalf("expected the retry to succeed, got %v", err)
+		}
+
+		if sent := server.Messages(chatID); len(sent) != 1 {
+			t.Errorf("expected the message to be sent once, got %d", len(sent))
+		}
+		requests := server.Requests()
+		if len(requests) != 2 || !requests[1].Replayed || requests[0].IdempotencyKey != requests[1].IdempotencyKey {
+			t.Errorf("expected the retry to replay the first response, got %+v", requests)
+		}
+	})
+
+	t.Run("status false is not retried", func(t *testing.T) {
+		server, api := newContract(t)
+		server.Fail(fakeserver.Failure{Endpoint: fakeserver.SET_OBSERVATION, Rejected: true, Message: "chat is closed"})
+
+		err := api.SetObservation(chatID, `{}`)
+		var apiErr *output_router_api.APIError
+		if !errors.As(err, &apiErr) || apiErr.Kind != output_router_api.ErrRejected || apiErr.Message != "chat is closed" {
+			t.Fatalf("expected a rejected APIError, got %v", err)
+		}
+		if count := server.RequestCount(fakeserver.SET_OBSERVATION); count != 1 {
+			t.Errorf("expected 1 attempt, got %d", count)
+		}
+	})
+
+	t.Run("wrong credentials", func(t *testing.T) {
+		server, _ := newContract(t)
+		api := output_router_api.NewRouterApi(server.Start(), "user", "wrong")
+
+		if err := api.SetRoute(chatID, "menu"); !errors.Is(err, output_router_api.ErrUnauthorized) {
+			t.Errorf("expected ErrUnauthorized, got %v", err)
+		}
+	})
+}
+
+func TestContract_SignedRequests(t *testing.T) {
+	secret := []byte("shared secret")
+	server := fakeserver.New(fakeserver.Options{
+		Authorize: func(req *http.Request, body []byte) bool {
+			timestamp := req.Header.Get(output_router_api.DEFAULT_HMAC_TIMESTAMP_HEADER)
+			expected := output_router_api.SignRequest(secret, timestamp, req.Method, req.URL.Path, body)
+			return req.Header.Get(output_router_api.DEFAULT_HMAC_SIGNATURE_HEADER) == expected
+		},
+	})
+	url := server.Start()
+	t.Cleanup(server.Close)
+
+	api := output_router_api.NewRouterApi(url, "", "", output_router_api.RouterApiOptions{
+		Auth: output_router_api.HMACSigner{KeyID: "partner", Secret: secret},
+	})
+	if err := api.SetRoute(chatID, "menu"); err != nil {
+		t.Fatalf("SetRoute() error = %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "doc.txt")
+	if err := os.WriteFile(path, []byte("signed"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := api.UploadFile(path); err != nil {
+		t.Fatalf("UploadFile() error = %v", err)
+	}
+
+	forged := output_router_api.NewRouterApi(url, "", "", output_router_api.RouterApiOptions{
+		Auth: output_router_api.HMACSigner{KeyID: "partner", Secret: []byte("guess")},
+	})
+	if err := forged.SetRoute(chatID, "menu"); !errors.Is(err, output_router_api.ErrUnauthorized) {
+		t.Errorf("expected ErrUnauthorized, got %v", err)
+	}
+}
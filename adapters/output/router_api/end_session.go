@@ -28,5 +28,5 @@ func (r *RouterApi) EndSession(chatID d_user.ChatID, actionId string) error {
 		return err
 	}
 
-	return r.post("/v1/actions/session/end", jsonPayload)
+	return r.post("/v1/actions/session/end", chatID, jsonPayload)
 }
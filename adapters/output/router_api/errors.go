@@ -0,0 +1,106 @@
+package output_router_api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	adapter_output "github.com/irissonnlima/chatgraph-go/core/ports/adapters/output"
+)
+
+// Error kinds of the Router API. Use errors.Is to tell them apart:
+//
+//	if errors.Is(err, output_router_api.ErrNotFound) { ... }
+var (
+	// ErrNotFound is returned when the resource does not exist (404).
+	ErrNotFound = errors.New("router api: not found")
+	// ErrUnauthorized is returned when the credentials are missing or refused (401, 403).
+	ErrUnauthorized = errors.New("router api: unauthorized")
+	// ErrValidation is returned when the request is invalid (400, 422).
+	ErrValidation = errors.New("router api: validation failed")
+	// ErrRateLimited is returned when the server keeps throttling requests (429).
+	ErrRateLimited = errors.New("router api: rate limited")
+	// ErrServer is returned when the server keeps failing (5xx).
+	ErrServer = errors.New("router api: server error")
+	// ErrRejected is returned when the server refuses the action (status false).
+	ErrRejected = errors.New("router api: action rejected")
+	// ErrAuthentication is returned when the request credentials cannot be
+	// produced, e.g. the OAuth2 token request failed. The request is not sent.
+	ErrAuthentication = errors.New("router api: authentication failed")
+)
+
+var _ adapter_output.IRetryableError = (*APIError)(nil)
+
+// APIError describes a failed Router API call.
+type APIError struct {
+	// Kind is one of the Err* kinds above, or nil for unexpected status codes.
+	Kind error
+	// Endpoint is the path that was called.
+	Endpoint string
+	// StatusCode is the HTTP status of the response, or zero when the request
+	// was refused before being sent.
+	StatusCode int
+	// Message is the message returned by the server, if any.
+	Message string
+	// RetryAfter is the delay requested by the server with Retry-After.
+	RetryAfter time.Duration
+}
+
+func (e *APIError) Error() string {
+	kind := "router api: unexpected response"
+	if e.Kind != nil {
+		kind = e.Kind.Error()
+	}
+	where := e.Endpoint
+	if e.StatusCode != 0 {
+		where = fmt.Sprintf("%s, status %d", e.Endpoint, e.StatusCode)
+	}
+	if e.Message != "" {
+		return fmt.Sprintf("%s (%s): %s", kind, where, e.Message)
+	}
+	return fmt.Sprintf("%s (%s)", kind, where)
+}
+
+// Unwrap returns the error kind.
+func (e *APIError) Unwrap() error {
+	return e.Kind
+}
+
+// Retryable reports whether the call may succeed if repeated: server errors
+// and rate limiting.
+func (e *APIError) Retryable() bool {
+	return e.Kind == ErrServer || e.Kind == ErrRateLimited
+}
+
+// kindOf returns the error kind of an HTTP status code.
+func kindOf(statusCode int) error {
+	switch {
+	case statusCode == http.StatusNotFound:
+		return ErrNotFound
+	case statusCode == http.StatusUnauthorized, statusCode == http.StatusForbidden:
+		return ErrUnauthorized
+	case statusCode == http.StatusBadRequest, statusCode == http.StatusUnprocessableEntity:
+		return ErrValidation
+	case statusCode == http.StatusTooManyRequests:
+		return ErrRateLimited
+	case statusCode >= 500:
+		return ErrServer
+	}
+	return nil
+}
+
+// parseRetryAfter parses a Retry-After header, in seconds or as an HTTP date.
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(value); err == nil && at.After(now) {
+		return at.Sub(now)
+	}
+	return 0
+}
@@ -0,0 +1,107 @@
+package fakeserver
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Failure is an injected failure. It answers the next Times requests to
+// Endpoint instead of the endpoint itself, or after it with AfterApply:
+//
+//	// Time out the next send once, with a client timeout below one second.
+//	server.Fail(fakeserver.Failure{Endpoint: fakeserver.SEND_MESSAGE, Delay: time.Second})
+//	// Answer the next two route changes with 503 and a Retry-After header.
+//	server.Fail(fakeserver.Failure{Endpoint: fakeserver.SET_ROUTE, StatusCode: 503, RetryAfter: time.Second, Times: 2})
+//	// Refuse every observation with status:false.
+//	server.Fail(fakeserver.Failure{Endpoint: fakeserver.SET_OBSERVATION, Rejected: true, Times: -1})
+//	// Send the next message, but lose the response.
+//	server.Fail(fakeserver.Failure{Endpoint: fakeserver.SEND_MESSAGE, StatusCode: 502, AfterApply: true})
+type Failure struct {
+	// Endpoint is the path the failure applies to, e.g. SEND_MESSAGE, or a
+	// FILES path with an ID. Empty applies to every endpoint.
+	Endpoint string
+	// Delay holds the response back, e.g. longer than the client timeout.
+	// Without StatusCode or Rejected, the request is then handled normally.
+	Delay time.Duration
+	// StatusCode answers with this HTTP status and a status:false envelope.
+	StatusCode int
+	// Rejected answers 200 with a status:false envelope.
+	Rejected bool
+	// Message is the message of the envelope. Defaults to "injected failure".
+	Message string
+	// RetryAfter is sent in the Retry-After header, in whole seconds.
+	RetryAfter time.Duration
+	// AfterApply handles the request before the failure answers it, as when
+	// the response is lost on its way back to the client.
+	AfterApply bool
+	// Times is the number of requests that fail. Defaults to 1; negative
+	// values fail every request until ClearFailures.
+	Times int
+}
+
+// Fail queues an injected failure. Failures apply in the order they were queued.
+func (s *Server) Fail(failure Failure) {
+	if failure.Times == 0 {
+		failure.Times = 1
+	}
+	if failure.Message == "" {
+		failure.Message = "injected failure"
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures = append(s.failures, &failure)
+}
+
+// ClearFailures removes every queued failure.
+func (s *Server) ClearFailures() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures = nil
+}
+
+// takeFailure returns the first failure for path and uses it once.
+// Must be called with s.mu held.
+func (s *Server) takeFailure(path string) *Failure {
+	for i, failure := range s.failures {
+		if failure.Endpoint != "" && failure.Endpoint != path {
+			continue
+		}
+
+		taken := *failure
+		if failure.Times > 0 {
+			failure.Times--
+			if failure.Times == 0 {
+				s.failures = append(s.failures[:i], s.failures[i+1:]...)
+			}
+		}
+		return &taken
+	}
+	return nil
+}
+
+// fail answers a request with an injected failure. Returns false when the
+// failure only delays the request, which must then be handled as usual.
+func (s *Server) fail(w http.ResponseWriter, req *http.Request, failure *Failure) bool {
+	if failure.Delay > 0 {
+		s.sleep(req, failure.Delay)
+		if req.Context().Err() != nil {
+			return true
+		}
+	}
+
+	if failure.StatusCode == 0 && !failure.Rejected {
+		return false
+	}
+
+	if failure.RetryAfter > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(int(failure.RetryAfter.Seconds())))
+	}
+	statusCode := failure.StatusCode
+	if statusCode == 0 {
+		statusCode = http.StatusOK
+	}
+	writeEnvelope(w, statusCode, false, failure.Message, nil)
+	return true
+}
@@ -0,0 +1,280 @@
+package fakeserver
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"path/filepath"
+
+	dto_action "github.com/irissonnlima/chatgraph-go/adapters/dto/action"
+	dto_file "github.com/irissonnlima/chatgraph-go/adapters/dto/file"
+	dto_message "github.com/irissonnlima/chatgraph-go/adapters/dto/message"
+	dto_user "github.com/irissonnlima/chatgraph-go/adapters/dto/user"
+	d_file "github.com/irissonnlima/chatgraph-go/core/domain/file"
+	d_user "github.com/irissonnlima/chatgraph-go/core/domain/user"
+)
+
+// MAX_BODY_SIZE bounds request bodies, uploads included.
+const MAX_BODY_SIZE = 32 << 20
+
+// Request bodies of the action endpoints. They are declared here rather than
+// shared with the client, so contract tests catch changes on either side.
+type (
+	sendMessageRequest struct {
+		UserState dto_user.UserState  `json:"user_state"`
+		Message   dto_message.Message `json:"message"`
+	}
+	routeRequest struct {
+		ChatID *dto_user.ChatID `json:"chat_id"`
+		Route  string           `json:"route"`
+	}
+	observationRequest struct {
+		ChatID      *dto_user.ChatID `json:"chat_id"`
+		Observation string           `json:"observation"`
+	}
+	endSessionRequest struct {
+		ChatID    *dto_user.ChatID     `json:"chat_id"`
+		EndAction dto_action.EndAction `json:"end_action"`
+	}
+	transferRequest struct {
+		ChatID   *dto_user.ChatID          `json:"chat_id"`
+		Transfer dto_action.TransferToMenu `json:"transfer"`
+		Message  dto_message.Message       `json:"message"`
+	}
+)
+
+// readBody reads a request body up to MAX_BODY_SIZE.
+func readBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil {
+		return nil, nil
+	}
+	return io.ReadAll(io.LimitReader(req.Body, MAX_BODY_SIZE))
+}
+
+// decode parses a JSON body, answering 400 when it is invalid.
+func decode(w http.ResponseWriter, body []byte, v any) bool {
+	if err := json.Unmarshal(body, v); err != nil {
+		writeEnvelope(w, http.StatusBadRequest, false, "invalid JSON: "+err.Error(), nil)
+		return false
+	}
+	return true
+}
+
+// chatIDOf validates a chat ID, answering 400 when it is missing.
+func chatIDOf(w http.ResponseWriter, chatID *dto_user.ChatID) (d_user.ChatID, bool) {
+	if chatID == nil || chatID.UserID == "" || chatID.CompanyID == "" {
+		writeEnvelope(w, http.StatusBadRequest, false, "chat_id is required", nil)
+		return d_user.ChatID{}, false
+	}
+	return chatID.ToDomain(), true
+}
+
+func (s *Server) sendMessage(w http.ResponseWriter, body []byte) {
+	var req sendMessageRequest
+	if !decode(w, body, &req) {
+		return
+	}
+	chatID, ok := chatIDOf(w, req.UserState.ChatID)
+	if !ok {
+		return
+	}
+
+	s.mu.Lock()
+	s.session(chatID)
+	s.messages[chatID] = append(s.messages[chatID], req.Message.ToDomain())
+	s.mu.Unlock()
+
+	writeEnvelope(w, http.StatusOK, true, "message sent", nil)
+}
+
+func (s *Server) setRoute(w http.ResponseWriter, body []byte) {
+	var req routeRequest
+	if !decode(w, body, &req) {
+		return
+	}
+	chatID, ok := chatIDOf(w, req.ChatID)
+	if !ok {
+		return
+	}
+	if req.Route == "" {
+		writeEnvelope(w, http.StatusBadRequest, false, "route is required", nil)
+		return
+	}
+
+	s.mu.Lock()
+	session := s.session(chatID)
+	if session.Route == "" {
+		session.Route = req.Route
+	} else {
+		session.Route += ROUTE_SEPARATOR + req.Route
+	}
+	s.mu.Unlock()
+
+	writeEnvelope(w, http.StatusOK, true, "route updated", nil)
+}
+
+func (s *Server) setObservation(w http.ResponseWriter, body []byte) {
+	var req observationRequest
+	if !decode(w, body, &req) {
+		return
+	}
+	chatID, ok := chatIDOf(w, req.ChatID)
+	if !ok {
+		return
+	}
+	if req.Observation != "" && !json.Valid([]byte(req.Observation)) {
+		writeEnvelope(w, http.StatusBadRequest, false, "observation must be JSON", nil)
+		return
+	}
+
+	s.mu.Lock()
+	s.session(chatID).Observation = req.Observation
+	s.mu.Unlock()
+
+	writeEnvelope(w, http.StatusOK, true, "observation updated", nil)
+}
+
+// endSession closes the session of a chat. The next one starts with an empty
+// route and observation, keeping the menu.
+func (s *Server) endSession(w http.ResponseWriter, body []byte) {
+	var req endSessionRequest
+	if !decode(w, body, &req) {
+		return
+	}
+	chatID, ok := chatIDOf(w, req.ChatID)
+	if !ok {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session := s.session(chatID)
+	if session.Route == "" {
+		writeEnvelope(w, http.StatusOK, false, "chat has no open session", nil)
+		return
+	}
+	session.SessionID++
+	session.Route = ""
+	session.Observation = ""
+	session.EndActions = append(session.EndActions, req.EndAction.ID)
+
+	writeEnvelope(w, http.StatusOK, true, "session ended", nil)
+}
+
+// transfer moves a chat to another menu and answers with the new session state.
+func (s *Server) transfer(w http.ResponseWriter, body []byte) {
+	var req transferRequest
+	if !decode(w, body, &req) {
+		return
+	}
+	chatID, ok := chatIDOf(w, req.ChatID)
+	if !ok {
+		return
+	}
+	if req.Transfer.MenuID < 1 {
+		writeEnvelope(w, http.StatusBadRequest, false, "menu_id is required", nil)
+		return
+	}
+
+	s.mu.Lock()
+	session := s.session(chatID)
+	session.Menu = d_user.Menu{ID: req.Transfer.MenuID}
+	session.Route = req.Transfer.Route
+	session.Transfers = append(session.Transfers, Transfer{
+		MenuID:  req.Transfer.MenuID,
+		Route:   req.Transfer.Route,
+		Message: req.Message.ToDomain(),
+	})
+	menu := dto_user.MenuFromDomain(session.Menu)
+	state := dto_user.UserState{
+		SessionID: session.SessionID,
+		ChatID:    req.ChatID,
+		Menu:      &menu,
+		Route:     session.Route,
+	}
+	s.mu.Unlock()
+
+	writeEnvelope(w, http.StatusOK, true, "chat transferred", state)
+}
+
+// uploadFile stores the "content" field of a multipart form. The file ID is
+// the SHA-256 of its content, so the client finds it again before uploading.
+func (s *Server) uploadFile(w http.ResponseWriter, req *http.Request, body []byte) {
+	_, params, err := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	if err != nil || params["boundary"] == "" {
+		writeEnvelope(w, http.StatusBadRequest, false, "expected a multipart form", nil)
+		return
+	}
+
+	form, err := multipart.NewReader(bytes.NewReader(body), params["boundary"]).ReadForm(MAX_BODY_SIZE)
+	if err != nil {
+		writeEnvelope(w, http.StatusBadRequest, false, "invalid multipart form: "+err.Error(), nil)
+		return
+	}
+	defer form.RemoveAll()
+
+	headers := form.File["content"]
+	if len(headers) == 0 {
+		writeEnvelope(w, http.StatusBadRequest, false, "content is required", nil)
+		return
+	}
+	content, err := headers[0].Open()
+	if err != nil {
+		writeEnvelope(w, http.StatusBadRequest, false, "could not read content", nil)
+		return
+	}
+	defer content.Close()
+
+	data, err := io.ReadAll(content)
+	if err != nil {
+		writeEnvelope(w, http.StatusBadRequest, false, "could not read content", nil)
+		return
+	}
+
+	hash := sha256.Sum256(data)
+	id := hex.EncodeToString(hash[:])
+	name := filepath.Base(headers[0].Filename)
+	file := d_file.File{
+		ID:   id,
+		Type: d_file.TypeFromName(name),
+		URL:  "http://" + req.Host + FILES + id + "/content",
+		Name: name,
+	}
+
+	s.mu.Lock()
+	s.files[id] = storedFile{file: file, data: data}
+	s.mu.Unlock()
+
+	writeEnvelope(w, http.StatusOK, true, "file uploaded", dto_file.FileFromDomain(file))
+}
+
+func (s *Server) getFile(w http.ResponseWriter, fileID string) {
+	s.mu.Lock()
+	stored, ok := s.files[fileID]
+	s.mu.Unlock()
+
+	if !ok {
+		writeEnvelope(w, http.StatusNotFound, false, "file not found", nil)
+		return
+	}
+	writeEnvelope(w, http.StatusOK, true, "file found", dto_file.FileFromDomain(stored.file))
+}
+
+func (s *Server) downloadFile(w http.ResponseWriter, fileID string) {
+	s.mu.Lock()
+	stored, ok := s.files[fileID]
+	s.mu.Unlock()
+
+	if !ok {
+		http.Error(w, "file not found", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", http.DetectContentType(stored.data))
+	w.Write(stored.data)
+}
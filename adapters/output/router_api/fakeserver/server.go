@@ -0,0 +1,296 @@
+// Package fakeserver is an in-memory fake of the Router API, for contract
+// tests of the RouterApi client and for running bots locally without the
+// real service.
+//
+// The server answers the action endpoints with the same envelopes as the
+// Router API, keeps sessions, sent messages and files in memory so tests can
+// inspect them, and can inject failures:
+//
+//	server := fakeserver.New(fakeserver.Options{Username: "user", Password: "pass"})
+//	url := server.Start()
+//	defer server.Close()
+//
+//	server.Fail(fakeserver.Failure{Endpoint: fakeserver.SEND_MESSAGE, StatusCode: 500})
+//	router := output_router_api.NewRouterApi(url, "user", "pass")
+package fakeserver
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	output_router_api "github.com/irissonnlima/chatgraph-go/adapters/output/router_api"
+	d_file "github.com/irissonnlima/chatgraph-go/core/domain/file"
+	d_message "github.com/irissonnlima/chatgraph-go/core/domain/message"
+	d_user "github.com/irissonnlima/chatgraph-go/core/domain/user"
+)
+
+// Endpoints served by the fake server.
+const (
+	SEND_MESSAGE    = "/v1/actions/messages/send"
+	SET_ROUTE       = "/v1/actions/session/route"
+	SET_OBSERVATION = "/v1/actions/session/observation"
+	END_SESSION     = "/v1/actions/session/end"
+	TRANSFER        = "/v1/actions/session/transfer"
+	UPLOAD_FILE     = "/v1/actions/files/upload"
+	// FILES is the prefix of GET /v1/actions/files/{id} and of the download
+	// URLs of uploaded files, /v1/actions/files/{id}/content.
+	FILES = "/v1/actions/files/"
+)
+
+// ROUTE_SEPARATOR separates routes in the session history.
+const ROUTE_SEPARATOR = "."
+
+// Options configures the fake server.
+type Options struct {
+	// Username and Password, when set, are required as basic auth on every
+	// action endpoint. Downloads of uploaded files need no credentials.
+	Username string
+	Password string
+	// Authorize, when set, replaces the basic auth check, e.g. to verify
+	// bearer tokens or request signatures. body is the raw request body.
+	Authorize func(req *http.Request, body []byte) bool
+}
+
+// Request is a request received by the fake server.
+type Request struct {
+	Method string
+	Path   string
+	// Body is the raw request body.
+	Body []byte
+	// IdempotencyKey is the output_router_api.IDEMPOTENCY_KEY_HEADER of the
+	// request, if any. A request repeating a key is answered with the response
+	// recorded for the first one, without being applied again.
+	IdempotencyKey string
+	// Replayed reports whether the response recorded for the key was replayed.
+	Replayed bool
+	// Failed reports whether an injected failure answered the request.
+	Failed bool
+}
+
+// Server is an in-memory fake of the Router API. It is an http.Handler, so it
+// can be mounted on any server, or started on a local port with Start.
+type Server struct {
+	options Options
+
+	mu       sync.Mutex
+	sessions map[d_user.ChatID]*Session
+	messages map[d_user.ChatID][]d_message.Message
+	files    map[string]storedFile
+	requests []Request
+	failures []*Failure
+	// responses holds the response recorded for each idempotency key.
+	responses map[string]*httptest.ResponseRecorder
+
+	http *httptest.Server
+	// closing is closed by Close to release delayed responses.
+	closing   chan struct{}
+	closeOnce sync.Once
+}
+
+// storedFile is an uploaded file with its content.
+type storedFile struct {
+	file d_file.File
+	data []byte
+}
+
+// New creates an empty fake server.
+func New(options ...Options) *Server {
+	opts := Options{}
+	if len(options) > 0 {
+		opts = options[0]
+	}
+
+	return &Server{
+		options:   opts,
+		sessions:  make(map[d_user.ChatID]*Session),
+		messages:  make(map[d_user.ChatID][]d_message.Message),
+		files:     make(map[string]storedFile),
+		responses: make(map[string]*httptest.ResponseRecorder),
+		closing:   make(chan struct{}),
+	}
+}
+
+// Start serves the fake server on a local port and returns its base URL.
+func (s *Server) Start() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.http == nil {
+		s.http = httptest.NewServer(s)
+	}
+	return s.http.URL
+}
+
+// Close stops a server started with Start and releases delayed responses.
+func (s *Server) Close() {
+	s.closeOnce.Do(func() { close(s.closing) })
+
+	s.mu.Lock()
+	server := s.http
+	s.mu.Unlock()
+
+	if server != nil {
+		server.Close()
+	}
+}
+
+// ServeHTTP dispatches a request to its endpoint.
+func (s *Server) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	path := req.URL.Path
+
+	// Downloads of uploaded files, as used by d_file.File.Bytes.
+	if req.Method == http.MethodGet && strings.HasPrefix(path, FILES) && strings.HasSuffix(path, "/content") {
+		s.downloadFile(w, strings.TrimSuffix(strings.TrimPrefix(path, FILES), "/content"))
+		return
+	}
+
+	body, err := readBody(req)
+	if err != nil {
+		writeEnvelope(w, http.StatusBadRequest, false, "could not read request body", nil)
+		return
+	}
+
+	key := req.Header.Get(output_router_api.IDEMPOTENCY_KEY_HEADER)
+	index, failure := s.record(req.Method, path, body, key)
+	if failure != nil && !failure.AfterApply && s.fail(w, req, failure) {
+		return
+	}
+
+	if !s.authorized(req, body) {
+		writeEnvelope(w, http.StatusUnauthorized, false, "invalid credentials", nil)
+		return
+	}
+
+	if recorded, ok := s.replay(key, index); ok {
+		writeRecorded(w, recorded)
+		return
+	}
+
+	recorded := httptest.NewRecorder()
+	s.dispatch(recorded, req, path, body)
+	s.remember(key, recorded)
+
+	if failure != nil && failure.AfterApply && s.fail(w, req, failure) {
+		return
+	}
+	writeRecorded(w, recorded)
+}
+
+// dispatch handles a request with its endpoint.
+func (s *Server) dispatch(w http.ResponseWriter, req *http.Request, path string, body []byte) {
+	switch {
+	case req.Method == http.MethodPost && path == SEND_MESSAGE:
+		s.sendMessage(w, body)
+	case req.Method == http.MethodPost && path == SET_ROUTE:
+		s.setRoute(w, body)
+	case req.Method == http.MethodPost && path == SET_OBSERVATION:
+		s.setObservation(w, body)
+	case req.Method == http.MethodPost && path == END_SESSION:
+		s.endSession(w, body)
+	case req.Method == http.MethodPost && path == TRANSFER:
+		s.transfer(w, body)
+	case req.Method == http.MethodPost && path == UPLOAD_FILE:
+		s.uploadFile(w, req, body)
+	case req.Method == http.MethodGet && strings.HasPrefix(path, FILES):
+		s.getFile(w, strings.TrimPrefix(path, FILES))
+	default:
+		writeEnvelope(w, http.StatusNotFound, false, "endpoint not found", nil)
+	}
+}
+
+// replay returns the response recorded for an idempotency key, marking the
+// request at index as replayed.
+func (s *Server) replay(key string, index int) (*httptest.ResponseRecorder, bool) {
+	if key == "" {
+		return nil, false
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	recorded, ok := s.responses[key]
+	if ok && index < len(s.requests) {
+		s.requests[index].Replayed = true
+	}
+	return recorded, ok
+}
+
+// remember records the response for an idempotency key. Server errors are
+// not recorded, so the action can be retried.
+func (s *Server) remember(key string, recorded *httptest.ResponseRecorder) {
+	if key == "" || recorded.Code >= http.StatusInternalServerError {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.responses[key] = recorded
+}
+
+// writeRecorded answers with a recorded response.
+func writeRecorded(w http.ResponseWriter, recorded *httptest.ResponseRecorder) {
+	for name, values := range recorded.Header() {
+		w.Header()[name] = values
+	}
+	w.WriteHeader(recorded.Code)
+	w.Write(recorded.Body.Bytes())
+}
+
+// authorized checks the request credentials, when required.
+func (s *Server) authorized(req *http.Request, body []byte) bool {
+	if s.options.Authorize != nil {
+		return s.options.Authorize(req, body)
+	}
+	if s.options.Username == "" && s.options.Password == "" {
+		return true
+	}
+	username, password, ok := req.BasicAuth()
+	return ok && username == s.options.Username && password == s.options.Password
+}
+
+// record logs a request and returns its index and the injected failure that
+// answers it, if any.
+func (s *Server) record(method, path string, body []byte, key string) (int, *Failure) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	failure := s.takeFailure(path)
+	s.requests = append(s.requests, Request{
+		Method:         method,
+		Path:           path,
+		Body:           body,
+		IdempotencyKey: key,
+		Failed:         failure != nil,
+	})
+	return len(s.requests) - 1, failure
+}
+
+// sleep waits for d, or until the request is cancelled or the server closes.
+func (s *Server) sleep(req *http.Request, d time.Duration) {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+	case <-req.Context().Done():
+	case <-s.closing:
+	}
+}
+
+// envelope is the response format of the Router API.
+type envelope struct {
+	Status  bool   `json:"status"`
+	Message string `json:"message"`
+	Data    any    `json:"data,omitempty"`
+}
+
+// writeEnvelope answers with a Router API envelope.
+func writeEnvelope(w http.ResponseWriter, statusCode int, status bool, message string, data any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(envelope{Status: status, Message: message, Data: data})
+}
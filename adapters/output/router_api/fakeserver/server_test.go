@@ -0,0 +1,91 @@
+package fakeserver
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	d_user "github.com/irissonnlima/chatgraph-go/core/domain/user"
+)
+
+func post(t *testing.T, server *Server, path, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	server.ServeHTTP(rec, req)
+	return rec
+}
+
+const routeBody = `{"chat_id": {"user_id": "u1", "company_id": "c1"}, "route": "menu"}`
+
+func TestFail_Times(t *testing.T) {
+	server := New()
+	server.Fail(Failure{Endpoint: SET_ROUTE, StatusCode: http.StatusBadGateway, Times: 2})
+	server.Fail(Failure{Endpoint: SEND_MESSAGE, Rejected: true, Times: -1})
+
+	for i, expected := range []int{http.StatusBadGateway, http.StatusBadGateway, http.StatusOK} {
+		if rec := post(t, server, SET_ROUTE, routeBody); rec.Code != expected {
+			t.Errorf("request %d: expected status %d, got %d", i+1, expected, rec.Code)
+		}
+	}
+
+	session, _ := server.Session(d_user.ChatID{UserID: "u1", CompanyID: "c1"})
+	if session.Route != "menu" {
+		t.Errorf("expected only the successful request to be applied, got route %q", session.Route)
+	}
+
+	for i := 0; i < 3; i++ {
+		rec := post(t, server, SEND_MESSAGE, `{}`)
+		if !strings.Contains(rec.Body.String(), `"status":false`) {
+			t.Errorf("expected a permanent rejection, got %s", rec.Body.String())
+		}
+	}
+
+	server.ClearFailures()
+	if rec := post(t, server, SEND_MESSAGE, `{}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("expected the missing chat id to be refused once failures are cleared, got %d", rec.Code)
+	}
+	if failed := server.Requests()[0].Failed; !failed {
+		t.Error("expected the first request to be recorded as failed")
+	}
+}
+
+func TestServer_Validation(t *testing.T) {
+	server := New(Options{Username: "user", Password: "pass"})
+
+	if rec := post(t, server, SET_ROUTE, routeBody); rec.Code != http.StatusUnauthorized {
+		t.Errorf("expected 401 without credentials, got %d", rec.Code)
+	}
+
+	tests := []struct {
+		name string
+		path string
+		body string
+		code int
+	}{
+		{"invalid json", SET_ROUTE, `{`, http.StatusBadRequest},
+		{"missing route", SET_ROUTE, `{"chat_id": {"user_id": "u1", "company_id": "c1"}}`, http.StatusBadRequest},
+		{"invalid observation", SET_OBSERVATION, `{"chat_id": {"user_id": "u1", "company_id": "c1"}, "observation": "{"}`, http.StatusBadRequest},
+		{"missing menu", TRANSFER, `{"chat_id": {"user_id": "u1", "company_id": "c1"}}`, http.StatusBadRequest},
+		{"unknown endpoint", "/v1/actions/unknown", `{}`, http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(tt.body))
+			req.SetBasicAuth("user", "pass")
+			rec := httptest.NewRecorder()
+			server.ServeHTTP(rec, req)
+
+			if rec.Code != tt.code {
+				t.Errorf("expected status %d, got %d: %s", tt.code, rec.Code, rec.Body.String())
+			}
+		})
+	}
+
+	server.Reset()
+	if len(server.Requests()) != 0 {
+		t.Error("expected Reset to discard the requests")
+	}
+}
@@ -0,0 +1,120 @@
+package fakeserver
+
+import (
+	"net/http/httptest"
+
+	d_file "github.com/irissonnlima/chatgraph-go/core/domain/file"
+	d_message "github.com/irissonnlima/chatgraph-go/core/domain/message"
+	d_user "github.com/irissonnlima/chatgraph-go/core/domain/user"
+)
+
+// Session is the state the fake server keeps for a chat.
+type Session struct {
+	// SessionID increases every time the session ends, starting at 1.
+	SessionID int64
+	ChatID    d_user.ChatID
+	// Menu is the menu the chat was last transferred to.
+	Menu d_user.Menu
+	// Route is the route history joined by ROUTE_SEPARATOR.
+	Route string
+	// Observation is the JSON observation, as sent by the client.
+	Observation string
+	// EndActions holds the IDs of the end actions of the past sessions.
+	EndActions []string
+	// Transfers holds the messages forwarded by menu transfers.
+	Transfers []Transfer
+}
+
+// Transfer is a menu transfer received by the fake server.
+type Transfer struct {
+	MenuID  int
+	Route   string
+	Message d_message.Message
+}
+
+// session returns the session of a chat, creating it if needed.
+// Must be called with s.mu held.
+func (s *Server) session(chatID d_user.ChatID) *Session {
+	session, ok := s.sessions[chatID]
+	if !ok {
+		session = &Session{SessionID: 1, ChatID: chatID}
+		s.sessions[chatID] = session
+	}
+	return session
+}
+
+// Session returns a copy of the session of a chat, and whether the chat
+// was seen by the server.
+func (s *Server) Session(chatID d_user.ChatID) (Session, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session, ok := s.sessions[chatID]
+	if !ok {
+		return Session{}, false
+	}
+
+	copied := *session
+	copied.EndActions = append([]string(nil), session.EndActions...)
+	copied.Transfers = append([]Transfer(nil), session.Transfers...)
+	return copied, true
+}
+
+// Messages returns the messages sent to a chat, in order.
+func (s *Server) Messages(chatID d_user.ChatID) []d_message.Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]d_message.Message(nil), s.messages[chatID]...)
+}
+
+// File returns an uploaded file and its content.
+func (s *Server) File(fileID string) (d_file.File, []byte, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.files[fileID]
+	return stored.file, stored.data, ok
+}
+
+// AddFile stores a file as if it had been uploaded, e.g. to test GetFile.
+// The file URL is left as given.
+func (s *Server) AddFile(file d_file.File, data []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.files[file.ID] = storedFile{file: file, data: data}
+}
+
+// Requests returns the requests received so far, including failed ones.
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Request(nil), s.requests...)
+}
+
+// RequestCount returns the number of requests received for an endpoint path.
+func (s *Server) RequestCount(path string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	count := 0
+	for _, req := range s.requests {
+		if req.Path == path {
+			count++
+		}
+	}
+	return count
+}
+
+// Reset discards every session, message, file, request, failure and
+// recorded idempotent response.
+func (s *Server) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sessions = make(map[d_user.ChatID]*Session)
+	s.messages = make(map[d_user.ChatID][]d_message.Message)
+	s.files = make(map[string]storedFile)
+	s.requests = nil
+	s.failures = nil
+	s.responses = make(map[string]*httptest.ResponseRecorder)
+}
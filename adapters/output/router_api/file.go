@@ -18,7 +18,7 @@ type fileReturn struct {
 }
 
 func (r *RouterApi) GetFile(fileID string) (*d_file.File, error) {
-	returnBytes, err := r.get("/v1/actions/files/" + fileID)
+	returnBytes, err := r.get("/v1/actions/files/"+fileID, "/v1/actions/files/{id}")
 	if err != nil {
 		return nil, err
 	}
@@ -35,7 +35,7 @@ func (r *RouterApi) GetFile(fileID string) (*d_file.File, error) {
 	}
 
 	if !ret.Status {
-		return nil, errors.New(ret.Message)
+		return nil, &APIError{Kind: ErrRejected, Endpoint: "/v1/actions/files/" + fileID, StatusCode: 200, Message: ret.Message}
 	}
 
 	return nil, nil
@@ -54,7 +54,7 @@ func (r *RouterApi) UploadFile(filepath string) (*d_file.File, error) {
 	hashHex := hex.EncodeToString(hash[:])
 
 	file, err := r.GetFile(hashHex)
-	if err != nil {
+	if err != nil && !errors.Is(err, ErrNotFound) {
 		return nil, err
 	}
 
@@ -80,7 +80,7 @@ func (r *RouterApi) UploadFile(filepath string) (*d_file.File, error) {
 	}
 
 	if !ret.Status {
-		return nil, errors.New(ret.Message)
+		return nil, &APIError{Kind: ErrRejected, Endpoint: "/v1/actions/files/upload", StatusCode: 200, Message: ret.Message}
 	}
 
 	return nil, nil
@@ -0,0 +1,200 @@
+package output_router_api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DEFAULT_OAUTH2_REFRESH_BEFORE is how long before expiry a cached token is refreshed.
+	DEFAULT_OAUTH2_REFRESH_BEFORE = 30 * time.Second
+	// DEFAULT_OAUTH2_TOKEN_LIFETIME is assumed when the token response has no expires_in.
+	DEFAULT_OAUTH2_TOKEN_LIFETIME = 5 * time.Minute
+)
+
+// ErrTokenRequest is returned when an OAuth2 access token cannot be obtained.
+var ErrTokenRequest = errors.New("router api: oauth2 token request failed")
+
+// OAuth2Options configures the OAuth2 client credentials flow.
+type OAuth2Options struct {
+	// TokenURL is the token endpoint of the authorization server.
+	TokenURL string
+	// ClientID and ClientSecret are sent with basic auth, as recommended by RFC 6749.
+	ClientID     string
+	ClientSecret string
+	// Scopes are requested space-separated. Optional.
+	Scopes []string
+	// HTTPClient requests tokens. Defaults to the client shared by RouterApi.
+	HTTPClient *http.Client
+	// RefreshBefore refreshes the token this long before it expires, so
+	// requests never carry one about to expire. Defaults to DEFAULT_OAUTH2_REFRESH_BEFORE.
+	// It is capped at half the token lifetime, so short-lived tokens are still reused.
+	RefreshBefore time.Duration
+	// Now returns the current time. Defaults to time.Now.
+	Now func() time.Time
+}
+
+// tokenResponse is the token endpoint response.
+type tokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int64  `json:"expires_in"`
+	Error       string `json:"error"`
+	Description string `json:"error_description"`
+}
+
+// OAuth2ClientCredentials authenticates with bearer tokens from the OAuth2
+// client credentials flow. Tokens are cached and shared by concurrent
+// requests, refreshed shortly before they expire, and discarded when the
+// Router API refuses them.
+type OAuth2ClientCredentials struct {
+	options OAuth2Options
+
+	mu    sync.Mutex
+	token string
+	// refreshAt is when the cached token stops being handed out.
+	refreshAt time.Time
+	// pending is the token request in flight, if any.
+	pending *tokenCall
+}
+
+// tokenCall is a token request shared by the callers waiting for it.
+type tokenCall struct {
+	done  chan struct{}
+	token string
+	err   error
+}
+
+// NewOAuth2ClientCredentials creates an authenticator for the client credentials flow.
+func NewOAuth2ClientCredentials(options OAuth2Options) *OAuth2ClientCredentials {
+	if options.HTTPClient == nil {
+		options.HTTPClient = defaultHTTPClient
+	}
+	if options.RefreshBefore <= 0 {
+		options.RefreshBefore = DEFAULT_OAUTH2_REFRESH_BEFORE
+	}
+	if options.Now == nil {
+		options.Now = time.Now
+	}
+	return &OAuth2ClientCredentials{options: options}
+}
+
+// Authenticate sets a bearer token, requesting a new one when needed.
+func (o *OAuth2ClientCredentials) Authenticate(req *http.Request, _ []byte) error {
+	token, err := o.Token(req.Context())
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	return nil
+}
+
+// Token returns the cached access token, requesting a new one if there is
+// none or it is about to expire. Concurrent callers share a single token
+// request, made without holding the lock by the caller that started it; the
+// others stop waiting when their context is done.
+func (o *OAuth2ClientCredentials) Token(ctx context.Context) (string, error) {
+	o.mu.Lock()
+	if o.token != "" && o.options.Now().Before(o.refreshAt) {
+		token := o.token
+		o.mu.Unlock()
+		return token, nil
+	}
+
+	if call := o.pending; call != nil {
+		o.mu.Unlock()
+		return call.wait(ctx)
+	}
+	call := &tokenCall{done: make(chan struct{})}
+	o.pending = call
+	o.mu.Unlock()
+
+	o.fetch(ctx, call)
+	return call.token, call.err
+}
+
+// wait returns the result of the call, or an error once ctx is done.
+func (call *tokenCall) wait(ctx context.Context) (string, error) {
+	select {
+	case <-call.done:
+		return call.token, call.err
+	case <-ctx.Done():
+		return "", fmt.Errorf("%w: %v", ErrTokenRequest, ctx.Err())
+	}
+}
+
+// fetch requests a token for call, caches it and wakes the waiting callers.
+func (o *OAuth2ClientCredentials) fetch(ctx context.Context, call *tokenCall) {
+	now := o.options.Now()
+	response, err := o.requestToken(ctx)
+
+	o.mu.Lock()
+	if err == nil {
+		lifetime := DEFAULT_OAUTH2_TOKEN_LIFETIME
+		if response.ExpiresIn > 0 {
+			lifetime = time.Duration(response.ExpiresIn) * time.Second
+		}
+		o.token = response.AccessToken
+		o.refreshAt = now.Add(lifetime - min(o.options.RefreshBefore, lifetime/2))
+		call.token = response.AccessToken
+	}
+	call.err = err
+	o.pending = nil
+	o.mu.Unlock()
+	close(call.done)
+}
+
+// Invalidate discards the cached token, so the next request gets a new one.
+func (o *OAuth2ClientCredentials) Invalidate() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.token = ""
+}
+
+// requestToken calls the token endpoint.
+func (o *OAuth2ClientCredentials) requestToken(ctx context.Context) (tokenResponse, error) {
+	form := url.Values{"grant_type": {"client_credentials"}}
+	if len(o.options.Scopes) > 0 {
+		form.Set("scope", strings.Join(o.options.Scopes, " "))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, o.options.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return tokenResponse{}, fmt.Errorf("%w: %v", ErrTokenRequest, err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(url.QueryEscape(o.options.ClientID), url.QueryEscape(o.options.ClientSecret))
+
+	resp, err := o.options.HTTPClient.Do(req)
+	if err != nil {
+		return tokenResponse{}, fmt.Errorf("%w: %v", ErrTokenRequest, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return tokenResponse{}, fmt.Errorf("%w: %v", ErrTokenRequest, err)
+	}
+
+	var response tokenResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		return tokenResponse{}, fmt.Errorf("%w: status %d: invalid response", ErrTokenRequest, resp.StatusCode)
+	}
+	if resp.StatusCode != http.StatusOK || response.AccessToken == "" {
+		return tokenResponse{}, fmt.Errorf("%w: status %d: %s %s",
+			ErrTokenRequest, resp.StatusCode, response.Error, response.Description)
+	}
+	if response.TokenType != "" && !strings.EqualFold(response.TokenType, "bearer") {
+		return tokenResponse{}, fmt.Errorf("%w: unsupported token type %q", ErrTokenRequest, response.TokenType)
+	}
+	return response, nil
+}
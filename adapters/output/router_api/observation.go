@@ -24,5 +24,5 @@ func (r *RouterApi) SetObservation(chatID d_user.ChatID, observation string) err
 		return err
 	}
 
-	return r.post("/v1/actions/session/observation", jsonPayload)
+	return r.post("/v1/actions/session/observation", chatID, jsonPayload)
 }
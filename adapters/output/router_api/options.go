@@ -0,0 +1,82 @@
+package output_router_api
+
+import (
+	"math/rand/v2"
+	"net/http"
+	"time"
+)
+
+const (
+	// DEFAULT_TIMEOUT bounds each HTTP attempt.
+	DEFAULT_TIMEOUT = 10 * time.Second
+	// DEFAULT_RETRY_BASE_DELAY is the delay before the first retry.
+	DEFAULT_RETRY_BASE_DELAY = 500 * time.Millisecond
+	// DEFAULT_RETRY_MAX_DELAY caps the delay between retries, including Retry-After.
+	DEFAULT_RETRY_MAX_DELAY = 10 * time.Second
+)
+
+// RouterApiOptions configures the Router API client.
+type RouterApiOptions struct {
+	// Auth adds credentials to every request. Defaults to BasicAuth with the
+	// username and password given to NewRouterApi.
+	Auth IAuthenticator
+	// HTTPClient sends the requests. Defaults to a client shared by every
+	// RouterApi without one.
+	HTTPClient *http.Client
+	// Timeout bounds each attempt. Defaults to DEFAULT_TIMEOUT.
+	Timeout time.Duration
+	// MaxRetries is the number of attempts for retryable failures.
+	// Defaults to MAX_RETRIES.
+	MaxRetries int
+	// RetryBaseDelay is the delay before the first retry; it doubles on each
+	// retry and is randomized (full jitter). Defaults to DEFAULT_RETRY_BASE_DELAY.
+	RetryBaseDelay time.Duration
+	// RetryMaxDelay caps the delay between retries. Defaults to DEFAULT_RETRY_MAX_DELAY.
+	RetryMaxDelay time.Duration
+
+	// Breaker enables a circuit breaker that fails calls fast while the API is down.
+	// Nil disables it.
+	Breaker *BreakerOptions
+	// EndpointRateLimit limits the calls to each endpoint, with one bucket per endpoint.
+	EndpointRateLimit RateLimit
+	// EndpointRateLimits overrides EndpointRateLimit for some endpoints, keyed by
+	// path, e.g. "/v1/actions/messages/send" or "/v1/actions/files/{id}".
+	EndpointRateLimits map[string]RateLimit
+	// CompanyRateLimit limits the calls made for each company, with one bucket
+	// per CompanyID. File calls are not made for a company and skip it.
+	CompanyRateLimit RateLimit
+}
+
+// withDefaults returns a copy of the options with defaults applied.
+func (o RouterApiOptions) withDefaults() RouterApiOptions {
+	if o.HTTPClient == nil {
+		o.HTTPClient = defaultHTTPClient
+	}
+	if o.Timeout <= 0 {
+		o.Timeout = DEFAULT_TIMEOUT
+	}
+	if o.MaxRetries <= 0 {
+		o.MaxRetries = MAX_RETRIES
+	}
+	if o.RetryBaseDelay <= 0 {
+		o.RetryBaseDelay = DEFAULT_RETRY_BASE_DELAY
+	}
+	if o.RetryMaxDelay <= 0 {
+		o.RetryMaxDelay = DEFAULT_RETRY_MAX_DELAY
+	}
+	return o
+}
+
+// retryDelay returns the randomized delay before retry number attempt (1-based).
+// A positive retryAfter, sent by the server, is used instead, capped at RetryMaxDelay.
+func (o RouterApiOptions) retryDelay(attempt int, retryAfter time.Duration) time.Duration {
+	if retryAfter > 0 {
+		return min(retryAfter, o.RetryMaxDelay)
+	}
+
+	delay := o.RetryMaxDelay
+	if attempt < 32 {
+		delay = min(o.RetryBaseDelay<<(attempt-1), o.RetryMaxDelay)
+	}
+	return rand.N(delay) + 1
+}
@@ -0,0 +1,160 @@
+package output_router_api
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// BUCKET_SWEEP_INTERVAL is how often idle token buckets are dropped, so
+// per-company buckets do not accumulate for companies that stopped calling.
+const BUCKET_SWEEP_INTERVAL = time.Minute
+
+// RateLimit configures a token bucket: calls are spread at Rate per second,
+// with bursts of up to Burst calls. A zero Rate disables the limit.
+type RateLimit struct {
+	// Rate is the number of calls per second.
+	Rate float64
+	// Burst is the number of calls allowed at once. Defaults to 1.
+	Burst int
+}
+
+// enabled reports whether the limit applies.
+func (l RateLimit) enabled() bool {
+	return l.Rate > 0
+}
+
+// tokenBucket is a token bucket that lets callers reserve tokens ahead of
+// time: the bucket goes negative and each caller waits for its own token.
+type tokenBucket struct {
+	rate  float64
+	burst float64
+	now   func() time.Time
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+// newTokenBucket creates a full bucket.
+func newTokenBucket(limit RateLimit, now func() time.Time) *tokenBucket {
+	burst := float64(max(limit.Burst, 1))
+	return &tokenBucket{
+		rate:   limit.Rate,
+		burst:  burst,
+		now:    now,
+		tokens: burst,
+		last:   now(),
+	}
+}
+
+// reserve takes a token and returns how long the caller must wait before using it.
+func (b *tokenBucket) reserve() time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now()
+	b.tokens = min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+
+	b.tokens--
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
+
+// idle reports whether the bucket has refilled completely at now. Dropping an
+// idle bucket loses nothing, since new buckets start full.
+func (b *tokenBucket) idle(now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.tokens+now.Sub(b.last).Seconds()*b.rate >= b.burst
+}
+
+// cancel returns a reserved token that will not be used.
+func (b *tokenBucket) cancel() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.tokens = min(b.burst, b.tokens+1)
+}
+
+// rateLimiter holds one token bucket per endpoint and one per company.
+type rateLimiter struct {
+	endpoint  RateLimit
+	endpoints map[string]RateLimit
+	company   RateLimit
+	now       func() time.Time
+
+	mu        sync.Mutex
+	buckets   map[string]*tokenBucket
+	lastSweep time.Time
+}
+
+// newRateLimiter creates a limiter from the client options.
+func newRateLimiter(opts RouterApiOptions) *rateLimiter {
+	return &rateLimiter{
+		endpoint:  opts.EndpointRateLimit,
+		endpoints: opts.EndpointRateLimits,
+		company:   opts.CompanyRateLimit,
+		now:       time.Now,
+		buckets:   make(map[string]*tokenBucket),
+	}
+}
+
+// bucket returns the bucket for key, creating it with limit if needed.
+// Idle buckets are dropped every BUCKET_SWEEP_INTERVAL.
+func (l *rateLimiter) bucket(key string, limit RateLimit) *tokenBucket {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if now := l.now(); now.Sub(l.lastSweep) >= BUCKET_SWEEP_INTERVAL {
+		for k, b := range l.buckets {
+			if b.idle(now) {
+				delete(l.buckets, k)
+			}
+		}
+		l.lastSweep = now
+	}
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = newTokenBucket(limit, l.now)
+		l.buckets[key] = b
+	}
+	return b
+}
+
+// wait blocks until both the endpoint and the company allow a call,
+// or until ctx is done. Calls without a company skip the company limit.
+func (l *rateLimiter) wait(ctx context.Context, endpoint, company string) error {
+	var buckets []*tokenBucket
+
+	limit, ok := l.endpoints[endpoint]
+	if !ok {
+		limit = l.endpoint
+	}
+	if limit.enabled() {
+		buckets = append(buckets, l.bucket("endpoint:"+endpoint, limit))
+	}
+	if company != "" && l.company.enabled() {
+		buckets = append(buckets, l.bucket("company:"+company, l.company))
+	}
+
+	var delay time.Duration
+	for _, b := range buckets {
+		delay = max(delay, b.reserve())
+	}
+	if delay == 0 {
+		return nil
+	}
+
+	if err := sleep(ctx, delay); err != nil {
+		for _, b := range buckets {
+			b.cancel()
+		}
+		return fmt.Errorf("router api: waiting for rate limit on %s: %w", endpoint, err)
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package output_router_api
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/irissonnlima/chatgraph-go/core/clocktest"
+)
+
+func TestTokenBucket(t *testing.T) {
+	clock := clocktest.NewFakeClock(time.Unix(0, 0))
+	bucket := newTokenBucket(RateLimit{Rate: 2, Burst: 2}, clock.Now)
+
+	if bucket.reserve() != 0 || bucket.reserve() != 0 {
+		t.Fatal("expected the burst to go through without waiting")
+	}
+	if wait := bucket.reserve(); wait != 500*time.Millisecond {
+		t.Errorf("expected to wait 500ms for the third token, got %s", wait)
+	}
+	if wait := bucket.reserve(); wait != time.Second {
+		t.Errorf("expected to wait 1s for the fourth token, got %s", wait)
+	}
+
+	bucket.cancel()
+	clock.Advance(time.Second)
+	if wait := bucket.reserve(); wait != 0 {
+		t.Errorf("expected the refilled bucket to let the call through, got %s", wait)
+	}
+}
+
+func TestRateLimiter_Keys(t *testing.T) {
+	clock := clocktest.NewFakeClock(time.Unix(0, 0))
+	limiter := newRateLimiter(RouterApiOptions{
+		EndpointRateLimit:  RateLimit{Rate: 1},
+		EndpointRateLimits: map[string]RateLimit{"/files/{id}": {Rate: 1, Burst: 3}},
+		CompanyRateLimit:   RateLimit{Rate: 1},
+	})
+	limiter.now = clock.Now
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// Each endpoint and company has its own bucket.
+	if err := limiter.wait(ctx, "/send", "c1"); err != nil {
+		t.Fatalf("expected the first call to go through, got %v", err)
+	}
+	if err := limiter.wait(ctx, "/route", "c2"); err != nil {
+		t.Fatalf("expected another endpoint and company to go through, got %v", err)
+	}
+	if err := limiter.wait(ctx, "/send", "c3"); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected the endpoint limit to make the call wait, got %v", err)
+	}
+	if err := limiter.wait(ctx, "/route", "c1"); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected the company limit to make the call wait, got %v", err)
+	}
+
+	// Overrides replace the endpoint limit; calls without a company skip the company limit.
+	for i := 0; i < 3; i++ {
+		if err := limiter.wait(ctx, "/files/{id}", ""); err != nil {
+			t.Fatalf("expected call %d to go through the overridden burst, got %v", i+1, err)
+		}
+	}
+}
+
+func TestRateLimiter_DropsIdleBuckets(t *testing.T) {
+	clock := clocktest.NewFakeClock(time.Unix(0, 0))
+	limiter := newRateLimiter(RouterApiOptions{CompanyRateLimit: RateLimit{Rate: 1, Burst: 2}})
+	limiter.now = clock.Now
+	ctx := context.Background()
+
+	for _, company := range []string{"c1", "c2"} {
+		if err := limiter.wait(ctx, "/send", company); err != nil {
+			t.Fatalf("wait() error = %v", err)
+		}
+	}
+	if len(limiter.buckets) != 2 {
+		t.Fatalf("expected 2 buckets, got %d", len(limiter.buckets))
+	}
+
+	// c2 keeps calling, so its bucket is not refilled at the next sweep.
+	clock.Advance(BUCKET_SWEEP_INTERVAL - time.Second)
+	for i := 0; i < 2; i++ {
+		limiter.bucket("company:c2", limiter.company).reserve()
+	}
+	clock.Advance(time.Second)
+	if err := limiter.wait(ctx, "/send", "c3"); err != nil {
+		t.Fatalf("wait() error = %v", err)
+	}
+
+	if _, ok := limiter.buckets["company:c1"]; ok {
+		t.Error("expected the idle bucket to be dropped")
+	}
+	if _, ok := limiter.buckets["company:c2"]; !ok {
+		t.Error("expected the busy bucket to be kept")
+	}
+}
@@ -2,114 +2,173 @@ package output_router_api
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"log"
 	"mime/multipart"
 	"net/http"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
+	d_idempotency "github.com/irissonnlima/chatgraph-go/core/domain/idempotency"
+	d_user "github.com/irissonnlima/chatgraph-go/core/domain/user"
 	adapter_output "github.com/irissonnlima/chatgraph-go/core/ports/adapters/output"
 )
 
+// MAX_RETRIES is the default number of attempts for retryable failures.
 const MAX_RETRIES = 5
 
+// IDEMPOTENCY_KEY_HEADER carries the idempotency key of an action (see
+// d_idempotency). Every attempt of an action sends the same key, so the API
+// can recognize retries whose first response was lost.
+const IDEMPOTENCY_KEY_HEADER = "Idempotency-Key"
+
 type routerReturn struct {
 	Status  bool   `json:"status"`
 	Message string `json:"message"`
 }
 
+// RouterApi implements every executor port against the Router API.
+var (
+	_ adapter_output.IMessenger     = (*RouterApi)(nil)
+	_ adapter_output.ISessionStore  = (*RouterApi)(nil)
+	_ adapter_output.ITransferer    = (*RouterApi)(nil)
+	_ adapter_output.IFileStore     = (*RouterApi)(nil)
+	_ adapter_output.IContextBinder = (*RouterApi)(nil)
+
+	_ adapter_output.IAvailabilityReporter = (*RouterApi)(nil)
+)
+
+// defaultHTTPClient is shared by every RouterApi without a configured client.
+var defaultHTTPClient = &http.Client{}
+
 type RouterApi struct {
-	Url      string
+	Url string
+	// Username and Password are sent with basic auth unless
+	// RouterApiOptions.Auth sets another authenticator.
 	Username string
 	Password string
+
+	options RouterApiOptions
+	ctx     context.Context
+	// breaker and limiter are shared by the copies made by WithContext.
+	breaker *circuitBreaker
+	limiter *rateLimiter
 }
 
-func NewRouterApi(url, username, password string) adapter_output.IBotExecutor {
-	return &RouterApi{
+// call describes a request to the Router API.
+type call struct {
+	method   string
+	endpoint string
+	// name identifies the endpoint for rate limiting, without path parameters.
+	name string
+	// company is the company the call is made for, if any.
+	company     string
+	payload     []byte
+	contentType string
+	// idempotencyKey is sent with every attempt, when set.
+	idempotencyKey string
+}
+
+// NewRouterApi creates a Router API client.
+// Optional options configure the HTTP client, timeouts and retries.
+func NewRouterApi(url, username, password string, options ...RouterApiOptions) adapter_output.IBotExecutor {
+	opts := RouterApiOptions{}
+	if len(options) > 0 {
+		opts = options[0]
+	}
+
+	opts = opts.withDefaults()
+	api := &RouterApi{
 		Url:      url,
 		Username: username,
 		Password: password,
+		options:  opts,
+		limiter:  newRateLimiter(opts),
+	}
+	if opts.Breaker != nil {
+		api.breaker = newCircuitBreaker(*opts.Breaker)
 	}
+	return api
 }
 
-func (r *RouterApi) post(endpoint string, payload []byte) error {
-	var err error
-
-	for i := 0; i < MAX_RETRIES; i++ {
-		if i > 0 {
-			time.Sleep(time.Duration(i) * 2 * time.Second)
-		}
-
-		req, e := http.NewRequest(http.MethodPost, r.Url+endpoint, bytes.NewBuffer(payload))
-		if e != nil {
-			return e
-		}
-
-		req.Header.Set("Content-Type", "application/json")
-		req.SetBasicAuth(r.Username, r.Password)
-
-		client := &http.Client{}
-		resp, e := client.Do(req)
-		if e != nil {
-			err = e
-			log.Printf("[WARN] Request failed: %v. Retrying...", err)
-			continue
-		}
-
-		body, e := io.ReadAll(resp.Body)
-		resp.Body.Close()
-		if e != nil {
-			err = e
-			log.Printf("[WARN] Failed to read body: %v. Retrying...", err)
-			continue
-		}
+// WithContext returns a copy of the client whose calls are bound to ctx:
+// requests are cancelled and retries stop once ctx is done.
+func (r *RouterApi) WithContext(ctx context.Context) adapter_output.IBotExecutor {
+	bound := *r
+	bound.ctx = ctx
+	return &bound
+}
 
-		var result routerReturn
-		if e := json.Unmarshal(body, &result); e != nil {
-			err = e
-			log.Printf("[WARN] Failed to unmarshal: %v. Retrying...", err)
-			continue
-		}
+// Available reports whether calls are let through: false while the circuit
+// breaker is open. Without a breaker the API is always reported available.
+func (r *RouterApi) Available() bool {
+	return r.breaker == nil || r.breaker.current() != BreakerOpen
+}
 
-		if result.Status {
-			log.Printf("[INFO] %s", result.Message)
-			return nil
-		}
+// BreakerState returns the state of the circuit breaker, BreakerClosed without one.
+func (r *RouterApi) BreakerState() BreakerState {
+	if r.breaker == nil {
+		return BreakerClosed
+	}
+	return r.breaker.current()
+}
 
-		err = errors.New(result.Message)
-		log.Printf("[ERROR] %s", result.Message)
+// context returns the context calls are bound to.
+func (r *RouterApi) context() context.Context {
+	if r.ctx == nil {
+		return context.Background()
 	}
+	return r.ctx
+}
 
+// post sends a JSON action for a chat and checks the status of the returned envelope.
+func (r *RouterApi) post(endpoint string, chatID d_user.ChatID, payload []byte) error {
+	_, err := r.postEnvelope(endpoint, chatID, payload)
 	return err
 }
 
-func (r *RouterApi) get(endpoint string) ([]byte, error) {
-	req, err := http.NewRequest(http.MethodGet, r.Url+endpoint, nil)
+// postEnvelope sends a JSON action for a chat, checks the status of the
+// returned envelope and returns the raw response for endpoints that send data.
+// The action takes the next idempotency key of the bound context, if any.
+func (r *RouterApi) postEnvelope(endpoint string, chatID d_user.ChatID, payload []byte) ([]byte, error) {
+	body, err := r.do(call{
+		method:         http.MethodPost,
+		endpoint:       endpoint,
+		name:           endpoint,
+		company:        chatID.CompanyID,
+		payload:        payload,
+		contentType:    "application/json",
+		idempotencyKey: d_idempotency.ActionKey(r.context()),
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	req.SetBasicAuth(r.Username, r.Password)
-
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, err
+	var result routerReturn
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("router api: invalid response from %s: %w", endpoint, err)
 	}
-	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, err
+	if !result.Status {
+		log.Printf("[ERROR] %s", result.Message)
+		return nil, &APIError{Kind: ErrRejected, Endpoint: endpoint, StatusCode: http.StatusOK, Message: result.Message}
 	}
 
+	log.Printf("[INFO] %s", result.Message)
 	return body, nil
 }
 
+// get fetches a resource. name identifies the endpoint for rate limiting.
+func (r *RouterApi) get(endpoint, name string) ([]byte, error) {
+	return r.do(call{method: http.MethodGet, endpoint: endpoint, name: name})
+}
+
 func (r *RouterApi) uploadFileMultipart(endpoint, filePath string) ([]byte, error) {
 	// Open the file
 	file, err := os.Open(filePath)
@@ -138,28 +197,198 @@ func (r *RouterApi) uploadFileMultipart(endpoint, filePath string) ([]byte, erro
 		return nil, err
 	}
 
-	// Create the request
-	req, err := http.NewRequest(http.MethodPost, r.Url+endpoint, &body)
-	if err != nil {
-		return nil, err
+	// The form is kept in memory so it can be sent again on retries
+	return r.do(call{
+		method:      http.MethodPost,
+		endpoint:    endpoint,
+		name:        endpoint,
+		payload:     body.Bytes(),
+		contentType: writer.FormDataContentType(),
+	})
+}
+
+// do sends a request, retrying network failures, 5xx and 429 responses with
+// jittered exponential backoff (or the server's Retry-After). Other failures
+// are returned at once. Waiting stops when the bound context is done.
+//
+// Each attempt first goes through the circuit breaker, failing fast with
+// ErrCircuitOpen while it is open, then waits for the rate limits.
+func (r *RouterApi) do(c call) ([]byte, error) {
+	opts := r.options.withDefaults()
+	ctx := r.context()
+
+	var err error
+	// reauthenticated is set once cached credentials were refused and renewed.
+	reauthenticated := false
+	for attempt := 1; attempt <= opts.MaxRetries; attempt++ {
+		if attempt > 1 {
+			var retryAfter time.Duration
+			var apiErr *APIError
+			if errors.As(err, &apiErr) {
+				retryAfter = apiErr.RetryAfter
+			}
+
+			delay := opts.retryDelay(attempt-1, retryAfter)
+			log.Printf("[WARN] %s %s failed: %v. Retrying in %s...", c.method, c.endpoint, err, delay)
+			if waitErr := sleep(ctx, delay); waitErr != nil {
+				return nil, fmt.Errorf("router api: %w (last error: %v)", waitErr, err)
+			}
+		}
+
+		var body []byte
+		var retry bool
+		body, retry, err = r.guardedAttempt(ctx, opts, c)
+		if err != nil && !reauthenticated && r.invalidateCredentials(err) {
+			reauthenticated = true
+			body, retry, err = r.guardedAttempt(ctx, opts, c)
+		}
+		if err == nil {
+			return body, nil
+		}
+		if !retry || ctx.Err() != nil {
+			return nil, err
+		}
+	}
+
+	return nil, err
+}
+
+// guardedAttempt runs an attempt through the circuit breaker and the rate limits.
+func (r *RouterApi) guardedAttempt(ctx context.Context, opts RouterApiOptions, c call) (body []byte, retry bool, err error) {
+	if r.breaker != nil {
+		if err := r.breaker.allow(); err != nil {
+			return nil, false, fmt.Errorf("%w (%s %s)", err, c.method, c.endpoint)
+		}
+	}
+
+	if r.limiter != nil {
+		if err := r.limiter.wait(ctx, c.name, c.company); err != nil {
+			if r.breaker != nil {
+				r.breaker.done(outcomeIgnored)
+			}
+			return nil, false, err
+		}
+	}
+
+	body, retry, err = r.attempt(ctx, opts, c)
+	if r.breaker != nil {
+		r.breaker.done(outcomeOf(ctx, err))
+	}
+	return body, retry, err
+}
+
+// authenticator returns the configured authenticator, or basic auth with the
+// client's username and password.
+func (r *RouterApi) authenticator() IAuthenticator {
+	if r.options.Auth != nil {
+		return r.options.Auth
+	}
+	return BasicAuth{Username: r.Username, Password: r.Password}
+}
+
+// invalidateCredentials discards cached credentials after a 401, reporting
+// whether the request should be sent again with new ones.
+func (r *RouterApi) invalidateCredentials(err error) bool {
+	invalidator, ok := r.authenticator().(ICredentialInvalidator)
+	if !ok || !errors.Is(err, ErrUnauthorized) {
+		return false
 	}
+	invalidator.Invalidate()
+	return true
+}
 
-	req.Header.Set("Content-Type", writer.FormDataContentType())
-	req.SetBasicAuth(r.Username, r.Password)
+// outcomeOf classifies the result of an attempt for the circuit breaker.
+// Failures caused by the caller giving up do not count against the API.
+func outcomeOf(ctx context.Context, err error) outcome {
+	if err == nil {
+		return outcomeSuccess
+	}
+	if ctx.Err() != nil || errors.Is(err, ErrAuthentication) {
+		return outcomeIgnored
+	}
 
-	// Send the request
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		if apiErr.Retryable() {
+			return outcomeFailure
+		}
+		return outcomeSuccess
+	}
+	return outcomeFailure
+}
+
+// attempt sends a single request. retry reports whether a failure may
+// succeed if the request is repeated.
+func (r *RouterApi) attempt(
+	ctx context.Context,
+	opts RouterApiOptions,
+	c call,
+) (body []byte, retry bool, err error) {
+	ctx, cancel := context.WithTimeout(ctx, opts.Timeout)
+	defer cancel()
+
+	var reader io.Reader
+	if c.payload != nil {
+		reader = bytes.NewReader(c.payload)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, c.method, r.Url+c.endpoint, reader)
 	if err != nil {
-		return nil, err
+		return nil, false, err
+	}
+	if c.contentType != "" {
+		req.Header.Set("Content-Type", c.contentType)
+	}
+	if c.idempotencyKey != "" {
+		req.Header.Set(IDEMPOTENCY_KEY_HEADER, c.idempotencyKey)
+	}
+	if err := r.authenticator().Authenticate(req, c.payload); err != nil {
+		return nil, false, fmt.Errorf("%w: %w", ErrAuthentication, err)
+	}
+
+	resp, err := opts.HTTPClient.Do(req)
+	if err != nil {
+		return nil, true, err
 	}
 	defer resp.Body.Close()
 
-	// Read response body
-	respBody, err := io.ReadAll(resp.Body)
+	body, err = io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, err
+		return nil, true, err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		apiErr := &APIError{
+			Kind:       kindOf(resp.StatusCode),
+			Endpoint:   c.endpoint,
+			StatusCode: resp.StatusCode,
+			Message:    messageOf(body),
+			RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()),
+		}
+		return nil, apiErr.Retryable(), apiErr
 	}
 
-	return respBody, nil
+	return body, false, nil
+}
+
+// messageOf returns the message of a routerReturn envelope, or the raw body.
+func messageOf(body []byte) string {
+	var result routerReturn
+	if err := json.Unmarshal(body, &result); err == nil && result.Message != "" {
+		return result.Message
+	}
+	return strings.TrimSpace(string(body))
+}
+
+// sleep waits for d or until ctx is done.
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
 }
@@ -0,0 +1,323 @@
+package output_router_api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	d_idempotency "github.com/irissonnlima/chatgraph-go/core/domain/idempotency"
+	d_message "github.com/irissonnlima/chatgraph-go/core/domain/message"
+	d_user "github.com/irissonnlima/chatgraph-go/core/domain/user"
+)
+
+var testChat = d_user.ChatID{UserID: "u1", CompanyID: "c1"}
+
+// fastOptions retry quickly, so tests do not wait for real backoff.
+var fastOptions = RouterApiOptions{
+	Timeout:        time.Second,
+	MaxRetries:     3,
+	RetryBaseDelay: time.Millisecond,
+	RetryMaxDelay:  5 * time.Millisecond,
+}
+
+// newTestApi returns a client for a server answering with the given handler,
+// and the number of requests it received.
+func newTestApi(t *testing.T, handler http.HandlerFunc, options ...RouterApiOptions) (*RouterApi, *atomic.Int32) {
+	t.Helper()
+
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		calls.Add(1)
+		handler(w, req)
+	}))
+	t.Cleanup(server.Close)
+
+	opts := fastOptions
+	if len(options) > 0 {
+		opts = options[0]
+	}
+	return NewRouterApi(server.URL, "user", "pass", opts).(*RouterApi), &calls
+}
+
+func ok(w http.ResponseWriter, _ *http.Request) {
+	fmt.Fprint(w, `{"status": true, "message": "ok"}`)
+}
+
+func TestPost_Success(t *testing.T) {
+	api, calls := newTestApi(t, func(w http.ResponseWriter, req *http.Request) {
+		user, pass, _ := req.BasicAuth()
+		if user != "user" || pass != "pass" {
+			t.Errorf("expected basic auth, got %q:%q", user, pass)
+		}
+		if req.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("expected JSON content type, got %q", req.Header.Get("Content-Type"))
+		}
+		ok(w, req)
+	})
+
+	if err := api.SetRoute(testChat, "menu"); err != nil {
+		t.Fatalf("SetRoute() error = %v", err)
+	}
+	if calls.Load() != 1 {
+		t.Errorf("expected 1 call, got %d", calls.Load())
+	}
+}
+
+func TestPost_RetriesServerErrors(t *testing.T) {
+	var failures atomic.Int32
+	api, calls := newTestApi(t, func(w http.ResponseWriter, req *http.Request) {
+		if failures.Add(1) < 3 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		ok(w, req)
+	})
+
+	if err := api.SetRoute(testChat, "menu"); err != nil {
+		t.Fatalf("SetRoute() error = %v", err)
+	}
+	if calls.Load() != 3 {
+		t.Errorf("expected 3 calls, got %d", calls.Load())
+	}
+}
+
+func TestPost_GivesUp(t *testing.T) {
+	api, calls := newTestApi(t, func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Retry-After", "1")
+		w.WriteHeader(http.StatusTooManyRequests)
+	})
+
+	start := time.Now()
+	err := api.SetRoute(testChat, "menu")
+	if !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("expected ErrRateLimited, got %v", err)
+	}
+	if calls.Load() != 3 {
+		t.Errorf("expected 3 calls, got %d", calls.Load())
+	}
+	if time.Since(start) > 500*time.Millisecond {
+		t.Errorf("expected Retry-After to be capped by RetryMaxDelay")
+	}
+}
+
+func TestPost_DoesNotRetry(t *testing.T) {
+	tests := []struct {
+		name     string
+		handler  http.HandlerFunc
+		expected error
+	}{
+		{
+			name: "status false",
+			handler: func(w http.ResponseWriter, _ *http.Request) {
+				fmt.Fprint(w, `{"status": false, "message": "chat is closed"}`)
+			},
+			expected: ErrRejected,
+		},
+		{
+			name:     "unauthorized",
+			handler:  func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusUnauthorized) },
+			expected: ErrUnauthorized,
+		},
+		{
+			name: "validation",
+			handler: func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(http.StatusBadRequest)
+				fmt.Fprint(w, `{"status": false, "message": "route is required"}`)
+			},
+			expected: ErrValidation,
+		},
+		{
+			name:     "not found",
+			handler:  func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusNotFound) },
+			expected: ErrNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api, calls := newTestApi(t, tt.handler)
+
+			err := api.SetRoute(testChat, "menu")
+			if !errors.Is(err, tt.expected) {
+				t.Fatalf("expected %v, got %v", tt.expected, err)
+			}
+			var apiErr *APIError
+			if !errors.As(err, &apiErr) || apiErr.Endpoint != "/v1/actions/session/route" {
+				t.Errorf("expected an APIError for the route endpoint, got %#v", err)
+			}
+			if calls.Load() != 1 {
+				t.Errorf("expected 1 call, got %d", calls.Load())
+			}
+		})
+	}
+
+	api, _ := newTestApi(t, func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, `{"status": false, "message": "route is required"}`)
+	})
+	var apiErr *APIError
+	if err := api.SetRoute(testChat, ""); !errors.As(err, &apiErr) || apiErr.Message != "route is required" {
+		t.Errorf("expected the server message, got %v", err)
+	}
+}
+
+func TestWithContext_StopsRetrying(t *testing.T) {
+	api, calls := newTestApi(t, func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}, RouterApiOptions{MaxRetries: 5, RetryBaseDelay: time.Hour, RetryMaxDelay: time.Hour})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	err := api.WithContext(ctx).SendMessage(testChat, d_message.Message{}, "whatsapp")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected DeadlineExceeded, got %v", err)
+	}
+	if time.Since(start) > time.Second {
+		t.Error("expected the wait between retries to stop with the context")
+	}
+	if calls.Load() != 1 {
+		t.Errorf("expected 1 call, got %d", calls.Load())
+	}
+}
+
+func TestIdempotencyKey(t *testing.T) {
+	var keys []string
+	api, _ := newTestApi(t, func(w http.ResponseWriter, req *http.Request) {
+		keys = append(keys, req.Header.Get(IDEMPOTENCY_KEY_HEADER))
+		if len(keys) == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		ok(w, req)
+	})
+
+	ctx := d_idempotency.WithScope(context.Background(), d_idempotency.NewScope(testChat, "m1", "start"))
+	bound := api.WithContext(ctx)
+	if err := bound.SendMessage(testChat, d_message.Message{}, "whatsapp"); err != nil {
+		t.Fatalf("SendMessage() error = %v", err)
+	}
+	if err := bound.SetRoute(testChat, "menu"); err != nil {
+		t.Fatalf("SetRoute() error = %v", err)
+	}
+
+	first := d_idempotency.Key(testChat, "m1", "start", 1)
+	second := d_idempotency.Key(testChat, "m1", "start", 2)
+	if len(keys) != 3 || keys[0] != first || keys[1] != first || keys[2] != second {
+		t.Errorf("expected retries to reuse the key of their action, got %v", keys)
+	}
+
+	keys = nil
+	if err := api.SetRoute(testChat, "menu"); err != nil {
+		t.Fatalf("SetRoute() error = %v", err)
+	}
+	if keys[0] != "" {
+		t.Errorf("expected no key without a scope, got %q", keys[0])
+	}
+}
+
+func TestAttemptTimeout(t *testing.T) {
+	var attempts atomic.Int32
+	api, _ := newTestApi(t, func(w http.ResponseWriter, req *http.Request) {
+		if attempts.Add(1) == 1 {
+			select {
+			case <-req.Context().Done():
+			case <-time.After(200 * time.Millisecond):
+			}
+			return
+		}
+		ok(w, req)
+	}, RouterApiOptions{Timeout: 20 * time.Millisecond, MaxRetries: 2, RetryBaseDelay: time.Millisecond})
+
+	if err := api.SetRoute(testChat, "menu"); err != nil {
+		t.Fatalf("expected the timed out attempt to be retried, got %v", err)
+	}
+}
+
+func TestSharedHTTPClient(t *testing.T) {
+	var used atomic.Int32
+	client := &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		used.Add(1)
+		return http.DefaultTransport.RoundTrip(req)
+	})}
+
+	opts := fastOptions
+	opts.HTTPClient = client
+	api, _ := newTestApi(t, ok, opts)
+
+	api.SetRoute(testChat, "a")
+	api.SetObservation(testChat, "{}")
+	if used.Load() != 2 {
+		t.Errorf("expected the configured client to send both requests, got %d", used.Load())
+	}
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
+
+func TestUploadFile_NotFoundUploads(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "note.txt")
+	if err := os.WriteFile(path, []byte("content"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	api, _ := newTestApi(t, func(w http.ResponseWriter, req *http.Request) {
+		if req.Method == http.MethodGet {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if req.URL.Path != "/v1/actions/files/upload" {
+			t.Errorf("unexpected path %s", req.URL.Path)
+		}
+		fmt.Fprint(w, `{"status": true, "message": "ok", "data": {"id": "f1", "type": "file", "url": "http://files/f1", "name": "note.txt"}}`)
+	})
+
+	file, err := api.UploadFile(path)
+	if err != nil {
+		t.Fatalf("UploadFile() error = %v", err)
+	}
+	if file.ID != "f1" {
+		t.Errorf("expected file f1, got %+v", file)
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	opts := RouterApiOptions{RetryBaseDelay: 100 * time.Millisecond, RetryMaxDelay: time.Second}.withDefaults()
+
+	for attempt := 1; attempt <= 40; attempt++ {
+		limit := min(100*time.Millisecond<<min(attempt-1, 20), time.Second)
+		if d := opts.retryDelay(attempt, 0); d <= 0 || d > limit {
+			t.Errorf("attempt %d: delay %s out of (0, %s]", attempt, d, limit)
+		}
+	}
+	if d := opts.retryDelay(1, 5*time.Second); d != time.Second {
+		t.Errorf("expected Retry-After to be capped at 1s, got %s", d)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := map[string]time.Duration{
+		"":                              0,
+		"3":                             3 * time.Second,
+		"-1":                            0,
+		"soon":                          0,
+		"Mon, 01 Jan 2024 12:00:10 GMT": 10 * time.Second,
+		"Mon, 01 Jan 2024 11:00:00 GMT": 0,
+	}
+	for value, expected := range tests {
+		if got := parseRetryAfter(value, now); got != expected {
+			t.Errorf("parseRetryAfter(%q) = %s, want %s", value, got, expected)
+		}
+	}
+}
@@ -1,7 +1,6 @@
 package output_router_api
 
 import (
-	dto_file "github.com/irissonnlima/chatgraph-go/adapters/dto/file"
 	dto_message "github.com/irissonnlima/chatgraph-go/adapters/dto/message"
 	dto_user "github.com/irissonnlima/chatgraph-go/adapters/dto/user"
 	d_message "github.com/irissonnlima/chatgraph-go/core/domain/message"
@@ -24,33 +23,11 @@ func (r *RouterApi) SendMessage(to d_user.ChatID, message d_message.Message, pla
 		}
 	}
 
-	buttons := make([]dto_message.Button, len(message.Buttons))
-	for i, btn := range message.Buttons {
-		buttons[i] = dto_message.Button{
-			Type:   btn.Type.String(),
-			Title:  btn.Title,
-			Detail: btn.Detail,
-		}
-	}
-
-	var displayButton *dto_message.Button = nil
-	if !message.DisplayButton.IsEmpty() {
-		displayButton = &dto_message.Button{
-			Type:   message.DisplayButton.Type.String(),
-			Title:  message.DisplayButton.Title,
-			Detail: message.DisplayButton.Detail,
-		}
-	}
-
-	var file *dto_file.File = nil
-	if message.HasFile() {
-		file = &dto_file.File{
-			ID:   message.File.ID,
-			Type: message.File.Type.String(),
-			URL:  message.File.URL,
-			Name: message.File.Name,
-		}
-	}
+	// The send endpoint never received the message id or date; they are left
+	// empty so the wire format stays as it was.
+	dtoMessage := dto_message.MessageFromDomain(message)
+	dtoMessage.TextMessage.ID = ""
+	dtoMessage.DateTime = ""
 
 	payload := SendMessagePayload{
 		UserState: dto_user.UserState{
@@ -60,17 +37,7 @@ func (r *RouterApi) SendMessage(to d_user.ChatID, message d_message.Message, pla
 			},
 			Platform: platform,
 		},
-		Message: dto_message.Message{
-			TextMessage: &dto_message.TextMessage{
-				Title:        message.TextMessage.Title,
-				Detail:       message.TextMessage.Detail,
-				Caption:      message.TextMessage.Caption,
-				MentionedIds: message.TextMessage.MentionedIds,
-			},
-			Buttons:       buttons,
-			DisplayButton: displayButton,
-			File:          file,
-		},
+		Message: dtoMessage,
 	}
 
 	jsonPayload, err := json.Marshal(payload)
@@ -78,5 +45,5 @@ func (r *RouterApi) SendMessage(to d_user.ChatID, message d_message.Message, pla
 		return err
 	}
 
-	return r.post("/v1/actions/messages/send", jsonPayload)
+	return r.post("/v1/actions/messages/send", to, jsonPayload)
 }
@@ -0,0 +1,38 @@
+package output_router_api
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+
+	d_message "github.com/irissonnlima/chatgraph-go/core/domain/message"
+)
+
+func TestSendMessage_WireFormat(t *testing.T) {
+	var got map[string]map[string]any
+	api, _ := newTestApi(t, func(w http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+		if err := json.Unmarshal(body, &got); err != nil {
+			t.Errorf("invalid payload: %v", err)
+		}
+		ok(w, req)
+	})
+
+	message := d_message.Message{
+		TextMessage: d_message.TextMessage{ID: "m1", Detail: "hello"},
+		DateTime:    "2024-01-01T00:00:00Z",
+	}
+	if err := api.SendMessage(testChat, message, "whatsapp"); err != nil {
+		t.Fatalf("SendMessage() error = %v", err)
+	}
+
+	sent := got["message"]
+	if sent["date_time"] != "" {
+		t.Errorf("expected an empty date_time, got %v", sent["date_time"])
+	}
+	text, _ := sent["text_message"].(map[string]any)
+	if text["id"] != "" || text["detail"] != "hello" {
+		t.Errorf("expected the text without its id, got %v", text)
+	}
+}
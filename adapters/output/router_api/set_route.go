@@ -24,5 +24,5 @@ func (r *RouterApi) SetRoute(chatID d_user.ChatID, route string) error {
 		return err
 	}
 
-	return r.post("/v1/actions/session/route", jsonPayload)
+	return r.post("/v1/actions/session/route", chatID, jsonPayload)
 }
@@ -0,0 +1,151 @@
+// Package boltstore provides an ISessionRepository backed by an embedded bbolt
+// key-value database, a single file with transactional, crash-safe writes.
+package boltstore
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	dto_session "github.com/irissonnlima/chatgraph-go/adapters/dto/session"
+	d_session "github.com/irissonnlima/chatgraph-go/core/domain/session"
+	d_user "github.com/irissonnlima/chatgraph-go/core/domain/user"
+	bolt "go.etcd.io/bbolt"
+)
+
+const (
+	// DEFAULT_BUCKET is the bucket holding the sessions.
+	DEFAULT_BUCKET = "sessions"
+	// DEFAULT_TIMEOUT is how long to wait for the database file lock.
+	DEFAULT_TIMEOUT = 5 * time.Second
+	// DEFAULT_FILE_MODE is the permission of the database file.
+	DEFAULT_FILE_MODE os.FileMode = 0o600
+)
+
+// ErrMissingPath is returned when no database path is given.
+var ErrMissingPath = errors.New("boltstore: path is required")
+
+// BoltStoreOptions configures the bbolt session store.
+type BoltStoreOptions struct {
+	// Bucket is the bucket holding the sessions. Defaults to DEFAULT_BUCKET.
+	Bucket string
+	// Timeout is how long to wait for the file lock held by another process.
+	// Defaults to DEFAULT_TIMEOUT.
+	Timeout time.Duration
+}
+
+// BoltStore stores sessions as JSON values keyed by chat ID.
+type BoltStore struct {
+	db     *bolt.DB
+	bucket []byte
+}
+
+// NewBoltStore opens (or creates) the database at path.
+// Only one process can open the database at a time.
+func NewBoltStore(path string, options ...BoltStoreOptions) (*BoltStore, error) {
+	if path == "" {
+		return nil, ErrMissingPath
+	}
+
+	opts := BoltStoreOptions{}
+	if len(options) > 0 {
+		opts = options[0]
+	}
+	if opts.Bucket == "" {
+		opts.Bucket = DEFAULT_BUCKET
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = DEFAULT_TIMEOUT
+	}
+
+	db, err := bolt.Open(path, DEFAULT_FILE_MODE, &bolt.Options{Timeout: opts.Timeout})
+	if err != nil {
+		return nil, fmt.Errorf("boltstore: %w", err)
+	}
+
+	bucket := []byte(opts.Bucket)
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(bucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("boltstore: %w", err)
+	}
+
+	return &BoltStore{db: db, bucket: bucket}, nil
+}
+
+// LoadSession returns the stored session of a chat.
+func (s *BoltStore) LoadSession(chatID d_user.ChatID) (d_session.Session, bool, error) {
+	var (
+		session d_session.Session
+		ok      bool
+	)
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		var err error
+		session, ok, err = s.get(tx, chatID)
+		return err
+	})
+	if err != nil {
+		return d_session.Session{}, false, fmt.Errorf("boltstore: %w", err)
+	}
+	return session, ok, nil
+}
+
+// UpdateSession applies update to the session of a chat in a single transaction.
+func (s *BoltStore) UpdateSession(chatID d_user.ChatID, update func(session *d_session.Session) error) error {
+	var updateErr error
+
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		session, _, err := s.get(tx, chatID)
+		if err != nil {
+			return err
+		}
+		if updateErr = update(&session); updateErr != nil {
+			return updateErr
+		}
+		session.ChatID = chatID
+
+		data, err := json.Marshal(dto_session.FromDomain(session))
+		if err != nil {
+			return err
+		}
+		return tx.Bucket(s.bucket).Put(key(chatID), data)
+	})
+	if updateErr != nil {
+		return updateErr
+	}
+	if err != nil {
+		return fmt.Errorf("boltstore: %w", err)
+	}
+	return nil
+}
+
+// Close closes the database.
+func (s *BoltStore) Close() error {
+	return s.db.Close()
+}
+
+// get reads the session of a chat within tx.
+func (s *BoltStore) get(tx *bolt.Tx, chatID d_user.ChatID) (d_session.Session, bool, error) {
+	data := tx.Bucket(s.bucket).Get(key(chatID))
+	if data == nil {
+		return d_session.Session{}, false, nil
+	}
+
+	var dto dto_session.Session
+	if err := json.Unmarshal(data, &dto); err != nil {
+		return d_session.Session{}, false, fmt.Errorf("invalid session: %w", err)
+	}
+	return dto.ToDomain(), true, nil
+}
+
+// key returns the database key of a chat, separating the IDs with a NUL byte,
+// which chat IDs do not contain.
+func key(chatID d_user.ChatID) []byte {
+	return []byte(chatID.CompanyID + "\x00" + chatID.UserID)
+}
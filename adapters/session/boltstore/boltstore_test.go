@@ -0,0 +1,82 @@
+package boltstore
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	d_session "github.com/irissonnlima/chatgraph-go/core/domain/session"
+	d_user "github.com/irissonnlima/chatgraph-go/core/domain/user"
+)
+
+var testChat = d_user.ChatID{UserID: "u1", CompanyID: "c1"}
+
+func TestNewBoltStore_MissingPath(t *testing.T) {
+	if _, err := NewBoltStore(""); !errors.Is(err, ErrMissingPath) {
+		t.Errorf("expected ErrMissingPath, got %v", err)
+	}
+}
+
+func TestBoltStore_RoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sessions.db")
+
+	store, err := NewBoltStore(path)
+	if err != nil {
+		t.Fatalf("NewBoltStore() error = %v", err)
+	}
+
+	if _, ok, err := store.LoadSession(testChat); err != nil || ok {
+		t.Fatalf("expected no session, got ok=%v err=%v", ok, err)
+	}
+
+	err = store.UpdateSession(testChat, func(s *d_session.Session) error {
+		s.ID = 1
+		s.Route = "start.menu"
+		s.Observation = `{"name":"Ana"}`
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("UpdateSession() error = %v", err)
+	}
+	if err := store.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	// Reopening the database keeps the session.
+	store, err = NewBoltStore(path)
+	if err != nil {
+		t.Fatalf("NewBoltStore() error = %v", err)
+	}
+	defer store.Close()
+
+	session, ok, err := store.LoadSession(testChat)
+	if err != nil || !ok {
+		t.Fatalf("expected a session, got ok=%v err=%v", ok, err)
+	}
+	if session.ID != 1 || session.Route != "start.menu" || session.Observation != `{"name":"Ana"}` {
+		t.Errorf("unexpected session %+v", session)
+	}
+	if session.ChatID != testChat {
+		t.Errorf("expected chat id to be set, got %+v", session.ChatID)
+	}
+}
+
+func TestBoltStore_UpdateError(t *testing.T) {
+	store, err := NewBoltStore(filepath.Join(t.TempDir(), "sessions.db"), BoltStoreOptions{Bucket: "custom"})
+	if err != nil {
+		t.Fatalf("NewBoltStore() error = %v", err)
+	}
+	defer store.Close()
+
+	failure := errors.New("failure")
+	err = store.UpdateSession(testChat, func(s *d_session.Session) error {
+		s.ID = 1
+		return failure
+	})
+	if !errors.Is(err, failure) {
+		t.Fatalf("expected update error, got %v", err)
+	}
+	if _, ok, _ := store.LoadSession(testChat); ok {
+		t.Error("expected nothing to be stored")
+	}
+}
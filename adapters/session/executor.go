@@ -0,0 +1,21 @@
+package session
+
+import (
+	output_compose "github.com/irissonnlima/chatgraph-go/adapters/output/compose"
+	adapter_output "github.com/irissonnlima/chatgraph-go/core/ports/adapters/output"
+)
+
+// NewExecutor creates an executor that keeps sessions and menu transfers in
+// repository and sends messages through messenger. Files and optional
+// capabilities are served by messenger when it provides them.
+func NewExecutor(messenger adapter_output.IMessenger, repository adapter_output.ISessionRepository, options ...SessionOptions) adapter_output.IBotExecutor {
+	sessions := NewStore(repository, options...)
+	files, _ := messenger.(adapter_output.IFileStore)
+
+	return output_compose.NewExecutor(output_compose.Parts{
+		Messenger:  messenger,
+		Sessions:   sessions,
+		Transferer: sessions,
+		Files:      files,
+	})
+}
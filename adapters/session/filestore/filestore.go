@@ -0,0 +1,148 @@
+// Package filestore provides an ISessionRepository that keeps one JSON file per chat
+// in a directory. It suits single-instance bots and local development.
+package filestore
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	dto_session "github.com/irissonnlima/chatgraph-go/adapters/dto/session"
+	d_session "github.com/irissonnlima/chatgraph-go/core/domain/session"
+	d_user "github.com/irissonnlima/chatgraph-go/core/domain/user"
+)
+
+// DEFAULT_FILE_MODE is the permission of the session files.
+const DEFAULT_FILE_MODE os.FileMode = 0o600
+
+var (
+	// ErrMissingDir is returned when no directory is given.
+	ErrMissingDir = errors.New("filestore: directory is required")
+	// ErrClosed is returned when using a closed store.
+	ErrClosed = errors.New("filestore: store is closed")
+)
+
+// FileStore stores sessions as JSON files named after the chat ID.
+// Writes go to a temporary file that is renamed over the previous one, so a
+// crash never leaves a partially written session behind.
+type FileStore struct {
+	dir string
+
+	mu     sync.Mutex
+	closed bool
+}
+
+// NewFileStore creates a store in dir, creating the directory if needed.
+func NewFileStore(dir string) (*FileStore, error) {
+	if dir == "" {
+		return nil, ErrMissingDir
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("filestore: %w", err)
+	}
+	return &FileStore{dir: dir}, nil
+}
+
+// LoadSession returns the stored session of a chat.
+func (s *FileStore) LoadSession(chatID d_user.ChatID) (d_session.Session, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return d_session.Session{}, false, ErrClosed
+	}
+	return s.load(chatID)
+}
+
+// UpdateSession applies update to the session of a chat and writes it back.
+func (s *FileStore) UpdateSession(chatID d_user.ChatID, update func(session *d_session.Session) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return ErrClosed
+	}
+
+	session, _, err := s.load(chatID)
+	if err != nil {
+		return err
+	}
+	if err := update(&session); err != nil {
+		return err
+	}
+	session.ChatID = chatID
+	return s.save(session)
+}
+
+// Close marks the store as closed.
+func (s *FileStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.closed = true
+	return nil
+}
+
+// load reads the session file of a chat. Must be called with s.mu held.
+func (s *FileStore) load(chatID d_user.ChatID) (d_session.Session, bool, error) {
+	data, err := os.ReadFile(s.path(chatID))
+	if errors.Is(err, os.ErrNotExist) {
+		return d_session.Session{}, false, nil
+	}
+	if err != nil {
+		return d_session.Session{}, false, fmt.Errorf("filestore: %w", err)
+	}
+
+	var dto dto_session.Session
+	if err := json.Unmarshal(data, &dto); err != nil {
+		return d_session.Session{}, false, fmt.Errorf("filestore: invalid session file: %w", err)
+	}
+	return dto.ToDomain(), true, nil
+}
+
+// save atomically writes the session file of a chat. Must be called with s.mu held.
+func (s *FileStore) save(session d_session.Session) error {
+	data, err := json.Marshal(dto_session.FromDomain(session))
+	if err != nil {
+		return fmt.Errorf("filestore: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(s.dir, ".session-*")
+	if err != nil {
+		return fmt.Errorf("filestore: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("filestore: %w", err)
+	}
+	if err := tmp.Chmod(DEFAULT_FILE_MODE); err != nil {
+		tmp.Close()
+		return fmt.Errorf("filestore: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("filestore: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("filestore: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), s.path(session.ChatID)); err != nil {
+		return fmt.Errorf("filestore: %w", err)
+	}
+	return nil
+}
+
+// path returns the session file of a chat. IDs are base64url-encoded, so any
+// value maps to a valid file name and the '.' between them is unambiguous.
+func (s *FileStore) path(chatID d_user.ChatID) string {
+	name := base64.RawURLEncoding.EncodeToString([]byte(chatID.CompanyID)) + "." +
+		base64.RawURLEncoding.EncodeToString([]byte(chatID.UserID)) + ".json"
+	return filepath.Join(s.dir, name)
+}
@@ -0,0 +1,124 @@
+package filestore
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	d_session "github.com/irissonnlima/chatgraph-go/core/domain/session"
+	d_user "github.com/irissonnlima/chatgraph-go/core/domain/user"
+)
+
+var testChat = d_user.ChatID{UserID: "u1", CompanyID: "c1"}
+
+func newTestStore(t *testing.T, dir string) *FileStore {
+	t.Helper()
+
+	store, err := NewFileStore(dir)
+	if err != nil {
+		t.Fatalf("NewFileStore() error = %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+func TestNewFileStore_MissingDir(t *testing.T) {
+	if _, err := NewFileStore(""); !errors.Is(err, ErrMissingDir) {
+		t.Errorf("expected ErrMissingDir, got %v", err)
+	}
+}
+
+func TestFileStore_RoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	store := newTestStore(t, dir)
+
+	if _, ok, err := store.LoadSession(testChat); err != nil || ok {
+		t.Fatalf("expected no session, got ok=%v err=%v", ok, err)
+	}
+
+	err := store.UpdateSession(testChat, func(s *d_session.Session) error {
+		if !s.IsEmpty() {
+			t.Errorf("expected an empty session, got %+v", s)
+		}
+		s.ID = 1
+		s.Route = "start.menu"
+		s.Observation = `{"name":"Ana"}`
+		s.Menu = d_user.Menu{ID: 2}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("UpdateSession() error = %v", err)
+	}
+
+	// A new store on the same directory sees the session.
+	session, ok, err := newTestStore(t, dir).LoadSession(testChat)
+	if err != nil || !ok {
+		t.Fatalf("expected a session, got ok=%v err=%v", ok, err)
+	}
+	if session.ID != 1 || session.Route != "start.menu" || session.Observation != `{"name":"Ana"}` || session.Menu.ID != 2 {
+		t.Errorf("unexpected session %+v", session)
+	}
+	if session.ChatID != testChat {
+		t.Errorf("expected chat id to be set, got %+v", session.ChatID)
+	}
+
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Errorf("expected only the session file, got %d entries", len(entries))
+	}
+}
+
+func TestFileStore_UpdateError(t *testing.T) {
+	store := newTestStore(t, t.TempDir())
+	failure := errors.New("failure")
+
+	err := store.UpdateSession(testChat, func(s *d_session.Session) error {
+		s.ID = 1
+		return failure
+	})
+	if !errors.Is(err, failure) {
+		t.Fatalf("expected update error, got %v", err)
+	}
+	if _, ok, _ := store.LoadSession(testChat); ok {
+		t.Error("expected nothing to be stored")
+	}
+}
+
+func TestFileStore_DistinctChats(t *testing.T) {
+	store := newTestStore(t, t.TempDir())
+	chats := []d_user.ChatID{
+		{CompanyID: "a.b", UserID: "c"},
+		{CompanyID: "a", UserID: "b.c"},
+		{CompanyID: "../x", UserID: "/y"},
+	}
+
+	for i, chatID := range chats {
+		err := store.UpdateSession(chatID, func(s *d_session.Session) error {
+			s.ID = int64(i + 1)
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("UpdateSession(%+v) error = %v", chatID, err)
+		}
+	}
+
+	for i, chatID := range chats {
+		session, ok, err := store.LoadSession(chatID)
+		if err != nil || !ok || session.ID != int64(i+1) {
+			t.Errorf("LoadSession(%+v) = %+v, %v, %v", chatID, session, ok, err)
+		}
+	}
+}
+
+func TestFileStore_Closed(t *testing.T) {
+	store := newTestStore(t, t.TempDir())
+	store.Close()
+
+	if _, _, err := store.LoadSession(testChat); !errors.Is(err, ErrClosed) {
+		t.Errorf("expected ErrClosed, got %v", err)
+	}
+	err := store.UpdateSession(testChat, func(s *d_session.Session) error { return nil })
+	if !errors.Is(err, ErrClosed) {
+		t.Errorf("expected ErrClosed, got %v", err)
+	}
+}
@@ -0,0 +1,34 @@
+package session
+
+import "time"
+
+// DEFAULT_START_ROUTE is the route of new sessions.
+const DEFAULT_START_ROUTE = "start"
+
+// SessionOptions configures the session receiver and store.
+type SessionOptions struct {
+	// StartRoute is the route of new sessions. Defaults to DEFAULT_START_ROUTE.
+	StartRoute string
+	// Now returns the current time, used for session timestamps. Defaults to time.Now.
+	Now func() time.Time
+}
+
+// withDefaults returns a copy of the options with defaults applied.
+func (o SessionOptions) withDefaults() SessionOptions {
+	if o.StartRoute == "" {
+		o.StartRoute = DEFAULT_START_ROUTE
+	}
+	if o.Now == nil {
+		o.Now = time.Now
+	}
+	return o
+}
+
+// firstOptions returns the first options given, with defaults applied.
+func firstOptions(options []SessionOptions) SessionOptions {
+	opts := SessionOptions{}
+	if len(options) > 0 {
+		opts = options[0]
+	}
+	return opts.withDefaults()
+}
@@ -0,0 +1,131 @@
+// Package session lets the chatbot keep its own session state in an
+// ISessionRepository, so it can run against a bare messaging channel instead
+// of the Router API.
+//
+// A Receiver loads the stored user state of each incoming message before the
+// engine runs, and a Store persists route, observation and session changes
+// afterwards. NewExecutor pairs a Store with another adapter for messaging:
+//
+//	repository, _ := boltstore.NewBoltStore("sessions.db")
+//	receiver := session.NewReceiver[Obs](rabbit, repository)
+//	executor := session.NewExecutor(messenger, repository)
+//	app := service.NewChatbotApp(engine, receiver, executor)
+package session
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+
+	d_session "github.com/irissonnlima/chatgraph-go/core/domain/session"
+	d_user "github.com/irissonnlima/chatgraph-go/core/domain/user"
+	adapter_input "github.com/irissonnlima/chatgraph-go/core/ports/adapters/input"
+	adapter_output "github.com/irissonnlima/chatgraph-go/core/ports/adapters/output"
+)
+
+// ErrClosed is the reason used to nack deliveries that arrive after Close.
+var ErrClosed = errors.New("session: receiver is closed")
+
+// Receiver is an IMessageReceiver that fills the user state of each delivery
+// of another receiver with the session kept in a repository.
+type Receiver[Obs any] struct {
+	receiver   adapter_input.IMessageReceiver[Obs]
+	repository adapter_output.ISessionRepository
+	options    SessionOptions
+
+	once      sync.Once
+	out       chan adapter_input.Delivery[Obs]
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// NewReceiver wraps receiver, loading the user state of its deliveries from repository.
+func NewReceiver[Obs any](receiver adapter_input.IMessageReceiver[Obs], repository adapter_output.ISessionRepository, options ...SessionOptions) *Receiver[Obs] {
+	return &Receiver[Obs]{
+		receiver:   receiver,
+		repository: repository,
+		options:    firstOptions(options),
+		out:        make(chan adapter_input.Delivery[Obs]),
+		done:       make(chan struct{}),
+	}
+}
+
+// ConsumeMessage starts consuming from the wrapped receiver and returns the
+// channel of deliveries with their stored user state. Deliveries whose session
+// cannot be loaded are nacked and skipped.
+//
+// The state is loaded when the delivery is forwarded, possibly before earlier
+// messages of the chat were handled. Executors built by NewExecutor load it
+// again right before the handler runs (see adapter_output.IStateLoader), so
+// the handler sees their changes; the state loaded here starts new sessions.
+func (r *Receiver[Obs]) ConsumeMessage() <-chan adapter_input.Delivery[Obs] {
+	r.once.Do(func() {
+		go func() {
+			defer close(r.out)
+
+			for delivery := range r.receiver.ConsumeMessage() {
+				state, err := r.loadState(delivery.UserState)
+				if err != nil {
+					log.Printf("[ERROR] Failed to load session of %s/%s: %v",
+						delivery.UserState.ChatID.CompanyID, delivery.UserState.ChatID.UserID, err)
+					_ = delivery.Nack(err)
+					continue
+				}
+				delivery.UserState = state
+
+				select {
+				case r.out <- delivery:
+				case <-r.done:
+					_ = delivery.Nack(ErrClosed)
+				}
+			}
+		}()
+	})
+
+	return r.out
+}
+
+// loadState returns the stored state of the chat, keeping the user
+// information and platform reported by the incoming message when present.
+func (r *Receiver[Obs]) loadState(incoming d_user.UserState[Obs]) (d_user.UserState[Obs], error) {
+	if incoming.ChatID.IsEmpty() {
+		return incoming, fmt.Errorf("session: delivery has no chat id")
+	}
+
+	stored, ok, err := r.repository.LoadSession(incoming.ChatID)
+	if err != nil {
+		return incoming, err
+	}
+	if !ok {
+		stored = d_session.New(incoming.ChatID, 1, r.options.StartRoute, r.options.Now())
+	}
+
+	state, err := d_session.ToUserState[Obs](stored)
+	if err != nil {
+		return incoming, fmt.Errorf("session: invalid stored observation: %w", err)
+	}
+
+	state.ChatID = incoming.ChatID
+	if !incoming.User.IsEmpty() {
+		state.User = incoming.User
+	}
+	if incoming.Platform != "" {
+		state.Platform = incoming.Platform
+	}
+	return state, nil
+}
+
+// SetPrefetch forwards the prefetch limit to the wrapped receiver when it supports it.
+func (r *Receiver[Obs]) SetPrefetch(count int) error {
+	if setter, ok := r.receiver.(adapter_input.IPrefetchSetter); ok {
+		return setter.SetPrefetch(count)
+	}
+	return nil
+}
+
+// Close closes the wrapped receiver. The repository is not closed.
+func (r *Receiver[Obs]) Close() error {
+	r.closeOnce.Do(func() { close(r.done) })
+	return r.receiver.Close()
+}
@@ -0,0 +1,239 @@
+package session
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/irissonnlima/chatgraph-go/adapters/session/filestore"
+	route_return "github.com/irissonnlima/chatgraph-go/core/domain"
+	d_action "github.com/irissonnlima/chatgraph-go/core/domain/action"
+	d_context "github.com/irissonnlima/chatgraph-go/core/domain/context"
+	d_message "github.com/irissonnlima/chatgraph-go/core/domain/message"
+	d_session "github.com/irissonnlima/chatgraph-go/core/domain/session"
+	d_user "github.com/irissonnlima/chatgraph-go/core/domain/user"
+	adapter_input "github.com/irissonnlima/chatgraph-go/core/ports/adapters/input"
+	"github.com/irissonnlima/chatgraph-go/core/service"
+)
+
+type testObs struct {
+	Name string `json:"name,omitempty"`
+}
+
+var testChat = d_user.ChatID{UserID: "u1", CompanyID: "c1"}
+
+// settled reports the outcome of a delivery on a channel.
+type settled chan error
+
+func (s settled) Ack() error              { s <- nil; return nil }
+func (s settled) Nack(reason error) error { s <- reason; return nil }
+
+// chanReceiver is a bare messaging channel: deliveries carry only the chat ID.
+type chanReceiver struct {
+	out  chan adapter_input.Delivery[testObs]
+	once sync.Once
+}
+
+func newChanReceiver() *chanReceiver {
+	return &chanReceiver{out: make(chan adapter_input.Delivery[testObs])}
+}
+
+func (r *chanReceiver) ConsumeMessage() <-chan adapter_input.Delivery[testObs] { return r.out }
+
+func (r *chanReceiver) Close() error {
+	r.once.Do(func() { close(r.out) })
+	return nil
+}
+
+// send delivers a text message and waits until it is settled.
+func (r *chanReceiver) send(t *testing.T, text string) error {
+	t.Helper()
+
+	ack := make(settled, 1)
+	r.out <- adapter_input.Delivery[testObs]{
+		UserState:    d_user.UserState[testObs]{ChatID: testChat, Platform: "test"},
+		Message:      d_message.Message{TextMessage: d_message.TextMessage{Detail: text}},
+		Acknowledger: ack,
+	}
+
+	select {
+	case err := <-ack:
+		return err
+	case <-time.After(5 * time.Second):
+		t.Fatal("delivery was not settled")
+		return nil
+	}
+}
+
+// messenger records sent messages.
+type messenger struct {
+	mu   sync.Mutex
+	sent []string
+}
+
+func (m *messenger) SendMessage(to d_user.ChatID, message d_message.Message, platform string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sent = append(m.sent, message.EntireText())
+	return nil
+}
+
+func newTestEngine() *service.Engine[testObs] {
+	engine := service.NewEngine[testObs]()
+	engine.RegisterRoute("timeout_route", func(ctx *d_context.ChatContext[testObs]) route_return.RouteReturn {
+		return nil
+	})
+	engine.RegisterRoute("loop_route", func(ctx *d_context.ChatContext[testObs]) route_return.RouteReturn {
+		return nil
+	})
+	engine.RegisterRoute("start", func(ctx *d_context.ChatContext[testObs]) route_return.RouteReturn {
+		ctx.SendTextMessage("What is your name?")
+		return ctx.NextRoute("name")
+	})
+	engine.RegisterRoute("name", func(ctx *d_context.ChatContext[testObs]) route_return.RouteReturn {
+		switch ctx.Message.EntireText() {
+		case "bye":
+			return &d_action.EndAction{ID: "bye"}
+		case "support":
+			return &d_action.TransferToMenu{MenuID: 7, Route: "queue"}
+		}
+		if ctx.UserState.Observation.Name != "" {
+			ctx.SendTextMessage("Welcome back " + ctx.UserState.Observation.Name)
+			return nil
+		}
+		ctx.SetObservation(testObs{Name: ctx.Message.EntireText()})
+		ctx.SendTextMessage("Hello " + ctx.Message.EntireText())
+		return nil
+	})
+	return engine
+}
+
+// runApp runs a ChatbotApp over a session receiver and executor until the test ends.
+func runApp(t *testing.T, store *filestore.FileStore, m *messenger) *chanReceiver {
+	t.Helper()
+
+	inner := newChanReceiver()
+	app := service.NewChatbotApp(
+		newTestEngine(),
+		NewReceiver[testObs](inner, store),
+		NewExecutor(m, store),
+	)
+
+	done := make(chan error, 1)
+	go func() { done <- app.Start(context.Background()) }()
+	t.Cleanup(func() {
+		_ = app.Shutdown(context.Background())
+		<-done
+	})
+	return inner
+}
+
+func newTestStore(t *testing.T, dir string) *filestore.FileStore {
+	t.Helper()
+
+	store, err := filestore.NewFileStore(dir)
+	if err != nil {
+		t.Fatalf("NewFileStore() error = %v", err)
+	}
+	return store
+}
+
+func TestSession_PersistsState(t *testing.T) {
+	dir := t.TempDir()
+	m := &messenger{}
+	inner := runApp(t, newTestStore(t, dir), m)
+
+	for _, text := range []string{"hi", "Ana", "again"} {
+		if err := inner.send(t, text); err != nil {
+			t.Fatalf("send(%q) error = %v", text, err)
+		}
+	}
+
+	want := []string{"What is your name?", "Hello Ana", "Welcome back Ana"}
+	if len(m.sent) != len(want) {
+		t.Fatalf("expected %v, got %v", want, m.sent)
+	}
+	for i := range want {
+		if m.sent[i] != want[i] {
+			t.Errorf("message %d: expected %q, got %q", i, want[i], m.sent[i])
+		}
+	}
+
+	// Another store on the same directory sees the persisted session.
+	session, ok, err := newTestStore(t, dir).LoadSession(testChat)
+	if err != nil || !ok {
+		t.Fatalf("expected a stored session, got ok=%v err=%v", ok, err)
+	}
+	if session.ID != 1 || session.Observation != `{"name":"Ana"}` {
+		t.Errorf("unexpected session %+v", session)
+	}
+	if session.Route != "start.name.name.name" {
+		t.Errorf("expected every route to be appended, got %q", session.Route)
+	}
+}
+
+func TestSession_EndAndTransfer(t *testing.T) {
+	store := newTestStore(t, t.TempDir())
+	m := &messenger{}
+	inner := runApp(t, store, m)
+
+	for _, text := range []string{"hi", "Ana", "bye"} {
+		if err := inner.send(t, text); err != nil {
+			t.Fatalf("send(%q) error = %v", text, err)
+		}
+	}
+
+	session, _, _ := store.LoadSession(testChat)
+	if session.ID != 2 || session.Route != DEFAULT_START_ROUTE || session.Observation != "" {
+		t.Errorf("expected a new session after EndAction, got %+v", session)
+	}
+
+	for _, text := range []string{"hi", "support"} {
+		if err := inner.send(t, text); err != nil {
+			t.Fatalf("send(%q) error = %v", text, err)
+		}
+	}
+
+	session, _, _ = store.LoadSession(testChat)
+	if session.Menu.ID != 7 || session.Route != "queue" {
+		t.Errorf("expected transfer to menu 7 at queue, got %+v", session)
+	}
+}
+
+func TestReceiver_InvalidStoredObservation(t *testing.T) {
+	store := newTestStore(t, t.TempDir())
+	err := store.UpdateSession(testChat, func(s *d_session.Session) error {
+		*s = d_session.New(testChat, 1, "start", time.Now())
+		s.Observation = "{"
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	inner := runApp(t, store, &messenger{})
+	if err := inner.send(t, "hi"); err == nil {
+		t.Error("expected delivery to be nacked")
+	}
+}
+
+func TestStore_LoadState(t *testing.T) {
+	store := newTestStore(t, t.TempDir())
+	sessions := NewStore(store)
+
+	if _, ok, err := sessions.LoadState(testChat); ok || err != nil {
+		t.Fatalf("expected no state for a new chat, got ok=%v err=%v", ok, err)
+	}
+
+	if err := sessions.SetRoute(testChat, "name"); err != nil {
+		t.Fatalf("SetRoute() error = %v", err)
+	}
+	session, ok, err := sessions.LoadState(testChat)
+	if err != nil || !ok {
+		t.Fatalf("expected a stored state, got ok=%v err=%v", ok, err)
+	}
+	if session.Route != "start.name" {
+		t.Errorf("unexpected session %+v", session)
+	}
+}
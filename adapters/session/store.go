@@ -0,0 +1,80 @@
+package session
+
+import (
+	d_action "github.com/irissonnlima/chatgraph-go/core/domain/action"
+	d_message "github.com/irissonnlima/chatgraph-go/core/domain/message"
+	d_session "github.com/irissonnlima/chatgraph-go/core/domain/session"
+	d_user "github.com/irissonnlima/chatgraph-go/core/domain/user"
+	adapter_output "github.com/irissonnlima/chatgraph-go/core/ports/adapters/output"
+)
+
+// Store is an ISessionStore and ITransferer that keeps session changes in a
+// repository. NewExecutor combines it with a messenger to build a full
+// executor.
+type Store struct {
+	repository adapter_output.ISessionRepository
+	options    SessionOptions
+}
+
+// NewStore creates a session store backed by repository.
+func NewStore(repository adapter_output.ISessionRepository, options ...SessionOptions) *Store {
+	return &Store{
+		repository: repository,
+		options:    firstOptions(options),
+	}
+}
+
+// LoadState returns the stored session of a chat, so routes can run for it
+// without an inbound message.
+func (s *Store) LoadState(chatID d_user.ChatID) (d_session.Session, bool, error) {
+	return s.repository.LoadSession(chatID)
+}
+
+// SetObservation stores the JSON observation of the chat.
+func (s *Store) SetObservation(chatID d_user.ChatID, observation string) error {
+	return s.update(chatID, func(session *d_session.Session) {
+		session.Observation = observation
+	})
+}
+
+// SetRoute appends the route to the chat's route history.
+func (s *Store) SetRoute(chatID d_user.ChatID, route string) error {
+	return s.update(chatID, func(session *d_session.Session) {
+		session.AppendRoute(route)
+	})
+}
+
+// EndSession ends the chat's session; the next message starts a new one.
+func (s *Store) EndSession(chatID d_user.ChatID, actionId string) error {
+	return s.update(chatID, func(session *d_session.Session) {
+		*session = session.Next(s.options.StartRoute, s.options.Now())
+	})
+}
+
+// TransferToMenu moves the chat to the target menu and route. The message that
+// triggered the transfer is not forwarded, as there is no Router API menu to
+// hand it to.
+func (s *Store) TransferToMenu(chatID d_user.ChatID, transfer d_action.TransferToMenu, message d_message.Message) error {
+	return s.update(chatID, func(session *d_session.Session) {
+		route := transfer.Route
+		if route == "" {
+			route = s.options.StartRoute
+		}
+		session.Menu = d_user.Menu{ID: transfer.MenuID}
+		session.Route = route
+	})
+}
+
+// update applies change to the session of a chat, starting a session first
+// when none is stored.
+func (s *Store) update(chatID d_user.ChatID, change func(session *d_session.Session)) error {
+	return s.repository.UpdateSession(chatID, func(session *d_session.Session) error {
+		now := s.options.Now()
+		if session.IsEmpty() {
+			*session = d_session.New(chatID, 1, s.options.StartRoute, now)
+		}
+		change(session)
+		session.UpdatedAt = now
+		return nil
+	})
+}
@@ -0,0 +1,330 @@
+package simulator
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	d_action "github.com/irissonnlima/chatgraph-go/core/domain/action"
+	d_file "github.com/irissonnlima/chatgraph-go/core/domain/file"
+	d_message "github.com/irissonnlima/chatgraph-go/core/domain/message"
+	d_route "github.com/irissonnlima/chatgraph-go/core/domain/route"
+	d_user "github.com/irissonnlima/chatgraph-go/core/domain/user"
+)
+
+// ROUTE_SEPARATOR separates routes in the session history, as in the Router API.
+const ROUTE_SEPARATOR = '.'
+
+// ErrFileNotFound is returned by GetFile for unknown file IDs.
+var ErrFileNotFound = errors.New("simulator: file not found")
+
+// Console is an IBotExecutor that prints the bot's messages to a terminal and
+// keeps the session state in memory. Uploaded files are served over a local
+// HTTP server so handlers can download them with File.Bytes.
+type Console[Obs any] struct {
+	out        io.Writer
+	startRoute string
+	platform   string
+
+	mu      sync.Mutex
+	state   d_user.UserState[Obs]
+	buttons []d_message.Button
+	ended   bool
+
+	files    map[string]string
+	fileSeq  int
+	listener net.Listener
+}
+
+// NewConsole creates a console executor writing to out, with a fresh session
+// for chatID starting at startRoute.
+func NewConsole[Obs any](out io.Writer, chatID d_user.ChatID, startRoute string, platform string) *Console[Obs] {
+	c := &Console[Obs]{
+		out:        out,
+		startRoute: startRoute,
+		platform:   platform,
+		files:      make(map[string]string),
+	}
+	c.state.ChatID = chatID
+	c.reset()
+	return c
+}
+
+// reset starts a new session. Must be called with c.mu held or before the console is shared.
+func (c *Console[Obs]) reset() {
+	var obs Obs
+	c.state = d_user.UserState[Obs]{
+		SessionID:   c.state.SessionID + 1,
+		ChatID:      c.state.ChatID,
+		Route:       d_route.NewRoute(c.startRoute, ROUTE_SEPARATOR),
+		DirectionIn: true,
+		Observation: obs,
+		Platform:    c.platform,
+	}
+	c.buttons = nil
+}
+
+// State returns a copy of the current session state.
+func (c *Console[Obs]) State() d_user.UserState[Obs] {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	state := c.state
+	state.Route = d_route.Route{
+		History:   append([]string(nil), c.state.Route.History...),
+		Separator: c.state.Route.Separator,
+	}
+	return state
+}
+
+// Reset discards the session and starts a new one at the start route.
+func (c *Console[Obs]) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.reset()
+}
+
+// SetState replaces the current route and observation.
+// An empty route keeps the current one; an empty observation keeps the current one.
+func (c *Console[Obs]) SetState(route string, observation string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if observation != "" {
+		if err := c.state.LoadObservation(observation); err != nil {
+			return fmt.Errorf("invalid observation: %w", err)
+		}
+	}
+	if route != "" {
+		c.state.Route = d_route.NewRoute(route, ROUTE_SEPARATOR)
+	}
+	return nil
+}
+
+// Button returns the n-th (1-based) button of the last bot message.
+func (c *Console[Obs]) Button(n int) (d_message.Button, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if n < 1 || n > len(c.buttons) {
+		return d_message.Button{}, false
+	}
+	return c.buttons[n-1], true
+}
+
+// takeEnded reports whether the session ended since the last call.
+func (c *Console[Obs]) takeEnded() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ended := c.ended
+	c.ended = false
+	return ended
+}
+
+// SendMessage prints the message, rendering buttons as numbered choices.
+func (c *Console[Obs]) SendMessage(to d_user.ChatID, message d_message.Message, platform string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var b strings.Builder
+	b.WriteString("bot> ")
+
+	lines := []string{}
+	if message.TextMessage.Title != "" {
+		lines = append(lines, "*"+message.TextMessage.Title+"*")
+	}
+	if message.TextMessage.Detail != "" {
+		lines = append(lines, message.TextMessage.Detail)
+	}
+	if message.TextMessage.Caption != "" {
+		lines = append(lines, "_"+message.TextMessage.Caption+"_")
+	}
+	if message.HasFile() {
+		lines = append(lines, fmt.Sprintf("[%s] %s (%s)", message.File.Type, message.File.Name, message.File.URL))
+	}
+	if len(lines) == 0 {
+		lines = append(lines, "(empty message)")
+	}
+	b.WriteString(strings.Join(lines, "\n     "))
+
+	if message.HasButtons() {
+		c.buttons = append([]d_message.Button(nil), message.Buttons...)
+		for i, button := range message.Buttons {
+			if button.Type == d_message.URL {
+				fmt.Fprintf(&b, "\n     [%d] %s -> %s", i+1, button.Title, button.Detail)
+				continue
+			}
+			fmt.Fprintf(&b, "\n     [%d] %s", i+1, button.Title)
+		}
+	}
+	if !message.DisplayButton.IsEmpty() {
+		fmt.Fprintf(&b, "\n     (menu: %s)", message.DisplayButton.Title)
+	}
+
+	fmt.Fprintln(c.out, b.String())
+	return nil
+}
+
+// SetObservation stores the observation in the session.
+func (c *Console[Obs]) SetObservation(chatID d_user.ChatID, observation string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.state.LoadObservation(observation)
+}
+
+// SetRoute appends the route to the session history.
+func (c *Console[Obs]) SetRoute(chatID d_user.ChatID, route string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.state.Route = c.state.Route.Next(route)
+	return nil
+}
+
+// EndSession ends the session; the next message starts a new one.
+func (c *Console[Obs]) EndSession(chatID d_user.ChatID, actionId string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	fmt.Fprintf(c.out, "---- session ended (action %q) ----\n", actionId)
+	c.reset()
+	c.ended = true
+	return nil
+}
+
+// TransferToMenu prints the transfer and moves the session to the target menu and route.
+func (c *Console[Obs]) TransferToMenu(chatID d_user.ChatID, transfer d_action.TransferToMenu, message d_message.Message) error {
+	c.mu.Lock()
+	fmt.Fprintf(c.out, "---- transferred to menu %d ----\n", transfer.MenuID)
+
+	route := transfer.Route
+	if route == "" {
+		route = c.startRoute
+	}
+	c.state.Menu = d_user.Menu{ID: transfer.MenuID}
+	c.state.Route = d_route.NewRoute(route, ROUTE_SEPARATOR)
+	c.mu.Unlock()
+
+	if message.EntireText() != "" || message.HasFile() {
+		return c.SendMessage(chatID, message, c.platform)
+	}
+	return nil
+}
+
+// UploadFile registers a local file and serves it over the local file server.
+func (c *Console[Obs]) UploadFile(path string) (*d_file.File, error) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(absPath); err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.serveFiles(); err != nil {
+		return nil, err
+	}
+
+	c.fileSeq++
+	id := fmt.Sprintf("file-%d", c.fileSeq)
+	c.files[id] = absPath
+
+	return c.file(id), nil
+}
+
+// GetFile returns a previously uploaded file.
+func (c *Console[Obs]) GetFile(fileID string) (*d_file.File, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.files[fileID]; !ok {
+		return nil, ErrFileNotFound
+	}
+	return c.file(fileID), nil
+}
+
+// file builds the domain file for an uploaded file. Must be called with c.mu held.
+func (c *Console[Obs]) file(id string) *d_file.File {
+	path := c.files[id]
+	return &d_file.File{
+		ID:   id,
+		Type: fileType(path),
+		URL:  fmt.Sprintf("http://%s/files/%s/%s", c.listener.Addr(), id, filepath.Base(path)),
+		Name: filepath.Base(path),
+	}
+}
+
+// serveFiles starts the local file server on first use. Must be called with c.mu held.
+func (c *Console[Obs]) serveFiles() error {
+	if c.listener != nil {
+		return nil
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return fmt.Errorf("starting file server: %w", err)
+	}
+	c.listener = listener
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/files/{id}/{name}", func(w http.ResponseWriter, r *http.Request) {
+		c.mu.Lock()
+		path, ok := c.files[r.PathValue("id")]
+		c.mu.Unlock()
+
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		http.ServeFile(w, r, path)
+	})
+
+	go http.Serve(listener, mux)
+	return nil
+}
+
+// Close stops the local file server.
+func (c *Console[Obs]) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.listener == nil {
+		return nil
+	}
+	err := c.listener.Close()
+	c.listener = nil
+	return err
+}
+
+// observationJSON returns the observation as JSON for display.
+func observationJSON[Obs any](obs Obs) string {
+	data, err := json.Marshal(obs)
+	if err != nil {
+		return fmt.Sprintf("%+v", obs)
+	}
+	return string(data)
+}
+
+// fileType guesses the file type from its extension.
+func fileType(path string) d_file.FileType {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".png", ".jpg", ".jpeg", ".gif", ".webp":
+		return d_file.IMAGE_SEND_TYPE
+	case ".mp4", ".avi", ".mov", ".mkv":
+		return d_file.VIDEO_SEND_TYPE
+	case ".mp3", ".wav", ".ogg", ".m4a":
+		return d_file.AUDIO_SEND_TYPE
+	default:
+		return d_file.FILE_SEND_TYPE
+	}
+}
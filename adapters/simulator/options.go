@@ -0,0 +1,53 @@
+package simulator
+
+import (
+	"io"
+	"os"
+
+	d_user "github.com/irissonnlima/chatgraph-go/core/domain/user"
+)
+
+// Default values for simulator options.
+const (
+	// DEFAULT_START_ROUTE is the route of a new session.
+	DEFAULT_START_ROUTE = "start"
+	// DEFAULT_PLATFORM is the platform reported in the simulated user state.
+	DEFAULT_PLATFORM = "simulator"
+)
+
+// DEFAULT_CHAT_ID identifies the simulated chat.
+var DEFAULT_CHAT_ID = d_user.ChatID{UserID: "simulator", CompanyID: "local"}
+
+// SimulatorOptions configures the terminal simulator.
+type SimulatorOptions struct {
+	// In is where user input is read from. Defaults to os.Stdin.
+	In io.Reader
+	// Out is where the conversation is printed. Defaults to os.Stdout.
+	Out io.Writer
+	// ChatID identifies the simulated chat. Defaults to DEFAULT_CHAT_ID.
+	ChatID d_user.ChatID
+	// StartRoute is the route of a new session. Defaults to DEFAULT_START_ROUTE.
+	StartRoute string
+	// Platform is reported in the simulated user state. Defaults to DEFAULT_PLATFORM.
+	Platform string
+}
+
+// withDefaults fills in default values.
+func (o SimulatorOptions) withDefaults() SimulatorOptions {
+	if o.In == nil {
+		o.In = os.Stdin
+	}
+	if o.Out == nil {
+		o.Out = os.Stdout
+	}
+	if o.ChatID.IsEmpty() {
+		o.ChatID = DEFAULT_CHAT_ID
+	}
+	if o.StartRoute == "" {
+		o.StartRoute = DEFAULT_START_ROUTE
+	}
+	if o.Platform == "" {
+		o.Platform = DEFAULT_PLATFORM
+	}
+	return o
+}
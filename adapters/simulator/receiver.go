@@ -0,0 +1,84 @@
+package simulator
+
+import (
+	"errors"
+	"sync"
+
+	adapter_input "github.com/irissonnlima/chatgraph-go/core/ports/adapters/input"
+)
+
+// ErrClosed is returned when sending to a simulator that has stopped.
+var ErrClosed = errors.New("simulator: closed")
+
+// receiver is an in-memory IMessageReceiver fed by the simulator.
+type receiver[Obs any] struct {
+	// mu guards out: send holds the read lock, Close closes out under the write lock.
+	mu        sync.RWMutex
+	out       chan adapter_input.Delivery[Obs]
+	closed    bool
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func newReceiver[Obs any]() *receiver[Obs] {
+	return &receiver[Obs]{
+		out:  make(chan adapter_input.Delivery[Obs]),
+		done: make(chan struct{}),
+	}
+}
+
+// ConsumeMessage returns the channel fed by the simulator.
+func (r *receiver[Obs]) ConsumeMessage() <-chan adapter_input.Delivery[Obs] {
+	return r.out
+}
+
+// send hands a delivery to the application, failing if the receiver is closed.
+func (r *receiver[Obs]) send(delivery adapter_input.Delivery[Obs]) error {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if r.closed {
+		return ErrClosed
+	}
+
+	select {
+	case r.out <- delivery:
+		return nil
+	case <-r.done:
+		return ErrClosed
+	}
+}
+
+// Close closes the delivery channel.
+func (r *receiver[Obs]) Close() error {
+	r.closeOnce.Do(func() {
+		close(r.done)
+
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.closed = true
+		close(r.out)
+	})
+	return nil
+}
+
+// turnAcknowledger reports the end of a turn to the simulator.
+type turnAcknowledger struct {
+	done chan error
+}
+
+func newTurnAcknowledger() *turnAcknowledger {
+	return &turnAcknowledger{done: make(chan error, 1)}
+}
+
+// Ack reports that the turn completed successfully.
+func (a *turnAcknowledger) Ack() error {
+	a.done <- nil
+	return nil
+}
+
+// Nack reports that the turn failed.
+func (a *turnAcknowledger) Nack(reason error) error {
+	a.done <- reason
+	return nil
+}
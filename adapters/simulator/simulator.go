@@ -0,0 +1,301 @@
+// Package simulator provides an interactive terminal simulator for developing
+// chatbots locally, without RabbitMQ or the Router API.
+//
+// The simulator wires an Engine to an in-memory receiver and a Console executor
+// through a regular ChatbotApp, so handlers run exactly as in production:
+//
+//	engine := chat.NewEngine[Obs]()
+//	// register routes...
+//	sim := simulator.NewSimulator(engine)
+//	sim.Run(ctx)
+package simulator
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	d_message "github.com/irissonnlima/chatgraph-go/core/domain/message"
+	d_user "github.com/irissonnlima/chatgraph-go/core/domain/user"
+	adapter_input "github.com/irissonnlima/chatgraph-go/core/ports/adapters/input"
+	"github.com/irissonnlima/chatgraph-go/core/service"
+)
+
+// helpText describes the simulator commands.
+const helpText = `Type a message and press Enter to send it to the bot.
+  <n>                  select button n of the last bot message
+  /file <path> [text]  send a file, with an optional caption
+  /state               show the session state
+  /route <path>        set the route, e.g. /route start.menu
+  /obs <json>          set the observation, e.g. /obs {"step":1}
+  /reset               end the session and start a new one
+  /help                show this help
+  /quit                exit`
+
+// Simulator runs a chatbot in the terminal.
+type Simulator[Obs any] struct {
+	options  SimulatorOptions
+	console  *Console[Obs]
+	receiver *receiver[Obs]
+	app      *service.ChatbotApp[Obs]
+
+	appErr chan error
+}
+
+// NewSimulator creates a terminal simulator for the engine.
+// Optional options configure input, output and the simulated chat.
+func NewSimulator[Obs any](engine *service.Engine[Obs], options ...SimulatorOptions) *Simulator[Obs] {
+	opts := SimulatorOptions{}
+	if len(options) > 0 {
+		opts = options[0]
+	}
+	opts = opts.withDefaults()
+
+	console := NewConsole[Obs](opts.Out, opts.ChatID, opts.StartRoute, opts.Platform)
+	receiver := newReceiver[Obs]()
+
+	return &Simulator[Obs]{
+		options:  opts,
+		console:  console,
+		receiver: receiver,
+		app:      service.NewChatbotApp(engine, receiver, console),
+		appErr:   make(chan error, 1),
+	}
+}
+
+// Console returns the console executor holding the session state.
+func (s *Simulator[Obs]) Console() *Console[Obs] {
+	return s.console
+}
+
+// Start starts the underlying application in the background.
+// Run calls it automatically; call it directly only when driving the simulator with Send.
+func (s *Simulator[Obs]) Start(ctx context.Context) {
+	go func() {
+		err := s.app.Start(ctx)
+		// Unblock pending sends if the app stopped on its own (e.g. invalid routes).
+		s.receiver.Close()
+		s.appErr <- err
+	}()
+}
+
+// Stop shuts the application down and releases the console resources.
+func (s *Simulator[Obs]) Stop(ctx context.Context) error {
+	err := s.app.Shutdown(ctx)
+	s.console.Close()
+	return err
+}
+
+// Send delivers a message from the simulated user and waits until the bot
+// finishes handling it. Returns the handling error, if any.
+func (s *Simulator[Obs]) Send(message d_message.Message) error {
+	ack := newTurnAcknowledger()
+	delivery := adapter_input.Delivery[Obs]{
+		UserState:    s.console.State(),
+		Message:      message,
+		Acknowledger: ack,
+	}
+
+	if err := s.receiver.send(delivery); err != nil {
+		return err
+	}
+	return <-ack.done
+}
+
+// Run starts the application and reads user input until /quit, end of input
+// or ctx cancellation. It returns the application error, if any.
+func (s *Simulator[Obs]) Run(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	s.Start(ctx)
+
+	lines := make(chan string)
+	go func() {
+		defer close(lines)
+		scanner := bufio.NewScanner(s.options.In)
+		for scanner.Scan() {
+			select {
+			case lines <- scanner.Text():
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	s.printf("chatgraph simulator - chat %s/%s, type /help for commands\n",
+		s.options.ChatID.CompanyID, s.options.ChatID.UserID)
+
+	for {
+		s.printf("you> ")
+
+		select {
+		case <-ctx.Done():
+			s.printf("\n")
+			return s.stop()
+		case err := <-s.appErr:
+			s.printf("\n")
+			s.console.Close()
+			return err
+		case line, ok := <-lines:
+			if !ok {
+				s.printf("\n")
+				return s.stop()
+			}
+			if quit := s.handleLine(strings.TrimSpace(line)); quit {
+				return s.stop()
+			}
+		}
+	}
+}
+
+// stop shuts the application down and waits for it to return.
+func (s *Simulator[Obs]) stop() error {
+	err := s.Stop(context.Background())
+	if startErr := <-s.appErr; startErr != nil && !errors.Is(startErr, service.ErrAppShutdown) {
+		return startErr
+	}
+	return err
+}
+
+// handleLine runs a command or sends the line as a message.
+// Returns true when the user asked to quit.
+func (s *Simulator[Obs]) handleLine(line string) bool {
+	if line == "" {
+		return false
+	}
+
+	if n, err := strconv.Atoi(line); err == nil {
+		if button, ok := s.console.Button(n); ok {
+			s.selectButton(button)
+			return false
+		}
+	}
+
+	if !strings.HasPrefix(line, "/") {
+		s.turn(d_message.Message{TextMessage: d_message.TextMessage{Detail: line}})
+		return false
+	}
+
+	command, arg, _ := strings.Cut(line, " ")
+	arg = strings.TrimSpace(arg)
+
+	switch command {
+	case "/quit", "/exit":
+		return true
+
+	case "/help":
+		s.printf("%s\n", helpText)
+
+	case "/state":
+		s.printState(s.console.State())
+
+	case "/reset":
+		s.console.Reset()
+		s.printf("---- session reset ----\n")
+
+	case "/route":
+		if arg == "" {
+			s.printf("usage: /route <path>\n")
+			return false
+		}
+		if err := s.console.SetState(arg, ""); err != nil {
+			s.printf("error> %v\n", err)
+		}
+
+	case "/obs":
+		if arg == "" {
+			s.printf("usage: /obs <json>\n")
+			return false
+		}
+		if err := s.console.SetState("", arg); err != nil {
+			s.printf("error> %v\n", err)
+		}
+
+	case "/file":
+		path, caption, _ := strings.Cut(arg, " ")
+		if path == "" {
+			s.printf("usage: /file <path> [text]\n")
+			return false
+		}
+		file, err := s.console.UploadFile(path)
+		if err != nil {
+			s.printf("error> %v\n", err)
+			return false
+		}
+		s.turn(d_message.Message{
+			TextMessage: d_message.TextMessage{Caption: strings.TrimSpace(caption)},
+			File:        *file,
+		})
+
+	default:
+		s.printf("unknown command %s, type /help\n", command)
+	}
+
+	return false
+}
+
+// selectButton sends the postback of a button, or shows the link of a URL button.
+func (s *Simulator[Obs]) selectButton(button d_message.Button) {
+	if button.Type == d_message.URL {
+		s.printf("(link) %s\n", button.Detail)
+		return
+	}
+
+	value := button.Detail
+	if value == "" {
+		value = button.Title
+	}
+	s.turn(d_message.Message{TextMessage: d_message.TextMessage{Detail: value}})
+}
+
+// turn sends a message and prints how the session changed.
+func (s *Simulator[Obs]) turn(message d_message.Message) {
+	before := s.console.State()
+
+	if err := s.Send(message); err != nil {
+		s.printf("error> %v\n", err)
+	}
+
+	if s.console.takeEnded() {
+		return
+	}
+	s.printChanges(before, s.console.State())
+}
+
+// printChanges prints route and observation changes between two states.
+func (s *Simulator[Obs]) printChanges(before, after d_user.UserState[Obs]) {
+	beforeRoute, afterRoute := routePath(before), routePath(after)
+	if beforeRoute != afterRoute {
+		s.printf("     ~ route: %s -> %s\n", beforeRoute, afterRoute)
+	}
+
+	beforeObs, afterObs := observationJSON(before.Observation), observationJSON(after.Observation)
+	if beforeObs != afterObs {
+		s.printf("     ~ observation: %s -> %s\n", beforeObs, afterObs)
+	}
+}
+
+// printState prints the full session state.
+func (s *Simulator[Obs]) printState(state d_user.UserState[Obs]) {
+	s.printf("session:     %d\n", state.SessionID)
+	s.printf("chat:        %s/%s\n", state.ChatID.CompanyID, state.ChatID.UserID)
+	s.printf("route:       %s\n", routePath(state))
+	s.printf("observation: %s\n", observationJSON(state.Observation))
+	if !state.Menu.IsEmpty() {
+		s.printf("menu:        %d\n", state.Menu.ID)
+	}
+}
+
+// printf writes to the simulator output.
+func (s *Simulator[Obs]) printf(format string, args ...any) {
+	fmt.Fprintf(s.options.Out, format, args...)
+}
+
+// routePath returns the full route history joined by its separator.
+func routePath[Obs any](state d_user.UserState[Obs]) string {
+	return state.Route.Path()
+}
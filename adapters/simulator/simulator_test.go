@@ -0,0 +1,158 @@
+package simulator
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	route_return "github.com/irissonnlima/chatgraph-go/core/domain"
+	d_action "github.com/irissonnlima/chatgraph-go/core/domain/action"
+	d_context "github.com/irissonnlima/chatgraph-go/core/domain/context"
+	d_message "github.com/irissonnlima/chatgraph-go/core/domain/message"
+	"github.com/irissonnlima/chatgraph-go/core/service"
+)
+
+type testObs struct {
+	Choice string `json:"choice,omitempty"`
+	File   string `json:"file,omitempty"`
+}
+
+func newTestEngine() *service.Engine[testObs] {
+	engine := service.NewEngine[testObs]()
+	engine.RegisterRoute("timeout_route", func(ctx *d_context.ChatContext[testObs]) route_return.RouteReturn {
+		return nil
+	})
+	engine.RegisterRoute("loop_route", func(ctx *d_context.ChatContext[testObs]) route_return.RouteReturn {
+		return nil
+	})
+	engine.RegisterRoute("start", func(ctx *d_context.ChatContext[testObs]) route_return.RouteReturn {
+		ctx.SendMessage(d_message.Message{
+			TextMessage: d_message.TextMessage{Detail: "Pick one"},
+			Buttons: []d_message.Button{
+				{Type: d_message.POSTBACK, Title: "Red", Detail: "red"},
+				{Type: d_message.URL, Title: "Site", Detail: "https://example.com"},
+			},
+		})
+		return ctx.NextRoute("choice")
+	})
+	engine.RegisterRoute("choice", func(ctx *d_context.ChatContext[testObs]) route_return.RouteReturn {
+		if ctx.Message.HasFile() {
+			data, err := ctx.Message.File.Bytes()
+			if err != nil {
+				ctx.SendTextMessage("error: " + err.Error())
+				return nil
+			}
+			ctx.SetObservation(testObs{File: string(data)})
+			return nil
+		}
+		if ctx.Message.EntireText() == "bye" {
+			return &d_action.EndAction{ID: "bye"}
+		}
+		ctx.SetObservation(testObs{Choice: ctx.Message.EntireText()})
+		ctx.SendTextMessage("You chose " + ctx.Message.EntireText())
+		return nil
+	})
+	return engine
+}
+
+func runScript(t *testing.T, script string) string {
+	t.Helper()
+
+	var out bytes.Buffer
+	sim := NewSimulator(newTestEngine(), SimulatorOptions{In: strings.NewReader(script), Out: &out})
+	if err := sim.Run(context.Background()); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	return out.String()
+}
+
+func TestRun_ButtonsAndChanges(t *testing.T) {
+	out := runScript(t, "hi\n1\n2\n/state\n/quit\n")
+
+	for _, want := range []string{
+		"bot> Pick one",
+		"[1] Red",
+		"[2] Site -> https://example.com",
+		"~ route: start -> start.choice",
+		"bot> You chose red",
+		`~ observation: {} -> {"choice":"red"}`,
+		"(link) https://example.com",
+		"route:       start.choice.choice",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output does not contain %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestRun_SetStateAndReset(t *testing.T) {
+	out := runScript(t, "/route start.choice\n/obs {\"choice\":\"blue\"}\n/state\n/reset\n/state\n")
+
+	if !strings.Contains(out, `observation: {"choice":"blue"}`) {
+		t.Errorf("expected observation to be set:\n%s", out)
+	}
+	if !strings.Contains(out, "route:       start.choice\n") {
+		t.Errorf("expected route to be set:\n%s", out)
+	}
+	if !strings.Contains(out, "session:     2") {
+		t.Errorf("expected a new session after /reset:\n%s", out)
+	}
+}
+
+func TestRun_EndSession(t *testing.T) {
+	out := runScript(t, "hi\nbye\n/state\n")
+
+	if !strings.Contains(out, `session ended (action "bye")`) {
+		t.Errorf("expected session end:\n%s", out)
+	}
+	if !strings.Contains(out, "route:       start\n") {
+		t.Errorf("expected session to restart at start:\n%s", out)
+	}
+}
+
+func TestRun_File(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "note.txt")
+	if err := os.WriteFile(path, []byte("content"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	out := runScript(t, "hi\n/file "+path+"\n")
+
+	if !strings.Contains(out, `{"file":"content"}`) {
+		t.Errorf("expected handler to download the file:\n%s", out)
+	}
+}
+
+func TestRun_InvalidRoutes(t *testing.T) {
+	engine := service.NewEngine[testObs]()
+	sim := NewSimulator(engine, SimulatorOptions{In: strings.NewReader("hi\n"), Out: &bytes.Buffer{}})
+
+	if err := sim.Run(context.Background()); err == nil {
+		t.Error("expected Run to fail without a start route")
+	}
+}
+
+func TestSend(t *testing.T) {
+	sim := NewSimulator(newTestEngine(), SimulatorOptions{Out: &bytes.Buffer{}})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sim.Start(ctx)
+	defer sim.Stop(context.Background())
+
+	for _, text := range []string{"hi", "green"} {
+		if err := sim.Send(d_message.Message{TextMessage: d_message.TextMessage{Detail: text}}); err != nil {
+			t.Fatalf("Send(%q) error = %v", text, err)
+		}
+	}
+
+	state := sim.Console().State()
+	if state.Observation.Choice != "green" {
+		t.Errorf("expected choice green, got %q", state.Observation.Choice)
+	}
+	if state.Route.Current() != "choice" {
+		t.Errorf("expected route choice, got %q", state.Route.Current())
+	}
+}
@@ -11,21 +11,55 @@
 //	    ctx.SendTextMessage("Hello!")
 //	    return ctx.NextRoute("next")
 //	})
-//	app.Start()
+//	app.Start(ctx)
 package chat
 
 import (
+	"context"
+	"io"
 	"testing"
-
+	"time"
+
+	"github.com/irissonnlima/chatgraph-go/adapters/campaigns"
+	campaigns_boltstore "github.com/irissonnlima/chatgraph-go/adapters/campaigns/boltstore"
+	dto_calendar "github.com/irissonnlima/chatgraph-go/adapters/dto/calendar"
+	dto_campaign "github.com/irissonnlima/chatgraph-go/adapters/dto/campaign"
+	"github.com/irissonnlima/chatgraph-go/adapters/handoff"
+	handoff_boltstore "github.com/irissonnlima/chatgraph-go/adapters/handoff/boltstore"
+	"github.com/irissonnlima/chatgraph-go/adapters/inactivity"
+	inactivity_boltstore "github.com/irissonnlima/chatgraph-go/adapters/inactivity/boltstore"
+	input_fanin "github.com/irissonnlima/chatgraph-go/adapters/input/fanin"
 	input_queue "github.com/irissonnlima/chatgraph-go/adapters/input/queue"
+	input_webhook "github.com/irissonnlima/chatgraph-go/adapters/input/webhook"
+	"github.com/irissonnlima/chatgraph-go/adapters/jobs"
+	jobs_boltstore "github.com/irissonnlima/chatgraph-go/adapters/jobs/boltstore"
+	"github.com/irissonnlima/chatgraph-go/adapters/loopback"
+	"github.com/irissonnlima/chatgraph-go/adapters/outbox"
+	outbox_boltstore "github.com/irissonnlima/chatgraph-go/adapters/outbox/boltstore"
+	output_compose "github.com/irissonnlima/chatgraph-go/adapters/output/compose"
+	output_dedup "github.com/irissonnlima/chatgraph-go/adapters/output/dedup"
 	output_router_api "github.com/irissonnlima/chatgraph-go/adapters/output/router_api"
+	"github.com/irissonnlima/chatgraph-go/adapters/session"
+	"github.com/irissonnlima/chatgraph-go/adapters/session/boltstore"
+	"github.com/irissonnlima/chatgraph-go/adapters/session/filestore"
+	"github.com/irissonnlima/chatgraph-go/adapters/simulator"
+	"github.com/irissonnlima/chatgraph-go/core/clocktest"
 	route_return "github.com/irissonnlima/chatgraph-go/core/domain"
 	d_action "github.com/irissonnlima/chatgraph-go/core/domain/action"
+	d_calendar "github.com/irissonnlima/chatgraph-go/core/domain/calendar"
+	d_campaign "github.com/irissonnlima/chatgraph-go/core/domain/campaign"
 	d_context "github.com/irissonnlima/chatgraph-go/core/domain/context"
+	d_department "github.com/irissonnlima/chatgraph-go/core/domain/department"
+	d_event "github.com/irissonnlima/chatgraph-go/core/domain/event"
 	d_file "github.com/irissonnlima/chatgraph-go/core/domain/file"
+	d_handoff "github.com/irissonnlima/chatgraph-go/core/domain/handoff"
+	d_idempotency "github.com/irissonnlima/chatgraph-go/core/domain/idempotency"
+	d_inactivity "github.com/irissonnlima/chatgraph-go/core/domain/inactivity"
+	d_job "github.com/irissonnlima/chatgraph-go/core/domain/job"
 	d_message "github.com/irissonnlima/chatgraph-go/core/domain/message"
 	d_route "github.com/irissonnlima/chatgraph-go/core/domain/route"
 	d_router "github.com/irissonnlima/chatgraph-go/core/domain/router"
+	d_session "github.com/irissonnlima/chatgraph-go/core/domain/session"
 	d_user "github.com/irissonnlima/chatgraph-go/core/domain/user"
 	adapter_input "github.com/irissonnlima/chatgraph-go/core/ports/adapters/input"
 	adapter_output "github.com/irissonnlima/chatgraph-go/core/ports/adapters/output"
@@ -50,6 +84,151 @@ type App[Obs any] = service.ChatbotApp[Obs]
 // It is designed to be testable in isolation without requiring external dependencies.
 type Engine[Obs any] = service.Engine[Obs]
 
+// AppOptions configures how the application processes incoming messages.
+type AppOptions = service.AppOptions
+
+// PoolMetrics is a snapshot of the application's worker pool.
+type PoolMetrics = service.PoolMetrics
+
+// AbandonedHandler is a handler still running after its timeout.
+type AbandonedHandler = service.AbandonedHandler
+
+// ErrTooManyAbandonedHandlers is returned by HandleMessage when the app stops
+// while the message waits for AppOptions.MaxAbandonedHandlers.
+var ErrTooManyAbandonedHandlers = service.ErrTooManyAbandonedHandlers
+
+// JobRunner performs background jobs and resumes their chats at a
+// completion route when they finish.
+type JobRunner[Obs any] = service.JobRunner[Obs]
+
+// JobOptions configures a JobRunner.
+type JobOptions = service.JobOptions
+
+// JobRequest describes a job enqueued by a handler.
+type JobRequest = service.JobRequest
+
+// Job is a background job. The completion route gets it from Context.Job.
+type Job = d_job.Job
+
+// JobTask performs the work of a job and returns its result.
+type JobTask = d_job.Task
+
+// JobStatus is the state of a job.
+type JobStatus = d_job.Status
+
+// Job statuses.
+const (
+	JobPending   = d_job.PENDING
+	JobRunning   = d_job.RUNNING
+	JobSucceeded = d_job.SUCCEEDED
+	JobFailed    = d_job.FAILED
+)
+
+// ErrUnknownJobTask is returned when enqueuing a job for a task that is not registered.
+var ErrUnknownJobTask = service.ErrUnknownTask
+
+// CampaignSender delivers broadcast campaigns to lists of chats, throttled,
+// and reports the delivery to each recipient.
+type CampaignSender[Obs any] = service.CampaignSender[Obs]
+
+// CampaignOptions configures a CampaignSender.
+type CampaignOptions = service.CampaignOptions
+
+// Campaign is a broadcast: a message sent to every recipient, or an entry
+// route run for each.
+type Campaign = d_campaign.Campaign
+
+// CampaignRecipient is a chat a campaign is delivered to.
+type CampaignRecipient = d_campaign.Recipient
+
+// CampaignReport is the delivery report of a campaign.
+type CampaignReport = d_campaign.Report
+
+// Campaign statuses.
+const (
+	CampaignRunning   = d_campaign.RUNNING
+	CampaignFinished  = d_campaign.FINISHED
+	CampaignCancelled = d_campaign.CANCELLED
+)
+
+// Campaign recipient statuses.
+const (
+	RecipientPending = d_campaign.PENDING
+	RecipientSent    = d_campaign.SENT
+	RecipientSkipped = d_campaign.SKIPPED
+	RecipientFailed  = d_campaign.FAILED
+)
+
+// ErrCampaignNotFound is returned for campaigns that are not stored.
+var ErrCampaignNotFound = service.ErrCampaignNotFound
+
+// InactivityScheduler reminds users who go silent on routes with
+// InactivityRouteOps, then moves their sessions on.
+type InactivityScheduler[Obs any] = service.InactivityScheduler[Obs]
+
+// InactivityOptions configures an InactivityScheduler.
+type InactivityOptions = service.InactivityOptions
+
+// InactivityTimer tracks the silence of a chat on a route.
+type InactivityTimer = d_inactivity.Timer
+
+// ErrTicketNotFound is returned for handoff tickets that are not open.
+var ErrTicketNotFound = service.ErrTicketNotFound
+
+// ErrChatHandedOff is returned when resuming a chat handed off to an agent,
+// e.g. for an event: the bot is paused for the chat until the ticket closes.
+var ErrChatHandedOff = service.ErrChatHandedOff
+
+// ErrUnknownDepartment is returned when handing a chat off to a department
+// that is not in HandoffOptions.Departments.
+var ErrUnknownDepartment = service.ErrUnknownDepartment
+
+// HandoffDesk hands conversations off to human agents and pauses the bot
+// for them until the agent closes the ticket.
+type HandoffDesk[Obs any] = service.HandoffDesk[Obs]
+
+// HandoffOptions configures a HandoffDesk.
+type HandoffOptions = service.HandoffOptions
+
+// HandoffTicket is a conversation handed off to a department or an agent.
+type HandoffTicket = d_handoff.Ticket
+
+// Department is a department conversations can be handed off to.
+type Department = d_department.Department
+
+// FakeClock is a manual clock for testing jobs, campaigns and inactivity
+// timers: pass its Now method as their Now option.
+type FakeClock = clocktest.FakeClock
+
+// Event is a backend event that runs a route for a chat without an inbound
+// message. The route gets it from Context.Event.
+type Event = d_event.Event
+
+// EventHandler runs routes for chats in response to backend events; App
+// implements it.
+type EventHandler = adapter_input.IEventHandler
+
+// EventChecker is implemented by event handlers that can tell upfront that
+// they cannot handle any event; App implements it.
+type EventChecker = adapter_input.IEventChecker
+
+// ErrInvalidEvent is wrapped by the errors of events that must not be retried.
+var ErrInvalidEvent = adapter_input.ErrInvalidEvent
+
+// ErrUnavailable is wrapped by the errors of events that can be retried later.
+var ErrUnavailable = adapter_input.ErrUnavailable
+
+// ErrRouteNotFound is returned when no handler is registered for a route.
+var ErrRouteNotFound = service.ErrRouteNotFound
+
+// ErrNoChatState is returned by HandleEvent when the executor keeps no chat
+// state (see StateLoader).
+var ErrNoChatState = service.ErrNoChatState
+
+// ErrExecutorUnavailable is returned when a chat cannot be resumed because
+// the executor reports that its backend is down.
+var ErrExecutorUnavailable = service.ErrExecutorUnavailable
+
 // EngineTester is a test helper for validating chatbot handler executions.
 type EngineTester[Obs any] = service.EngineTester[Obs]
 
@@ -66,6 +245,9 @@ const (
 	ExecSetRoute       = service.ExecSetRoute
 	ExecGetFile        = service.ExecGetFile
 	ExecUploadFile     = service.ExecUploadFile
+	ExecSendTyping     = service.ExecSendTyping
+	ExecEditMessage    = service.ExecEditMessage
+	ExecReact          = service.ExecReact
 )
 
 // ============================================================================
@@ -81,6 +263,9 @@ type RedirectResponse = d_action.RedirectResponse
 // TransferToMenu transfers the user to a different menu.
 type TransferToMenu = d_action.TransferToMenu
 
+// HandoffToAgent hands the conversation off to a human agent (see HandoffDesk).
+type HandoffToAgent = d_action.HandoffToAgent
+
 // ============================================================================
 // Type Aliases - Message Types
 // ============================================================================
@@ -151,6 +336,56 @@ type LoopCountRouteOps = d_router.LoopCountRouteOps
 // ProtectedRouteOps configures route protection settings.
 type ProtectedRouteOps = d_router.ProtectedRouteOps
 
+// BufferedRouteOps makes a route's actions transactional: they are performed
+// only when the handler returns normally.
+type BufferedRouteOps = d_router.BufferedRouteOps
+
+// ProgressRouteOps sends "please wait" feedback while a route's handler is slow.
+type ProgressRouteOps = d_router.ProgressRouteOps
+
+// InactivityRouteOps reminds users who go silent on a route, then moves the
+// session to another route or ends it.
+type InactivityRouteOps = d_router.InactivityRouteOps
+
+// BusinessHoursRouteOps redirects users to an "after hours" route while
+// their company is closed.
+type BusinessHoursRouteOps = d_router.BusinessHoursRouteOps
+
+// ============================================================================
+// Type Aliases - Business Hours
+// ============================================================================
+
+// Calendar holds the business hours of a company: weekly periods in a
+// timezone, holidays and exceptions.
+type Calendar = d_calendar.Calendar
+
+// Calendars holds the calendar of each company, with a default one.
+type Calendars = d_calendar.Calendars
+
+// CalendarPeriod is an opening period within a day.
+type CalendarPeriod = d_calendar.Period
+
+// CalendarDate is a calendar day; Year 0 recurs every year.
+type CalendarDate = d_calendar.Date
+
+// CalendarException overrides the opening hours of a day.
+type CalendarException = d_calendar.Exception
+
+// Holiday is a day the business is closed.
+type Holiday = d_calendar.Holiday
+
+// HolidayRule returns the holidays of a year.
+type HolidayRule = d_calendar.HolidayRule
+
+// Brazilian holiday rules, for Calendar.HolidayRules. Use
+// BrazilStateHolidays for the holidays of a state.
+var (
+	// BrazilNationalHolidays are the national holidays, including Good Friday.
+	BrazilNationalHolidays HolidayRule = d_calendar.BrazilNational
+	// BrazilOptionalHolidays are Carnival Monday and Tuesday, and Corpus Christi.
+	BrazilOptionalHolidays HolidayRule = d_calendar.BrazilOptional
+)
+
 // RouteTrigger defines a regex-based trigger for automatic route changes.
 type RouteTrigger = d_router.RouteTrigger
 
@@ -161,21 +396,468 @@ type RouteTrigger = d_router.RouteTrigger
 // MessageReceiver is the interface for message queue consumers.
 type MessageReceiver[Obs any] = adapter_input.IMessageReceiver[Obs]
 
+// Delivery is a single incoming message that must be acknowledged or negatively acknowledged.
+type Delivery[Obs any] = adapter_input.Delivery[Obs]
+
 // RouterService is the interface for routing and messaging operations.
+// It combines Messenger, SessionStore, Transferer and FileStore.
 type RouterService = adapter_output.IBotExecutor
 
+// Messenger sends messages to chats.
+type Messenger = adapter_output.IMessenger
+
+// SessionStore keeps the route, observation and session of chats.
+type SessionStore = adapter_output.ISessionStore
+
+// Transferer transfers chats to other menus.
+type Transferer = adapter_output.ITransferer
+
+// FileStore uploads and retrieves files.
+type FileStore = adapter_output.IFileStore
+
+// TypingNotifier is an optional executor capability that shows typing indicators.
+type TypingNotifier = adapter_output.ITypingNotifier
+
+// MessageEditor is an optional executor capability that edits sent messages.
+type MessageEditor = adapter_output.IMessageEditor
+
+// Reactor is an optional executor capability that reacts to messages.
+type Reactor = adapter_output.IReactor
+
+// AvailabilityReporter is an optional executor capability that reports whether
+// its backend is up. See AppOptions.UnavailableRoute.
+type AvailabilityReporter = adapter_output.IAvailabilityReporter
+
+// StateLoader is an optional executor capability that loads the stored state
+// of a chat, so events can run routes for it.
+type StateLoader = adapter_output.IStateLoader
+
+// ErrHandlerPanic is returned by HandleMessage when a route handler panics.
+var ErrHandlerPanic = service.ErrHandlerPanic
+
+// ErrUnsupported is returned by adapters for capabilities they cannot provide.
+var ErrUnsupported = adapter_output.ErrUnsupported
+
+// ExecutorParts holds the ports a composed executor delegates to.
+type ExecutorParts = output_compose.Parts
+
+// DedupStore remembers the idempotency keys of the actions already performed.
+type DedupStore = adapter_output.IDedupStore
+
+// MemoryDedupStoreOptions configures the in-memory dedup store.
+type MemoryDedupStoreOptions = output_dedup.MemoryStoreOptions
+
+// OutboxExecutor queues the actions that fail while the executor is down and
+// performs them later, in order per chat.
+type OutboxExecutor = outbox.Executor
+
+// OutboxOptions configures the outbox executor and its dispatcher.
+type OutboxOptions = outbox.OutboxOptions
+
+// OutboxMetrics is a snapshot of the outbox backlog and counters.
+type OutboxMetrics = outbox.Metrics
+
+// OutboxRepository durably keeps the actions queued by an OutboxExecutor.
+type OutboxRepository = adapter_output.IOutboxRepository
+
+// BoltOutboxRepositoryOptions configures the bbolt outbox repository.
+type BoltOutboxRepositoryOptions = outbox_boltstore.BoltStoreOptions
+
+// JobRepository durably keeps the jobs of a JobRunner.
+type JobRepository = adapter_output.IJobRepository
+
+// BoltJobRepositoryOptions configures the bbolt job repository.
+type BoltJobRepositoryOptions = jobs_boltstore.BoltStoreOptions
+
+// CampaignRepository durably keeps the campaigns of a CampaignSender and
+// the delivery state of their recipients.
+type CampaignRepository = adapter_output.ICampaignRepository
+
+// BoltCampaignRepositoryOptions configures the bbolt campaign repository.
+type BoltCampaignRepositoryOptions = campaigns_boltstore.BoltStoreOptions
+
+// InactivityRepository keeps the inactivity timers of an InactivityScheduler.
+type InactivityRepository = adapter_output.IInactivityRepository
+
+// BoltInactivityRepositoryOptions configures the bbolt inactivity repository.
+type BoltInactivityRepositoryOptions = inactivity_boltstore.BoltStoreOptions
+
+// HandoffRepository keeps the open tickets of a HandoffDesk.
+type HandoffRepository = adapter_output.IHandoffRepository
+
+// BoltHandoffRepositoryOptions configures the bbolt handoff repository.
+type BoltHandoffRepositoryOptions = handoff_boltstore.BoltStoreOptions
+
+// AgentConsole is where human agents handle handoff tickets, e.g. a helpdesk.
+type AgentConsole = adapter_output.IAgentConsole
+
+// OptOutList tells the chats that opted out of campaigns.
+type OptOutList = adapter_output.IOptOutList
+
+// MemoryOptOutList is an OptOutList kept in memory.
+type MemoryOptOutList = campaigns.MemoryOptOutList
+
+// IdempotencyKey returns the idempotency key of an action performed with ctx,
+// e.g. a handler's context, so handlers can pass it to their own services.
+// Returns an empty string for messages without an ID. Call it once per action.
+func IdempotencyKey(ctx context.Context) string {
+	return d_idempotency.ActionKey(ctx)
+}
+
 // ============================================================================
 // Constructors - Adapters
 // ============================================================================
 
+// RouterApiOptions configures timeouts, retries and the HTTP client of the Router API.
+type RouterApiOptions = output_router_api.RouterApiOptions
+
+// BreakerOptions configures the circuit breaker of the Router API.
+type BreakerOptions = output_router_api.BreakerOptions
+
+// BreakerState is the state of the Router API circuit breaker.
+type BreakerState = output_router_api.BreakerState
+
+// Circuit breaker states reported through BreakerOptions.OnStateChange.
+const (
+	BreakerClosed   = output_router_api.BreakerClosed
+	BreakerOpen     = output_router_api.BreakerOpen
+	BreakerHalfOpen = output_router_api.BreakerHalfOpen
+)
+
+// RateLimit configures a token bucket for Router API calls.
+type RateLimit = output_router_api.RateLimit
+
+// RouterApiAuthenticator adds credentials to every Router API request.
+type RouterApiAuthenticator = output_router_api.IAuthenticator
+
+// BasicAuth authenticates Router API requests with HTTP basic auth (the default).
+type BasicAuth = output_router_api.BasicAuth
+
+// BearerToken authenticates Router API requests with a static bearer token.
+type BearerToken = output_router_api.BearerToken
+
+// HMACSigner signs each Router API request with a shared secret.
+type HMACSigner = output_router_api.HMACSigner
+
+// OAuth2Options configures the OAuth2 client credentials authenticator.
+type OAuth2Options = output_router_api.OAuth2Options
+
+// RouterApiError describes a failed Router API call. Its Kind is one of the
+// ErrRouterApi* errors below, which can be matched with errors.Is.
+type RouterApiError = output_router_api.APIError
+
+// Router API error kinds.
+var (
+	ErrRouterApiNotFound     = output_router_api.ErrNotFound
+	ErrRouterApiUnauthorized = output_router_api.ErrUnauthorized
+	ErrRouterApiValidation   = output_router_api.ErrValidation
+	ErrRouterApiRateLimited  = output_router_api.ErrRateLimited
+	ErrRouterApiServer       = output_router_api.ErrServer
+	ErrRouterApiRejected     = output_router_api.ErrRejected
+	// ErrRouterApiCircuitOpen is returned without calling the API while the breaker is open.
+	ErrRouterApiCircuitOpen = output_router_api.ErrCircuitOpen
+	// ErrRouterApiAuthentication is returned when credentials cannot be
+	// obtained, e.g. the OAuth2 token request failed.
+	ErrRouterApiAuthentication = output_router_api.ErrAuthentication
+)
+
+// RabbitMQOptions configures connection, topology, QoS, reconnection and
+// dead-lettering for the RabbitMQ receiver.
+type RabbitMQOptions = input_queue.RabbitMQOptions
+
+// QueueDeclareOptions configures how the RabbitMQ queue is declared.
+type QueueDeclareOptions = input_queue.QueueDeclareOptions
+
+// ExchangeOptions configures a RabbitMQ exchange declaration.
+type ExchangeOptions = input_queue.ExchangeOptions
+
+// BindingOptions binds the RabbitMQ queue to an exchange.
+type BindingOptions = input_queue.BindingOptions
+
+// BackoffOptions configures exponential backoff with jitter for reconnections.
+type BackoffOptions = input_queue.BackoffOptions
+
+// ConnectionState describes the state of the RabbitMQ connection.
+type ConnectionState = input_queue.ConnectionState
+
+// Connection state constants reported through RabbitMQOptions.OnStateChange.
+const (
+	StateConnecting   = input_queue.StateConnecting
+	StateConnected    = input_queue.StateConnected
+	StateDisconnected = input_queue.StateDisconnected
+	StateClosed       = input_queue.StateClosed
+)
+
 // NewRabbitMQ creates a new RabbitMQ message receiver.
-func NewRabbitMQ[Obs any](user, password, host, vhost, queue string) MessageReceiver[Obs] {
-	return input_queue.NewRabbitMQ[Obs](user, password, host, vhost, queue)
+// Optional options configure redelivery and dead-lettering of failed messages.
+func NewRabbitMQ[Obs any](user, password, host, vhost, queue string, options ...RabbitMQOptions) MessageReceiver[Obs] {
+	return input_queue.NewRabbitMQ[Obs](user, password, host, vhost, queue, options...)
+}
+
+// NewRabbitMQWithOptions creates a new RabbitMQ message receiver from a full
+// configuration, including URL, TLS, topology declaration and backoff.
+func NewRabbitMQWithOptions[Obs any](options RabbitMQOptions) (MessageReceiver[Obs], error) {
+	return input_queue.NewRabbitMQWithOptions[Obs](options)
+}
+
+// Webhook is an HTTP message receiver; it is also an http.Handler.
+type Webhook[Obs any] = input_webhook.Webhook[Obs]
+
+// WebhookOptions configures the HTTP webhook receiver.
+type WebhookOptions = input_webhook.WebhookOptions
+
+// NewWebhook creates an HTTP webhook receiver that authenticates requests with
+// an HMAC-SHA256 signature keyed with the shared secret.
+func NewWebhook[Obs any](secret string, options ...WebhookOptions) (*Webhook[Obs], error) {
+	return input_webhook.NewWebhook[Obs](secret, options...)
+}
+
+// EventEndpoint is an http.Handler that runs signed backend events
+// through an EventHandler.
+type EventEndpoint = input_webhook.EventHandler
+
+// NewEventHandler creates an HTTP endpoint for backend events that
+// authenticates requests like NewWebhook and runs them through handler,
+// usually the App.
+func NewEventHandler(secret string, handler EventHandler, options ...WebhookOptions) (*EventEndpoint, error) {
+	return input_webhook.NewEventHandler(secret, handler, options...)
+}
+
+// NewFanIn creates a receiver that merges the deliveries of several receivers,
+// e.g. RabbitMQ and an HTTP webhook.
+func NewFanIn[Obs any](receivers ...MessageReceiver[Obs]) MessageReceiver[Obs] {
+	return input_fanin.NewFanIn[Obs](receivers...)
+}
+
+// Session is the stored state of a chat kept by a SessionRepository.
+type Session = d_session.Session
+
+// SessionRepository persists chat sessions when the bot runs without the Router API.
+type SessionRepository = adapter_output.ISessionRepository
+
+// SessionOptions configures the session receiver and store.
+type SessionOptions = session.SessionOptions
+
+// BoltSessionRepositoryOptions configures the bbolt session repository.
+type BoltSessionRepositoryOptions = boltstore.BoltStoreOptions
+
+// NewFileSessionRepository creates a session repository that keeps one JSON file per chat in dir.
+func NewFileSessionRepository(dir string) (SessionRepository, error) {
+	return filestore.NewFileStore(dir)
+}
+
+// NewBoltSessionRepository creates a session repository backed by the bbolt database at path.
+func NewBoltSessionRepository(path string, options ...BoltSessionRepositoryOptions) (SessionRepository, error) {
+	return boltstore.NewBoltStore(path, options...)
+}
+
+// NewSessionReceiver wraps a receiver, loading the user state of each message from repository.
+func NewSessionReceiver[Obs any](receiver MessageReceiver[Obs], repository SessionRepository, options ...SessionOptions) MessageReceiver[Obs] {
+	return session.NewReceiver(receiver, repository, options...)
+}
+
+// NewSessionExecutor creates an executor that keeps sessions and menu transfers
+// in repository and sends messages through messenger. Files and optional
+// capabilities are served by messenger when it provides them.
+func NewSessionExecutor(messenger Messenger, repository SessionRepository, options ...SessionOptions) RouterService {
+	return session.NewExecutor(messenger, repository, options...)
+}
+
+// LoopbackStore keeps in-memory sessions and bot replies for the loopback adapters.
+type LoopbackStore = loopback.Store
+
+// LoopbackStoreOptions configures the loopback session store.
+type LoopbackStoreOptions = loopback.StoreOptions
+
+// LoopbackSession is the session of a chat kept by the loopback store.
+type LoopbackSession = loopback.Session
+
+// LoopbackReceiver is an in-memory receiver fed by Send and SendAndWait.
+type LoopbackReceiver[Obs any] = loopback.Receiver[Obs]
+
+// LoopbackExecutor is an in-memory executor that persists sessions in a LoopbackStore.
+type LoopbackExecutor = loopback.Executor
+
+// NewLoopbackStore creates an in-memory session store.
+func NewLoopbackStore(options ...LoopbackStoreOptions) *LoopbackStore {
+	return loopback.NewStore(options...)
+}
+
+// NewLoopbackReceiver creates an in-memory receiver that injects the user
+// state kept in store, for running a bot in-process.
+func NewLoopbackReceiver[Obs any](store *LoopbackStore) *LoopbackReceiver[Obs] {
+	return loopback.NewReceiver[Obs](store)
+}
+
+// NewLoopbackExecutor creates an in-memory executor that records replies and
+// session changes in store.
+func NewLoopbackExecutor(store *LoopbackStore) *LoopbackExecutor {
+	return loopback.NewExecutor(store)
+}
+
+// Simulator runs a chatbot interactively in the terminal.
+type Simulator[Obs any] = simulator.Simulator[Obs]
+
+// SimulatorOptions configures the terminal simulator.
+type SimulatorOptions = simulator.SimulatorOptions
+
+// NewSimulator creates a terminal simulator that runs the engine with an
+// in-memory receiver and a console executor, without RabbitMQ or the Router API.
+func NewSimulator[Obs any](engine *Engine[Obs], options ...SimulatorOptions) *Simulator[Obs] {
+	return simulator.NewSimulator(engine, options...)
+}
+
+// NewComposedExecutor creates an executor that delegates each operation to one of its parts.
+func NewComposedExecutor(parts ExecutorParts) RouterService {
+	return output_compose.NewExecutor(parts)
+}
+
+// NewComposedExecutorFrom adapts an existing executor, such as the Router API,
+// replacing the parts set in overrides.
+func NewComposedExecutorFrom(executor RouterService, overrides ExecutorParts) RouterService {
+	return output_compose.NewExecutorFrom(executor, overrides)
+}
+
+// NewDedupExecutor wraps an executor so that actions already performed for a
+// message are skipped when the message is processed again.
+func NewDedupExecutor(executor RouterService, store DedupStore) RouterService {
+	return output_dedup.NewExecutor(executor, store)
+}
+
+// NewMemoryDedupStore creates an in-memory dedup store whose keys expire after a TTL.
+func NewMemoryDedupStore(options ...MemoryDedupStoreOptions) DedupStore {
+	return output_dedup.NewMemoryStore(options...)
+}
+
+// NewOutboxExecutor wraps an executor, keeping the actions that fail with a
+// retryable error in repository. Run its dispatcher with `go executor.Run(ctx)`.
+func NewOutboxExecutor(executor RouterService, repository OutboxRepository, options ...OutboxOptions) *OutboxExecutor {
+	return outbox.NewExecutor(executor, repository, options...)
+}
+
+// NewMemoryOutboxRepository creates an outbox repository kept in memory,
+// which loses its actions when the process stops.
+func NewMemoryOutboxRepository() OutboxRepository {
+	return outbox.NewMemoryRepository()
+}
+
+// NewBoltOutboxRepository creates an outbox repository backed by the bbolt database at path.
+func NewBoltOutboxRepository(path string, options ...BoltOutboxRepositoryOptions) (OutboxRepository, error) {
+	return outbox_boltstore.NewBoltStore(path, options...)
+}
+
+// NewMemoryJobRepository creates a job repository kept in memory, which
+// loses its jobs when the process stops.
+func NewMemoryJobRepository() JobRepository {
+	return jobs.NewMemoryRepository()
+}
+
+// NewBoltJobRepository creates a job repository backed by the bbolt database at path.
+func NewBoltJobRepository(path string, options ...BoltJobRepositoryOptions) (JobRepository, error) {
+	return jobs_boltstore.NewBoltStore(path, options...)
+}
+
+// NewMemoryCampaignRepository creates a campaign repository kept in memory,
+// which loses its campaigns when the process stops.
+func NewMemoryCampaignRepository() CampaignRepository {
+	return campaigns.NewMemoryRepository()
+}
+
+// NewBoltCampaignRepository creates a campaign repository backed by the bbolt database at path.
+func NewBoltCampaignRepository(path string, options ...BoltCampaignRepositoryOptions) (CampaignRepository, error) {
+	return campaigns_boltstore.NewBoltStore(path, options...)
+}
+
+// NewMemoryInactivityRepository creates an inactivity repository kept in
+// memory, which loses its timers when the process stops.
+func NewMemoryInactivityRepository() InactivityRepository {
+	return inactivity.NewMemoryRepository()
+}
+
+// NewBoltInactivityRepository creates an inactivity repository backed by the bbolt database at path.
+func NewBoltInactivityRepository(path string, options ...BoltInactivityRepositoryOptions) (InactivityRepository, error) {
+	return inactivity_boltstore.NewBoltStore(path, options...)
+}
+
+// NewMemoryHandoffRepository creates a handoff repository kept in memory,
+// which loses its tickets when the process stops.
+func NewMemoryHandoffRepository() HandoffRepository {
+	return handoff.NewMemoryRepository()
+}
+
+// NewBoltHandoffRepository creates a handoff repository backed by the bbolt database at path.
+func NewBoltHandoffRepository(path string, options ...BoltHandoffRepositoryOptions) (HandoffRepository, error) {
+	return handoff_boltstore.NewBoltStore(path, options...)
+}
+
+// NewMemoryOptOutList creates an opt-out list holding chatIDs.
+func NewMemoryOptOutList(chatIDs ...ChatID) *MemoryOptOutList {
+	return campaigns.NewMemoryOptOutList(chatIDs...)
+}
+
+// ParseCampaignRecipientsCSV reads a CSV recipient list with user_id and
+// company_id columns; the other columns become the recipients' variables.
+func ParseCampaignRecipientsCSV(r io.Reader) ([]CampaignRecipient, error) {
+	return dto_campaign.ParseRecipientsCSV(r)
+}
+
+// ParseCampaignRecipientsJSON reads a JSON recipient list: an array of
+// objects with a chat_id and optional variables.
+func ParseCampaignRecipientsJSON(r io.Reader) ([]CampaignRecipient, error) {
+	return dto_campaign.ParseRecipientsJSON(r)
+}
+
+// WriteCampaignReportCSV writes the delivery report of a campaign as CSV,
+// one row per recipient.
+func WriteCampaignReportCSV(w io.Writer, report CampaignReport) error {
+	return dto_campaign.WriteReportCSV(w, report)
+}
+
+// ParseCalendarPeriod parses a period written as "HH:MM-HH:MM".
+func ParseCalendarPeriod(s string) (CalendarPeriod, error) {
+	return d_calendar.ParsePeriod(s)
+}
+
+// MustParseCalendarPeriod is ParseCalendarPeriod for periods known to be
+// valid. It panics otherwise.
+func MustParseCalendarPeriod(s string) CalendarPeriod {
+	return d_calendar.MustParsePeriod(s)
+}
+
+// ParseCalendarDate parses a date written as "YYYY-MM-DD", or "MM-DD" for a
+// date recurring every year.
+func ParseCalendarDate(s string) (CalendarDate, error) {
+	return d_calendar.ParseDate(s)
+}
+
+// BrazilStateHolidays returns the rule of the holidays of a Brazilian state,
+// given by its code (e.g. "SP").
+func BrazilStateHolidays(state string) (HolidayRule, error) {
+	return d_calendar.BrazilState(state)
+}
+
+// ParseHolidaysCSV reads a CSV holiday list with a date column and an
+// optional name column.
+func ParseHolidaysCSV(r io.Reader) ([]Holiday, error) {
+	return dto_calendar.ParseHolidaysCSV(r)
+}
+
+// ParseHolidaysJSON reads a JSON holiday list: an array of objects with a
+// date and an optional name.
+func ParseHolidaysJSON(r io.Reader) ([]Holiday, error) {
+	return dto_calendar.ParseHolidaysJSON(r)
 }
 
 // NewRouterApi creates a new Router API service.
-func NewRouterApi(url, username, password string) RouterService {
-	return output_router_api.NewRouterApi(url, username, password)
+// Optional options configure timeouts, retries and the HTTP client.
+func NewRouterApi(url, username, password string, options ...RouterApiOptions) RouterService {
+	return output_router_api.NewRouterApi(url, username, password, options...)
+}
+
+// NewOAuth2ClientCredentials creates a Router API authenticator for the OAuth2
+// client credentials flow. Tokens are cached and refreshed before they expire.
+func NewOAuth2ClientCredentials(options OAuth2Options) RouterApiAuthenticator {
+	return output_router_api.NewOAuth2ClientCredentials(options)
 }
 
 // ============================================================================
@@ -183,12 +865,49 @@ func NewRouterApi(url, username, password string) RouterService {
 // ============================================================================
 
 // NewApp creates a new chatbot application with the provided engine and adapters.
+// Optional options configure concurrent message processing.
 func NewApp[Obs any](
 	engine *Engine[Obs],
 	receiver MessageReceiver[Obs],
 	router RouterService,
+	options ...AppOptions,
 ) *App[Obs] {
-	return service.NewChatbotApp(engine, receiver, router)
+	return service.NewChatbotApp(engine, receiver, router, options...)
+}
+
+// NewCampaignSender creates a sender for the campaigns of app, kept in
+// repository. Call Run to start sending.
+func NewCampaignSender[Obs any](app *App[Obs], repository CampaignRepository, options ...CampaignOptions) *CampaignSender[Obs] {
+	return service.NewCampaignSender(app, repository, options...)
+}
+
+// NewInactivityScheduler creates a scheduler for the inactivity timers of
+// app, kept in repository. Create it before starting the app, then run it
+// with `go scheduler.Run(ctx)`.
+func NewInactivityScheduler[Obs any](app *App[Obs], repository InactivityRepository, options ...InactivityOptions) *InactivityScheduler[Obs] {
+	return service.NewInactivityScheduler(app, repository, options...)
+}
+
+// NewHandoffDesk creates a desk handing the chats of app off to the agents
+// of console, with tickets kept in repository. Create it before starting the app.
+func NewHandoffDesk[Obs any](
+	app *App[Obs],
+	repository HandoffRepository,
+	console AgentConsole,
+	options ...HandoffOptions,
+) *HandoffDesk[Obs] {
+	return service.NewHandoffDesk(app, repository, console, options...)
+}
+
+// NewFakeClock creates a manual clock set to now.
+func NewFakeClock(now time.Time) *FakeClock {
+	return clocktest.NewFakeClock(now)
+}
+
+// NewJobRunner creates a runner for the background jobs of app, kept in
+// repository. Register its tasks, then run it with `go runner.Run(ctx)`.
+func NewJobRunner[Obs any](app *App[Obs], repository JobRepository, options ...JobOptions) *JobRunner[Obs] {
+	return service.NewJobRunner(app, repository, options...)
 }
 
 // NewRoute creates a new Route from a path string.
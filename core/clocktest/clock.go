@@ -0,0 +1,43 @@
+// Package clocktest provides FakeClock, a manual clock for testing
+// time-based features such as jobs, campaigns, inactivity timers, rate
+// limits and retries without waiting.
+package clocktest
+
+import (
+	"sync"
+	"time"
+)
+
+// FakeClock is a clock that only moves when told to. Pass its Now method
+// wherever a component takes a Now option, e.g. a JobRunner, a
+// CampaignSender or an InactivityScheduler. It is safe for concurrent use.
+type FakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+// NewFakeClock creates a clock set to now.
+func NewFakeClock(now time.Time) *FakeClock {
+	return &FakeClock{now: now}
+}
+
+// Now returns the time of the clock.
+func (c *FakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// Advance moves the clock forward by d.
+func (c *FakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// Set moves the clock to now.
+func (c *FakeClock) Set(now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = now
+}
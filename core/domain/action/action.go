@@ -41,3 +41,22 @@ type TransferToMenu struct {
 
 // IsRouteReturn implements the RouteReturn interface.
 func (TransferToMenu) IsRouteReturn() {}
+
+// HandoffToAgent hands the conversation off to a human agent: the chat joins
+// the queue of a department, or of one of its agents, and the bot pauses for
+// it until the agent closes the ticket (see service.HandoffDesk).
+type HandoffToAgent struct {
+	// DepartmentID is the department the chat is handed off to.
+	DepartmentID string
+	// Agent is the agent whose queue the chat joins. Empty for the
+	// department's queue.
+	Agent string
+	// Reason tells the agent why the chat was handed off.
+	Reason string
+	// ResumeRoute is where the bot resumes when the ticket is closed, unless
+	// the agent chooses another route. Defaults to the desk's ResumeRoute.
+	ResumeRoute string
+}
+
+// IsRouteReturn implements the RouteReturn interface.
+func (HandoffToAgent) IsRouteReturn() {}
@@ -66,3 +66,9 @@ func TestTransferToMenu_Fields(t *testing.T) {
 		t.Errorf("TransferToMenu.Route = %v, want support", transfer.Route)
 	}
 }
+
+func TestHandoffToAgent_IsRouteReturn(t *testing.T) {
+	handoff := HandoffToAgent{DepartmentID: "billing"}
+	// Should not panic - just verify it implements the interface
+	handoff.IsRouteReturn()
+}
@@ -0,0 +1,133 @@
+package d_calendar
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ErrUnknownState is returned by BrazilState for unknown state codes.
+var ErrUnknownState = errors.New("unknown Brazilian state")
+
+// fixedHoliday is a holiday on the same day every year.
+type fixedHoliday struct {
+	month time.Month
+	day   int
+	name  string
+}
+
+// brazilStates holds the state holidays of each Brazilian state (UF),
+// including the state dates that coincide with national holidays. The
+// municipal holidays of the capitals are not included.
+var brazilStates = map[string][]fixedHoliday{
+	"AC": {{time.January, 23, "Dia do Evangélico"}, {time.June, 15, "Aniversário do Acre"}, {time.September, 5, "Dia da Amazônia"}, {time.November, 17, "Assinatura do Tratado de Petrópolis"}},
+	"AL": {{time.June, 24, "São João"}, {time.June, 29, "São Pedro"}, {time.September, 16, "Emancipação Política de Alagoas"}},
+	"AM": {{time.September, 5, "Elevação do Amazonas à Categoria de Província"}},
+	"AP": {{time.March, 19, "São José"}, {time.July, 25, "São Tiago"}, {time.October, 5, "Criação do Estado do Amapá"}},
+	"BA": {{time.July, 2, "Independência da Bahia"}},
+	"CE": {{time.March, 19, "São José"}, {time.March, 25, "Data Magna do Ceará"}},
+	"DF": {{time.April, 21, "Fundação de Brasília"}, {time.November, 30, "Dia do Evangélico"}},
+	"ES": {},
+	"GO": {},
+	"MA": {{time.July, 28, "Adesão do Maranhão à Independência do Brasil"}},
+	"MG": {{time.April, 21, "Data Magna de Minas Gerais"}},
+	"MS": {{time.October, 11, "Criação do Estado de Mato Grosso do Sul"}},
+	"MT": {},
+	"PA": {{time.August, 15, "Adesão do Grão-Pará à Independência do Brasil"}},
+	"PB": {{time.August, 5, "Fundação do Estado da Paraíba"}},
+	"PE": {{time.March, 6, "Data Magna de Pernambuco"}, {time.June, 24, "São João"}},
+	"PI": {{time.March, 13, "Batalha do Jenipapo"}, {time.October, 19, "Dia do Piauí"}},
+	"PR": {{time.December, 19, "Emancipação Política do Paraná"}},
+	"RJ": {{time.April, 23, "Dia de São Jorge"}},
+	"RN": {{time.June, 29, "São Pedro"}, {time.October, 3, "Mártires de Cunhaú e Uruaçu"}},
+	"RO": {{time.January, 4, "Criação do Estado de Rondônia"}, {time.June, 18, "Dia do Evangélico"}},
+	"RR": {{time.October, 5, "Criação do Estado de Roraima"}},
+	"RS": {{time.September, 20, "Revolução Farroupilha"}},
+	"SC": {{time.August, 11, "Criação da Capitania de Santa Catarina"}},
+	"SE": {{time.July, 8, "Emancipação Política de Sergipe"}},
+	"SP": {{time.July, 9, "Revolução Constitucionalista"}},
+	"TO": {{time.March, 18, "Autonomia do Tocantins"}, {time.September, 8, "Nossa Senhora da Natividade"}, {time.October, 5, "Criação do Estado do Tocantins"}},
+}
+
+// Easter returns the date of Easter Sunday in a year of the Gregorian
+// calendar.
+func Easter(year int) Date {
+	a := year % 19
+	b, c := year/100, year%100
+	d, e := b/4, b%4
+	f := (b + 8) / 25
+	g := (b - f + 1) / 3
+	h := (19*a + b - d - g + 15) % 30
+	i, k := c/4, c%4
+	l := (32 + 2*e + 2*i - h - k) % 7
+	m := (a + 11*h + 22*l) / 451
+	month := (h + l - 7*m + 114) / 31
+	day := (h+l-7*m+114)%31 + 1
+	return Date{Year: year, Month: time.Month(month), Day: day}
+}
+
+// easterOffset returns the day days after Easter in a year.
+func easterOffset(year, days int) Date {
+	easter := Easter(year)
+	return DateOf(time.Date(year, easter.Month, easter.Day+days, 0, 0, 0, 0, time.UTC))
+}
+
+// BrazilNational returns the Brazilian national holidays of a year,
+// including Good Friday and, from 2024 on, the Dia da Consciência Negra.
+func BrazilNational(year int) []Holiday {
+	holidays := []Holiday{
+		{Date: Date{year, time.January, 1}, Name: "Confraternização Universal"},
+		{Date: easterOffset(year, -2), Name: "Paixão de Cristo"},
+		{Date: Date{year, time.April, 21}, Name: "Tiradentes"},
+		{Date: Date{year, time.May, 1}, Name: "Dia do Trabalho"},
+		{Date: Date{year, time.September, 7}, Name: "Independência do Brasil"},
+		{Date: Date{year, time.October, 12}, Name: "Nossa Senhora Aparecida"},
+		{Date: Date{year, time.November, 2}, Name: "Finados"},
+		{Date: Date{year, time.November, 15}, Name: "Proclamação da República"},
+		{Date: Date{year, time.December, 25}, Name: "Natal"},
+	}
+	if year >= 2024 {
+		holidays = append(holidays, Holiday{Date: Date{year, time.November, 20}, Name: "Dia Nacional de Zumbi e da Consciência Negra"})
+	}
+	return holidays
+}
+
+// BrazilOptional returns the national "pontos facultativos" of a year that
+// most businesses close on: Carnival Monday and Tuesday, and Corpus Christi.
+func BrazilOptional(year int) []Holiday {
+	return []Holiday{
+		{Date: easterOffset(year, -48), Name: "Carnaval"},
+		{Date: easterOffset(year, -47), Name: "Carnaval"},
+		{Date: easterOffset(year, 60), Name: "Corpus Christi"},
+	}
+}
+
+// BrazilState returns the rule of the state holidays of a Brazilian state,
+// given by its code (e.g. "SP"). Municipal holidays are not included: add
+// them to the calendar's Holidays, e.g. from a holiday file.
+func BrazilState(state string) (HolidayRule, error) {
+	fixed, ok := brazilStates[strings.ToUpper(strings.TrimSpace(state))]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownState, state)
+	}
+
+	return func(year int) []Holiday {
+		holidays := make([]Holiday, 0, len(fixed))
+		for _, h := range fixed {
+			holidays = append(holidays, Holiday{Date: Date{year, h.month, h.day}, Name: h.name})
+		}
+		return holidays
+	}, nil
+}
+
+// BrazilStates returns the codes of the Brazilian states known to BrazilState.
+func BrazilStates() []string {
+	states := make([]string, 0, len(brazilStates))
+	for state := range brazilStates {
+		states = append(states, state)
+	}
+	sort.Strings(states)
+	return states
+}
@@ -0,0 +1,72 @@
+package d_calendar
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestEaster(t *testing.T) {
+	tests := map[int]Date{
+		2019: {2019, time.April, 21},
+		2024: {2024, time.March, 31},
+		2025: {2025, time.April, 20},
+		2026: {2026, time.April, 5},
+		2038: {2038, time.April, 25},
+	}
+
+	for year, want := range tests {
+		if got := Easter(year); got != want {
+			t.Errorf("Easter(%d) = %v, want %v", year, got, want)
+		}
+	}
+}
+
+func TestBrazilNational(t *testing.T) {
+	calendar := Calendar{HolidayRules: []HolidayRule{BrazilNational}}
+
+	if holiday, ok := calendar.Holiday(Date{2026, time.April, 3}); !ok || holiday.Name != "Paixão de Cristo" {
+		t.Errorf("expected Good Friday, got %+v, %v", holiday, ok)
+	}
+	if _, ok := calendar.Holiday(Date{2025, time.November, 20}); !ok {
+		t.Error("expected the Dia da Consciência Negra from 2024 on")
+	}
+	if _, ok := calendar.Holiday(Date{2023, time.November, 20}); ok {
+		t.Error("expected no national holiday on 2023-11-20")
+	}
+	if _, ok := calendar.Holiday(Date{2025, time.March, 4}); ok {
+		t.Error("expected Carnival to be optional")
+	}
+}
+
+func TestBrazilOptional(t *testing.T) {
+	want := []Date{{2025, time.March, 3}, {2025, time.March, 4}, {2025, time.June, 19}}
+
+	holidays := BrazilOptional(2025)
+	if len(holidays) != len(want) {
+		t.Fatalf("expected %d holidays, got %+v", len(want), holidays)
+	}
+	for i, holiday := range holidays {
+		if holiday.Date != want[i] {
+			t.Errorf("holiday %d = %v, want %v", i, holiday.Date, want[i])
+		}
+	}
+}
+
+func TestBrazilState(t *testing.T) {
+	rule, err := BrazilState("ba")
+	if err != nil {
+		t.Fatalf("BrazilState() error = %v", err)
+	}
+	holidays := rule(2025)
+	if len(holidays) != 1 || holidays[0].Date != (Date{2025, time.July, 2}) {
+		t.Errorf("unexpected holidays %+v", holidays)
+	}
+
+	if _, err := BrazilState("XX"); !errors.Is(err, ErrUnknownState) {
+		t.Errorf("expected ErrUnknownState, got %v", err)
+	}
+	if states := BrazilStates(); len(states) != 27 {
+		t.Errorf("expected 27 states, got %d", len(states))
+	}
+}
@@ -0,0 +1,347 @@
+// Package d_calendar provides business-hours calendars: weekly opening
+// periods in a timezone, holidays (including the Brazilian national and
+// state ones, see brazil.go) and per-date exceptions, so routes can tell
+// whether a company is open and when it opens next.
+package d_calendar
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// MAX_OPENING_SEARCH_DAYS bounds how far NextOpening looks ahead.
+const MAX_OPENING_SEARCH_DAYS = 400
+
+// DAY is the End of a period lasting until midnight.
+const DAY = 24 * time.Hour
+
+// Errors returned when parsing and validating calendars.
+var (
+	// ErrInvalidPeriod is returned for periods that are malformed, end
+	// before they start or last past midnight.
+	ErrInvalidPeriod = errors.New("invalid period")
+	// ErrInvalidDate is returned for malformed dates.
+	ErrInvalidDate = errors.New("invalid date")
+)
+
+// Period is an opening period within a day, as offsets from midnight.
+type Period struct {
+	// Start is when the period starts, e.g. 8*time.Hour for 08:00.
+	Start time.Duration
+	// End is when the period ends, excluded. Use DAY for midnight.
+	End time.Duration
+}
+
+// ParsePeriod parses a period written as "HH:MM-HH:MM", e.g. "08:00-18:00".
+// "24:00" stands for midnight at the end of the day.
+func ParsePeriod(s string) (Period, error) {
+	start, end, ok := strings.Cut(strings.TrimSpace(s), "-")
+	if !ok {
+		return Period{}, fmt.Errorf("%w: %q", ErrInvalidPeriod, s)
+	}
+
+	var p Period
+	var err error
+	if p.Start, err = parseClock(start); err != nil {
+		return Period{}, fmt.Errorf("%w: %q", ErrInvalidPeriod, s)
+	}
+	if p.End, err = parseClock(end); err != nil {
+		return Period{}, fmt.Errorf("%w: %q", ErrInvalidPeriod, s)
+	}
+	return p, p.Validate()
+}
+
+// MustParsePeriod is ParsePeriod for periods known to be valid. It panics
+// otherwise.
+func MustParsePeriod(s string) Period {
+	p, err := ParsePeriod(s)
+	if err != nil {
+		panic(err)
+	}
+	return p
+}
+
+// parseClock parses a time of day written as "HH:MM".
+func parseClock(s string) (time.Duration, error) {
+	var hours, minutes int
+	if _, err := fmt.Sscanf(strings.TrimSpace(s), "%d:%d", &hours, &minutes); err != nil {
+		return 0, err
+	}
+	if minutes < 0 || minutes > 59 || hours < 0 || hours > 24 || (hours == 24 && minutes != 0) {
+		return 0, ErrInvalidPeriod
+	}
+	return time.Duration(hours)*time.Hour + time.Duration(minutes)*time.Minute, nil
+}
+
+// Validate checks that the period starts before it ends, within a day.
+func (p Period) Validate() error {
+	if p.Start < 0 || p.End > DAY || p.Start >= p.End {
+		return fmt.Errorf("%w: %v-%v", ErrInvalidPeriod, p.Start, p.End)
+	}
+	return nil
+}
+
+// Contains reports whether the offset from midnight falls in the period.
+func (p Period) Contains(offset time.Duration) bool {
+	return offset >= p.Start && offset < p.End
+}
+
+// Date is a calendar day. A Date with Year 0 recurs every year.
+type Date struct {
+	Year  int
+	Month time.Month
+	Day   int
+}
+
+// DateOf returns the date of t in its location.
+func DateOf(t time.Time) Date {
+	year, month, day := t.Date()
+	return Date{Year: year, Month: month, Day: day}
+}
+
+// ParseDate parses a date written as "YYYY-MM-DD", or as "MM-DD" for a date
+// recurring every year.
+func ParseDate(s string) (Date, error) {
+	s = strings.TrimSpace(s)
+	layout := "2006-01-02"
+	if len(s) == len("01-02") {
+		layout = "01-02"
+	}
+
+	t, err := time.Parse(layout, s)
+	if err != nil {
+		return Date{}, fmt.Errorf("%w: %q", ErrInvalidDate, s)
+	}
+	date := DateOf(t)
+	if layout == "01-02" {
+		date.Year = 0
+	}
+	return date, nil
+}
+
+// Matches reports whether the date falls on day, ignoring the year of
+// recurring dates.
+func (d Date) Matches(day Date) bool {
+	return d.Month == day.Month && d.Day == day.Day && (d.Year == 0 || d.Year == day.Year)
+}
+
+// String returns the date as "YYYY-MM-DD", or "MM-DD" when it recurs.
+func (d Date) String() string {
+	if d.Year == 0 {
+		return fmt.Sprintf("%02d-%02d", int(d.Month), d.Day)
+	}
+	return fmt.Sprintf("%04d-%02d-%02d", d.Year, int(d.Month), d.Day)
+}
+
+// at returns the time of day offset on the date, in loc.
+func (d Date) at(offset time.Duration, loc *time.Location) time.Time {
+	// time.Date normalizes the nanoseconds into the wall clock of the day.
+	return time.Date(d.Year, d.Month, d.Day, 0, 0, 0, int(offset), loc)
+}
+
+// next returns the following day.
+func (d Date) next() Date {
+	return DateOf(time.Date(d.Year, d.Month, d.Day+1, 0, 0, 0, 0, time.UTC))
+}
+
+// Holiday is a day the business is closed.
+type Holiday struct {
+	// Date is the day of the holiday; Year 0 makes it recur every year.
+	Date Date
+	// Name describes the holiday, e.g. "Natal".
+	Name string
+}
+
+// HolidayRule returns the holidays of a year, for holidays whose date
+// changes every year, such as the ones depending on Easter.
+type HolidayRule func(year int) []Holiday
+
+// Exception overrides the opening hours of a single day, e.g. a company
+// opening on a holiday or closing early on Christmas Eve.
+type Exception struct {
+	// Date is the day overridden; Year 0 makes it recur every year.
+	Date Date
+	// Name describes the exception.
+	Name string
+	// Hours are the opening periods of the day. Empty means closed.
+	Hours []Period
+}
+
+// Calendar holds the business hours of a company.
+type Calendar struct {
+	// Location is the timezone of the opening hours. Defaults to UTC.
+	Location *time.Location
+	// Weekly holds the opening periods of each weekday. Days without
+	// periods are closed.
+	Weekly map[time.Weekday][]Period
+	// Holidays are closed all day.
+	Holidays []Holiday
+	// HolidayRules add the holidays of each year, e.g. BrazilNational.
+	HolidayRules []HolidayRule
+	// Exceptions override the weekly periods and the holidays on their dates.
+	Exceptions []Exception
+}
+
+// Validate checks the periods of the calendar.
+func (c Calendar) Validate() error {
+	for weekday, periods := range c.Weekly {
+		for _, p := range periods {
+			if err := p.Validate(); err != nil {
+				return fmt.Errorf("%s: %w", weekday, err)
+			}
+		}
+	}
+	for _, exception := range c.Exceptions {
+		for _, p := range exception.Hours {
+			if err := p.Validate(); err != nil {
+				return fmt.Errorf("exception %s: %w", exception.Date, err)
+			}
+		}
+	}
+	return nil
+}
+
+// WithExceptions returns a copy of the calendar with more exceptions, e.g.
+// to derive the calendar of a company from a shared one.
+func (c Calendar) WithExceptions(exceptions ...Exception) Calendar {
+	c.Exceptions = append(append([]Exception(nil), c.Exceptions...), exceptions...)
+	return c
+}
+
+// location returns the timezone of the calendar.
+func (c Calendar) location() *time.Location {
+	if c.Location == nil {
+		return time.UTC
+	}
+	return c.Location
+}
+
+// Holiday returns the holiday on a day, if any.
+func (c Calendar) Holiday(day Date) (Holiday, bool) {
+	for _, holiday := range c.Holidays {
+		if holiday.Date.Matches(day) {
+			return holiday, true
+		}
+	}
+	for _, rule := range c.HolidayRules {
+		for _, holiday := range rule(day.Year) {
+			if holiday.Date.Matches(day) {
+				return holiday, true
+			}
+		}
+	}
+	return Holiday{}, false
+}
+
+// Hours returns the opening periods of a day, sorted by start: the ones of
+// its exception, none on holidays, or else the weekly ones. Exceptions for
+// a specific year win over recurring ones.
+func (c Calendar) Hours(day Date) []Period {
+	var exception *Exception
+	for i := range c.Exceptions {
+		if c.Exceptions[i].Date.Matches(day) && (exception == nil || exception.Date.Year == 0) {
+			exception = &c.Exceptions[i]
+		}
+	}
+
+	var periods []Period
+	switch {
+	case exception != nil:
+		periods = exception.Hours
+	default:
+		if _, ok := c.Holiday(day); ok {
+			return nil
+		}
+		weekday := time.Date(day.Year, day.Month, day.Day, 0, 0, 0, 0, time.UTC).Weekday()
+		periods = c.Weekly[weekday]
+	}
+
+	sorted := append([]Period(nil), periods...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Start < sorted[j].Start })
+	return sorted
+}
+
+// IsOpen reports whether the business is open at t.
+func (c Calendar) IsOpen(t time.Time) bool {
+	local := t.In(c.location())
+	offset := sinceMidnight(local)
+	for _, p := range c.Hours(DateOf(local)) {
+		if p.Contains(offset) {
+			return true
+		}
+	}
+	return false
+}
+
+// NextOpening returns when the business is open next, from t on: t itself
+// when it is open, or the start of the next opening period, in the
+// calendar's timezone. ok is false when it does not open within
+// MAX_OPENING_SEARCH_DAYS.
+func (c Calendar) NextOpening(t time.Time) (opening time.Time, ok bool) {
+	if c.IsOpen(t) {
+		return t, true
+	}
+
+	loc := c.location()
+	local := t.In(loc)
+	offset := sinceMidnight(local)
+	day := DateOf(local)
+	for i := 0; i < MAX_OPENING_SEARCH_DAYS; i++ {
+		for _, p := range c.Hours(day) {
+			if i > 0 || p.Start > offset {
+				return day.at(p.Start, loc), true
+			}
+		}
+		day = day.next()
+	}
+	return time.Time{}, false
+}
+
+// sinceMidnight returns the time of day of t.
+func sinceMidnight(t time.Time) time.Duration {
+	hour, minute, second := t.Clock()
+	return time.Duration(hour)*time.Hour + time.Duration(minute)*time.Minute +
+		time.Duration(second)*time.Second + time.Duration(t.Nanosecond())
+}
+
+// Calendars holds the calendar of each company, for bots serving several.
+type Calendars struct {
+	// Default is the calendar of the companies without one of their own.
+	Default Calendar
+	// Companies holds the calendars of companies, by company ID.
+	Companies map[string]Calendar
+}
+
+// For returns the calendar of a company.
+func (c Calendars) For(companyID string) Calendar {
+	if calendar, ok := c.Companies[companyID]; ok {
+		return calendar
+	}
+	return c.Default
+}
+
+// IsOpen reports whether a company is open at t.
+func (c Calendars) IsOpen(companyID string, t time.Time) bool {
+	return c.For(companyID).IsOpen(t)
+}
+
+// NextOpening returns when a company is open next, from t on.
+func (c Calendars) NextOpening(companyID string, t time.Time) (time.Time, bool) {
+	return c.For(companyID).NextOpening(t)
+}
+
+// Validate checks the periods of every calendar.
+func (c Calendars) Validate() error {
+	if err := c.Default.Validate(); err != nil {
+		return fmt.Errorf("default calendar: %w", err)
+	}
+	for companyID, calendar := range c.Companies {
+		if err := calendar.Validate(); err != nil {
+			return fmt.Errorf("calendar of company %s: %w", companyID, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,172 @@
+package d_calendar
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// brt is the Brasília timezone, without depending on the system's tzdata.
+var brt = time.FixedZone("BRT", -3*60*60)
+
+// newTestCalendar returns a calendar open 08:00-12:00 and 13:00-18:00 on
+// weekdays and 09:00-13:00 on Saturdays, in São Paulo.
+func newTestCalendar() Calendar {
+	weekday := []Period{MustParsePeriod("13:00-18:00"), MustParsePeriod("08:00-12:00")}
+	sp, _ := BrazilState("SP")
+	return Calendar{
+		Location: brt,
+		Weekly: map[time.Weekday][]Period{
+			time.Monday: weekday, time.Tuesday: weekday, time.Wednesday: weekday,
+			time.Thursday: weekday, time.Friday: weekday,
+			time.Saturday: {MustParsePeriod("09:00-13:00")},
+		},
+		HolidayRules: []HolidayRule{BrazilNational, sp},
+		Exceptions: []Exception{
+			{Date: Date{0, time.December, 24}, Name: "Véspera de Natal", Hours: []Period{MustParsePeriod("08:00-12:00")}},
+		},
+	}
+}
+
+func TestParsePeriod(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    Period
+		wantErr bool
+	}{
+		{"08:00-18:00", Period{8 * time.Hour, 18 * time.Hour}, false},
+		{" 18:30 - 24:00 ", Period{18*time.Hour + 30*time.Minute, DAY}, false},
+		{"18:00-08:00", Period{}, true},
+		{"08:00", Period{}, true},
+		{"08:61-09:00", Period{}, true},
+		{"25:00-26:00", Period{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParsePeriod(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParsePeriod() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr && !errors.Is(err, ErrInvalidPeriod) {
+				t.Errorf("expected ErrInvalidPeriod, got %v", err)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("ParsePeriod() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseDate(t *testing.T) {
+	if got, err := ParseDate("2025-03-04"); err != nil || got != (Date{2025, time.March, 4}) {
+		t.Errorf("ParseDate() = %v, %v", got, err)
+	}
+	if got, err := ParseDate("12-24"); err != nil || got != (Date{0, time.December, 24}) {
+		t.Errorf("ParseDate() = %v, %v", got, err)
+	}
+	if _, err := ParseDate("24/12"); !errors.Is(err, ErrInvalidDate) {
+		t.Errorf("expected ErrInvalidDate, got %v", err)
+	}
+}
+
+func TestCalendar_IsOpen(t *testing.T) {
+	calendar := newTestCalendar()
+
+	tests := []struct {
+		name string
+		at   time.Time
+		want bool
+	}{
+		{"weekday morning", time.Date(2025, time.March, 12, 9, 0, 0, 0, brt), true},
+		{"lunch break", time.Date(2025, time.March, 12, 12, 30, 0, 0, brt), false},
+		{"closing time", time.Date(2025, time.March, 12, 18, 0, 0, 0, brt), false},
+		{"saturday", time.Date(2025, time.March, 15, 10, 0, 0, 0, brt), true},
+		{"sunday", time.Date(2025, time.March, 16, 10, 0, 0, 0, brt), false},
+		{"other timezone", time.Date(2025, time.March, 12, 11, 0, 0, 0, time.UTC), true},
+		{"good friday", time.Date(2025, time.April, 18, 9, 0, 0, 0, brt), false},
+		{"state holiday", time.Date(2025, time.July, 9, 9, 0, 0, 0, brt), false},
+		{"christmas eve morning", time.Date(2025, time.December, 24, 9, 0, 0, 0, brt), true},
+		{"christmas eve afternoon", time.Date(2025, time.December, 24, 14, 0, 0, 0, brt), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := calendar.IsOpen(tt.at); got != tt.want {
+				t.Errorf("IsOpen(%v) = %v, want %v", tt.at, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCalendar_NextOpening(t *testing.T) {
+	calendar := newTestCalendar()
+
+	tests := []struct {
+		name string
+		at   time.Time
+		want time.Time
+	}{
+		{"open", time.Date(2025, time.March, 12, 9, 0, 0, 0, brt), time.Date(2025, time.March, 12, 9, 0, 0, 0, brt)},
+		{"before opening", time.Date(2025, time.March, 12, 7, 0, 0, 0, brt), time.Date(2025, time.March, 12, 8, 0, 0, 0, brt)},
+		{"lunch break", time.Date(2025, time.March, 12, 12, 30, 0, 0, brt), time.Date(2025, time.March, 12, 13, 0, 0, 0, brt)},
+		{"saturday afternoon", time.Date(2025, time.March, 15, 14, 0, 0, 0, brt), time.Date(2025, time.March, 17, 8, 0, 0, 0, brt)},
+		{"easter weekend", time.Date(2025, time.April, 17, 19, 0, 0, 0, brt), time.Date(2025, time.April, 19, 9, 0, 0, 0, brt)},
+		{"christmas", time.Date(2025, time.December, 24, 13, 0, 0, 0, brt), time.Date(2025, time.December, 26, 8, 0, 0, 0, brt)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := calendar.NextOpening(tt.at)
+			if !ok || !got.Equal(tt.want) {
+				t.Errorf("NextOpening(%v) = %v, %v, want %v", tt.at, got, ok, tt.want)
+			}
+		})
+	}
+
+	if _, ok := (Calendar{}).NextOpening(time.Now()); ok {
+		t.Error("expected a calendar without periods to never open")
+	}
+}
+
+func TestCalendar_ExceptionOpensHoliday(t *testing.T) {
+	calendar := newTestCalendar().WithExceptions(Exception{
+		Date:  Date{2025, time.November, 20},
+		Name:  "Mutirão",
+		Hours: []Period{MustParsePeriod("10:00-14:00")},
+	})
+
+	if !calendar.IsOpen(time.Date(2025, time.November, 20, 11, 0, 0, 0, brt)) {
+		t.Error("expected the exception to open the holiday")
+	}
+	if calendar.IsOpen(time.Date(2024, time.November, 20, 11, 0, 0, 0, brt)) {
+		t.Error("expected the exception to apply to its year only")
+	}
+	if len(newTestCalendar().Exceptions) != 1 {
+		t.Error("expected WithExceptions to leave the original calendar untouched")
+	}
+}
+
+func TestCalendars_For(t *testing.T) {
+	closed := Calendar{Location: brt}
+	calendars := Calendars{
+		Default:   newTestCalendar(),
+		Companies: map[string]Calendar{"closed": closed},
+	}
+	at := time.Date(2025, time.March, 12, 9, 0, 0, 0, brt)
+
+	if !calendars.IsOpen("acme", at) {
+		t.Error("expected the default calendar for companies without one")
+	}
+	if calendars.IsOpen("closed", at) {
+		t.Error("expected the company's calendar")
+	}
+	if err := calendars.Validate(); err != nil {
+		t.Errorf("Validate() error = %v", err)
+	}
+
+	calendars.Companies["broken"] = Calendar{Weekly: map[time.Weekday][]Period{time.Monday: {{Start: 2 * time.Hour, End: time.Hour}}}}
+	if err := calendars.Validate(); !errors.Is(err, ErrInvalidPeriod) {
+		t.Errorf("expected ErrInvalidPeriod, got %v", err)
+	}
+}
@@ -0,0 +1,179 @@
+// Package d_campaign provides broadcast campaigns, which send a message to a
+// list of chats, or run an entry route for each, and track the delivery to
+// every recipient.
+package d_campaign
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	d_message "github.com/irissonnlima/chatgraph-go/core/domain/message"
+	d_user "github.com/irissonnlima/chatgraph-go/core/domain/user"
+)
+
+// Status is the state of a campaign.
+type Status string
+
+// Campaign statuses.
+const (
+	// RUNNING campaigns still have recipients to deliver to.
+	RUNNING Status = "running"
+	// FINISHED campaigns delivered to, skipped or gave up on every recipient.
+	FINISHED Status = "finished"
+	// CANCELLED campaigns were stopped before finishing.
+	CANCELLED Status = "cancelled"
+)
+
+// RecipientStatus is the delivery state of a recipient.
+type RecipientStatus string
+
+// Recipient statuses.
+const (
+	// PENDING recipients were not delivered to yet.
+	PENDING RecipientStatus = "pending"
+	// SENT recipients got the message or ran the entry route.
+	SENT RecipientStatus = "sent"
+	// SKIPPED recipients were not delivered to on purpose, e.g. they opted out.
+	SKIPPED RecipientStatus = "skipped"
+	// FAILED recipients could not be delivered to.
+	FAILED RecipientStatus = "failed"
+)
+
+// Errors returned by Campaign.Validate.
+var (
+	// ErrMissingContent is returned for campaigns with neither a message nor
+	// an entry route.
+	ErrMissingContent = errors.New("campaign message or entry route is required")
+	// ErrAmbiguousContent is returned for campaigns with both a message and
+	// an entry route.
+	ErrAmbiguousContent = errors.New("campaign must have either a message or an entry route")
+	// ErrNoRecipients is returned for campaigns without recipients.
+	ErrNoRecipients = errors.New("campaign has no recipients")
+)
+
+// Reasons recorded in Recipient.Error for skipped recipients.
+const (
+	// REASON_OPTED_OUT is the reason of recipients that opted out.
+	REASON_OPTED_OUT = "opted out"
+	// REASON_DUPLICATE is the reason of recipients listed more than once.
+	REASON_DUPLICATE = "duplicate recipient"
+)
+
+// Campaign is a broadcast to a list of recipients.
+type Campaign struct {
+	// ID identifies the campaign. It scopes the idempotency keys of the
+	// actions performed for each recipient.
+	ID string
+	// Name describes the campaign.
+	Name string
+	// Message is sent to every recipient, after replacing {{key}}
+	// placeholders with the recipient's variables.
+	Message d_message.Message
+	// EntryRoute is run for every recipient instead of sending Message, as
+	// a backend event whose payload holds the recipient's variables.
+	EntryRoute string
+	// ReplyRoute is set as the route of recipients that got Message, so
+	// their replies land in the campaign flow.
+	ReplyRoute string
+	// Platform is the messaging platform the message is sent through.
+	Platform string
+	// MaxAttempts is the number of delivery attempts per recipient.
+	MaxAttempts int
+	// Status is the state of the campaign.
+	Status Status
+	// CreatedAt is when the campaign was created.
+	CreatedAt time.Time
+	// UpdatedAt is when the campaign last changed.
+	UpdatedAt time.Time
+}
+
+// Validate reports whether the campaign can be sent.
+func (c Campaign) Validate() error {
+	hasMessage := c.HasMessage()
+	if !hasMessage && c.EntryRoute == "" {
+		return ErrMissingContent
+	}
+	if hasMessage && c.EntryRoute != "" {
+		return ErrAmbiguousContent
+	}
+	return nil
+}
+
+// HasMessage reports whether the campaign sends a message.
+func (c Campaign) HasMessage() bool {
+	return c.Message.EntireText() != "" || c.Message.HasFile()
+}
+
+// Render returns the campaign message for a recipient, with the {{key}}
+// placeholders of its texts replaced by the recipient's variables.
+func (c Campaign) Render(recipient Recipient) d_message.Message {
+	message := c.Message
+	if len(recipient.Variables) == 0 {
+		return message
+	}
+
+	pairs := make([]string, 0, 2*len(recipient.Variables))
+	for key, value := range recipient.Variables {
+		pairs = append(pairs, "{{"+key+"}}", value)
+	}
+	replacer := strings.NewReplacer(pairs...)
+
+	message.TextMessage.Title = replacer.Replace(message.TextMessage.Title)
+	message.TextMessage.Detail = replacer.Replace(message.TextMessage.Detail)
+	message.TextMessage.Caption = replacer.Replace(message.TextMessage.Caption)
+	return message
+}
+
+// Recipient is a chat a campaign is delivered to.
+type Recipient struct {
+	// Index is the position of the recipient in the campaign's list.
+	Index int
+	// ChatID is the recipient's chat.
+	ChatID d_user.ChatID
+	// Variables fill in the placeholders of the campaign message.
+	Variables map[string]string
+	// Status is the delivery state of the recipient.
+	Status RecipientStatus
+	// Attempts is the number of delivery attempts made.
+	Attempts int
+	// Error is the reason the recipient was skipped or the last delivery error.
+	Error string
+	// UpdatedAt is when the recipient last changed.
+	UpdatedAt time.Time
+}
+
+// Finished reports whether the recipient needs no more delivery attempts.
+func (r Recipient) Finished() bool {
+	return r.Status != PENDING
+}
+
+// Report is the delivery report of a campaign.
+type Report struct {
+	// Campaign is the reported campaign.
+	Campaign Campaign
+	// Total is the number of recipients.
+	Total int
+	// Pending, Sent, Skipped and Failed count the recipients per status.
+	Pending, Sent, Skipped, Failed int
+	// Recipients holds every recipient, in list order.
+	Recipients []Recipient
+}
+
+// NewReport builds the report of a campaign from its recipients.
+func NewReport(campaign Campaign, recipients []Recipient) Report {
+	report := Report{Campaign: campaign, Total: len(recipients), Recipients: recipients}
+	for _, recipient := range recipients {
+		switch recipient.Status {
+		case PENDING:
+			report.Pending++
+		case SENT:
+			report.Sent++
+		case SKIPPED:
+			report.Skipped++
+		case FAILED:
+			report.Failed++
+		}
+	}
+	return report
+}
@@ -0,0 +1,60 @@
+package d_campaign
+
+import (
+	"errors"
+	"testing"
+
+	d_message "github.com/irissonnlima/chatgraph-go/core/domain/message"
+)
+
+func TestCampaign_Validate(t *testing.T) {
+	message := d_message.Message{TextMessage: d_message.TextMessage{Detail: "Hi"}}
+
+	tests := []struct {
+		name     string
+		campaign Campaign
+		expected error
+	}{
+		{name: "message", campaign: Campaign{Message: message}},
+		{name: "entry route", campaign: Campaign{EntryRoute: "promo"}},
+		{name: "nothing", campaign: Campaign{}, expected: ErrMissingContent},
+		{name: "both", campaign: Campaign{Message: message, EntryRoute: "promo"}, expected: ErrAmbiguousContent},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.campaign.Validate(); !errors.Is(err, tt.expected) {
+				t.Errorf("Validate() = %v, want %v", err, tt.expected)
+			}
+		})
+	}
+}
+
+func TestCampaign_Render(t *testing.T) {
+	campaign := Campaign{Message: d_message.Message{TextMessage: d_message.TextMessage{
+		Title:  "Hello {{name}}",
+		Detail: "{{name}}, your code is {{code}}. {{unknown}} stays.",
+	}}}
+
+	message := campaign.Render(Recipient{Variables: map[string]string{"name": "Ana", "code": "X1"}})
+
+	if message.TextMessage.Title != "Hello Ana" {
+		t.Errorf("unexpected title %q", message.TextMessage.Title)
+	}
+	if message.TextMessage.Detail != "Ana, your code is X1. {{unknown}} stays." {
+		t.Errorf("unexpected detail %q", message.TextMessage.Detail)
+	}
+	if campaign.Message.TextMessage.Title != "Hello {{name}}" {
+		t.Error("expected the campaign message to be left untouched")
+	}
+}
+
+func TestNewReport(t *testing.T) {
+	report := NewReport(Campaign{ID: "c1"}, []Recipient{
+		{Status: SENT}, {Status: SENT}, {Status: SKIPPED}, {Status: FAILED}, {Status: PENDING},
+	})
+
+	if report.Total != 5 || report.Sent != 2 || report.Skipped != 1 || report.Failed != 1 || report.Pending != 1 {
+		t.Errorf("unexpected report %+v", report)
+	}
+}
@@ -0,0 +1,71 @@
+package d_context
+
+import (
+	"errors"
+
+	d_message "github.com/irissonnlima/chatgraph-go/core/domain/message"
+	adapter_output "github.com/irissonnlima/chatgraph-go/core/ports/adapters/output"
+)
+
+// The methods below use optional executor capabilities and degrade gracefully
+// when the executor does not provide them.
+
+// SendTyping shows a typing indicator to the user.
+// Does nothing if the executor does not support typing indicators.
+func (c *ChatContext[Obs]) SendTyping() error {
+	if c.Context.Err() != nil {
+		return c.Context.Err()
+	}
+
+	notifier, ok := c.router.(adapter_output.ITypingNotifier)
+	if !ok {
+		return nil
+	}
+	return ignoreUnsupported(awaitErr(c, func() error {
+		return notifier.SendTyping(c.UserState.ChatID, c.UserState.Platform)
+	}))
+}
+
+// EditMessage replaces the content of a message sent earlier.
+// Sends the message as a new one if the executor does not support edits.
+func (c *ChatContext[Obs]) EditMessage(messageID string, message d_message.Message) error {
+	if c.Context.Err() != nil {
+		return c.Context.Err()
+	}
+
+	return awaitErr(c, func() error {
+		editor, ok := c.router.(adapter_output.IMessageEditor)
+		if ok {
+			err := editor.EditMessage(c.UserState.ChatID, messageID, message, c.UserState.Platform)
+			if !errors.Is(err, adapter_output.ErrUnsupported) {
+				return err
+			}
+		}
+		return c.router.SendMessage(c.UserState.ChatID, message, c.UserState.Platform)
+	})
+}
+
+// React adds a reaction to the incoming message; an empty reaction removes it.
+// Does nothing if the executor does not support reactions or the incoming
+// message has no ID.
+func (c *ChatContext[Obs]) React(reaction string) error {
+	if c.Context.Err() != nil {
+		return c.Context.Err()
+	}
+
+	reactor, ok := c.router.(adapter_output.IReactor)
+	if !ok || c.Message.TextMessage.ID == "" {
+		return nil
+	}
+	return ignoreUnsupported(awaitErr(c, func() error {
+		return reactor.React(c.UserState.ChatID, c.Message.TextMessage.ID, reaction, c.UserState.Platform)
+	}))
+}
+
+// ignoreUnsupported treats ErrUnsupported as success.
+func ignoreUnsupported(err error) error {
+	if errors.Is(err, adapter_output.ErrUnsupported) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,123 @@
+package d_context
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	d_message "github.com/irissonnlima/chatgraph-go/core/domain/message"
+	d_user "github.com/irissonnlima/chatgraph-go/core/domain/user"
+	adapter_output "github.com/irissonnlima/chatgraph-go/core/ports/adapters/output"
+)
+
+// CapableRouter adds the optional capabilities to MockRouter.
+type CapableRouter struct {
+	MockRouter
+	err      error
+	typing   int
+	edited   string
+	reaction string
+}
+
+func (r *CapableRouter) SendTyping(chatID d_user.ChatID, platform string) error {
+	r.typing++
+	return r.err
+}
+
+func (r *CapableRouter) EditMessage(chatID d_user.ChatID, messageID string, message d_message.Message, platform string) error {
+	r.edited = messageID
+	return r.err
+}
+
+func (r *CapableRouter) React(chatID d_user.ChatID, messageID string, reaction string, platform string) error {
+	r.reaction = messageID + ":" + reaction
+	return r.err
+}
+
+func newCapabilityContext(router adapter_output.IBotExecutor) (ChatContext[TestObservation], func()) {
+	userState := d_user.UserState[TestObservation]{ChatID: d_user.ChatID{UserID: "user1", CompanyID: "comp1"}}
+	message := d_message.Message{TextMessage: d_message.TextMessage{ID: "in-1"}}
+	ctx, cancel := NewChatContext(userState, message, router, 5*time.Second)
+	return ctx, cancel
+}
+
+func TestChatContext_Capabilities(t *testing.T) {
+	router := &CapableRouter{}
+	ctx, cancel := newCapabilityContext(router)
+	defer cancel()
+
+	if err := ctx.SendTyping(); err != nil || router.typing != 1 {
+		t.Errorf("SendTyping() error = %v, calls = %d", err, router.typing)
+	}
+	if err := ctx.EditMessage("out-1", d_message.Message{}); err != nil || router.edited != "out-1" {
+		t.Errorf("EditMessage() error = %v, edited = %q", err, router.edited)
+	}
+	if err := ctx.React("👍"); err != nil || router.reaction != "in-1:👍" {
+		t.Errorf("React() error = %v, reaction = %q", err, router.reaction)
+	}
+}
+
+func TestChatContext_CapabilitiesAbsent(t *testing.T) {
+	sent := 0
+	router := &MockRouter{
+		SendMessageFunc: func(chatID d_user.ChatID, message d_message.Message, platform string) error {
+			sent++
+			return nil
+		},
+	}
+	ctx, cancel := newCapabilityContext(router)
+	defer cancel()
+
+	if err := ctx.SendTyping(); err != nil {
+		t.Errorf("SendTyping() error = %v", err)
+	}
+	if err := ctx.React("👍"); err != nil {
+		t.Errorf("React() error = %v", err)
+	}
+	if err := ctx.EditMessage("out-1", d_message.Message{}); err != nil {
+		t.Errorf("EditMessage() error = %v", err)
+	}
+	if sent != 1 {
+		t.Errorf("expected EditMessage to fall back to SendMessage, got %d sends", sent)
+	}
+}
+
+func TestChatContext_CapabilitiesUnsupported(t *testing.T) {
+	sent := 0
+	router := &CapableRouter{err: adapter_output.ErrUnsupported}
+	router.SendMessageFunc = func(chatID d_user.ChatID, message d_message.Message, platform string) error {
+		sent++
+		return nil
+	}
+	ctx, cancel := newCapabilityContext(router)
+	defer cancel()
+
+	if err := ctx.SendTyping(); err != nil {
+		t.Errorf("SendTyping() error = %v", err)
+	}
+	if err := ctx.React("👍"); err != nil {
+		t.Errorf("React() error = %v", err)
+	}
+	if err := ctx.EditMessage("out-1", d_message.Message{}); err != nil || sent != 1 {
+		t.Errorf("EditMessage() error = %v, sends = %d", err, sent)
+	}
+}
+
+func TestChatContext_CapabilityErrors(t *testing.T) {
+	failure := errors.New("failure")
+	router := &CapableRouter{err: failure}
+	ctx, cancel := newCapabilityContext(router)
+	defer cancel()
+
+	if err := ctx.SendTyping(); !errors.Is(err, failure) {
+		t.Errorf("expected SendTyping error, got %v", err)
+	}
+	if err := ctx.EditMessage("out-1", d_message.Message{}); !errors.Is(err, failure) {
+		t.Errorf("expected EditMessage error, got %v", err)
+	}
+
+	cancel()
+	if err := ctx.React("👍"); err == nil {
+		t.Error("expected React to fail on a cancelled context")
+	}
+}
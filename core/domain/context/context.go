@@ -24,6 +24,9 @@ type ChatContext[Obs any] struct {
 	Message d_message.Message
 	// router provides messaging and session management capabilities.
 	router adapter_output.IBotExecutor
+	// bound is set when router is bound to the context and stops its calls
+	// once the context is done.
+	bound bool
 }
 
 // NewChatContext creates a new ChatContext with the provided parameters.
@@ -36,15 +39,77 @@ func NewChatContext[Obs any](
 
 	timeout time.Duration,
 ) (ChatContext[Obs], context.CancelFunc) {
+	return NewChatContextWithParent(context.Background(), userState, message, router, timeout)
+}
+
+// NewChatContextWithParent is like NewChatContext, but derives the handler's
+// context from parent, so it carries parent's values (e.g. the idempotency
+// scope of the message).
+func NewChatContextWithParent[Obs any](
+	parent context.Context,
+	userState d_user.UserState[Obs],
+	message d_message.Message,
+
+	router adapter_output.IBotExecutor,
 
-	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	timeout time.Duration,
+) (ChatContext[Obs], context.CancelFunc) {
+
+	ctx, cancel := context.WithTimeout(parent, timeout)
+
+	// Bind the executor to the handler's context when it supports it, so its
+	// calls stop once the handler times out.
+	binder, bound := router.(adapter_output.IContextBinder)
+	if bound {
+		router = binder.WithContext(ctx)
+	}
 
 	ctxChatbot := ChatContext[Obs]{
 		Context:   ctx,
 		UserState: userState,
 		Message:   message,
 		router:    router,
+		bound:     bound,
 	}
 
 	return ctxChatbot, cancel
 }
+
+// await runs an executor call and returns its result, or the context's error
+// as soon as the context is done. Calls of executors that are not bound to
+// the context cannot be stopped: they finish in the background, and their
+// result is dropped.
+func await[Obs any, T any](c *ChatContext[Obs], call func() (T, error)) (T, error) {
+	var zero T
+	if err := c.Context.Err(); err != nil {
+		return zero, err
+	}
+	if c.bound {
+		return call()
+	}
+
+	type result struct {
+		value T
+		err   error
+	}
+	done := make(chan result, 1)
+	go func() {
+		value, err := call()
+		done <- result{value, err}
+	}()
+
+	select {
+	case r := <-done:
+		return r.value, r.err
+	case <-c.Context.Done():
+		return zero, c.Context.Err()
+	}
+}
+
+// awaitErr is await for calls that only return an error.
+func awaitErr[Obs any](c *ChatContext[Obs], call func() error) error {
+	_, err := await(c, func() (struct{}, error) {
+		return struct{}{}, call()
+	})
+	return err
+}
@@ -1,7 +1,9 @@
 package d_context
 
 import (
+	"context"
 	"errors"
+	"os"
 	"testing"
 	"time"
 
@@ -215,6 +217,9 @@ func TestChatContext_SetObservation(t *testing.T) {
 	if savedObs != `{"value":"new_value"}` {
 		t.Errorf("SetObservation() saved = %v, want %v", savedObs, `{"value":"new_value"}`)
 	}
+	if got := ctx.GetObservation(); got.Value != "new_value" {
+		t.Errorf("GetObservation() after SetObservation() = %v, want new_value", got.Value)
+	}
 }
 
 func TestChatContext_SetObservation_ContextCanceled(t *testing.T) {
@@ -401,6 +406,50 @@ func TestChatContext_LoadFileBytes_UploadError(t *testing.T) {
 	}
 }
 
+// TestChatContext_LoadFileBytes_AbandonedUpload tests that an upload still
+// running when the handler times out can read the temp file, which is
+// removed once the upload finishes.
+func TestChatContext_LoadFileBytes_AbandonedUpload(t *testing.T) {
+	release := make(chan struct{})
+	type upload struct {
+		path string
+		data []byte
+		err  error
+	}
+	uploaded := make(chan upload, 1)
+	router := &MockRouter{
+		UploadFileFunc: func(filepath string) (*d_file.File, error) {
+			<-release
+			data, err := os.ReadFile(filepath)
+			uploaded <- upload{path: filepath, data: data, err: err}
+			return &d_file.File{ID: "f1"}, nil
+		},
+	}
+
+	ctx, cancel := NewChatContext(d_user.UserState[TestObservation]{}, d_message.Message{}, router, 10*time.Millisecond)
+	defer cancel()
+
+	if _, err := ctx.LoadFileBytes("report.pdf", []byte("content")); err == nil {
+		t.Fatal("LoadFileBytes() should return error when the handler times out")
+	}
+	close(release)
+
+	got := <-uploaded
+	if got.err != nil || string(got.data) != "content" {
+		t.Fatalf("expected the upload to read the temp file, got %q, %v", got.data, got.err)
+	}
+	deadline := time.Now().Add(time.Second)
+	for {
+		if _, err := os.Stat(got.path); os.IsNotExist(err) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("expected the temp file to be removed after the upload")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
 func TestChatContext_GetFile(t *testing.T) {
 	expectedFile := &d_file.File{
 		ID:   "f1",
@@ -445,3 +494,43 @@ func TestChatContext_GetFile_ContextCanceled(t *testing.T) {
 		t.Error("GetFile() should return error when context is canceled")
 	}
 }
+
+func TestChatContext_SendMessage_Deadline(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+
+	router := &MockRouter{
+		SendMessageFunc: func(chatID d_user.ChatID, message d_message.Message, platform string) error {
+			<-release
+			return nil
+		},
+	}
+
+	ctx, cancel := NewChatContext(d_user.UserState[TestObservation]{}, d_message.Message{}, router, 20*time.Millisecond)
+	defer cancel()
+
+	err := ctx.SendTextMessage("Test")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("SendMessage() error = %v, want context.DeadlineExceeded", err)
+	}
+}
+
+func TestChatContext_GetFile_Deadline(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+
+	router := &MockRouter{
+		GetFileFunc: func(fileID string) (*d_file.File, error) {
+			<-release
+			return &d_file.File{ID: fileID}, nil
+		},
+	}
+
+	ctx, cancel := NewChatContext(d_user.UserState[TestObservation]{}, d_message.Message{}, router, 20*time.Millisecond)
+	defer cancel()
+
+	file, err := ctx.GetFile("file123")
+	if !errors.Is(err, context.DeadlineExceeded) || file != nil {
+		t.Errorf("GetFile() = %v, %v, want nil, context.DeadlineExceeded", file, err)
+	}
+}
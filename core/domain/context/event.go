@@ -0,0 +1,10 @@
+package d_context
+
+import d_event "github.com/irissonnlima/chatgraph-go/core/domain/event"
+
+// Event returns the backend event that started or resumed the chat, with
+// its payload (see d_event.Event.DecodePayload). ok is false when the route
+// is handling a message.
+func (c *ChatContext[Obs]) Event() (event d_event.Event, ok bool) {
+	return d_event.FromContext(c.Context)
+}
@@ -4,6 +4,7 @@ import (
 	d_file "github.com/irissonnlima/chatgraph-go/core/domain/file"
 	"os"
 	"path/filepath"
+	"sync/atomic"
 )
 
 func (c *ChatContext[Obs]) LoadFile(filePath string) (*d_file.File, error) {
@@ -11,7 +12,9 @@ func (c *ChatContext[Obs]) LoadFile(filePath string) (*d_file.File, error) {
 		return nil, c.Context.Err()
 	}
 
-	return c.router.UploadFile(filePath)
+	return await(c, func() (*d_file.File, error) {
+		return c.router.UploadFile(filePath)
+	})
 }
 
 func (c *ChatContext[Obs]) LoadFileBytes(fileName string, data []byte) (*d_file.File, error) {
@@ -28,17 +31,29 @@ func (c *ChatContext[Obs]) LoadFileBytes(fileName string, data []byte) (*d_file.
 	if err != nil {
 		return nil, err
 	}
-	defer os.Remove(tempFile.Name()) // Clean up the temp file afterwards
 
 	// Write the data to the temporary file
 	if _, err := tempFile.Write(data); err != nil {
 		tempFile.Close()
+		os.Remove(tempFile.Name())
 		return nil, err
 	}
 	tempFile.Close()
 
-	// Use the UploadFile method to upload the temporary file
-	uploadedFile, err := c.router.UploadFile(tempFile.Name())
+	// Use the UploadFile method to upload the temporary file. The upload may
+	// outlive the handler's context, so it removes the temp file when it
+	// finishes; the file is removed here only if the upload never started.
+	var claimed atomic.Bool
+	uploadedFile, err := await(c, func() (*d_file.File, error) {
+		if !claimed.CompareAndSwap(false, true) {
+			return nil, c.Context.Err()
+		}
+		defer os.Remove(tempFile.Name())
+		return c.router.UploadFile(tempFile.Name())
+	})
+	if claimed.CompareAndSwap(false, true) {
+		os.Remove(tempFile.Name())
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -51,5 +66,13 @@ func (c *ChatContext[Obs]) GetFile(fileID string) (*d_file.File, error) {
 		return nil, c.Context.Err()
 	}
 
-	return c.router.GetFile(fileID)
+	return await(c, func() (*d_file.File, error) {
+		return c.router.GetFile(fileID)
+	})
+}
+
+// DownloadFile downloads the contents of a file, e.g. the one attached to the
+// incoming message, stopping when the handler's context is done.
+func (c *ChatContext[Obs]) DownloadFile(file d_file.File) ([]byte, error) {
+	return file.BytesContext(c.Context)
 }
@@ -0,0 +1,10 @@
+package d_context
+
+import d_job "github.com/irissonnlima/chatgraph-go/core/domain/job"
+
+// Job returns the background job whose completion resumed the chat, with
+// its result (see d_job.Job.DecodeResult). ok is false when the route was
+// not resumed by a job.
+func (c *ChatContext[Obs]) Job() (job d_job.Job, ok bool) {
+	return d_job.FromContext(c.Context)
+}
@@ -17,5 +17,12 @@ func (c *ChatContext[Obs]) SetObservation(observation Obs) error {
 	if err != nil {
 		return err
 	}
-	return c.router.SetObservation(c.UserState.ChatID, string(obsString))
+	err = awaitErr(c, func() error {
+		return c.router.SetObservation(c.UserState.ChatID, string(obsString))
+	})
+	if err != nil {
+		return err
+	}
+	c.UserState.Observation = observation
+	return nil
 }
@@ -0,0 +1,67 @@
+// Package d_event provides the backend events that start or resume a
+// conversation proactively (e.g. an appointment reminder, an order shipped),
+// running a route for a chat without an inbound message.
+package d_event
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	d_user "github.com/irissonnlima/chatgraph-go/core/domain/user"
+)
+
+// Errors returned by Event.Validate.
+var (
+	// ErrMissingChatID is returned for events without a chat.
+	ErrMissingChatID = errors.New("event chat ID is required")
+	// ErrMissingRoute is returned for events without a route.
+	ErrMissingRoute = errors.New("event route is required")
+)
+
+// Event is a backend event addressed to a chat.
+type Event struct {
+	// ID identifies the event. It scopes the idempotency keys of the route's
+	// actions, so handling the same event again does not repeat them.
+	ID string
+	// Name is the kind of event, e.g. "order_shipped".
+	Name string
+	// ChatID is the chat the route runs for.
+	ChatID d_user.ChatID
+	// Route is the route executed for the chat.
+	Route string
+	// Platform is the messaging platform of chats without stored state. It
+	// overrides the stored platform when set.
+	Platform string
+	// Payload is the JSON data of the event.
+	Payload string
+}
+
+// Validate reports whether the event can be handled.
+func (e Event) Validate() error {
+	if e.ChatID.IsEmpty() {
+		return ErrMissingChatID
+	}
+	if e.Route == "" {
+		return ErrMissingRoute
+	}
+	return nil
+}
+
+// DecodePayload decodes the payload of the event into target.
+func (e Event) DecodePayload(target any) error {
+	return json.Unmarshal([]byte(e.Payload), target)
+}
+
+type eventKey struct{}
+
+// WithEvent returns a context carrying event, e.g. to the route it runs.
+func WithEvent(ctx context.Context, event Event) context.Context {
+	return context.WithValue(ctx, eventKey{}, event)
+}
+
+// FromContext returns the event carried by ctx.
+func FromContext(ctx context.Context) (Event, bool) {
+	event, ok := ctx.Value(eventKey{}).(Event)
+	return event, ok
+}
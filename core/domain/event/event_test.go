@@ -0,0 +1,55 @@
+package d_event
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	d_user "github.com/irissonnlima/chatgraph-go/core/domain/user"
+)
+
+func TestEvent_Validate(t *testing.T) {
+	chatID := d_user.ChatID{UserID: "u1", CompanyID: "c1"}
+
+	tests := []struct {
+		name  string
+		event Event
+		want  error
+	}{
+		{"valid", Event{ChatID: chatID, Route: "reminder"}, nil},
+		{"missing chat", Event{ChatID: d_user.ChatID{UserID: "u1"}, Route: "reminder"}, ErrMissingChatID},
+		{"missing route", Event{ChatID: chatID}, ErrMissingRoute},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.event.Validate(); !errors.Is(err, tt.want) {
+				t.Errorf("Validate() = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestEvent_DecodePayload(t *testing.T) {
+	var payload struct {
+		Order int `json:"order"`
+	}
+
+	if err := (Event{Payload: `{"order":42}`}).DecodePayload(&payload); err != nil {
+		t.Fatalf("DecodePayload() error = %v", err)
+	}
+	if payload.Order != 42 {
+		t.Errorf("unexpected payload %+v", payload)
+	}
+}
+
+func TestWithEvent(t *testing.T) {
+	if _, ok := FromContext(context.Background()); ok {
+		t.Error("expected no event in an empty context")
+	}
+
+	ctx := WithEvent(context.Background(), Event{ID: "e1"})
+	if event, ok := FromContext(ctx); !ok || event.ID != "e1" {
+		t.Errorf("FromContext() = %+v, %v", event, ok)
+	}
+}
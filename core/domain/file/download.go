@@ -1,6 +1,7 @@
 package d_file
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"net/http"
@@ -9,11 +10,21 @@ import (
 // Bytes downloads the file from the URL and returns its contents as a byte slice.
 // Returns nil and an error if the download fails or the URL is empty.
 func (f File) Bytes() ([]byte, error) {
+	return f.BytesContext(context.Background())
+}
+
+// BytesContext is like Bytes, but stops the download when ctx is done.
+func (f File) BytesContext(ctx context.Context) ([]byte, error) {
 	if f.URL == "" {
 		return nil, fmt.Errorf("file URL is empty")
 	}
 
-	resp, err := http.Get(f.URL)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, f.URL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download file: %w", err)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to download file: %w", err)
 	}
@@ -77,3 +77,18 @@ func (f File) IsEmpty() bool {
 func (f File) Extension() string {
 	return strings.ToLower(filepath.Ext(f.Name))
 }
+
+// TypeFromName guesses the FileType from the extension of a file name.
+// Unknown extensions are sent as FILE_SEND_TYPE.
+func TypeFromName(name string) FileType {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".png", ".jpg", ".jpeg", ".gif", ".webp":
+		return IMAGE_SEND_TYPE
+	case ".mp4", ".avi", ".mov", ".mkv":
+		return VIDEO_SEND_TYPE
+	case ".mp3", ".wav", ".ogg", ".m4a":
+		return AUDIO_SEND_TYPE
+	default:
+		return FILE_SEND_TYPE
+	}
+}
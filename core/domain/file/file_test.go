@@ -1,7 +1,12 @@
 package d_file
 
 import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
 	"testing"
+	"time"
 )
 
 func TestFileType_String(t *testing.T) {
@@ -322,3 +327,46 @@ func TestFile_Bytes_InvalidURL(t *testing.T) {
 		t.Error("File.Bytes() should return error for invalid URL")
 	}
 }
+
+func TestFile_BytesContext_Deadline(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := File{URL: server.URL}.BytesContext(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("File.BytesContext() error = %v, want context.DeadlineExceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("File.BytesContext() took %v after the deadline", elapsed)
+	}
+}
+
+func TestTypeFromName(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected FileType
+	}{
+		{"photo.JPG", IMAGE_SEND_TYPE},
+		{"clip.mp4", VIDEO_SEND_TYPE},
+		{"voice.ogg", AUDIO_SEND_TYPE},
+		{"report.pdf", FILE_SEND_TYPE},
+		{"noextension", FILE_SEND_TYPE},
+	}
+
+	for _, tt := range tests {
+		if got := TypeFromName(tt.name); got != tt.expected {
+			t.Errorf("TypeFromName(%q) = %v, want %v", tt.name, got, tt.expected)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+// Package d_handoff provides the tickets of conversations handed off to
+// human agents. While a chat has an open ticket the bot is paused for it:
+// messages are relayed between the user and the agent until the agent
+// closes the ticket and the bot resumes.
+package d_handoff
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	d_department "github.com/irissonnlima/chatgraph-go/core/domain/department"
+	d_route "github.com/irissonnlima/chatgraph-go/core/domain/route"
+	d_session "github.com/irissonnlima/chatgraph-go/core/domain/session"
+	d_user "github.com/irissonnlima/chatgraph-go/core/domain/user"
+)
+
+// Status is the state of a ticket.
+type Status string
+
+// Statuses of a ticket.
+const (
+	// QUEUED tickets wait for an agent.
+	QUEUED Status = "QUEUED"
+	// ASSIGNED tickets are being handled by an agent.
+	ASSIGNED Status = "ASSIGNED"
+	// CLOSED tickets were closed by their agent and the bot resumed.
+	CLOSED Status = "CLOSED"
+)
+
+// Ticket is a conversation handed off to a department or an agent. A chat
+// has at most one open ticket.
+type Ticket struct {
+	// ID identifies the ticket.
+	ID string
+	// Session is a snapshot of the chat when it was handed off, used for the
+	// summary and to resume the chat when the executor keeps no state.
+	Session d_session.Session
+	// Department is the department the chat was handed off to.
+	Department d_department.Department
+	// Agent is the agent handling the ticket, or the agent whose queue it
+	// waits in. Empty while it waits in the department's queue.
+	Agent string
+	// Status is the state of the ticket.
+	Status Status
+	// Reason is why the chat was handed off, as given by the route.
+	Reason string
+	// ResumeRoute is where the bot resumes when the ticket is closed, unless
+	// the agent chooses another route.
+	ResumeRoute string
+	// LastMessage is the text of the message that led to the handoff.
+	LastMessage string
+	// CreatedAt is when the chat was handed off.
+	CreatedAt time.Time
+	// AssignedAt is when an agent took the ticket. Zero while queued.
+	AssignedAt time.Time
+	// ClosedAt is when the ticket was closed. Zero while open.
+	ClosedAt time.Time
+}
+
+// ChatID returns the chat of the ticket.
+func (t Ticket) ChatID() d_user.ChatID {
+	return t.Session.ChatID
+}
+
+// Queue returns the queue the ticket waits in: the agent's queue when the
+// chat was handed off to an agent, or else the department's.
+func (t Ticket) Queue() string {
+	return QueueOf(t.Department.ID, t.Agent)
+}
+
+// QueueOf returns the queue of the tickets handed off to a department, or
+// to one of its agents.
+func QueueOf(departmentID, agent string) string {
+	if agent != "" {
+		return "agent:" + agent
+	}
+	return "department:" + departmentID
+}
+
+// Summary describes the conversation so far for the agent taking the
+// ticket: who the user is, why and from where the chat was handed off, the
+// routes the user went through and the session's observation.
+func (t Ticket) Summary() string {
+	var b strings.Builder
+	line := func(label, value string) {
+		if value != "" {
+			fmt.Fprintf(&b, "%s: %s\n", label, value)
+		}
+	}
+
+	s := t.Session
+	line("Ticket", t.ID)
+	line("Department", t.Department.Name)
+	line("Customer", customer(s.User, s.ChatID))
+	line("Platform", s.Platform)
+	line("Reason", t.Reason)
+	if s.Route != "" {
+		history := d_route.NewRoute(s.Route, d_session.ROUTE_SEPARATOR).HistoryDedup()
+		line("Route history", strings.Join(history, " > "))
+	}
+	if t.LastMessage != "" {
+		line("Last message", fmt.Sprintf("%q", t.LastMessage))
+	}
+	if s.Observation != "" && s.Observation != "null" && s.Observation != "{}" {
+		line("Observation", s.Observation)
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// customer describes the user of a chat.
+func customer(user d_user.User, chatID d_user.ChatID) string {
+	contact := user.Phone
+	if contact == "" {
+		contact = user.Email
+	}
+	switch {
+	case user.Name != "" && contact != "":
+		return fmt.Sprintf("%s (%s)", user.Name, contact)
+	case user.Name != "":
+		return user.Name
+	case contact != "":
+		return contact
+	}
+	return chatID.UserID
+}
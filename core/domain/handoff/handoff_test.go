@@ -0,0 +1,59 @@
+package d_handoff
+
+import (
+	"testing"
+
+	d_department "github.com/irissonnlima/chatgraph-go/core/domain/department"
+	d_session "github.com/irissonnlima/chatgraph-go/core/domain/session"
+	d_user "github.com/irissonnlima/chatgraph-go/core/domain/user"
+)
+
+func TestTicket_Queue(t *testing.T) {
+	ticket := Ticket{Department: d_department.Department{ID: "billing"}}
+	if got := ticket.Queue(); got != "department:billing" {
+		t.Errorf("Queue() = %q, want department:billing", got)
+	}
+
+	ticket.Agent = "maria"
+	if got := ticket.Queue(); got != "agent:maria" {
+		t.Errorf("Queue() = %q, want agent:maria", got)
+	}
+}
+
+func TestTicket_Summary(t *testing.T) {
+	ticket := Ticket{
+		ID:          "t1",
+		Department:  d_department.Department{ID: "billing", Name: "Billing"},
+		Reason:      "second copy of invoice",
+		LastMessage: "I want to talk to someone",
+		Session: d_session.Session{
+			ChatID:      d_user.ChatID{UserID: "u1", CompanyID: "c1"},
+			User:        d_user.User{Name: "Ana", Phone: "5511999999999"},
+			Route:       "start.menu.menu.invoice",
+			Observation: `{"invoice":"123"}`,
+			Platform:    "whatsapp",
+		},
+	}
+
+	want := "Ticket: t1\n" +
+		"Department: Billing\n" +
+		"Customer: Ana (5511999999999)\n" +
+		"Platform: whatsapp\n" +
+		"Reason: second copy of invoice\n" +
+		"Route history: start > menu > invoice\n" +
+		"Last message: \"I want to talk to someone\"\n" +
+		"Observation: {\"invoice\":\"123\"}"
+	if got := ticket.Summary(); got != want {
+		t.Errorf("Summary() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestTicket_SummaryWithoutUser(t *testing.T) {
+	ticket := Ticket{Session: d_session.Session{
+		ChatID:      d_user.ChatID{UserID: "u1", CompanyID: "c1"},
+		Observation: "{}",
+	}}
+	if got := ticket.Summary(); got != "Customer: u1" {
+		t.Errorf("Summary() = %q, want the user ID only", got)
+	}
+}
@@ -0,0 +1,109 @@
+// Package d_idempotency derives deterministic idempotency keys for the
+// outbound actions of a handler, so a retried or redelivered message produces
+// the same keys and adapters can recognize actions already performed.
+//
+// A Scope is created for each inbound message being processed, and carried by
+// the handler's context, redirects included. Each outbound action takes the
+// next key of the scope: the n-th action performed while handling a message
+// always gets the same key. The route the message starts on is part of the
+// keys: a redelivered message that finds the chat on the route an earlier
+// attempt set runs other actions, which must not be taken for the earlier ones.
+package d_idempotency
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	d_user "github.com/irissonnlima/chatgraph-go/core/domain/user"
+)
+
+// Scope numbers the actions performed while handling an inbound message.
+// It is safe for concurrent use.
+type Scope struct {
+	// ChatID is the chat the message came from.
+	ChatID d_user.ChatID
+	// MessageID is the ID of the inbound message.
+	MessageID string
+	// Route is the route the message starts on; redirects keep it.
+	Route string
+
+	sequence atomic.Int64
+}
+
+// NewScope creates a scope for an inbound message handled on route, which
+// may be empty for actions that run no route.
+// Returns nil when the message has no ID, since its keys could not be
+// reproduced on redelivery.
+func NewScope(chatID d_user.ChatID, messageID string, route string) *Scope {
+	if messageID == "" {
+		return nil
+	}
+	return &Scope{ChatID: chatID, MessageID: messageID, Route: route}
+}
+
+// Next returns the key of the next action.
+func (s *Scope) Next() string {
+	return Key(s.ChatID, s.MessageID, s.Route, s.sequence.Add(1))
+}
+
+// Key returns the idempotency key of the action with the given sequence
+// number (starting at 1): the hex-encoded SHA-256 of its components.
+func Key(chatID d_user.ChatID, messageID string, route string, sequence int64) string {
+	hash := sha256.Sum256([]byte(strings.Join([]string{
+		chatID.CompanyID,
+		chatID.UserID,
+		messageID,
+		route,
+		strconv.FormatInt(sequence, 10),
+	}, "\x00")))
+	return hex.EncodeToString(hash[:])
+}
+
+type (
+	scopeKey  struct{}
+	actionKey struct{}
+)
+
+// WithScope returns a context carrying scope. A nil scope leaves ctx unchanged.
+func WithScope(ctx context.Context, scope *Scope) context.Context {
+	if scope == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, scopeKey{}, scope)
+}
+
+// WithoutScope returns a context that hides the scope of ctx, for actions
+// whose number of occurrences is not deterministic (e.g. progress messages
+// sent on a timer), so they do not shift the keys of the others.
+func WithoutScope(ctx context.Context) context.Context {
+	return context.WithValue(ctx, scopeKey{}, (*Scope)(nil))
+}
+
+// ScopeFrom returns the scope carried by ctx, or nil.
+func ScopeFrom(ctx context.Context) *Scope {
+	scope, _ := ctx.Value(scopeKey{}).(*Scope)
+	return scope
+}
+
+// WithKey returns a context carrying the key of an action already numbered,
+// so adapters called with it reuse the key instead of taking a new one.
+func WithKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, actionKey{}, key)
+}
+
+// ActionKey returns the key of an action performed with ctx: the key set by
+// WithKey, otherwise the next key of the scope. Returns an empty string when
+// ctx carries neither. Call it once per action.
+func ActionKey(ctx context.Context) string {
+	if key, ok := ctx.Value(actionKey{}).(string); ok {
+		return key
+	}
+	if scope := ScopeFrom(ctx); scope != nil {
+		return scope.Next()
+	}
+	return ""
+}
@@ -0,0 +1,77 @@
+package d_idempotency
+
+import (
+	"context"
+	"testing"
+
+	d_user "github.com/irissonnlima/chatgraph-go/core/domain/user"
+)
+
+var chatID = d_user.ChatID{UserID: "u1", CompanyID: "c1"}
+
+func TestScope_Deterministic(t *testing.T) {
+	first := NewScope(chatID, "m1", "start")
+	second := NewScope(chatID, "m1", "start")
+
+	for i := 0; i < 3; i++ {
+		a, b := first.Next(), second.Next()
+		if a != b {
+			t.Errorf("action %d: expected the same key, got %s and %s", i+1, a, b)
+		}
+	}
+}
+
+func TestKey_Components(t *testing.T) {
+	base := Key(chatID, "m1", "start", 1)
+
+	others := map[string]string{
+		"sequence": Key(chatID, "m1", "start", 2),
+		"message":  Key(chatID, "m2", "start", 1),
+		"chat":     Key(d_user.ChatID{UserID: "u2", CompanyID: "c1"}, "m1", "start", 1),
+		"route":    Key(chatID, "m1", "menu", 1),
+	}
+	for name, key := range others {
+		if key == base {
+			t.Errorf("expected the %s to change the key", name)
+		}
+	}
+}
+
+func TestNewScope_WithoutMessageID(t *testing.T) {
+	if scope := NewScope(chatID, "", "start"); scope != nil {
+		t.Errorf("expected no scope, got %+v", scope)
+	}
+
+	ctx := WithScope(context.Background(), nil)
+	if key := ActionKey(ctx); key != "" {
+		t.Errorf("expected no key, got %q", key)
+	}
+}
+
+func TestActionKey(t *testing.T) {
+	ctx := WithScope(context.Background(), NewScope(chatID, "m1", "start"))
+
+	if got, want := ActionKey(ctx), Key(chatID, "m1", "start", 1); got != want {
+		t.Errorf("ActionKey() = %s, want %s", got, want)
+	}
+
+	fixed := WithKey(ctx, "k")
+	if ActionKey(fixed) != "k" || ActionKey(fixed) != "k" {
+		t.Error("expected the fixed key to be reused")
+	}
+
+	if got, want := ActionKey(ctx), Key(chatID, "m1", "start", 2); got != want {
+		t.Errorf("expected fixed keys not to advance the sequence, got %s, want %s", got, want)
+	}
+}
+
+func TestWithoutScope(t *testing.T) {
+	ctx := WithScope(context.Background(), NewScope(chatID, "m1", "start"))
+
+	if key := ActionKey(WithoutScope(ctx)); key != "" {
+		t.Errorf("expected no key without the scope, got %s", key)
+	}
+	if got, want := ActionKey(ctx), Key(chatID, "m1", "start", 1); got != want {
+		t.Errorf("expected the hidden scope not to advance, got %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,52 @@
+// Package d_inactivity provides the timers that react when a user goes
+// silent mid-flow: they remind the user, then move the session on, as
+// configured by the InactivityRouteOps of the route the user was left on.
+package d_inactivity
+
+import (
+	"time"
+
+	d_session "github.com/irissonnlima/chatgraph-go/core/domain/session"
+	d_user "github.com/irissonnlima/chatgraph-go/core/domain/user"
+)
+
+// Timer tracks the silence of a chat on a route. A chat has at most one
+// timer, replaced whenever the chat's route is set and removed by the next
+// inbound message.
+type Timer struct {
+	// ID identifies the timer, so a replaced timer is not run.
+	ID string
+	// Session is a snapshot of the chat when the timer was set, used to run
+	// the inactivity route when the executor keeps no state.
+	Session d_session.Session
+	// Route is the route the user was left on, whose inactivity options apply.
+	Route string
+	// RemindAt is when the reminder is due. Zero when the route has none.
+	RemindAt time.Time
+	// Reminded is set once the reminder was sent.
+	Reminded bool
+	// Deadline is when the session moves on.
+	Deadline time.Time
+	// CreatedAt is when the timer was set.
+	CreatedAt time.Time
+}
+
+// ChatID returns the chat of the timer.
+func (t Timer) ChatID() d_user.ChatID {
+	return t.Session.ChatID
+}
+
+// ReminderDue reports whether the reminder is due at now.
+func (t Timer) ReminderDue(now time.Time) bool {
+	return !t.RemindAt.IsZero() && !t.Reminded && !now.Before(t.RemindAt)
+}
+
+// Expired reports whether the deadline passed at now.
+func (t Timer) Expired(now time.Time) bool {
+	return !now.Before(t.Deadline)
+}
+
+// Due reports whether the timer has work to do at now.
+func (t Timer) Due(now time.Time) bool {
+	return t.ReminderDue(now) || t.Expired(now)
+}
@@ -0,0 +1,39 @@
+package d_inactivity
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTimer_Due(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	timer := Timer{RemindAt: now.Add(time.Minute), Deadline: now.Add(time.Hour)}
+
+	tests := []struct {
+		name         string
+		timer        Timer
+		at           time.Time
+		wantReminder bool
+		wantExpired  bool
+	}{
+		{"silent for a while", timer, now, false, false},
+		{"reminder due", timer, now.Add(time.Minute), true, false},
+		{"reminded", Timer{RemindAt: timer.RemindAt, Reminded: true, Deadline: timer.Deadline}, now.Add(time.Minute), false, false},
+		{"expired", timer, now.Add(time.Hour), true, true},
+		{"no reminder", Timer{Deadline: timer.Deadline}, now.Add(time.Minute), false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.timer.ReminderDue(tt.at); got != tt.wantReminder {
+				t.Errorf("ReminderDue() = %v, want %v", got, tt.wantReminder)
+			}
+			if got := tt.timer.Expired(tt.at); got != tt.wantExpired {
+				t.Errorf("Expired() = %v, want %v", got, tt.wantExpired)
+			}
+			if got := tt.timer.Due(tt.at); got != (tt.wantReminder || tt.wantExpired) {
+				t.Errorf("Due() = %v", got)
+			}
+		})
+	}
+}
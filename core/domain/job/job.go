@@ -0,0 +1,128 @@
+// Package d_job provides the background jobs that handlers enqueue for work
+// taking longer than a handler may run (e.g. generating a PDF invoice). When
+// a job finishes, the conversation resumes at the job's completion route.
+package d_job
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	d_session "github.com/irissonnlima/chatgraph-go/core/domain/session"
+)
+
+// Status is the state of a job.
+type Status string
+
+// Job statuses.
+const (
+	// PENDING jobs are waiting for their first attempt or for a retry.
+	PENDING Status = "PENDING"
+	// RUNNING jobs have a task attempt in progress.
+	RUNNING Status = "RUNNING"
+	// SUCCEEDED jobs finished with a result.
+	SUCCEEDED Status = "SUCCEEDED"
+	// FAILED jobs failed their last allowed attempt.
+	FAILED Status = "FAILED"
+)
+
+// ErrNoResult is returned by Job.DecodeResult when the job has no result.
+var ErrNoResult = errors.New("job has no result")
+
+// Task performs the work of a job. Its result is stored as JSON and made
+// available to the completion route. Tasks must honor ctx.Done(): it is
+// cancelled when the task times out or the runner stops.
+type Task func(ctx context.Context, job Job) (any, error)
+
+// Job is a background task enqueued by a handler.
+type Job struct {
+	// ID identifies the job.
+	ID string
+	// Task is the name of the task that performs the job.
+	Task string
+	// Payload is the JSON input of the task.
+	Payload string
+	// Session is a snapshot of the chat when the job was enqueued, used to
+	// resume it.
+	Session d_session.Session
+	// CompletionRoute is executed for the chat when the job succeeds.
+	CompletionRoute string
+	// FailureRoute is executed for the chat when the job fails. When empty,
+	// failed jobs do not resume the chat.
+	FailureRoute string
+	// WaitingRoute, when set, is the route the chat waits on for the job.
+	// A chat found on another route when the job finishes is not resumed.
+	WaitingRoute string
+
+	// Status is the state of the job.
+	Status Status
+	// Result is the JSON result of a SUCCEEDED job.
+	Result string
+	// Error is the error of the last failed attempt.
+	Error string
+	// Attempts is the number of task attempts started.
+	Attempts int
+	// MaxAttempts is the number of attempts allowed.
+	MaxAttempts int
+	// Resumed is set once the chat was resumed after the job finished.
+	Resumed bool
+	// Stale is set, along with Resumed, when the chat had left WaitingRoute
+	// once the job finished, so the resume route was not executed.
+	Stale bool
+
+	// CreatedAt is when the job was enqueued.
+	CreatedAt time.Time
+	// UpdatedAt is when the job last changed.
+	UpdatedAt time.Time
+	// NextAttempt is when the job may be processed again.
+	NextAttempt time.Time
+}
+
+// Finished reports whether the job succeeded or failed.
+func (j Job) Finished() bool {
+	return j.Status == SUCCEEDED || j.Status == FAILED
+}
+
+// Due reports whether the job has work left that may be done at now: a task
+// attempt, or resuming the chat once it finished.
+func (j Job) Due(now time.Time) bool {
+	if j.Finished() && j.Resumed {
+		return false
+	}
+	return !now.Before(j.NextAttempt)
+}
+
+// ResumeRoute returns the route the chat resumes at once the job finished.
+func (j Job) ResumeRoute() string {
+	if j.Status == SUCCEEDED {
+		return j.CompletionRoute
+	}
+	return j.FailureRoute
+}
+
+// DecodePayload decodes the payload of the job into target.
+func (j Job) DecodePayload(target any) error {
+	return json.Unmarshal([]byte(j.Payload), target)
+}
+
+// DecodeResult decodes the result of the job into target.
+func (j Job) DecodeResult(target any) error {
+	if j.Result == "" {
+		return ErrNoResult
+	}
+	return json.Unmarshal([]byte(j.Result), target)
+}
+
+type jobKey struct{}
+
+// WithJob returns a context carrying job, e.g. to the completion route.
+func WithJob(ctx context.Context, job Job) context.Context {
+	return context.WithValue(ctx, jobKey{}, job)
+}
+
+// FromContext returns the job carried by ctx.
+func FromContext(ctx context.Context) (Job, bool) {
+	job, ok := ctx.Value(jobKey{}).(Job)
+	return job, ok
+}
@@ -0,0 +1,73 @@
+package d_job
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestJob_Due(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		job  Job
+		want bool
+	}{
+		{"pending", Job{Status: PENDING}, true},
+		{"waiting for a retry", Job{Status: PENDING, NextAttempt: now.Add(time.Second)}, false},
+		{"interrupted while running", Job{Status: RUNNING}, true},
+		{"finished, not resumed", Job{Status: SUCCEEDED}, true},
+		{"finished and resumed", Job{Status: FAILED, Resumed: true}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.job.Due(now); got != tt.want {
+				t.Errorf("Due() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestJob_ResumeRoute(t *testing.T) {
+	job := Job{CompletionRoute: "done", FailureRoute: "failed", Status: SUCCEEDED}
+	if route := job.ResumeRoute(); route != "done" {
+		t.Errorf("ResumeRoute() = %q, want done", route)
+	}
+
+	job.Status = FAILED
+	if route := job.ResumeRoute(); route != "failed" {
+		t.Errorf("ResumeRoute() = %q, want failed", route)
+	}
+}
+
+func TestJob_DecodeResult(t *testing.T) {
+	var result struct {
+		URL string `json:"url"`
+	}
+
+	if err := (Job{}).DecodeResult(&result); !errors.Is(err, ErrNoResult) {
+		t.Errorf("expected ErrNoResult, got %v", err)
+	}
+
+	job := Job{Result: `{"url":"https://example.com/invoice.pdf"}`}
+	if err := job.DecodeResult(&result); err != nil {
+		t.Fatalf("DecodeResult() error = %v", err)
+	}
+	if result.URL != "https://example.com/invoice.pdf" {
+		t.Errorf("unexpected result %+v", result)
+	}
+}
+
+func TestWithJob(t *testing.T) {
+	if _, ok := FromContext(context.Background()); ok {
+		t.Error("expected no job in an empty context")
+	}
+
+	ctx := WithJob(context.Background(), Job{ID: "j1"})
+	if job, ok := FromContext(ctx); !ok || job.ID != "j1" {
+		t.Errorf("FromContext() = %+v, %v", job, ok)
+	}
+}
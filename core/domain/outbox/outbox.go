@@ -0,0 +1,68 @@
+// Package d_outbox provides the outbound actions kept in an outbox while the
+// executor's backend is failing, to be performed later in order.
+package d_outbox
+
+import (
+	"time"
+
+	d_action "github.com/irissonnlima/chatgraph-go/core/domain/action"
+	d_message "github.com/irissonnlima/chatgraph-go/core/domain/message"
+	d_user "github.com/irissonnlima/chatgraph-go/core/domain/user"
+)
+
+// ActionType identifies the executor operation of an action.
+type ActionType string
+
+// Types of outbound actions.
+const (
+	SEND_MESSAGE     ActionType = "SEND_MESSAGE"
+	SET_ROUTE        ActionType = "SET_ROUTE"
+	SET_OBSERVATION  ActionType = "SET_OBSERVATION"
+	END_SESSION      ActionType = "END_SESSION"
+	TRANSFER_TO_MENU ActionType = "TRANSFER_TO_MENU"
+)
+
+// Action is an outbound action waiting in the outbox. Only the fields of its
+// type are set.
+type Action struct {
+	// ID is assigned by the repository. IDs increase in the order actions are
+	// added, which is the order they are performed in.
+	ID uint64
+	// Type is the executor operation.
+	Type ActionType
+	// ChatID is the chat the action is for.
+	ChatID d_user.ChatID
+	// Platform is the messaging platform of SEND_MESSAGE.
+	Platform string
+	// Message is the message of SEND_MESSAGE and TRANSFER_TO_MENU.
+	Message d_message.Message
+	// Route is the route of SET_ROUTE.
+	Route string
+	// Observation is the JSON observation of SET_OBSERVATION.
+	Observation string
+	// EndActionID is the end action of END_SESSION.
+	EndActionID string
+	// Transfer is the transfer of TRANSFER_TO_MENU.
+	Transfer d_action.TransferToMenu
+	// IdempotencyKey is sent with every attempt, when set.
+	IdempotencyKey string
+
+	// CreatedAt is when the action was first attempted.
+	CreatedAt time.Time
+	// Attempts is the number of failed attempts.
+	Attempts int
+	// LastError is the error of the last attempt.
+	LastError string
+	// NextAttempt is when the action may be attempted again.
+	NextAttempt time.Time
+}
+
+// Expired reports whether the action is older than ttl.
+func (a Action) Expired(now time.Time, ttl time.Duration) bool {
+	return ttl > 0 && now.Sub(a.CreatedAt) >= ttl
+}
+
+// Due reports whether the action may be attempted at now.
+func (a Action) Due(now time.Time) bool {
+	return !now.Before(a.NextAttempt)
+}
@@ -83,6 +83,16 @@ func (r Route) Current() string {
 	return r.History[len(r.History)-1]
 }
 
+// Path returns the full history joined by the separator.
+//
+// Example:
+//
+//	route := NewRoute("start.menu", '.')
+//	route.Path() // returns "start.menu"
+func (r Route) Path() string {
+	return strings.Join(r.History, string(r.Separator))
+}
+
 // Previous returns a new Route with deduplicated history and without the last route.
 // Useful for navigating back without getting stuck in repeated route loops.
 //
@@ -470,3 +470,11 @@ func TestRoute_EdgeCases(t *testing.T) {
 		}
 	})
 }
+
+func TestRoute_Path(t *testing.T) {
+	route := NewRoute("start.menu", '.').Next("options")
+
+	if got := route.Path(); got != "start.menu.options" {
+		t.Errorf("Route.Path() = %v, want start.menu.options", got)
+	}
+}
@@ -1,7 +1,12 @@
 package d_router
 
 import (
+	"errors"
 	"time"
+
+	d_action "github.com/irissonnlima/chatgraph-go/core/domain/action"
+	d_calendar "github.com/irissonnlima/chatgraph-go/core/domain/calendar"
+	d_message "github.com/irissonnlima/chatgraph-go/core/domain/message"
 )
 
 // Default values for route handler options.
@@ -19,6 +24,27 @@ var (
 	}
 )
 
+// DEFAULT_PROGRESS_AFTER is how long a handler with progress options may run
+// before the user gets feedback, when ProgressRouteOps.After is not set.
+const DEFAULT_PROGRESS_AFTER = 3 * time.Second
+
+// ProgressRouteOps configures feedback for slow handlers, so users do not
+// stare at silence and retype their message while a handler waits on a slow
+// backend. The feedback stops as soon as the handler returns.
+type ProgressRouteOps struct {
+	// After is how long the handler may run before the feedback is sent.
+	// Defaults to DEFAULT_PROGRESS_AFTER.
+	After time.Duration
+	// Message is the interim message, e.g. "Please wait...". If nil, a typing
+	// indicator is shown instead, where the executor supports it.
+	Message *d_message.Message
+	// Repeat, if positive, repeats the feedback at this interval while the
+	// handler runs.
+	Repeat time.Duration
+	// Reminder, if set, is sent on repeats instead of Message.
+	Reminder *d_message.Message
+}
+
 // ProtectedRouteOps configures route protection settings.
 // When enabled, users who don't meet the protection criteria will be redirected
 // to the specified route instead of accessing the protected handler.
@@ -49,6 +75,76 @@ type LoopCountRouteOps struct {
 	Route string
 }
 
+// BufferedRouteOps configures transactional buffering of a handler's actions.
+// The messages, observations, edits and reactions of a buffered handler are
+// collected and performed, in order, only when it returns normally. When it
+// times out or panics they are discarded, so the user never sees half of its
+// output.
+type BufferedRouteOps struct {
+	// Compensation, if set, is sent to the user when the actions are
+	// discarded, e.g. an apology explaining that the request failed.
+	Compensation *d_message.Message
+}
+
+// InactivityRouteOps configures what happens when the user goes silent on a
+// route, i.e. the route was set and no message arrived since. Timers are
+// cancelled by the next inbound message and run by an InactivityScheduler.
+type InactivityRouteOps struct {
+	// Reminder, if positive, is how long the user may stay silent before
+	// ReminderMessage is sent. It must be shorter than After.
+	Reminder time.Duration
+	// ReminderMessage is the reminder, e.g. "Are you still there?".
+	ReminderMessage *d_message.Message
+	// After is how long the user may stay silent before the session moves
+	// on. Required.
+	After time.Duration
+	// Route, if set, is executed for the chat once After elapsed, as if the
+	// user had been redirected to it.
+	Route string
+	// End ends the session with this action once After elapsed, when Route
+	// is not set.
+	End *d_action.EndAction
+}
+
+// Validate checks that the inactivity options are consistent.
+func (o InactivityRouteOps) Validate() error {
+	switch {
+	case o.After <= 0:
+		return errors.New("inactivity After must be positive")
+	case o.Reminder > 0 && o.Reminder >= o.After:
+		return errors.New("inactivity Reminder must be shorter than After")
+	case o.Reminder > 0 && o.ReminderMessage == nil:
+		return errors.New("inactivity ReminderMessage is required with Reminder")
+	case o.Route == "" && o.End == nil:
+		return errors.New("inactivity Route or End is required")
+	}
+	return nil
+}
+
+// BusinessHoursRouteOps restricts a route to the business hours of the
+// chat's company, e.g. a route transferring users to human agents. Outside
+// business hours, the user is redirected to the specified route instead of
+// accessing the handler.
+type BusinessHoursRouteOps struct {
+	// Calendars holds the business hours of each company. Required.
+	Calendars *d_calendar.Calendars
+	// Route is the route name to redirect to while the company is closed.
+	// Its handler can tell the user when it opens, with
+	// Calendars.NextOpening.
+	Route string
+	// Now returns the current time. Defaults to time.Now.
+	Now func() time.Time
+}
+
+// IsOpen reports whether the company is open now.
+func (o BusinessHoursRouteOps) IsOpen(companyID string) bool {
+	now := time.Now
+	if o.Now != nil {
+		now = o.Now
+	}
+	return o.Calendars.IsOpen(companyID, now())
+}
+
 // RouterHandlerOptions configures the behavior and constraints for router handler execution.
 // It provides settings for error tracking, execution time limits, and route protection
 // to ensure robust and controlled request processing.
@@ -65,6 +161,20 @@ type RouterHandlerOptions struct {
 	// When enabled, unauthorized users will be redirected to the specified route.
 	Protected *ProtectedRouteOps
 
+	// Buffered enables transactional buffering of the handler's actions.
+	// If nil, actions are performed as soon as the handler calls them.
+	Buffered *BufferedRouteOps
+
+	// Progress sends feedback to the user while the handler is slow.
+	// If nil, no feedback is sent.
+	Progress *ProgressRouteOps
+	// Inactivity reminds the user, then moves the session on, when they go
+	// silent on the route. If nil, the route waits for the user forever.
+	Inactivity *InactivityRouteOps
+	// BusinessHours redirects users outside the business hours of their
+	// company. If nil, the route is always available.
+	BusinessHours *BusinessHoursRouteOps
+
 	// Triggers is a list of regex-based triggers that can automatically redirect
 	// the conversation to a different route based on message content.
 	Triggers []RouteTrigger
@@ -84,6 +194,18 @@ func (o *RouterHandlerOptions) SetOps(other RouterHandlerOptions) {
 	if other.Protected != nil {
 		o.Protected = other.Protected
 	}
+	if other.Buffered != nil {
+		o.Buffered = other.Buffered
+	}
+	if other.Progress != nil {
+		o.Progress = other.Progress
+	}
+	if other.Inactivity != nil {
+		o.Inactivity = other.Inactivity
+	}
+	if other.BusinessHours != nil {
+		o.BusinessHours = other.BusinessHours
+	}
 	if len(other.Triggers) > 0 {
 		o.Triggers = other.Triggers
 	}
@@ -101,6 +223,12 @@ func (o *RouterHandlerOptions) GetRhoRoutes() []string {
 	if o.Protected != nil {
 		rhoRoutes = append(rhoRoutes, o.Protected.Route)
 	}
+	if o.Inactivity != nil && o.Inactivity.Route != "" {
+		rhoRoutes = append(rhoRoutes, o.Inactivity.Route)
+	}
+	if o.BusinessHours != nil && o.BusinessHours.Route != "" {
+		rhoRoutes = append(rhoRoutes, o.BusinessHours.Route)
+	}
 
 	return rhoRoutes
 }
@@ -3,6 +3,10 @@ package d_router
 import (
 	"testing"
 	"time"
+
+	d_action "github.com/irissonnlima/chatgraph-go/core/domain/action"
+	d_calendar "github.com/irissonnlima/chatgraph-go/core/domain/calendar"
+	d_message "github.com/irissonnlima/chatgraph-go/core/domain/message"
 )
 
 func TestRouterHandlerOptions_SetOps(t *testing.T) {
@@ -61,6 +65,48 @@ func TestRouterHandlerOptions_SetOps(t *testing.T) {
 		}
 	})
 
+	t.Run("sets buffered when provided", func(t *testing.T) {
+		opts := RouterHandlerOptions{}
+
+		buffered := &BufferedRouteOps{}
+
+		opts.SetOps(RouterHandlerOptions{Buffered: buffered})
+
+		if opts.Buffered != buffered {
+			t.Error("Buffered should have been set")
+		}
+	})
+
+	t.Run("sets progress when provided", func(t *testing.T) {
+		opts := RouterHandlerOptions{}
+
+		progress := &ProgressRouteOps{After: time.Second}
+
+		opts.SetOps(RouterHandlerOptions{Progress: progress})
+
+		if opts.Progress != progress {
+			t.Error("Progress should have been set")
+		}
+	})
+
+	t.Run("sets inactivity when provided", func(t *testing.T) {
+		opts := RouterHandlerOptions{}
+		inactivity := &InactivityRouteOps{After: time.Minute, Route: "goodbye"}
+		opts.SetOps(RouterHandlerOptions{Inactivity: inactivity})
+		if opts.Inactivity != inactivity {
+			t.Error("Inactivity should have been set")
+		}
+	})
+
+	t.Run("sets business hours when provided", func(t *testing.T) {
+		opts := RouterHandlerOptions{}
+		hours := &BusinessHoursRouteOps{Calendars: &d_calendar.Calendars{}, Route: "after_hours"}
+		opts.SetOps(RouterHandlerOptions{BusinessHours: hours})
+		if opts.BusinessHours != hours {
+			t.Error("BusinessHours should have been set")
+		}
+	})
+
 	t.Run("sets triggers when provided", func(t *testing.T) {
 		opts := RouterHandlerOptions{}
 
@@ -117,6 +163,43 @@ func TestRouterHandlerOptions_GetRhoRoutes(t *testing.T) {
 		}
 	})
 
+	t.Run("returns the inactivity route when set", func(t *testing.T) {
+		opts := RouterHandlerOptions{
+			Inactivity: &InactivityRouteOps{After: time.Minute, Route: "goodbye"},
+		}
+
+		routes := opts.GetRhoRoutes()
+
+		if len(routes) != 1 || routes[0] != "goodbye" {
+			t.Errorf("routes = %v, want [goodbye]", routes)
+		}
+	})
+
+	t.Run("returns the business hours route when set", func(t *testing.T) {
+		opts := RouterHandlerOptions{
+			BusinessHours: &BusinessHoursRouteOps{Route: "after_hours"},
+		}
+
+		routes := opts.GetRhoRoutes()
+
+		if len(routes) != 1 || routes[0] != "after_hours" {
+			t.Errorf("routes = %v, want [after_hours]", routes)
+		}
+	})
+
+	t.Run("skips empty routes", func(t *testing.T) {
+		opts := RouterHandlerOptions{
+			Inactivity:    &InactivityRouteOps{After: time.Minute},
+			BusinessHours: &BusinessHoursRouteOps{},
+		}
+
+		routes := opts.GetRhoRoutes()
+
+		if len(routes) != 0 {
+			t.Errorf("routes = %v, want none", routes)
+		}
+	})
+
 	t.Run("returns empty when no options set", func(t *testing.T) {
 		opts := RouterHandlerOptions{}
 
@@ -143,6 +226,50 @@ func TestRouterHandlerOptions_GetRhoRoutes(t *testing.T) {
 	})
 }
 
+func TestInactivityRouteOps_Validate(t *testing.T) {
+	reminder := &d_message.Message{TextMessage: d_message.TextMessage{Detail: "Still there?"}}
+
+	tests := []struct {
+		name    string
+		ops     InactivityRouteOps
+		wantErr bool
+	}{
+		{"route", InactivityRouteOps{After: time.Hour, Route: "goodbye"}, false},
+		{"end with reminder", InactivityRouteOps{Reminder: time.Minute, ReminderMessage: reminder, After: time.Hour, End: &d_action.EndAction{ID: "idle"}}, false},
+		{"missing after", InactivityRouteOps{Route: "goodbye"}, true},
+		{"reminder after deadline", InactivityRouteOps{Reminder: time.Hour, ReminderMessage: reminder, After: time.Minute, Route: "goodbye"}, true},
+		{"reminder without message", InactivityRouteOps{Reminder: time.Minute, After: time.Hour, Route: "goodbye"}, true},
+		{"missing route and end", InactivityRouteOps{After: time.Hour}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.ops.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestBusinessHoursRouteOps_IsOpen(t *testing.T) {
+	always := d_calendar.Calendar{Weekly: map[time.Weekday][]d_calendar.Period{}}
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		always.Weekly[day] = []d_calendar.Period{{Start: 0, End: d_calendar.DAY}}
+	}
+	ops := BusinessHoursRouteOps{
+		Calendars: &d_calendar.Calendars{Default: always, Companies: map[string]d_calendar.Calendar{"closed": {}}},
+		Route:     "after_hours",
+		Now:       func() time.Time { return time.Date(2025, time.March, 12, 9, 0, 0, 0, time.UTC) },
+	}
+
+	if !ops.IsOpen("acme") {
+		t.Error("expected acme to be open")
+	}
+	if ops.IsOpen("closed") {
+		t.Error("expected the company without periods to be closed")
+	}
+}
+
 func TestDefaultValues(t *testing.T) {
 	t.Run("DEFAULT_TIMEOUT has correct values", func(t *testing.T) {
 		if DEFAULT_TIMEOUT.Duration != 5*time.Minute {
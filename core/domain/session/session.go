@@ -0,0 +1,120 @@
+// Package d_session provides the persisted session model used by session stores
+// when the chatbot keeps its own state instead of relying on the Router API.
+package d_session
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+
+	d_route "github.com/irissonnlima/chatgraph-go/core/domain/route"
+	d_user "github.com/irissonnlima/chatgraph-go/core/domain/user"
+)
+
+// ROUTE_SEPARATOR joins the route history of a stored session.
+const ROUTE_SEPARATOR = '.'
+
+// Session is the stored state of a chat.
+// Route and Observation are kept serialized, the same way the Router API keeps them.
+type Session struct {
+	// ID is the unique identifier for the current session of the chat.
+	ID int64
+	// ChatID identifies the user and company for this chat.
+	ChatID d_user.ChatID
+	// User contains the user's personal information.
+	User d_user.User
+	// Menu is the current menu context.
+	Menu d_user.Menu
+	// Route is the navigation history joined by ROUTE_SEPARATOR.
+	Route string
+	// Observation is the JSON observation of the session.
+	Observation string
+	// Platform identifies the messaging platform (e.g., "whatsapp", "telegram").
+	Platform string
+	// CreatedAt is when the session was started.
+	CreatedAt time.Time
+	// UpdatedAt is when the session was last changed.
+	UpdatedAt time.Time
+}
+
+// New returns a fresh session for a chat, positioned at the start route.
+func New(chatID d_user.ChatID, id int64, startRoute string, now time.Time) Session {
+	return Session{
+		ID:        id,
+		ChatID:    chatID,
+		Route:     startRoute,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+}
+
+// IsEmpty returns true if the session was never started.
+func (s Session) IsEmpty() bool {
+	return s.ID == 0
+}
+
+// AppendRoute adds a route to the navigation history.
+func (s *Session) AppendRoute(route string) {
+	if s.Route == "" {
+		s.Route = route
+		return
+	}
+	s.Route += string(ROUTE_SEPARATOR) + route
+}
+
+// Next returns the session that follows this one once it ends.
+// The user information and platform are kept; route and observation are reset.
+func (s Session) Next(startRoute string, now time.Time) Session {
+	next := New(s.ChatID, s.ID+1, startRoute, now)
+	next.User = s.User
+	next.Platform = s.Platform
+	return next
+}
+
+// ToUserState converts a stored session into the user state handed to the
+// engine, decoding the observation into Obs.
+func ToUserState[Obs any](s Session) (d_user.UserState[Obs], error) {
+	state := d_user.UserState[Obs]{
+		SessionID:   s.ID,
+		ChatID:      s.ChatID,
+		User:        s.User,
+		Menu:        s.Menu,
+		Route:       d_route.NewRoute(s.Route, ROUTE_SEPARATOR),
+		DirectionIn: true,
+		Platform:    s.Platform,
+	}
+	if !s.CreatedAt.IsZero() {
+		state.DtCreated = s.CreatedAt.Format(time.RFC3339)
+	}
+
+	if strings.TrimSpace(s.Observation) != "" {
+		if err := state.LoadObservation(s.Observation); err != nil {
+			return state, err
+		}
+	}
+	return state, nil
+}
+
+// FromUserState converts a user state into a session, encoding the
+// observation as JSON. It is the inverse of ToUserState, e.g. to keep a
+// snapshot of the state to resume the chat later.
+func FromUserState[Obs any](state d_user.UserState[Obs]) (Session, error) {
+	observation, err := json.Marshal(state.Observation)
+	if err != nil {
+		return Session{}, err
+	}
+
+	session := Session{
+		ID:          state.SessionID,
+		ChatID:      state.ChatID,
+		User:        state.User,
+		Menu:        state.Menu,
+		Route:       strings.Join(state.Route.History, string(ROUTE_SEPARATOR)),
+		Observation: string(observation),
+		Platform:    state.Platform,
+	}
+	if created, err := time.Parse(time.RFC3339, state.DtCreated); err == nil {
+		session.CreatedAt = created
+	}
+	return session, nil
+}
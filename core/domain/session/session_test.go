@@ -0,0 +1,97 @@
+package d_session
+
+import (
+	"testing"
+	"time"
+
+	d_user "github.com/irissonnlima/chatgraph-go/core/domain/user"
+)
+
+type testObs struct {
+	Name string `json:"name"`
+}
+
+var testChat = d_user.ChatID{UserID: "u1", CompanyID: "c1"}
+
+func TestAppendRoute(t *testing.T) {
+	s := Session{}
+	s.AppendRoute("start")
+	s.AppendRoute("menu")
+	s.AppendRoute("menu")
+
+	if s.Route != "start.menu.menu" {
+		t.Errorf("expected start.menu.menu, got %q", s.Route)
+	}
+}
+
+func TestNext(t *testing.T) {
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	s := New(testChat, 4, "start", now.Add(-time.Hour))
+	s.User = d_user.User{Name: "Ana"}
+	s.Platform = "whatsapp"
+	s.Menu = d_user.Menu{ID: 2}
+	s.Observation = `{"name":"Ana"}`
+	s.AppendRoute("menu")
+
+	next := s.Next("begin", now)
+
+	if next.ID != 5 || next.Route != "begin" || next.Observation != "" || !next.Menu.IsEmpty() {
+		t.Errorf("expected a fresh session, got %+v", next)
+	}
+	if next.User.Name != "Ana" || next.Platform != "whatsapp" || next.ChatID != testChat {
+		t.Errorf("expected chat, user and platform to be kept, got %+v", next)
+	}
+	if !next.CreatedAt.Equal(now) {
+		t.Errorf("expected CreatedAt %v, got %v", now, next.CreatedAt)
+	}
+}
+
+func TestToUserState(t *testing.T) {
+	s := New(testChat, 3, "start.menu", time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC))
+	s.Observation = `{"name":"Ana"}`
+
+	state, err := ToUserState[testObs](s)
+	if err != nil {
+		t.Fatalf("ToUserState() error = %v", err)
+	}
+	if state.SessionID != 3 || state.ChatID != testChat {
+		t.Errorf("unexpected state %+v", state)
+	}
+	if state.Route.Current() != "menu" {
+		t.Errorf("expected route menu, got %q", state.Route.Current())
+	}
+	if state.Observation.Name != "Ana" {
+		t.Errorf("expected observation Ana, got %q", state.Observation.Name)
+	}
+	if state.DtCreated != "2024-01-02T03:04:05Z" {
+		t.Errorf("unexpected DtCreated %q", state.DtCreated)
+	}
+
+	s.Observation = "{"
+	if _, err := ToUserState[testObs](s); err == nil {
+		t.Error("expected an error for an invalid observation")
+	}
+}
+
+func TestFromUserState(t *testing.T) {
+	s := New(testChat, 3, "start.menu", time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC))
+	s.User = d_user.User{Name: "Ana"}
+	s.Platform = "whatsapp"
+	s.Observation = `{"name":"Ana"}`
+
+	state, err := ToUserState[testObs](s)
+	if err != nil {
+		t.Fatalf("ToUserState() error = %v", err)
+	}
+	got, err := FromUserState(state)
+	if err != nil {
+		t.Fatalf("FromUserState() error = %v", err)
+	}
+
+	if got.ID != 3 || got.ChatID != testChat || got.Route != "start.menu" || got.Platform != "whatsapp" {
+		t.Errorf("unexpected session %+v", got)
+	}
+	if got.Observation != s.Observation || got.User.Name != "Ana" || !got.CreatedAt.Equal(s.CreatedAt) {
+		t.Errorf("unexpected session %+v", got)
+	}
+}
@@ -0,0 +1,34 @@
+package adapter_input
+
+import (
+	"context"
+	"errors"
+
+	d_event "github.com/irissonnlima/chatgraph-go/core/domain/event"
+)
+
+// Errors returned by IEventHandler.
+var (
+	// ErrInvalidEvent is returned for events that cannot be handled, e.g.
+	// for a route that is not registered. They must not be retried.
+	ErrInvalidEvent = errors.New("invalid event")
+	// ErrUnavailable is returned when an event cannot be handled now but may
+	// be later, e.g. while the executor is down.
+	ErrUnavailable = errors.New("temporarily unavailable")
+)
+
+// IEventHandler runs routes for chats in response to backend events
+// instead of user messages, e.g. for an HTTP endpoint.
+type IEventHandler interface {
+	// HandleEvent executes the event's route for its chat and returns once
+	// the route and its result were handled.
+	HandleEvent(ctx context.Context, event d_event.Event) error
+}
+
+// IEventChecker is an optional interface for event handlers that can tell
+// upfront that they cannot handle any event, e.g. because of how they were
+// configured. Event sources check it when they are created, to fail fast.
+type IEventChecker interface {
+	// CheckEvents returns an error when no event can be handled.
+	CheckEvents() error
+}
@@ -11,11 +11,60 @@ import (
 // Implementations of this interface handle the connection to message brokers
 // and provide a channel for receiving incoming messages.
 type IMessageReceiver[Obs any] interface {
-	// ConsumeMessage returns a channel that yields incoming messages.
-	// Each message includes the user's current state and the received message.
+	// ConsumeMessage returns a channel that yields incoming deliveries.
+	// Each delivery includes the user's current state and the received message,
+	// and must be settled exactly once through Ack or Nack.
 	// The channel should be closed when the adapter is stopped.
-	ConsumeMessage() <-chan struct {
-		UserState d_user.UserState[Obs]
-		Message   d_message.Message
+	ConsumeMessage() <-chan Delivery[Obs]
+
+	// Close stops consuming, closes the delivery channel and releases the
+	// underlying resources (e.g. broker channel and connection).
+	// Deliveries that were not settled before Close are returned to their source.
+	// The application calls Close only after in-flight deliveries are settled,
+	// except the ones it leaves unsettled on purpose while stopping.
+	Close() error
+}
+
+// IPrefetchSetter is an optional interface for receivers that can limit how many
+// unacknowledged deliveries are in flight at once.
+// The application sets the prefetch to match its processing capacity.
+type IPrefetchSetter interface {
+	// SetPrefetch limits the number of unacknowledged deliveries to count.
+	SetPrefetch(count int) error
+}
+
+// IAcknowledger settles a delivery with the system it was received from.
+type IAcknowledger interface {
+	// Ack confirms that the delivery was processed successfully.
+	Ack() error
+
+	// Nack reports that processing failed with the given reason.
+	// The receiver decides whether the delivery is redelivered or dead-lettered.
+	Nack(reason error) error
+}
+
+// Delivery is a single incoming message yielded by an IMessageReceiver.
+type Delivery[Obs any] struct {
+	// UserState is the user's session state at the time the message was received.
+	UserState d_user.UserState[Obs]
+	// Message is the received message.
+	Message d_message.Message
+	// Acknowledger settles the delivery. A nil Acknowledger makes Ack and Nack no-ops.
+	Acknowledger IAcknowledger
+}
+
+// Ack confirms that the delivery was processed successfully.
+func (d Delivery[Obs]) Ack() error {
+	if d.Acknowledger == nil {
+		return nil
+	}
+	return d.Acknowledger.Ack()
+}
+
+// Nack reports that processing the delivery failed with the given reason.
+func (d Delivery[Obs]) Nack(reason error) error {
+	if d.Acknowledger == nil {
+		return nil
 	}
+	return d.Acknowledger.Nack(reason)
 }
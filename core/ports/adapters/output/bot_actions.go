@@ -9,13 +9,15 @@ import (
 	d_user "github.com/irissonnlima/chatgraph-go/core/domain/user"
 )
 
-// IBotExecutor defines the interface for routing and messaging operations.
-// Implementations handle message sending, session management, and state updates.
-type IBotExecutor interface {
+// IMessenger sends messages to chats.
+type IMessenger interface {
 	// SendMessage sends a message to the specified chat ID.
 	// Returns an error if the message could not be delivered.
 	SendMessage(to d_user.ChatID, message d_message.Message, platform string) error
+}
 
+// ISessionStore keeps the session state of chats.
+type ISessionStore interface {
 	// SetObservation updates the observation data for the specified chat.
 	// The observation is stored as a JSON string.
 	SetObservation(chatID d_user.ChatID, observation string) error
@@ -27,13 +29,29 @@ type IBotExecutor interface {
 	// EndSession terminates the session for the specified chat.
 	// This should clean up any session-related resources.
 	EndSession(chatID d_user.ChatID, actionId string) error
+}
 
+// ITransferer transfers chats to other menus.
+type ITransferer interface {
 	// TransferToMenu transfers the user to other menu.
 	TransferToMenu(chatID d_user.ChatID, transfer d_action.TransferToMenu, message d_message.Message) error
+}
 
+// IFileStore uploads and retrieves files.
+type IFileStore interface {
 	// UploadFile uploads a file from the given filepath.
 	UploadFile(filepath string) (*d_file.File, error)
 
 	// GetFile retrieves a file by its ID.
 	GetFile(fileID string) (*d_file.File, error)
 }
+
+// IBotExecutor combines every port the application needs to run a chatbot.
+// Implementations that only provide some of them can be combined with the
+// compose adapter.
+type IBotExecutor interface {
+	IMessenger
+	ISessionStore
+	ITransferer
+	IFileStore
+}
@@ -0,0 +1,40 @@
+package adapter_output
+
+import (
+	d_campaign "github.com/irissonnlima/chatgraph-go/core/domain/campaign"
+	d_user "github.com/irissonnlima/chatgraph-go/core/domain/user"
+)
+
+// ICampaignRepository durably keeps broadcast campaigns and the delivery
+// state of their recipients, so sending resumes after a restart.
+// Implementations must be safe for concurrent use.
+type ICampaignRepository interface {
+	// SaveCampaign stores a campaign, replacing the stored campaign with the same ID.
+	SaveCampaign(campaign d_campaign.Campaign) error
+
+	// Campaign returns a stored campaign. ok is false when no campaign has the ID.
+	Campaign(id string) (campaign d_campaign.Campaign, ok bool, err error)
+
+	// Campaigns returns every stored campaign, oldest first.
+	Campaigns() ([]d_campaign.Campaign, error)
+
+	// SaveRecipients stores recipients of a campaign, replacing the stored
+	// recipients with the same Index.
+	SaveRecipients(campaignID string, recipients []d_campaign.Recipient) error
+
+	// Recipients returns the stored recipients of a campaign, by Index.
+	Recipients(campaignID string) ([]d_campaign.Recipient, error)
+
+	// DeleteCampaign removes a campaign and its recipients. Deleting a
+	// missing campaign is not an error.
+	DeleteCampaign(id string) error
+
+	// Close releases the resources held by the repository.
+	Close() error
+}
+
+// IOptOutList tells the chats that opted out of campaigns.
+type IOptOutList interface {
+	// OptedOut reports whether the chat opted out.
+	OptedOut(chatID d_user.ChatID) (bool, error)
+}
@@ -0,0 +1,61 @@
+package adapter_output
+
+import (
+	"context"
+	"errors"
+
+	d_message "github.com/irissonnlima/chatgraph-go/core/domain/message"
+	d_session "github.com/irissonnlima/chatgraph-go/core/domain/session"
+	d_user "github.com/irissonnlima/chatgraph-go/core/domain/user"
+)
+
+// ErrUnsupported is returned by adapters that expose an optional capability
+// they cannot provide, e.g. a composed executor whose messenger lacks it.
+// Callers should treat it as if the capability was absent.
+var ErrUnsupported = errors.New("capability not supported")
+
+// Optional capabilities are discovered by type assertion on the executor, so
+// adapters that lack them need no changes.
+
+// ITypingNotifier shows a typing indicator in a chat.
+type ITypingNotifier interface {
+	// SendTyping shows that the bot is typing. The indicator is cleared by the
+	// platform when the next message is sent or after a timeout.
+	SendTyping(chatID d_user.ChatID, platform string) error
+}
+
+// IMessageEditor edits messages already sent to a chat.
+type IMessageEditor interface {
+	// EditMessage replaces the content of the message with the given ID.
+	EditMessage(chatID d_user.ChatID, messageID string, message d_message.Message, platform string) error
+}
+
+// IReactor reacts to messages of a chat.
+type IReactor interface {
+	// React adds a reaction (usually an emoji) to the message with the given ID.
+	// An empty reaction removes the previous one.
+	React(chatID d_user.ChatID, messageID string, reaction string, platform string) error
+}
+
+// IContextBinder is implemented by executors whose calls can be bound to a
+// context, so they are cancelled together with the handler that makes them.
+type IContextBinder interface {
+	// WithContext returns an executor whose calls use ctx.
+	WithContext(ctx context.Context) IBotExecutor
+}
+
+// IAvailabilityReporter is implemented by executors that can tell when their
+// backend is down, e.g. while a circuit breaker is open.
+type IAvailabilityReporter interface {
+	// Available reports whether calls are expected to go through.
+	Available() bool
+}
+
+// IStateLoader is implemented by executors that keep the state of chats, so
+// routes can run for a chat without an inbound message carrying its state
+// (e.g. for backend events).
+type IStateLoader interface {
+	// LoadState returns the stored state of a chat. ok is false when the chat
+	// has none.
+	LoadState(chatID d_user.ChatID) (state d_session.Session, ok bool, err error)
+}
@@ -0,0 +1,12 @@
+package adapter_output
+
+// IDedupStore remembers the idempotency keys of the outbound actions already
+// performed, so they are not repeated when the same inbound message is
+// processed again. Implementations must be safe for concurrent use.
+type IDedupStore interface {
+	// Seen reports whether the action with the given key was already performed.
+	Seen(key string) (bool, error)
+
+	// Record stores the key of an action that was performed.
+	Record(key string) error
+}
@@ -0,0 +1,38 @@
+package adapter_output
+
+import (
+	d_handoff "github.com/irissonnlima/chatgraph-go/core/domain/handoff"
+	d_message "github.com/irissonnlima/chatgraph-go/core/domain/message"
+	d_user "github.com/irissonnlima/chatgraph-go/core/domain/user"
+)
+
+// IHandoffRepository keeps the open handoff tickets, one per chat.
+// Implementations must be safe for concurrent use.
+type IHandoffRepository interface {
+	// Save stores a ticket, replacing the ticket of its chat.
+	Save(ticket d_handoff.Ticket) error
+
+	// Get returns the open ticket of a chat. ok is false when the chat has none.
+	Get(chatID d_user.ChatID) (ticket d_handoff.Ticket, ok bool, err error)
+
+	// List returns every open ticket, oldest first.
+	List() ([]d_handoff.Ticket, error)
+
+	// Delete removes the ticket of a chat. Deleting a missing ticket is not an error.
+	Delete(chatID d_user.ChatID) error
+
+	// Close releases the resources held by the repository.
+	Close() error
+}
+
+// IAgentConsole is where human agents handle handoff tickets, e.g. a
+// helpdesk. It is told about new tickets and receives the messages users
+// send while their chats are handed off; agents answer, take and close
+// tickets through service.HandoffDesk.
+type IAgentConsole interface {
+	// TicketOpened announces a new ticket, with its summary for the agent.
+	TicketOpened(ticket d_handoff.Ticket, summary string) error
+
+	// Relay delivers a message sent by the user of a ticket to its agent.
+	Relay(ticket d_handoff.Ticket, message d_message.Message) error
+}
@@ -0,0 +1,25 @@
+package adapter_output
+
+import (
+	d_inactivity "github.com/irissonnlima/chatgraph-go/core/domain/inactivity"
+	d_user "github.com/irissonnlima/chatgraph-go/core/domain/user"
+)
+
+// IInactivityRepository keeps the inactivity timers of chats, one per chat.
+// Implementations must be safe for concurrent use.
+type IInactivityRepository interface {
+	// Save stores a timer, replacing the timer of its chat.
+	Save(timer d_inactivity.Timer) error
+
+	// Get returns the timer of a chat. ok is false when the chat has none.
+	Get(chatID d_user.ChatID) (timer d_inactivity.Timer, ok bool, err error)
+
+	// List returns every stored timer, earliest deadline first.
+	List() ([]d_inactivity.Timer, error)
+
+	// Delete removes the timer of a chat. Deleting a missing timer is not an error.
+	Delete(chatID d_user.ChatID) error
+
+	// Close releases the resources held by the repository.
+	Close() error
+}
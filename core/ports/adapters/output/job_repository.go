@@ -0,0 +1,24 @@
+package adapter_output
+
+import (
+	d_job "github.com/irissonnlima/chatgraph-go/core/domain/job"
+)
+
+// IJobRepository durably keeps background jobs until they finished and the
+// chat was resumed. Implementations must be safe for concurrent use.
+type IJobRepository interface {
+	// Save stores a job, replacing the stored job with the same ID.
+	Save(job d_job.Job) error
+
+	// Get returns a stored job. ok is false when no job has the ID.
+	Get(id string) (job d_job.Job, ok bool, err error)
+
+	// List returns every stored job, oldest first.
+	List() ([]d_job.Job, error)
+
+	// Delete removes a job. Deleting a missing job is not an error.
+	Delete(id string) error
+
+	// Close releases the resources held by the repository.
+	Close() error
+}
@@ -0,0 +1,35 @@
+package adapter_output
+
+import (
+	d_outbox "github.com/irissonnlima/chatgraph-go/core/domain/outbox"
+	d_user "github.com/irissonnlima/chatgraph-go/core/domain/user"
+)
+
+// IOutboxRepository durably keeps the outbound actions that could not be
+// performed, until they are. Implementations must be safe for concurrent use.
+type IOutboxRepository interface {
+	// Append stores an action and returns it with its assigned ID.
+	Append(action d_outbox.Action) (d_outbox.Action, error)
+
+	// Pending returns every stored action, ordered by ID.
+	Pending() ([]d_outbox.Action, error)
+
+	// HasPending reports whether a chat has stored actions.
+	HasPending(chatID d_user.ChatID) (bool, error)
+
+	// Update replaces a stored action, e.g. after a failed attempt.
+	Update(action d_outbox.Action) error
+
+	// Delete removes an action. Deleting a missing action is not an error.
+	Delete(id uint64) error
+
+	// Close releases the resources held by the repository.
+	Close() error
+}
+
+// IRetryableError is implemented by errors that tell whether the failed
+// operation may succeed if it is repeated.
+type IRetryableError interface {
+	// Retryable reports whether repeating the operation may succeed.
+	Retryable() bool
+}
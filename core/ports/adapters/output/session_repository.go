@@ -0,0 +1,23 @@
+package adapter_output
+
+import (
+	d_session "github.com/irissonnlima/chatgraph-go/core/domain/session"
+	d_user "github.com/irissonnlima/chatgraph-go/core/domain/user"
+)
+
+// ISessionRepository persists chat sessions, so the chatbot can keep its own
+// state when running against a bare messaging channel instead of the Router API.
+// Implementations must be safe for concurrent use.
+type ISessionRepository interface {
+	// LoadSession returns the stored session of a chat.
+	// ok is false when the chat has no stored session.
+	LoadSession(chatID d_user.ChatID) (session d_session.Session, ok bool, err error)
+
+	// UpdateSession atomically applies update to the session of a chat and
+	// stores the result. update receives an empty session when none is stored.
+	// Nothing is stored if update returns an error.
+	UpdateSession(chatID d_user.ChatID, update func(session *d_session.Session) error) error
+
+	// Close releases the resources held by the store.
+	Close() error
+}
@@ -0,0 +1,146 @@
+// Package service provides the main chatbot application service.
+// This file contains the tracking of abandoned handlers, which keep running
+// after timing out without honoring their context.
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	d_user "github.com/irissonnlima/chatgraph-go/core/domain/user"
+)
+
+// ErrTooManyAbandonedHandlers is returned by HandleMessage when the app stops
+// while the message waits for the number of abandoned handlers to drop below
+// AppOptions.MaxAbandonedHandlers.
+var ErrTooManyAbandonedHandlers = errors.New("too many abandoned handlers")
+
+// AbandonedHandler is a handler that kept running after the engine stopped
+// waiting for it, because it timed out without honoring ctx.Done().
+type AbandonedHandler struct {
+	// Route is the route of the handler.
+	Route string
+	// ChatID identifies the chat the handler was processing.
+	ChatID d_user.ChatID
+	// Since is when the engine stopped waiting for the handler.
+	Since time.Time
+}
+
+// abandonedTracker keeps the handlers that are still running after the
+// engine stopped waiting for them.
+type abandonedTracker struct {
+	mu      sync.Mutex
+	nextID  uint64
+	running map[uint64]AbandonedHandler
+	// total is the number of handlers abandoned since the engine was created.
+	total uint64
+	// released is closed, and replaced, when an abandoned handler finishes.
+	released chan struct{}
+}
+
+// handlerRun tells whether a handler goroutine finished, so the engine only
+// registers it as abandoned when it is still running.
+type handlerRun struct {
+	mu       sync.Mutex
+	finished bool
+	// id is the tracker ID of the abandoned handler, zero while it is not abandoned.
+	id uint64
+}
+
+// newAbandonedTracker creates an empty tracker.
+func newAbandonedTracker() *abandonedTracker {
+	return &abandonedTracker{
+		running:  make(map[uint64]AbandonedHandler),
+		released: make(chan struct{}),
+	}
+}
+
+// abandon registers the handler of run as abandoned, unless it already finished.
+func (t *abandonedTracker) abandon(run *handlerRun, handler AbandonedHandler) {
+	run.mu.Lock()
+	defer run.mu.Unlock()
+	if run.finished {
+		return
+	}
+
+	t.mu.Lock()
+	t.nextID++
+	run.id = t.nextID
+	t.running[run.id] = handler
+	t.total++
+	count := len(t.running)
+	t.mu.Unlock()
+
+	log.Printf("[WARN] Handler for route %s of chat %v is still running after the engine stopped waiting (%d abandoned)",
+		handler.Route, handler.ChatID, count)
+}
+
+// finish marks the handler of run as finished, removing it from the
+// abandoned handlers if it was one.
+func (t *abandonedTracker) finish(run *handlerRun) {
+	run.mu.Lock()
+	defer run.mu.Unlock()
+	run.finished = true
+	if run.id == 0 {
+		return
+	}
+
+	t.mu.Lock()
+	handler := t.running[run.id]
+	delete(t.running, run.id)
+	close(t.released)
+	t.released = make(chan struct{})
+	t.mu.Unlock()
+
+	log.Printf("[INFO] Abandoned handler for route %s of chat %v finished %v after being abandoned",
+		handler.Route, handler.ChatID, time.Since(handler.Since).Round(time.Millisecond))
+}
+
+// count returns the number of abandoned handlers still running and the
+// number abandoned since the engine was created.
+func (t *abandonedTracker) count() (int, uint64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return len(t.running), t.total
+}
+
+// waitBelow blocks until fewer than limit abandoned handlers are running.
+// Returns false when ctx is done or stop is closed first.
+func (t *abandonedTracker) waitBelow(ctx context.Context, stop <-chan struct{}, limit int) bool {
+	for {
+		t.mu.Lock()
+		if len(t.running) < limit {
+			t.mu.Unlock()
+			return true
+		}
+		released := t.released
+		t.mu.Unlock()
+
+		select {
+		case <-released:
+		case <-ctx.Done():
+			return false
+		case <-stop:
+			return false
+		}
+	}
+}
+
+// list returns the abandoned handlers still running, oldest first.
+func (t *abandonedTracker) list() []AbandonedHandler {
+	t.mu.Lock()
+	handlers := make([]AbandonedHandler, 0, len(t.running))
+	for _, handler := range t.running {
+		handlers = append(handlers, handler)
+	}
+	t.mu.Unlock()
+
+	sort.Slice(handlers, func(i, j int) bool {
+		return handlers[i].Since.Before(handlers[j].Since)
+	})
+	return handlers
+}
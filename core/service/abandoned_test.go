@@ -0,0 +1,183 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	route_return "github.com/irissonnlima/chatgraph-go/core/domain"
+	d_context "github.com/irissonnlima/chatgraph-go/core/domain/context"
+	d_message "github.com/irissonnlima/chatgraph-go/core/domain/message"
+	d_router "github.com/irissonnlima/chatgraph-go/core/domain/router"
+	d_user "github.com/irissonnlima/chatgraph-go/core/domain/user"
+	adapter_input "github.com/irissonnlima/chatgraph-go/core/ports/adapters/input"
+)
+
+// newStuckEngine returns an engine whose start route ignores its timeout
+// until release is closed.
+func newStuckEngine(release <-chan struct{}) *Engine[TestObs] {
+	engine := NewEngine[TestObs](d_router.RouterHandlerOptions{
+		Timeout: &d_router.TimeoutRouteOps{Duration: 20 * time.Millisecond, Route: "timeout_route"},
+	})
+	engine.RegisterRoute("start", func(ctx *d_context.ChatContext[TestObs]) route_return.RouteReturn {
+		<-release
+		return nil
+	})
+	engine.RegisterRoute("timeout_route", func(ctx *d_context.ChatContext[TestObs]) route_return.RouteReturn {
+		return nil
+	})
+	return engine
+}
+
+// waitAbandoned waits until the engine has want abandoned handlers.
+func waitAbandoned(t *testing.T, engine *Engine[TestObs], want int) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for len(engine.AbandonedHandlers()) != want {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected %d abandoned handlers, got %d", want, len(engine.AbandonedHandlers()))
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// TestEngine_TracksAbandonedHandlers tests that handlers ignoring their
+// timeout are tracked until they return.
+func TestEngine_TracksAbandonedHandlers(t *testing.T) {
+	release := make(chan struct{})
+	engine := newStuckEngine(release)
+
+	userState := userOn("start")
+	userState.ChatID = d_user.ChatID{UserID: "u1", CompanyID: "c1"}
+
+	if _, err := engine.Execute(userState, d_message.Message{}, newMockExecutor()); err != nil {
+		t.Fatalf("Execute returned error: %v", err)
+	}
+
+	handlers := engine.AbandonedHandlers()
+	if len(handlers) != 1 {
+		t.Fatalf("expected 1 abandoned handler, got %d", len(handlers))
+	}
+	if handlers[0].Route != "start" || handlers[0].ChatID != userState.ChatID || handlers[0].Since.IsZero() {
+		t.Errorf("unexpected abandoned handler %+v", handlers[0])
+	}
+
+	close(release)
+	waitAbandoned(t, engine, 0)
+
+	if _, total := engine.abandoned.count(); total != 1 {
+		t.Errorf("expected 1 handler abandoned in total, got %d", total)
+	}
+}
+
+// TestEngine_CompletedHandlersAreNotAbandoned tests that handlers returning
+// in time are not tracked.
+func TestEngine_CompletedHandlersAreNotAbandoned(t *testing.T) {
+	release := make(chan struct{})
+	close(release)
+	engine := newStuckEngine(release)
+
+	if _, err := engine.Execute(userOn("start"), d_message.Message{}, newMockExecutor()); err != nil {
+		t.Fatalf("Execute returned error: %v", err)
+	}
+	if _, total := engine.abandoned.count(); total != 0 {
+		t.Errorf("expected no abandoned handlers, got %d", total)
+	}
+}
+
+// TestHandleMessage_MaxAbandonedHandlers tests that messages wait while the
+// cap on abandoned handlers is reached, and run once one finishes.
+func TestHandleMessage_MaxAbandonedHandlers(t *testing.T) {
+	release := make(chan struct{})
+	engine := newStuckEngine(release)
+	app := NewChatbotApp(engine, nil, newMockExecutor(), AppOptions{MaxAbandonedHandlers: 1})
+
+	if err := app.HandleMessage(userOn("start"), d_message.Message{}); err != nil {
+		t.Fatalf("HandleMessage returned error: %v", err)
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- app.HandleMessage(userOn("timeout_route"), d_message.Message{}) }()
+
+	select {
+	case err := <-done:
+		t.Fatalf("expected the message to wait for the abandoned handler, got %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+	metrics := app.Metrics()
+	if metrics.AbandonedHandlers != 1 || metrics.AbandonedTotal != 1 || metrics.Throttled != 1 {
+		t.Errorf("unexpected metrics %+v", metrics)
+	}
+
+	close(release)
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("expected the message to run once the handler finished, got %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected the message to run once the handler finished")
+	}
+}
+
+// TestHandleMessage_MaxAbandonedHandlersShutdown tests that a message
+// waiting for abandoned handlers is rejected when the app stops.
+func TestHandleMessage_MaxAbandonedHandlersShutdown(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	engine := newStuckEngine(release)
+	app := NewChatbotApp(engine, &fakeReceiver{}, newMockExecutor(), AppOptions{MaxAbandonedHandlers: 1})
+
+	if err := app.HandleMessage(userOn("start"), d_message.Message{}); err != nil {
+		t.Fatalf("HandleMessage returned error: %v", err)
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- app.HandleMessage(userOn("timeout_route"), d_message.Message{}) }()
+	if err := app.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown returned error: %v", err)
+	}
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, ErrTooManyAbandonedHandlers) {
+			t.Errorf("expected ErrTooManyAbandonedHandlers, got %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected the waiting message to give up when the app stops")
+	}
+}
+
+// TestHandleDelivery_MaxAbandonedHandlersShutdown tests that a delivery held
+// for abandoned handlers when the app stops is left unsettled, so the
+// receiver returns it without counting a retry.
+func TestHandleDelivery_MaxAbandonedHandlersShutdown(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	engine := newStuckEngine(release)
+	app := NewChatbotApp(engine, &fakeReceiver{}, newMockExecutor(), AppOptions{MaxAbandonedHandlers: 1})
+
+	if err := app.HandleMessage(userOn("start"), d_message.Message{}); err != nil {
+		t.Fatalf("HandleMessage returned error: %v", err)
+	}
+
+	ack := &fakeAcknowledger{}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		app.handleDelivery(adapter_input.Delivery[TestObs]{UserState: userOn("timeout_route"), Acknowledger: ack})
+	}()
+	if err := app.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown returned error: %v", err)
+	}
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected the held delivery to give up when the app stops")
+	}
+	if ack.acked != 0 || len(ack.nacked) != 0 {
+		t.Errorf("expected the delivery to be left unsettled, got acked=%d nacked=%v", ack.acked, ack.nacked)
+	}
+}
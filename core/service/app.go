@@ -3,10 +3,18 @@
 package service
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"log"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	route_return "github.com/irissonnlima/chatgraph-go/core/domain"
 	d_action "github.com/irissonnlima/chatgraph-go/core/domain/action"
+	d_file "github.com/irissonnlima/chatgraph-go/core/domain/file"
+	d_idempotency "github.com/irissonnlima/chatgraph-go/core/domain/idempotency"
 	d_message "github.com/irissonnlima/chatgraph-go/core/domain/message"
 	d_route "github.com/irissonnlima/chatgraph-go/core/domain/route"
 	d_user "github.com/irissonnlima/chatgraph-go/core/domain/user"
@@ -14,6 +22,71 @@ import (
 	adapter_output "github.com/irissonnlima/chatgraph-go/core/ports/adapters/output"
 )
 
+// Default values for application options.
+const (
+	// DEFAULT_WORKERS is the default number of concurrent workers.
+	// A single worker processes messages one at a time, in arrival order.
+	DEFAULT_WORKERS = 1
+	// DEFAULT_WORKER_QUEUE_SIZE is the default number of deliveries queued per
+	// worker, counted towards the pool's shared capacity.
+	DEFAULT_WORKER_QUEUE_SIZE = 1
+	// DEFAULT_SHUTDOWN_TIMEOUT is the default time allowed for in-flight handlers to
+	// finish when Start shuts the application down on its own.
+	DEFAULT_SHUTDOWN_TIMEOUT = 30 * time.Second
+)
+
+// Lifecycle errors returned by Start.
+var (
+	// ErrAppAlreadyStarted is returned when Start is called more than once.
+	ErrAppAlreadyStarted = errors.New("app already started")
+	// ErrAppShutdown is returned when Start is called after Shutdown.
+	ErrAppShutdown = errors.New("app is shut down")
+)
+
+// ErrExecutorUnavailable is returned when a chat cannot be resumed because
+// the executor reports that its backend is down.
+var ErrExecutorUnavailable = errors.New("executor unavailable")
+
+// AppOptions configures how the application processes incoming messages.
+type AppOptions struct {
+	// Workers is the number of messages processed in parallel.
+	// Messages from the same ChatID are always processed by the same worker,
+	// so they keep their arrival order. Defaults to DEFAULT_WORKERS.
+	Workers int
+	// WorkerQueueSize is the number of deliveries queued per worker. The pool
+	// shares Workers*WorkerQueueSize slots across its workers, so a slow chat
+	// can queue past its worker's share without stalling other chats; the
+	// consumer only waits once every slot is taken.
+	// Defaults to DEFAULT_WORKER_QUEUE_SIZE.
+	WorkerQueueSize int
+	// ShutdownTimeout bounds the graceful shutdown that Start performs when its
+	// context is cancelled. Defaults to DEFAULT_SHUTDOWN_TIMEOUT.
+	ShutdownTimeout time.Duration
+	// UnavailableRoute, if set, handles messages while the executor reports
+	// that its backend is down (see adapter_output.IAvailabilityReporter), e.g.
+	// to tell the user the system is unavailable. The handler's result is not
+	// applied, so the user stays on their route and resumes it afterwards.
+	// The message is acknowledged even if the route fails. Requires
+	// UnavailableMessenger.
+	UnavailableRoute string
+	// UnavailableMessenger sends the messages of UnavailableRoute, since the
+	// executor cannot while it is down, e.g. a messenger talking to the
+	// channel directly. Session and file operations are not supported there.
+	UnavailableMessenger adapter_output.IMessenger
+	// MaxAbandonedHandlers caps the handlers still running after their
+	// timeout (see Engine.AbandonedHandlers). While the cap is reached,
+	// HandleMessage waits for an abandoned handler to finish before running
+	// another one, so the workers slow down and stop taking deliveries instead
+	// of piling up goroutines. Zero means no cap.
+	MaxAbandonedHandlers int
+}
+
+// prefetch returns how many unacknowledged deliveries the pool can hold:
+// one in progress plus a full queue per worker.
+func (o AppOptions) prefetch() int {
+	return o.Workers * (o.WorkerQueueSize + 1)
+}
+
 // ChatbotApp is the main application structure that manages routes and message processing.
 // It is generic over Obs, allowing custom observation data types to be used throughout
 // the application.
@@ -24,6 +97,27 @@ type ChatbotApp[Obs any] struct {
 	messageReceiver adapter_input.IMessageReceiver[Obs]
 	// botExecutor provides messaging and session management capabilities.
 	botExecutor adapter_output.IBotExecutor
+	// options holds the message processing configuration.
+	options AppOptions
+	// throttled counts the messages held back by MaxAbandonedHandlers.
+	throttled atomic.Uint64
+	// pool processes deliveries concurrently with per-chat ordering.
+	pool *workerPool[Obs]
+	// inactivity, if set, keeps the inactivity timers of chats.
+	inactivity *InactivityScheduler[Obs]
+	// handoff, if set, hands chats off to human agents and pauses them.
+	handoff *HandoffDesk[Obs]
+
+	// started is set once Start begins consuming.
+	started atomic.Bool
+	// stopCh is closed to stop the consume loop.
+	stopCh   chan struct{}
+	stopOnce sync.Once
+	// loopDone is closed when Start stops submitting deliveries.
+	loopDone chan struct{}
+	// shutdownOnce guards the shutdown sequence; shutdownErr holds its result.
+	shutdownOnce sync.Once
+	shutdownErr  error
 }
 
 /*
@@ -31,112 +125,582 @@ NewChatbotApp creates a new ChatbotApp instance with the provided adapters.
 The queueAdapter is used to consume incoming messages from the message broker.
 The routerActions provides messaging and session management capabilities.
 
-Optional defaultOptions can be provided to set default handler options.
+Optional options can be provided to configure message processing.
 
 If not provided, the following defaults are used:
-  - Timeout: 5 minutes (redirects to "timeout_route")
-  - Loop Limit: 3 iterations (redirects to "loop_route")
-  - Protected: nil (no protection by default)
+  - Workers: 1 (messages are processed one at a time)
+  - WorkerQueueSize: 1
+  - ShutdownTimeout: 30 seconds
 */
 func NewChatbotApp[Obs any](
 	engine *Engine[Obs],
 	messageReceiver adapter_input.IMessageReceiver[Obs],
 	botExecutor adapter_output.IBotExecutor,
+	options ...AppOptions,
 ) *ChatbotApp[Obs] {
-	return &ChatbotApp[Obs]{
+	opts := AppOptions{
+		Workers:         DEFAULT_WORKERS,
+		WorkerQueueSize: DEFAULT_WORKER_QUEUE_SIZE,
+		ShutdownTimeout: DEFAULT_SHUTDOWN_TIMEOUT,
+	}
+	if len(options) > 0 {
+		o := options[0]
+		if o.Workers > 0 {
+			opts.Workers = o.Workers
+		}
+		if o.WorkerQueueSize > 0 {
+			opts.WorkerQueueSize = o.WorkerQueueSize
+		}
+		if o.ShutdownTimeout > 0 {
+			opts.ShutdownTimeout = o.ShutdownTimeout
+		}
+		opts.UnavailableRoute = o.UnavailableRoute
+		opts.UnavailableMessenger = o.UnavailableMessenger
+		opts.MaxAbandonedHandlers = o.MaxAbandonedHandlers
+	}
+
+	app := &ChatbotApp[Obs]{
 		engine:          engine,
 		messageReceiver: messageReceiver,
 		botExecutor:     botExecutor,
+		options:         opts,
+		stopCh:          make(chan struct{}),
+		loopDone:        make(chan struct{}),
 	}
+	app.pool = newWorkerPool(opts.Workers, opts.WorkerQueueSize, app.handleDelivery)
+
+	return app
+}
+
+// Metrics returns a snapshot of the worker pool (queue depth and busy
+// workers) and of the abandoned handlers.
+func (app *ChatbotApp[Obs]) Metrics() PoolMetrics {
+	metrics := app.pool.metrics()
+	metrics.AbandonedHandlers, metrics.AbandonedTotal = app.engine.abandoned.count()
+	metrics.Throttled = app.throttled.Load()
+	return metrics
 }
 
 // HandleMessage processes an incoming message by finding and executing
 // the appropriate route handler based on the user's current route.
 // Returns an error if no handler is registered for the route or if
 // the handler returns an error.
-func (app *ChatbotApp[Obs]) HandleMessage(userState d_user.UserState[Obs], message d_message.Message) (err error) {
-	result, err := app.engine.Execute(userState, message, app.botExecutor)
+//
+// Messages with an ID are handled within an idempotency scope (see
+// d_idempotency), so every outbound action, including the ones applying the
+// handler's result, gets a key that is the same when the message is redelivered.
+//
+// While AppOptions.MaxAbandonedHandlers is reached, the message waits for an
+// abandoned handler to finish; it is rejected with ErrTooManyAbandonedHandlers
+// if the app stops meanwhile. Accepted messages cancel the chat's
+// inactivity timer (see InactivityScheduler). Messages of chats handed off
+// to an agent are relayed to the agent console instead (see HandoffDesk).
+func (app *ChatbotApp[Obs]) HandleMessage(userState d_user.UserState[Obs], message d_message.Message) error {
+	if err := app.admit(context.Background(), userState.ChatID); err != nil {
+		return err
+	}
+	if app.inactivity != nil {
+		app.inactivity.cancel(userState.ChatID)
+	}
+	if app.handoff != nil {
+		if handled, err := app.handoff.intercept(userState, message); handled {
+			return err
+		}
+	}
+	return app.handle(context.Background(), userState, message)
+}
+
+// admit waits while AppOptions.MaxAbandonedHandlers is reached. Returns
+// ErrTooManyAbandonedHandlers when ctx is done or the app stops first.
+func (app *ChatbotApp[Obs]) admit(ctx context.Context, chatID d_user.ChatID) error {
+	limit := app.options.MaxAbandonedHandlers
+	if limit <= 0 {
+		return nil
+	}
+	abandoned, _ := app.engine.abandoned.count()
+	if abandoned < limit {
+		return nil
+	}
+
+	app.throttled.Add(1)
+	log.Printf("[WARN] Holding work for chat %v: %d handlers abandoned", chatID, abandoned)
+	if !app.engine.abandoned.waitBelow(ctx, app.stopCh, limit) {
+		return ErrTooManyAbandonedHandlers
+	}
+	return nil
+}
+
+// handle processes a message accepted by HandleMessage, redirected to
+// another route while handling it, or resumed. The handler's context is
+// derived from parent.
+func (app *ChatbotApp[Obs]) handle(
+	parent context.Context,
+	userState d_user.UserState[Obs],
+	message d_message.Message,
+) error {
+	// A redirect keeps numbering the actions of the message it handles, under
+	// the route the message started on.
+	ctx := parent
+	if d_idempotency.ScopeFrom(parent) == nil {
+		scope := d_idempotency.NewScope(userState.ChatID, message.TextMessage.ID, userState.Route.Current())
+		ctx = d_idempotency.WithScope(parent, scope)
+	}
+
+	if app.options.UnavailableRoute != "" && !app.available() {
+		return app.handleUnavailable(ctx, userState, message)
+	}
+
+	result, err := app.engine.execute(ctx, userState, message, app.botExecutor)
 	if err != nil {
 		return err
 	}
 
-	app.handleResult(userState, message, result)
+	app.handleResult(ctx, bindExecutor(ctx, app.botExecutor), userState, message, result)
 	return nil
 }
 
+// resume proactively executes route for the chat of userState, as if the
+// user had been redirected to it, with message standing for the event that
+// resumed the chat. Its ID scopes the idempotency keys, so resuming again
+// after a failure does not repeat actions. Unlike HandleMessage, the chat is
+// not resumed while the executor is unavailable: ErrExecutorUnavailable is
+// returned instead, so the caller retries later. Chats handed off to an agent
+// are not resumed either: ErrChatHandedOff is returned.
+func (app *ChatbotApp[Obs]) resume(
+	parent context.Context,
+	userState d_user.UserState[Obs],
+	route string,
+	message d_message.Message,
+) error {
+	if !app.available() {
+		return ErrExecutorUnavailable
+	}
+	handedOff, err := app.handedOff(userState.ChatID)
+	if err != nil {
+		return err
+	}
+	if handedOff {
+		return ErrChatHandedOff
+	}
+
+	userState.Route = userState.Route.Next(route)
+	return app.handle(parent, userState, message)
+}
+
+// bindExecutor binds executor to ctx when it supports it.
+func bindExecutor(ctx context.Context, executor adapter_output.IBotExecutor) adapter_output.IBotExecutor {
+	if binder, ok := executor.(adapter_output.IContextBinder); ok {
+		return binder.WithContext(ctx)
+	}
+	return executor
+}
+
+// available reports whether the executor's backend is up.
+// Executors that cannot tell are always considered available.
+func (app *ChatbotApp[Obs]) available() bool {
+	reporter, ok := app.botExecutor.(adapter_output.IAvailabilityReporter)
+	return !ok || reporter.Available()
+}
+
+// handleUnavailable runs the unavailable route for a message received while
+// the executor is down, sending through AppOptions.UnavailableMessenger. Its
+// result is discarded: the stored route is left untouched, since the executor
+// could not persist it anyway. Failures are only logged, so the message is
+// acknowledged rather than redelivered while the executor is still down.
+// While the unavailable route is closed (see BusinessHoursRouteOps), its
+// after-hours route runs instead.
+func (app *ChatbotApp[Obs]) handleUnavailable(
+	ctx context.Context,
+	userState d_user.UserState[Obs],
+	message d_message.Message,
+) error {
+	if app.options.UnavailableMessenger == nil {
+		return ErrExecutorUnavailable
+	}
+
+	log.Printf("[WARN] Executor unavailable, handling chat %v with route %s",
+		userState.ChatID, app.options.UnavailableRoute)
+
+	userState.Route = userState.Route.Next(app.options.UnavailableRoute)
+	if route, closed := app.engine.afterHours(userState); closed {
+		userState.Route = userState.Route.Next(route)
+	}
+	executor := fallbackExecutor{IMessenger: app.options.UnavailableMessenger}
+	if _, err := app.engine.run(ctx, userState, message, executor); err != nil {
+		log.Printf("[ERROR] Unavailable route failed for chat %v: %v", userState.ChatID, err)
+	}
+	return nil
+}
+
+// fallbackExecutor sends messages through a messenger and supports nothing
+// else. It runs the unavailable route while the executor is down.
+type fallbackExecutor struct {
+	adapter_output.IMessenger
+}
+
+func (fallbackExecutor) SetObservation(d_user.ChatID, string) error {
+	return adapter_output.ErrUnsupported
+}
+
+func (fallbackExecutor) SetRoute(d_user.ChatID, string) error {
+	return adapter_output.ErrUnsupported
+}
+
+func (fallbackExecutor) EndSession(d_user.ChatID, string) error {
+	return adapter_output.ErrUnsupported
+}
+
+func (fallbackExecutor) TransferToMenu(d_user.ChatID, d_action.TransferToMenu, d_message.Message) error {
+	return adapter_output.ErrUnsupported
+}
+
+func (fallbackExecutor) UploadFile(string) (*d_file.File, error) {
+	return nil, adapter_output.ErrUnsupported
+}
+
+func (fallbackExecutor) GetFile(string) (*d_file.File, error) {
+	return nil, adapter_output.ErrUnsupported
+}
+
 // handleRedirect processes a redirect action by executing the target route.
 func (app *ChatbotApp[Obs]) handleRedirect(
+	ctx context.Context,
+	executor adapter_output.IBotExecutor,
 	userState d_user.UserState[Obs],
 	message d_message.Message,
 	redirect d_action.RedirectResponse,
 ) error {
-	err := app.botExecutor.SetRoute(userState.ChatID, redirect.TargetRoute)
+	err := executor.SetRoute(userState.ChatID, redirect.TargetRoute)
 	if err != nil {
 		log.Printf("[ERROR] Failed to set route for chat %v: %v", userState.ChatID, err)
 	}
 	// Update user state with new route
 	userState.Route = userState.Route.Next(redirect.TargetRoute)
-	return app.HandleMessage(userState, message)
+	return app.handle(ctx, userState, message)
 }
 
-// handleResult processes the result of a route handler.
+// handleResult processes the result of a route handler with executor.
+// Redirects are handled within ctx. The inactivity timer of the chat is set
+// for the route the user is left on, or cancelled when the session ends.
 func (app *ChatbotApp[Obs]) handleResult(
+	ctx context.Context,
+	executor adapter_output.IBotExecutor,
 	userState d_user.UserState[Obs],
 	message d_message.Message,
 	result route_return.RouteReturn,
 ) {
 	chatID := userState.ChatID
 	var err error
+	// waiting is the route the user is left on, if any.
+	var waiting *d_route.Route
+	redirected := false
 
+	// Handlers may return actions either by value or by pointer.
 	switch r := result.(type) {
 	case *d_action.EndAction:
-		err = app.botExecutor.EndSession(chatID, r.ID)
+		err = executor.EndSession(chatID, r.ID)
+	case d_action.EndAction:
+		err = executor.EndSession(chatID, r.ID)
 
 	case *d_action.RedirectResponse:
-		err = app.handleRedirect(userState, message, *r)
+		err = app.handleRedirect(ctx, executor, userState, message, *r)
+		redirected = true
+	case d_action.RedirectResponse:
+		err = app.handleRedirect(ctx, executor, userState, message, r)
+		redirected = true
 
 	case *d_action.TransferToMenu:
-		err = app.botExecutor.TransferToMenu(chatID, *r, message)
+		err = executor.TransferToMenu(chatID, *r, message)
+	case d_action.TransferToMenu:
+		err = executor.TransferToMenu(chatID, r, message)
+
+	case *d_action.HandoffToAgent:
+		err = app.handleHandoff(executor, userState, message, *r)
+	case d_action.HandoffToAgent:
+		err = app.handleHandoff(executor, userState, message, r)
 
 	case *d_route.Route:
-		err = app.botExecutor.SetRoute(chatID, r.Current())
+		err = executor.SetRoute(chatID, r.Current())
+		waiting = r
+	case d_route.Route:
+		err = executor.SetRoute(chatID, r.Current())
+		waiting = &r
 
 	case nil:
-		err = app.botExecutor.SetRoute(chatID, userState.Route.Current())
+		err = executor.SetRoute(chatID, userState.Route.Current())
+		waiting = &userState.Route
 
 	default:
 		log.Printf("[WARN] Unhandled route return type for chat %v: %T", chatID, r)
-		err = app.botExecutor.SetRoute(chatID, userState.Route.Current())
+		err = executor.SetRoute(chatID, userState.Route.Current())
+		waiting = &userState.Route
 	}
 
 	if err != nil {
 		log.Printf("[ERROR] Failed to handle result for chat %v: %v", chatID, err)
 	}
+
+	// Redirected routes handle their own results.
+	if app.inactivity != nil && !redirected {
+		if waiting != nil {
+			app.inactivity.track(userState, *waiting)
+		} else {
+			app.inactivity.cancel(chatID)
+		}
+	}
+}
+
+// handleHandoff hands the chat off to an agent with the HandoffDesk. The
+// user is left on the current route, so the stored session stays where the
+// user was, including when the chat cannot be handed off.
+func (app *ChatbotApp[Obs]) handleHandoff(
+	executor adapter_output.IBotExecutor,
+	userState d_user.UserState[Obs],
+	message d_message.Message,
+	handoff d_action.HandoffToAgent,
+) error {
+	if err := executor.SetRoute(userState.ChatID, userState.Route.Current()); err != nil {
+		log.Printf("[ERROR] Failed to set route for chat %v: %v", userState.ChatID, err)
+	}
+	if app.handoff == nil {
+		return errors.New("handoff requested but no HandoffDesk was created")
+	}
+	return app.handoff.open(executor, userState, message, handoff)
 }
 
 // checkHealthRoutes validates the registered routes before starting the application.
 func (app *ChatbotApp[Obs]) checkHealthRoutes() error {
-	return app.engine.ValidateRoutes()
+	if err := app.engine.ValidateRoutes(); err != nil {
+		return err
+	}
+
+	if route := app.options.UnavailableRoute; route != "" {
+		if _, exists := app.engine.routes[route]; !exists {
+			return fmt.Errorf("unavailable route '%s' is not registered", route)
+		}
+		if app.options.UnavailableMessenger == nil {
+			return fmt.Errorf("unavailable route '%s' requires an UnavailableMessenger", route)
+		}
+	}
+
+	if app.handoff != nil {
+		route := app.handoff.options.ResumeRoute
+		if _, exists := app.engine.routes[route]; !exists {
+			return fmt.Errorf("handoff resume route '%s' is not registered", route)
+		}
+	}
+
+	if app.inactivity == nil {
+		for routeName, handler := range app.engine.routes {
+			if handler.HandlerOptions.Inactivity != nil {
+				log.Printf("[WARN] Route '%s' has inactivity options but no InactivityScheduler was created", routeName)
+			}
+		}
+	}
+	return nil
+}
+
+// onChat runs fn on the worker owning the chat, behind the deliveries queued
+// for it, so it does not overlap with the chat's messages, and waits for it.
+// fn is skipped with ctx's error when ctx is done before its turn. Before
+// Start, or once the pool stopped, fn runs on the calling goroutine.
+//
+// onChat must not be called from a worker (e.g. synchronously from a
+// handler), since the worker would wait for itself.
+func (app *ChatbotApp[Obs]) onChat(ctx context.Context, chatID d_user.ChatID, fn func() error) error {
+	var err error
+	done := make(chan struct{})
+	task := func() {
+		defer close(done)
+		if err = ctx.Err(); err == nil {
+			err = fn()
+		}
+	}
+	if !app.pool.do(chatID, task) {
+		return fn()
+	}
+	<-done
+	return err
 }
 
-// Start begins consuming messages from the message receiver in an infinite loop.
-// It only returns an error in case of a critical failure.
-// Non-critical errors from HandleMessage are logged but do not stop the consumer.
-func (app *ChatbotApp[Obs]) Start() error {
+// handleDelivery processes a single delivery and settles it with the receiver.
+// Successfully handled deliveries are acknowledged; failures are negatively
+// acknowledged so the receiver can redeliver or dead-letter them.
+func (app *ChatbotApp[Obs]) handleDelivery(delivery adapter_input.Delivery[Obs]) {
+	userState, err := app.currentState(delivery.UserState)
+	if err == nil {
+		err = app.HandleMessage(userState, delivery.Message)
+	}
+	if errors.Is(err, ErrTooManyAbandonedHandlers) {
+		// The app is stopping: closing the receiver returns the delivery to
+		// its source, without counting a failed attempt as a nack would.
+		log.Printf("[WARN] Leaving message of chat %v unsettled: the app is stopping", delivery.UserState.ChatID)
+		return
+	}
+	if err != nil {
+		log.Printf("[ERROR] Failed to handle message: %v", err)
+		if nackErr := delivery.Nack(err); nackErr != nil {
+			log.Printf("[ERROR] Failed to nack message for chat %v: %v", delivery.UserState.ChatID, nackErr)
+		}
+		return
+	}
+
+	if ackErr := delivery.Ack(); ackErr != nil {
+		log.Printf("[ERROR] Failed to ack message for chat %v: %v", delivery.UserState.ChatID, ackErr)
+	}
+}
+
+// currentState returns the stored state of the delivery's chat when the
+// executor keeps it (see adapter_output.IStateLoader), keeping the user and
+// platform reported by the delivery. Loading it right before the handler runs
+// lets a delivery queued behind other messages of its chat see the changes
+// they made. Otherwise the state carried by the delivery is returned.
+func (app *ChatbotApp[Obs]) currentState(incoming d_user.UserState[Obs]) (d_user.UserState[Obs], error) {
+	if incoming.ChatID.IsEmpty() {
+		return incoming, nil
+	}
+
+	stored, found, err := app.loadState(incoming.ChatID)
+	if err != nil {
+		return incoming, fmt.Errorf("loading chat state: %w", err)
+	}
+	if !found {
+		return incoming, nil
+	}
+
+	if !incoming.User.IsEmpty() {
+		stored.User = incoming.User
+	}
+	if incoming.Platform != "" {
+		stored.Platform = incoming.Platform
+	}
+	return stored, nil
+}
+
+// Start begins consuming messages from the message receiver until ctx is cancelled,
+// Shutdown is called, or the receiver closes its channel.
+// Messages are processed by the worker pool configured through AppOptions.
+// Each delivery is acknowledged after it is handled successfully and negatively
+// acknowledged when HandleMessage fails; failures never stop the consumer.
+// Messages still held by MaxAbandonedHandlers when the app stops are left
+// unsettled, so closing the receiver returns them to their source.
+//
+// When ctx is cancelled or the receiver channel closes, Start performs a graceful
+// shutdown bounded by AppOptions.ShutdownTimeout before returning. When Shutdown
+// is called explicitly, Start returns as soon as consumption stops and the
+// caller of Shutdown waits for the drain.
+func (app *ChatbotApp[Obs]) Start(ctx context.Context) error {
 	if err := app.checkHealthRoutes(); err != nil {
 		log.Printf("[ERROR] Failed to setup routes: %v", err)
 		return err
 	}
 
-	messages := app.messageReceiver.ConsumeMessage()
+	if !app.started.CompareAndSwap(false, true) {
+		select {
+		case <-app.stopCh:
+			return ErrAppShutdown
+		default:
+			return ErrAppAlreadyStarted
+		}
+	}
 
-	for msg := range messages {
-		err := app.HandleMessage(msg.UserState, msg.Message)
-		if err != nil {
-			log.Printf("[ERROR] Failed to handle message: %v", err)
+	if setter, ok := app.messageReceiver.(adapter_input.IPrefetchSetter); ok {
+		if err := setter.SetPrefetch(app.options.prefetch()); err != nil {
+			log.Printf("[WARN] Failed to set receiver prefetch: %v", err)
 		}
 	}
 
-	log.Println("[CRITICAL] Message channel closed unexpectedly")
-	return nil
+	app.pool.start()
+	stopRequested := app.consume(ctx, app.messageReceiver.ConsumeMessage())
+	close(app.loopDone)
+
+	if stopRequested {
+		return nil
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), app.options.ShutdownTimeout)
+	defer cancel()
+	return app.Shutdown(shutdownCtx)
+}
+
+// consume submits deliveries to the worker pool until ctx is cancelled,
+// Shutdown is called, or the deliveries channel closes.
+// Returns true if consumption stopped because Shutdown was called.
+func (app *ChatbotApp[Obs]) consume(ctx context.Context, deliveries <-chan adapter_input.Delivery[Obs]) bool {
+	for {
+		select {
+		case <-app.stopCh:
+			log.Println("[INFO] Shutdown requested, stopped consuming messages")
+			return true
+
+		case <-ctx.Done():
+			log.Println("[INFO] Context cancelled, shutting down")
+			return false
+
+		case delivery, ok := <-deliveries:
+			if !ok {
+				log.Println("[CRITICAL] Message channel closed unexpectedly")
+				return false
+			}
+			app.pool.submit(delivery)
+		}
+	}
+}
+
+// Shutdown gracefully stops the application:
+//  1. stops consuming new messages;
+//  2. waits for in-flight and queued handlers to finish, until ctx expires;
+//  3. closes the message receiver, releasing its broker resources.
+//
+// Deliveries that were not acknowledged before the receiver is closed are
+// returned to the broker. Shutdown is idempotent: subsequent calls return the
+// result of the first one.
+func (app *ChatbotApp[Obs]) Shutdown(ctx context.Context) error {
+	app.shutdownOnce.Do(func() {
+		app.shutdownErr = app.shutdown(ctx)
+	})
+	return app.shutdownErr
+}
+
+// shutdown performs the shutdown sequence described in Shutdown.
+func (app *ChatbotApp[Obs]) shutdown(ctx context.Context) error {
+	app.stopOnce.Do(func() { close(app.stopCh) })
+
+	// Claiming the started flag prevents a later Start from consuming.
+	neverStarted := app.started.CompareAndSwap(false, true)
+
+	var errs []error
+
+	// The receiver is closed even when the deadline passes, so its broker
+	// resources are released and unacknowledged deliveries are returned.
+	if !neverStarted {
+		select {
+		case <-app.loopDone:
+		case <-ctx.Done():
+			log.Println("[ERROR] Shutdown deadline exceeded while waiting for the consumer to stop")
+			errs = append(errs, fmt.Errorf("waiting for consumer to stop: %w", ctx.Err()))
+		}
+	}
+
+	drained := make(chan struct{})
+	go func() {
+		app.pool.stop()
+		close(drained)
+	}()
+
+	select {
+	case <-drained:
+		log.Println("[INFO] All in-flight handlers finished")
+	case <-ctx.Done():
+		metrics := app.pool.metrics()
+		log.Printf("[ERROR] Shutdown deadline exceeded with %d busy workers and %d queued messages",
+			metrics.BusyWorkers, metrics.QueueDepth)
+		errs = append(errs, fmt.Errorf("draining handlers: %w", ctx.Err()))
+	}
+
+	if err := app.messageReceiver.Close(); err != nil {
+		errs = append(errs, fmt.Errorf("closing receiver: %w", err))
+	}
+
+	return errors.Join(errs...)
 }
@@ -0,0 +1,688 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	route_return "github.com/irissonnlima/chatgraph-go/core/domain"
+	d_action "github.com/irissonnlima/chatgraph-go/core/domain/action"
+	d_context "github.com/irissonnlima/chatgraph-go/core/domain/context"
+	d_idempotency "github.com/irissonnlima/chatgraph-go/core/domain/idempotency"
+	d_message "github.com/irissonnlima/chatgraph-go/core/domain/message"
+	d_route "github.com/irissonnlima/chatgraph-go/core/domain/route"
+	d_router "github.com/irissonnlima/chatgraph-go/core/domain/router"
+	d_session "github.com/irissonnlima/chatgraph-go/core/domain/session"
+	d_user "github.com/irissonnlima/chatgraph-go/core/domain/user"
+	adapter_input "github.com/irissonnlima/chatgraph-go/core/ports/adapters/input"
+	adapter_output "github.com/irissonnlima/chatgraph-go/core/ports/adapters/output"
+)
+
+// fakeAcknowledger records how a delivery was settled.
+type fakeAcknowledger struct {
+	mu     sync.Mutex
+	acked  int
+	nacked []error
+}
+
+func (a *fakeAcknowledger) Ack() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.acked++
+	return nil
+}
+
+func (a *fakeAcknowledger) Nack(reason error) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.nacked = append(a.nacked, reason)
+	return nil
+}
+
+// fakeReceiver yields a fixed list of deliveries. Unless keepOpen is set,
+// its channel is closed right after the deliveries; otherwise it is closed by Close.
+type fakeReceiver struct {
+	deliveries []adapter_input.Delivery[TestObs]
+	keepOpen   bool
+	prefetch   int
+	closed     atomic.Int32
+	out        chan adapter_input.Delivery[TestObs]
+}
+
+func (r *fakeReceiver) ConsumeMessage() <-chan adapter_input.Delivery[TestObs] {
+	r.out = make(chan adapter_input.Delivery[TestObs], len(r.deliveries))
+	for _, d := range r.deliveries {
+		r.out <- d
+	}
+	if !r.keepOpen {
+		close(r.out)
+	}
+	return r.out
+}
+
+func (r *fakeReceiver) Close() error {
+	if r.closed.Add(1) == 1 && r.keepOpen {
+		close(r.out)
+	}
+	return nil
+}
+
+func (r *fakeReceiver) SetPrefetch(count int) error {
+	r.prefetch = count
+	return nil
+}
+
+func newTestApp(receiver adapter_input.IMessageReceiver[TestObs], options ...AppOptions) *ChatbotApp[TestObs] {
+	return newTestAppWithHandler(receiver, func(ctx *d_context.ChatContext[TestObs]) route_return.RouteReturn {
+		return nil
+	}, options...)
+}
+
+func newTestAppWithHandler(
+	receiver adapter_input.IMessageReceiver[TestObs],
+	handler d_router.RouteHandler[TestObs],
+	options ...AppOptions,
+) *ChatbotApp[TestObs] {
+	return newTestAppWithRoutes(receiver, newMockExecutor(), map[string]d_router.RouteHandler[TestObs]{
+		"start":         handler,
+		"timeout_route": handler,
+		"loop_route":    handler,
+	}, options...)
+}
+
+// newTestAppWithRoutes returns an app on executor with the given routes, and
+// a "start" route doing nothing unless routes has one.
+func newTestAppWithRoutes(
+	receiver adapter_input.IMessageReceiver[TestObs],
+	executor adapter_output.IBotExecutor,
+	routes map[string]d_router.RouteHandler[TestObs],
+	options ...AppOptions,
+) *ChatbotApp[TestObs] {
+	engine := NewEngine[TestObs]()
+	engine.RegisterRoute("start", func(ctx *d_context.ChatContext[TestObs]) route_return.RouteReturn {
+		return nil
+	})
+	for name, handler := range routes {
+		engine.RegisterRoute(name, handler)
+	}
+
+	return NewChatbotApp(engine, receiver, executor, options...)
+}
+
+// TestNewChatbotApp_DefaultOptions tests the default application options.
+func TestNewChatbotApp_DefaultOptions(t *testing.T) {
+	app := newTestApp(&fakeReceiver{})
+
+	if app.options.Workers != DEFAULT_WORKERS {
+		t.Errorf("expected %d workers, got %d", DEFAULT_WORKERS, app.options.Workers)
+	}
+	if app.Metrics().Workers != DEFAULT_WORKERS {
+		t.Errorf("expected metrics to report %d workers, got %d", DEFAULT_WORKERS, app.Metrics().Workers)
+	}
+}
+
+// TestStart_AcksAndNacks tests that deliveries are settled according to the handling result.
+func TestStart_AcksAndNacks(t *testing.T) {
+	ok := &fakeAcknowledger{}
+	missing := &fakeAcknowledger{}
+
+	okDelivery := newTestDelivery("a", "hi")
+	okDelivery.UserState.Route = d_route.NewRoute("start", '.')
+	okDelivery.Acknowledger = ok
+
+	missingDelivery := newTestDelivery("b", "hi")
+	missingDelivery.UserState.Route = d_route.NewRoute("nonexistent", '.')
+	missingDelivery.Acknowledger = missing
+
+	receiver := &fakeReceiver{deliveries: []adapter_input.Delivery[TestObs]{okDelivery, missingDelivery}}
+	app := newTestApp(receiver, AppOptions{Workers: 3, WorkerQueueSize: 4})
+
+	if err := app.Start(context.Background()); err != nil {
+		t.Fatalf("Start returned error: %v", err)
+	}
+
+	if ok.acked != 1 || len(ok.nacked) != 0 {
+		t.Errorf("expected successful delivery to be acked once, got acked=%d nacked=%d", ok.acked, len(ok.nacked))
+	}
+	if missing.acked != 0 || len(missing.nacked) != 1 {
+		t.Errorf("expected failed delivery to be nacked once, got acked=%d nacked=%d", missing.acked, len(missing.nacked))
+	}
+	if len(missing.nacked) == 1 && missing.nacked[0] == nil {
+		t.Error("expected nack reason to be set")
+	}
+	if receiver.prefetch != 15 {
+		t.Errorf("expected prefetch 15, got %d", receiver.prefetch)
+	}
+	if app.Metrics().Processed != 2 {
+		t.Errorf("expected 2 processed deliveries, got %d", app.Metrics().Processed)
+	}
+	if receiver.closed.Load() != 1 {
+		t.Errorf("expected receiver to be closed once, got %d", receiver.closed.Load())
+	}
+}
+
+// TestStart_ContextCancelDrainsHandlers tests that cancelling Start waits for in-flight handlers.
+func TestStart_ContextCancelDrainsHandlers(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	var finished atomic.Bool
+
+	ack := &fakeAcknowledger{}
+	delivery := newTestDelivery("a", "hi")
+	delivery.UserState.Route = d_route.NewRoute("start", '.')
+	delivery.Acknowledger = ack
+
+	receiver := &fakeReceiver{deliveries: []adapter_input.Delivery[TestObs]{delivery}, keepOpen: true}
+	app := newTestAppWithHandler(receiver, func(ctx *d_context.ChatContext[TestObs]) route_return.RouteReturn {
+		close(started)
+		<-release
+		finished.Store(true)
+		return nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errChan := make(chan error, 1)
+	go func() { errChan <- app.Start(ctx) }()
+
+	<-started
+	cancel()
+
+	select {
+	case err := <-errChan:
+		t.Fatalf("Start returned before the handler finished: %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+	if err := <-errChan; err != nil {
+		t.Fatalf("Start returned error: %v", err)
+	}
+	if !finished.Load() {
+		t.Error("expected handler to finish before Start returned")
+	}
+	if ack.acked != 1 {
+		t.Errorf("expected delivery to be acked, got %d", ack.acked)
+	}
+	if receiver.closed.Load() != 1 {
+		t.Errorf("expected receiver to be closed once, got %d", receiver.closed.Load())
+	}
+}
+
+// TestShutdown_DeadlineExceeded tests that Shutdown gives up on slow handlers at its deadline.
+func TestShutdown_DeadlineExceeded(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	defer close(release)
+
+	delivery := newTestDelivery("a", "hi")
+	delivery.UserState.Route = d_route.NewRoute("start", '.')
+
+	receiver := &fakeReceiver{deliveries: []adapter_input.Delivery[TestObs]{delivery}, keepOpen: true}
+	app := newTestAppWithHandler(receiver, func(ctx *d_context.ChatContext[TestObs]) route_return.RouteReturn {
+		close(started)
+		<-release
+		return nil
+	})
+
+	errChan := make(chan error, 1)
+	go func() { errChan <- app.Start(context.Background()) }()
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	err := app.Shutdown(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected deadline exceeded, got %v", err)
+	}
+	if startErr := <-errChan; startErr != nil {
+		t.Errorf("Start returned error: %v", startErr)
+	}
+	if receiver.closed.Load() != 1 {
+		t.Errorf("expected receiver to be closed once, got %d", receiver.closed.Load())
+	}
+	if again := app.Shutdown(context.Background()); !errors.Is(again, context.DeadlineExceeded) {
+		t.Errorf("expected Shutdown to be idempotent, got %v", again)
+	}
+}
+
+// TestShutdown_ConsumerStuckClosesReceiver tests that Shutdown still closes the
+// receiver when the consumer does not stop before the deadline.
+func TestShutdown_ConsumerStuckClosesReceiver(t *testing.T) {
+	started := make(chan struct{}, 3)
+	release := make(chan struct{})
+	defer close(release)
+
+	// One delivery in progress, one queued and one blocking the consumer.
+	var deliveries []adapter_input.Delivery[TestObs]
+	for i := 0; i < 3; i++ {
+		delivery := newTestDelivery("a", "hi")
+		delivery.UserState.Route = d_route.NewRoute("start", '.')
+		deliveries = append(deliveries, delivery)
+	}
+
+	receiver := &fakeReceiver{deliveries: deliveries, keepOpen: true}
+	app := newTestAppWithHandler(receiver, func(ctx *d_context.ChatContext[TestObs]) route_return.RouteReturn {
+		started <- struct{}{}
+		<-release
+		return nil
+	}, AppOptions{Workers: 1, WorkerQueueSize: 1})
+
+	go app.Start(context.Background())
+	<-started
+	// Wait until the consumer took the last delivery and blocks submitting it.
+	for len(receiver.out) > 0 || app.Metrics().QueueDepth < 1 {
+		time.Sleep(time.Millisecond)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	if err := app.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected deadline exceeded, got %v", err)
+	}
+	if receiver.closed.Load() != 1 {
+		t.Errorf("expected receiver to be closed once, got %d", receiver.closed.Load())
+	}
+}
+
+// TestHandleDelivery_LoadsCurrentState tests that deliveries run on the state
+// kept by the executor rather than the one they were queued with.
+func TestHandleDelivery_LoadsCurrentState(t *testing.T) {
+	var got d_user.UserState[TestObs]
+	receiver := &fakeReceiver{}
+	app := newTestAppWithHandler(receiver, func(ctx *d_context.ChatContext[TestObs]) route_return.RouteReturn {
+		got = ctx.UserState
+		return nil
+	})
+
+	delivery := newTestDelivery("a", "hi")
+	delivery.UserState.Route = d_route.NewRoute("start", '.')
+	delivery.UserState.Platform = "telegram"
+	ack := &fakeAcknowledger{}
+	delivery.Acknowledger = ack
+
+	app.botExecutor = &stateExecutor{mockExecutor: newMockExecutor(), sessions: map[d_user.ChatID]d_session.Session{
+		delivery.UserState.ChatID: {ChatID: delivery.UserState.ChatID, Route: "start.loop_route", Platform: "whatsapp"},
+	}}
+
+	app.handleDelivery(delivery)
+
+	if ack.acked != 1 {
+		t.Fatalf("expected delivery to be acked, got acked=%d nacked=%v", ack.acked, ack.nacked)
+	}
+	if got.Route.Current() != "loop_route" {
+		t.Errorf("expected the stored route loop_route, got %q", got.Route.Current())
+	}
+	if got.Platform != "telegram" {
+		t.Errorf("expected the delivery's platform to be kept, got %q", got.Platform)
+	}
+}
+
+// TestStart_AfterShutdown tests that a shut down app cannot be started.
+func TestStart_AfterShutdown(t *testing.T) {
+	receiver := &fakeReceiver{keepOpen: true}
+	app := newTestApp(receiver)
+	receiver.ConsumeMessage()
+
+	if err := app.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown returned error: %v", err)
+	}
+	if err := app.Start(context.Background()); !errors.Is(err, ErrAppShutdown) {
+		t.Errorf("expected ErrAppShutdown, got %v", err)
+	}
+}
+
+// TestDelivery_NilAcknowledger tests that deliveries without acknowledger are no-ops.
+func TestDelivery_NilAcknowledger(t *testing.T) {
+	d := newTestDelivery("a", "hi")
+
+	if err := d.Ack(); err != nil {
+		t.Errorf("Ack returned error: %v", err)
+	}
+	if err := d.Nack(errors.New("failed")); err != nil {
+		t.Errorf("Nack returned error: %v", err)
+	}
+}
+
+// TestHandleMessage_ValueReturns tests that actions returned by value are handled like pointers.
+func TestHandleMessage_ValueReturns(t *testing.T) {
+	tests := []struct {
+		name     string
+		result   route_return.RouteReturn
+		expected ExpectedAction
+	}{
+		{
+			name:     "route value",
+			result:   d_route.NewRoute("start.next", '.'),
+			expected: ExpectedAction{Type: ExecSetRoute, Route: "next"},
+		},
+		{
+			name:     "route pointer",
+			result:   func() *d_route.Route { r := d_route.NewRoute("start.next", '.'); return &r }(),
+			expected: ExpectedAction{Type: ExecSetRoute, Route: "next"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := NewEngine[TestObs]()
+			engine.RegisterRoute("start", func(ctx *d_context.ChatContext[TestObs]) route_return.RouteReturn {
+				return tt.result
+			})
+			executor := newMockExecutor()
+			app := NewChatbotApp(engine, &fakeReceiver{}, executor)
+
+			delivery := newTestDelivery("a", "hi")
+			delivery.UserState.Route = d_route.NewRoute("start", '.')
+			if err := app.HandleMessage(delivery.UserState, delivery.Message); err != nil {
+				t.Fatalf("HandleMessage returned error: %v", err)
+			}
+
+			if len(executor.expectedExec) != 1 {
+				t.Fatalf("expected 1 action, got %d", len(executor.expectedExec))
+			}
+			got := executor.expectedExec[0]
+			if got.Type != tt.expected.Type || got.Route != tt.expected.Route {
+				t.Errorf("expected %+v, got %+v", tt.expected, got)
+			}
+		})
+	}
+}
+
+// unavailableExecutor is a mock executor whose backend is down.
+type unavailableExecutor struct {
+	*mockExecutor
+	available bool
+}
+
+func (e *unavailableExecutor) Available() bool {
+	return e.available
+}
+
+// SendMessage refuses to send while the backend is down, like an open breaker.
+func (e *unavailableExecutor) SendMessage(to d_user.ChatID, message d_message.Message, platform string) error {
+	if !e.available {
+		return errCircuitOpen
+	}
+	return e.mockExecutor.SendMessage(to, message, platform)
+}
+
+var errCircuitOpen = errors.New("circuit open")
+
+// LoadState keeps no state for any chat.
+func (e *unavailableExecutor) LoadState(d_user.ChatID) (d_session.Session, bool, error) {
+	return d_session.Session{}, false, nil
+}
+
+// TestHandleMessage_UnavailableRoute tests that messages are handled by the
+// unavailable route, without changing the stored route, while the executor is down.
+func TestHandleMessage_UnavailableRoute(t *testing.T) {
+	var handled []string
+	engine := NewEngine[TestObs]()
+	engine.RegisterRoute("start", func(ctx *d_context.ChatContext[TestObs]) route_return.RouteReturn {
+		handled = append(handled, "start")
+		return ctx.NextRoute("next")
+	})
+	engine.RegisterRoute("unavailable", func(ctx *d_context.ChatContext[TestObs]) route_return.RouteReturn {
+		handled = append(handled, ctx.GetRoute().Current())
+		ctx.SendTextMessage("We are offline")
+		return ctx.NextRoute("next")
+	})
+	executor := &unavailableExecutor{mockExecutor: newMockExecutor()}
+	fallback := newMockExecutor()
+	app := NewChatbotApp(engine, &fakeReceiver{}, executor, AppOptions{
+		UnavailableRoute:     "unavailable",
+		UnavailableMessenger: fallback,
+	})
+
+	delivery := newTestDelivery("a", "hi")
+	delivery.UserState.Route = d_route.NewRoute("start", '.')
+
+	if err := app.HandleMessage(delivery.UserState, delivery.Message); err != nil {
+		t.Fatalf("HandleMessage returned error: %v", err)
+	}
+	if len(handled) != 1 || handled[0] != "unavailable" {
+		t.Fatalf("expected the unavailable route to handle the message, got %v", handled)
+	}
+	if texts := sentTexts(fallback); len(texts) != 1 || texts[0] != "We are offline" {
+		t.Errorf("expected the fallback messenger to send the reply, got %v", texts)
+	}
+	if len(executor.expectedExec) != 0 {
+		t.Errorf("expected the result to be discarded, got %+v", executor.expectedExec)
+	}
+
+	executor.available = true
+	if err := app.HandleMessage(delivery.UserState, delivery.Message); err != nil {
+		t.Fatalf("HandleMessage returned error: %v", err)
+	}
+	if len(handled) != 2 || handled[1] != "start" {
+		t.Errorf("expected the user's route to handle the message once available, got %v", handled)
+	}
+}
+
+// TestHandleMessage_UnavailableRouteBusinessHours tests that the after-hours
+// route runs while the unavailable route is closed.
+func TestHandleMessage_UnavailableRouteBusinessHours(t *testing.T) {
+	routes := map[string]d_router.RouteHandler[TestObs]{}
+	for _, route := range []string{"unavailable", "after_hours"} {
+		reply := route
+		routes[route] = func(ctx *d_context.ChatContext[TestObs]) route_return.RouteReturn {
+			ctx.SendTextMessage(reply)
+			return nil
+		}
+	}
+	fallback := newMockExecutor()
+	app := newTestAppWithRoutes(&fakeReceiver{}, &unavailableExecutor{mockExecutor: newMockExecutor()}, routes, AppOptions{
+		UnavailableRoute:     "unavailable",
+		UnavailableMessenger: fallback,
+	})
+	app.engine.RegisterRoute("unavailable", routes["unavailable"], d_router.RouterHandlerOptions{BusinessHours: closedHours("after_hours")})
+
+	delivery := newTestDelivery("a", "hi")
+	if err := app.HandleMessage(delivery.UserState, delivery.Message); err != nil {
+		t.Fatalf("HandleMessage returned error: %v", err)
+	}
+	if texts := sentTexts(fallback); len(texts) != 1 || texts[0] != "after_hours" {
+		t.Errorf("expected the after-hours route to reply, got %v", texts)
+	}
+}
+
+// TestHandleMessage_UnavailableRouteFails tests that a failing unavailable
+// route still settles the message instead of having it redelivered.
+func TestHandleMessage_UnavailableRouteFails(t *testing.T) {
+	engine := NewEngine[TestObs]()
+	engine.RegisterRoute("start", func(ctx *d_context.ChatContext[TestObs]) route_return.RouteReturn {
+		return nil
+	})
+	engine.RegisterRoute("unavailable", func(ctx *d_context.ChatContext[TestObs]) route_return.RouteReturn {
+		panic("boom")
+	})
+	executor := &unavailableExecutor{mockExecutor: newMockExecutor()}
+	app := NewChatbotApp(engine, &fakeReceiver{}, executor, AppOptions{
+		UnavailableRoute:     "unavailable",
+		UnavailableMessenger: newMockExecutor(),
+	})
+
+	delivery := newTestDelivery("a", "hi")
+	delivery.UserState.Route = d_route.NewRoute("start", '.')
+
+	if err := app.HandleMessage(delivery.UserState, delivery.Message); err != nil {
+		t.Errorf("expected the message to be settled, got %v", err)
+	}
+}
+
+// TestStart_UnavailableRouteWithoutMessenger tests that the unavailable route
+// needs a messenger of its own.
+func TestStart_UnavailableRouteWithoutMessenger(t *testing.T) {
+	app := newTestApp(&fakeReceiver{}, AppOptions{UnavailableRoute: "start"})
+
+	if err := app.Start(context.Background()); err == nil {
+		t.Error("expected Start to fail without an unavailable messenger")
+	}
+}
+
+// TestStart_UnregisteredUnavailableRoute tests that the unavailable route must be registered.
+func TestStart_UnregisteredUnavailableRoute(t *testing.T) {
+	app := newTestApp(&fakeReceiver{}, AppOptions{UnavailableRoute: "missing", UnavailableMessenger: newMockExecutor()})
+
+	if err := app.Start(context.Background()); err == nil {
+		t.Error("expected Start to fail with an unregistered unavailable route")
+	}
+}
+
+// keyRecorder is a mock executor that records the idempotency key of the
+// messages and route changes made through it.
+type keyRecorder struct {
+	*mockExecutor
+	ctx  context.Context
+	keys *[]string
+}
+
+func (e *keyRecorder) WithContext(ctx context.Context) adapter_output.IBotExecutor {
+	return &keyRecorder{mockExecutor: e.mockExecutor, ctx: ctx, keys: e.keys}
+}
+
+func (e *keyRecorder) record() {
+	ctx := e.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	*e.keys = append(*e.keys, d_idempotency.ActionKey(ctx))
+}
+
+func (e *keyRecorder) SendMessage(chatID d_user.ChatID, msg d_message.Message, platform string) error {
+	e.record()
+	return e.mockExecutor.SendMessage(chatID, msg, platform)
+}
+
+func (e *keyRecorder) SetRoute(chatID d_user.ChatID, route string) error {
+	e.record()
+	return e.mockExecutor.SetRoute(chatID, route)
+}
+
+// TestHandleMessage_IdempotencyKeys tests that processing a message twice
+// produces the same idempotency keys, including for the result's route change.
+func TestHandleMessage_IdempotencyKeys(t *testing.T) {
+	engine := NewEngine[TestObs]()
+	engine.RegisterRoute("start", func(ctx *d_context.ChatContext[TestObs]) route_return.RouteReturn {
+		ctx.SendTextMessage("one")
+		ctx.SendTextMessage("two")
+		return ctx.NextRoute("next")
+	})
+	var keys []string
+	app := NewChatbotApp(engine, &fakeReceiver{}, &keyRecorder{mockExecutor: newMockExecutor(), keys: &keys})
+
+	delivery := newTestDelivery("a", "hi")
+	delivery.UserState.Route = d_route.NewRoute("start", '.')
+	delivery.Message.TextMessage.ID = "m1"
+
+	for i := 0; i < 2; i++ {
+		if err := app.HandleMessage(delivery.UserState, delivery.Message); err != nil {
+			t.Fatalf("HandleMessage returned error: %v", err)
+		}
+	}
+
+	if len(keys) != 6 {
+		t.Fatalf("expected 6 keys, got %d", len(keys))
+	}
+	for i := 0; i < 3; i++ {
+		want := d_idempotency.Key(delivery.UserState.ChatID, "m1", "start", int64(i+1))
+		if keys[i] != want || keys[i+3] != want {
+			t.Errorf("action %d: expected key %s on both runs, got %s and %s", i+1, want, keys[i], keys[i+3])
+		}
+	}
+
+	// Messages without an ID get no keys.
+	keys = nil
+	delivery.Message.TextMessage.ID = ""
+	if err := app.HandleMessage(delivery.UserState, delivery.Message); err != nil {
+		t.Fatalf("HandleMessage returned error: %v", err)
+	}
+	for _, key := range keys {
+		if key != "" {
+			t.Errorf("expected no key, got %s", key)
+		}
+	}
+}
+
+// TestHandleMessage_IdempotencyKeysAfterRouteChange tests that a message
+// redelivered after an earlier attempt moved the chat to another route gets
+// other keys, so the actions of that route are not taken for the earlier ones.
+func TestHandleMessage_IdempotencyKeysAfterRouteChange(t *testing.T) {
+	engine := NewEngine[TestObs]()
+	engine.RegisterRoute("start", func(ctx *d_context.ChatContext[TestObs]) route_return.RouteReturn {
+		ctx.SendTextMessage("one")
+		return ctx.NextRoute("next")
+	})
+	engine.RegisterRoute("next", func(ctx *d_context.ChatContext[TestObs]) route_return.RouteReturn {
+		ctx.SendTextMessage("two")
+		return nil
+	})
+	var keys []string
+	app := NewChatbotApp(engine, &fakeReceiver{}, &keyRecorder{mockExecutor: newMockExecutor(), keys: &keys})
+
+	delivery := newTestDelivery("a", "hi")
+	delivery.UserState.Route = d_route.NewRoute("start", '.')
+	delivery.Message.TextMessage.ID = "m1"
+	if err := app.HandleMessage(delivery.UserState, delivery.Message); err != nil {
+		t.Fatalf("HandleMessage returned error: %v", err)
+	}
+	first := keys
+
+	// The redelivery finds the chat on the route the first attempt set.
+	keys = nil
+	delivery.UserState.Route = d_route.NewRoute("start.next", '.')
+	if err := app.HandleMessage(delivery.UserState, delivery.Message); err != nil {
+		t.Fatalf("HandleMessage returned error: %v", err)
+	}
+
+	if len(first) != 2 || len(keys) != 2 {
+		t.Fatalf("expected 2 keys per attempt, got %v and %v", first, keys)
+	}
+	for i := range keys {
+		if keys[i] == first[i] {
+			t.Errorf("action %d: expected a new key after the route change, got %s again", i+1, keys[i])
+		}
+		if want := d_idempotency.Key(delivery.UserState.ChatID, "m1", "next", int64(i+1)); keys[i] != want {
+			t.Errorf("action %d: expected key %s, got %s", i+1, want, keys[i])
+		}
+	}
+}
+
+// TestHandleMessage_IdempotencyKeysAcrossRedirect tests that a redirect keeps
+// numbering the actions of the message, so its keys do not repeat those of
+// the route that redirected.
+func TestHandleMessage_IdempotencyKeysAcrossRedirect(t *testing.T) {
+	engine := NewEngine[TestObs]()
+	engine.RegisterRoute("start", func(ctx *d_context.ChatContext[TestObs]) route_return.RouteReturn {
+		ctx.SendTextMessage("one")
+		return d_action.RedirectResponse{TargetRoute: "menu"}
+	})
+	engine.RegisterRoute("menu", func(ctx *d_context.ChatContext[TestObs]) route_return.RouteReturn {
+		ctx.SendTextMessage("two")
+		return nil
+	})
+	var keys []string
+	app := NewChatbotApp(engine, &fakeReceiver{}, &keyRecorder{mockExecutor: newMockExecutor(), keys: &keys})
+
+	delivery := newTestDelivery("a", "hi")
+	delivery.UserState.Route = d_route.NewRoute("start", '.')
+	delivery.Message.TextMessage.ID = "m1"
+	if err := app.HandleMessage(delivery.UserState, delivery.Message); err != nil {
+		t.Fatalf("HandleMessage returned error: %v", err)
+	}
+
+	// "one", the redirect's SetRoute, "two" and the final SetRoute.
+	if len(keys) != 4 {
+		t.Fatalf("expected 4 keys, got %d", len(keys))
+	}
+	for i, key := range keys {
+		if want := d_idempotency.Key(delivery.UserState.ChatID, "m1", "start", int64(i+1)); key != want {
+			t.Errorf("action %d: expected key %s, got %s", i+1, want, key)
+		}
+	}
+}
@@ -0,0 +1,220 @@
+// Package service provides the main chatbot application service.
+// This file contains the actionBuffer, which holds the actions of buffered
+// routes until their handler returns normally.
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+
+	d_action "github.com/irissonnlima/chatgraph-go/core/domain/action"
+	d_file "github.com/irissonnlima/chatgraph-go/core/domain/file"
+	d_message "github.com/irissonnlima/chatgraph-go/core/domain/message"
+	d_router "github.com/irissonnlima/chatgraph-go/core/domain/router"
+	d_user "github.com/irissonnlima/chatgraph-go/core/domain/user"
+	adapter_output "github.com/irissonnlima/chatgraph-go/core/ports/adapters/output"
+)
+
+// errDiscarded is returned to a buffered handler that keeps calling its
+// executor after its actions were discarded.
+var errDiscarded = errors.New("action discarded: the handler did not complete")
+
+// bufferedAction is an action collected by an actionBuffer.
+type bufferedAction struct {
+	// name identifies the action in logs.
+	name string
+	// perform performs the action with an executor.
+	perform func(executor adapter_output.IBotExecutor) error
+}
+
+// actionQueue holds the actions of a buffered handler, shared by the copies
+// of its actionBuffer.
+type actionQueue struct {
+	mu      sync.Mutex
+	actions []bufferedAction
+	closed  bool
+}
+
+// actionBuffer is an IBotExecutor that collects the actions of a buffered
+// handler (see d_router.BufferedRouteOps) instead of performing them. File
+// operations and typing indicators are not buffered, since the handler needs
+// their results right away.
+type actionBuffer struct {
+	executor adapter_output.IBotExecutor
+	queue    *actionQueue
+}
+
+var (
+	_ adapter_output.IBotExecutor    = (*actionBuffer)(nil)
+	_ adapter_output.IContextBinder  = (*actionBuffer)(nil)
+	_ adapter_output.ITypingNotifier = (*actionBuffer)(nil)
+	_ adapter_output.IMessageEditor  = (*actionBuffer)(nil)
+	_ adapter_output.IReactor        = (*actionBuffer)(nil)
+)
+
+// newActionBuffer creates an empty buffer in front of executor.
+func newActionBuffer(executor adapter_output.IBotExecutor) *actionBuffer {
+	return &actionBuffer{executor: executor, queue: &actionQueue{}}
+}
+
+// WithContext binds the calls that are not buffered to ctx.
+func (b *actionBuffer) WithContext(ctx context.Context) adapter_output.IBotExecutor {
+	return &actionBuffer{executor: bindExecutor(ctx, b.executor), queue: b.queue}
+}
+
+// add collects an action. Fails once the buffer was committed or discarded.
+func (b *actionBuffer) add(name string, perform func(executor adapter_output.IBotExecutor) error) error {
+	b.queue.mu.Lock()
+	defer b.queue.mu.Unlock()
+
+	if b.queue.closed {
+		return errDiscarded
+	}
+	b.queue.actions = append(b.queue.actions, bufferedAction{name: name, perform: perform})
+	return nil
+}
+
+// take closes the buffer and returns its actions.
+func (b *actionBuffer) take() []bufferedAction {
+	b.queue.mu.Lock()
+	defer b.queue.mu.Unlock()
+
+	actions := b.queue.actions
+	b.queue.actions = nil
+	b.queue.closed = true
+	return actions
+}
+
+// commit performs the collected actions in order with executor. It stops at
+// the first failure, so the user never sees later actions without the
+// earlier ones.
+func (b *actionBuffer) commit(executor adapter_output.IBotExecutor) error {
+	actions := b.take()
+	for i, action := range actions {
+		if err := action.perform(executor); err != nil {
+			return fmt.Errorf("%s failed, skipping %d buffered actions: %w", action.name, len(actions)-i-1, err)
+		}
+	}
+	return nil
+}
+
+// discard drops the collected actions and returns how many there were.
+func (b *actionBuffer) discard() int {
+	return len(b.take())
+}
+
+// SendMessage collects a message.
+func (b *actionBuffer) SendMessage(to d_user.ChatID, message d_message.Message, platform string) error {
+	return b.add("SendMessage", func(executor adapter_output.IBotExecutor) error {
+		return executor.SendMessage(to, message, platform)
+	})
+}
+
+// SetObservation collects an observation update.
+func (b *actionBuffer) SetObservation(chatID d_user.ChatID, observation string) error {
+	return b.add("SetObservation", func(executor adapter_output.IBotExecutor) error {
+		return executor.SetObservation(chatID, observation)
+	})
+}
+
+// SetRoute collects a route update.
+func (b *actionBuffer) SetRoute(chatID d_user.ChatID, route string) error {
+	return b.add("SetRoute", func(executor adapter_output.IBotExecutor) error {
+		return executor.SetRoute(chatID, route)
+	})
+}
+
+// EndSession collects the end of the session.
+func (b *actionBuffer) EndSession(chatID d_user.ChatID, actionId string) error {
+	return b.add("EndSession", func(executor adapter_output.IBotExecutor) error {
+		return executor.EndSession(chatID, actionId)
+	})
+}
+
+// TransferToMenu collects a transfer.
+func (b *actionBuffer) TransferToMenu(chatID d_user.ChatID, transfer d_action.TransferToMenu, message d_message.Message) error {
+	return b.add("TransferToMenu", func(executor adapter_output.IBotExecutor) error {
+		return executor.TransferToMenu(chatID, transfer, message)
+	})
+}
+
+// EditMessage collects an edit. Returns ErrUnsupported right away when the
+// executor cannot edit messages, so the context falls back to a new message.
+func (b *actionBuffer) EditMessage(chatID d_user.ChatID, messageID string, message d_message.Message, platform string) error {
+	if _, ok := b.executor.(adapter_output.IMessageEditor); !ok {
+		return adapter_output.ErrUnsupported
+	}
+	return b.add("EditMessage", func(executor adapter_output.IBotExecutor) error {
+		if editor, ok := executor.(adapter_output.IMessageEditor); ok {
+			err := editor.EditMessage(chatID, messageID, message, platform)
+			if !errors.Is(err, adapter_output.ErrUnsupported) {
+				return err
+			}
+		}
+		return executor.SendMessage(chatID, message, platform)
+	})
+}
+
+// React collects a reaction. Returns ErrUnsupported right away when the
+// executor does not support reactions.
+func (b *actionBuffer) React(chatID d_user.ChatID, messageID string, reaction string, platform string) error {
+	if _, ok := b.executor.(adapter_output.IReactor); !ok {
+		return adapter_output.ErrUnsupported
+	}
+	return b.add("React", func(executor adapter_output.IBotExecutor) error {
+		reactor, ok := executor.(adapter_output.IReactor)
+		if !ok {
+			return nil
+		}
+		if err := reactor.React(chatID, messageID, reaction, platform); !errors.Is(err, adapter_output.ErrUnsupported) {
+			return err
+		}
+		return nil
+	})
+}
+
+// SendTyping shows a typing indicator right away.
+func (b *actionBuffer) SendTyping(chatID d_user.ChatID, platform string) error {
+	notifier, ok := b.executor.(adapter_output.ITypingNotifier)
+	if !ok {
+		return adapter_output.ErrUnsupported
+	}
+	return notifier.SendTyping(chatID, platform)
+}
+
+// UploadFile uploads the file right away.
+func (b *actionBuffer) UploadFile(filepath string) (*d_file.File, error) {
+	return b.executor.UploadFile(filepath)
+}
+
+// GetFile retrieves the file right away.
+func (b *actionBuffer) GetFile(fileID string) (*d_file.File, error) {
+	return b.executor.GetFile(fileID)
+}
+
+// discardBuffer drops the actions of a buffered handler that did not
+// complete and sends the route's compensation, if any, with executor.
+func discardBuffer[Obs any](
+	buffer *actionBuffer,
+	options *d_router.BufferedRouteOps,
+	userState d_user.UserState[Obs],
+	executor adapter_output.IBotExecutor,
+) {
+	if buffer == nil {
+		return
+	}
+
+	discarded := buffer.discard()
+	log.Printf("[WARN] Discarded %d buffered actions for chat %v on route %s",
+		discarded, userState.ChatID, userState.Route.Current())
+
+	if options.Compensation == nil {
+		return
+	}
+	if err := executor.SendMessage(userState.ChatID, *options.Compensation, userState.Platform); err != nil {
+		log.Printf("[ERROR] Failed to send compensation to chat %v: %v", userState.ChatID, err)
+	}
+}
@@ -0,0 +1,190 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	route_return "github.com/irissonnlima/chatgraph-go/core/domain"
+	d_action "github.com/irissonnlima/chatgraph-go/core/domain/action"
+	d_context "github.com/irissonnlima/chatgraph-go/core/domain/context"
+	d_message "github.com/irissonnlima/chatgraph-go/core/domain/message"
+	d_route "github.com/irissonnlima/chatgraph-go/core/domain/route"
+	d_router "github.com/irissonnlima/chatgraph-go/core/domain/router"
+	d_user "github.com/irissonnlima/chatgraph-go/core/domain/user"
+	adapter_output "github.com/irissonnlima/chatgraph-go/core/ports/adapters/output"
+)
+
+var compensation = d_message.Message{TextMessage: d_message.TextMessage{Detail: "Sorry, something went wrong"}}
+
+func userOn(route string) d_user.UserState[TestObs] {
+	return d_user.UserState[TestObs]{
+		Route: d_route.Route{History: []string{route}, Separator: '/'},
+	}
+}
+
+func execTypes(actions []ExpectedAction) []ActionExecType {
+	types := make([]ActionExecType, len(actions))
+	for i, action := range actions {
+		types[i] = action.Type
+	}
+	return types
+}
+
+// TestBuffered_CommitsInOrderOnReturn tests that a buffered handler's actions
+// are performed in order once it returns.
+func TestBuffered_CommitsInOrderOnReturn(t *testing.T) {
+	engine := NewEngine[TestObs]()
+	mock := newMockExecutor()
+
+	var duringHandler int
+	engine.RegisterRoute("start", func(ctx *d_context.ChatContext[TestObs]) route_return.RouteReturn {
+		ctx.SendTextMessage("first")
+		ctx.SetObservation(TestObs{Value: "saved"})
+		ctx.SendTextMessage("second")
+		ctx.SendTyping()
+		duringHandler = len(mock.expectedExec)
+		return nil
+	}, d_router.RouterHandlerOptions{Buffered: &d_router.BufferedRouteOps{}})
+
+	if _, err := engine.Execute(userOn("start"), d_message.Message{}, mock); err != nil {
+		t.Fatalf("Execute returned error: %v", err)
+	}
+
+	if duringHandler != 1 {
+		t.Errorf("only the typing indicator should be performed during the handler, got %d actions", duringHandler)
+	}
+	want := []ActionExecType{ExecSendTyping, ExecSendMessage, ExecSetObservation, ExecSendMessage}
+	if got := execTypes(mock.expectedExec); !equalTypes(got, want) {
+		t.Errorf("actions = %v, want %v", got, want)
+	}
+	if detail := mock.expectedExec[3].Message.TextMessage.Detail; detail != "second" {
+		t.Errorf("expected the second message last, got %q", detail)
+	}
+}
+
+// TestBuffered_DiscardsOnTimeout tests that a buffered handler's actions are
+// replaced by the compensation when it times out.
+func TestBuffered_DiscardsOnTimeout(t *testing.T) {
+	engine := NewEngine[TestObs](d_router.RouterHandlerOptions{
+		Timeout: &d_router.TimeoutRouteOps{Duration: 50 * time.Millisecond, Route: "timeout_route"},
+	})
+	mock := newMockExecutor()
+
+	done := make(chan error, 1)
+	engine.RegisterRoute("start", func(ctx *d_context.ChatContext[TestObs]) route_return.RouteReturn {
+		ctx.SendTextMessage("half of the answer")
+		<-ctx.Done()
+		done <- ctx.SendTextMessage("too late")
+		return nil
+	}, d_router.RouterHandlerOptions{Buffered: &d_router.BufferedRouteOps{Compensation: &compensation}})
+
+	result, err := engine.Execute(userOn("start"), d_message.Message{}, mock)
+	if err != nil {
+		t.Fatalf("Execute returned error: %v", err)
+	}
+	if redirect, ok := result.(*d_action.RedirectResponse); !ok || redirect.TargetRoute != "timeout_route" {
+		t.Fatalf("expected a redirect to timeout_route, got %#v", result)
+	}
+	if err := <-done; err == nil {
+		t.Error("expected actions after the timeout to fail")
+	}
+
+	if len(mock.expectedExec) != 1 || mock.expectedExec[0].Message.TextMessage.Detail != compensation.TextMessage.Detail {
+		t.Errorf("expected only the compensation, got %+v", mock.expectedExec)
+	}
+}
+
+// TestBuffered_DiscardsOnPanic tests that a panicking buffered handler's
+// actions are discarded and the panic is returned as an error.
+func TestBuffered_DiscardsOnPanic(t *testing.T) {
+	engine := NewEngine[TestObs]()
+	mock := newMockExecutor()
+
+	engine.RegisterRoute("start", func(ctx *d_context.ChatContext[TestObs]) route_return.RouteReturn {
+		ctx.SendTextMessage("half of the answer")
+		panic("boom")
+	}, d_router.RouterHandlerOptions{Buffered: &d_router.BufferedRouteOps{}})
+
+	_, err := engine.Execute(userOn("start"), d_message.Message{}, mock)
+	if !errors.Is(err, ErrHandlerPanic) {
+		t.Fatalf("expected ErrHandlerPanic, got %v", err)
+	}
+	if len(mock.expectedExec) != 0 {
+		t.Errorf("expected no actions, got %+v", mock.expectedExec)
+	}
+}
+
+// TestExecute_Panic tests that panics of unbuffered handlers are recovered.
+func TestExecute_Panic(t *testing.T) {
+	engine := NewEngine[TestObs]()
+	mock := newMockExecutor()
+
+	engine.RegisterRoute("start", func(ctx *d_context.ChatContext[TestObs]) route_return.RouteReturn {
+		ctx.SendTextMessage("before the panic")
+		panic("boom")
+	})
+
+	_, err := engine.Execute(userOn("start"), d_message.Message{}, mock)
+	if !errors.Is(err, ErrHandlerPanic) {
+		t.Fatalf("expected ErrHandlerPanic, got %v", err)
+	}
+	if len(mock.expectedExec) != 1 {
+		t.Errorf("unbuffered actions should be performed right away, got %+v", mock.expectedExec)
+	}
+}
+
+// TestBuffered_StopsAtFirstFailure tests that a failed action skips the later ones.
+func TestBuffered_StopsAtFirstFailure(t *testing.T) {
+	mock := newMockExecutor()
+	buffer := newActionBuffer(mock)
+
+	buffer.SetObservation(d_user.ChatID{}, "{}")
+	buffer.EndSession(d_user.ChatID{}, "end")
+	buffer.SendMessage(d_user.ChatID{}, d_message.Message{}, "")
+
+	if err := buffer.commit(mock); !errors.Is(err, ErrPrematureEnded) {
+		t.Fatalf("expected the EndSession error, got %v", err)
+	}
+	if got := execTypes(mock.expectedExec); !equalTypes(got, []ActionExecType{ExecSetObservation}) {
+		t.Errorf("actions = %v", got)
+	}
+}
+
+// basicExecutor supports no optional capability.
+type basicExecutor struct {
+	adapter_output.IBotExecutor
+}
+
+// TestBuffered_EditFallsBackToSend tests that edits become new messages when
+// the executor cannot edit.
+func TestBuffered_EditFallsBackToSend(t *testing.T) {
+	mock := newMockExecutor()
+	basic := basicExecutor{mock}
+
+	engine := NewEngine[TestObs]()
+	engine.RegisterRoute("start", func(ctx *d_context.ChatContext[TestObs]) route_return.RouteReturn {
+		ctx.EditMessage("m1", d_message.Message{})
+		ctx.React("👍")
+		return nil
+	}, d_router.RouterHandlerOptions{Buffered: &d_router.BufferedRouteOps{}})
+
+	if _, err := engine.Execute(userOn("start"), d_message.Message{}, basic); err != nil {
+		t.Fatalf("Execute returned error: %v", err)
+	}
+	if got := execTypes(mock.expectedExec); !equalTypes(got, []ActionExecType{ExecSendMessage}) {
+		t.Errorf("actions = %v", got)
+	}
+}
+
+func equalTypes(a, b []ActionExecType) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,560 @@
+// Package service provides the main chatbot application service.
+// This file contains the CampaignSender, which delivers broadcast campaigns
+// to lists of chats.
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	d_campaign "github.com/irissonnlima/chatgraph-go/core/domain/campaign"
+	d_event "github.com/irissonnlima/chatgraph-go/core/domain/event"
+	d_idempotency "github.com/irissonnlima/chatgraph-go/core/domain/idempotency"
+	d_user "github.com/irissonnlima/chatgraph-go/core/domain/user"
+	adapter_input "github.com/irissonnlima/chatgraph-go/core/ports/adapters/input"
+	adapter_output "github.com/irissonnlima/chatgraph-go/core/ports/adapters/output"
+)
+
+// Default values of CampaignOptions.
+const (
+	// DEFAULT_CAMPAIGN_RATE is the number of deliveries per second.
+	DEFAULT_CAMPAIGN_RATE = 10.0
+	// DEFAULT_CAMPAIGN_INTERVAL is how often the sender looks for pending recipients.
+	DEFAULT_CAMPAIGN_INTERVAL = 5 * time.Second
+	// DEFAULT_CAMPAIGN_MAX_ATTEMPTS is the number of delivery attempts per recipient.
+	DEFAULT_CAMPAIGN_MAX_ATTEMPTS = 3
+)
+
+// Errors returned by CampaignSender.
+var (
+	// ErrCampaignNotFound is returned for campaigns that are not stored.
+	ErrCampaignNotFound = errors.New("campaign not found")
+	// ErrCampaignExists is returned when creating a campaign with the ID of
+	// a stored one.
+	ErrCampaignExists = errors.New("campaign already exists")
+)
+
+// errChatBusy is returned by perform for chats a campaign must not
+// interrupt: their delivery is deferred to a later dispatch.
+var errChatBusy = errors.New("chat is busy")
+
+// errThrottled is returned by perform when ctx is done while waiting for the
+// delivery's turn: the campaign is paused.
+var errThrottled = errors.New("waiting for the delivery rate")
+
+// CampaignOptions configures a CampaignSender.
+type CampaignOptions struct {
+	// Rate is the number of deliveries per second, across campaigns, e.g. to
+	// stay within the limits of the messaging platform. Skipped recipients
+	// do not count. Defaults to DEFAULT_CAMPAIGN_RATE.
+	Rate float64
+	// Interval is how often the sender looks for pending recipients, e.g.
+	// to retry failed deliveries. New campaigns wake it up earlier.
+	// Defaults to DEFAULT_CAMPAIGN_INTERVAL.
+	Interval time.Duration
+	// MaxAttempts is the number of delivery attempts per recipient, unless
+	// the campaign sets its own. Defaults to DEFAULT_CAMPAIGN_MAX_ATTEMPTS.
+	MaxAttempts int
+	// OptOut, if set, tells the chats that opted out: they are skipped.
+	OptOut adapter_output.IOptOutList
+	// Now returns the current time. Defaults to time.Now.
+	Now func() time.Time
+}
+
+// withDefaults returns a copy of the options with defaults applied.
+func (o CampaignOptions) withDefaults() CampaignOptions {
+	if o.Rate <= 0 {
+		o.Rate = DEFAULT_CAMPAIGN_RATE
+	}
+	if o.Interval <= 0 {
+		o.Interval = DEFAULT_CAMPAIGN_INTERVAL
+	}
+	if o.MaxAttempts <= 0 {
+		o.MaxAttempts = DEFAULT_CAMPAIGN_MAX_ATTEMPTS
+	}
+	if o.Now == nil {
+		o.Now = time.Now
+	}
+	return o
+}
+
+// CampaignSender delivers broadcast campaigns: it sends the campaign message
+// to each recipient and sets their route to the campaign's reply route, so
+// replies land in the campaign flow, or runs the campaign's entry route for
+// each recipient as a backend event (see ChatbotApp.HandleEvent), whose
+// payload holds the recipient's variables.
+//
+// Deliveries are throttled to Rate per second. Recipients that opted out
+// are skipped, and failed deliveries are retried up to MaxAttempts, except
+// for errors implementing adapter_output.IRetryableError that are not
+// retryable. While the executor is unavailable, sending pauses. Chats that
+// are handed off to an agent (see HandoffDesk) or waiting for a reply on a
+// route with Inactivity options (see InactivityScheduler) are not
+// interrupted: their delivery is deferred, without counting an attempt,
+// until they are idle. Messages run on the chat's worker, so they do not
+// overlap with the chat's own deliveries.
+//
+// Campaigns and the delivery state of each recipient are kept in an
+// ICampaignRepository, so with a durable repository sending resumes where it
+// stopped after a restart. Actions are idempotent per campaign and
+// recipient: with a dedup executor, a recipient interrupted by a crash is
+// not sent the message twice.
+type CampaignSender[Obs any] struct {
+	app        *ChatbotApp[Obs]
+	repository adapter_output.ICampaignRepository
+	options    CampaignOptions
+
+	// mu serializes the dispatches, which share the throttle.
+	mu sync.Mutex
+	// next is when the next delivery may start.
+	next time.Time
+	// sleep waits for d or until ctx is done.
+	sleep func(ctx context.Context, d time.Duration) error
+
+	// wake is signalled when a campaign is created.
+	wake chan struct{}
+}
+
+// NewCampaignSender creates a sender that delivers campaigns through app.
+// Call Run to start sending.
+func NewCampaignSender[Obs any](
+	app *ChatbotApp[Obs],
+	repository adapter_output.ICampaignRepository,
+	options ...CampaignOptions,
+) *CampaignSender[Obs] {
+	opts := CampaignOptions{}
+	if len(options) > 0 {
+		opts = options[0]
+	}
+
+	return &CampaignSender[Obs]{
+		app:        app,
+		repository: repository,
+		options:    opts.withDefaults(),
+		sleep:      sleep,
+		wake:       make(chan struct{}, 1),
+	}
+}
+
+// Create stores a campaign for recipients, in order, and returns it. Its
+// entry and reply routes must be registered; campaigns with an entry route
+// run it as an event, so they need an executor keeping the chats' state
+// (ErrNoChatState otherwise). Campaigns without an ID get a
+// random one. Recipients listed more than once are skipped after their
+// first occurrence.
+func (s *CampaignSender[Obs]) Create(
+	campaign d_campaign.Campaign,
+	recipients []d_campaign.Recipient,
+) (d_campaign.Campaign, error) {
+	if err := campaign.Validate(); err != nil {
+		return d_campaign.Campaign{}, err
+	}
+	if len(recipients) == 0 {
+		return d_campaign.Campaign{}, d_campaign.ErrNoRecipients
+	}
+	for _, route := range []string{campaign.EntryRoute, campaign.ReplyRoute} {
+		if _, exists := s.app.engine.routes[route]; route != "" && !exists {
+			return d_campaign.Campaign{}, fmt.Errorf("%w: %s", ErrRouteNotFound, route)
+		}
+	}
+	if campaign.EntryRoute != "" {
+		if err := s.app.CheckEvents(); err != nil {
+			return d_campaign.Campaign{}, err
+		}
+	}
+
+	if campaign.ID == "" {
+		id, err := newID()
+		if err != nil {
+			return d_campaign.Campaign{}, err
+		}
+		campaign.ID = id
+	} else if _, exists, err := s.repository.Campaign(campaign.ID); err != nil {
+		return d_campaign.Campaign{}, fmt.Errorf("loading campaign: %w", err)
+	} else if exists {
+		return d_campaign.Campaign{}, fmt.Errorf("%w: %s", ErrCampaignExists, campaign.ID)
+	}
+
+	if campaign.MaxAttempts <= 0 {
+		campaign.MaxAttempts = s.options.MaxAttempts
+	}
+	now := s.options.Now()
+	campaign.Status = d_campaign.RUNNING
+	campaign.CreatedAt = now
+	campaign.UpdatedAt = now
+
+	stored := make([]d_campaign.Recipient, len(recipients))
+	seen := make(map[d_user.ChatID]bool, len(recipients))
+	for i, recipient := range recipients {
+		recipient.Index = i
+		recipient.Status = d_campaign.PENDING
+		recipient.Attempts = 0
+		recipient.Error = ""
+		recipient.UpdatedAt = now
+
+		switch {
+		case recipient.ChatID.IsEmpty():
+			recipient.Status = d_campaign.FAILED
+			recipient.Error = d_event.ErrMissingChatID.Error()
+		case seen[recipient.ChatID]:
+			recipient.Status = d_campaign.SKIPPED
+			recipient.Error = d_campaign.REASON_DUPLICATE
+		}
+		seen[recipient.ChatID] = true
+		stored[i] = recipient
+	}
+
+	// Recipients are stored first: a campaign is never stored without them.
+	if err := s.repository.SaveRecipients(campaign.ID, stored); err != nil {
+		return d_campaign.Campaign{}, fmt.Errorf("saving campaign recipients: %w", err)
+	}
+	if err := s.repository.SaveCampaign(campaign); err != nil {
+		return d_campaign.Campaign{}, fmt.Errorf("saving campaign: %w", err)
+	}
+
+	log.Printf("[INFO] Created campaign %s (%s) for %d recipients", campaign.ID, campaign.Name, len(stored))
+	s.signal()
+	return campaign, nil
+}
+
+// Cancel stops a running campaign. Recipients not delivered to yet stay pending.
+func (s *CampaignSender[Obs]) Cancel(id string) error {
+	campaign, ok, err := s.repository.Campaign(id)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrCampaignNotFound, id)
+	}
+	if campaign.Status != d_campaign.RUNNING {
+		return nil
+	}
+
+	campaign.Status = d_campaign.CANCELLED
+	campaign.UpdatedAt = s.options.Now()
+	if err := s.repository.SaveCampaign(campaign); err != nil {
+		return fmt.Errorf("saving campaign: %w", err)
+	}
+	log.Printf("[INFO] Cancelled campaign %s", id)
+	return nil
+}
+
+// Campaign returns a stored campaign. ok is false when no campaign has the ID.
+func (s *CampaignSender[Obs]) Campaign(id string) (campaign d_campaign.Campaign, ok bool, err error) {
+	return s.repository.Campaign(id)
+}
+
+// Report returns the delivery report of a campaign, with every recipient.
+func (s *CampaignSender[Obs]) Report(id string) (d_campaign.Report, error) {
+	campaign, ok, err := s.repository.Campaign(id)
+	if err != nil {
+		return d_campaign.Report{}, err
+	}
+	if !ok {
+		return d_campaign.Report{}, fmt.Errorf("%w: %s", ErrCampaignNotFound, id)
+	}
+
+	recipients, err := s.repository.Recipients(id)
+	if err != nil {
+		return d_campaign.Report{}, err
+	}
+	return d_campaign.NewReport(campaign, recipients), nil
+}
+
+// signal wakes Run up.
+func (s *CampaignSender[Obs]) signal() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run delivers the running campaigns every Interval, and whenever a
+// campaign is created, until ctx is cancelled.
+func (s *CampaignSender[Obs]) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.options.Interval)
+	defer ticker.Stop()
+
+	for {
+		s.Dispatch(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-s.wake:
+		}
+	}
+}
+
+// Dispatch makes one delivery attempt to the pending recipients of the
+// running campaigns, oldest campaign first, and returns how many recipients
+// were processed. Failed deliveries are retried by the next call.
+func (s *CampaignSender[Obs]) Dispatch(ctx context.Context) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	campaigns, err := s.repository.Campaigns()
+	if err != nil {
+		log.Printf("[ERROR] Failed to list campaigns: %v", err)
+		return 0
+	}
+
+	processed := 0
+	for _, campaign := range campaigns {
+		if campaign.Status != d_campaign.RUNNING {
+			continue
+		}
+		n, stopped := s.send(ctx, campaign)
+		processed += n
+		if stopped {
+			break
+		}
+	}
+	return processed
+}
+
+// send makes one delivery attempt to the pending recipients of a campaign.
+// stopped is true when sending stopped for every campaign: ctx is done or
+// the executor is unavailable.
+func (s *CampaignSender[Obs]) send(ctx context.Context, campaign d_campaign.Campaign) (processed int, stopped bool) {
+	recipients, err := s.repository.Recipients(campaign.ID)
+	if err != nil {
+		log.Printf("[ERROR] Failed to list recipients of campaign %s: %v", campaign.ID, err)
+		return 0, false
+	}
+
+	for _, recipient := range recipients {
+		if recipient.Finished() {
+			continue
+		}
+		if ctx.Err() != nil {
+			return processed, true
+		}
+		if !s.running(campaign.ID) {
+			return processed, false
+		}
+		if !s.app.available() {
+			log.Printf("[WARN] Executor unavailable, pausing campaign %s", campaign.ID)
+			return processed, true
+		}
+
+		paused, deferred := s.deliver(ctx, campaign, &recipient)
+		if paused {
+			return processed, true
+		}
+		if deferred {
+			continue
+		}
+		recipient.UpdatedAt = s.options.Now()
+		if err := s.repository.SaveRecipients(campaign.ID, []d_campaign.Recipient{recipient}); err != nil {
+			log.Printf("[ERROR] Failed to save recipient %d of campaign %s: %v", recipient.Index, campaign.ID, err)
+		}
+		processed++
+	}
+
+	s.finish(campaign)
+	return processed, false
+}
+
+// deliver makes a delivery attempt to a recipient, unless they opted out,
+// and records its outcome. paused is true when the recipient could not be
+// attempted, e.g. while the executor is unavailable. deferred is true when
+// the recipient's chat is busy: it is left untouched, for a later dispatch.
+func (s *CampaignSender[Obs]) deliver(
+	ctx context.Context,
+	campaign d_campaign.Campaign,
+	recipient *d_campaign.Recipient,
+) (paused, deferred bool) {
+	if s.options.OptOut != nil {
+		optedOut, err := s.options.OptOut.OptedOut(recipient.ChatID)
+		if err != nil {
+			log.Printf("[ERROR] Failed to check opt-out of chat %v: %v", recipient.ChatID, err)
+			return true, false
+		}
+		if optedOut {
+			recipient.Status = d_campaign.SKIPPED
+			recipient.Error = d_campaign.REASON_OPTED_OUT
+			return false, false
+		}
+	}
+
+	err := s.perform(ctx, campaign, *recipient)
+	switch {
+	case err == nil:
+		recipient.Attempts++
+		recipient.Status = d_campaign.SENT
+		recipient.Error = ""
+		return false, false
+	case errors.Is(err, errChatBusy), errors.Is(err, ErrChatHandedOff):
+		log.Printf("[INFO] Deferring campaign %s for chat %v: %v", campaign.ID, recipient.ChatID, err)
+		return false, true
+	case errors.Is(err, adapter_input.ErrUnavailable), errors.Is(err, context.Canceled),
+		errors.Is(err, errThrottled):
+		log.Printf("[WARN] Pausing campaign %s: %v", campaign.ID, err)
+		return true, false
+	}
+
+	recipient.Attempts++
+	recipient.Error = err.Error()
+	if recipient.Attempts >= campaign.MaxAttempts || !taskRetryable(err) ||
+		errors.Is(err, adapter_input.ErrInvalidEvent) {
+		recipient.Status = d_campaign.FAILED
+	}
+	log.Printf("[ERROR] Campaign %s failed to deliver to chat %v (attempt %d): %v",
+		campaign.ID, recipient.ChatID, recipient.Attempts, err)
+	return false, false
+}
+
+// perform delivers a campaign to a recipient, unless their chat is busy.
+// Deliveries are throttled only once the chat is known to be idle, so
+// deferred recipients do not slow the campaign down.
+func (s *CampaignSender[Obs]) perform(
+	ctx context.Context,
+	campaign d_campaign.Campaign,
+	recipient d_campaign.Recipient,
+) error {
+	// The delivery ID scopes the idempotency keys of the recipient's actions.
+	deliveryID := fmt.Sprintf("campaign:%s:%d", campaign.ID, recipient.Index)
+
+	if campaign.EntryRoute != "" {
+		if err := s.idle(recipient.ChatID); err != nil {
+			return err
+		}
+		if err := s.throttle(ctx); err != nil {
+			return err
+		}
+		payload := ""
+		if len(recipient.Variables) > 0 {
+			data, err := json.Marshal(recipient.Variables)
+			if err != nil {
+				return fmt.Errorf("encoding recipient variables: %w", err)
+			}
+			payload = string(data)
+		}
+		return s.app.HandleEvent(ctx, d_event.Event{
+			ID:       deliveryID,
+			Name:     "campaign",
+			ChatID:   recipient.ChatID,
+			Route:    campaign.EntryRoute,
+			Platform: campaign.Platform,
+			Payload:  payload,
+		})
+	}
+
+	return s.app.onChat(ctx, recipient.ChatID, func() error {
+		if err := s.idle(recipient.ChatID); err != nil {
+			return err
+		}
+
+		platform := campaign.Platform
+		if platform == "" {
+			userState, _, err := s.app.loadState(recipient.ChatID)
+			if err != nil {
+				return fmt.Errorf("loading chat state: %w", err)
+			}
+			platform = userState.Platform
+		}
+		if err := s.throttle(ctx); err != nil {
+			return err
+		}
+
+		ctx := d_idempotency.WithScope(ctx, d_idempotency.NewScope(recipient.ChatID, deliveryID, ""))
+		executor := bindExecutor(ctx, s.app.botExecutor)
+
+		if err := executor.SendMessage(recipient.ChatID, campaign.Render(recipient), platform); err != nil {
+			return err
+		}
+		if campaign.ReplyRoute != "" {
+			return executor.SetRoute(recipient.ChatID, campaign.ReplyRoute)
+		}
+		return nil
+	})
+}
+
+// idle returns errChatBusy when a chat is handed off to an agent, or waiting
+// for a reply on a route with Inactivity options.
+func (s *CampaignSender[Obs]) idle(chatID d_user.ChatID) error {
+	handedOff, err := s.app.handedOff(chatID)
+	if err != nil {
+		return err
+	}
+	if handedOff {
+		return fmt.Errorf("%w: handed off to an agent", errChatBusy)
+	}
+	if s.app.inactivity != nil {
+		_, waiting, err := s.app.inactivity.Timer(chatID)
+		if err != nil {
+			return fmt.Errorf("loading inactivity timer: %w", err)
+		}
+		if waiting {
+			return fmt.Errorf("%w: waiting for a reply", errChatBusy)
+		}
+	}
+	return nil
+}
+
+// throttle waits until the next delivery may start, keeping deliveries Rate
+// per second apart. Returns errThrottled when ctx is done first.
+func (s *CampaignSender[Obs]) throttle(ctx context.Context) error {
+	now := s.options.Now()
+	if wait := s.next.Sub(now); wait > 0 {
+		if err := s.sleep(ctx, wait); err != nil {
+			return fmt.Errorf("%w: %w", errThrottled, err)
+		}
+		now = s.next
+	}
+	s.next = now.Add(time.Duration(float64(time.Second) / s.options.Rate))
+	return nil
+}
+
+// running reports whether a campaign is still running, e.g. it was not
+// cancelled while sending.
+func (s *CampaignSender[Obs]) running(id string) bool {
+	campaign, ok, err := s.repository.Campaign(id)
+	if err != nil {
+		log.Printf("[ERROR] Failed to load campaign %s: %v", id, err)
+		return false
+	}
+	return ok && campaign.Status == d_campaign.RUNNING
+}
+
+// finish stores a campaign as FINISHED once no recipient is pending.
+func (s *CampaignSender[Obs]) finish(campaign d_campaign.Campaign) {
+	report, err := s.Report(campaign.ID)
+	if err != nil {
+		log.Printf("[ERROR] Failed to report campaign %s: %v", campaign.ID, err)
+		return
+	}
+	if report.Pending > 0 || report.Campaign.Status != d_campaign.RUNNING {
+		return
+	}
+
+	campaign = report.Campaign
+	campaign.Status = d_campaign.FINISHED
+	campaign.UpdatedAt = s.options.Now()
+	if err := s.repository.SaveCampaign(campaign); err != nil {
+		log.Printf("[ERROR] Failed to save campaign %s: %v", campaign.ID, err)
+		return
+	}
+	log.Printf("[INFO] Campaign %s finished: %d sent, %d skipped, %d failed",
+		campaign.ID, report.Sent, report.Skipped, report.Failed)
+}
+
+// sleep waits for d or until ctx is done.
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
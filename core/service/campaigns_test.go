@@ -0,0 +1,462 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/irissonnlima/chatgraph-go/core/clocktest"
+	route_return "github.com/irissonnlima/chatgraph-go/core/domain"
+	d_campaign "github.com/irissonnlima/chatgraph-go/core/domain/campaign"
+	d_context "github.com/irissonnlima/chatgraph-go/core/domain/context"
+	d_handoff "github.com/irissonnlima/chatgraph-go/core/domain/handoff"
+	d_idempotency "github.com/irissonnlima/chatgraph-go/core/domain/idempotency"
+	d_inactivity "github.com/irissonnlima/chatgraph-go/core/domain/inactivity"
+	d_message "github.com/irissonnlima/chatgraph-go/core/domain/message"
+	d_router "github.com/irissonnlima/chatgraph-go/core/domain/router"
+	d_session "github.com/irissonnlima/chatgraph-go/core/domain/session"
+	d_user "github.com/irissonnlima/chatgraph-go/core/domain/user"
+)
+
+// campaignStore is an in-memory ICampaignRepository.
+type campaignStore struct {
+	mu         sync.Mutex
+	campaigns  map[string]d_campaign.Campaign
+	recipients map[string]map[int]d_campaign.Recipient
+}
+
+func newCampaignStore() *campaignStore {
+	return &campaignStore{
+		campaigns:  make(map[string]d_campaign.Campaign),
+		recipients: make(map[string]map[int]d_campaign.Recipient),
+	}
+}
+
+func (s *campaignStore) SaveCampaign(campaign d_campaign.Campaign) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.campaigns[campaign.ID] = campaign
+	return nil
+}
+
+func (s *campaignStore) Campaign(id string) (d_campaign.Campaign, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	campaign, ok := s.campaigns[id]
+	return campaign, ok, nil
+}
+
+func (s *campaignStore) Campaigns() ([]d_campaign.Campaign, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	campaigns := make([]d_campaign.Campaign, 0, len(s.campaigns))
+	for _, campaign := range s.campaigns {
+		campaigns = append(campaigns, campaign)
+	}
+	sort.Slice(campaigns, func(i, j int) bool { return campaigns[i].CreatedAt.Before(campaigns[j].CreatedAt) })
+	return campaigns, nil
+}
+
+func (s *campaignStore) SaveRecipients(campaignID string, recipients []d_campaign.Recipient) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.recipients[campaignID] == nil {
+		s.recipients[campaignID] = make(map[int]d_campaign.Recipient)
+	}
+	for _, recipient := range recipients {
+		s.recipients[campaignID][recipient.Index] = recipient
+	}
+	return nil
+}
+
+func (s *campaignStore) Recipients(campaignID string) ([]d_campaign.Recipient, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	recipients := make([]d_campaign.Recipient, 0, len(s.recipients[campaignID]))
+	for _, recipient := range s.recipients[campaignID] {
+		recipients = append(recipients, recipient)
+	}
+	sort.Slice(recipients, func(i, j int) bool { return recipients[i].Index < recipients[j].Index })
+	return recipients, nil
+}
+
+func (s *campaignStore) DeleteCampaign(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.campaigns, id)
+	delete(s.recipients, id)
+	return nil
+}
+
+func (s *campaignStore) Close() error {
+	return nil
+}
+
+// optOutList is an IOptOutList holding the opted-out chats.
+type optOutList map[d_user.ChatID]bool
+
+func (l optOutList) OptedOut(chatID d_user.ChatID) (bool, error) {
+	return l[chatID], nil
+}
+
+// campaignExecutor is a mock executor that records the messages and routes
+// of each chat, and fails the first sends to the chats in failures.
+type campaignExecutor struct {
+	*mockExecutor
+	available bool
+	failures  map[string]int
+	sent      []string
+	routes    []string
+}
+
+func newCampaignExecutor() *campaignExecutor {
+	return &campaignExecutor{mockExecutor: newMockExecutor(), available: true, failures: make(map[string]int)}
+}
+
+func (e *campaignExecutor) SendMessage(chatID d_user.ChatID, message d_message.Message, platform string) error {
+	if e.failures[chatID.UserID] > 0 {
+		e.failures[chatID.UserID]--
+		return errors.New("platform rejected the message")
+	}
+	e.sent = append(e.sent, chatID.UserID+": "+message.TextMessage.Detail)
+	return nil
+}
+
+func (e *campaignExecutor) SetRoute(chatID d_user.ChatID, route string) error {
+	e.routes = append(e.routes, chatID.UserID+": "+route)
+	return nil
+}
+
+func (e *campaignExecutor) Available() bool {
+	return e.available
+}
+
+// LoadState keeps no state for any chat, so entry routes start afresh.
+func (e *campaignExecutor) LoadState(d_user.ChatID) (d_session.Session, bool, error) {
+	return d_session.Session{}, false, nil
+}
+
+func campaignChat(userID string) d_user.ChatID {
+	return d_user.ChatID{UserID: userID, CompanyID: "c1"}
+}
+
+// newCampaignTest returns a sender over an app whose "promo" route greets
+// the recipient named in the event payload. Its throttle advances clock
+// instead of sleeping, recording the waits.
+func newCampaignTest(executor *campaignExecutor, clock *clocktest.FakeClock, options ...CampaignOptions) (*CampaignSender[TestObs], *campaignStore, *[]time.Duration) {
+	app := newTestAppWithRoutes(&fakeReceiver{}, executor, map[string]d_router.RouteHandler[TestObs]{
+		"promo": func(ctx *d_context.ChatContext[TestObs]) route_return.RouteReturn {
+			event, _ := ctx.Event()
+			var variables map[string]string
+			event.DecodePayload(&variables)
+			ctx.SendTextMessage("Deals for " + variables["name"])
+			return nil
+		},
+	})
+
+	opts := CampaignOptions{}
+	if len(options) > 0 {
+		opts = options[0]
+	}
+	opts.Now = clock.Now
+
+	store := newCampaignStore()
+	sender := NewCampaignSender(app, store, opts)
+
+	var waits []time.Duration
+	sender.sleep = func(ctx context.Context, d time.Duration) error {
+		waits = append(waits, d)
+		clock.Advance(d)
+		return nil
+	}
+	return sender, store, &waits
+}
+
+// TestCampaignSender_SendsMessage tests that the rendered message is sent to
+// each recipient, throttled, and their route set to the reply route, skipping
+// opted-out and duplicate recipients.
+func TestCampaignSender_SendsMessage(t *testing.T) {
+	clock := clocktest.NewFakeClock(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+	executor := newCampaignExecutor()
+	sender, _, waits := newCampaignTest(executor, clock, CampaignOptions{
+		Rate:   2,
+		OptOut: optOutList{campaignChat("u2"): true},
+	})
+
+	campaign, err := sender.Create(d_campaign.Campaign{
+		Name:       "Black Friday",
+		Message:    d_message.Message{TextMessage: d_message.TextMessage{Detail: "Hi {{name}}, 50% off!"}},
+		ReplyRoute: "promo",
+	}, []d_campaign.Recipient{
+		{ChatID: campaignChat("u1"), Variables: map[string]string{"name": "Ana"}},
+		{ChatID: campaignChat("u2"), Variables: map[string]string{"name": "Bia"}},
+		{ChatID: campaignChat("u3"), Variables: map[string]string{"name": "Caio"}},
+		{ChatID: campaignChat("u1"), Variables: map[string]string{"name": "Ana"}},
+	})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	if processed := sender.Dispatch(context.Background()); processed != 3 {
+		t.Errorf("expected 3 recipients processed, got %d", processed)
+	}
+
+	wantSent := []string{"u1: Hi Ana, 50% off!", "u3: Hi Caio, 50% off!"}
+	if len(executor.sent) != 2 || executor.sent[0] != wantSent[0] || executor.sent[1] != wantSent[1] {
+		t.Errorf("expected %v, got %v", wantSent, executor.sent)
+	}
+	if len(executor.routes) != 2 || executor.routes[0] != "u1: promo" || executor.routes[1] != "u3: promo" {
+		t.Errorf("expected the reply route to be set, got %v", executor.routes)
+	}
+	if len(*waits) != 1 || (*waits)[0] != 500*time.Millisecond {
+		t.Errorf("expected deliveries 500ms apart, got waits %v", *waits)
+	}
+
+	report, err := sender.Report(campaign.ID)
+	if err != nil {
+		t.Fatalf("Report() error = %v", err)
+	}
+	if report.Campaign.Status != d_campaign.FINISHED || report.Sent != 2 || report.Skipped != 2 || report.Pending != 0 {
+		t.Errorf("unexpected report %+v", report)
+	}
+	if report.Recipients[1].Error != d_campaign.REASON_OPTED_OUT || report.Recipients[3].Error != d_campaign.REASON_DUPLICATE {
+		t.Errorf("unexpected skip reasons %+v", report.Recipients)
+	}
+}
+
+// TestCampaignSender_RunsEntryRoute tests that the entry route runs for each
+// recipient with their variables.
+func TestCampaignSender_RunsEntryRoute(t *testing.T) {
+	clock := clocktest.NewFakeClock(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+	executor := newCampaignExecutor()
+	sender, _, _ := newCampaignTest(executor, clock)
+
+	_, err := sender.Create(d_campaign.Campaign{EntryRoute: "promo"}, []d_campaign.Recipient{
+		{ChatID: campaignChat("u1"), Variables: map[string]string{"name": "Ana"}},
+		{ChatID: campaignChat("u2"), Variables: map[string]string{"name": "Bia"}},
+	})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	sender.Dispatch(context.Background())
+
+	if len(executor.sent) != 2 || executor.sent[0] != "u1: Deals for Ana" || executor.sent[1] != "u2: Deals for Bia" {
+		t.Errorf("unexpected messages %v", executor.sent)
+	}
+	if len(executor.routes) != 2 || executor.routes[1] != "u2: promo" {
+		t.Errorf("expected the entry route to be stored, got %v", executor.routes)
+	}
+}
+
+// TestCampaignSender_DefersBusyChats tests that chats handed off to an agent
+// or waiting for a reply are not interrupted, nor charged an attempt or a
+// throttle wait, and are delivered to once idle.
+func TestCampaignSender_DefersBusyChats(t *testing.T) {
+	clock := clocktest.NewFakeClock(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+	executor := newCampaignExecutor()
+	sender, _, waits := newCampaignTest(executor, clock)
+	tickets := newHandoffStore()
+	NewHandoffDesk(sender.app, tickets, &fakeConsole{})
+	timers := newInactivityStore()
+	NewInactivityScheduler(sender.app, timers)
+
+	tickets.Save(d_handoff.Ticket{ID: "t1", Session: d_session.Session{ChatID: campaignChat("u1")}})
+	timers.Save(d_inactivity.Timer{ID: "i1", Session: d_session.Session{ChatID: campaignChat("u2")}})
+
+	message := d_message.Message{TextMessage: d_message.TextMessage{Detail: "Hi"}}
+	campaign, err := sender.Create(d_campaign.Campaign{Message: message}, []d_campaign.Recipient{
+		{ChatID: campaignChat("u1")}, {ChatID: campaignChat("u2")}, {ChatID: campaignChat("u3")},
+	})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if processed := sender.Dispatch(context.Background()); processed != 1 {
+		t.Fatalf("expected 1 recipient processed, got %d", processed)
+	}
+	if len(executor.sent) != 1 || executor.sent[0] != "u3: Hi" {
+		t.Fatalf("expected only u3 to be sent the message, got %v", executor.sent)
+	}
+	if len(*waits) != 0 {
+		t.Errorf("expected deferred chats not to be throttled, got waits %v", *waits)
+	}
+	report, _ := sender.Report(campaign.ID)
+	if report.Pending != 2 || report.Recipients[0].Attempts != 0 || report.Campaign.Status != d_campaign.RUNNING {
+		t.Fatalf("expected busy chats to stay pending without attempts, got %+v", report)
+	}
+
+	tickets.Delete(campaignChat("u1"))
+	timers.Delete(campaignChat("u2"))
+	sender.Dispatch(context.Background())
+	if len(executor.sent) != 3 {
+		t.Errorf("expected idle chats to be sent the message, got %v", executor.sent)
+	}
+	if report, _ := sender.Report(campaign.ID); report.Sent != 3 || report.Campaign.Status != d_campaign.FINISHED {
+		t.Errorf("expected the campaign to finish, got %+v", report)
+	}
+}
+
+// TestCampaignSender_IdempotencyKeys tests that the actions of each delivery
+// are keyed by campaign and recipient, so they do not collide across
+// recipients or campaigns.
+func TestCampaignSender_IdempotencyKeys(t *testing.T) {
+	var keys []string
+	app := newTestAppWithRoutes(&fakeReceiver{}, &keyRecorder{mockExecutor: newMockExecutor(), keys: &keys}, nil)
+	sender := NewCampaignSender(app, newCampaignStore(), CampaignOptions{Rate: 1e9})
+
+	message := d_message.Message{TextMessage: d_message.TextMessage{Detail: "Hi"}}
+	recipients := []d_campaign.Recipient{{ChatID: campaignChat("u1")}, {ChatID: campaignChat("u2")}}
+	for _, id := range []string{"c1", "c2"} {
+		if _, err := sender.Create(d_campaign.Campaign{ID: id, Message: message, Platform: "whatsapp"}, recipients); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+		sender.Dispatch(context.Background())
+	}
+
+	expected := []string{
+		d_idempotency.Key(campaignChat("u1"), "campaign:c1:0", "", 1),
+		d_idempotency.Key(campaignChat("u2"), "campaign:c1:1", "", 1),
+		d_idempotency.Key(campaignChat("u1"), "campaign:c2:0", "", 1),
+		d_idempotency.Key(campaignChat("u2"), "campaign:c2:1", "", 1),
+	}
+	if len(keys) != len(expected) {
+		t.Fatalf("expected %d keys, got %v", len(expected), keys)
+	}
+	for i, key := range keys {
+		if key != expected[i] {
+			t.Errorf("delivery %d: expected key %s, got %s", i, expected[i], key)
+		}
+	}
+}
+
+// TestCampaignSender_RetriesThenFails tests that failed deliveries are
+// retried by the next dispatches, up to MaxAttempts.
+func TestCampaignSender_RetriesThenFails(t *testing.T) {
+	clock := clocktest.NewFakeClock(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+	executor := newCampaignExecutor()
+	executor.failures["u1"] = 1
+	executor.failures["u2"] = 5
+	sender, _, _ := newCampaignTest(executor, clock, CampaignOptions{MaxAttempts: 2})
+
+	message := d_message.Message{TextMessage: d_message.TextMessage{Detail: "Hi"}}
+	campaign, err := sender.Create(d_campaign.Campaign{Message: message}, []d_campaign.Recipient{
+		{ChatID: campaignChat("u1")}, {ChatID: campaignChat("u2")},
+	})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	sender.Dispatch(context.Background())
+	report, _ := sender.Report(campaign.ID)
+	if report.Pending != 2 || report.Campaign.Status != d_campaign.RUNNING {
+		t.Fatalf("expected both recipients to be retried, got %+v", report)
+	}
+
+	sender.Dispatch(context.Background())
+	report, _ = sender.Report(campaign.ID)
+	if report.Sent != 1 || report.Failed != 1 || report.Campaign.Status != d_campaign.FINISHED {
+		t.Fatalf("expected u1 sent and u2 failed, got %+v", report)
+	}
+	failed := report.Recipients[1]
+	if failed.Attempts != 2 || failed.Error != "platform rejected the message" {
+		t.Errorf("unexpected failed recipient %+v", failed)
+	}
+}
+
+// TestCampaignSender_ResumesAfterRestart tests that a new sender over the
+// same repository delivers to the recipients left pending, and that sending
+// pauses while the executor is unavailable.
+func TestCampaignSender_ResumesAfterRestart(t *testing.T) {
+	clock := clocktest.NewFakeClock(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+	executor := newCampaignExecutor()
+	sender, store, _ := newCampaignTest(executor, clock)
+
+	message := d_message.Message{TextMessage: d_message.TextMessage{Detail: "Hi"}}
+	campaign, err := sender.Create(d_campaign.Campaign{Message: message}, []d_campaign.Recipient{
+		{ChatID: campaignChat("u1")}, {ChatID: campaignChat("u2")},
+	})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	// u1 was delivered to before a crash.
+	store.SaveRecipients(campaign.ID, []d_campaign.Recipient{{Index: 0, ChatID: campaignChat("u1"), Status: d_campaign.SENT, Attempts: 1}})
+
+	restarted := NewCampaignSender(sender.app, store, CampaignOptions{Now: clock.Now})
+	executor.available = false
+	if processed := restarted.Dispatch(context.Background()); processed != 0 || len(executor.sent) != 0 {
+		t.Fatalf("expected sending to pause while unavailable, got %d processed", processed)
+	}
+
+	executor.available = true
+	restarted.Dispatch(context.Background())
+	if len(executor.sent) != 1 || executor.sent[0] != "u2: Hi" {
+		t.Errorf("expected only u2 to be sent the message, got %v", executor.sent)
+	}
+	if stored, _, _ := restarted.Campaign(campaign.ID); stored.Status != d_campaign.FINISHED {
+		t.Errorf("expected the campaign to finish, got %s", stored.Status)
+	}
+}
+
+// TestCampaignSender_Cancel tests that cancelled campaigns are not sent.
+func TestCampaignSender_Cancel(t *testing.T) {
+	clock := clocktest.NewFakeClock(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+	executor := newCampaignExecutor()
+	sender, _, _ := newCampaignTest(executor, clock)
+
+	message := d_message.Message{TextMessage: d_message.TextMessage{Detail: "Hi"}}
+	campaign, _ := sender.Create(d_campaign.Campaign{Message: message}, []d_campaign.Recipient{{ChatID: campaignChat("u1")}})
+	if err := sender.Cancel(campaign.ID); err != nil {
+		t.Fatalf("Cancel() error = %v", err)
+	}
+	sender.Dispatch(context.Background())
+
+	if len(executor.sent) != 0 {
+		t.Errorf("expected no messages, got %v", executor.sent)
+	}
+	if err := sender.Cancel("missing"); !errors.Is(err, ErrCampaignNotFound) {
+		t.Errorf("expected ErrCampaignNotFound, got %v", err)
+	}
+}
+
+// TestCampaignSender_Create_Validation tests the campaigns Create rejects.
+func TestCampaignSender_Create_Validation(t *testing.T) {
+	clock := clocktest.NewFakeClock(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+	sender, _, _ := newCampaignTest(newCampaignExecutor(), clock)
+	recipients := []d_campaign.Recipient{{ChatID: campaignChat("u1")}}
+
+	tests := []struct {
+		name       string
+		campaign   d_campaign.Campaign
+		recipients []d_campaign.Recipient
+		expected   error
+	}{
+		{name: "no content", campaign: d_campaign.Campaign{}, recipients: recipients, expected: d_campaign.ErrMissingContent},
+		{name: "no recipients", campaign: d_campaign.Campaign{EntryRoute: "promo"}, expected: d_campaign.ErrNoRecipients},
+		{name: "unknown route", campaign: d_campaign.Campaign{EntryRoute: "missing"}, recipients: recipients, expected: ErrRouteNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := sender.Create(tt.campaign, tt.recipients); !errors.Is(err, tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, err)
+			}
+		})
+	}
+
+	if _, err := sender.Create(d_campaign.Campaign{ID: "c1", EntryRoute: "promo"}, recipients); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if _, err := sender.Create(d_campaign.Campaign{ID: "c1", EntryRoute: "promo"}, recipients); !errors.Is(err, ErrCampaignExists) {
+		t.Errorf("expected ErrCampaignExists, got %v", err)
+	}
+
+	// Entry routes run as events, which need the chats' state.
+	stateless := NewCampaignSender(newTestAppWithRoutes(&fakeReceiver{}, newMockExecutor(), map[string]d_router.RouteHandler[TestObs]{
+		"promo": func(ctx *d_context.ChatContext[TestObs]) route_return.RouteReturn { return nil },
+	}), newCampaignStore())
+	if _, err := stateless.Create(d_campaign.Campaign{EntryRoute: "promo"}, recipients); !errors.Is(err, ErrNoChatState) {
+		t.Errorf("expected ErrNoChatState, got %v", err)
+	}
+}
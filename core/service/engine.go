@@ -4,9 +4,13 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"regexp"
+	"runtime/debug"
+	"strings"
+	"time"
 
 	route_return "github.com/irissonnlima/chatgraph-go/core/domain"
 	d_action "github.com/irissonnlima/chatgraph-go/core/domain/action"
@@ -17,6 +21,18 @@ import (
 	adapter_output "github.com/irissonnlima/chatgraph-go/core/ports/adapters/output"
 )
 
+// ErrHandlerPanic is returned when a route handler panics.
+var ErrHandlerPanic = errors.New("handler panicked")
+
+// ErrRouteNotFound is returned when no handler is registered for a route.
+var ErrRouteNotFound = errors.New("route not found")
+
+// handlerPanic is a value recovered from a panicking handler.
+type handlerPanic struct {
+	value any
+	stack []byte
+}
+
 // Engine handles route registration and execution logic without I/O.
 // It is designed to be testable in isolation without requiring external dependencies.
 type Engine[Obs any] struct {
@@ -26,6 +42,8 @@ type Engine[Obs any] struct {
 	defaultOptions d_router.RouterHandlerOptions
 	// routeTriggers holds the route triggers configuration.
 	routeTriggers []d_router.RouteTrigger
+	// abandoned tracks the handlers still running after their timeout.
+	abandoned *abandonedTracker
 }
 
 // NewEngine creates a new Engine instance with optional default options.
@@ -49,6 +67,7 @@ func NewEngine[Obs any](defaultOptions ...d_router.RouterHandlerOptions) *Engine
 	return &Engine[Obs]{
 		routes:         make(map[string]d_router.RouterHandlerAdmnistrator[Obs]),
 		defaultOptions: defaultOpts,
+		abandoned:      newAbandonedTracker(),
 	}
 }
 
@@ -60,9 +79,13 @@ func (e *Engine[Obs]) RegisterRoute(
 	options ...d_router.RouterHandlerOptions,
 ) {
 	rho := d_router.RouterHandlerOptions{
-		Timeout:   e.defaultOptions.Timeout,
-		LoopCount: e.defaultOptions.LoopCount,
-		Protected: e.defaultOptions.Protected,
+		Timeout:       e.defaultOptions.Timeout,
+		LoopCount:     e.defaultOptions.LoopCount,
+		Protected:     e.defaultOptions.Protected,
+		Buffered:      e.defaultOptions.Buffered,
+		Progress:      e.defaultOptions.Progress,
+		Inactivity:    e.defaultOptions.Inactivity,
+		BusinessHours: e.defaultOptions.BusinessHours,
 	}
 
 	if len(options) > 0 {
@@ -82,6 +105,38 @@ func (e *Engine[Obs]) RegisterTrigger(trigger d_router.RouteTrigger) {
 	e.routeTriggers = append(e.routeTriggers, trigger)
 }
 
+// afterHours returns the after-hours route of the user's current route when
+// it has BusinessHours options and the chat's company is closed.
+func (e *Engine[Obs]) afterHours(userState d_user.UserState[Obs]) (route string, closed bool) {
+	current := userState.Route.Current()
+	hours := e.routes[current].HandlerOptions.BusinessHours
+	if hours == nil || current == hours.Route || hours.IsOpen(userState.ChatID.CompanyID) {
+		return "", false
+	}
+	return hours.Route, true
+}
+
+// afterHoursCycle follows the after-hours routes from route and returns the
+// routes visited once they repeat, or nil when the chain ends. Each after-hours
+// route is checked for business hours again, so a cycle whose routes are all
+// closed would redirect forever.
+func (e *Engine[Obs]) afterHoursCycle(route string) []string {
+	visited := map[string]bool{route: true}
+	chain := []string{route}
+	for {
+		hours := e.routes[route].HandlerOptions.BusinessHours
+		if hours == nil || hours.Route == route {
+			return nil
+		}
+		route = hours.Route
+		chain = append(chain, route)
+		if visited[route] {
+			return chain
+		}
+		visited[route] = true
+	}
+}
+
 // applyTriggers checks if the message matches any trigger regex.
 // If a match is found, returns the route associated with the trigger.
 // Returns empty string if no trigger matches.
@@ -107,10 +162,24 @@ func (e *Engine[Obs]) applyTriggers(messageText string) string {
 //   - Trigger matching
 //   - Loop detection
 //   - Handler execution with timeout
+//   - Handler panics, returned as ErrHandlerPanic
+//   - Business hours, for routes with BusinessHours options
+//   - Buffering of the handler's actions, for routes with Buffered options
+//   - Progress feedback while the handler is slow, for routes with Progress options
 func (e *Engine[Obs]) Execute(
 	userState d_user.UserState[Obs],
 	message d_message.Message,
 	router adapter_output.IBotExecutor,
+) (route_return.RouteReturn, error) {
+	return e.execute(context.Background(), userState, message, router)
+}
+
+// execute is Execute with the parent of the handler's context.
+func (e *Engine[Obs]) execute(
+	parent context.Context,
+	userState d_user.UserState[Obs],
+	message d_message.Message,
+	router adapter_output.IBotExecutor,
 ) (route_return.RouteReturn, error) {
 	// Check for triggers
 	preRoute := e.applyTriggers(message.EntireText())
@@ -131,38 +200,102 @@ func (e *Engine[Obs]) Execute(
 		}, nil
 	}
 
+	return e.run(parent, userState, message, router)
+}
+
+// run executes the handler of the user's current route, without applying
+// triggers or loop detection. The handler's context is derived from parent.
+// While the route is closed for the chat's company, the handler does not
+// run: a redirect to the route's after-hours route is returned instead.
+func (e *Engine[Obs]) run(
+	parent context.Context,
+	userState d_user.UserState[Obs],
+	message d_message.Message,
+	router adapter_output.IBotExecutor,
+) (route_return.RouteReturn, error) {
+	route := userState.Route
+
 	// Get route handler
 	routeFunc, exists := e.routes[route.Current()]
 	if !exists {
-		return nil, fmt.Errorf("route not found: %s", route.Current())
+		return nil, fmt.Errorf("%w: %s", ErrRouteNotFound, route.Current())
+	}
+
+	// Check business hours
+	if target, closed := e.afterHours(userState); closed {
+		log.Printf("[INFO] Route %s is closed for chat %v, redirecting to %s",
+			route.Current(), userState.ChatID, target)
+		return &d_action.RedirectResponse{TargetRoute: target}, nil
+	}
+
+	// Buffered handlers collect their actions until they return normally
+	handlerRouter := router
+	buffered := routeFunc.HandlerOptions.Buffered
+	var buffer *actionBuffer
+	if buffered != nil {
+		buffer = newActionBuffer(router)
+		handlerRouter = buffer
 	}
 
 	// Create context with router
-	ctx, cancel := d_context.NewChatContext(
+	ctx, cancel := d_context.NewChatContextWithParent(
+		parent,
 		userState,
 		message,
-		router,
+		handlerRouter,
 		routeFunc.HandlerOptions.Timeout.Duration,
 	)
 	defer cancel()
 
-	// Channel to receive the result
+	// Channels to receive the result or a panic
 	resultChan := make(chan route_return.RouteReturn, 1)
+	panicChan := make(chan handlerPanic, 1)
+	run := &handlerRun{}
 
 	// Execute handler in goroutine
 	go func() {
+		defer e.abandoned.finish(run)
+		defer func() {
+			if value := recover(); value != nil {
+				panicChan <- handlerPanic{value: value, stack: debug.Stack()}
+			}
+		}()
 		resultChan <- routeFunc.Handler(&ctx)
 	}()
 
-	// Wait for result or timeout
+	// Keep the user informed while the handler is slow
+	stopProgress := startProgress(parent, routeFunc.HandlerOptions.Progress, userState, router)
+
+	// Wait for result, panic or timeout
 	select {
 	case result := <-resultChan:
+		stopProgress()
+		if buffer != nil {
+			if err := buffer.commit(bindExecutor(parent, router)); err != nil {
+				log.Printf("[ERROR] Failed to commit buffered actions for chat %v on route %s: %v",
+					userState.ChatID, route.Current(), err)
+			}
+		}
 		if result == nil {
 			return userState.Route.Next(userState.Route.Current()), nil
 		}
 		return result, nil
 
+	case p := <-panicChan:
+		stopProgress()
+		log.Printf("[ERROR] Handler panic for route %s: %v\n%s", route.Current(), p.value, p.stack)
+		discardBuffer(buffer, buffered, userState, bindExecutor(parent, router))
+		return nil, fmt.Errorf("%w: route %s: %v", ErrHandlerPanic, route.Current(), p.value)
+
 	case <-ctx.Done():
+		stopProgress()
+		// The handler cannot be stopped: track it until it returns
+		e.abandoned.abandon(run, AbandonedHandler{
+			Route:  route.Current(),
+			ChatID: userState.ChatID,
+			Since:  time.Now(),
+		})
+		discardBuffer(buffer, buffered, userState, bindExecutor(parent, router))
 		if ctx.Err() == context.DeadlineExceeded {
 			log.Printf("[ERROR] Handler timeout for route: %s", route.Current())
 			return &d_action.RedirectResponse{
@@ -173,6 +306,13 @@ func (e *Engine[Obs]) Execute(
 	}
 }
 
+// AbandonedHandlers returns the handlers that are still running after the
+// engine stopped waiting for them, oldest first. Handlers should honor
+// ctx.Done() so they stop when they time out.
+func (e *Engine[Obs]) AbandonedHandlers() []AbandonedHandler {
+	return e.abandoned.list()
+}
+
 // ValidateRoutes checks that all required routes are registered.
 // Returns an error if validation fails.
 func (e *Engine[Obs]) ValidateRoutes() error {
@@ -198,6 +338,45 @@ func (e *Engine[Obs]) ValidateRoutes() error {
 		}
 	}
 
+	// Check the business hours of the routes
+	for routeName, handler := range e.routes {
+		hours := handler.HandlerOptions.BusinessHours
+		if hours == nil {
+			continue
+		}
+		if hours.Calendars == nil {
+			return fmt.Errorf("business hours of route '%s' have no calendars", routeName)
+		}
+		if err := hours.Calendars.Validate(); err != nil {
+			return fmt.Errorf("business hours of route '%s': %w", routeName, err)
+		}
+		if _, exists := e.routes[hours.Route]; !exists {
+			return fmt.Errorf("business hours route '%s' in route '%s' is not registered", hours.Route, routeName)
+		}
+	}
+
+	// Closed routes must not redirect back to themselves through other ones
+	for routeName := range e.routes {
+		if cycle := e.afterHoursCycle(routeName); cycle != nil {
+			return fmt.Errorf("business hours routes of route '%s' form a cycle: %s",
+				routeName, strings.Join(cycle, " -> "))
+		}
+	}
+
+	// Check the inactivity options of the routes
+	for routeName, handler := range e.routes {
+		inactivity := handler.HandlerOptions.Inactivity
+		if inactivity == nil {
+			continue
+		}
+		if err := inactivity.Validate(); err != nil {
+			return fmt.Errorf("route '%s': %w", routeName, err)
+		}
+		if _, exists := e.routes[inactivity.Route]; inactivity.Route != "" && !exists {
+			return fmt.Errorf("inactivity route '%s' in route '%s' is not registered", inactivity.Route, routeName)
+		}
+	}
+
 	// Also check the default options triggers
 	rhoRoutes := e.defaultOptions.GetRhoRoutes()
 	for _, rhoRoute := range rhoRoutes {
@@ -1,11 +1,13 @@
 package service
 
 import (
+	"strings"
 	"testing"
 	"time"
 
 	route_return "github.com/irissonnlima/chatgraph-go/core/domain"
 	d_action "github.com/irissonnlima/chatgraph-go/core/domain/action"
+	d_calendar "github.com/irissonnlima/chatgraph-go/core/domain/calendar"
 	d_context "github.com/irissonnlima/chatgraph-go/core/domain/context"
 	d_message "github.com/irissonnlima/chatgraph-go/core/domain/message"
 	d_route "github.com/irissonnlima/chatgraph-go/core/domain/route"
@@ -267,6 +269,59 @@ func TestExecute_TriggerMatch(t *testing.T) {
 	}
 }
 
+// TestExecute_BusinessHours tests that routes with business hours redirect
+// the chats of closed companies, and run for the open ones.
+func TestExecute_BusinessHours(t *testing.T) {
+	open := d_calendar.Calendar{Weekly: map[time.Weekday][]d_calendar.Period{
+		time.Wednesday: {{Start: 8 * time.Hour, End: 18 * time.Hour}},
+	}}
+	hours := &d_router.BusinessHoursRouteOps{
+		Calendars: &d_calendar.Calendars{Default: open, Companies: map[string]d_calendar.Calendar{"closed": {}}},
+		Route:     "after_hours",
+		Now:       func() time.Time { return time.Date(2025, time.March, 12, 9, 0, 0, 0, time.UTC) },
+	}
+
+	engine := NewEngine[TestObs]()
+	engine.RegisterRoute("agent", func(ctx *d_context.ChatContext[TestObs]) route_return.RouteReturn {
+		return d_action.TransferToMenu{MenuID: 7}
+	}, d_router.RouterHandlerOptions{BusinessHours: hours})
+
+	tests := []struct {
+		company  string
+		redirect bool
+	}{
+		{company: "acme", redirect: false},
+		{company: "closed", redirect: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.company, func(t *testing.T) {
+			userState := d_user.UserState[TestObs]{
+				ChatID: d_user.ChatID{UserID: "u1", CompanyID: tt.company},
+				Route:  d_route.NewRoute("start.agent", '.'),
+			}
+
+			result, err := engine.Execute(userState, d_message.Message{}, newMockExecutor())
+			if err != nil {
+				t.Fatalf("Execute returned error: %v", err)
+			}
+
+			redirect, ok := result.(*d_action.RedirectResponse)
+			if ok != tt.redirect {
+				t.Fatalf("unexpected result %#v", result)
+			}
+			if ok && redirect.TargetRoute != "after_hours" {
+				t.Errorf("expected target route 'after_hours', got '%s'", redirect.TargetRoute)
+			}
+		})
+	}
+}
+
+// closedHours returns business hours closed at all times, redirecting to route.
+func closedHours(route string) *d_router.BusinessHoursRouteOps {
+	return &d_router.BusinessHoursRouteOps{Calendars: &d_calendar.Calendars{}, Route: route}
+}
+
 // TestExecute_LoopDetection tests loop detection.
 func TestExecute_LoopDetection(t *testing.T) {
 	engine := NewEngine[TestObs]()
@@ -490,6 +545,32 @@ func TestValidateRoutes_MissingRouteHandlerTrigger(t *testing.T) {
 	}
 }
 
+// TestValidateRoutes_AfterHoursCycle tests that routes whose after-hours
+// routes redirect back to them are rejected, since they would redirect
+// forever while all of them are closed.
+func TestValidateRoutes_AfterHoursCycle(t *testing.T) {
+	handler := func(ctx *d_context.ChatContext[TestObs]) route_return.RouteReturn {
+		return nil
+	}
+	engine := NewEngine[TestObs]()
+	engine.RegisterRoute("start", handler)
+	engine.RegisterRoute("timeout_route", handler)
+	engine.RegisterRoute("loop_route", handler)
+	engine.RegisterRoute("sales", handler, d_router.RouterHandlerOptions{BusinessHours: closedHours("support")})
+	engine.RegisterRoute("support", handler, d_router.RouterHandlerOptions{BusinessHours: closedHours("closed")})
+	engine.RegisterRoute("closed", handler, d_router.RouterHandlerOptions{BusinessHours: closedHours("closed")})
+
+	if err := engine.ValidateRoutes(); err != nil {
+		t.Fatalf("expected a chain ending on its own after-hours route to be valid, got %v", err)
+	}
+
+	engine.RegisterRoute("support", handler, d_router.RouterHandlerOptions{BusinessHours: closedHours("sales")})
+	err := engine.ValidateRoutes()
+	if err == nil || !strings.Contains(err.Error(), "cycle") {
+		t.Errorf("expected an error for the after-hours cycle, got %v", err)
+	}
+}
+
 // TestExecute_TriggerSameRoute tests that trigger doesn't redirect when already on that route.
 func TestExecute_TriggerSameRoute(t *testing.T) {
 	engine := NewEngine[TestObs]()
@@ -18,6 +18,9 @@ const (
 	ExecSetRoute
 	ExecGetFile
 	ExecUploadFile
+	ExecSendTyping
+	ExecEditMessage
+	ExecReact
 )
 
 // ExpectedAction represents an expected action during execution.
@@ -33,6 +36,10 @@ type ExpectedAction struct {
 	FileID   string
 	FilePath string
 	FileName string
+
+	// MessageID is the edited or reacted message.
+	MessageID string
+	Reaction  string
 }
 
 // EngineTester is a test helper class for validating Engine executions.
@@ -111,6 +118,17 @@ func (e *EngineTester[Obs]) validateActions(actual, expected []ExpectedAction) {
 			if exp.FilePath != "" && act.FilePath != exp.FilePath {
 				e.t.Errorf("Action %d: expected filePath %q, got %q", i, exp.FilePath, act.FilePath)
 			}
+		case ExecEditMessage:
+			if exp.MessageID != "" && act.MessageID != exp.MessageID {
+				e.t.Errorf("Action %d: expected messageID %q, got %q", i, exp.MessageID, act.MessageID)
+			}
+			if exp.Message != nil && !reflect.DeepEqual(act.Message, exp.Message) {
+				e.t.Errorf("Action %d: message mismatch\nExpected: %+v\nGot: %+v", i, exp.Message, act.Message)
+			}
+		case ExecReact:
+			if exp.Reaction != "" && act.Reaction != exp.Reaction {
+				e.t.Errorf("Action %d: expected reaction %q, got %q", i, exp.Reaction, act.Reaction)
+			}
 		}
 	}
 }
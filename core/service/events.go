@@ -0,0 +1,146 @@
+// Package service provides the main chatbot application service.
+// This file contains the handling of backend events, which run routes for
+// chats proactively, without an inbound message.
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+
+	d_event "github.com/irissonnlima/chatgraph-go/core/domain/event"
+	d_message "github.com/irissonnlima/chatgraph-go/core/domain/message"
+	d_session "github.com/irissonnlima/chatgraph-go/core/domain/session"
+	d_user "github.com/irissonnlima/chatgraph-go/core/domain/user"
+	adapter_input "github.com/irissonnlima/chatgraph-go/core/ports/adapters/input"
+	adapter_output "github.com/irissonnlima/chatgraph-go/core/ports/adapters/output"
+)
+
+var (
+	_ adapter_input.IEventHandler = (*ChatbotApp[struct{}])(nil)
+	_ adapter_input.IEventChecker = (*ChatbotApp[struct{}])(nil)
+)
+
+// ErrNoChatState is returned by HandleEvent when the executor keeps no chat
+// state (see adapter_output.IStateLoader).
+var ErrNoChatState = errors.New("the executor keeps no chat state")
+
+// HandleEvent executes the event's route for its chat, as if the user had
+// been redirected to it, and handles its result like HandleMessage does: the
+// route's options (timeout, buffering, progress...) apply and its actions
+// are idempotent per event ID. Handlers get the event from
+// ChatContext.Event.
+//
+// The route runs on the chat's worker, behind the messages queued for it, so
+// HandleEvent must not be called synchronously from a handler. The chat's
+// state is loaded right before it runs; a chat without stored state runs
+// with an empty one. Events without an ID get a random one.
+//
+// The executor must keep the chat's state (see adapter_output.IStateLoader):
+// otherwise the route would run on an empty state and overwrite the chat's,
+// so HandleEvent returns ErrNoChatState. The Router API client keeps none:
+// compose it with a session store (see CheckEvents).
+//
+// Events that cannot be handled are rejected with an error wrapping
+// adapter_input.ErrInvalidEvent. While AppOptions.MaxAbandonedHandlers is
+// reached the event waits, until ctx is done; then, while the executor is
+// unavailable, or while the chat is handed off to an agent (ErrChatHandedOff),
+// the error wraps adapter_input.ErrUnavailable, so the caller can retry later.
+func (app *ChatbotApp[Obs]) HandleEvent(ctx context.Context, event d_event.Event) error {
+	if err := event.Validate(); err != nil {
+		return fmt.Errorf("%w: %w", adapter_input.ErrInvalidEvent, err)
+	}
+	if _, exists := app.engine.routes[event.Route]; !exists {
+		return fmt.Errorf("%w: %w: %s", adapter_input.ErrInvalidEvent, ErrRouteNotFound, event.Route)
+	}
+	if event.ID == "" {
+		id, err := newID()
+		if err != nil {
+			return err
+		}
+		event.ID = id
+	}
+
+	if err := app.admit(ctx, event.ChatID); err != nil {
+		return fmt.Errorf("%w: %w", adapter_input.ErrUnavailable, err)
+	}
+
+	err := app.onChat(ctx, event.ChatID, func() error {
+		userState, _, err := app.storedState(event.ChatID)
+		if err != nil {
+			return fmt.Errorf("loading chat state: %w", err)
+		}
+		if event.Platform != "" {
+			userState.Platform = event.Platform
+		}
+
+		log.Printf("[INFO] Handling event %s (%s) for chat %v on route %s",
+			event.ID, event.Name, event.ChatID, event.Route)
+
+		// The event ID scopes the idempotency keys of the route.
+		message := d_message.Message{TextMessage: d_message.TextMessage{ID: "event:" + event.ID}}
+		return app.resume(d_event.WithEvent(ctx, event), userState, event.Route, message)
+	})
+	if errors.Is(err, ErrExecutorUnavailable) || errors.Is(err, ErrChatHandedOff) {
+		return fmt.Errorf("%w: %w", adapter_input.ErrUnavailable, err)
+	}
+	return err
+}
+
+// CheckEvents returns ErrNoChatState when the executor keeps no chat state,
+// so HandleEvent would refuse every event. Event sources call it when they
+// are created, to fail at startup instead of on the first event.
+func (app *ChatbotApp[Obs]) CheckEvents() error {
+	if _, _, err := app.storedState(d_user.ChatID{}); errors.Is(err, ErrNoChatState) {
+		return err
+	}
+	return nil
+}
+
+// loadState returns the stored state of a chat, when the executor keeps
+// one. Otherwise found is false and the state only holds the chat ID.
+func (app *ChatbotApp[Obs]) loadState(chatID d_user.ChatID) (d_user.UserState[Obs], bool, error) {
+	userState, found, err := app.storedState(chatID)
+	if errors.Is(err, ErrNoChatState) {
+		return userState, false, nil
+	}
+	return userState, found, err
+}
+
+// storedState is loadState, returning ErrNoChatState when the executor
+// keeps no state.
+func (app *ChatbotApp[Obs]) storedState(chatID d_user.ChatID) (d_user.UserState[Obs], bool, error) {
+	empty := d_user.UserState[Obs]{ChatID: chatID, DirectionIn: true}
+
+	loader, ok := app.botExecutor.(adapter_output.IStateLoader)
+	if !ok {
+		return empty, false, ErrNoChatState
+	}
+
+	session, found, err := loader.LoadState(chatID)
+	if errors.Is(err, adapter_output.ErrUnsupported) {
+		return empty, false, ErrNoChatState
+	}
+	if err != nil || !found {
+		return empty, false, err
+	}
+
+	userState, err := d_session.ToUserState[Obs](session)
+	if err != nil {
+		return empty, false, fmt.Errorf("decoding chat session: %w", err)
+	}
+	userState.ChatID = chatID
+	return userState, true, nil
+}
+
+// newID returns a random ID for jobs and events.
+func newID() (string, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", fmt.Errorf("generating ID: %w", err)
+	}
+	return hex.EncodeToString(id), nil
+}
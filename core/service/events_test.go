@@ -0,0 +1,212 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	route_return "github.com/irissonnlima/chatgraph-go/core/domain"
+	d_context "github.com/irissonnlima/chatgraph-go/core/domain/context"
+	d_event "github.com/irissonnlima/chatgraph-go/core/domain/event"
+	d_router "github.com/irissonnlima/chatgraph-go/core/domain/router"
+	d_session "github.com/irissonnlima/chatgraph-go/core/domain/session"
+	d_user "github.com/irissonnlima/chatgraph-go/core/domain/user"
+	adapter_input "github.com/irissonnlima/chatgraph-go/core/ports/adapters/input"
+	adapter_output "github.com/irissonnlima/chatgraph-go/core/ports/adapters/output"
+)
+
+// stateExecutor is a mock executor that keeps the session of chats.
+type stateExecutor struct {
+	*mockExecutor
+	sessions map[d_user.ChatID]d_session.Session
+}
+
+func (e *stateExecutor) LoadState(chatID d_user.ChatID) (d_session.Session, bool, error) {
+	session, ok := e.sessions[chatID]
+	return session, ok, nil
+}
+
+var eventChat = d_user.ChatID{UserID: "u1", CompanyID: "c1"}
+
+// newEventTest returns an app on executor whose "shipped" route greets the
+// user with the order of the event.
+func newEventTest(executor adapter_output.IBotExecutor) *ChatbotApp[TestObs] {
+	return newTestAppWithRoutes(&fakeReceiver{}, executor, map[string]d_router.RouteHandler[TestObs]{
+		"shipped": func(ctx *d_context.ChatContext[TestObs]) route_return.RouteReturn {
+			event, _ := ctx.Event()
+			var payload struct {
+				Order string `json:"order"`
+			}
+			if err := event.DecodePayload(&payload); err != nil {
+				ctx.SendTextMessage("no payload: " + err.Error())
+				return nil
+			}
+			ctx.SendTextMessage(ctx.GetObservation().Value + ", order " + payload.Order + " shipped on " + ctx.UserState.Platform)
+			return nil
+		},
+	})
+}
+
+// TestHandleEvent_LoadsState tests that the event's route runs with the
+// chat's stored state and its result is applied.
+func TestHandleEvent_LoadsState(t *testing.T) {
+	executor := &stateExecutor{mockExecutor: newMockExecutor(), sessions: map[d_user.ChatID]d_session.Session{
+		eventChat: {ChatID: eventChat, Route: "start", Observation: `{"value":"Ana"}`, Platform: "whatsapp"},
+	}}
+	app := newEventTest(executor)
+
+	err := app.HandleEvent(context.Background(), d_event.Event{
+		ID: "e1", ChatID: eventChat, Route: "shipped", Payload: `{"order":"42"}`,
+	})
+	if err != nil {
+		t.Fatalf("HandleEvent() error = %v", err)
+	}
+
+	texts := sentTexts(executor.mockExecutor)
+	if len(texts) != 1 || texts[0] != "Ana, order 42 shipped on whatsapp" {
+		t.Errorf("unexpected messages %v", texts)
+	}
+	last := executor.expectedExec[len(executor.expectedExec)-1]
+	if last.Type != ExecSetRoute || last.Route != "shipped" {
+		t.Errorf("expected the route to be set to shipped, got %+v", last)
+	}
+}
+
+// TestHandleEvent_BusinessHours tests that an event resuming a chat on a
+// closed route runs the after-hours route instead.
+func TestHandleEvent_BusinessHours(t *testing.T) {
+	executor := &stateExecutor{mockExecutor: newMockExecutor(), sessions: map[d_user.ChatID]d_session.Session{}}
+	app := newEventTest(executor)
+	app.engine.RegisterRoute("agent", func(ctx *d_context.ChatContext[TestObs]) route_return.RouteReturn {
+		ctx.SendTextMessage("Talking to an agent")
+		return nil
+	}, d_router.RouterHandlerOptions{BusinessHours: closedHours("after_hours")})
+	app.engine.RegisterRoute("after_hours", func(ctx *d_context.ChatContext[TestObs]) route_return.RouteReturn {
+		ctx.SendTextMessage("We are closed")
+		return nil
+	})
+
+	if err := app.HandleEvent(context.Background(), d_event.Event{ID: "e1", ChatID: eventChat, Route: "agent"}); err != nil {
+		t.Fatalf("HandleEvent() error = %v", err)
+	}
+	if texts := sentTexts(executor.mockExecutor); len(texts) != 1 || texts[0] != "We are closed" {
+		t.Errorf("expected the after-hours route to run, got %v", texts)
+	}
+}
+
+// TestHandleEvent_WithoutStoredState tests that chats without stored state
+// run with an empty state, on the event's platform.
+func TestHandleEvent_WithoutStoredState(t *testing.T) {
+	executor := &stateExecutor{mockExecutor: newMockExecutor(), sessions: map[d_user.ChatID]d_session.Session{}}
+	app := newEventTest(executor)
+
+	err := app.HandleEvent(context.Background(), d_event.Event{
+		ChatID: eventChat, Route: "shipped", Platform: "telegram", Payload: `{"order":"7"}`,
+	})
+	if err != nil {
+		t.Fatalf("HandleEvent() error = %v", err)
+	}
+
+	texts := sentTexts(executor.mockExecutor)
+	if len(texts) != 1 || texts[0] != ", order 7 shipped on telegram" {
+		t.Errorf("unexpected messages %v", texts)
+	}
+}
+
+// TestHandleEvent_Errors tests the errors callers use to decide whether to
+// retry an event.
+func TestHandleEvent_Errors(t *testing.T) {
+	valid := d_event.Event{ID: "e1", ChatID: eventChat, Route: "shipped"}
+
+	tests := []struct {
+		name      string
+		event     d_event.Event
+		available bool
+		expected  []error
+	}{
+		{name: "missing chat", event: d_event.Event{Route: "shipped"}, available: true,
+			expected: []error{adapter_input.ErrInvalidEvent, d_event.ErrMissingChatID}},
+		{name: "unknown route", event: d_event.Event{ChatID: eventChat, Route: "missing"}, available: true,
+			expected: []error{adapter_input.ErrInvalidEvent, ErrRouteNotFound}},
+		{name: "executor unavailable", event: valid, available: false,
+			expected: []error{adapter_input.ErrUnavailable, ErrExecutorUnavailable}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			executor := &unavailableExecutor{mockExecutor: newMockExecutor(), available: tt.available}
+			app := newEventTest(executor)
+
+			err := app.HandleEvent(context.Background(), tt.event)
+			for _, target := range tt.expected {
+				if !errors.Is(err, target) {
+					t.Errorf("expected %v, got %v", target, err)
+				}
+			}
+			if len(executor.expectedExec) != 0 {
+				t.Errorf("expected no actions, got %+v", executor.expectedExec)
+			}
+		})
+	}
+}
+
+// TestHandleEvent_RequiresState tests that events are refused when the
+// executor keeps no chat state, instead of running on an empty one.
+func TestHandleEvent_RequiresState(t *testing.T) {
+	executor := newMockExecutor()
+	app := newEventTest(executor)
+
+	err := app.HandleEvent(context.Background(), d_event.Event{ChatID: eventChat, Route: "shipped"})
+	if !errors.Is(err, ErrNoChatState) {
+		t.Fatalf("expected ErrNoChatState, got %v", err)
+	}
+	if len(executor.expectedExec) != 0 {
+		t.Errorf("expected no actions, got %+v", executor.expectedExec)
+	}
+}
+
+// TestCheckEvents tests that apps whose executor keeps no chat state are
+// reported upfront.
+func TestCheckEvents(t *testing.T) {
+	if err := newEventTest(newMockExecutor()).CheckEvents(); !errors.Is(err, ErrNoChatState) {
+		t.Errorf("expected ErrNoChatState without chat state, got %v", err)
+	}
+
+	executor := &stateExecutor{mockExecutor: newMockExecutor(), sessions: map[d_user.ChatID]d_session.Session{}}
+	if err := newEventTest(executor).CheckEvents(); err != nil {
+		t.Errorf("expected no error with chat state, got %v", err)
+	}
+}
+
+// TestHandleEvent_RunsOnChatWorker tests that an event waits for the
+// messages queued for its chat.
+func TestHandleEvent_RunsOnChatWorker(t *testing.T) {
+	executor := &stateExecutor{mockExecutor: newMockExecutor(), sessions: map[d_user.ChatID]d_session.Session{}}
+	app := newEventTest(executor)
+
+	release := make(chan struct{})
+	app.pool.start()
+	defer app.pool.stop()
+	app.pool.do(eventChat, func() { <-release })
+
+	done := make(chan error, 1)
+	go func() {
+		done <- app.HandleEvent(context.Background(), d_event.Event{ChatID: eventChat, Route: "shipped"})
+	}()
+
+	select {
+	case err := <-done:
+		t.Fatalf("expected the event to wait for the chat's worker, got %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(release)
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("HandleEvent() error = %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected the event to run once the chat's worker is free")
+	}
+}
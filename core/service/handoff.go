@@ -0,0 +1,519 @@
+// Package service provides the main chatbot application service.
+// This file contains the HandoffDesk, which hands conversations off to human
+// agents and pauses the bot for them until the agent closes the ticket.
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"sync"
+	"time"
+
+	d_action "github.com/irissonnlima/chatgraph-go/core/domain/action"
+	d_department "github.com/irissonnlima/chatgraph-go/core/domain/department"
+	d_handoff "github.com/irissonnlima/chatgraph-go/core/domain/handoff"
+	d_idempotency "github.com/irissonnlima/chatgraph-go/core/domain/idempotency"
+	d_message "github.com/irissonnlima/chatgraph-go/core/domain/message"
+	d_session "github.com/irissonnlima/chatgraph-go/core/domain/session"
+	d_user "github.com/irissonnlima/chatgraph-go/core/domain/user"
+	adapter_output "github.com/irissonnlima/chatgraph-go/core/ports/adapters/output"
+)
+
+// Default values of HandoffOptions.
+const (
+	// DEFAULT_HANDOFF_RESUME_ROUTE is where the bot resumes when a ticket is
+	// closed and neither the handoff nor the agent chose a route.
+	DEFAULT_HANDOFF_RESUME_ROUTE = "start"
+	// DEFAULT_HANDOFF_HANDLE_TIME is the average time an agent spends on a ticket.
+	DEFAULT_HANDOFF_HANDLE_TIME = 5 * time.Minute
+	// DEFAULT_HANDOFF_AGENTS is the number of agents serving each queue.
+	DEFAULT_HANDOFF_AGENTS = 1
+)
+
+// Errors returned by HandoffDesk.
+var (
+	// ErrUnknownDepartment is returned when handing a chat off to a
+	// department that is not in HandoffOptions.Departments.
+	ErrUnknownDepartment = errors.New("unknown department")
+	// ErrTicketNotFound is returned for tickets that are not open, e.g.
+	// because they were closed.
+	ErrTicketNotFound = errors.New("handoff ticket not found")
+	// ErrChatHandedOff is returned when resuming a chat that has an open
+	// ticket, e.g. for an event or a job: the bot is paused for the chat
+	// until the ticket is closed.
+	ErrChatHandedOff = errors.New("chat is handed off to an agent")
+)
+
+// HandoffOptions configures a HandoffDesk.
+type HandoffOptions struct {
+	// Departments are the departments chats can be handed off to. When
+	// empty, any department ID is accepted.
+	Departments []d_department.Department
+	// ResumeRoute is where the bot resumes when a ticket is closed, unless
+	// the handoff or the agent chose a route. Defaults to DEFAULT_HANDOFF_RESUME_ROUTE.
+	ResumeRoute string
+	// HandleTime is the average time an agent spends on a ticket, used to
+	// estimate the wait. Defaults to DEFAULT_HANDOFF_HANDLE_TIME.
+	HandleTime time.Duration
+	// Agents is the number of agents serving each queue at a time, used to
+	// estimate the wait. Defaults to DEFAULT_HANDOFF_AGENTS.
+	Agents int
+	// QueueMessage returns the message telling a queued user their position
+	// and estimated wait. It is sent on handoff and whenever the user writes
+	// while queued. Defaults to an English text.
+	QueueMessage func(position int, wait time.Duration) d_message.Message
+	// AssignedMessage, if set, is sent to the user when an agent takes the ticket.
+	AssignedMessage *d_message.Message
+	// Now returns the current time. Defaults to time.Now.
+	Now func() time.Time
+}
+
+// withDefaults returns a copy of the options with defaults applied.
+func (o HandoffOptions) withDefaults() HandoffOptions {
+	if o.ResumeRoute == "" {
+		o.ResumeRoute = DEFAULT_HANDOFF_RESUME_ROUTE
+	}
+	if o.HandleTime <= 0 {
+		o.HandleTime = DEFAULT_HANDOFF_HANDLE_TIME
+	}
+	if o.Agents <= 0 {
+		o.Agents = DEFAULT_HANDOFF_AGENTS
+	}
+	if o.QueueMessage == nil {
+		o.QueueMessage = defaultQueueMessage
+	}
+	if o.Now == nil {
+		o.Now = time.Now
+	}
+	return o
+}
+
+// defaultQueueMessage tells the user their position and estimated wait.
+func defaultQueueMessage(position int, wait time.Duration) d_message.Message {
+	minutes := int(math.Ceil(wait.Minutes()))
+	text := fmt.Sprintf("You are number %d in the queue. Estimated wait: %d minute(s).", position, minutes)
+	return d_message.Message{TextMessage: d_message.TextMessage{Detail: text}}
+}
+
+// HandoffDesk hands conversations off to human agents. A route hands its
+// chat off by returning a d_action.HandoffToAgent: the desk opens a ticket
+// in the queue of the department (or agent), tells the console with a
+// summary of the conversation and tells the user their queue position and
+// estimated wait.
+//
+// While the chat has an open ticket the engine is paused for it: the app
+// relays the user's messages to the console instead of running routes.
+// Agents take tickets with Take or Next, answer with Send and finish with
+// Close, which resumes the bot at the ticket's resume route or the one the
+// agent chose.
+//
+// Tickets are kept in an IHandoffRepository, so with a durable repository
+// handed-off chats stay paused across restarts. Closed tickets are removed:
+// the console is the record of past tickets.
+type HandoffDesk[Obs any] struct {
+	app        *ChatbotApp[Obs]
+	repository adapter_output.IHandoffRepository
+	console    adapter_output.IAgentConsole
+	options    HandoffOptions
+
+	// mu serializes the changes to tickets, so a ticket is not taken or
+	// closed twice.
+	mu sync.Mutex
+}
+
+// NewHandoffDesk creates a desk for the chats of app and attaches it to app,
+// which hands chats off and pauses them from then on. Create it before
+// starting the app.
+func NewHandoffDesk[Obs any](
+	app *ChatbotApp[Obs],
+	repository adapter_output.IHandoffRepository,
+	console adapter_output.IAgentConsole,
+	options ...HandoffOptions,
+) *HandoffDesk[Obs] {
+	opts := HandoffOptions{}
+	if len(options) > 0 {
+		opts = options[0]
+	}
+
+	desk := &HandoffDesk[Obs]{
+		app:        app,
+		repository: repository,
+		console:    console,
+		options:    opts.withDefaults(),
+	}
+	app.handoff = desk
+	return desk
+}
+
+// Ticket returns the open ticket of a chat. ok is false when the chat is not
+// handed off.
+func (d *HandoffDesk[Obs]) Ticket(chatID d_user.ChatID) (ticket d_handoff.Ticket, ok bool, err error) {
+	return d.repository.Get(chatID)
+}
+
+// Tickets returns every open ticket, oldest first.
+func (d *HandoffDesk[Obs]) Tickets() ([]d_handoff.Ticket, error) {
+	return d.repository.List()
+}
+
+// Queue returns the tickets waiting in a queue, oldest first. Queues are
+// named by d_handoff.QueueOf.
+func (d *HandoffDesk[Obs]) Queue(queue string) ([]d_handoff.Ticket, error) {
+	tickets, err := d.repository.List()
+	if err != nil {
+		return nil, err
+	}
+
+	queued := make([]d_handoff.Ticket, 0, len(tickets))
+	for _, ticket := range tickets {
+		if ticket.Status == d_handoff.QUEUED && ticket.Queue() == queue {
+			queued = append(queued, ticket)
+		}
+	}
+	return queued, nil
+}
+
+// Position returns the position of a queued ticket in its queue, starting
+// at 1, and its estimated wait. position is 0 once an agent took the ticket.
+func (d *HandoffDesk[Obs]) Position(ticket d_handoff.Ticket) (position int, wait time.Duration, err error) {
+	if ticket.Status != d_handoff.QUEUED {
+		return 0, 0, nil
+	}
+	queued, err := d.Queue(ticket.Queue())
+	if err != nil {
+		return 0, 0, err
+	}
+	for i, t := range queued {
+		if t.ID == ticket.ID {
+			position = i + 1
+			break
+		}
+	}
+	return position, d.estimate(position), nil
+}
+
+// estimate returns the estimated wait of the ticket at position in its
+// queue: the agents take tickets Agents at a time, HandleTime each.
+func (d *HandoffDesk[Obs]) estimate(position int) time.Duration {
+	rounds := (position + d.options.Agents - 1) / d.options.Agents
+	return time.Duration(rounds) * d.options.HandleTime
+}
+
+// department returns the department of a handoff.
+func (d *HandoffDesk[Obs]) department(id string) (d_department.Department, error) {
+	if len(d.options.Departments) == 0 {
+		return d_department.Department{ID: id}, nil
+	}
+	for _, department := range d.options.Departments {
+		if department.ID == id {
+			return department, nil
+		}
+	}
+	return d_department.Department{}, fmt.Errorf("%w: %s", ErrUnknownDepartment, id)
+}
+
+// open hands the chat of userState off as requested by its route, unless it
+// already has an open ticket.
+func (d *HandoffDesk[Obs]) open(
+	executor adapter_output.IBotExecutor,
+	userState d_user.UserState[Obs],
+	message d_message.Message,
+	handoff d_action.HandoffToAgent,
+) error {
+	department, err := d.department(handoff.DepartmentID)
+	if err != nil {
+		return err
+	}
+	resumeRoute := handoff.ResumeRoute
+	if resumeRoute == "" {
+		resumeRoute = d.options.ResumeRoute
+	}
+	if _, exists := d.app.engine.routes[resumeRoute]; !exists {
+		return fmt.Errorf("%w: %s", ErrRouteNotFound, resumeRoute)
+	}
+	session, err := d_session.FromUserState(userState)
+	if err != nil {
+		return fmt.Errorf("encoding handoff session: %w", err)
+	}
+	id, err := newID()
+	if err != nil {
+		return err
+	}
+
+	ticket := d_handoff.Ticket{
+		ID:          id,
+		Session:     session,
+		Department:  department,
+		Agent:       handoff.Agent,
+		Status:      d_handoff.QUEUED,
+		Reason:      handoff.Reason,
+		ResumeRoute: resumeRoute,
+		LastMessage: message.TextMessage.Detail,
+		CreatedAt:   d.options.Now(),
+	}
+
+	d.mu.Lock()
+	if existing, ok, err := d.repository.Get(ticket.ChatID()); err != nil || ok {
+		d.mu.Unlock()
+		if err != nil {
+			return fmt.Errorf("loading handoff ticket: %w", err)
+		}
+		log.Printf("[WARN] Chat %v is already handed off with ticket %s", ticket.ChatID(), existing.ID)
+		return nil
+	}
+	err = d.repository.Save(ticket)
+	d.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("saving handoff ticket: %w", err)
+	}
+
+	log.Printf("[INFO] Chat %v handed off to queue %s with ticket %s", ticket.ChatID(), ticket.Queue(), ticket.ID)
+
+	if err := d.console.TicketOpened(ticket, ticket.Summary()); err != nil {
+		log.Printf("[ERROR] Failed to open ticket %s in the agent console: %v", ticket.ID, err)
+	}
+	return d.sendPosition(executor, ticket)
+}
+
+// intercept relays a message of a handed-off chat to the agent console.
+// handled is false when the chat has no open ticket, so the engine handles
+// the message. Queued users are told their position again; failing to tell
+// them is only logged, so a redelivery does not relay the message twice.
+func (d *HandoffDesk[Obs]) intercept(
+	userState d_user.UserState[Obs],
+	message d_message.Message,
+) (handled bool, err error) {
+	ticket, ok, err := d.repository.Get(userState.ChatID)
+	if err != nil {
+		return true, fmt.Errorf("loading handoff ticket: %w", err)
+	}
+	if !ok {
+		return false, nil
+	}
+
+	if err := d.console.Relay(ticket, message); err != nil {
+		return true, fmt.Errorf("relaying message of ticket %s: %w", ticket.ID, err)
+	}
+	if ticket.Status == d_handoff.QUEUED {
+		ctx := d.scope(context.Background(), ticket, message.TextMessage.ID)
+		if err := d.sendPosition(bindExecutor(ctx, d.app.botExecutor), ticket); err != nil {
+			log.Printf("[ERROR] Failed to send the queue position of ticket %s: %v", ticket.ID, err)
+		}
+	}
+	return true, nil
+}
+
+// handedOff reports whether a chat has an open ticket, so the bot is paused
+// for it.
+func (app *ChatbotApp[Obs]) handedOff(chatID d_user.ChatID) (bool, error) {
+	if app.handoff == nil {
+		return false, nil
+	}
+	_, ok, err := app.handoff.Ticket(chatID)
+	if err != nil {
+		return false, fmt.Errorf("loading handoff ticket: %w", err)
+	}
+	return ok, nil
+}
+
+// sendPosition tells the user of a queued ticket their position and
+// estimated wait.
+func (d *HandoffDesk[Obs]) sendPosition(executor adapter_output.IBotExecutor, ticket d_handoff.Ticket) error {
+	position, wait, err := d.Position(ticket)
+	if err != nil || position == 0 {
+		return err
+	}
+	message := d.options.QueueMessage(position, wait)
+	return executor.SendMessage(ticket.ChatID(), message, ticket.Session.Platform)
+}
+
+// find returns the open ticket with an ID. The caller holds d.mu.
+func (d *HandoffDesk[Obs]) find(ticketID string) (d_handoff.Ticket, error) {
+	tickets, err := d.repository.List()
+	if err != nil {
+		return d_handoff.Ticket{}, err
+	}
+	for _, ticket := range tickets {
+		if ticket.ID == ticketID {
+			return ticket, nil
+		}
+	}
+	return d_handoff.Ticket{}, fmt.Errorf("%w: %s", ErrTicketNotFound, ticketID)
+}
+
+// Take assigns a ticket to an agent, e.g. one the agent picked in the
+// console, and sends AssignedMessage to the user. Taking an assigned ticket
+// moves it to the agent.
+func (d *HandoffDesk[Obs]) Take(ctx context.Context, ticketID, agent string) (d_handoff.Ticket, error) {
+	d.mu.Lock()
+	ticket, err := d.find(ticketID)
+	if err == nil {
+		ticket, err = d.assign(ticket, agent)
+	}
+	d.mu.Unlock()
+	if err != nil {
+		return d_handoff.Ticket{}, err
+	}
+	d.greet(ctx, ticket)
+	return ticket, nil
+}
+
+// Next assigns the oldest ticket waiting in a queue to an agent. ok is false
+// when the queue is empty.
+func (d *HandoffDesk[Obs]) Next(ctx context.Context, queue, agent string) (ticket d_handoff.Ticket, ok bool, err error) {
+	d.mu.Lock()
+	queued, err := d.Queue(queue)
+	if err == nil && len(queued) > 0 {
+		ticket, err = d.assign(queued[0], agent)
+		ok = err == nil
+	}
+	d.mu.Unlock()
+	if !ok {
+		return d_handoff.Ticket{}, false, err
+	}
+	d.greet(ctx, ticket)
+	return ticket, true, nil
+}
+
+// assign stores a ticket as assigned to agent. The caller holds d.mu.
+func (d *HandoffDesk[Obs]) assign(ticket d_handoff.Ticket, agent string) (d_handoff.Ticket, error) {
+	if ticket.Status == d_handoff.QUEUED {
+		ticket.AssignedAt = d.options.Now()
+	}
+	ticket.Status = d_handoff.ASSIGNED
+	ticket.Agent = agent
+	if err := d.repository.Save(ticket); err != nil {
+		return d_handoff.Ticket{}, fmt.Errorf("saving handoff ticket: %w", err)
+	}
+	log.Printf("[INFO] Ticket %s of chat %v taken by agent %s", ticket.ID, ticket.ChatID(), agent)
+	return ticket, nil
+}
+
+// greet sends AssignedMessage to the user of a ticket just taken.
+func (d *HandoffDesk[Obs]) greet(ctx context.Context, ticket d_handoff.Ticket) {
+	if d.options.AssignedMessage == nil {
+		return
+	}
+	executor := bindExecutor(d.scope(ctx, ticket, "assigned:"+ticket.Agent), d.app.botExecutor)
+	if err := executor.SendMessage(ticket.ChatID(), *d.options.AssignedMessage, ticket.Session.Platform); err != nil {
+		log.Printf("[ERROR] Failed to tell chat %v its ticket was taken: %v", ticket.ChatID(), err)
+	}
+}
+
+// Send delivers a message from the agent of a ticket to its user. Pass the
+// console's message ID in message.TextMessage.ID, so sending it again does
+// not repeat it with idempotent executors.
+func (d *HandoffDesk[Obs]) Send(ctx context.Context, ticketID string, message d_message.Message) error {
+	d.mu.Lock()
+	ticket, err := d.find(ticketID)
+	d.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	executor := bindExecutor(d.scope(ctx, ticket, message.TextMessage.ID), d.app.botExecutor)
+	return executor.SendMessage(ticket.ChatID(), message, ticket.Session.Platform)
+}
+
+// Close closes a ticket and resumes the bot for its chat at route, or at
+// the ticket's ResumeRoute when route is empty, as if the user had been
+// redirected to it. The route runs on the chat's worker, behind the messages
+// queued for the chat. While the executor is unavailable, or when resuming
+// fails, the ticket is kept open and the error is returned, so the agent
+// retries later.
+func (d *HandoffDesk[Obs]) Close(ctx context.Context, ticketID, route string) (d_handoff.Ticket, error) {
+	d.mu.Lock()
+	ticket, err := d.find(ticketID)
+	d.mu.Unlock()
+	if err != nil {
+		return d_handoff.Ticket{}, err
+	}
+	if route == "" {
+		route = ticket.ResumeRoute
+	}
+	if _, exists := d.app.engine.routes[route]; !exists {
+		return d_handoff.Ticket{}, fmt.Errorf("%w: %s", ErrRouteNotFound, route)
+	}
+	if !d.app.available() {
+		return d_handoff.Ticket{}, ErrExecutorUnavailable
+	}
+
+	err = d.app.onChat(ctx, ticket.ChatID(), func() error {
+		ticket, err = d.resume(ctx, ticketID, route)
+		return err
+	})
+	if err != nil {
+		return d_handoff.Ticket{}, err
+	}
+
+	ticket.Status = d_handoff.CLOSED
+	ticket.ClosedAt = d.options.Now()
+	return ticket, nil
+}
+
+// resume removes a ticket and executes route for its chat, with the chat's
+// stored state or the ticket's snapshot. The ticket is removed first, so
+// the route may hand the chat off again, and restored when resuming fails.
+func (d *HandoffDesk[Obs]) resume(ctx context.Context, ticketID, route string) (d_handoff.Ticket, error) {
+	d.mu.Lock()
+	ticket, err := d.find(ticketID)
+	if err == nil {
+		if err = d.repository.Delete(ticket.ChatID()); err != nil {
+			err = fmt.Errorf("deleting handoff ticket: %w", err)
+		}
+	}
+	d.mu.Unlock()
+	if err != nil {
+		return d_handoff.Ticket{}, err
+	}
+
+	log.Printf("[INFO] Ticket %s of chat %v closed, resuming at route %s", ticket.ID, ticket.ChatID(), route)
+	if err := d.resumeChat(ctx, ticket, route); err != nil {
+		log.Printf("[ERROR] Failed to resume chat %v, reopening ticket %s: %v", ticket.ChatID(), ticket.ID, err)
+		d.restore(ticket)
+		return d_handoff.Ticket{}, err
+	}
+	return ticket, nil
+}
+
+// resumeChat executes route for the chat of a removed ticket.
+func (d *HandoffDesk[Obs]) resumeChat(ctx context.Context, ticket d_handoff.Ticket, route string) error {
+	userState, found, err := d.app.loadState(ticket.ChatID())
+	if err != nil {
+		return fmt.Errorf("loading chat state: %w", err)
+	}
+	if !found {
+		if userState, err = d_session.ToUserState[Obs](ticket.Session); err != nil {
+			return fmt.Errorf("decoding handoff session: %w", err)
+		}
+	}
+
+	// The ticket ID scopes the idempotency keys of the resume route.
+	message := d_message.Message{TextMessage: d_message.TextMessage{ID: "handoff:" + ticket.ID}}
+	return d.app.resume(ctx, userState, route, message)
+}
+
+// restore stores a removed ticket again, unless its chat was handed off
+// again meanwhile.
+func (d *HandoffDesk[Obs]) restore(ticket d_handoff.Ticket) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if _, ok, err := d.repository.Get(ticket.ChatID()); err != nil || ok {
+		return
+	}
+	if err := d.repository.Save(ticket); err != nil {
+		log.Printf("[ERROR] Failed to restore handoff ticket %s of chat %v: %v", ticket.ID, ticket.ChatID(), err)
+	}
+}
+
+// scope returns ctx with the idempotency scope of an action of a ticket.
+// An action without an ID gets no scope.
+func (d *HandoffDesk[Obs]) scope(ctx context.Context, ticket d_handoff.Ticket, actionID string) context.Context {
+	if actionID == "" {
+		return ctx
+	}
+	return d_idempotency.WithScope(ctx, d_idempotency.NewScope(ticket.ChatID(), "handoff:"+ticket.ID+":"+actionID, ""))
+}
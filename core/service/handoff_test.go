@@ -0,0 +1,411 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/irissonnlima/chatgraph-go/core/clocktest"
+	route_return "github.com/irissonnlima/chatgraph-go/core/domain"
+	d_action "github.com/irissonnlima/chatgraph-go/core/domain/action"
+	d_context "github.com/irissonnlima/chatgraph-go/core/domain/context"
+	d_department "github.com/irissonnlima/chatgraph-go/core/domain/department"
+	d_event "github.com/irissonnlima/chatgraph-go/core/domain/event"
+	d_handoff "github.com/irissonnlima/chatgraph-go/core/domain/handoff"
+	d_message "github.com/irissonnlima/chatgraph-go/core/domain/message"
+	d_route "github.com/irissonnlima/chatgraph-go/core/domain/route"
+	d_router "github.com/irissonnlima/chatgraph-go/core/domain/router"
+	d_user "github.com/irissonnlima/chatgraph-go/core/domain/user"
+	adapter_input "github.com/irissonnlima/chatgraph-go/core/ports/adapters/input"
+)
+
+// handoffStore is an in-memory IHandoffRepository.
+type handoffStore struct {
+	mu      sync.Mutex
+	tickets map[d_user.ChatID]d_handoff.Ticket
+}
+
+func newHandoffStore() *handoffStore {
+	return &handoffStore{tickets: make(map[d_user.ChatID]d_handoff.Ticket)}
+}
+
+func (s *handoffStore) Save(ticket d_handoff.Ticket) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tickets[ticket.ChatID()] = ticket
+	return nil
+}
+
+func (s *handoffStore) Get(chatID d_user.ChatID) (d_handoff.Ticket, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ticket, ok := s.tickets[chatID]
+	return ticket, ok, nil
+}
+
+func (s *handoffStore) List() ([]d_handoff.Ticket, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	tickets := make([]d_handoff.Ticket, 0, len(s.tickets))
+	for _, ticket := range s.tickets {
+		tickets = append(tickets, ticket)
+	}
+	sort.Slice(tickets, func(i, j int) bool { return tickets[i].CreatedAt.Before(tickets[j].CreatedAt) })
+	return tickets, nil
+}
+
+func (s *handoffStore) Delete(chatID d_user.ChatID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.tickets, chatID)
+	return nil
+}
+
+func (s *handoffStore) Close() error {
+	return nil
+}
+
+// fakeConsole is an IAgentConsole that records tickets and relayed messages.
+type fakeConsole struct {
+	summaries []string
+	relayed   []string
+}
+
+func (c *fakeConsole) TicketOpened(ticket d_handoff.Ticket, summary string) error {
+	c.summaries = append(c.summaries, summary)
+	return nil
+}
+
+func (c *fakeConsole) Relay(ticket d_handoff.Ticket, message d_message.Message) error {
+	c.relayed = append(c.relayed, message.TextMessage.Detail)
+	return nil
+}
+
+// newHandoffTest returns an app whose "support" route hands chats off to
+// the billing department and whose "after_support" route thanks the user.
+func newHandoffTest() (*unavailableExecutor, *clocktest.FakeClock, *HandoffDesk[TestObs], *fakeConsole) {
+	bot := func(ctx *d_context.ChatContext[TestObs]) route_return.RouteReturn {
+		ctx.SendTextMessage("bot")
+		return nil
+	}
+	executor := &unavailableExecutor{mockExecutor: newMockExecutor(), available: true}
+	app := newTestAppWithRoutes(&fakeReceiver{}, executor, map[string]d_router.RouteHandler[TestObs]{
+		"start": bot,
+		"menu":  bot,
+		"support": func(ctx *d_context.ChatContext[TestObs]) route_return.RouteReturn {
+			return d_action.HandoffToAgent{DepartmentID: "billing", Reason: "invoice", ResumeRoute: "after_support"}
+		},
+		"after_support": func(ctx *d_context.ChatContext[TestObs]) route_return.RouteReturn {
+			ctx.SendTextMessage("Thanks, " + ctx.GetObservation().Value)
+			return nil
+		},
+	})
+	clock := clocktest.NewFakeClock(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+	console := &fakeConsole{}
+	desk := NewHandoffDesk(app, newHandoffStore(), console, HandoffOptions{
+		Departments: []d_department.Department{{ID: "billing", Name: "Billing"}},
+		HandleTime:  4 * time.Minute,
+		Now:         clock.Now,
+	})
+	return executor, clock, desk, console
+}
+
+// handOff hands the chat of a user off through the "support" route.
+func handOff(t *testing.T, app *ChatbotApp[TestObs], userID string) d_user.ChatID {
+	t.Helper()
+	chatID := d_user.ChatID{UserID: userID, CompanyID: "c1"}
+	userState := d_user.UserState[TestObs]{
+		ChatID:      chatID,
+		Route:       d_route.NewRoute("start.menu.support", '.'),
+		Observation: TestObs{Value: userID},
+		Platform:    "whatsapp",
+	}
+	message := d_message.Message{TextMessage: d_message.TextMessage{Detail: "I need help"}}
+	if err := app.HandleMessage(userState, message); err != nil {
+		t.Fatalf("HandleMessage() error = %v", err)
+	}
+	return chatID
+}
+
+// userWrites handles a message of a user left on the "menu" route.
+func userWrites(t *testing.T, app *ChatbotApp[TestObs], chatID d_user.ChatID, text string) {
+	t.Helper()
+	userState := d_user.UserState[TestObs]{
+		ChatID:      chatID,
+		Route:       d_route.NewRoute("start.menu", '.'),
+		Observation: TestObs{Value: chatID.UserID},
+	}
+	message := d_message.Message{TextMessage: d_message.TextMessage{Detail: text}}
+	if err := app.HandleMessage(userState, message); err != nil {
+		t.Fatalf("HandleMessage() error = %v", err)
+	}
+}
+
+// TestHandoffDesk_QueuesAndPausesBot tests that a handoff opens a ticket
+// with a summary, tells the user their position and relays their messages
+// instead of running routes.
+func TestHandoffDesk_QueuesAndPausesBot(t *testing.T) {
+	executor, clock, desk, console := newHandoffTest()
+	first := handOff(t, desk.app, "ana")
+	clock.Advance(time.Second)
+	handOff(t, desk.app, "bia")
+
+	ticket, ok, _ := desk.Ticket(first)
+	if !ok || ticket.Status != d_handoff.QUEUED || ticket.Queue() != "department:billing" {
+		t.Fatalf("expected a queued ticket, got %+v", ticket)
+	}
+	if len(console.summaries) != 2 || console.summaries[0] != ticket.Summary() {
+		t.Errorf("expected the console to get the summaries, got %v", console.summaries)
+	}
+
+	want := []string{
+		"You are number 1 in the queue. Estimated wait: 4 minute(s).",
+		"You are number 2 in the queue. Estimated wait: 8 minute(s).",
+	}
+	if texts := sentTexts(executor.mockExecutor); len(texts) != 2 || texts[0] != want[0] || texts[1] != want[1] {
+		t.Fatalf("expected the queue positions, got %v", texts)
+	}
+
+	userWrites(t, desk.app, first, "hello?")
+	if len(console.relayed) != 1 || console.relayed[0] != "hello?" {
+		t.Errorf("expected the message to be relayed, got %v", console.relayed)
+	}
+	texts := sentTexts(executor.mockExecutor)
+	if len(texts) != 3 || texts[2] != want[0] {
+		t.Errorf("expected the position again and no route to run, got %v", texts)
+	}
+}
+
+// TestHandoffDesk_RelaysOnceWhenPositionFails tests that a message of a
+// queued chat is acknowledged once relayed, even when its queue position
+// cannot be sent, so a redelivery does not relay it twice.
+func TestHandoffDesk_RelaysOnceWhenPositionFails(t *testing.T) {
+	executor, _, desk, console := newHandoffTest()
+	chatID := handOff(t, desk.app, "ana")
+	sent := len(sentTexts(executor.mockExecutor))
+
+	executor.available = false
+	userWrites(t, desk.app, chatID, "hello?")
+	if len(console.relayed) != 1 || console.relayed[0] != "hello?" {
+		t.Errorf("expected the message to be relayed once, got %v", console.relayed)
+	}
+	if texts := sentTexts(executor.mockExecutor); len(texts) != sent {
+		t.Errorf("expected no position to be sent, got %v", texts[sent:])
+	}
+}
+
+// TestHandoffDesk_PausesEvents tests that events for a handed-off chat do not
+// run routes until the ticket is closed.
+func TestHandoffDesk_PausesEvents(t *testing.T) {
+	executor, _, desk, _ := newHandoffTest()
+	chatID := handOff(t, desk.app, "ana")
+	ticket, _, _ := desk.Ticket(chatID)
+	sent := len(sentTexts(executor.mockExecutor))
+
+	err := desk.app.HandleEvent(context.Background(), d_event.Event{ID: "e1", ChatID: chatID, Route: "menu"})
+	if !errors.Is(err, ErrChatHandedOff) || !errors.Is(err, adapter_input.ErrUnavailable) {
+		t.Fatalf("expected ErrChatHandedOff wrapping ErrUnavailable, got %v", err)
+	}
+	if texts := sentTexts(executor.mockExecutor); len(texts) != sent {
+		t.Fatalf("expected no route to run, got %v", texts[sent:])
+	}
+
+	if _, err := desk.Close(context.Background(), ticket.ID, "start"); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if err := desk.app.HandleEvent(context.Background(), d_event.Event{ID: "e1", ChatID: chatID, Route: "menu"}); err != nil {
+		t.Fatalf("HandleEvent() error = %v", err)
+	}
+	if texts := sentTexts(executor.mockExecutor); len(texts) != sent+2 {
+		t.Errorf("expected the resume and event routes to run once closed, got %v", texts[sent:])
+	}
+}
+
+// TestHandoffDesk_AgentConversation tests that agents take tickets in
+// order, talk with the user and close the ticket, resuming the bot.
+func TestHandoffDesk_AgentConversation(t *testing.T) {
+	executor, clock, desk, console := newHandoffTest()
+	first := handOff(t, desk.app, "ana")
+	clock.Advance(time.Second)
+	second := handOff(t, desk.app, "bia")
+
+	clock.Advance(time.Minute)
+	ticket, ok, err := desk.Next(context.Background(), "department:billing", "maria")
+	if err != nil || !ok || ticket.ChatID() != first || ticket.Agent != "maria" {
+		t.Fatalf("expected the oldest ticket, got %+v, %v, %v", ticket, ok, err)
+	}
+	if !ticket.AssignedAt.Equal(clock.Now()) {
+		t.Errorf("expected the ticket to be assigned now, got %v", ticket.AssignedAt)
+	}
+	queued, _, _ := desk.Ticket(second)
+	if position, _, _ := desk.Position(queued); position != 1 {
+		t.Errorf("expected the second chat to move up, got position %d", position)
+	}
+
+	// Assigned chats are relayed without position messages.
+	sent := len(sentTexts(executor.mockExecutor))
+	userWrites(t, desk.app, first, "my invoice")
+	if len(console.relayed) != 1 || len(sentTexts(executor.mockExecutor)) != sent {
+		t.Errorf("expected only the relay, got %v", sentTexts(executor.mockExecutor))
+	}
+
+	reply := d_message.Message{TextMessage: d_message.TextMessage{ID: "m1", Detail: "Here it is"}}
+	if err := desk.Send(context.Background(), ticket.ID, reply); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	closed, err := desk.Close(context.Background(), ticket.ID, "")
+	if err != nil || closed.Status != d_handoff.CLOSED {
+		t.Fatalf("Close() = %+v, %v", closed, err)
+	}
+	texts := sentTexts(executor.mockExecutor)
+	if len(texts) != sent+2 || texts[sent] != "Here it is" || texts[sent+1] != "Thanks, ana" {
+		t.Errorf("expected the agent's reply then the resume route, got %v", texts)
+	}
+	if _, ok, _ := desk.Ticket(first); ok {
+		t.Error("expected the ticket to be removed")
+	}
+
+	// The bot handles the chat again.
+	userWrites(t, desk.app, first, "hi")
+	if texts := sentTexts(executor.mockExecutor); texts[len(texts)-1] != "bot" {
+		t.Errorf("expected the bot to answer, got %v", texts)
+	}
+	if _, err := desk.Close(context.Background(), ticket.ID, ""); !errors.Is(err, ErrTicketNotFound) {
+		t.Errorf("expected ErrTicketNotFound, got %v", err)
+	}
+}
+
+// TestHandoffDesk_CloseWaitsForExecutor tests that tickets stay open while
+// the executor is unavailable, and that agents may choose the resume route.
+func TestHandoffDesk_CloseWaitsForExecutor(t *testing.T) {
+	executor, _, desk, _ := newHandoffTest()
+	chatID := handOff(t, desk.app, "ana")
+	ticket, _, _ := desk.Ticket(chatID)
+
+	if _, err := desk.Close(context.Background(), ticket.ID, "missing"); !errors.Is(err, ErrRouteNotFound) {
+		t.Errorf("expected ErrRouteNotFound, got %v", err)
+	}
+
+	executor.available = false
+	if _, err := desk.Close(context.Background(), ticket.ID, "start"); !errors.Is(err, ErrExecutorUnavailable) {
+		t.Fatalf("expected ErrExecutorUnavailable, got %v", err)
+	}
+	if _, ok, _ := desk.Ticket(chatID); !ok {
+		t.Fatal("expected the ticket to stay open")
+	}
+
+	executor.available = true
+	if _, err := desk.Close(context.Background(), ticket.ID, "start"); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	last := executor.expectedExec[len(executor.expectedExec)-1]
+	if last.Type != ExecSetRoute || last.Route != "start" {
+		t.Errorf("expected the chat to resume at start, got %+v", last)
+	}
+}
+
+// TestHandoffDesk_CloseKeepsTicketWhenResumeFails tests that a ticket whose
+// resume route fails stays open, so closing it can be retried.
+func TestHandoffDesk_CloseKeepsTicketWhenResumeFails(t *testing.T) {
+	executor, _, desk, _ := newHandoffTest()
+	desk.app.engine.RegisterRoute("broken", func(ctx *d_context.ChatContext[TestObs]) route_return.RouteReturn {
+		panic("broken route")
+	})
+	chatID := handOff(t, desk.app, "ana")
+	ticket, _, _ := desk.Ticket(chatID)
+
+	if _, err := desk.Close(context.Background(), ticket.ID, "broken"); !errors.Is(err, ErrHandlerPanic) {
+		t.Fatalf("expected ErrHandlerPanic, got %v", err)
+	}
+	if reopened, ok, _ := desk.Ticket(chatID); !ok || reopened.ID != ticket.ID {
+		t.Fatalf("expected the ticket to stay open, got %+v", reopened)
+	}
+
+	closed, err := desk.Close(context.Background(), ticket.ID, "start")
+	if err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if closed.Status != d_handoff.CLOSED {
+		t.Errorf("expected a closed ticket, got %+v", closed)
+	}
+	last := executor.expectedExec[len(executor.expectedExec)-1]
+	if last.Type != ExecSetRoute || last.Route != "start" {
+		t.Errorf("expected the chat to resume at start, got %+v", last)
+	}
+}
+
+// TestHandoffDesk_CloseRunsOnChatWorker tests that the resume route waits
+// for the messages queued for the chat.
+func TestHandoffDesk_CloseRunsOnChatWorker(t *testing.T) {
+	_, _, desk, _ := newHandoffTest()
+	chatID := handOff(t, desk.app, "ana")
+	ticket, _, _ := desk.Ticket(chatID)
+
+	release := make(chan struct{})
+	desk.app.pool.start()
+	defer desk.app.pool.stop()
+	desk.app.pool.do(chatID, func() { <-release })
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := desk.Close(context.Background(), ticket.ID, "start")
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		t.Fatalf("expected Close to wait for the chat's worker, got %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+	if _, ok, _ := desk.Ticket(chatID); !ok {
+		t.Fatal("expected the ticket to stay open while the chat's worker is busy")
+	}
+	close(release)
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Close() error = %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected Close to finish once the chat's worker is free")
+	}
+}
+
+// TestHandoffDesk_AgentQueue tests handoffs to an agent's queue and to
+// unknown departments.
+func TestHandoffDesk_AgentQueue(t *testing.T) {
+	executor, _, desk, console := newHandoffTest()
+	desk.app.engine.RegisterRoute("maria", func(ctx *d_context.ChatContext[TestObs]) route_return.RouteReturn {
+		return &d_action.HandoffToAgent{DepartmentID: "billing", Agent: "maria"}
+	})
+	desk.app.engine.RegisterRoute("sales", func(ctx *d_context.ChatContext[TestObs]) route_return.RouteReturn {
+		return d_action.HandoffToAgent{DepartmentID: "sales"}
+	})
+
+	chatID := d_user.ChatID{UserID: "ana", CompanyID: "c1"}
+	userState := d_user.UserState[TestObs]{ChatID: chatID, Route: d_route.NewRoute("start.maria", '.')}
+	if err := desk.app.HandleMessage(userState, d_message.Message{}); err != nil {
+		t.Fatalf("HandleMessage() error = %v", err)
+	}
+	queued, err := desk.Queue(d_handoff.QueueOf("billing", "maria"))
+	if err != nil || len(queued) != 1 || queued[0].ResumeRoute != DEFAULT_HANDOFF_RESUME_ROUTE {
+		t.Fatalf("expected the ticket in maria's queue, got %+v, %v", queued, err)
+	}
+
+	other := d_user.ChatID{UserID: "bia", CompanyID: "c1"}
+	userState = d_user.UserState[TestObs]{ChatID: other, Route: d_route.NewRoute("start.sales", '.')}
+	if err := desk.app.HandleMessage(userState, d_message.Message{}); err != nil {
+		t.Fatalf("HandleMessage() error = %v", err)
+	}
+	if _, ok, _ := desk.Ticket(other); ok || len(console.summaries) != 1 {
+		t.Error("expected no ticket for an unknown department")
+	}
+	last := executor.expectedExec[len(executor.expectedExec)-1]
+	if last.Type != ExecSetRoute || last.Route != "sales" {
+		t.Errorf("expected the route to be kept, got %+v", last)
+	}
+}
@@ -0,0 +1,350 @@
+// Package service provides the main chatbot application service.
+// This file contains the InactivityScheduler, which reminds silent users and
+// moves their sessions on, as configured by the routes' Inactivity options.
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	d_idempotency "github.com/irissonnlima/chatgraph-go/core/domain/idempotency"
+	d_inactivity "github.com/irissonnlima/chatgraph-go/core/domain/inactivity"
+	d_message "github.com/irissonnlima/chatgraph-go/core/domain/message"
+	d_route "github.com/irissonnlima/chatgraph-go/core/domain/route"
+	d_router "github.com/irissonnlima/chatgraph-go/core/domain/router"
+	d_session "github.com/irissonnlima/chatgraph-go/core/domain/session"
+	d_user "github.com/irissonnlima/chatgraph-go/core/domain/user"
+	adapter_output "github.com/irissonnlima/chatgraph-go/core/ports/adapters/output"
+)
+
+// DEFAULT_INACTIVITY_INTERVAL is how often the scheduler looks for due timers.
+const DEFAULT_INACTIVITY_INTERVAL = 10 * time.Second
+
+// InactivityOptions configures an InactivityScheduler.
+type InactivityOptions struct {
+	// Interval is how often the scheduler looks for due timers, i.e. how late
+	// reminders and deadlines may run. Defaults to DEFAULT_INACTIVITY_INTERVAL.
+	Interval time.Duration
+	// Now returns the current time. Defaults to time.Now.
+	Now func() time.Time
+}
+
+// withDefaults returns a copy of the options with defaults applied.
+func (o InactivityOptions) withDefaults() InactivityOptions {
+	if o.Interval <= 0 {
+		o.Interval = DEFAULT_INACTIVITY_INTERVAL
+	}
+	if o.Now == nil {
+		o.Now = time.Now
+	}
+	return o
+}
+
+// InactivityScheduler runs the Inactivity options of routes. Whenever the
+// app leaves a chat on a route with Inactivity options, it sets a timer for
+// the chat; the next inbound message cancels it. When the user stays silent,
+// the scheduler sends the route's reminder, then, at the deadline, executes
+// the inactivity route for the chat (as if the user had been redirected to
+// it) or ends the session.
+//
+// Timers are kept in an IInactivityRepository, so with a durable repository
+// they survive restarts. While the executor is unavailable, due timers wait
+// for it to come back. Like job completions, reminders and inactivity
+// routes run on the chat's worker, so they do not overlap with its messages,
+// and a timer cancelled by a reply handled meanwhile is not run.
+type InactivityScheduler[Obs any] struct {
+	app        *ChatbotApp[Obs]
+	repository adapter_output.IInactivityRepository
+	options    InactivityOptions
+
+	// mu serializes the changes to timers, so a timer cancelled by a message
+	// is not stored again by the scheduler.
+	mu sync.Mutex
+}
+
+// NewInactivityScheduler creates a scheduler for the chats of app and
+// attaches it to app, which sets and cancels the timers from then on.
+// Create it before starting the app, then call Run.
+func NewInactivityScheduler[Obs any](
+	app *ChatbotApp[Obs],
+	repository adapter_output.IInactivityRepository,
+	options ...InactivityOptions,
+) *InactivityScheduler[Obs] {
+	opts := InactivityOptions{}
+	if len(options) > 0 {
+		opts = options[0]
+	}
+
+	scheduler := &InactivityScheduler[Obs]{
+		app:        app,
+		repository: repository,
+		options:    opts.withDefaults(),
+	}
+	app.inactivity = scheduler
+	return scheduler
+}
+
+// Timer returns the timer of a chat. ok is false when the chat has none,
+// e.g. it is not waiting on a route with Inactivity options.
+func (s *InactivityScheduler[Obs]) Timer(chatID d_user.ChatID) (timer d_inactivity.Timer, ok bool, err error) {
+	return s.repository.Get(chatID)
+}
+
+// track sets the timer of a chat left on route, when route has Inactivity
+// options, or cancels it.
+func (s *InactivityScheduler[Obs]) track(userState d_user.UserState[Obs], route d_route.Route) {
+	policy := s.policy(route.Current())
+	if policy == nil {
+		s.cancel(userState.ChatID)
+		return
+	}
+
+	userState.Route = route
+	session, err := d_session.FromUserState(userState)
+	if err != nil {
+		log.Printf("[ERROR] Failed to encode inactivity session of chat %v: %v", userState.ChatID, err)
+		return
+	}
+	id, err := newID()
+	if err != nil {
+		log.Printf("[ERROR] Failed to set inactivity timer of chat %v: %v", userState.ChatID, err)
+		return
+	}
+
+	now := s.options.Now()
+	timer := d_inactivity.Timer{
+		ID:        id,
+		Session:   session,
+		Route:     route.Current(),
+		Deadline:  now.Add(policy.After),
+		CreatedAt: now,
+	}
+	if policy.Reminder > 0 {
+		timer.RemindAt = now.Add(policy.Reminder)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.repository.Save(timer); err != nil {
+		log.Printf("[ERROR] Failed to save inactivity timer of chat %v: %v", userState.ChatID, err)
+	}
+}
+
+// cancel removes the timer of a chat, e.g. because the user replied.
+func (s *InactivityScheduler[Obs]) cancel(chatID d_user.ChatID) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.repository.Delete(chatID); err != nil {
+		log.Printf("[ERROR] Failed to cancel inactivity timer of chat %v: %v", chatID, err)
+	}
+}
+
+// policy returns the Inactivity options of a route, or nil.
+func (s *InactivityScheduler[Obs]) policy(route string) *d_router.InactivityRouteOps {
+	return s.app.engine.routes[route].HandlerOptions.Inactivity
+}
+
+// Run processes the due timers every Interval until ctx is cancelled.
+func (s *InactivityScheduler[Obs]) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.options.Interval)
+	defer ticker.Stop()
+
+	for {
+		s.Dispatch(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Dispatch processes the due timers once and returns how many reminders
+// were sent and sessions moved on.
+func (s *InactivityScheduler[Obs]) Dispatch(ctx context.Context) int {
+	timers, err := s.repository.List()
+	if err != nil {
+		log.Printf("[ERROR] Failed to list inactivity timers: %v", err)
+		return 0
+	}
+
+	processed := 0
+	for _, timer := range timers {
+		if ctx.Err() != nil || !s.app.available() {
+			break
+		}
+		if !timer.Due(s.options.Now()) {
+			continue
+		}
+		if s.process(ctx, timer) {
+			processed++
+		}
+	}
+	return processed
+}
+
+// process sends the reminder of a due timer, or moves its session on once
+// it expired, on the chat's worker so it does not overlap with the chat's
+// messages. Returns false if there was nothing to do.
+func (s *InactivityScheduler[Obs]) process(ctx context.Context, timer d_inactivity.Timer) bool {
+	policy := s.policy(timer.Route)
+	if policy == nil {
+		// The route no longer has Inactivity options.
+		s.remove(timer)
+		return false
+	}
+
+	processed := false
+	s.app.onChat(ctx, timer.ChatID(), func() error {
+		if timer.Expired(s.options.Now()) {
+			processed = s.expire(ctx, timer, policy)
+		} else {
+			processed = s.remind(ctx, timer, policy)
+		}
+		return nil
+	})
+	return processed
+}
+
+// remind sends the reminder of a timer, unless the timer was cancelled or
+// replaced since it was listed, e.g. because the user replied. Reminders are
+// sent once, even when sending fails.
+func (s *InactivityScheduler[Obs]) remind(
+	ctx context.Context,
+	timer d_inactivity.Timer,
+	policy *d_router.InactivityRouteOps,
+) bool {
+	if policy.ReminderMessage == nil || !s.claimReminder(timer) {
+		return false
+	}
+
+	executor := bindExecutor(s.scope(ctx, timer, "reminder"), s.app.botExecutor)
+	err := executor.SendMessage(timer.ChatID(), *policy.ReminderMessage, timer.Session.Platform)
+	if err != nil {
+		log.Printf("[ERROR] Failed to remind chat %v on route %s: %v", timer.ChatID(), timer.Route, err)
+	}
+	return true
+}
+
+// claimReminder marks the reminder of timer as sent if it is still the timer
+// of its chat, so a reply handled meanwhile is not followed by a reminder.
+func (s *InactivityScheduler[Obs]) claimReminder(timer d_inactivity.Timer) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.current(timer) {
+		return false
+	}
+	timer.Reminded = true
+	if err := s.repository.Save(timer); err != nil {
+		log.Printf("[ERROR] Failed to save inactivity timer of chat %v: %v", timer.ChatID(), err)
+		return false
+	}
+	return true
+}
+
+// expire removes an expired timer and moves its session on: the inactivity
+// route is executed, or the session is ended. When the executor became
+// unavailable meanwhile, the timer is restored so a later dispatch retries.
+// Chats handed off to an agent meanwhile are left to the agent.
+func (s *InactivityScheduler[Obs]) expire(
+	ctx context.Context,
+	timer d_inactivity.Timer,
+	policy *d_router.InactivityRouteOps,
+) bool {
+	if !s.remove(timer) {
+		return false
+	}
+
+	log.Printf("[INFO] Chat %v inactive on route %s since %s", timer.ChatID(), timer.Route, timer.CreatedAt.Format(time.RFC3339))
+
+	handedOff, err := s.app.handedOff(timer.ChatID())
+	switch {
+	case err != nil:
+	case handedOff:
+		err = ErrChatHandedOff
+	case policy.Route != "":
+		err = s.resume(ctx, timer, policy.Route)
+	default:
+		executor := bindExecutor(s.scope(ctx, timer, "end"), s.app.botExecutor)
+		err = executor.EndSession(timer.ChatID(), policy.End.ID)
+	}
+
+	if errors.Is(err, ErrExecutorUnavailable) {
+		s.restore(timer)
+		return false
+	}
+	if errors.Is(err, ErrChatHandedOff) {
+		log.Printf("[INFO] Not moving inactive chat %v on from route %s: %v", timer.ChatID(), timer.Route, err)
+		return true
+	}
+	if err != nil {
+		log.Printf("[ERROR] Failed to move inactive chat %v on from route %s: %v", timer.ChatID(), timer.Route, err)
+	}
+	return true
+}
+
+// resume executes route for the chat of a timer, with the chat's stored
+// state or the timer's snapshot.
+func (s *InactivityScheduler[Obs]) resume(ctx context.Context, timer d_inactivity.Timer, route string) error {
+	userState, found, err := s.app.loadState(timer.ChatID())
+	if err != nil {
+		return fmt.Errorf("loading chat state: %w", err)
+	}
+	if !found {
+		if userState, err = d_session.ToUserState[Obs](timer.Session); err != nil {
+			return fmt.Errorf("decoding inactivity session: %w", err)
+		}
+	}
+
+	// The timer ID scopes the idempotency keys of the inactivity route.
+	message := d_message.Message{TextMessage: d_message.TextMessage{ID: "inactivity:" + timer.ID}}
+	return s.app.resume(ctx, userState, route, message)
+}
+
+// scope returns ctx with the idempotency scope of an action of the timer,
+// apart from the keys of its inactivity route.
+func (s *InactivityScheduler[Obs]) scope(ctx context.Context, timer d_inactivity.Timer, action string) context.Context {
+	return d_idempotency.WithScope(ctx, d_idempotency.NewScope(timer.ChatID(), "inactivity:"+timer.ID+":"+action, ""))
+}
+
+// current reports whether timer is still the timer of its chat, i.e. it was
+// not cancelled or replaced. The caller holds s.mu.
+func (s *InactivityScheduler[Obs]) current(timer d_inactivity.Timer) bool {
+	stored, ok, err := s.repository.Get(timer.ChatID())
+	if err != nil {
+		log.Printf("[ERROR] Failed to load inactivity timer of chat %v: %v", timer.ChatID(), err)
+		return false
+	}
+	return ok && stored.ID == timer.ID
+}
+
+// remove deletes timer if it is still the timer of its chat.
+func (s *InactivityScheduler[Obs]) remove(timer d_inactivity.Timer) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.current(timer) {
+		return false
+	}
+	if err := s.repository.Delete(timer.ChatID()); err != nil {
+		log.Printf("[ERROR] Failed to delete inactivity timer of chat %v: %v", timer.ChatID(), err)
+		return false
+	}
+	return true
+}
+
+// restore stores a removed timer again, unless its chat got a new one.
+func (s *InactivityScheduler[Obs]) restore(timer d_inactivity.Timer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok, err := s.repository.Get(timer.ChatID()); err != nil || ok {
+		return
+	}
+	if err := s.repository.Save(timer); err != nil {
+		log.Printf("[ERROR] Failed to restore inactivity timer of chat %v: %v", timer.ChatID(), err)
+	}
+}
@@ -0,0 +1,296 @@
+package service
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/irissonnlima/chatgraph-go/core/clocktest"
+	route_return "github.com/irissonnlima/chatgraph-go/core/domain"
+	d_action "github.com/irissonnlima/chatgraph-go/core/domain/action"
+	d_context "github.com/irissonnlima/chatgraph-go/core/domain/context"
+	d_inactivity "github.com/irissonnlima/chatgraph-go/core/domain/inactivity"
+	d_message "github.com/irissonnlima/chatgraph-go/core/domain/message"
+	d_route "github.com/irissonnlima/chatgraph-go/core/domain/route"
+	d_router "github.com/irissonnlima/chatgraph-go/core/domain/router"
+	d_user "github.com/irissonnlima/chatgraph-go/core/domain/user"
+)
+
+// inactivityStore is an in-memory IInactivityRepository. listed, if set,
+// runs after List took its snapshot.
+type inactivityStore struct {
+	mu     sync.Mutex
+	timers map[d_user.ChatID]d_inactivity.Timer
+	listed func()
+}
+
+func newInactivityStore() *inactivityStore {
+	return &inactivityStore{timers: make(map[d_user.ChatID]d_inactivity.Timer)}
+}
+
+func (s *inactivityStore) Save(timer d_inactivity.Timer) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.timers[timer.ChatID()] = timer
+	return nil
+}
+
+func (s *inactivityStore) Get(chatID d_user.ChatID) (d_inactivity.Timer, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	timer, ok := s.timers[chatID]
+	return timer, ok, nil
+}
+
+func (s *inactivityStore) List() ([]d_inactivity.Timer, error) {
+	s.mu.Lock()
+	timers := make([]d_inactivity.Timer, 0, len(s.timers))
+	for _, timer := range s.timers {
+		timers = append(timers, timer)
+	}
+	s.mu.Unlock()
+
+	sort.Slice(timers, func(i, j int) bool { return timers[i].Deadline.Before(timers[j].Deadline) })
+	if s.listed != nil {
+		s.listed()
+	}
+	return timers, nil
+}
+
+func (s *inactivityStore) Delete(chatID d_user.ChatID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.timers, chatID)
+	return nil
+}
+
+func (s *inactivityStore) Close() error {
+	return nil
+}
+
+// endingExecutor is a mock executor that records ended sessions.
+type endingExecutor struct {
+	*unavailableExecutor
+	ended []string
+}
+
+func (e *endingExecutor) EndSession(chatID d_user.ChatID, actionID string) error {
+	e.ended = append(e.ended, actionID)
+	return nil
+}
+
+var inactivityChat = d_user.ChatID{UserID: "u1", CompanyID: "c1"}
+
+var stillThere = &d_message.Message{TextMessage: d_message.TextMessage{Detail: "Are you still there?"}}
+
+// newInactivityTest returns an app whose "ask" route reminds silent users
+// after 5 minutes and says goodbye after 30, and whose "survey" route ends
+// silent sessions after 10 minutes.
+func newInactivityTest() (*endingExecutor, *clocktest.FakeClock, *InactivityScheduler[TestObs], *inactivityStore) {
+	executor := &endingExecutor{unavailableExecutor: &unavailableExecutor{mockExecutor: newMockExecutor(), available: true}}
+	app := newTestAppWithRoutes(&fakeReceiver{}, executor, map[string]d_router.RouteHandler[TestObs]{
+		"goodbye": func(ctx *d_context.ChatContext[TestObs]) route_return.RouteReturn {
+			ctx.SendTextMessage("Closing the chat, " + ctx.GetObservation().Value)
+			return nil
+		},
+	})
+	for _, route := range []string{"ask", "survey"} {
+		app.engine.RegisterRoute(route, func(ctx *d_context.ChatContext[TestObs]) route_return.RouteReturn {
+			return nil
+		}, inactivityOptions(route))
+	}
+	clock := clocktest.NewFakeClock(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+	store := newInactivityStore()
+	scheduler := NewInactivityScheduler(app, store, InactivityOptions{Now: clock.Now})
+	return executor, clock, scheduler, store
+}
+
+// inactivityOptions returns the options of the routes of newInactivityTest.
+func inactivityOptions(route string) d_router.RouterHandlerOptions {
+	switch route {
+	case "ask":
+		return d_router.RouterHandlerOptions{Inactivity: &d_router.InactivityRouteOps{
+			Reminder: 5 * time.Minute, ReminderMessage: stillThere, After: 30 * time.Minute, Route: "goodbye",
+		}}
+	case "survey":
+		return d_router.RouterHandlerOptions{Inactivity: &d_router.InactivityRouteOps{
+			After: 10 * time.Minute, End: &d_action.EndAction{ID: "idle"},
+		}}
+	}
+	return d_router.RouterHandlerOptions{}
+}
+
+// waitOn handles a message that leaves the user on route.
+func waitOn(t *testing.T, app *ChatbotApp[TestObs], route string) {
+	t.Helper()
+	userState := d_user.UserState[TestObs]{
+		ChatID:      inactivityChat,
+		Route:       d_route.NewRoute(route, '.'),
+		Observation: TestObs{Value: "Ana"},
+		Platform:    "whatsapp",
+	}
+	if err := app.HandleMessage(userState, d_message.Message{}); err != nil {
+		t.Fatalf("HandleMessage() error = %v", err)
+	}
+}
+
+// TestInactivityScheduler_RemindsThenMovesOn tests that a silent user is
+// reminded once, then moved to the inactivity route at the deadline.
+func TestInactivityScheduler_RemindsThenMovesOn(t *testing.T) {
+	executor, clock, scheduler, store := newInactivityTest()
+	waitOn(t, scheduler.app, "ask")
+
+	if _, ok, _ := scheduler.Timer(inactivityChat); !ok {
+		t.Fatal("expected a timer for the chat")
+	}
+	if n := scheduler.Dispatch(context.Background()); n != 0 {
+		t.Errorf("expected nothing due yet, processed %d", n)
+	}
+
+	clock.Advance(5 * time.Minute)
+	if n := scheduler.Dispatch(context.Background()); n != 1 {
+		t.Errorf("expected the reminder, processed %d", n)
+	}
+	clock.Advance(time.Minute)
+	scheduler.Dispatch(context.Background())
+	if texts := sentTexts(executor.mockExecutor); len(texts) != 1 || texts[0] != stillThere.TextMessage.Detail {
+		t.Fatalf("expected one reminder, got %v", texts)
+	}
+
+	clock.Advance(24 * time.Minute)
+	if n := scheduler.Dispatch(context.Background()); n != 1 {
+		t.Errorf("expected the deadline, processed %d", n)
+	}
+
+	texts := sentTexts(executor.mockExecutor)
+	if len(texts) != 2 || texts[1] != "Closing the chat, Ana" {
+		t.Errorf("expected the goodbye route with the chat's snapshot, got %v", texts)
+	}
+	last := executor.expectedExec[len(executor.expectedExec)-1]
+	if last.Type != ExecSetRoute || last.Route != "goodbye" {
+		t.Errorf("expected the route to be set to goodbye, got %+v", last)
+	}
+	if len(store.timers) != 0 {
+		t.Errorf("expected no timer left, got %+v", store.timers)
+	}
+}
+
+// TestInactivityScheduler_MessageCancelsTimer tests that a reply cancels the
+// timer, and a reply leading to a route without options does not set one.
+func TestInactivityScheduler_MessageCancelsTimer(t *testing.T) {
+	executor, clock, scheduler, _ := newInactivityTest()
+	waitOn(t, scheduler.app, "ask")
+	first, _, _ := scheduler.Timer(inactivityChat)
+
+	// Replying restarts the silence.
+	clock.Advance(4 * time.Minute)
+	waitOn(t, scheduler.app, "ask")
+	second, _, _ := scheduler.Timer(inactivityChat)
+	if second.ID == first.ID || !second.RemindAt.Equal(clock.Now().Add(5*time.Minute)) {
+		t.Errorf("expected a new timer, got %+v", second)
+	}
+
+	waitOn(t, scheduler.app, "start")
+	if _, ok, _ := scheduler.Timer(inactivityChat); ok {
+		t.Error("expected the timer to be cancelled")
+	}
+
+	clock.Advance(time.Hour)
+	if n := scheduler.Dispatch(context.Background()); n != 0 {
+		t.Errorf("expected nothing due, processed %d", n)
+	}
+	if texts := sentTexts(executor.mockExecutor); len(texts) != 0 {
+		t.Errorf("expected no messages, got %v", texts)
+	}
+}
+
+// TestInactivityScheduler_ReplyBeforeReminder tests that a timer cancelled
+// by a reply after the dispatch listed it does not send its reminder.
+func TestInactivityScheduler_ReplyBeforeReminder(t *testing.T) {
+	executor, clock, scheduler, store := newInactivityTest()
+	waitOn(t, scheduler.app, "ask")
+
+	store.listed = func() {
+		store.listed = nil
+		waitOn(t, scheduler.app, "start")
+	}
+	clock.Advance(5 * time.Minute)
+	if n := scheduler.Dispatch(context.Background()); n != 0 {
+		t.Errorf("expected nothing processed, processed %d", n)
+	}
+	if texts := sentTexts(executor.mockExecutor); len(texts) != 0 {
+		t.Errorf("expected no reminder, got %v", texts)
+	}
+}
+
+// TestInactivityScheduler_EndsSession tests that routes with an End action
+// end silent sessions.
+func TestInactivityScheduler_EndsSession(t *testing.T) {
+	executor, clock, scheduler, _ := newInactivityTest()
+	waitOn(t, scheduler.app, "survey")
+
+	clock.Advance(10 * time.Minute)
+	if n := scheduler.Dispatch(context.Background()); n != 1 {
+		t.Errorf("expected the deadline, processed %d", n)
+	}
+	if len(executor.ended) != 1 || executor.ended[0] != "idle" {
+		t.Errorf("expected the session to end with idle, got %v", executor.ended)
+	}
+	if _, ok, _ := scheduler.Timer(inactivityChat); ok {
+		t.Error("expected the timer to be removed")
+	}
+}
+
+// TestInactivityScheduler_WaitsForExecutor tests that due timers wait while
+// the executor is unavailable.
+func TestInactivityScheduler_WaitsForExecutor(t *testing.T) {
+	executor, clock, scheduler, _ := newInactivityTest()
+	waitOn(t, scheduler.app, "survey")
+
+	executor.available = false
+	clock.Advance(time.Hour)
+	if n := scheduler.Dispatch(context.Background()); n != 0 {
+		t.Errorf("expected nothing processed while unavailable, processed %d", n)
+	}
+	if _, ok, _ := scheduler.Timer(inactivityChat); !ok {
+		t.Fatal("expected the timer to be kept")
+	}
+
+	executor.available = true
+	if n := scheduler.Dispatch(context.Background()); n != 1 {
+		t.Errorf("expected the deadline, processed %d", n)
+	}
+	if len(executor.ended) != 1 {
+		t.Errorf("expected the session to end, got %v", executor.ended)
+	}
+}
+
+// TestValidateRoutes_Inactivity tests that invalid inactivity options are
+// rejected before the app starts.
+func TestValidateRoutes_Inactivity(t *testing.T) {
+	tests := []struct {
+		name       string
+		inactivity *d_router.InactivityRouteOps
+	}{
+		{"missing deadline", &d_router.InactivityRouteOps{Route: "start"}},
+		{"unregistered route", &d_router.InactivityRouteOps{After: time.Minute, Route: "missing"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := NewEngine[TestObs](d_router.RouterHandlerOptions{
+				Timeout:   &d_router.TimeoutRouteOps{Duration: time.Second, Route: "start"},
+				LoopCount: &d_router.LoopCountRouteOps{Count: 3, Route: "start"},
+			})
+			engine.RegisterRoute("start", func(ctx *d_context.ChatContext[TestObs]) route_return.RouteReturn {
+				return nil
+			}, d_router.RouterHandlerOptions{Inactivity: tt.inactivity})
+
+			if err := engine.ValidateRoutes(); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,570 @@
+// Package service provides the main chatbot application service.
+// This file contains the JobRunner, which performs background jobs and
+// resumes their chats when they finish.
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	d_context "github.com/irissonnlima/chatgraph-go/core/domain/context"
+	d_job "github.com/irissonnlima/chatgraph-go/core/domain/job"
+	d_message "github.com/irissonnlima/chatgraph-go/core/domain/message"
+	d_session "github.com/irissonnlima/chatgraph-go/core/domain/session"
+	d_user "github.com/irissonnlima/chatgraph-go/core/domain/user"
+	adapter_output "github.com/irissonnlima/chatgraph-go/core/ports/adapters/output"
+)
+
+// Default values of JobOptions.
+const (
+	// DEFAULT_JOB_WORKERS is the number of tasks performed at a time.
+	DEFAULT_JOB_WORKERS = 4
+	// DEFAULT_JOB_INTERVAL is how often the runner looks for due jobs.
+	DEFAULT_JOB_INTERVAL = time.Second
+	// DEFAULT_JOB_MAX_ATTEMPTS is the number of attempts of a task.
+	DEFAULT_JOB_MAX_ATTEMPTS = 3
+	// DEFAULT_JOB_RETRY_BASE_DELAY is the delay after the first failed attempt.
+	DEFAULT_JOB_RETRY_BASE_DELAY = 10 * time.Second
+	// DEFAULT_JOB_RETRY_MAX_DELAY caps the delay between attempts.
+	DEFAULT_JOB_RETRY_MAX_DELAY = 10 * time.Minute
+	// DEFAULT_JOB_TASK_TIMEOUT bounds each attempt of a task.
+	DEFAULT_JOB_TASK_TIMEOUT = 30 * time.Minute
+	// DEFAULT_JOB_RETENTION is how long finished jobs are kept for status queries.
+	DEFAULT_JOB_RETENTION = 24 * time.Hour
+)
+
+// Errors returned by JobRunner.
+var (
+	// ErrUnknownTask is returned when enqueuing a job for a task that is not
+	// registered. Stored jobs whose task is no longer registered fail with it.
+	ErrUnknownTask = errors.New("unknown job task")
+	// ErrTaskPanic is the error of a task attempt that panicked.
+	ErrTaskPanic = errors.New("job task panicked")
+	// ErrTaskInterrupted is the error of a job whose last attempt was
+	// interrupted, e.g. by a crash.
+	ErrTaskInterrupted = errors.New("job task interrupted")
+)
+
+// errStaleJob is returned when resuming a chat that left the job's waiting route.
+var errStaleJob = errors.New("chat left the waiting route")
+
+// JobOptions configures a JobRunner.
+type JobOptions struct {
+	// Workers is the number of tasks performed at a time. Defaults to DEFAULT_JOB_WORKERS.
+	Workers int
+	// Interval is how often the runner looks for due jobs. New jobs wake it
+	// up earlier. Defaults to DEFAULT_JOB_INTERVAL.
+	Interval time.Duration
+	// MaxAttempts is the number of attempts of a task, unless the job sets
+	// its own. Defaults to DEFAULT_JOB_MAX_ATTEMPTS.
+	MaxAttempts int
+	// RetryBaseDelay is the delay after the first failed attempt, doubled
+	// after each one. Failed resumes are retried after RetryBaseDelay.
+	// Defaults to DEFAULT_JOB_RETRY_BASE_DELAY.
+	RetryBaseDelay time.Duration
+	// RetryMaxDelay caps the delay between attempts. Defaults to DEFAULT_JOB_RETRY_MAX_DELAY.
+	RetryMaxDelay time.Duration
+	// TaskTimeout bounds each attempt of a task. Defaults to DEFAULT_JOB_TASK_TIMEOUT.
+	TaskTimeout time.Duration
+	// Retention is how long jobs are kept after their chat was resumed, so
+	// their status can still be queried. Defaults to DEFAULT_JOB_RETENTION.
+	Retention time.Duration
+	// Now returns the current time. Defaults to time.Now.
+	Now func() time.Time
+}
+
+// withDefaults returns a copy of the options with defaults applied.
+func (o JobOptions) withDefaults() JobOptions {
+	if o.Workers <= 0 {
+		o.Workers = DEFAULT_JOB_WORKERS
+	}
+	if o.Interval <= 0 {
+		o.Interval = DEFAULT_JOB_INTERVAL
+	}
+	if o.MaxAttempts <= 0 {
+		o.MaxAttempts = DEFAULT_JOB_MAX_ATTEMPTS
+	}
+	if o.RetryBaseDelay <= 0 {
+		o.RetryBaseDelay = DEFAULT_JOB_RETRY_BASE_DELAY
+	}
+	if o.RetryMaxDelay <= 0 {
+		o.RetryMaxDelay = DEFAULT_JOB_RETRY_MAX_DELAY
+	}
+	if o.TaskTimeout <= 0 {
+		o.TaskTimeout = DEFAULT_JOB_TASK_TIMEOUT
+	}
+	if o.Retention <= 0 {
+		o.Retention = DEFAULT_JOB_RETENTION
+	}
+	if o.Now == nil {
+		o.Now = time.Now
+	}
+	return o
+}
+
+// retryDelay returns the delay after the failed attempt number attempts.
+func (o JobOptions) retryDelay(attempts int) time.Duration {
+	delay := o.RetryBaseDelay
+	for i := 1; i < attempts && delay < o.RetryMaxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, o.RetryMaxDelay)
+}
+
+// JobRequest describes a job enqueued by a handler.
+type JobRequest struct {
+	// Task is the name of a task registered with RegisterTask.
+	Task string
+	// Payload is the input of the task, stored as JSON.
+	Payload any
+	// CompletionRoute is executed for the chat when the job succeeds. Required.
+	CompletionRoute string
+	// FailureRoute is executed for the chat when the job fails. When empty,
+	// failed jobs do not resume the chat.
+	FailureRoute string
+	// WaitingRoute is the route the handler leaves the user on while the job
+	// runs. When set, and the executor keeps the chat's state, the chat is only
+	// resumed if it is still on WaitingRoute; otherwise the job is marked stale.
+	WaitingRoute string
+	// MaxAttempts overrides JobOptions.MaxAttempts when positive.
+	MaxAttempts int
+}
+
+// JobRunner performs the background jobs enqueued by handlers, for work
+// that takes longer than a handler may run. When a job finishes, the runner
+// resumes its chat by executing the job's completion (or failure) route, as
+// if the user had been redirected to it; the route reads the job, with its
+// result, from ChatContext.Job.
+//
+// Jobs are kept in an IJobRepository, so with a durable repository they
+// survive restarts: pending jobs, jobs interrupted while running and chats
+// not resumed yet are picked up again by Run. Failed attempts are retried
+// with backoff, except for errors implementing
+// adapter_output.IRetryableError that are not retryable.
+//
+// Completion routes run on the app's worker pool, behind the messages queued
+// for the chat, so they never overlap with them. Set JobRequest.WaitingRoute
+// so a chat that moved on meanwhile is not resumed. Chats handed off to an
+// agent are resumed once the ticket is closed, by a later dispatch.
+type JobRunner[Obs any] struct {
+	app        *ChatbotApp[Obs]
+	repository adapter_output.IJobRepository
+	options    JobOptions
+
+	mu    sync.Mutex
+	tasks map[string]d_job.Task
+	// processing holds the chats whose jobs are being processed.
+	processing map[d_user.ChatID]bool
+
+	// slots limits the jobs processed at a time.
+	slots chan struct{}
+	// wake is signalled when a job is enqueued or a slot is freed.
+	wake chan struct{}
+}
+
+// NewJobRunner creates a runner that resumes the chats of app.
+// Call Run to start processing jobs.
+func NewJobRunner[Obs any](
+	app *ChatbotApp[Obs],
+	repository adapter_output.IJobRepository,
+	options ...JobOptions,
+) *JobRunner[Obs] {
+	opts := JobOptions{}
+	if len(options) > 0 {
+		opts = options[0]
+	}
+	opts = opts.withDefaults()
+
+	return &JobRunner[Obs]{
+		app:        app,
+		repository: repository,
+		options:    opts,
+		tasks:      make(map[string]d_job.Task),
+		processing: make(map[d_user.ChatID]bool),
+		slots:      make(chan struct{}, opts.Workers),
+		wake:       make(chan struct{}, 1),
+	}
+}
+
+// RegisterTask registers the task performing the jobs named name.
+// Tasks must be registered before Run, including the ones of stored jobs.
+func (r *JobRunner[Obs]) RegisterTask(name string, task d_job.Task) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.tasks[name] = task
+}
+
+// task returns the task registered as name.
+func (r *JobRunner[Obs]) task(name string) (d_job.Task, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	task, ok := r.tasks[name]
+	return task, ok
+}
+
+// Enqueue stores a job for the chat of ctx and returns it. The chat is
+// resumed with its state loaded from the executor (see
+// adapter_output.IStateLoader) or, when the executor keeps no state, with a
+// snapshot of ctx.UserState, so set the observation before enqueuing. The
+// handler then typically leaves the user on a waiting route:
+//
+//	job, err := runner.Enqueue(ctx, service.JobRequest{
+//		Task:            "invoice",
+//		Payload:         order,
+//		CompletionRoute: "invoice_ready",
+//		WaitingRoute:    "waiting_invoice",
+//	})
+//	...
+//	return ctx.NextRoute("waiting_invoice")
+func (r *JobRunner[Obs]) Enqueue(ctx *d_context.ChatContext[Obs], request JobRequest) (d_job.Job, error) {
+	if _, ok := r.task(request.Task); !ok {
+		return d_job.Job{}, fmt.Errorf("%w: %s", ErrUnknownTask, request.Task)
+	}
+	if request.CompletionRoute == "" {
+		return d_job.Job{}, errors.New("job completion route is required")
+	}
+	for _, route := range []string{request.CompletionRoute, request.FailureRoute, request.WaitingRoute} {
+		if _, exists := r.app.engine.routes[route]; route != "" && !exists {
+			return d_job.Job{}, fmt.Errorf("%w: %s", ErrRouteNotFound, route)
+		}
+	}
+
+	payload, err := json.Marshal(request.Payload)
+	if err != nil {
+		return d_job.Job{}, fmt.Errorf("encoding job payload: %w", err)
+	}
+	session, err := d_session.FromUserState(ctx.UserState)
+	if err != nil {
+		return d_job.Job{}, fmt.Errorf("encoding job session: %w", err)
+	}
+	id, err := newID()
+	if err != nil {
+		return d_job.Job{}, err
+	}
+
+	maxAttempts := request.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = r.options.MaxAttempts
+	}
+
+	now := r.options.Now()
+	job := d_job.Job{
+		ID:              id,
+		Task:            request.Task,
+		Payload:         string(payload),
+		Session:         session,
+		CompletionRoute: request.CompletionRoute,
+		FailureRoute:    request.FailureRoute,
+		WaitingRoute:    request.WaitingRoute,
+		Status:          d_job.PENDING,
+		MaxAttempts:     maxAttempts,
+		CreatedAt:       now,
+		UpdatedAt:       now,
+	}
+	if err := r.repository.Save(job); err != nil {
+		return d_job.Job{}, fmt.Errorf("saving job: %w", err)
+	}
+
+	log.Printf("[INFO] Enqueued job %s (%s) for chat %v", job.ID, job.Task, job.Session.ChatID)
+	r.signal()
+	return job, nil
+}
+
+// Job returns a stored job. ok is false when no job has the ID, or it was
+// removed after JobOptions.Retention.
+func (r *JobRunner[Obs]) Job(id string) (job d_job.Job, ok bool, err error) {
+	return r.repository.Get(id)
+}
+
+// Jobs returns the stored jobs of a chat, oldest first.
+func (r *JobRunner[Obs]) Jobs(chatID d_user.ChatID) ([]d_job.Job, error) {
+	jobs, err := r.repository.List()
+	if err != nil {
+		return nil, err
+	}
+
+	var chatJobs []d_job.Job
+	for _, job := range jobs {
+		if job.Session.ChatID == chatID {
+			chatJobs = append(chatJobs, job)
+		}
+	}
+	return chatJobs, nil
+}
+
+// signal wakes Run up.
+func (r *JobRunner[Obs]) signal() {
+	select {
+	case r.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run processes the due jobs every Interval, and whenever a job is
+// enqueued, until ctx is cancelled. It then waits for the tasks in
+// progress, which see ctx cancelled: their attempts do not count, and the
+// jobs are performed again by the next Run.
+func (r *JobRunner[Obs]) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.options.Interval)
+	defer ticker.Stop()
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	for {
+		r.dispatch(ctx, &wg)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-r.wake:
+		}
+	}
+}
+
+// Dispatch processes the due jobs once, up to Workers at a time, waits for
+// them and returns how many were processed. Jobs left over are processed by
+// the next call.
+func (r *JobRunner[Obs]) Dispatch(ctx context.Context) int {
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	return r.dispatch(ctx, &wg)
+}
+
+// dispatch starts processing the due jobs, while slots are free, and removes
+// the expired ones. Jobs of a chat are processed one at a time, so its
+// resumes do not overlap. Returns how many were started.
+func (r *JobRunner[Obs]) dispatch(ctx context.Context, wg *sync.WaitGroup) int {
+	jobs, err := r.repository.List()
+	if err != nil {
+		log.Printf("[ERROR] Failed to list jobs: %v", err)
+		return 0
+	}
+
+	started := 0
+	for _, job := range jobs {
+		if ctx.Err() != nil {
+			break
+		}
+
+		now := r.options.Now()
+		if job.Finished() && job.Resumed {
+			if now.Sub(job.UpdatedAt) >= r.options.Retention {
+				if err := r.repository.Delete(job.ID); err != nil {
+					log.Printf("[ERROR] Failed to delete job %s: %v", job.ID, err)
+				}
+			}
+			continue
+		}
+		if !job.Due(now) || !r.claim(job.Session.ChatID) {
+			continue
+		}
+
+		select {
+		case r.slots <- struct{}{}:
+		default:
+			r.release(job.Session.ChatID)
+			return started
+		}
+
+		started++
+		wg.Add(1)
+		go func(job d_job.Job) {
+			defer wg.Done()
+			defer r.signal()
+			defer func() { <-r.slots }()
+			defer r.release(job.Session.ChatID)
+			r.process(ctx, job)
+		}(job)
+	}
+	return started
+}
+
+// claim marks the jobs of a chat as in progress. Returns false if they already were.
+func (r *JobRunner[Obs]) claim(chatID d_user.ChatID) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.processing[chatID] {
+		return false
+	}
+	r.processing[chatID] = true
+	return true
+}
+
+// release marks the jobs of a chat as no longer in progress.
+func (r *JobRunner[Obs]) release(chatID d_user.ChatID) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.processing, chatID)
+}
+
+// process performs an attempt of a job's task, then resumes its chat once
+// the job finished.
+func (r *JobRunner[Obs]) process(ctx context.Context, job d_job.Job) {
+	if !job.Finished() {
+		var ok bool
+		if job, ok = r.attempt(ctx, job); !ok || !job.Finished() {
+			return
+		}
+	}
+	r.resume(ctx, job)
+}
+
+// attempt performs an attempt of a job's task and stores the outcome.
+// Returns false if the job could not be stored.
+func (r *JobRunner[Obs]) attempt(ctx context.Context, job d_job.Job) (d_job.Job, bool) {
+	task, ok := r.task(job.Task)
+	switch {
+	case !ok:
+		return r.finish(job, nil, fmt.Errorf("%w: %s", ErrUnknownTask, job.Task))
+	case job.Status == d_job.RUNNING && job.Attempts >= job.MaxAttempts:
+		return r.finish(job, nil, ErrTaskInterrupted)
+	}
+
+	job.Status = d_job.RUNNING
+	job.Attempts++
+	job.UpdatedAt = r.options.Now()
+	if !r.save(job) {
+		return job, false
+	}
+
+	result, err := r.perform(ctx, task, job)
+	if err != nil && ctx.Err() != nil {
+		// The runner is stopping: the job is performed again by the next Run.
+		job.Status = d_job.PENDING
+		job.Attempts--
+		job.UpdatedAt = r.options.Now()
+		return job, r.save(job)
+	}
+	if err != nil && job.Attempts < job.MaxAttempts && taskRetryable(err) {
+		now := r.options.Now()
+		log.Printf("[WARN] Job %s (%s) failed attempt %d of %d: %v", job.ID, job.Task, job.Attempts, job.MaxAttempts, err)
+		job.Status = d_job.PENDING
+		job.Error = err.Error()
+		job.UpdatedAt = now
+		job.NextAttempt = now.Add(r.options.retryDelay(job.Attempts))
+		return job, r.save(job)
+	}
+	return r.finish(job, result, err)
+}
+
+// perform runs a task attempt bounded by TaskTimeout, recovering its panics.
+func (r *JobRunner[Obs]) perform(ctx context.Context, task d_job.Task, job d_job.Job) (result any, err error) {
+	ctx, cancel := context.WithTimeout(ctx, r.options.TaskTimeout)
+	defer cancel()
+
+	defer func() {
+		if value := recover(); value != nil {
+			log.Printf("[ERROR] Job %s (%s) panicked: %v\n%s", job.ID, job.Task, value, debug.Stack())
+			err = fmt.Errorf("%w: %v", ErrTaskPanic, value)
+		}
+	}()
+	return task(ctx, job)
+}
+
+// taskRetryable reports whether a failed task attempt may be retried.
+func taskRetryable(err error) bool {
+	var retryable adapter_output.IRetryableError
+	if errors.As(err, &retryable) {
+		return retryable.Retryable()
+	}
+	return true
+}
+
+// finish stores a job as SUCCEEDED with result, or as FAILED with err.
+func (r *JobRunner[Obs]) finish(job d_job.Job, result any, err error) (d_job.Job, bool) {
+	if err == nil {
+		data, encodeErr := json.Marshal(result)
+		if encodeErr != nil {
+			err = fmt.Errorf("encoding job result: %w", encodeErr)
+		} else {
+			job.Result = string(data)
+		}
+	}
+
+	if err != nil {
+		log.Printf("[ERROR] Job %s (%s) for chat %v failed after %d attempts: %v",
+			job.ID, job.Task, job.Session.ChatID, job.Attempts, err)
+		job.Status = d_job.FAILED
+		job.Error = err.Error()
+	} else {
+		log.Printf("[INFO] Job %s (%s) for chat %v succeeded", job.ID, job.Task, job.Session.ChatID)
+		job.Status = d_job.SUCCEEDED
+		job.Error = ""
+	}
+
+	job.UpdatedAt = r.options.Now()
+	job.NextAttempt = time.Time{}
+	return job, r.save(job)
+}
+
+// resume executes the resume route of a finished job for its chat, with the
+// job in the route's context. Failed resumes are retried by a later dispatch;
+// stale ones are not.
+func (r *JobRunner[Obs]) resume(ctx context.Context, job d_job.Job) {
+	if route := job.ResumeRoute(); route != "" {
+		err := r.resumeChat(ctx, job, route)
+		if errors.Is(err, errStaleJob) {
+			log.Printf("[WARN] Not resuming chat %v after job %s: %v", job.Session.ChatID, job.ID, err)
+			job.Stale = true
+			err = nil
+		}
+		if err != nil {
+			now := r.options.Now()
+			if errors.Is(err, ErrChatHandedOff) {
+				log.Printf("[INFO] Deferring resume of chat %v after job %s: %v", job.Session.ChatID, job.ID, err)
+			} else {
+				log.Printf("[ERROR] Failed to resume chat %v at route %s after job %s: %v",
+					job.Session.ChatID, route, job.ID, err)
+			}
+			job.UpdatedAt = now
+			job.NextAttempt = now.Add(r.options.retryDelay(1))
+			r.save(job)
+			return
+		}
+	}
+
+	job.Resumed = true
+	job.UpdatedAt = r.options.Now()
+	r.save(job)
+}
+
+// resumeChat executes route for the chat of a job on the chat's worker, with
+// the chat's stored state or the job's snapshot. Returns errStaleJob when the
+// stored state shows the chat left the job's waiting route.
+func (r *JobRunner[Obs]) resumeChat(ctx context.Context, job d_job.Job, route string) error {
+	chatID := job.Session.ChatID
+	return r.app.onChat(ctx, chatID, func() error {
+		userState, found, err := r.app.loadState(chatID)
+		if err != nil {
+			return fmt.Errorf("loading chat state: %w", err)
+		}
+		if !found {
+			if userState, err = d_session.ToUserState[Obs](job.Session); err != nil {
+				return fmt.Errorf("decoding job session: %w", err)
+			}
+		} else if current := userState.Route.Current(); job.WaitingRoute != "" && current != job.WaitingRoute {
+			return fmt.Errorf("%w: chat is on route %s, not %s", errStaleJob, current, job.WaitingRoute)
+		}
+
+		// The job ID scopes the idempotency keys of the resumed route.
+		message := d_message.Message{TextMessage: d_message.TextMessage{ID: "job:" + job.ID}}
+		return r.app.resume(d_job.WithJob(ctx, job), userState, route, message)
+	})
+}
+
+// save stores a job, logging failures.
+func (r *JobRunner[Obs]) save(job d_job.Job) bool {
+	if err := r.repository.Save(job); err != nil {
+		log.Printf("[ERROR] Failed to save job %s: %v", job.ID, err)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,463 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/irissonnlima/chatgraph-go/core/clocktest"
+	route_return "github.com/irissonnlima/chatgraph-go/core/domain"
+	d_context "github.com/irissonnlima/chatgraph-go/core/domain/context"
+	d_job "github.com/irissonnlima/chatgraph-go/core/domain/job"
+	d_message "github.com/irissonnlima/chatgraph-go/core/domain/message"
+	d_route "github.com/irissonnlima/chatgraph-go/core/domain/route"
+	d_router "github.com/irissonnlima/chatgraph-go/core/domain/router"
+	d_session "github.com/irissonnlima/chatgraph-go/core/domain/session"
+	d_user "github.com/irissonnlima/chatgraph-go/core/domain/user"
+	adapter_output "github.com/irissonnlima/chatgraph-go/core/ports/adapters/output"
+)
+
+// jobStore is an in-memory IJobRepository.
+type jobStore struct {
+	mu   sync.Mutex
+	jobs map[string]d_job.Job
+}
+
+func newJobStore() *jobStore {
+	return &jobStore{jobs: make(map[string]d_job.Job)}
+}
+
+func (s *jobStore) Save(job d_job.Job) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.jobs[job.ID] = job
+	return nil
+}
+
+func (s *jobStore) Get(id string) (d_job.Job, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	job, ok := s.jobs[id]
+	return job, ok, nil
+}
+
+func (s *jobStore) List() ([]d_job.Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	jobs := make([]d_job.Job, 0, len(s.jobs))
+	for _, job := range s.jobs {
+		jobs = append(jobs, job)
+	}
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].CreatedAt.Before(jobs[j].CreatedAt) })
+	return jobs, nil
+}
+
+func (s *jobStore) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.jobs, id)
+	return nil
+}
+
+func (s *jobStore) Close() error {
+	return nil
+}
+
+// notRetryable is a task error that must not be retried.
+type notRetryable struct{}
+
+func (notRetryable) Error() string   { return "invalid order" }
+func (notRetryable) Retryable() bool { return false }
+
+var jobChat = d_user.ChatID{UserID: "u1", CompanyID: "c1"}
+
+// newJobTest returns an app whose "done" and "failed" routes send the job's
+// result or error, and a runner for it.
+func newJobTest(executor adapter_output.IBotExecutor, clock *clocktest.FakeClock) (*ChatbotApp[TestObs], *JobRunner[TestObs], *jobStore) {
+	app := newTestAppWithRoutes(&fakeReceiver{}, executor, map[string]d_router.RouteHandler[TestObs]{
+		"done": func(ctx *d_context.ChatContext[TestObs]) route_return.RouteReturn {
+			job, _ := ctx.Job()
+			var result struct {
+				URL string `json:"url"`
+			}
+			if err := job.DecodeResult(&result); err != nil {
+				ctx.SendTextMessage("no result: " + err.Error())
+				return nil
+			}
+			ctx.SendTextMessage(result.URL + " for " + ctx.GetObservation().Value)
+			return nil
+		},
+		"failed": func(ctx *d_context.ChatContext[TestObs]) route_return.RouteReturn {
+			job, _ := ctx.Job()
+			ctx.SendTextMessage("failed: " + job.Error)
+			return nil
+		},
+		"waiting": func(ctx *d_context.ChatContext[TestObs]) route_return.RouteReturn {
+			return nil
+		},
+	})
+
+	store := newJobStore()
+	runner := NewJobRunner(app, store, JobOptions{
+		MaxAttempts:    2,
+		RetryBaseDelay: time.Minute,
+		Now:            clock.Now,
+	})
+	return app, runner, store
+}
+
+// enqueue enqueues a job for jobChat, on route "waiting".
+func enqueue(t *testing.T, runner *JobRunner[TestObs], request JobRequest) d_job.Job {
+	t.Helper()
+	userState := d_user.UserState[TestObs]{
+		ChatID:      jobChat,
+		Route:       d_route.NewRoute("start.waiting", '.'),
+		Observation: TestObs{Value: "Ana"},
+	}
+	ctx, cancel := d_context.NewChatContext(userState, d_message.Message{}, newMockExecutor(), time.Second)
+	defer cancel()
+
+	job, err := runner.Enqueue(&ctx, request)
+	if err != nil {
+		t.Fatalf("Enqueue returned error: %v", err)
+	}
+	return job
+}
+
+func sentTexts(executor *mockExecutor) []string {
+	var texts []string
+	for _, action := range executor.expectedExec {
+		if action.Type == ExecSendMessage {
+			texts = append(texts, action.Message.TextMessage.Detail)
+		}
+	}
+	return texts
+}
+
+// TestJobRunner_ResumesAtCompletionRoute tests that a finished job resumes its
+// chat at the completion route, with the result and the snapshot of the chat.
+func TestJobRunner_ResumesAtCompletionRoute(t *testing.T) {
+	executor := newMockExecutor()
+	clock := clocktest.NewFakeClock(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+	_, runner, _ := newJobTest(executor, clock)
+
+	var payload struct{ Order int }
+	runner.RegisterTask("invoice", func(ctx context.Context, job d_job.Job) (any, error) {
+		if err := job.DecodePayload(&payload); err != nil {
+			return nil, err
+		}
+		return map[string]string{"url": "invoice.pdf"}, nil
+	})
+
+	job := enqueue(t, runner, JobRequest{Task: "invoice", Payload: map[string]int{"Order": 42}, CompletionRoute: "done"})
+	if job.Status != d_job.PENDING || job.ID == "" {
+		t.Fatalf("unexpected enqueued job %+v", job)
+	}
+
+	if processed := runner.Dispatch(context.Background()); processed != 1 {
+		t.Fatalf("expected 1 job processed, got %d", processed)
+	}
+
+	if payload.Order != 42 {
+		t.Errorf("expected the task to get the payload, got %+v", payload)
+	}
+	if got := sentTexts(executor); !equalTexts(got, []string{"invoice.pdf for Ana"}) {
+		t.Errorf("messages = %v", got)
+	}
+	if last := executor.expectedExec[len(executor.expectedExec)-1]; last.Type != ExecSetRoute || last.Route != "done" {
+		t.Errorf("expected the chat to be left on the completion route, got %+v", last)
+	}
+
+	stored, ok, _ := runner.Job(job.ID)
+	if !ok || stored.Status != d_job.SUCCEEDED || !stored.Resumed || stored.Attempts != 1 {
+		t.Errorf("unexpected stored job %+v", stored)
+	}
+	if processed := runner.Dispatch(context.Background()); processed != 0 {
+		t.Errorf("expected a resumed job not to be processed again, got %d", processed)
+	}
+}
+
+// TestJobRunner_RetriesThenFails tests that failed attempts are retried after
+// the retry delay and the failure route runs after the last one.
+func TestJobRunner_RetriesThenFails(t *testing.T) {
+	executor := newMockExecutor()
+	clock := clocktest.NewFakeClock(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+	_, runner, _ := newJobTest(executor, clock)
+
+	attempts := 0
+	runner.RegisterTask("analysis", func(ctx context.Context, job d_job.Job) (any, error) {
+		attempts++
+		return nil, errors.New("bureau offline")
+	})
+	job := enqueue(t, runner, JobRequest{Task: "analysis", CompletionRoute: "done", FailureRoute: "failed"})
+
+	runner.Dispatch(context.Background())
+	stored, _, _ := runner.Job(job.ID)
+	if stored.Status != d_job.PENDING || stored.Error != "bureau offline" || !stored.NextAttempt.Equal(clock.Now().Add(time.Minute)) {
+		t.Fatalf("expected the job to wait for a retry, got %+v", stored)
+	}
+
+	if processed := runner.Dispatch(context.Background()); processed != 0 {
+		t.Fatalf("expected no retry before the delay, got %d", processed)
+	}
+
+	clock.Advance(time.Minute)
+	runner.Dispatch(context.Background())
+
+	stored, _, _ = runner.Job(job.ID)
+	if attempts != 2 || stored.Status != d_job.FAILED || !stored.Resumed {
+		t.Errorf("expected the job to fail after 2 attempts, got %d attempts and %+v", attempts, stored)
+	}
+	if got := sentTexts(executor); !equalTexts(got, []string{"failed: bureau offline"}) {
+		t.Errorf("messages = %v", got)
+	}
+}
+
+// TestJobRunner_NotRetryable tests that non-retryable errors fail the job at once.
+func TestJobRunner_NotRetryable(t *testing.T) {
+	executor := newMockExecutor()
+	clock := clocktest.NewFakeClock(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+	_, runner, _ := newJobTest(executor, clock)
+
+	runner.RegisterTask("invoice", func(ctx context.Context, job d_job.Job) (any, error) {
+		return nil, notRetryable{}
+	})
+	job := enqueue(t, runner, JobRequest{Task: "invoice", CompletionRoute: "done"})
+
+	runner.Dispatch(context.Background())
+
+	stored, _, _ := runner.Job(job.ID)
+	if stored.Status != d_job.FAILED || stored.Attempts != 1 || !stored.Resumed {
+		t.Errorf("unexpected job %+v", stored)
+	}
+	if len(executor.expectedExec) != 0 {
+		t.Errorf("failed jobs without failure route should not resume the chat, got %+v", executor.expectedExec)
+	}
+}
+
+// TestJobRunner_Panic tests that a panicking task is recovered as a failed attempt.
+func TestJobRunner_Panic(t *testing.T) {
+	executor := newMockExecutor()
+	clock := clocktest.NewFakeClock(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+	_, runner, _ := newJobTest(executor, clock)
+
+	runner.RegisterTask("invoice", func(ctx context.Context, job d_job.Job) (any, error) {
+		panic("boom")
+	})
+	job := enqueue(t, runner, JobRequest{Task: "invoice", CompletionRoute: "done", MaxAttempts: 1})
+
+	runner.Dispatch(context.Background())
+
+	stored, _, _ := runner.Job(job.ID)
+	if stored.Status != d_job.FAILED || stored.Error != "job task panicked: boom" {
+		t.Errorf("unexpected job %+v", stored)
+	}
+}
+
+// TestJobRunner_PicksUpInterruptedJobs tests that jobs left running by a
+// crash are performed again, unless it was their last attempt.
+func TestJobRunner_PicksUpInterruptedJobs(t *testing.T) {
+	executor := newMockExecutor()
+	clock := clocktest.NewFakeClock(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+	_, runner, store := newJobTest(executor, clock)
+
+	runner.RegisterTask("invoice", func(ctx context.Context, job d_job.Job) (any, error) {
+		return map[string]string{"url": "again.pdf"}, nil
+	})
+	retried := enqueue(t, runner, JobRequest{Task: "invoice", CompletionRoute: "done"})
+	retried.Status, retried.Attempts = d_job.RUNNING, 1
+	store.Save(retried)
+
+	exhausted := enqueue(t, runner, JobRequest{Task: "invoice", CompletionRoute: "done", FailureRoute: "failed"})
+	exhausted.Status, exhausted.Attempts = d_job.RUNNING, 2
+	store.Save(exhausted)
+
+	// Jobs of a chat are processed one at a time.
+	if processed := runner.Dispatch(context.Background()); processed != 1 {
+		t.Fatalf("expected 1 job processed, got %d", processed)
+	}
+	runner.Dispatch(context.Background())
+
+	if stored, _, _ := runner.Job(retried.ID); stored.Status != d_job.SUCCEEDED || stored.Attempts != 2 {
+		t.Errorf("expected the interrupted job to be performed again, got %+v", stored)
+	}
+	if stored, _, _ := runner.Job(exhausted.ID); stored.Status != d_job.FAILED || stored.Error != ErrTaskInterrupted.Error() {
+		t.Errorf("expected the job interrupted on its last attempt to fail, got %+v", stored)
+	}
+}
+
+// TestJobRunner_ShutdownDoesNotCountAttempt tests that a task cancelled by
+// the runner stopping is performed again later.
+func TestJobRunner_ShutdownDoesNotCountAttempt(t *testing.T) {
+	executor := newMockExecutor()
+	clock := clocktest.NewFakeClock(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+	_, runner, _ := newJobTest(executor, clock)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	runner.RegisterTask("invoice", func(taskCtx context.Context, job d_job.Job) (any, error) {
+		cancel()
+		<-taskCtx.Done()
+		return nil, taskCtx.Err()
+	})
+	job := enqueue(t, runner, JobRequest{Task: "invoice", CompletionRoute: "done"})
+
+	runner.Dispatch(ctx)
+
+	stored, _, _ := runner.Job(job.ID)
+	if stored.Status != d_job.PENDING || stored.Attempts != 0 {
+		t.Errorf("expected the job to be pending with no attempts, got %+v", stored)
+	}
+}
+
+// TestJobRunner_RetriesResumeWhileUnavailable tests that the chat is resumed
+// once the executor is available again.
+func TestJobRunner_RetriesResumeWhileUnavailable(t *testing.T) {
+	clock := clocktest.NewFakeClock(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+	executor := &unavailableExecutor{mockExecutor: newMockExecutor()}
+
+	_, runner, _ := newJobTest(executor, clock)
+
+	runner.RegisterTask("invoice", func(ctx context.Context, job d_job.Job) (any, error) {
+		return map[string]string{"url": "invoice.pdf"}, nil
+	})
+	job := enqueue(t, runner, JobRequest{Task: "invoice", CompletionRoute: "done"})
+
+	runner.Dispatch(context.Background())
+	stored, _, _ := runner.Job(job.ID)
+	if stored.Status != d_job.SUCCEEDED || stored.Resumed || len(executor.expectedExec) != 0 {
+		t.Fatalf("expected the resume to wait for the executor, got %+v", stored)
+	}
+
+	executor.available = true
+	clock.Advance(time.Minute)
+	runner.Dispatch(context.Background())
+
+	stored, _, _ = runner.Job(job.ID)
+	if !stored.Resumed || stored.Attempts != 1 {
+		t.Errorf("expected the chat to be resumed without running the task again, got %+v", stored)
+	}
+	if got := sentTexts(executor.mockExecutor); !equalTexts(got, []string{"invoice.pdf for Ana"}) {
+		t.Errorf("messages = %v", got)
+	}
+}
+
+// TestJobRunner_RemovesJobsAfterRetention tests that resumed jobs are kept
+// for status queries until the retention expires.
+func TestJobRunner_RemovesJobsAfterRetention(t *testing.T) {
+	executor := newMockExecutor()
+	clock := clocktest.NewFakeClock(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+	_, runner, _ := newJobTest(executor, clock)
+
+	runner.RegisterTask("invoice", func(ctx context.Context, job d_job.Job) (any, error) {
+		return nil, nil
+	})
+	job := enqueue(t, runner, JobRequest{Task: "invoice", CompletionRoute: "done"})
+	runner.Dispatch(context.Background())
+
+	if jobs, _ := runner.Jobs(jobChat); len(jobs) != 1 || jobs[0].ID != job.ID {
+		t.Fatalf("expected the job of the chat, got %+v", jobs)
+	}
+
+	clock.Advance(DEFAULT_JOB_RETENTION)
+	runner.Dispatch(context.Background())
+
+	if _, ok, _ := runner.Job(job.ID); ok {
+		t.Error("expected the job to be removed after the retention")
+	}
+}
+
+// TestJobRunner_Enqueue_Validation tests that jobs for unknown tasks or
+// routes are rejected.
+func TestJobRunner_Enqueue_Validation(t *testing.T) {
+	_, runner, _ := newJobTest(newMockExecutor(), clocktest.NewFakeClock(time.Time{}))
+	runner.RegisterTask("invoice", func(ctx context.Context, job d_job.Job) (any, error) {
+		return nil, nil
+	})
+
+	ctx, cancel := d_context.NewChatContext(d_user.UserState[TestObs]{ChatID: jobChat}, d_message.Message{}, newMockExecutor(), time.Second)
+	defer cancel()
+
+	if _, err := runner.Enqueue(&ctx, JobRequest{Task: "missing", CompletionRoute: "done"}); !errors.Is(err, ErrUnknownTask) {
+		t.Errorf("expected ErrUnknownTask, got %v", err)
+	}
+	if _, err := runner.Enqueue(&ctx, JobRequest{Task: "invoice"}); err == nil {
+		t.Error("expected an error without completion route")
+	}
+	if _, err := runner.Enqueue(&ctx, JobRequest{Task: "invoice", CompletionRoute: "missing"}); err == nil {
+		t.Error("expected an error for an unknown completion route")
+	}
+}
+
+// TestJobRunner_StaleResume tests that a chat that left the job's waiting
+// route is not resumed, and the job is marked stale.
+func TestJobRunner_StaleResume(t *testing.T) {
+	clock := clocktest.NewFakeClock(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+	executor := &stateExecutor{mockExecutor: newMockExecutor(), sessions: map[d_user.ChatID]d_session.Session{}}
+	_, runner, _ := newJobTest(executor, clock)
+	runner.RegisterTask("invoice", func(ctx context.Context, job d_job.Job) (any, error) {
+		return map[string]string{"url": "invoice.pdf"}, nil
+	})
+
+	job := enqueue(t, runner, JobRequest{Task: "invoice", CompletionRoute: "done", WaitingRoute: "waiting"})
+	// The user moved on while the job ran.
+	executor.sessions[jobChat] = d_session.Session{ChatID: jobChat, Route: "start.menu"}
+	runner.Dispatch(context.Background())
+
+	stored, _, _ := runner.Job(job.ID)
+	if !stored.Resumed || !stored.Stale {
+		t.Errorf("expected the job to be marked stale, got %+v", stored)
+	}
+	if got := sentTexts(executor.mockExecutor); len(got) != 0 {
+		t.Errorf("expected the completion route not to run, got %v", got)
+	}
+
+	// A chat still waiting is resumed.
+	job = enqueue(t, runner, JobRequest{Task: "invoice", CompletionRoute: "done", WaitingRoute: "waiting"})
+	executor.sessions[jobChat] = d_session.Session{ChatID: jobChat, Route: "start.waiting"}
+	runner.Dispatch(context.Background())
+
+	stored, _, _ = runner.Job(job.ID)
+	if !stored.Resumed || stored.Stale {
+		t.Errorf("expected the job to resume the chat, got %+v", stored)
+	}
+	if got := sentTexts(executor.mockExecutor); len(got) != 1 {
+		t.Errorf("expected the completion route to run, got %v", got)
+	}
+}
+
+// TestJobRunner_ResumesOnChatWorker tests that a resume waits for the
+// messages queued for the chat.
+func TestJobRunner_ResumesOnChatWorker(t *testing.T) {
+	clock := clocktest.NewFakeClock(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+	app, runner, _ := newJobTest(newMockExecutor(), clock)
+	runner.RegisterTask("invoice", func(ctx context.Context, job d_job.Job) (any, error) {
+		return nil, nil
+	})
+
+	release := make(chan struct{})
+	app.pool.start()
+	defer app.pool.stop()
+	app.pool.do(jobChat, func() { <-release })
+
+	enqueue(t, runner, JobRequest{Task: "invoice", CompletionRoute: "done"})
+	dispatched := make(chan struct{})
+	go func() {
+		runner.Dispatch(context.Background())
+		close(dispatched)
+	}()
+
+	select {
+	case <-dispatched:
+		t.Fatal("expected the resume to wait for the chat's worker")
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(release)
+	select {
+	case <-dispatched:
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected the resume to run once the chat's worker is free")
+	}
+}
@@ -71,3 +71,28 @@ func (m *mockExecutor) GetFile(fileID string) (*d_file.File, error) {
 	})
 	return &d_file.File{ID: fileID, URL: "test-url", Name: "test"}, nil
 }
+
+func (m *mockExecutor) SendTyping(chatID d_user.ChatID, platform string) error {
+	m.expectedExec = append(m.expectedExec, ExpectedAction{
+		Type: ExecSendTyping,
+	})
+	return nil
+}
+
+func (m *mockExecutor) EditMessage(chatID d_user.ChatID, messageID string, msg d_message.Message, platform string) error {
+	m.expectedExec = append(m.expectedExec, ExpectedAction{
+		Type:      ExecEditMessage,
+		MessageID: messageID,
+		Message:   &msg,
+	})
+	return nil
+}
+
+func (m *mockExecutor) React(chatID d_user.ChatID, messageID string, reaction string, platform string) error {
+	m.expectedExec = append(m.expectedExec, ExpectedAction{
+		Type:      ExecReact,
+		MessageID: messageID,
+		Reaction:  reaction,
+	})
+	return nil
+}
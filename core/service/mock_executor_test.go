@@ -178,3 +178,25 @@ func TestMockExecutor_MultipleActions(t *testing.T) {
 		t.Errorf("action 2: expected ExecSetRoute")
 	}
 }
+
+// TestMockExecutor_Capabilities tests recording of the optional capabilities.
+func TestMockExecutor_Capabilities(t *testing.T) {
+	mock := newMockExecutor()
+
+	mock.SendTyping(d_user.ChatID{}, "whatsapp")
+	mock.EditMessage(d_user.ChatID{}, "m1", d_message.Message{}, "whatsapp")
+	mock.React(d_user.ChatID{}, "m2", "👍", "whatsapp")
+
+	if len(mock.expectedExec) != 3 {
+		t.Fatalf("expected 3 actions, got %d", len(mock.expectedExec))
+	}
+	if mock.expectedExec[0].Type != ExecSendTyping {
+		t.Errorf("action 0: expected ExecSendTyping")
+	}
+	if a := mock.expectedExec[1]; a.Type != ExecEditMessage || a.MessageID != "m1" {
+		t.Errorf("action 1: expected ExecEditMessage of m1, got %+v", a)
+	}
+	if a := mock.expectedExec[2]; a.Type != ExecReact || a.MessageID != "m2" || a.Reaction != "👍" {
+		t.Errorf("action 2: expected ExecReact of m2, got %+v", a)
+	}
+}
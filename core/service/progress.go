@@ -0,0 +1,90 @@
+// Package service provides the main chatbot application service.
+// This file contains the progress feedback sent while a slow handler runs.
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	d_idempotency "github.com/irissonnlima/chatgraph-go/core/domain/idempotency"
+	d_message "github.com/irissonnlima/chatgraph-go/core/domain/message"
+	d_router "github.com/irissonnlima/chatgraph-go/core/domain/router"
+	d_user "github.com/irissonnlima/chatgraph-go/core/domain/user"
+	adapter_output "github.com/irissonnlima/chatgraph-go/core/ports/adapters/output"
+)
+
+// startProgress sends the progress feedback of a slow handler (see
+// d_router.ProgressRouteOps) with executor, bound to parent, until the
+// returned function is called. The function waits for the feedback in flight,
+// so nothing is sent after the handler's own actions.
+func startProgress[Obs any](
+	parent context.Context,
+	options *d_router.ProgressRouteOps,
+	userState d_user.UserState[Obs],
+	executor adapter_output.IBotExecutor,
+) (stop func()) {
+	if options == nil {
+		return func() {}
+	}
+
+	after := options.After
+	if after <= 0 {
+		after = d_router.DEFAULT_PROGRESS_AFTER
+	}
+
+	// Progress actions are timing dependent: they must not take keys from
+	// the message's idempotency scope.
+	ctx, cancel := context.WithCancel(d_idempotency.WithoutScope(parent))
+	executor = bindExecutor(ctx, executor)
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		timer := time.NewTimer(after)
+		defer timer.Stop()
+
+		message := options.Message
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-timer.C:
+			}
+
+			sendProgress(executor, userState, message)
+			if options.Repeat <= 0 {
+				return
+			}
+			if options.Reminder != nil {
+				message = options.Reminder
+			}
+			timer.Reset(options.Repeat)
+		}
+	}()
+
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
+// sendProgress sends a progress message, or a typing indicator when message is nil.
+func sendProgress[Obs any](
+	executor adapter_output.IBotExecutor,
+	userState d_user.UserState[Obs],
+	message *d_message.Message,
+) {
+	var err error
+	if message != nil {
+		err = executor.SendMessage(userState.ChatID, *message, userState.Platform)
+	} else if notifier, ok := executor.(adapter_output.ITypingNotifier); ok {
+		err = notifier.SendTyping(userState.ChatID, userState.Platform)
+	}
+
+	if err != nil && !errors.Is(err, adapter_output.ErrUnsupported) && !errors.Is(err, context.Canceled) {
+		log.Printf("[WARN] Failed to send progress to chat %v: %v", userState.ChatID, err)
+	}
+}
@@ -0,0 +1,220 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	route_return "github.com/irissonnlima/chatgraph-go/core/domain"
+	d_action "github.com/irissonnlima/chatgraph-go/core/domain/action"
+	d_context "github.com/irissonnlima/chatgraph-go/core/domain/context"
+	d_file "github.com/irissonnlima/chatgraph-go/core/domain/file"
+	d_idempotency "github.com/irissonnlima/chatgraph-go/core/domain/idempotency"
+	d_message "github.com/irissonnlima/chatgraph-go/core/domain/message"
+	d_router "github.com/irissonnlima/chatgraph-go/core/domain/router"
+	d_user "github.com/irissonnlima/chatgraph-go/core/domain/user"
+	adapter_output "github.com/irissonnlima/chatgraph-go/core/ports/adapters/output"
+)
+
+// syncExecutor is a mockExecutor safe for the concurrent calls of a handler
+// and its progress feedback. It records the idempotency key of each message.
+type syncExecutor struct {
+	mu   *sync.Mutex
+	mock *mockExecutor
+	keys *[]string
+	ctx  context.Context
+}
+
+func newSyncExecutor() *syncExecutor {
+	return &syncExecutor{mu: &sync.Mutex{}, mock: newMockExecutor(), keys: &[]string{}, ctx: context.Background()}
+}
+
+func (e *syncExecutor) WithContext(ctx context.Context) adapter_output.IBotExecutor {
+	bound := *e
+	bound.ctx = ctx
+	return &bound
+}
+
+func (e *syncExecutor) SendMessage(chatID d_user.ChatID, msg d_message.Message, platform string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	*e.keys = append(*e.keys, d_idempotency.ActionKey(e.ctx))
+	return e.mock.SendMessage(chatID, msg, platform)
+}
+
+func (e *syncExecutor) SendTyping(chatID d_user.ChatID, platform string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.mock.SendTyping(chatID, platform)
+}
+
+func (e *syncExecutor) SetObservation(chatID d_user.ChatID, observation string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.mock.SetObservation(chatID, observation)
+}
+
+func (e *syncExecutor) SetRoute(chatID d_user.ChatID, route string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.mock.SetRoute(chatID, route)
+}
+
+func (e *syncExecutor) texts() []string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	var texts []string
+	for _, action := range e.mock.expectedExec {
+		switch action.Type {
+		case ExecSendMessage:
+			texts = append(texts, action.Message.TextMessage.Detail)
+		case ExecSendTyping:
+			texts = append(texts, "<typing>")
+		}
+	}
+	return texts
+}
+
+func (e *syncExecutor) EndSession(chatID d_user.ChatID, actionId string) error {
+	return e.mock.EndSession(chatID, actionId)
+}
+
+func (e *syncExecutor) TransferToMenu(chatID d_user.ChatID, transfer d_action.TransferToMenu, msg d_message.Message) error {
+	return e.mock.TransferToMenu(chatID, transfer, msg)
+}
+
+func (e *syncExecutor) UploadFile(filepath string) (*d_file.File, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.mock.UploadFile(filepath)
+}
+
+func (e *syncExecutor) GetFile(fileID string) (*d_file.File, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.mock.GetFile(fileID)
+}
+
+func textMessage(detail string) *d_message.Message {
+	return &d_message.Message{TextMessage: d_message.TextMessage{Detail: detail}}
+}
+
+// slowEngine returns an engine whose start route takes delay and then answers.
+func slowEngine(delay time.Duration, progress *d_router.ProgressRouteOps) *Engine[TestObs] {
+	engine := NewEngine[TestObs]()
+	engine.RegisterRoute("start", func(ctx *d_context.ChatContext[TestObs]) route_return.RouteReturn {
+		time.Sleep(delay)
+		ctx.SendTextMessage("answer")
+		return nil
+	}, d_router.RouterHandlerOptions{Progress: progress})
+	return engine
+}
+
+func equalTexts(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// TestProgress_SentWhenSlow tests that the interim message is sent once a
+// handler exceeds the threshold, before its answer.
+func TestProgress_SentWhenSlow(t *testing.T) {
+	engine := slowEngine(150*time.Millisecond, &d_router.ProgressRouteOps{
+		After:   30 * time.Millisecond,
+		Message: textMessage("Please wait..."),
+	})
+	executor := newSyncExecutor()
+
+	if _, err := engine.Execute(userOn("start"), d_message.Message{}, executor); err != nil {
+		t.Fatalf("Execute returned error: %v", err)
+	}
+	if got := executor.texts(); !equalTexts(got, []string{"Please wait...", "answer"}) {
+		t.Errorf("messages = %v", got)
+	}
+}
+
+// TestProgress_NotSentWhenFast tests that fast handlers get no feedback.
+func TestProgress_NotSentWhenFast(t *testing.T) {
+	engine := slowEngine(0, &d_router.ProgressRouteOps{
+		After:   100 * time.Millisecond,
+		Message: textMessage("Please wait..."),
+	})
+	executor := newSyncExecutor()
+
+	if _, err := engine.Execute(userOn("start"), d_message.Message{}, executor); err != nil {
+		t.Fatalf("Execute returned error: %v", err)
+	}
+	time.Sleep(150 * time.Millisecond)
+
+	if got := executor.texts(); !equalTexts(got, []string{"answer"}) {
+		t.Errorf("messages = %v", got)
+	}
+}
+
+// TestProgress_RemindersStopWithHandler tests that reminders repeat while the
+// handler runs and stop when it returns.
+func TestProgress_RemindersStopWithHandler(t *testing.T) {
+	engine := slowEngine(200*time.Millisecond, &d_router.ProgressRouteOps{
+		After:    20 * time.Millisecond,
+		Message:  textMessage("Please wait..."),
+		Repeat:   50 * time.Millisecond,
+		Reminder: textMessage("Still working..."),
+	})
+	executor := newSyncExecutor()
+
+	if _, err := engine.Execute(userOn("start"), d_message.Message{}, executor); err != nil {
+		t.Fatalf("Execute returned error: %v", err)
+	}
+	texts := executor.texts()
+	time.Sleep(120 * time.Millisecond)
+
+	if len(texts) < 3 || texts[0] != "Please wait..." || texts[1] != "Still working..." || texts[len(texts)-1] != "answer" {
+		t.Errorf("messages = %v", texts)
+	}
+	if after := executor.texts(); len(after) != len(texts) {
+		t.Errorf("progress continued after the handler returned: %v", after[len(texts):])
+	}
+}
+
+// TestProgress_TypingIndicator tests that a typing indicator is shown when no
+// message is configured.
+func TestProgress_TypingIndicator(t *testing.T) {
+	engine := slowEngine(100*time.Millisecond, &d_router.ProgressRouteOps{After: 20 * time.Millisecond})
+	executor := newSyncExecutor()
+
+	if _, err := engine.Execute(userOn("start"), d_message.Message{}, executor); err != nil {
+		t.Fatalf("Execute returned error: %v", err)
+	}
+	if got := executor.texts(); !equalTexts(got, []string{"<typing>", "answer"}) {
+		t.Errorf("actions = %v", got)
+	}
+}
+
+// TestProgress_DoesNotTakeIdempotencyKeys tests that progress messages do not
+// shift the keys of the handler's actions.
+func TestProgress_DoesNotTakeIdempotencyKeys(t *testing.T) {
+	engine := slowEngine(100*time.Millisecond, &d_router.ProgressRouteOps{
+		After:   20 * time.Millisecond,
+		Message: textMessage("Please wait..."),
+	})
+	executor := newSyncExecutor()
+	chatID := d_user.ChatID{UserID: "u1", CompanyID: "c1"}
+	ctx := d_idempotency.WithScope(context.Background(), d_idempotency.NewScope(chatID, "m1", "start"))
+
+	if _, err := engine.execute(ctx, userOn("start"), d_message.Message{}, executor); err != nil {
+		t.Fatalf("execute returned error: %v", err)
+	}
+
+	keys := *executor.keys
+	if len(keys) != 2 || keys[0] != "" || keys[1] != d_idempotency.Key(chatID, "m1", "start", 1) {
+		t.Errorf("keys = %q", keys)
+	}
+}
@@ -0,0 +1,223 @@
+// Package service provides the main chatbot application service.
+// This file contains the worker pool that processes deliveries concurrently
+// while preserving message order within each chat.
+package service
+
+import (
+	"hash/fnv"
+	"sync"
+	"sync/atomic"
+
+	d_user "github.com/irissonnlima/chatgraph-go/core/domain/user"
+	adapter_input "github.com/irissonnlima/chatgraph-go/core/ports/adapters/input"
+)
+
+// PoolMetrics is a point-in-time snapshot of the worker pool and of the
+// handlers abandoned by the engine.
+type PoolMetrics struct {
+	// Workers is the number of workers in the pool.
+	Workers int
+	// BusyWorkers is the number of workers currently handling a delivery.
+	BusyWorkers int
+	// QueueDepth is the number of deliveries waiting for a worker.
+	QueueDepth int
+	// Processed is the total number of deliveries handled since the pool started.
+	Processed uint64
+	// AbandonedHandlers is the number of handlers still running after their timeout.
+	AbandonedHandlers int
+	// AbandonedTotal is the number of handlers abandoned since the engine was created.
+	AbandonedTotal uint64
+	// Throttled is the number of messages that waited for abandoned handlers
+	// to finish, because AppOptions.MaxAbandonedHandlers was reached.
+	Throttled uint64
+}
+
+// workerPool dispatches deliveries to a fixed set of workers.
+// Each chat is pinned to one worker by hashing its ChatID, so messages from the
+// same chat are handled strictly in order while different chats run in parallel.
+// Other work on a chat, such as resuming it after a job, is queued behind its
+// deliveries the same way (see do).
+//
+// The pool holds up to workers*queueSize queued deliveries in total, shared by
+// the shards: a slow chat may queue more than queueSize deliveries on its
+// shard without stopping the dispatcher, which only blocks once the whole
+// pool is full. The trade-off is that a single stuck chat can take the whole
+// capacity before backpressure applies; the receiver prefetch (see
+// AppOptions) bounds the deliveries in flight either way.
+type workerPool[Obs any] struct {
+	shards []*shard
+	handle func(adapter_input.Delivery[Obs])
+	// slots holds a token per queued delivery, bounding the pool's capacity.
+	slots chan struct{}
+
+	// mu guards running; do holds it for reading while queueing.
+	mu      sync.RWMutex
+	running bool
+
+	wg        sync.WaitGroup
+	busy      atomic.Int64
+	queued    atomic.Int64
+	processed atomic.Uint64
+}
+
+// newWorkerPool creates a pool with the given number of workers, each with a
+// queue of queueSize pending deliveries.
+func newWorkerPool[Obs any](
+	workers int,
+	queueSize int,
+	handle func(adapter_input.Delivery[Obs]),
+) *workerPool[Obs] {
+	shards := make([]*shard, workers)
+	for i := range shards {
+		shards[i] = &shard{ready: make(chan struct{}, 1)}
+	}
+
+	return &workerPool[Obs]{
+		shards: shards,
+		handle: handle,
+		slots:  make(chan struct{}, workers*queueSize),
+	}
+}
+
+// start launches one goroutine per shard.
+func (p *workerPool[Obs]) start() {
+	p.mu.Lock()
+	p.running = true
+	p.mu.Unlock()
+
+	for _, shard := range p.shards {
+		p.wg.Add(1)
+		go p.work(shard)
+	}
+}
+
+// work runs the queued work of a single shard sequentially.
+func (p *workerPool[Obs]) work(shard *shard) {
+	defer p.wg.Done()
+
+	for {
+		task, ok := shard.pop()
+		if !ok {
+			return
+		}
+		<-p.slots
+		p.queued.Add(-1)
+		p.busy.Add(1)
+		task()
+		p.busy.Add(-1)
+		p.processed.Add(1)
+	}
+}
+
+// submit enqueues a delivery on the shard owning its chat.
+// It blocks while the pool is full, whatever the shard.
+func (p *workerPool[Obs]) submit(delivery adapter_input.Delivery[Obs]) {
+	p.enqueue(delivery.UserState.ChatID, func() { p.handle(delivery) })
+}
+
+// do enqueues task on the shard owning chatID, behind the chat's queued
+// deliveries, and returns without waiting for it. Returns false, without
+// queueing, when the pool is not running.
+func (p *workerPool[Obs]) do(chatID d_user.ChatID, task func()) bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if !p.running {
+		return false
+	}
+	p.enqueue(chatID, task)
+	return true
+}
+
+// enqueue takes a slot and pushes task on the shard owning chatID.
+func (p *workerPool[Obs]) enqueue(chatID d_user.ChatID, task func()) {
+	p.slots <- struct{}{}
+	p.queued.Add(1)
+	p.shards[shardFor(chatID, len(p.shards))].push(task)
+}
+
+// stop closes the shard queues and waits for queued work to be done.
+// submit must not be called after stop; do then returns false.
+func (p *workerPool[Obs]) stop() {
+	p.mu.Lock()
+	p.running = false
+	p.mu.Unlock()
+
+	for _, shard := range p.shards {
+		shard.close()
+	}
+	p.wg.Wait()
+}
+
+// metrics returns a snapshot of the pool state.
+func (p *workerPool[Obs]) metrics() PoolMetrics {
+	return PoolMetrics{
+		Workers:     len(p.shards),
+		BusyWorkers: int(p.busy.Load()),
+		QueueDepth:  int(p.queued.Load()),
+		Processed:   p.processed.Load(),
+	}
+}
+
+// shardFor maps a chat to one of n shards.
+func shardFor(chatID d_user.ChatID, n int) int {
+	h := fnv.New32a()
+	h.Write([]byte(chatID.CompanyID))
+	h.Write([]byte{0})
+	h.Write([]byte(chatID.UserID))
+	return int(h.Sum32() % uint32(n))
+}
+
+// shard is the FIFO queue of a worker. It is unbounded: the pool's slots
+// bound the work queued across all shards.
+type shard struct {
+	mu      sync.Mutex
+	pending []func()
+	closed  bool
+	// ready is signalled when work is pushed or the shard is closed.
+	ready chan struct{}
+}
+
+// push appends a task to the queue.
+func (s *shard) push(task func()) {
+	s.mu.Lock()
+	s.pending = append(s.pending, task)
+	s.mu.Unlock()
+	s.signal()
+}
+
+// pop removes the oldest task, waiting for one. ok is false once the shard
+// is closed and empty.
+func (s *shard) pop() (task func(), ok bool) {
+	for {
+		s.mu.Lock()
+		if len(s.pending) > 0 {
+			task = s.pending[0]
+			s.pending[0] = nil
+			s.pending = s.pending[1:]
+			s.mu.Unlock()
+			return task, true
+		}
+		closed := s.closed
+		s.mu.Unlock()
+		if closed {
+			return nil, false
+		}
+		<-s.ready
+	}
+}
+
+// close stops the worker once the queued work is done.
+func (s *shard) close() {
+	s.mu.Lock()
+	s.closed = true
+	s.mu.Unlock()
+	s.signal()
+}
+
+// signal wakes the worker up, if it waits.
+func (s *shard) signal() {
+	select {
+	case s.ready <- struct{}{}:
+	default:
+	}
+}
@@ -0,0 +1,198 @@
+package service
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	d_message "github.com/irissonnlima/chatgraph-go/core/domain/message"
+	d_user "github.com/irissonnlima/chatgraph-go/core/domain/user"
+	adapter_input "github.com/irissonnlima/chatgraph-go/core/ports/adapters/input"
+)
+
+func newTestDelivery(userID string, text string) adapter_input.Delivery[TestObs] {
+	return adapter_input.Delivery[TestObs]{
+		UserState: d_user.UserState[TestObs]{
+			ChatID: d_user.ChatID{UserID: userID, CompanyID: "company"},
+		},
+		Message: d_message.Message{
+			TextMessage: d_message.TextMessage{Detail: text},
+		},
+	}
+}
+
+// TestWorkerPool_PerChatOrdering tests that messages from one chat keep their order.
+func TestWorkerPool_PerChatOrdering(t *testing.T) {
+	var mu sync.Mutex
+	received := map[string][]string{}
+
+	pool := newWorkerPool(4, 2, func(d adapter_input.Delivery[TestObs]) {
+		// Make earlier messages slower so reordering would show up.
+		if d.Message.TextMessage.Detail == "0" {
+			time.Sleep(10 * time.Millisecond)
+		}
+		mu.Lock()
+		received[d.UserState.ChatID.UserID] = append(received[d.UserState.ChatID.UserID], d.Message.TextMessage.Detail)
+		mu.Unlock()
+	})
+	pool.start()
+
+	users := []string{"a", "b", "c", "d", "e"}
+	texts := []string{"0", "1", "2", "3"}
+	for _, text := range texts {
+		for _, user := range users {
+			pool.submit(newTestDelivery(user, text))
+		}
+	}
+	pool.stop()
+
+	for _, user := range users {
+		got := received[user]
+		if len(got) != len(texts) {
+			t.Fatalf("user %s: expected %d messages, got %d", user, len(texts), len(got))
+		}
+		for i, text := range texts {
+			if got[i] != text {
+				t.Errorf("user %s: message %d = %s, want %s", user, i, got[i], text)
+			}
+		}
+	}
+}
+
+// TestWorkerPool_ParallelChats tests that a slow chat does not block other chats.
+func TestWorkerPool_ParallelChats(t *testing.T) {
+	release := make(chan struct{})
+	done := make(chan string, 1)
+
+	slow := newTestDelivery("slow", "x")
+	var fast adapter_input.Delivery[TestObs]
+	for i := 0; ; i++ {
+		fast = newTestDelivery("fast"+string(rune('a'+i)), "x")
+		if shardFor(fast.UserState.ChatID, 2) != shardFor(slow.UserState.ChatID, 2) {
+			break
+		}
+	}
+
+	pool := newWorkerPool(2, 1, func(d adapter_input.Delivery[TestObs]) {
+		if d.UserState.ChatID.UserID == "slow" {
+			<-release
+			return
+		}
+		done <- d.UserState.ChatID.UserID
+	})
+	pool.start()
+	defer pool.stop()
+	defer close(release)
+
+	pool.submit(slow)
+	pool.submit(fast)
+
+	select {
+	case user := <-done:
+		if user != fast.UserState.ChatID.UserID {
+			t.Errorf("expected %s to finish, got %s", fast.UserState.ChatID.UserID, user)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("fast chat was blocked by slow chat")
+	}
+}
+
+// TestWorkerPool_SharedCapacity tests that a chat queueing past its worker's
+// share does not block deliveries for other workers.
+func TestWorkerPool_SharedCapacity(t *testing.T) {
+	release := make(chan struct{})
+	done := make(chan string, 1)
+
+	slow := newTestDelivery("slow", "x")
+	var fast adapter_input.Delivery[TestObs]
+	for i := 0; ; i++ {
+		fast = newTestDelivery("fast"+string(rune('a'+i)), "x")
+		if shardFor(fast.UserState.ChatID, 2) != shardFor(slow.UserState.ChatID, 2) {
+			break
+		}
+	}
+
+	pool := newWorkerPool(2, 2, func(d adapter_input.Delivery[TestObs]) {
+		if d.UserState.ChatID.UserID == "slow" {
+			<-release
+			return
+		}
+		done <- d.UserState.ChatID.UserID
+	})
+	pool.start()
+	defer pool.stop()
+	defer close(release)
+
+	// One in progress plus three queued: more than the slow worker's share.
+	for i := 0; i < 4; i++ {
+		pool.submit(slow)
+	}
+
+	submitted := make(chan struct{})
+	go func() {
+		pool.submit(fast)
+		close(submitted)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("fast chat was blocked by the slow chat's queue")
+	}
+	<-submitted
+}
+
+// TestWorkerPool_Metrics tests busy workers, queue depth and processed counters.
+func TestWorkerPool_Metrics(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+
+	pool := newWorkerPool(1, 2, func(d adapter_input.Delivery[TestObs]) {
+		started <- struct{}{}
+		<-release
+	})
+	pool.start()
+
+	pool.submit(newTestDelivery("a", "1"))
+	pool.submit(newTestDelivery("a", "2"))
+	<-started
+
+	m := pool.metrics()
+	if m.Workers != 1 {
+		t.Errorf("expected 1 worker, got %d", m.Workers)
+	}
+	if m.BusyWorkers != 1 {
+		t.Errorf("expected 1 busy worker, got %d", m.BusyWorkers)
+	}
+	if m.QueueDepth != 1 {
+		t.Errorf("expected queue depth 1, got %d", m.QueueDepth)
+	}
+
+	release <- struct{}{}
+	<-started
+	release <- struct{}{}
+	pool.stop()
+
+	m = pool.metrics()
+	if m.Processed != 2 {
+		t.Errorf("expected 2 processed, got %d", m.Processed)
+	}
+	if m.BusyWorkers != 0 || m.QueueDepth != 0 {
+		t.Errorf("expected idle pool, got %+v", m)
+	}
+}
+
+// TestShardFor tests that sharding is deterministic and within range.
+func TestShardFor(t *testing.T) {
+	chatID := d_user.ChatID{UserID: "user", CompanyID: "company"}
+
+	first := shardFor(chatID, 8)
+	for i := 0; i < 10; i++ {
+		if got := shardFor(chatID, 8); got != first {
+			t.Fatalf("shardFor() not deterministic: %d != %d", got, first)
+		}
+	}
+	if first < 0 || first >= 8 {
+		t.Errorf("shardFor() = %d, out of range", first)
+	}
+}
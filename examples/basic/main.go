@@ -8,9 +8,12 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/joho/godotenv"
 
@@ -56,8 +59,11 @@ func main() {
 	// Create the chatbot application with the engine
 	app := chat.NewApp(engine, rabbit, routerApi)
 
-	// Start the application
-	if err := app.Start(); err != nil {
+	// Start the application; Ctrl+C or SIGTERM triggers a graceful shutdown
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := app.Start(ctx); err != nil {
 		log.Fatalf("Failed to start the application: %v", err)
 	}
 }
@@ -2,8 +2,11 @@
 package main
 
 import (
+	"context"
 	"log"
 	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/joho/godotenv"
 
@@ -90,7 +93,11 @@ func main() {
 	// Create the app with the engine
 	app := chat.NewApp(engine, rabbit, routerApi)
 
-	if err := app.Start(); err != nil {
+	// Start the application; Ctrl+C or SIGTERM triggers a graceful shutdown
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := app.Start(ctx); err != nil {
 		log.Fatalf("Failed to start: %v", err)
 	}
 }
@@ -0,0 +1,45 @@
+// Example: fakerouter - A local fake of the Router API
+//
+// This example demonstrates:
+// - Running the in-memory fake Router API for local development
+// - Pointing the other examples at it through ROUTER_API_URL
+// - Inspecting what a bot sent, through the server logs
+package main
+
+import (
+	"log"
+	"net/http"
+	"os"
+
+	"github.com/joho/godotenv"
+
+	"github.com/irissonnlima/chatgraph-go/adapters/output/router_api/fakeserver"
+)
+
+func main() {
+	// Load environment variables from .env file
+	if err := godotenv.Load(); err != nil {
+		log.Println("[WARN] No .env file found, using environment variables")
+	}
+
+	addr := os.Getenv("FAKE_ROUTER_ADDR")
+	if addr == "" {
+		addr = "localhost:8090"
+	}
+
+	// Require the same credentials the bots are configured with
+	server := fakeserver.New(fakeserver.Options{
+		Username: os.Getenv("ROUTER_API_USER"),
+		Password: os.Getenv("ROUTER_API_PASSWORD"),
+	})
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		log.Printf("[INFO] %s %s", req.Method, req.URL.Path)
+		server.ServeHTTP(w, req)
+	})
+
+	log.Printf("[INFO] Fake Router API listening on http://%s", addr)
+	if err := http.ListenAndServe(addr, handler); err != nil {
+		log.Fatalf("[CRITICAL] %v", err)
+	}
+}
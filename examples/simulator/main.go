@@ -0,0 +1,129 @@
+// Example: simulator - Runs a chatbot in the terminal
+//
+// This example demonstrates:
+// - Developing a flow locally without RabbitMQ or the Router API
+// - Buttons rendered as numbered choices
+// - Observations and route changes shown after each turn
+// - Receiving files with the /file command
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/irissonnlima/chatgraph-go/chat"
+)
+
+// Obs defines the custom observation data for this chatbot.
+type Obs struct {
+	Name  string `json:"name,omitempty"`
+	Files int    `json:"files,omitempty"`
+}
+
+func main() {
+	// Keep the terminal clean: framework logs are only useful when debugging.
+	if os.Getenv("CHATGRAPH_DEBUG") == "" {
+		log.SetOutput(io.Discard)
+	}
+
+	engine := newEngine()
+	sim := chat.NewSimulator(engine)
+
+	// Run until /quit, end of input, Ctrl+C or SIGTERM
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := sim.Run(ctx); err != nil {
+		fmt.Fprintf(os.Stderr, "simulator: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// newEngine creates the engine and registers the routes of the demo bot.
+func newEngine() *chat.Engine[Obs] {
+	engine := chat.NewEngine[Obs]()
+	engine.RegisterRoute("timeout_route", handleTimeout)
+	engine.RegisterRoute("loop_route", handleLoop)
+	engine.RegisterRoute("start", handleStart)
+	engine.RegisterRoute("ask_name", handleAskName)
+	engine.RegisterRoute("menu", handleMenu)
+	engine.RegisterRoute("wait_file", handleWaitFile)
+	return engine
+}
+
+func handleTimeout(ctx *chat.Context[Obs]) chat.RouteReturn {
+	ctx.SendTextMessage("Your request has timed out. Please try again.")
+	return ctx.NextRoute("start")
+}
+
+func handleLoop(ctx *chat.Context[Obs]) chat.RouteReturn {
+	ctx.SendTextMessage("Let's start over.")
+	return &chat.RedirectResponse{TargetRoute: "start"}
+}
+
+func handleStart(ctx *chat.Context[Obs]) chat.RouteReturn {
+	ctx.SendTextMessage("Hi! What's your name?")
+	return ctx.NextRoute("ask_name")
+}
+
+func handleAskName(ctx *chat.Context[Obs]) chat.RouteReturn {
+	obs := ctx.GetObservation()
+	obs.Name = ctx.Message.EntireText()
+	ctx.SetObservation(obs)
+
+	ctx.SendTextMessage(fmt.Sprintf("Nice to meet you, %s!", obs.Name))
+	sendMenu(ctx)
+	return ctx.NextRoute("menu")
+}
+
+func handleMenu(ctx *chat.Context[Obs]) chat.RouteReturn {
+	switch ctx.Message.EntireText() {
+	case "send_file":
+		ctx.SendTextMessage("Send a file with /file <path>.")
+		return ctx.NextRoute("wait_file")
+	case "end":
+		ctx.SendTextMessage(fmt.Sprintf("Bye, %s!", ctx.GetObservation().Name))
+		return &chat.EndAction{ID: "finished"}
+	default:
+		sendMenu(ctx)
+		return nil
+	}
+}
+
+func handleWaitFile(ctx *chat.Context[Obs]) chat.RouteReturn {
+	if !ctx.Message.HasFile() {
+		ctx.SendTextMessage("That's not a file. Use /file <path>.")
+		return nil
+	}
+
+	data, err := ctx.DownloadFile(ctx.Message.File)
+	if err != nil {
+		ctx.SendTextMessage("Could not read the file: " + err.Error())
+		return nil
+	}
+
+	obs := ctx.GetObservation()
+	obs.Files++
+	ctx.SetObservation(obs)
+
+	ctx.SendTextMessage(fmt.Sprintf("Got %s (%d bytes). Files received: %d", ctx.Message.File.Name, len(data), obs.Files))
+	sendMenu(ctx)
+	return ctx.NextRoute("menu")
+}
+
+// sendMenu sends the main menu buttons.
+func sendMenu(ctx *chat.Context[Obs]) {
+	ctx.SendMessage(chat.Message{
+		TextMessage: chat.TextMessage{Detail: "What would you like to do?"},
+		Buttons: []chat.Button{
+			{Type: chat.POSTBACK, Title: "Send a file", Detail: "send_file"},
+			{Type: chat.POSTBACK, Title: "End the chat", Detail: "end"},
+			{Type: chat.URL, Title: "Documentation", Detail: "https://github.com/irissonnlima/chatgraph-go"},
+		},
+	})
+}
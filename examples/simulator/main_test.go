@@ -0,0 +1,35 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/irissonnlima/chatgraph-go/chat"
+)
+
+// TestConversation drives the demo bot through the simulator with a scripted conversation.
+func TestConversation(t *testing.T) {
+	var out bytes.Buffer
+	sim := chat.NewSimulator(newEngine(), chat.SimulatorOptions{
+		In:  strings.NewReader("hello\nAna\n2\n"),
+		Out: &out,
+	})
+
+	if err := sim.Run(context.Background()); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	for _, want := range []string{
+		"Nice to meet you, Ana!",
+		"[1] Send a file",
+		`~ observation: {} -> {"name":"Ana"}`,
+		"Bye, Ana!",
+		`session ended (action "finished")`,
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output does not contain %q:\n%s", want, out.String())
+		}
+	}
+}
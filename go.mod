@@ -4,4 +4,9 @@ go 1.23.2
 
 require github.com/rabbitmq/amqp091-go v1.10.0
 
-require github.com/joho/godotenv v1.5.1 // indirect
+require (
+	github.com/joho/godotenv v1.5.1
+	go.etcd.io/bbolt v1.3.11
+)
+
+require golang.org/x/sys v0.4.0 // indirect